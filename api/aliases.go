@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// AddBlockchainAlias makes the blockchain [chainID] reachable at
+// /ext/bc/[alias] on the node behind [client], and lets [alias] be used
+// in place of [chainID] in the node's API calls.
+// The node must have the Admin API enabled.
+// Aliases are held in memory, so they're lost when the node restarts.
+func AddBlockchainAlias(ctx context.Context, client Client, chainID ids.ID, alias string) error {
+	if _, err := client.AdminAPI().AliasChain(ctx, chainID.String(), alias); err != nil {
+		return fmt.Errorf("couldn't alias blockchain %s to %q: %w", chainID, alias, err)
+	}
+	return nil
+}
+
+// AddAPIAlias makes the API at [endpoint] (e.g. "bc/<chain ID>/rpc")
+// also reachable at /ext/[alias] on the node behind [client].
+// The node must have the Admin API enabled.
+// Aliases are held in memory, so they're lost when the node restarts.
+func AddAPIAlias(ctx context.Context, client Client, endpoint string, alias string) error {
+	if _, err := client.AdminAPI().Alias(ctx, endpoint, alias); err != nil {
+		return fmt.Errorf("couldn't alias API %q to %q: %w", endpoint, alias, err)
+	}
+	return nil
+}
@@ -1,7 +1,8 @@
 package api
 
 import (
-	"fmt"
+	"net"
+	"strconv"
 
 	"github.com/ava-labs/avalanchego/api/admin"
 	"github.com/ava-labs/avalanchego/api/health"
@@ -34,6 +35,8 @@ type APIClient struct {
 	admin        admin.Client
 	pindex       indexer.Client
 	cindex       indexer.Client
+	xindex       indexer.Client
+	xvtxindex    indexer.Client
 }
 
 // Returns a new API client for a node at [ipAddr]:[port].
@@ -41,7 +44,7 @@ type NewAPIClientF func(ipAddr string, port uint16) Client
 
 // NewAPIClient initialize most of avalanchego apis
 func NewAPIClient(ipAddr string, port uint16) Client {
-	uri := fmt.Sprintf("http://%s:%d", ipAddr, port)
+	uri := "http://" + net.JoinHostPort(ipAddr, strconv.Itoa(int(port)))
 	return &APIClient{
 		platform:     platformvm.NewClient(uri),
 		xChain:       avm.NewClient(uri, "X"),
@@ -55,6 +58,8 @@ func NewAPIClient(ipAddr string, port uint16) Client {
 		admin:        admin.NewClient(uri),
 		pindex:       indexer.NewClient(uri, "/ext/index/P/block"),
 		cindex:       indexer.NewClient(uri, "/ext/index/C/block"),
+		xindex:       indexer.NewClient(uri, "/ext/index/X/tx"),
+		xvtxindex:    indexer.NewClient(uri, "/ext/index/X/vtx"),
 	}
 }
 
@@ -105,3 +110,11 @@ func (c APIClient) PChainIndexAPI() indexer.Client {
 func (c APIClient) CChainIndexAPI() indexer.Client {
 	return c.cindex
 }
+
+func (c APIClient) XChainIndexAPI() indexer.Client {
+	return c.xindex
+}
+
+func (c APIClient) XChainVertexIndexAPI() indexer.Client {
+	return c.xvtxindex
+}
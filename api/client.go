@@ -27,5 +27,25 @@ type Client interface {
 	AdminAPI() admin.Client
 	PChainIndexAPI() indexer.Client
 	CChainIndexAPI() indexer.Client
+	XChainIndexAPI() indexer.Client
+	XChainVertexIndexAPI() indexer.Client
 	// TODO add methods
 }
+
+type withEthClient struct {
+	Client
+	ethClient EthClient
+}
+
+// WithEthClient returns a Client that behaves like [client]
+// except that CChainEthAPI returns [ethClient].
+func WithEthClient(client Client, ethClient EthClient) Client {
+	return &withEthClient{
+		Client:    client,
+		ethClient: ethClient,
+	}
+}
+
+func (c *withEthClient) CChainEthAPI() EthClient {
+	return c.ethClient
+}
@@ -2,20 +2,52 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"net"
+	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/coreth/core/types"
 	"github.com/ava-labs/coreth/ethclient"
 	"github.com/ava-labs/coreth/interfaces"
+	"github.com/ava-labs/coreth/rpc"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
 )
 
+// Max time to wait between attempts to re-establish a subscription
+const resubscribeBackoffMax = 5 * time.Second
+
 // Interface compliance
 var _ EthClient = &ethClient{}
 
+// EthTransport is the transport used by an EthClient
+type EthTransport string
+
+const (
+	// Websocket transport. Supports subscriptions.
+	EthTransportWS EthTransport = "ws"
+	// HTTP transport. Doesn't support subscriptions.
+	EthTransportHTTP EthTransport = "http"
+)
+
+// Validate returns an error if [t] isn't a known transport.
+// The empty transport is valid, and means [EthTransportWS].
+func (t EthTransport) Validate() error {
+	switch t {
+	case "", EthTransportWS, EthTransportHTTP:
+		return nil
+	default:
+		return fmt.Errorf("unknown eth transport %q", t)
+	}
+}
+
 type EthClient interface {
 	Close()
 	SendTransaction(context.Context, *types.Transaction) error
@@ -36,32 +68,121 @@ type EthClient interface {
 	HeaderByNumber(context.Context, *big.Int) (*types.Header, error)
 	SuggestGasTipCap(context.Context) (*big.Int, error)
 	FilterLogs(context.Context, interfaces.FilterQuery) ([]types.Log, error)
+	// Subscriptions are only supported by the websocket transport.
+	// If the connection is lost, the subscription is re-established
+	// over a new connection. Events emitted in between may be missed.
 	SubscribeFilterLogs(context.Context, interfaces.FilterQuery, chan<- types.Log) (interfaces.Subscription, error)
+	SubscribeNewHead(context.Context, chan<- *types.Header) (interfaces.Subscription, error)
 }
 
-// ethClient websocket ethclient.Client with mutexed api calls and lazy conn (on first call)
-// All calls are wrapped in a mutex, and try to create a connection if it doesn't exist yet
+// ethClient ethclient.Client with mutexed api calls and lazy conn (on first call)
+// All calls are wrapped in a mutex, and try to create a connection if it doesn't exist yet.
+// If a call fails because the connection was lost, the connection is
+// dropped, and a new one is created on the next call.
 type ethClient struct {
-	ipAddr string
-	port   uint
-	client ethclient.Client
-	lock   sync.Mutex
+	ipAddr    string
+	port      uint
+	transport EthTransport
+	// If non-nil, the HTTP requests of the HTTP transport are sent with it
+	httpClient *http.Client
+	client     ethclient.Client
+	// Held by the call in progress. A channel rather than a mutex,
+	// so that calls stop waiting for it once their context is done.
+	lock chan struct{}
+	// Guards [subs]
+	subsLock sync.Mutex
+	// Subscriptions that are re-established until they're
+	// unsubscribed, or the client is closed
+	subs map[*subscription]struct{}
+}
+
+// A subscription of an ethClient, re-established whenever it fails
+type subscription struct {
+	event.Subscription
+	client *ethClient
+}
+
+func (s *subscription) Unsubscribe() {
+	s.Subscription.Unsubscribe()
+	s.client.subsLock.Lock()
+	delete(s.client.subs, s)
+	s.client.subsLock.Unlock()
 }
 
 // NewEthClient mainly takes ip/port info for usage in future calls
 // Connection can't be initialized in constructor because node is not ready when the constructor is called
 // It follows convention of most avalanchego api constructors that can be called without having a ready node
 func NewEthClient(ipAddr string, port uint) EthClient {
+	return NewEthClientWithTransport(ipAddr, port, EthTransportWS)
+}
+
+// NewEthClientWithTransport is like NewEthClient but uses [transport]
+func NewEthClientWithTransport(ipAddr string, port uint, transport EthTransport) EthClient {
+	if transport == "" {
+		transport = EthTransportWS
+	}
 	return &ethClient{
-		ipAddr: ipAddr,
-		port:   port,
+		ipAddr:    ipAddr,
+		port:      port,
+		transport: transport,
+		lock:      make(chan struct{}, 1),
+		subs:      make(map[*subscription]struct{}),
 	}
 }
 
-// connect attempts to connect with websocket ethclient API
-func (c *ethClient) connect() error {
+// NewEthClientWithHTTPClient is like NewEthClient, but uses the HTTP
+// transport, whose requests are sent with [httpClient]. See NewHTTPClient.
+func NewEthClientWithHTTPClient(ipAddr string, port uint, httpClient *http.Client) EthClient {
+	return &ethClient{
+		ipAddr:     ipAddr,
+		port:       port,
+		transport:  EthTransportHTTP,
+		httpClient: httpClient,
+		lock:       make(chan struct{}, 1),
+		subs:       make(map[*subscription]struct{}),
+	}
+}
+
+// lockAndConnect acquires [c.lock] and connects with the ethclient API,
+// unless [ctx] is done first. [c.lock] is only held if nil is returned.
+func (c *ethClient) lockAndConnect(ctx context.Context) error {
+	select {
+	case c.lock <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := c.connect(ctx); err != nil {
+		c.unlock()
+		return err
+	}
+	return nil
+}
+
+func (c *ethClient) unlock() {
+	<-c.lock
+}
+
+// connect attempts to connect with the ethclient API
+// Assumes [c.lock] is held
+func (c *ethClient) connect(ctx context.Context) error {
 	if c.client == nil {
-		client, err := ethclient.Dial(fmt.Sprintf("ws://%s:%d/ext/bc/C/ws", c.ipAddr, c.port))
+		var uri string
+		host := net.JoinHostPort(c.ipAddr, strconv.Itoa(int(c.port)))
+		switch c.transport {
+		case EthTransportHTTP:
+			uri = "http://" + host + "/ext/bc/C/rpc"
+		default:
+			uri = "ws://" + host + "/ext/bc/C/ws"
+		}
+		if c.httpClient != nil {
+			rpcClient, err := rpc.DialHTTPWithClient(uri, c.httpClient)
+			if err != nil {
+				return err
+			}
+			c.client = ethclient.NewClient(rpcClient)
+			return nil
+		}
+		client, err := ethclient.DialContext(ctx, uri)
 		if err != nil {
 			return err
 		}
@@ -70,183 +191,270 @@ func (c *ethClient) connect() error {
 	return nil
 }
 
-// Close closes opened connection (if any)
+// checkErr drops the connection if [err] shows it was lost,
+// so that the next call reconnects. Returns [err].
+// Assumes [c.lock] is held
+func (c *ethClient) checkErr(err error) error {
+	if c.client != nil && isConnErr(err) {
+		c.client.Close()
+		c.client = nil
+	}
+	return err
+}
+
+// Returns true if [err] is caused by a broken connection
+// rather than by the request itself, or its context
+func isConnErr(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, rpc.ErrClientQuit) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr)
+}
+
+// Close closes opened connection (if any), and the subscriptions,
+// once they stopped being re-established
 func (c *ethClient) Close() {
+	c.subsLock.Lock()
+	subs := make([]*subscription, 0, len(c.subs))
+	for sub := range c.subs {
+		subs = append(subs, sub)
+	}
+	c.subsLock.Unlock()
+	// Waits for the loops re-establishing the subscriptions to exit,
+	// so that they don't reconnect once the connection is closed
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+
+	c.lock <- struct{}{}
+	defer c.unlock()
 	if c.client == nil {
 		return
 	}
-	c.lock.Lock()
-	defer c.lock.Unlock()
 	c.client.Close()
+	c.client = nil
+}
+
+// resubscribe returns a subscription that is first [sub], and is
+// re-established with [subscribeF] whenever it fails, until it's
+// unsubscribed or the client is closed
+func (c *ethClient) resubscribe(sub interfaces.Subscription, subscribeF func(context.Context) (interfaces.Subscription, error)) interfaces.Subscription {
+	first := true
+	s := &subscription{
+		Subscription: event.ResubscribeErr(resubscribeBackoffMax, func(ctx context.Context, _ error) (event.Subscription, error) {
+			if first {
+				first = false
+				return sub, nil
+			}
+			return subscribeF(ctx)
+		}),
+		client: c,
+	}
+	c.subsLock.Lock()
+	c.subs[s] = struct{}{}
+	c.subsLock.Unlock()
+	return s
+}
+
+func (c *ethClient) subscribeFilterLogs(ctx context.Context, query interfaces.FilterQuery, ch chan<- types.Log) (interfaces.Subscription, error) {
+	if err := c.lockAndConnect(ctx); err != nil {
+		return nil, err
+	}
+	defer c.unlock()
+	sub, err := c.client.SubscribeFilterLogs(ctx, query, ch)
+	return sub, c.checkErr(err)
+}
+
+func (c *ethClient) SubscribeFilterLogs(ctx context.Context, query interfaces.FilterQuery, ch chan<- types.Log) (interfaces.Subscription, error) {
+	sub, err := c.subscribeFilterLogs(ctx, query, ch)
+	if err != nil {
+		return nil, err
+	}
+	return c.resubscribe(sub, func(ctx context.Context) (interfaces.Subscription, error) {
+		return c.subscribeFilterLogs(ctx, query, ch)
+	}), nil
+}
+
+func (c *ethClient) subscribeNewHead(ctx context.Context, ch chan<- *types.Header) (interfaces.Subscription, error) {
+	if err := c.lockAndConnect(ctx); err != nil {
+		return nil, err
+	}
+	defer c.unlock()
+	sub, err := c.client.SubscribeNewHead(ctx, ch)
+	return sub, c.checkErr(err)
+}
+
+func (c *ethClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (interfaces.Subscription, error) {
+	sub, err := c.subscribeNewHead(ctx, ch)
+	if err != nil {
+		return nil, err
+	}
+	return c.resubscribe(sub, func(ctx context.Context) (interfaces.Subscription, error) {
+		return c.subscribeNewHead(ctx, ch)
+	}), nil
 }
 
 func (c *ethClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return err
 	}
-	return c.client.SendTransaction(ctx, tx)
+	defer c.unlock()
+	return c.checkErr(c.client.SendTransaction(ctx, tx))
 }
 
 func (c *ethClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.TransactionReceipt(ctx, txHash)
+	defer c.unlock()
+	res, err := c.client.TransactionReceipt(ctx, txHash)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.BalanceAt(ctx, account, blockNumber)
+	defer c.unlock()
+	res, err := c.client.BalanceAt(ctx, account, blockNumber)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.BlockByNumber(ctx, number)
+	defer c.unlock()
+	res, err := c.client.BlockByNumber(ctx, number)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.BlockByHash(ctx, hash)
+	defer c.unlock()
+	res, err := c.client.BlockByHash(ctx, hash)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) BlockNumber(ctx context.Context) (uint64, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return 0, err
 	}
-	return c.client.BlockNumber(ctx)
+	defer c.unlock()
+	res, err := c.client.BlockNumber(ctx)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) CallContract(ctx context.Context, msg interfaces.CallMsg, blockNumber *big.Int) ([]byte, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.CallContract(ctx, msg, blockNumber)
+	defer c.unlock()
+	res, err := c.client.CallContract(ctx, msg, blockNumber)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return 0, err
 	}
-	return c.client.NonceAt(ctx, account, blockNumber)
+	defer c.unlock()
+	res, err := c.client.NonceAt(ctx, account, blockNumber)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) AssetBalanceAt(ctx context.Context, account common.Address, assetID ids.ID, blockNumber *big.Int) (*big.Int, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.AssetBalanceAt(ctx, account, assetID, blockNumber)
+	defer c.unlock()
+	res, err := c.client.AssetBalanceAt(ctx, account, assetID, blockNumber)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.SuggestGasPrice(ctx)
+	defer c.unlock()
+	res, err := c.client.SuggestGasPrice(ctx)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) AcceptedCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.AcceptedCodeAt(ctx, account)
+	defer c.unlock()
+	res, err := c.client.AcceptedCodeAt(ctx, account)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) AcceptedNonceAt(ctx context.Context, account common.Address) (uint64, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return 0, err
 	}
-	return c.client.AcceptedNonceAt(ctx, account)
+	defer c.unlock()
+	res, err := c.client.AcceptedNonceAt(ctx, account)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.CodeAt(ctx, account, blockNumber)
+	defer c.unlock()
+	res, err := c.client.CodeAt(ctx, account, blockNumber)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) EstimateGas(ctx context.Context, msg interfaces.CallMsg) (uint64, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return 0, err
 	}
-	return c.client.EstimateGas(ctx, msg)
+	defer c.unlock()
+	res, err := c.client.EstimateGas(ctx, msg)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) AcceptedCallContract(ctx context.Context, call interfaces.CallMsg) ([]byte, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.AcceptedCallContract(ctx, call)
+	defer c.unlock()
+	res, err := c.client.AcceptedCallContract(ctx, call)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.HeaderByNumber(ctx, number)
+	defer c.unlock()
+	res, err := c.client.HeaderByNumber(ctx, number)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.SuggestGasTipCap(ctx)
+	defer c.unlock()
+	res, err := c.client.SuggestGasTipCap(ctx)
+	return res, c.checkErr(err)
 }
 
 func (c *ethClient) FilterLogs(ctx context.Context, query interfaces.FilterQuery) ([]types.Log, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
-		return nil, err
-	}
-	return c.client.FilterLogs(ctx, query)
-}
-
-func (c *ethClient) SubscribeFilterLogs(ctx context.Context, query interfaces.FilterQuery, ch chan<- types.Log) (interfaces.Subscription, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if err := c.connect(); err != nil {
+	if err := c.lockAndConnect(ctx); err != nil {
 		return nil, err
 	}
-	return c.client.SubscribeFilterLogs(ctx, query, ch)
+	defer c.unlock()
+	res, err := c.client.FilterLogs(ctx, query)
+	return res, c.checkErr(err)
 }
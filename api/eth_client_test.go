@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/coreth/interfaces"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEthClientContext(t *testing.T) {
+	assert := assert.New(t)
+	c := NewEthClient("127.0.0.1", 1).(*ethClient)
+
+	// Calls stop waiting for the call in progress once their context is done
+	c.lock <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	_, err := c.BlockNumber(ctx)
+	cancel()
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	c.unlock()
+
+	// Connecting respects the context
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = c.BlockNumber(ctx)
+	assert.Error(err)
+	assert.Nil(c.client)
+	c.Close()
+}
+
+// TestEthClientCloseSubscriptions tests that closing the client
+// stops the re-establishment of its subscriptions
+func TestEthClientCloseSubscriptions(t *testing.T) {
+	assert := assert.New(t)
+	c := NewEthClient("127.0.0.1", 1).(*ethClient)
+	// The subscription fails at once, and can't be re-established
+	lost := event.NewSubscription(func(<-chan struct{}) error {
+		return errors.New("connection lost")
+	})
+	var attempts int32
+	sub := c.resubscribe(lost, func(context.Context) (interfaces.Subscription, error) {
+		atomic.AddInt32(&attempts, 1)
+		return nil, errors.New("connection refused")
+	})
+	assert.Eventually(func() bool {
+		return atomic.LoadInt32(&attempts) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	c.Close()
+	assert.Empty(c.subs)
+	attemptsAtClose := atomic.LoadInt32(&attempts)
+	time.Sleep(2 * resubscribeBackoffMax / 10)
+	assert.Equal(attemptsAtClose, atomic.LoadInt32(&attempts))
+	// Unsubscribing a closed subscription is a no-op
+	sub.Unsubscribe()
+}
+
+func TestIsConnErr(t *testing.T) {
+	assert := assert.New(t)
+	assert.False(isConnErr(nil))
+	assert.False(isConnErr(context.Canceled))
+	assert.False(isConnErr(context.DeadlineExceeded))
+	assert.False(isConnErr(errors.New("execution reverted")))
+	assert.True(isConnErr(io.EOF))
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/api"
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+)
+
+// KeystoreUser is a user of a node's keystore,
+// with the private keys it holds
+type KeystoreUser struct {
+	Username string
+	Password string
+	// Imported to the user on the X-Chain, P-Chain and C-Chain.
+	// If empty, genesis.EWOQKey is imported, so that the user is funded.
+	Keys []*crypto.PrivateKeySECP256K1R
+}
+
+func (u KeystoreUser) userPass() api.UserPass {
+	return api.UserPass{Username: u.Username, Password: u.Password}
+}
+
+// HasKeystoreUser returns whether the keystore of
+// the node behind [client] has a user named [username]
+func HasKeystoreUser(ctx context.Context, client Client, username string) (bool, error) {
+	usernames, err := client.KeystoreAPI().ListUsers(ctx)
+	if err != nil {
+		return false, fmt.Errorf("couldn't list keystore users: %w", err)
+	}
+	for _, existing := range usernames {
+		if existing == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CreateKeystoreUser creates [user] in the keystore of the node behind
+// [client], unless it exists, and imports its keys on the X-Chain,
+// P-Chain and C-Chain. The node must have the Keystore API enabled.
+func CreateKeystoreUser(ctx context.Context, client Client, user KeystoreUser) error {
+	exists, err := HasKeystoreUser(ctx, client, user.Username)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := client.KeystoreAPI().CreateUser(ctx, user.userPass()); err != nil {
+			return fmt.Errorf("couldn't create keystore user %q: %w", user.Username, err)
+		}
+	}
+	return ImportKeystoreKeys(ctx, client, user)
+}
+
+// ImportKeystoreKeys imports the keys of [user], which must exist in the
+// keystore of the node behind [client], on the X-Chain, P-Chain and C-Chain
+func ImportKeystoreKeys(ctx context.Context, client Client, user KeystoreUser) error {
+	keys := user.Keys
+	if len(keys) == 0 {
+		keys = []*crypto.PrivateKeySECP256K1R{genesis.EWOQKey}
+	}
+	userPass := user.userPass()
+	for _, key := range keys {
+		if _, err := client.XChainAPI().ImportKey(ctx, userPass, key); err != nil {
+			return fmt.Errorf("couldn't import key to keystore user %q on the X-Chain: %w", user.Username, err)
+		}
+		if _, err := client.PChainAPI().ImportKey(ctx, userPass, key); err != nil {
+			return fmt.Errorf("couldn't import key to keystore user %q on the P-Chain: %w", user.Username, err)
+		}
+		if _, err := client.CChainAPI().ImportKey(ctx, userPass, key); err != nil {
+			return fmt.Errorf("couldn't import key to keystore user %q on the C-Chain: %w", user.Username, err)
+		}
+	}
+	return nil
+}
+
+// ExportKeystoreUser returns the keystore user [username] of the node
+// behind [client], as given to ImportKeystoreUser
+func ExportKeystoreUser(ctx context.Context, client Client, username string, password string) ([]byte, error) {
+	exported, err := client.KeystoreAPI().ExportUser(ctx, api.UserPass{Username: username, Password: password})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't export keystore user %q: %w", username, err)
+	}
+	return exported, nil
+}
+
+// ImportKeystoreUser imports [exported], a user exported with
+// ExportKeystoreUser, as [username] in the keystore of the node
+// behind [client]. [password] must be the exported user's password.
+func ImportKeystoreUser(ctx context.Context, client Client, username string, password string, exported []byte) error {
+	if _, err := client.KeystoreAPI().ImportUser(ctx, api.UserPass{Username: username, Password: password}, exported); err != nil {
+		return fmt.Errorf("couldn't import keystore user %q: %w", username, err)
+	}
+	return nil
+}
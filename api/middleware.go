@@ -0,0 +1,319 @@
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"unsafe"
+)
+
+var _ http.RoundTripper = RoundTripperFunc(nil)
+
+var ErrNoHTTPClient = errors.New("no HTTP client to replace")
+
+// Middleware wraps the round tripper of the HTTP requests sent to a node's
+// API, e.g. to log requests, measure their latency or add headers.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is a function that implements http.RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// ClientConfig determines how the HTTP requests
+// of a node's API clients are sent
+type ClientConfig struct {
+	// If non-nil, requests are sent over HTTPS, and the
+	// node's API cert must be signed by one of these certs
+	TLSRootCAs *x509.CertPool
+	// If non-empty, sent as auth token with each request,
+	// for nodes started with api-auth-required
+	AuthToken string
+	// If non-empty, and [AuthToken] is empty, an auth token is
+	// requested from the node's auth API with this password
+	// before the first request, and when the token is rejected
+	AuthPassword string
+	// Requests go through this middleware, the first one outermost
+	Middleware []Middleware
+}
+
+// NewAPIClientWithMiddleware returns a NewAPIClientF that creates clients
+// with [newAPIClientF], and sends the HTTP requests to [ipAddr]:[port]
+// through [middleware]. The first middleware is the outermost.
+// See NewAPIClientWithConfig.
+func NewAPIClientWithMiddleware(newAPIClientF NewAPIClientF, middleware ...Middleware) NewAPIClientF {
+	return NewAPIClientWithConfig(newAPIClientF, ClientConfig{Middleware: middleware})
+}
+
+// NewAPIClientWithConfig returns a NewAPIClientF that creates clients
+// with [newAPIClientF], and sends the HTTP requests to [ipAddr]:[port]
+// as given by [config]. See NewHTTPClient and NewAPIClientWithHTTPClient.
+func NewAPIClientWithConfig(newAPIClientF NewAPIClientF, config ClientConfig) NewAPIClientF {
+	return NewAPIClientWithHTTPClient(newAPIClientF, NewHTTPClient(config))
+}
+
+// NewAPIClientWithHTTPClient returns a NewAPIClientF that creates clients
+// with [newAPIClientF], whose HTTP requests are sent with [httpClient].
+// Panics if SetHTTPClient fails for a created client; use
+// SetHTTPClient directly to handle the error instead.
+func NewAPIClientWithHTTPClient(newAPIClientF NewAPIClientF, httpClient *http.Client) NewAPIClientF {
+	return func(ipAddr string, port uint16) Client {
+		client := newAPIClientF(ipAddr, port)
+		if err := SetHTTPClient(client, httpClient); err != nil {
+			panic(err)
+		}
+		return client
+	}
+}
+
+// SetHTTPClient makes [client] send its HTTP requests with [httpClient].
+//
+// The avalanchego API clients keep a copy of http.DefaultClient, which
+// can't be given to them, so the copies held by [client] are replaced by
+// [httpClient]. Since this depends on the layout of their unexported
+// fields, an error is returned if an avalanchego API client of an
+// APIClient holds no HTTP client to replace, or if any other [client]
+// holds none at all, rather than its requests silently not going through
+// [httpClient]. Websocket connections (e.g. of the default C-Chain eth
+// client) don't go through [httpClient]; see NewEthClientWithHTTPClient.
+func SetHTTPClient(client Client, httpClient *http.Client) error {
+	if apiClient, ok := client.(*APIClient); ok {
+		return apiClient.setHTTPClient(httpClient)
+	}
+	if setHTTPClients(reflect.ValueOf(client), httpClient, map[uintptr]struct{}{}) == 0 {
+		return fmt.Errorf("%w in %T", ErrNoHTTPClient, client)
+	}
+	return nil
+}
+
+func (c *APIClient) setHTTPClient(httpClient *http.Client) error {
+	// The eth client has its own transport
+	apiClients := []struct {
+		name   string
+		client interface{}
+	}{
+		{"P-Chain", c.platform},
+		{"X-Chain", c.xChain},
+		{"X-Chain wallet", c.xChainWallet},
+		{"C-Chain", c.cChain},
+		{"info", c.info},
+		{"health", c.health},
+		{"ipcs", c.ipcs},
+		{"keystore", c.keystore},
+		{"admin", c.admin},
+		{"P-Chain index", c.pindex},
+		{"C-Chain index", c.cindex},
+		{"X-Chain index", c.xindex},
+		{"X-Chain vertex index", c.xvtxindex},
+	}
+	for _, apiClient := range apiClients {
+		if setHTTPClients(reflect.ValueOf(apiClient.client), httpClient, map[uintptr]struct{}{}) == 0 {
+			return fmt.Errorf("%w in %s API client %T", ErrNoHTTPClient, apiClient.name, apiClient.client)
+		}
+	}
+	return nil
+}
+
+// NewHTTPClient returns an HTTP client that sends requests
+// as given by [config], with a transport of its own
+func NewHTTPClient(config ClientConfig) *http.Client {
+	var roundTripper http.RoundTripper
+	if config.TLSRootCAs != nil {
+		roundTripper = httpsRoundTripper(config.TLSRootCAs)
+	} else {
+		roundTripper = newTransport()
+	}
+	switch {
+	case config.AuthToken != "":
+		roundTripper = authTokenRoundTripper(roundTripper, config.AuthToken)
+	case config.AuthPassword != "":
+		roundTripper = authPasswordRoundTripper(roundTripper, config.AuthPassword)
+	}
+	for i := len(config.Middleware) - 1; i >= 0; i-- {
+		roundTripper = config.Middleware[i](roundTripper)
+	}
+	return &http.Client{Transport: roundTripper}
+}
+
+// Returns a transport with the settings of http.DefaultTransport
+func newTransport() *http.Transport {
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		return transport.Clone()
+	}
+	return &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		ForceAttemptHTTP2: true,
+	}
+}
+
+var httpClientType = reflect.TypeOf(http.Client{})
+
+// Replaces the HTTP clients reachable from [v], through pointers,
+// interfaces and struct fields, by [httpClient], and returns how many
+// were replaced. Clients pointed to are left unchanged, since they may
+// be shared (e.g. http.DefaultClient), and the pointers to them are
+// replaced instead. [seen] is the set of pointers already walked through.
+func setHTTPClients(v reflect.Value, httpClient *http.Client, seen map[uintptr]struct{}) int {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			return setHTTPClients(v.Elem(), httpClient, seen)
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return 0
+		}
+		if v.Type().Elem() == httpClientType {
+			if !v.CanAddr() {
+				return 0
+			}
+			settable(v).Set(reflect.ValueOf(httpClient))
+			return 1
+		}
+		if _, ok := seen[v.Pointer()]; ok {
+			return 0
+		}
+		seen[v.Pointer()] = struct{}{}
+		return setHTTPClients(v.Elem(), httpClient, seen)
+	case reflect.Struct:
+		if v.Type() == httpClientType {
+			if !v.CanAddr() {
+				return 0
+			}
+			settable(v).Set(reflect.ValueOf(httpClient).Elem())
+			return 1
+		}
+		replaced := 0
+		for i := 0; i < v.NumField(); i++ {
+			replaced += setHTTPClients(v.Field(i), httpClient, seen)
+		}
+		return replaced
+	}
+	return 0
+}
+
+// Returns [v], which must be addressable, as a settable value,
+// even if it's an unexported field
+func settable(v reflect.Value) reflect.Value {
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
+
+// Returns a round tripper that sends requests over HTTPS, trusting [rootCAs]
+func httpsRoundTripper(rootCAs *x509.CertPool) http.RoundTripper {
+	transport := newTransport()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs:    rootCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Scheme == "http" {
+			req = req.Clone(req.Context())
+			req.URL.Scheme = "https"
+		}
+		return transport.RoundTrip(req)
+	})
+}
+
+// Returns a round tripper that adds [token] to requests
+func authTokenRoundTripper(next http.RoundTripper, token string) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+token)
+		return next.RoundTrip(req)
+	})
+}
+
+// Returns a round tripper that adds to requests a token it gets from
+// the auth API with [password]. The token is requested again once rejected.
+func authPasswordRoundTripper(next http.RoundTripper, password string) http.RoundTripper {
+	var (
+		// Held while getting a token. A channel rather than a mutex, so
+		// that requests stop waiting for it once their context is done.
+		lock  = make(chan struct{}, 1)
+		token string
+	)
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		select {
+		case lock <- struct{}{}:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		if token == "" {
+			newToken, err := newAuthToken(next, req, password)
+			if err != nil {
+				<-lock
+				return nil, fmt.Errorf("couldn't get auth token: %w", err)
+			}
+			token = newToken
+		}
+		currentToken := token
+		<-lock
+
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+currentToken)
+		resp, err := next.RoundTrip(req)
+		if err == nil && resp.StatusCode == http.StatusUnauthorized {
+			lock <- struct{}{}
+			if token == currentToken {
+				token = ""
+			}
+			<-lock
+		}
+		return resp, err
+	})
+}
+
+// Requests an auth token for all endpoints from the auth API
+// of the node [req] is sent to, through [roundTripper]
+func newAuthToken(roundTripper http.RoundTripper, req *http.Request, password string) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "auth.newToken",
+		"params": map[string]interface{}{
+			"password":  password,
+			"endpoints": []string{"*"},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	authURL := *req.URL
+	authURL.Path = "/ext/auth"
+	authURL.RawQuery = ""
+	authReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, authURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	authReq.Header.Set("Content-Type", "application/json")
+	resp, err := roundTripper.RoundTrip(authReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var reply struct {
+		Result struct {
+			Token string `json:"token"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return "", fmt.Errorf("couldn't parse auth API reply (%s): %w", resp.Status, err)
+	}
+	if reply.Error != nil {
+		return "", errors.New(reply.Error.Message)
+	}
+	if reply.Result.Token == "" {
+		return "", errors.New("auth API returned no token")
+	}
+	return reply.Result.Token, nil
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIClientWithMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":{"isBootstrapped":%t}}`, r.Header.Get("Authorization") == "Bearer token")
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	assert.NoError(err)
+	port, err := strconv.Atoi(serverURL.Port())
+	assert.NoError(err)
+
+	var numRequests int32
+	counter := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&numRequests, 1)
+			return next.RoundTrip(req)
+		})
+	}
+	auth := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("Authorization", "Bearer token")
+			return next.RoundTrip(req)
+		})
+	}
+	newAPIClientF := NewAPIClientWithMiddleware(NewAPIClient, counter, auth)
+	client := newAPIClientF(serverURL.Hostname(), uint16(port))
+	bootstrapped, err := client.InfoAPI().IsBootstrapped(context.Background(), "X")
+	assert.NoError(err)
+	assert.True(bootstrapped)
+	assert.EqualValues(1, atomic.LoadInt32(&numRequests))
+	// The requests of every API go through the middleware
+	_, _ = client.HealthAPI().Health(context.Background())
+	_, _ = client.PChainAPI().GetHeight(context.Background())
+	_, _ = client.XChainIndexAPI().GetLastAccepted(context.Background())
+	assert.EqualValues(4, atomic.LoadInt32(&numRequests))
+
+	// Other requests, even to the same host, don't go through the
+	// middleware, and the default transport is left unchanged
+	defaultTransport := http.DefaultTransport
+	for _, url := range []string{server.URL, otherServerURL(t, server.Config.Handler)} {
+		resp, err := http.Post(url, "application/json", nil)
+		assert.NoError(err)
+		assert.NoError(resp.Body.Close())
+	}
+	assert.EqualValues(4, atomic.LoadInt32(&numRequests))
+	assert.Equal(defaultTransport, http.DefaultTransport)
+}
+
+// Starts a server with [handler], and returns its URL
+func otherServerURL(t *testing.T, handler http.Handler) string {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestNewAPIClientWithConfig(t *testing.T) {
+	assert := assert.New(t)
+	var numTokens int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/ext/auth" {
+			var req struct {
+				Params struct {
+					Password string `json:"password"`
+				} `json:"params"`
+			}
+			assert.NoError(json.NewDecoder(r.Body).Decode(&req))
+			if req.Params.Password != "password" {
+				fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"incorrect password"}}`)
+				return
+			}
+			atomic.AddInt32(&numTokens, 1)
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{"token":"token"}}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{"isBootstrapped":true}}`)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	assert.NoError(err)
+	port, err := strconv.Atoi(serverURL.Port())
+	assert.NoError(err)
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+
+	// Requests are sent over HTTPS, with a token got with the password
+	client := NewAPIClientWithConfig(NewAPIClient, ClientConfig{
+		TLSRootCAs:   rootCAs,
+		AuthPassword: "password",
+	})(serverURL.Hostname(), uint16(port))
+	for i := 0; i < 2; i++ {
+		bootstrapped, err := client.InfoAPI().IsBootstrapped(context.Background(), "X")
+		assert.NoError(err)
+		assert.True(bootstrapped)
+	}
+	assert.EqualValues(1, atomic.LoadInt32(&numTokens))
+
+	// Wrong password
+	client = NewAPIClientWithConfig(NewAPIClient, ClientConfig{
+		TLSRootCAs:   rootCAs,
+		AuthPassword: "wrong password",
+	})(serverURL.Hostname(), uint16(port))
+	_, err = client.InfoAPI().IsBootstrapped(context.Background(), "X")
+	assert.Error(err)
+
+	// Given token
+	client = NewAPIClientWithConfig(NewAPIClient, ClientConfig{
+		TLSRootCAs: rootCAs,
+		AuthToken:  "token",
+	})(serverURL.Hostname(), uint16(port))
+	bootstrapped, err := client.InfoAPI().IsBootstrapped(context.Background(), "X")
+	assert.NoError(err)
+	assert.True(bootstrapped)
+
+	// The token isn't sent by other clients
+	otherClient := NewAPIClientWithConfig(NewAPIClient, ClientConfig{
+		TLSRootCAs: rootCAs,
+	})(serverURL.Hostname(), uint16(port))
+	_, err = otherClient.InfoAPI().IsBootstrapped(context.Background(), "X")
+	assert.Error(err)
+
+	// The HTTP client of a config sends requests as its API clients do
+	httpClient := NewHTTPClient(ClientConfig{TLSRootCAs: rootCAs, AuthToken: "token"})
+	req, err := http.NewRequest(http.MethodPost, "http://"+serverURL.Host+"/ext/info", nil)
+	assert.NoError(err)
+	resp, err := httpClient.Do(req)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.NoError(resp.Body.Close())
+
+	// The server's cert isn't trusted
+	client = NewAPIClientWithConfig(NewAPIClient, ClientConfig{
+		TLSRootCAs: x509.NewCertPool(),
+		AuthToken:  "token",
+	})(serverURL.Hostname(), uint16(port))
+	_, err = client.InfoAPI().IsBootstrapped(context.Background(), "X")
+	assert.Error(err)
+}
+
+func TestAuthPasswordRoundTripperContext(t *testing.T) {
+	assert := assert.New(t)
+	// The auth API doesn't answer the first request until
+	// unblocked, so that request holds the token lock
+	requested, unblock := make(chan struct{}), make(chan struct{})
+	roundTripper := authPasswordRoundTripper(RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		close(requested)
+		<-unblock
+		return nil, errors.New("unreachable")
+	}), "password")
+	defer close(unblock)
+
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1:9650/ext/info", nil)
+		_, _ = roundTripper.RoundTrip(req)
+	}()
+	<-requested
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://127.0.0.1:9650/ext/info", nil)
+	assert.NoError(err)
+	_, err = roundTripper.RoundTrip(req)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func TestSetHTTPClient(t *testing.T) {
+	assert := assert.New(t)
+	httpClient := &http.Client{}
+
+	// Each avalanchego API client of the pinned version holds an HTTP client
+	client := NewAPIClient("127.0.0.1", 9650)
+	assert.NoError(SetHTTPClient(client, httpClient))
+	assert.NoError(SetHTTPClient(WithEthClient(client, nil), httpClient))
+	assert.NotPanics(func() {
+		NewAPIClientWithHTTPClient(NewAPIClient, httpClient)("127.0.0.1", 9650)
+	})
+
+	// An API client holding none is an error, rather than its
+	// requests silently not going through the HTTP client
+	apiClient := NewAPIClient("127.0.0.1", 9650).(*APIClient)
+	apiClient.info = nil
+	assert.ErrorIs(SetHTTPClient(apiClient, httpClient), ErrNoHTTPClient)
+	assert.ErrorIs(SetHTTPClient(WithEthClient(nil, nil), httpClient), ErrNoHTTPClient)
+	assert.Panics(func() {
+		NewAPIClientWithHTTPClient(func(string, uint16) Client { return apiClient }, httpClient)("127.0.0.1", 9650)
+	})
+}
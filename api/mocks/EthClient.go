@@ -378,6 +378,29 @@ func (_m *EthClient) SubscribeFilterLogs(_a0 context.Context, _a1 interfaces.Fil
 	return r0, r1
 }
 
+// SubscribeNewHead provides a mock function with given fields: _a0, _a1
+func (_m *EthClient) SubscribeNewHead(_a0 context.Context, _a1 chan<- *types.Header) (interfaces.Subscription, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 interfaces.Subscription
+	if rf, ok := ret.Get(0).(func(context.Context, chan<- *types.Header) interfaces.Subscription); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(interfaces.Subscription)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, chan<- *types.Header) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SuggestGasPrice provides a mock function with given fields: _a0
 func (_m *EthClient) SuggestGasPrice(_a0 context.Context) (*big.Int, error) {
 	ret := _m.Called(_a0)
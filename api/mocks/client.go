@@ -206,6 +206,38 @@ func (_m *Client) XChainAPI() avm.Client {
 	return r0
 }
 
+// XChainIndexAPI provides a mock function with given fields:
+func (_m *Client) XChainIndexAPI() indexer.Client {
+	ret := _m.Called()
+
+	var r0 indexer.Client
+	if rf, ok := ret.Get(0).(func() indexer.Client); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(indexer.Client)
+		}
+	}
+
+	return r0
+}
+
+// XChainVertexIndexAPI provides a mock function with given fields:
+func (_m *Client) XChainVertexIndexAPI() indexer.Client {
+	ret := _m.Called()
+
+	var r0 indexer.Client
+	if rf, ok := ret.Get(0).(func() indexer.Client); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(indexer.Client)
+		}
+	}
+
+	return r0
+}
+
 // XChainWalletAPI provides a mock function with given fields:
 func (_m *Client) XChainWalletAPI() avm.WalletClient {
 	ret := _m.Called()
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/api"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/platformvm/status"
+)
+
+// StakerRewards is the reward state of a primary network staker
+type StakerRewards struct {
+	// ID of the tx that added this staker
+	TxID   ids.ID
+	NodeID ids.NodeID
+	// When this staker stops staking, and is rewarded
+	EndTime time.Time
+	// Reward given to this staker if it's rewarded
+	PotentialReward uint64
+}
+
+// ValidatorRewards is the reward state of a primary
+// network validator and of its delegators
+type ValidatorRewards struct {
+	StakerRewards
+	// Fee charged to delegators, in percent
+	DelegationFee float32
+	Delegators    []StakerRewards
+}
+
+// GetPendingRewards returns the reward state of the current
+// primary network validators, as seen by the node behind [client].
+// If [nodeIDs] is non-empty, only those validators are returned.
+func GetPendingRewards(ctx context.Context, client Client, nodeIDs ...ids.NodeID) ([]ValidatorRewards, error) {
+	validators, err := client.PChainAPI().GetCurrentValidators(ctx, constants.PrimaryNetworkID, nodeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get current validators: %w", err)
+	}
+	rewards := make([]ValidatorRewards, len(validators))
+	for i, validator := range validators {
+		rewards[i] = ValidatorRewards{
+			StakerRewards: stakerRewards(validator.ClientStaker, validator.PotentialReward),
+			DelegationFee: validator.DelegationFee,
+			Delegators:    make([]StakerRewards, len(validator.Delegators)),
+		}
+		for j, delegator := range validator.Delegators {
+			rewards[i].Delegators[j] = stakerRewards(delegator.ClientStaker, delegator.PotentialReward)
+		}
+	}
+	return rewards, nil
+}
+
+func stakerRewards(staker platformvm.ClientStaker, potentialReward *uint64) StakerRewards {
+	rewards := StakerRewards{
+		TxID:    staker.TxID,
+		NodeID:  staker.NodeID,
+		EndTime: time.Unix(int64(staker.EndTime), 0),
+	}
+	if potentialReward != nil {
+		rewards.PotentialReward = *potentialReward
+	}
+	return rewards
+}
+
+// GetRewardUTXOs returns the UTXOs created when rewarding
+// the staker added by [txID]. Returns no UTXOs if the staker
+// hasn't been rewarded yet, or didn't earn a reward.
+func GetRewardUTXOs(ctx context.Context, client Client, txID ids.ID) ([]*avax.UTXO, error) {
+	utxosBytes, err := client.PChainAPI().GetRewardUTXOs(ctx, &api.GetTxArgs{TxID: txID})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get reward UTXOs of %s: %w", txID, err)
+	}
+	utxos := make([]*avax.UTXO, len(utxosBytes))
+	for i, utxoBytes := range utxosBytes {
+		utxo := &avax.UTXO{}
+		if _, err := platformvm.Codec.Unmarshal(utxoBytes, utxo); err != nil {
+			return nil, fmt.Errorf("couldn't parse reward UTXO of %s: %w", txID, err)
+		}
+		utxos[i] = utxo
+	}
+	return utxos, nil
+}
+
+// AwaitStakingPeriodEnd blocks until the tx [txID], which adds a
+// staker, is committed, and the staking period of the staker is over
+// and the staker has been removed from the current validator set, i.e.
+// until it has been rewarded or its reward has been denied. A staker
+// that's still pending is waited for. Returns an error if the tx is
+// aborted or dropped. It polls every [pollFreq].
+// Combined with short staking periods (see network.FastStakingConfig)
+// this allows end-of-period scenarios to be exercised in tests.
+func AwaitStakingPeriodEnd(ctx context.Context, client Client, txID ids.ID, pollFreq time.Duration) error {
+	committed := false
+	for {
+		ended, err := stakingPeriodEnded(ctx, client, txID, &committed)
+		if err != nil || ended {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("staking period of %s didn't end: %w", txID, ctx.Err())
+		case <-time.After(pollFreq):
+		}
+	}
+}
+
+// Returns whether the staking period of the staker added by [txID] is
+// over. [committed] is whether the tx is known to be committed, and is
+// set once it's seen committed.
+func stakingPeriodEnded(ctx context.Context, client Client, txID ids.ID, committed *bool) (bool, error) {
+	if !*committed {
+		txStatus, err := client.PChainAPI().GetTxStatus(ctx, txID, true)
+		if err != nil {
+			return false, fmt.Errorf("couldn't get status of %s: %w", txID, err)
+		}
+		switch txStatus.Status {
+		case status.Committed:
+			*committed = true
+		case status.Aborted, status.Dropped:
+			if txStatus.Reason != "" {
+				return false, fmt.Errorf("tx %s was %s: %s", txID, txStatus.Status, txStatus.Reason)
+			}
+			return false, fmt.Errorf("tx %s was %s", txID, txStatus.Status)
+		default:
+			return false, nil
+		}
+	}
+	// A staker is pending, then current, then removed, so it's looked
+	// for among the pending stakers first, so as not to miss it if it
+	// starts meanwhile
+	pending, err := isPendingStaker(ctx, client, txID)
+	if err != nil || pending {
+		return false, err
+	}
+	rewards, err := GetPendingRewards(ctx, client)
+	if err != nil {
+		return false, err
+	}
+	return !hasStaker(rewards, txID), nil
+}
+
+// Returns whether the staker added by [txID] is a pending primary
+// network validator or delegator
+func isPendingStaker(ctx context.Context, client Client, txID ids.ID) (bool, error) {
+	validators, delegators, err := client.PChainAPI().GetPendingValidators(ctx, constants.PrimaryNetworkID, nil)
+	if err != nil {
+		return false, fmt.Errorf("couldn't get pending validators: %w", err)
+	}
+	for _, staker := range append(validators, delegators...) {
+		// Pending stakers are returned as decoded JSON objects
+		fields, ok := staker.(map[string]interface{})
+		if ok && fields["txID"] == txID.String() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func hasStaker(rewards []ValidatorRewards, txID ids.ID) bool {
+	for _, validator := range rewards {
+		if validator.TxID == txID {
+			return true
+		}
+		for _, delegator := range validator.Delegators {
+			if delegator.TxID == txID {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/rpc"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/platformvm/status"
+	"github.com/stretchr/testify/assert"
+)
+
+// A P-Chain client on which the tx [txID] is processing for a poll, then
+// has status [txStatus]. The staker it adds is then pending for
+// [pendingPolls] polls, then current for [currentPolls] polls.
+type stakerTestPClient struct {
+	platformvm.Client
+	txID         ids.ID
+	txStatus     status.Status
+	pendingPolls int
+	currentPolls int
+
+	lock         sync.Mutex
+	statusCalls  int
+	pendingCalls int
+	currentCalls int
+}
+
+func (c *stakerTestPClient) GetTxStatus(_ context.Context, txID ids.ID, _ bool, _ ...rpc.Option) (*platformvm.GetTxStatusResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.statusCalls++
+	if txID != c.txID {
+		return &platformvm.GetTxStatusResponse{Status: status.Unknown}, nil
+	}
+	if c.statusCalls == 1 {
+		return &platformvm.GetTxStatusResponse{Status: status.Processing}, nil
+	}
+	return &platformvm.GetTxStatusResponse{Status: c.txStatus}, nil
+}
+
+func (c *stakerTestPClient) GetPendingValidators(context.Context, ids.ID, []ids.NodeID, ...rpc.Option) ([]interface{}, []interface{}, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.pendingCalls++
+	if c.pendingCalls > c.pendingPolls {
+		return nil, nil, nil
+	}
+	return []interface{}{map[string]interface{}{"txID": c.txID.String()}}, nil, nil
+}
+
+func (c *stakerTestPClient) GetCurrentValidators(context.Context, ids.ID, []ids.NodeID, ...rpc.Option) ([]platformvm.ClientPrimaryValidator, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.currentCalls++
+	if c.currentCalls > c.currentPolls {
+		return nil, nil
+	}
+	return []platformvm.ClientPrimaryValidator{{ClientStaker: platformvm.ClientStaker{TxID: c.txID}}}, nil
+}
+
+type stakerTestClient struct {
+	Client
+	pClient *stakerTestPClient
+}
+
+func (c *stakerTestClient) PChainAPI() platformvm.Client {
+	return c.pClient
+}
+
+func TestAwaitStakingPeriodEnd(t *testing.T) {
+	assert := assert.New(t)
+	pClient := &stakerTestPClient{
+		txID:         ids.GenerateTestID(),
+		txStatus:     status.Committed,
+		pendingPolls: 2,
+		currentPolls: 2,
+	}
+	client := &stakerTestClient{pClient: pClient}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	assert.NoError(AwaitStakingPeriodEnd(ctx, client, pClient.txID, time.Millisecond))
+	// The staker was waited for while its tx was processing, while it
+	// was pending and while it was current
+	assert.Equal(2, pClient.statusCalls)
+	assert.Equal(pClient.pendingPolls+pClient.currentPolls+1, pClient.pendingCalls)
+	assert.Equal(pClient.currentPolls+1, pClient.currentCalls)
+
+	// The staking period of a staker whose tx isn't committed never ends
+	for _, txStatus := range []status.Status{status.Aborted, status.Dropped} {
+		pClient := &stakerTestPClient{txID: ids.GenerateTestID(), txStatus: txStatus}
+		err := AwaitStakingPeriodEnd(ctx, &stakerTestClient{pClient: pClient}, pClient.txID, time.Millisecond)
+		assert.Error(err)
+		assert.Contains(err.Error(), txStatus.String())
+	}
+
+	// A staker still pending when [ctx] is done
+	pClient = &stakerTestPClient{txID: ids.GenerateTestID(), txStatus: status.Committed, pendingPolls: 1_000_000}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(AwaitStakingPeriodEnd(ctx, &stakerTestClient{pClient: pClient}, pClient.txID, time.Millisecond), context.DeadlineExceeded)
+}
@@ -0,0 +1,293 @@
+// Package cloud provisions the hosts of a devnet with Terraform, and runs
+// a network on them through the remote backend (see package remote):
+//
+//	nw, err := cloud.NewNetwork(ctx, log, networkConfig, cloud.Config{
+//		TerraformDir: "./devnet",
+//		Vars:         map[string]string{"regions": `["us-east-1","eu-west-1"]`},
+//		SSH:          sshConfig,
+//	}, "", "")
+//	defer nw.Stop(ctx) // destroys the hosts
+//
+// Any Terraform module, for any cloud, can provision the hosts, as long
+// as it has an output named [HostsOutput] listing them. Its elements are
+// objects with the field "address", the address of a host's SSH server,
+// and optionally "ip", the IP the host's nodes use (e.g. its private IP),
+// as in:
+//
+//	output "hosts" {
+//	  value = [for i in aws_instance.node : { address = i.public_ip }]
+//	}
+//
+// avalanchego needn't be installed on the hosts, since the remote backend
+// uploads the nodes' binary to them.
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/remote"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// Name of the Terraform output listing the provisioned hosts
+	HostsOutput = "hosts"
+	// Max time for the SSH servers of the provisioned hosts
+	// to be reachable, if not given in the config
+	DefaultSSHReadyTimeout = 5 * time.Minute
+	// Max time for Terraform to destroy the hosts when the network
+	// is stopped or fails to start, if not given in the config
+	DefaultDestroyTimeout = 30 * time.Minute
+
+	defaultTerraformPath = "terraform"
+	sshReadyPollFreq     = 2 * time.Second
+)
+
+// Config of a devnet
+type Config struct {
+	// Dir of the Terraform module provisioning the hosts, where its
+	// state is kept. Must not be empty.
+	TerraformDir string
+	// Variables of the Terraform module, as given to -var
+	Vars map[string]string
+	// Path of the terraform binary. Defaults to terraform, from the PATH.
+	TerraformPath string
+	// Where the output of Terraform is written. Defaults to io.Discard.
+	TerraformOutput io.Writer
+	// SSH client config the hosts are connected to with.
+	// Must not be nil.
+	SSH *ssh.ClientConfig
+	// Dir the files of the nodes are uploaded under on the hosts.
+	// Defaults to remote.DefaultDir.
+	HostDir string
+	// Max time for the SSH servers of the provisioned hosts to be
+	// reachable. Defaults to DefaultSSHReadyTimeout.
+	SSHReadyTimeout time.Duration
+	// Max time for Terraform to destroy the hosts when the network is
+	// stopped or fails to start. Destroying isn't bounded by the context
+	// of Stop, so that the hosts aren't leaked if it's cancelled.
+	// Defaults to DefaultDestroyTimeout.
+	DestroyTimeout time.Duration
+	// If true, the hosts are kept when the network is stopped, rather
+	// than destroyed, e.g. to inspect the nodes' logs
+	KeepOnStop bool
+}
+
+func (c *Config) validate() error {
+	switch {
+	case c.TerraformDir == "":
+		return errors.New("no Terraform dir given")
+	case c.SSH == nil:
+		return errors.New("no SSH client config given")
+	}
+	return nil
+}
+
+// A host of the hosts output of the Terraform module
+type hostOutput struct {
+	Address string `json:"address"`
+	IP      string `json:"ip"`
+}
+
+// Devnet is a set of hosts provisioned by Terraform
+type Devnet struct {
+	log    logging.Logger
+	config Config
+	// Hosts provisioned, in the order of the hosts output
+	Hosts []remote.HostConfig
+}
+
+// Provision applies the Terraform module of [config], and waits until
+// the SSH servers of the hosts it provisioned are reachable. If that
+// fails, the hosts provisioned, if any, are destroyed.
+func Provision(ctx context.Context, log logging.Logger, config Config) (*Devnet, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if config.TerraformPath == "" {
+		config.TerraformPath = defaultTerraformPath
+	}
+	if config.TerraformOutput == nil {
+		config.TerraformOutput = io.Discard
+	}
+	if config.SSHReadyTimeout == 0 {
+		config.SSHReadyTimeout = DefaultSSHReadyTimeout
+	}
+	if config.DestroyTimeout == 0 {
+		config.DestroyTimeout = DefaultDestroyTimeout
+	}
+	d := &Devnet{log: log, config: config}
+	if err := d.provision(ctx); err != nil {
+		if destroyErr := d.destroy(); destroyErr != nil {
+			log.Warn("couldn't destroy hosts after failed provisioning: %s", destroyErr)
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Devnet) provision(ctx context.Context) error {
+	log := d.log
+	log.Info("provisioning hosts with Terraform module %s", d.config.TerraformDir)
+	if _, err := d.terraform(ctx, "init", "-input=false"); err != nil {
+		return err
+	}
+	if _, err := d.terraform(ctx, append([]string{"apply", "-input=false", "-auto-approve"}, d.varArgs()...)...); err != nil {
+		return err
+	}
+	out, err := d.terraform(ctx, "output", "-json", HostsOutput)
+	if err != nil {
+		return err
+	}
+	var hosts []hostOutput
+	if err := json.Unmarshal(out, &hosts); err != nil {
+		return fmt.Errorf("couldn't parse Terraform output %q: %w", HostsOutput, err)
+	}
+	if len(hosts) == 0 {
+		return fmt.Errorf("terraform output %q has no hosts", HostsOutput)
+	}
+	for i, h := range hosts {
+		if h.Address == "" {
+			return fmt.Errorf("terraform output %q: host %d has no address", HostsOutput, i)
+		}
+		d.Hosts = append(d.Hosts, remote.HostConfig{
+			Address: h.Address,
+			IP:      h.IP,
+			Dir:     d.config.HostDir,
+			SSH:     d.config.SSH,
+		})
+	}
+	log.Info("provisioned %d hosts, waiting for their SSH servers", len(d.Hosts))
+	return d.awaitSSH(ctx)
+}
+
+// Returns the -var flags of the module's variables, sorted
+func (d *Devnet) varArgs() []string {
+	names := make([]string, 0, len(d.config.Vars))
+	for name := range d.config.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		args = append(args, fmt.Sprintf("-var=%s=%s", name, d.config.Vars[name]))
+	}
+	return args
+}
+
+// Runs terraform with [args] in the module's dir, and returns its stdout
+func (d *Devnet) terraform(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, d.config.TerraformPath, append([]string{"-chdir=" + d.config.TerraformDir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = io.MultiWriter(&stdout, d.config.TerraformOutput)
+	cmd.Stderr = io.MultiWriter(&stderr, d.config.TerraformOutput)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("terraform %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// Waits until the SSH servers of the hosts accept connections,
+// since hosts may not be booted yet when Terraform returns
+func (d *Devnet) awaitSSH(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, d.config.SSHReadyTimeout)
+	defer cancel()
+	for _, h := range d.Hosts {
+		addr := h.Address
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "22")
+		}
+		for {
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err == nil {
+				_ = conn.Close()
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("SSH server of host %s isn't reachable: %w", addr, err)
+			case <-time.After(sshReadyPollFreq):
+			}
+		}
+	}
+	return nil
+}
+
+// Destroy destroys the hosts provisioned by the Terraform module
+func (d *Devnet) Destroy(ctx context.Context) error {
+	d.log.Info("destroying hosts of Terraform module %s", d.config.TerraformDir)
+	_, err := d.terraform(ctx, append([]string{"destroy", "-input=false", "-auto-approve"}, d.varArgs()...)...)
+	return err
+}
+
+// Destroys the hosts within [d.config.DestroyTimeout],
+// regardless of the context the caller was given
+func (d *Devnet) destroy() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.DestroyTimeout)
+	defer cancel()
+	if err := d.Destroy(ctx); err != nil {
+		return fmt.Errorf("couldn't destroy hosts of Terraform module %s, which may still be running: %w", d.config.TerraformDir, err)
+	}
+	return nil
+}
+
+// NewNetwork provisions the hosts of [config] (see Provision), and returns
+// a network whose nodes run on them (see remote.NewNetwork). Stopping the
+// network destroys the hosts, unless [config.KeepOnStop] is set.
+// The hosts are also destroyed if the network can't be started.
+func NewNetwork(
+	ctx context.Context,
+	log logging.Logger,
+	networkConfig network.Config,
+	config Config,
+	rootDir string,
+	snapshotsDir string,
+) (network.Network, error) {
+	d, err := Provision(ctx, log, config)
+	if err != nil {
+		return nil, err
+	}
+	nw, err := remote.NewNetwork(log, networkConfig, remote.Config{Hosts: d.Hosts}, rootDir, snapshotsDir)
+	if err != nil {
+		if destroyErr := d.destroy(); destroyErr != nil {
+			log.Warn("%s", destroyErr)
+		}
+		return nil, err
+	}
+	return &cloudNetwork{Network: nw, devnet: d}, nil
+}
+
+// A network whose hosts are destroyed once it's stopped
+type cloudNetwork struct {
+	network.Network
+	devnet *Devnet
+}
+
+// Stops the network within [ctx], and then destroys the hosts within
+// their own timeout, so that a short or cancelled [ctx] doesn't leak them
+func (nw *cloudNetwork) Stop(ctx context.Context) error {
+	err := nw.Network.Stop(ctx)
+	if errors.Is(err, network.ErrStopped) || nw.devnet.config.KeepOnStop {
+		return err
+	}
+	if destroyErr := nw.devnet.destroy(); destroyErr != nil {
+		if err == nil {
+			return destroyErr
+		}
+		return fmt.Errorf("%w; %s", err, destroyErr)
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+// Writes a terraform binary that logs its args to [logPath], and
+// prints [hostsOutput] when asked for the hosts output
+func writeFakeTerraform(t *testing.T, logPath string, hostsOutput string) string {
+	path := filepath.Join(t.TempDir(), "terraform")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %s
+if [ "$2" = "output" ]; then
+	echo '%s'
+fi
+`, logPath, hostsOutput)
+	assert.NoError(t, os.WriteFile(path, []byte(script), 0o700))
+	return path
+}
+
+// Returns the invocations logged by a fake terraform
+func readInvocations(t *testing.T, logPath string) []string {
+	log, err := os.ReadFile(logPath)
+	assert.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(log)), "\n")
+}
+
+func TestProvision(t *testing.T) {
+	assert := assert.New(t)
+	// Stands in for the SSH server of the host
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+	defer listener.Close()
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "log")
+	config := Config{
+		TerraformDir:  dir,
+		TerraformPath: writeFakeTerraform(t, logPath, fmt.Sprintf(`[{"address": "%s", "ip": "10.0.0.1"}]`, listener.Addr())),
+		Vars:          map[string]string{"region": "us-east-1", "count": "1"},
+		SSH:           &ssh.ClientConfig{},
+		HostDir:       "/opt/runner",
+	}
+	d, err := Provision(context.Background(), logging.NoLog{}, config)
+	assert.NoError(err)
+	assert.Len(d.Hosts, 1)
+	assert.Equal(listener.Addr().String(), d.Hosts[0].Address)
+	assert.Equal("10.0.0.1", d.Hosts[0].IP)
+	assert.Equal("/opt/runner", d.Hosts[0].Dir)
+
+	assert.NoError(d.Destroy(context.Background()))
+	assert.Equal([]string{
+		"-chdir=" + dir + " init -input=false",
+		"-chdir=" + dir + " apply -input=false -auto-approve -var=count=1 -var=region=us-east-1",
+		"-chdir=" + dir + " output -json hosts",
+		"-chdir=" + dir + " destroy -input=false -auto-approve -var=count=1 -var=region=us-east-1",
+	}, readInvocations(t, logPath))
+}
+
+func TestProvisionFailure(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "log")
+	config := Config{
+		TerraformDir:  dir,
+		TerraformPath: writeFakeTerraform(t, logPath, "[]"),
+		SSH:           &ssh.ClientConfig{},
+	}
+	_, err := Provision(context.Background(), logging.NoLog{}, config)
+	assert.Error(err)
+	assert.Contains(err.Error(), "no hosts")
+	// What was provisioned is destroyed
+	invocations := readInvocations(t, logPath)
+	assert.Contains(invocations[len(invocations)-1], "destroy")
+
+	_, err = Provision(context.Background(), logging.NoLog{}, Config{SSH: &ssh.ClientConfig{}})
+	assert.Error(err)
+	_, err = Provision(context.Background(), logging.NoLog{}, Config{TerraformDir: dir})
+	assert.Error(err)
+}
+
+// A network whose Stop fails if its context is done
+type stopTestNetwork struct {
+	network.Network
+}
+
+func (*stopTestNetwork) Stop(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func TestStopDestroysWithCancelledContext(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "log")
+	d := &Devnet{
+		log: logging.NoLog{},
+		config: Config{
+			TerraformDir:    dir,
+			TerraformPath:   writeFakeTerraform(t, logPath, "[]"),
+			TerraformOutput: io.Discard,
+			DestroyTimeout:  DefaultDestroyTimeout,
+		},
+	}
+	nw := &cloudNetwork{Network: &stopTestNetwork{}, devnet: d}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := nw.Stop(ctx)
+	assert.ErrorIs(err, context.Canceled)
+	// The hosts are destroyed anyway
+	assert.Equal([]string{"-chdir=" + dir + " destroy -input=false -auto-approve"}, readInvocations(t, logPath))
+
+	// Failing to destroy them is reported with the module's dir
+	d.config.TerraformPath = filepath.Join(dir, "missing")
+	err = nw.Stop(context.Background())
+	assert.Error(err)
+	assert.Contains(err.Error(), "Terraform module "+dir)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/ava-labs/avalanche-network-runner/cmd/avalanche-network-runner/control"
 	"github.com/ava-labs/avalanche-network-runner/cmd/avalanche-network-runner/ping"
+	"github.com/ava-labs/avalanche-network-runner/cmd/avalanche-network-runner/scenario"
 	"github.com/ava-labs/avalanche-network-runner/cmd/avalanche-network-runner/server"
 	"github.com/spf13/cobra"
 )
@@ -28,6 +29,7 @@ func init() {
 		server.NewCommand(),
 		ping.NewCommand(),
 		control.NewCommand(),
+		scenario.NewCommand(),
 	)
 }
 
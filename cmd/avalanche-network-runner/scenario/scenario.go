@@ -0,0 +1,84 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/ava-labs/avalanche-network-runner/pkg/logutil"
+	"github.com/ava-labs/avalanche-network-runner/scenario"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logLevel   string
+	logDir     string
+	reportFile string
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scenario [options]",
+		Short: "Run declarative scenarios against a local network.",
+	}
+	cmd.AddCommand(newRunCommand())
+	return cmd
+}
+
+func newRunCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run [scenario file]",
+		Short: "Run the steps of a JSON or YAML scenario file, and report their outcome.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runFunc,
+	}
+	cmd.PersistentFlags().StringVar(&logLevel, "log-level", logutil.DefaultLogLevel, "log level")
+	cmd.PersistentFlags().StringVar(&logDir, "log-dir", filepath.Join(os.TempDir(), "avalanche-network-runner-scenario"), "log directory")
+	cmd.PersistentFlags().StringVar(&reportFile, "report-file", "", "path of a JSON file the report is written to")
+	return cmd
+}
+
+func runFunc(cmd *cobra.Command, args []string) error {
+	sc, err := scenario.LoadFile(args[0])
+	if err != nil {
+		return err
+	}
+	level, err := logging.ToLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	logConfig := logging.Config{
+		DisplayLevel: level,
+		LogLevel:     level,
+	}
+	logConfig.Directory = logDir
+	logFactory := logging.NewFactory(logConfig)
+	log, err := logFactory.Make("scenario")
+	if err != nil {
+		return err
+	}
+
+	// Interrupting stops the scenario, and its network
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	report, runErr := scenario.Run(ctx, sc, scenario.Config{Log: log})
+	fmt.Print(report)
+	if reportFile != "" {
+		reportBytes, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(reportFile, reportBytes, 0o644); err != nil {
+			return fmt.Errorf("couldn't write report: %w", err)
+		}
+	}
+	return runErr
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/ava-labs/avalanche-network-runner/pkg/logutil"
+	"github.com/ava-labs/avalanche-network-runner/pkg/observability"
 	"github.com/ava-labs/avalanche-network-runner/server"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -28,6 +29,10 @@ var (
 	gwDisabled         bool
 	dialTimeout        time.Duration
 	disableNodesOutput bool
+	idleSuspendTimeout time.Duration
+	resumeTimeout      time.Duration
+	observable         bool
+	grafanaHomePath    string
 )
 
 func NewCommand() *cobra.Command {
@@ -44,6 +49,11 @@ func NewCommand() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(&gwDisabled, "disable-grpc-gateway", false, "true to disable grpc-gateway server (overrides --grpc-gateway-port)")
 	cmd.PersistentFlags().DurationVar(&dialTimeout, "dial-timeout", 10*time.Second, "server dial timeout")
 	cmd.PersistentFlags().BoolVar(&disableNodesOutput, "disable-nodes-output", false, "true to disable nodes stdout/stderr")
+	cmd.PersistentFlags().DurationVar(&idleSuspendTimeout, "idle-suspend-timeout", 0, "pause the network's nodes after this long without requests to the server, not counting requests to the nodes' APIs (0 to disable)")
+	cmd.PersistentFlags().DurationVar(&resumeTimeout, "resume-timeout", server.DefaultResumeTimeout, "max time for paused nodes to become healthy again on the next request")
+
+	cmd.PersistentFlags().BoolVar(&observable, "observability", false, "true to start prometheus with the network, to scrape its nodes")
+	cmd.PersistentFlags().StringVar(&grafanaHomePath, "grafana-home-path", "", "grafana home path (e.g. /usr/share/grafana), to also start grafana with avalanchego dashboards (requires --observability)")
 
 	return cmd
 }
@@ -57,12 +67,23 @@ func serverFunc(cmd *cobra.Command, args []string) (err error) {
 	}
 	_ = zap.ReplaceGlobals(logger)
 
+	var observabilityConfig *observability.Config
+	if observable {
+		observabilityConfig = &observability.Config{
+			Grafana:         grafanaHomePath != "",
+			GrafanaHomePath: grafanaHomePath,
+		}
+	}
+
 	s, err := server.New(server.Config{
 		Port:                port,
 		GwPort:              gwPort,
 		GwDisabled:          gwDisabled,
 		DialTimeout:         dialTimeout,
 		RedirectNodesOutput: !disableNodesOutput,
+		IdleSuspendTimeout:  idleSuspendTimeout,
+		ResumeTimeout:       resumeTimeout,
+		Observability:       observabilityConfig,
 	})
 	if err != nil {
 		return err
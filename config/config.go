@@ -0,0 +1,252 @@
+// Package config loads network configs from JSON or YAML files.
+//
+// On top of the fields of network.Config, a config file may:
+//   - reference environment variables in string values, as $VAR or ${VAR}
+//   - give durations as strings, e.g. "1m30s", rather than nanoseconds
+//   - give file paths instead of inline contents, through the fields
+//     listed in [networkFileRefs] and [nodeFileRefs]. Relative paths
+//     are relative to the config file's directory.
+//
+// Config files are validated against a JSON schema generated from
+// network.Config (see Schema), so that unknown fields and fields of
+// the wrong type are reported with their path in the config
+// (e.g. "nodeConfigs[1].isBeacon").
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"gopkg.in/yaml.v3"
+)
+
+// Format of a config file
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+const nodeConfigsKey = "nodeConfigs"
+
+var (
+	// Network config field giving a file path --> field set to the file's contents
+	networkFileRefs = map[string]string{
+		"genesisFile": "genesis",
+	}
+	// Node config field giving a file path --> field set to the file's contents
+	nodeFileRefs = map[string]string{
+		"stakingKeyFile":       "stakingKey",
+		"stakingCertFile":      "stakingCert",
+		"configFilePath":       "configFile",
+		"cChainConfigFilePath": "cChainConfigFile",
+	}
+
+	errUnknownFormat = errors.New("unknown config format")
+)
+
+// FormatFromPath returns the format of the config file at [path],
+// based on its extension
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("%w: %q", errUnknownFormat, path)
+	}
+}
+
+// LoadFile loads and validates the network config at [path]
+func LoadFile(path string) (network.Config, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return network.Config{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return network.Config{}, fmt.Errorf("couldn't read config file: %w", err)
+	}
+	return Load(data, format, filepath.Dir(path))
+}
+
+// Load loads and validates the network config in [data].
+// Relative file references are resolved against [baseDir].
+func Load(data []byte, format Format, baseDir string) (network.Config, error) {
+	var raw interface{}
+	switch format {
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			return network.Config{}, fmt.Errorf("couldn't parse JSON config: %w", err)
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return network.Config{}, fmt.Errorf("couldn't parse YAML config: %w", err)
+		}
+	default:
+		return network.Config{}, fmt.Errorf("%w: %q", errUnknownFormat, format)
+	}
+
+	raw, err := expandEnv(raw, "")
+	if err != nil {
+		return network.Config{}, err
+	}
+	rawConfig, ok := raw.(map[string]interface{})
+	if !ok {
+		return network.Config{}, fmt.Errorf("expected config to be an object but got %T", raw)
+	}
+	if err := validate(rawConfig, Schema(), ""); err != nil {
+		return network.Config{}, err
+	}
+	if err := inlineFiles(rawConfig, networkFileRefs, baseDir, ""); err != nil {
+		return network.Config{}, err
+	}
+	if nodeConfigs, ok := rawConfig[nodeConfigsKey].([]interface{}); ok {
+		for i, nodeConfigIntf := range nodeConfigs {
+			path := fmt.Sprintf("%s[%d]", nodeConfigsKey, i)
+			nodeConfig, ok := nodeConfigIntf.(map[string]interface{})
+			if !ok {
+				return network.Config{}, fmt.Errorf("%s: expected object but got %T", path, nodeConfigIntf)
+			}
+			if err := inlineFiles(nodeConfig, nodeFileRefs, baseDir, path+"."); err != nil {
+				return network.Config{}, err
+			}
+		}
+	}
+
+	config, err := decode(rawConfig)
+	if err != nil {
+		return network.Config{}, err
+	}
+	if err := network.NewValidationError(config.Validate()); err != nil {
+		return network.Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	return config, nil
+}
+
+// Returns [raw] with environment variables in strings expanded.
+// [path] is the path of [raw] in the config, for error messages.
+func expandEnv(raw interface{}, path string) (interface{}, error) {
+	switch v := raw.(type) {
+	case string:
+		var missing []string
+		expanded := os.Expand(v, func(name string) string {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				missing = append(missing, name)
+			}
+			return value
+		})
+		if len(missing) != 0 {
+			return nil, fmt.Errorf("%s: undefined environment variables %v", displayPath(path), missing)
+		}
+		return expanded, nil
+	case map[string]interface{}:
+		for key, value := range v {
+			expanded, err := expandEnv(value, joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+		return v, nil
+	case []interface{}:
+		for i, value := range v {
+			expanded, err := expandEnv(value, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+		return v, nil
+	default:
+		return raw, nil
+	}
+}
+
+// Replaces the file references in [rawConfig], as given by [refs],
+// with the contents of the referenced files.
+// [prefix] is the path of [rawConfig] in the config, for error messages.
+func inlineFiles(rawConfig map[string]interface{}, refs map[string]string, baseDir string, prefix string) error {
+	// Iterate in a deterministic order so errors are reproducible
+	refKeys := make([]string, 0, len(refs))
+	for refKey := range refs {
+		refKeys = append(refKeys, refKey)
+	}
+	sort.Strings(refKeys)
+	for _, refKey := range refKeys {
+		pathIntf, ok := rawConfig[refKey]
+		if !ok {
+			continue
+		}
+		key := refs[refKey]
+		if _, ok := rawConfig[key]; ok {
+			return fmt.Errorf("%s%s and %s%s are mutually exclusive", prefix, refKey, prefix, key)
+		}
+		path, ok := pathIntf.(string)
+		if !ok {
+			return fmt.Errorf("%s%s: expected string but got %T", prefix, refKey, pathIntf)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s%s: %w", prefix, refKey, err)
+		}
+		delete(rawConfig, refKey)
+		rawConfig[key] = string(contents)
+	}
+	return nil
+}
+
+// Decodes [rawConfig] into a network config, rejecting unknown
+// fields and reporting type errors with the offending field.
+// Durations may be given as strings, e.g. "1m30s".
+func decode(rawConfig map[string]interface{}) (network.Config, error) {
+	converted, err := convertDurations(rawConfig, reflect.TypeOf(network.Config{}), "")
+	if err != nil {
+		return network.Config{}, err
+	}
+	configBytes, err := json.Marshal(converted)
+	if err != nil {
+		return network.Config{}, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(configBytes))
+	decoder.DisallowUnknownFields()
+	var config network.Config
+	if err := decoder.Decode(&config); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return network.Config{}, fmt.Errorf("%s: expected %s but got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return network.Config{}, fmt.Errorf("couldn't decode config: %w", err)
+	}
+	return config, nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "config"
+	}
+	return path
+}
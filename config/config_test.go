@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGenesis = `{"networkID": 1337}`
+
+// Writes the staking key/cert of a default node and a genesis to [dir]
+func writeTestFiles(t *testing.T, dir string) {
+	for _, name := range []string{"staking.key", "staking.crt"} {
+		contents, err := os.ReadFile(filepath.Join("..", "local", "default", "node0", name))
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), contents, 0o600))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "genesis.json"), []byte(testGenesis), 0o600))
+}
+
+func TestLoadFile(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir)
+	t.Setenv("TEST_BINARY_PATH", "/tmp/avalanchego")
+
+	yamlConfig := `
+genesisFile: genesis.json
+flags:
+  log-level: debug
+nodeConfigs:
+  - name: node1
+    isBeacon: true
+    binaryPath: ${TEST_BINARY_PATH}
+    stakingKeyFile: staking.key
+    stakingCertFile: staking.crt
+`
+	yamlPath := filepath.Join(dir, "network.yaml")
+	assert.NoError(os.WriteFile(yamlPath, []byte(yamlConfig), 0o600))
+	config, err := LoadFile(yamlPath)
+	assert.NoError(err)
+	assert.Equal(testGenesis, config.Genesis)
+	assert.Equal("debug", config.Flags["log-level"])
+	assert.Len(config.NodeConfigs, 1)
+	assert.Equal("node1", config.NodeConfigs[0].Name)
+	assert.True(config.NodeConfigs[0].IsBeacon)
+	assert.Equal("/tmp/avalanchego", config.NodeConfigs[0].BinaryPath)
+	stakingKey, err := os.ReadFile(filepath.Join(dir, "staking.key"))
+	assert.NoError(err)
+	assert.Equal(string(stakingKey), config.NodeConfigs[0].StakingKey)
+
+	// The same config as JSON
+	jsonConfig := `{
+		"genesisFile": "genesis.json",
+		"flags": {"log-level": "debug"},
+		"nodeConfigs": [{
+			"name": "node1",
+			"isBeacon": true,
+			"binaryPath": "$TEST_BINARY_PATH",
+			"stakingKeyFile": "staking.key",
+			"stakingCertFile": "staking.crt"
+		}]
+	}`
+	jsonPath := filepath.Join(dir, "network.json")
+	assert.NoError(os.WriteFile(jsonPath, []byte(jsonConfig), 0o600))
+	jsonLoadedConfig, err := LoadFile(jsonPath)
+	assert.NoError(err)
+	assert.Equal(config, jsonLoadedConfig)
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir)
+	tests := map[string]struct {
+		config      string
+		errContains string
+	}{
+		"unknown field": {
+			config:      `{"genesisFile": "genesis.json", "nodeConfig": []}`,
+			errContains: "nodeConfig",
+		},
+		"wrong type": {
+			config:      `{"genesisFile": "genesis.json", "nodeConfigs": [{"isBeacon": "yes"}]}`,
+			errContains: "isBeacon",
+		},
+		"wrong nested type": {
+			config:      `{"genesisFile": "genesis.json", "faucet": {"amount": "1000"}}`,
+			errContains: "faucet.amount: expected integer but got string",
+		},
+		"unknown nested field": {
+			config:      `{"genesisFile": "genesis.json", "nodeConfigs": [{"stakingKeyFiles": "staking.key"}]}`,
+			errContains: "nodeConfigs[0].stakingKeyFiles: unknown field",
+		},
+		"missing file": {
+			config:      `{"genesisFile": "missing.json"}`,
+			errContains: "genesisFile",
+		},
+		"inline and file": {
+			config:      `{"genesisFile": "genesis.json", "genesis": "{}"}`,
+			errContains: "mutually exclusive",
+		},
+		"undefined env var": {
+			config:      `{"genesisFile": "genesis.json", "nodeConfigs": [{"binaryPath": "${TEST_UNDEFINED_VAR}"}]}`,
+			errContains: "nodeConfigs[0].binaryPath",
+		},
+		"invalid config": {
+			config:      `{"genesisFile": "genesis.json", "nodeConfigs": [{"name": "node1"}]}`,
+			errContains: "invalid config",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load([]byte(tt.config), FormatJSON, dir)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	assert := assert.New(t)
+	schema := Schema()
+	assert.Equal(schemaDialect, schema["$schema"])
+	properties := schema["properties"].(map[string]interface{})
+	assert.Equal(map[string]interface{}{"type": "string"}, properties["genesisFile"])
+	assert.Equal(map[string]interface{}{"type": "string"}, properties["genesis"])
+	nodeProperties := properties[nodeConfigsKey].(map[string]interface{})["items"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(map[string]interface{}{"type": "string"}, nodeProperties["stakingKeyFile"])
+	assert.Equal(map[string]interface{}{"type": "boolean"}, nodeProperties["isBeacon"])
+	_, ok := properties["hooks"]
+	assert.False(ok)
+}
+
+func TestLoadDurations(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	writeTestFiles(t, dir)
+
+	yamlConfig := `
+genesisFile: genesis.json
+nodeStartDelay: 5s
+resourceUsageInterval: 1000000000
+faucet:
+  addrInterval: 1m30s
+webhooks:
+  urls: [http://127.0.0.1:8080]
+  timeout: 10s
+`
+	config, err := Load([]byte(yamlConfig), FormatYAML, dir)
+	assert.NoError(err)
+	assert.Equal(5*time.Second, config.NodeStartDelay)
+	assert.Equal(time.Second, config.ResourceUsageInterval)
+	assert.Equal(90*time.Second, config.Faucet.AddrInterval)
+	assert.Equal(10*time.Second, config.Webhooks.Timeout)
+
+	_, err = Load([]byte(`{"genesisFile": "genesis.json", "nodeStartDelay": "5 seconds"}`), FormatJSON, dir)
+	assert.Error(err)
+	assert.Contains(err.Error(), "nodeStartDelay")
+	_, err = Load([]byte(`{"genesisFile": "genesis.json", "nodeStartDelay": true}`), FormatJSON, dir)
+	assert.Error(err)
+	assert.Contains(err.Error(), "nodeStartDelay: expected string or integer but got boolean")
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	schemaOnce sync.Once
+	// Schema of config files, see Schema
+	fileSchema map[string]interface{}
+
+	durationType        = reflect.TypeOf(time.Duration(0))
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	schemaProviderType  = reflect.TypeOf((*schemaProvider)(nil)).Elem()
+)
+
+// Implemented by types that decode JSON values of another shape
+// than their Go type's, to give the schema of those values
+type schemaProvider interface {
+	JSONSchema() map[string]interface{}
+}
+
+// Schema returns the JSON schema of config files, generated from
+// network.Config and extended with the file reference fields.
+// Configs are validated against it before being decoded.
+func Schema() map[string]interface{} {
+	schemaOnce.Do(func() {
+		fileSchema = typeSchema(reflect.TypeOf(network.Config{}), map[reflect.Type]bool{})
+		addFileRefs(fileSchema, networkFileRefs)
+		nodeSchema := fileSchema["properties"].(map[string]interface{})[nodeConfigsKey].(map[string]interface{})["items"].(map[string]interface{})
+		addFileRefs(nodeSchema, nodeFileRefs)
+		fileSchema["$schema"] = schemaDialect
+	})
+	return fileSchema
+}
+
+// Adds the file reference fields in [refs] to the object [schema]
+func addFileRefs(schema map[string]interface{}, refs map[string]string) {
+	properties := schema["properties"].(map[string]interface{})
+	for refKey := range refs {
+		properties[refKey] = map[string]interface{}{"type": "string"}
+	}
+}
+
+// Returns the JSON schema of the values that decode into [t].
+// [visiting] holds the struct types being generated, to stop at
+// recursive types, which are then given an unconstrained schema.
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	if t.Implements(schemaProviderType) {
+		return reflect.Zero(t).Interface().(schemaProvider).JSONSchema()
+	}
+	if reflect.PtrTo(t).Implements(schemaProviderType) {
+		return reflect.New(t).Interface().(schemaProvider).JSONSchema()
+	}
+	if t.Kind() == reflect.Ptr {
+		return nullable(typeSchema(t.Elem(), visiting))
+	}
+	if t == durationType {
+		// See convertDurations
+		return map[string]interface{}{
+			"type":        []interface{}{"string", "integer"},
+			"description": `e.g. "1m30s", or a number of nanoseconds`,
+		}
+	}
+	if t.Implements(jsonUnmarshalerType) || reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		return map[string]interface{}{}
+	}
+	if t.Implements(textUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return map[string]interface{}{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Base64 encoded
+			return nullable(map[string]interface{}{"type": "string"})
+		}
+		return nullable(map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), visiting)})
+	case reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return nullable(map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), visiting)})
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]interface{}{}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		properties := map[string]interface{}{}
+		addStructFields(t, properties, visiting)
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	default:
+		// e.g. interface{}
+		return map[string]interface{}{}
+	}
+}
+
+// Adds the schemas of the JSON fields of the struct type [t] to [properties],
+// including the fields of its embedded structs
+func addStructFields(t reflect.Type, properties map[string]interface{}, visiting map[reflect.Type]bool) {
+	fields := map[string]reflect.Type{}
+	addStructFieldTypes(t, fields)
+	for name, fieldType := range fields {
+		properties[name] = typeSchema(fieldType, visiting)
+	}
+}
+
+// Replaces the strings in [raw], which decodes into [t], that are
+// time.Duration values with their number of nanoseconds, so that
+// durations can be given as e.g. "1m30s". [raw] must match the schema of [t].
+// [path] is the path of [raw] in the config, for error messages.
+func convertDurations(raw interface{}, t reflect.Type, path string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := raw.(type) {
+	case string:
+		if t != durationType {
+			return raw, nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", displayPath(path), err)
+		}
+		return int64(d), nil
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Map:
+			for key, value := range v {
+				converted, err := convertDurations(value, t.Elem(), joinPath(path, key))
+				if err != nil {
+					return nil, err
+				}
+				v[key] = converted
+			}
+		case reflect.Struct:
+			fields := map[string]reflect.Type{}
+			addStructFieldTypes(t, fields)
+			for key, value := range v {
+				fieldType, ok := fields[key]
+				if !ok {
+					continue
+				}
+				converted, err := convertDurations(value, fieldType, joinPath(path, key))
+				if err != nil {
+					return nil, err
+				}
+				v[key] = converted
+			}
+		}
+		return v, nil
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return raw, nil
+		}
+		for i, value := range v {
+			converted, err := convertDurations(value, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+		return v, nil
+	default:
+		return raw, nil
+	}
+}
+
+// Adds the types of the JSON fields of the struct type [t] to [fields],
+// including the fields of its embedded structs
+func addStructFieldTypes(t reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				addStructFieldTypes(fieldType, fields)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+}
+
+// Returns [schema] extended to also allow null
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []interface{}{typ, "null"}
+	}
+	return schema
+}
+
+// Returns an error giving the path of the first value of [raw],
+// in sorted key order, that doesn't match [schema].
+// [path] is the path of [raw] in the config, for error messages.
+func validate(raw interface{}, schema map[string]interface{}, path string) error {
+	if typ, ok := schema["type"]; ok && !matchesType(raw, typ) {
+		return fmt.Errorf("%s: expected %s but got %s", displayPath(path), typeString(typ), jsonType(raw))
+	}
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldPath := joinPath(path, key)
+			if propSchema, ok := properties[key]; ok {
+				if err := validate(v[key], propSchema.(map[string]interface{}), fieldPath); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s: unknown field", fieldPath)
+				}
+			case map[string]interface{}:
+				if err := validate(v[key], additional, fieldPath); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validate(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Returns true if [raw] is of the JSON schema type [typ],
+// which is a type name or a list of type names
+func matchesType(raw interface{}, typ interface{}) bool {
+	switch typ := typ.(type) {
+	case string:
+		actual := jsonType(raw)
+		return actual == typ || (typ == "number" && actual == "integer")
+	case []interface{}:
+		for _, t := range typ {
+			if matchesType(raw, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the JSON schema type of [raw], as decoded from JSON
+// with json.Decoder.UseNumber or from YAML
+func jsonType(raw interface{}) string {
+	switch v := raw.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return "integer"
+		}
+		if _, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return "integer"
+		}
+		return "number"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", raw)
+	}
+}
+
+func typeString(typ interface{}) string {
+	if types, ok := typ.([]interface{}); ok {
+		names := make([]string, len(types))
+		for i, t := range types {
+			names[i] = fmt.Sprint(t)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(typ)
+}
@@ -5,12 +5,11 @@ import (
 	"fmt"
 	"go/build"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/ava-labs/avalanche-network-runner/local"
 	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/pkg/runner"
 	"github.com/ava-labs/avalanchego/utils/logging"
 )
 
@@ -20,26 +19,6 @@ const (
 
 var goPath = os.ExpandEnv("$GOPATH")
 
-// Blocks until a signal is received on [signalChan], upon which
-// [n.Stop()] is called. If [signalChan] is closed, does nothing.
-// Closes [closedOnShutdownChan] amd [signalChan] when done shutting down network.
-// This function should only be called once.
-func shutdownOnSignal(
-	log logging.Logger,
-	n network.Network,
-	signalChan chan os.Signal,
-	closedOnShutdownChan chan struct{},
-) {
-	sig := <-signalChan
-	log.Info("got OS signal %s", sig)
-	if err := n.Stop(context.Background()); err != nil {
-		log.Debug("error while stopping network: %s", err)
-	}
-	signal.Reset()
-	close(signalChan)
-	close(closedOnShutdownChan)
-}
-
 // Shows example usage of the Avalanche Network Runner.
 // Creates a local five node Avalanche network
 // and waits for all nodes to become healthy.
@@ -66,36 +45,29 @@ func main() {
 }
 
 func run(log logging.Logger, binaryPath string) error {
+	// Kill the nodes left running by earlier runs that crashed, print
+	// the node URIs once the network is started, and stop the network
+	// on SIGINT/SIGTERM, even while it's starting
+	return runner.RunUntilSignalWithConfig(func(ctx context.Context) (network.Network, error) {
+		return start(ctx, log, binaryPath)
+	}, runner.Config{Log: log, CleanupStaleNetworks: true})
+}
+
+func start(ctx context.Context, log logging.Logger, binaryPath string) (network.Network, error) {
 	// Create the network
 	nw, err := local.NewDefaultNetwork(log, binaryPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() { // Stop the network when this function returns
-		if err := nw.Stop(context.Background()); err != nil {
-			log.Debug("error stopping network: %w", err)
-		}
-	}()
-
-	// When we get a SIGINT or SIGTERM, stop the network and close [closedOnShutdownCh]
-	signalsChan := make(chan os.Signal, 1)
-	signal.Notify(signalsChan, syscall.SIGINT)
-	signal.Notify(signalsChan, syscall.SIGTERM)
-	closedOnShutdownCh := make(chan struct{})
-	go func() {
-		shutdownOnSignal(log, nw, signalsChan, closedOnShutdownCh)
-	}()
 
 	// Wait until the nodes in the network are ready
-	ctx, cancel := context.WithTimeout(context.Background(), healthyTimeout)
+	ctx, cancel := context.WithTimeout(ctx, healthyTimeout)
 	defer cancel()
 	log.Info("waiting for all nodes to report healthy...")
 	if err := nw.Healthy(ctx); err != nil {
-		return err
+		return nw, err
 	}
 
-	log.Info("All nodes healthy. Network will run until you CTRL + C to exit...")
-	// Wait until done shutting down network after SIGINT/SIGTERM
-	<-closedOnShutdownCh
-	return nil
+	log.Info("All nodes healthy")
+	return nw, nil
 }
@@ -4,14 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/ava-labs/avalanche-network-runner/local"
 	"github.com/ava-labs/avalanche-network-runner/network"
 	"github.com/ava-labs/avalanche-network-runner/network/node"
-	"github.com/ava-labs/avalanchego/config"
+	"github.com/ava-labs/avalanche-network-runner/pkg/runner"
 	"github.com/ava-labs/avalanchego/staking"
 	"github.com/ava-labs/avalanchego/utils/logging"
 )
@@ -22,26 +20,6 @@ const (
 
 var goPath = os.ExpandEnv("$GOPATH")
 
-// Blocks until a signal is received on [signalChan], upon which
-// [n.Stop()] is called. If [signalChan] is closed, does nothing.
-// Closes [closedOnShutdownChan] amd [signalChan] when done shutting down network.
-// This function should only be called once.
-func shutdownOnSignal(
-	log logging.Logger,
-	n network.Network,
-	signalChan chan os.Signal,
-	closedOnShutdownChan chan struct{},
-) {
-	sig := <-signalChan
-	log.Info("got OS signal %s", sig)
-	if err := n.Stop(context.Background()); err != nil {
-		log.Debug("error while stopping network: %s", err)
-	}
-	signal.Reset()
-	close(signalChan)
-	close(closedOnShutdownChan)
-}
-
 // Shows example usage of the Avalanche Network Runner.
 // Creates a local five node Avalanche network
 // and waits for all nodes to become healthy.
@@ -70,59 +48,53 @@ func main() {
 }
 
 func run(log logging.Logger, binaryPath string) error {
+	// Kill the nodes left running by earlier runs that crashed, print
+	// the node URIs once the network is started, and stop the network
+	// on SIGINT/SIGTERM, even while it's starting
+	return runner.RunUntilSignalWithConfig(func(ctx context.Context) (network.Network, error) {
+		return start(ctx, log, binaryPath)
+	}, runner.Config{Log: log, CleanupStaleNetworks: true})
+}
+
+func start(ctx context.Context, log logging.Logger, binaryPath string) (network.Network, error) {
 	// Create the network
 	nw, err := local.NewDefaultNetwork(log, binaryPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() { // Stop the network when this function returns
-		if err := nw.Stop(context.Background()); err != nil {
-			log.Debug("error stopping network: %w", err)
-		}
-	}()
-
-	// When we get a SIGINT or SIGTERM, stop the network and close [closedOnShutdownCh]
-	signalsChan := make(chan os.Signal, 1)
-	signal.Notify(signalsChan, syscall.SIGINT)
-	signal.Notify(signalsChan, syscall.SIGTERM)
-	closedOnShutdownCh := make(chan struct{})
-	go func() {
-		shutdownOnSignal(log, nw, signalsChan, closedOnShutdownCh)
-	}()
 
 	// Wait until the nodes in the network are ready
-	ctx, cancel := context.WithTimeout(context.Background(), healthyTimeout)
+	healthyCtx, cancel := context.WithTimeout(ctx, healthyTimeout)
 	defer cancel()
 	log.Info("waiting for all nodes to report healthy...")
-	if err := nw.Healthy(ctx); err != nil {
-		return err
-
+	if err := nw.Healthy(healthyCtx); err != nil {
+		return nw, err
 	}
 
 	// Print the node names
 	nodeNames, err := nw.GetNodeNames()
 	if err != nil {
-		return err
+		return nw, err
 	}
 	log.Info("current network's nodes: %s", nodeNames)
 
 	// Get one node
 	node1, err := nw.GetNode(nodeNames[0])
 	if err != nil {
-		return err
+		return nw, err
 	}
 
 	// Get its node ID through its API and print it
-	node1ID, err := node1.GetAPIClient().InfoAPI().GetNodeID(context.Background())
+	node1ID, err := node1.GetAPIClient().InfoAPI().GetNodeID(ctx)
 	if err != nil {
-		return err
+		return nw, err
 	}
 	log.Info("one node's ID is: %s", node1ID)
 
 	// Add a new node with generated cert/key/nodeid
 	stakingCert, stakingKey, err := staking.NewCertAndKeyBytes()
 	if err != nil {
-		return err
+		return nw, err
 	}
 	nodeConfig := node.Config{
 		Name:        "New Node",
@@ -131,39 +103,36 @@ func run(log logging.Logger, binaryPath string) error {
 		StakingCert: string(stakingCert),
 		// The flags below would override the config in this node's config file,
 		// if it had one.
-		Flags: map[string]interface{}{
-			config.LogLevelKey: logging.Debug,
-			config.HTTPHostKey: "0.0.0.0",
-		},
+		Flags: (&node.FlagsBuilder{}).
+			LogLevel(logging.Debug).
+			HTTPHost("0.0.0.0").
+			Build(),
 	}
 	if _, err := nw.AddNode(nodeConfig); err != nil {
-		return err
+		return nw, err
 	}
 
 	// Remove one node
 	nodeToRemove := nodeNames[3]
 	log.Info("removing node %q", nodeToRemove)
 	if err := nw.RemoveNode(nodeToRemove); err != nil {
-		return err
+		return nw, err
 	}
 
 	// Wait until the nodes in the updated network are ready
-	ctx, cancel = context.WithTimeout(context.Background(), healthyTimeout)
+	healthyCtx, cancel = context.WithTimeout(ctx, healthyTimeout)
 	defer cancel()
 	log.Info("waiting for updated network to report healthy...")
-	if err := nw.Healthy(ctx); err != nil {
-		return err
+	if err := nw.Healthy(healthyCtx); err != nil {
+		return nw, err
 	}
 
 	// Print the node names
 	nodeNames, err = nw.GetNodeNames()
 	if err != nil {
-		return err
+		return nw, err
 	}
 	// Will have the new node but not the removed one
 	log.Info("updated network's nodes: %s", nodeNames)
-	log.Info("Network will run until you CTRL + C to exit...")
-	// Wait until done shutting down network after SIGINT/SIGTERM
-	<-closedOnShutdownCh
-	return nil
+	return nw, nil
 }
@@ -0,0 +1,214 @@
+// Package gateway serves the APIs of all of a network's nodes behind a
+// single address, routing requests by path (e.g. /node1/ext/info goes to
+// node1's /ext/info). Node addresses are looked up on every request, so
+// the gateway keeps working when nodes are restarted on new ports.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+// CORS configures the CORS headers the gateway adds to responses.
+// When AllowedOrigins is empty, the headers of the nodes' responses
+// are passed through unchanged.
+type CORS struct {
+	// Origins allowed to make requests, or "*" for any origin
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// Methods allowed in requests. Defaults to GET, POST and OPTIONS.
+	AllowedMethods []string `json:"allowedMethods"`
+	// Headers allowed in requests. Defaults to Content-Type.
+	AllowedHeaders []string `json:"allowedHeaders"`
+}
+
+const (
+	dialTimeout     = 30 * time.Second
+	keepAlive       = 30 * time.Second
+	idleConnTimeout = 90 * time.Second
+	// Requests to a node are often concurrent, e.g. from a wallet
+	maxIdleConnsPerNode = 10
+)
+
+var (
+	defaultAllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodOptions}
+	defaultAllowedHeaders = []string{"Content-Type"}
+)
+
+// Gateway is an HTTP handler that reverse proxies requests
+// to the node named by the first segment of their path
+type Gateway struct {
+	log logging.Logger
+	// Returns the host:port of the API of the node with this name.
+	// Called on every request.
+	getAddr func(nodeName string) (string, error)
+	cors    CORS
+	proxy   *httputil.ReverseProxy
+	// Used only by the gateway, so that its connections to the nodes
+	// aren't shared with other users of http.DefaultTransport
+	transport *http.Transport
+}
+
+// New returns a gateway to the nodes whose addresses are given by [getAddr]
+func New(log logging.Logger, getAddr func(nodeName string) (string, error), cors CORS) *Gateway {
+	if len(cors.AllowedMethods) == 0 {
+		cors.AllowedMethods = defaultAllowedMethods
+	}
+	if len(cors.AllowedHeaders) == 0 {
+		cors.AllowedHeaders = defaultAllowedHeaders
+	}
+	g := &Gateway{
+		log:     log,
+		getAddr: getAddr,
+		cors:    cors,
+		// The nodes are reached directly, never through a proxy
+		transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   dialTimeout,
+				KeepAlive: keepAlive,
+			}).DialContext,
+			MaxIdleConnsPerHost: maxIdleConnsPerNode,
+			IdleConnTimeout:     idleConnTimeout,
+		},
+	}
+	g.proxy = &httputil.ReverseProxy{
+		// The request's URL is rewritten by ServeHTTP
+		Director:  func(*http.Request) {},
+		Transport: g.transport,
+		ModifyResponse: func(resp *http.Response) error {
+			if len(g.cors.AllowedOrigins) > 0 {
+				// Don't duplicate the headers the gateway sets
+				for header := range resp.Header {
+					if strings.HasPrefix(header, "Access-Control-") {
+						resp.Header.Del(header)
+					}
+				}
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			g.log.Debug("error proxying %s: %s", r.URL, err)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	return g
+}
+
+// NewNetworkGateway returns a gateway to the nodes of [nw]
+func NewNetworkGateway(log logging.Logger, nw network.Network, cors CORS) *Gateway {
+	return New(log, func(nodeName string) (string, error) {
+		node, err := nw.GetNode(nodeName)
+		if err != nil {
+			return "", err
+		}
+		return net.JoinHostPort(node.GetURL(), strconv.Itoa(int(node.GetAPIPort()))), nil
+	}, cors)
+}
+
+// ServeHTTP proxies [r] to the node named by the first segment of its
+// path, with that segment removed. Responds 404 if there's no such node.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	preflight := g.setCORSHeaders(w, r)
+	if preflight {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	nodeName, path := splitPath(r.URL.Path)
+	if nodeName == "" {
+		http.NotFound(w, r)
+		return
+	}
+	addr, err := g.getAddr(nodeName)
+	switch {
+	case errors.Is(err, network.ErrNodeNotFound):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	outReq := r.Clone(r.Context())
+	outReq.URL = &url.URL{
+		Scheme:   "http",
+		Host:     addr,
+		Path:     path,
+		RawQuery: r.URL.RawQuery,
+	}
+	outReq.Host = addr
+	outReq.RequestURI = ""
+	g.proxy.ServeHTTP(w, outReq)
+}
+
+// Sets the CORS headers of the response to [r], if any are configured.
+// Returns true if [r] is a preflight request, which isn't proxied.
+func (g *Gateway) setCORSHeaders(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(g.cors.AllowedOrigins) == 0 || origin == "" {
+		return false
+	}
+	allowed := false
+	for _, allowedOrigin := range g.cors.AllowedOrigins {
+		if allowedOrigin == "*" || allowedOrigin == origin {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false
+	}
+	header := w.Header()
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Add("Vary", "Origin")
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	header.Set("Access-Control-Allow-Methods", strings.Join(g.cors.AllowedMethods, ", "))
+	header.Set("Access-Control-Allow-Headers", strings.Join(g.cors.AllowedHeaders, ", "))
+	return true
+}
+
+// Splits [path] into its first segment and the rest,
+// e.g. "/node1/ext/info" into "node1" and "/ext/info"
+func splitPath(path string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], "/"
+	}
+	return parts[0], "/" + parts[1]
+}
+
+// ListenAndServe serves the gateway on [addr] until [ctx] is done.
+// Returns right away if [addr] can't be listened on.
+func (g *Gateway) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer g.transport.CloseIdleConnections()
+	server := &http.Server{Handler: g}
+	served := make(chan struct{})
+	defer close(served)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = server.Close()
+		case <-served:
+		}
+	}()
+	// Closes [listener] when it returns
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
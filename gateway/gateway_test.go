@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// Starts a node API that answers with its name and the path requested
+func newTestNode(t *testing.T, name string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		fmt.Fprintf(w, "%s %s?%s", name, r.URL.Path, r.URL.RawQuery)
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func newTestGateway(t *testing.T, cors CORS) *httptest.Server {
+	addrs := map[string]string{
+		"node1": newTestNode(t, "node1"),
+		"node2": newTestNode(t, "node2"),
+	}
+	g := New(logging.NoLog{}, func(nodeName string) (string, error) {
+		addr, ok := addrs[nodeName]
+		if !ok {
+			return "", fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+		}
+		return addr, nil
+	}, cors)
+	server := httptest.NewServer(g)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGatewayRouting(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	server := newTestGateway(t, CORS{})
+
+	for nodeName, path := range map[string]string{
+		"node1": "/ext/info",
+		"node2": "/ext/bc/C/rpc",
+	} {
+		resp, err := http.Get(server.URL + "/" + nodeName + path + "?a=b")
+		assert.NoError(err)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(err)
+		_ = resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal(nodeName+" "+path+"?a=b", string(body))
+		// Headers of the nodes are passed through when CORS isn't configured
+		assert.Equal("*", resp.Header.Get("Access-Control-Allow-Origin"))
+	}
+
+	resp, err := http.Get(server.URL + "/node3/ext/info")
+	assert.NoError(err)
+	_ = resp.Body.Close()
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func TestGatewayCORS(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	server := newTestGateway(t, CORS{AllowedOrigins: []string{"http://localhost:3000"}})
+
+	// Preflight requests are answered by the gateway
+	req, err := http.NewRequest(http.MethodOptions, server.URL+"/node1/ext/info", nil)
+	assert.NoError(err)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(err)
+	_ = resp.Body.Close()
+	assert.Equal(http.StatusNoContent, resp.StatusCode)
+	assert.Equal("http://localhost:3000", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(resp.Header.Get("Access-Control-Allow-Methods"), http.MethodPost)
+
+	// The node's CORS headers are replaced
+	req, err = http.NewRequest(http.MethodGet, server.URL+"/node1/ext/info", nil)
+	assert.NoError(err)
+	req.Header.Set("Origin", "http://localhost:3000")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(err)
+	_ = resp.Body.Close()
+	assert.Equal([]string{"http://localhost:3000"}, resp.Header.Values("Access-Control-Allow-Origin"))
+
+	// Disallowed origins get no CORS headers
+	req, err = http.NewRequest(http.MethodGet, server.URL+"/node1/ext/info", nil)
+	assert.NoError(err)
+	req.Header.Set("Origin", "http://example.com")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(err)
+	_ = resp.Body.Close()
+	assert.Empty(resp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestListenAndServe(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	g := New(logging.NoLog{}, func(nodeName string) (string, error) {
+		return "", fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+	}, CORS{})
+
+	// An address in use is reported right away
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+	err = g.ListenAndServe(context.Background(), listener.Addr().String())
+	assert.Error(err)
+	addr := listener.Addr().String()
+	assert.NoError(listener.Close())
+
+	// Otherwise the gateway is served until the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- g.ListenAndServe(ctx, addr)
+	}()
+	assert.Eventually(func() bool {
+		resp, err := http.Get("http://" + addr + "/node1/ext/info")
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode == http.StatusNotFound
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	select {
+	case err := <-errCh:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		assert.Fail("gateway still serving")
+	}
+}
@@ -0,0 +1,37 @@
+package local
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ava-labs/avalanche-network-runner/api"
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/ids"
+	"golang.org/x/sync/errgroup"
+)
+
+// See network.Network
+func (ln *localNetwork) AddBlockchainAlias(ctx context.Context, chainID ids.ID, alias string) error {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	if ln.stopCalled() {
+		return network.ErrStopped
+	}
+	nodeNames := make([]string, 0, len(ln.nodes))
+	for nodeName := range ln.nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	errGr, ctx := errgroup.WithContext(ctx)
+	for _, nodeName := range nodeNames {
+		node := ln.nodes[nodeName]
+		errGr.Go(func() error {
+			return api.AddBlockchainAlias(ctx, node.client, chainID, alias)
+		})
+	}
+	if err := errGr.Wait(); err != nil {
+		return err
+	}
+	ln.log.Info("aliased blockchain %s to %q on %d nodes", chainID, alias, len(nodeNames))
+	return nil
+}
@@ -0,0 +1,238 @@
+package local
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+)
+
+// Max time to get the metrics of a node
+const metricsTimeout = 10 * time.Second
+
+// Node files that aren't collected, as they hold secrets
+var secretFileNames = map[string]struct{}{
+	stakingKeyFileName:      {},
+	apiTLSKeyFileName:       {},
+	apiAuthPasswordFileName: {},
+}
+
+// See network.Network
+func (ln *localNetwork) CollectArtifacts(ctx context.Context, path string) error {
+	start := time.Now()
+	err := ln.collectArtifacts(ctx, path)
+	ln.history.record(network.OpCollectArtifacts, path, start, err)
+	return err
+}
+
+func (ln *localNetwork) collectArtifacts(ctx context.Context, path string) error {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	if ln.dataDirsDeleted {
+		return errors.New("node dirs were deleted")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gzipWriter := gzip.NewWriter(f)
+	archive := &artifactsArchive{tarWriter: tar.NewWriter(gzipWriter)}
+
+	manifest := network.ArtifactsManifest{
+		NetworkID:   ln.networkID,
+		CollectedAt: time.Now(),
+		Nodes:       make([]network.NodeArtifacts, 0, len(ln.manifest)),
+	}
+	nodeNames := make([]string, 0, len(ln.manifest))
+	for nodeName := range ln.manifest {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	for _, nodeName := range nodeNames {
+		nodeArtifacts, err := ln.collectNodeArtifacts(ctx, archive, nodeName)
+		if err != nil {
+			return fmt.Errorf("couldn't collect artifacts of node %q: %w", nodeName, err)
+		}
+		manifest.Nodes = append(manifest.Nodes, nodeArtifacts)
+	}
+
+	if err := archive.addBytes(genesisFileName, ln.genesis); err != nil {
+		return err
+	}
+	historyBytes, err := json.MarshalIndent(ln.history.get(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := archive.addBytes("history.json", historyBytes); err != nil {
+		return err
+	}
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := archive.addBytes(network.ArtifactsManifestName, manifestBytes); err != nil {
+		return err
+	}
+	if err := archive.tarWriter.Close(); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Adds the files of [nodeName] to [archive], under <node name>/.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) collectNodeArtifacts(ctx context.Context, archive *artifactsArchive, nodeName string) (network.NodeArtifacts, error) {
+	manifest := ln.manifest[nodeName]
+	nodeArtifacts := network.NodeArtifacts{
+		Name:    nodeName,
+		NodeID:  manifest.nodeID,
+		APIPort: manifest.apiPort,
+		P2PPort: manifest.p2pPort,
+		Exited:  manifest.exited,
+	}
+	if manifest.exitErr != nil {
+		nodeArtifacts.ExitErr = manifest.exitErr.Error()
+	}
+	numFiles := len(archive.paths)
+
+	if node, ok := ln.nodes[nodeName]; ok {
+		nodeArtifacts.Running = true
+		metrics, err := getMetrics(ctx, node)
+		if err != nil {
+			nodeArtifacts.MetricsErr = err.Error()
+		} else if err := archive.addBytes(filepath.Join(nodeName, "metrics.txt"), metrics); err != nil {
+			return nodeArtifacts, err
+		}
+	}
+	if err := archive.addDir(manifest.dir, nodeName, manifest.dbDir); err != nil {
+		return nodeArtifacts, err
+	}
+	// The logs dir may be outside of the node's dir
+	if manifest.logsDir != "" && !isSubpath(manifest.dir, manifest.logsDir) {
+		if err := archive.addDir(manifest.logsDir, filepath.Join(nodeName, defaultLogsSubdir), ""); err != nil {
+			return nodeArtifacts, err
+		}
+	}
+	nodeArtifacts.Files = append([]string{}, archive.paths[numFiles:]...)
+	return nodeArtifacts, nil
+}
+
+// Returns the metrics of [node], in Prometheus text format
+func getMetrics(ctx context.Context, node *localNode) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, metricsTimeout)
+	defer cancel()
+	url := node.GetURI() + "/ext/metrics"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := node.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics API returned %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Returns true if [path] is [dir] or is under it
+func isSubpath(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
+
+// Writes files to a tar archive, recording their paths
+type artifactsArchive struct {
+	tarWriter *tar.Writer
+	paths     []string
+}
+
+func (a *artifactsArchive) addBytes(name string, contents []byte) error {
+	err := a.tarWriter.WriteHeader(&tar.Header{
+		Name:    filepath.ToSlash(name),
+		Mode:    0o644,
+		Size:    int64(len(contents)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := a.tarWriter.Write(contents); err != nil {
+		return err
+	}
+	a.paths = append(a.paths, filepath.ToSlash(name))
+	return nil
+}
+
+// Adds the regular files under [dir], other than secrets and the files
+// under [skipDir], to the archive under [prefix]. A missing [dir] is skipped.
+func (a *artifactsArchive) addDir(dir string, prefix string, skipDir string) error {
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case info.IsDir() && skipDir != "" && isSubpath(skipDir, path):
+			return filepath.SkipDir
+		case !info.Mode().IsRegular():
+			return nil
+		}
+		if _, ok := secretFileNames[info.Name()]; ok {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return a.addFile(path, filepath.Join(prefix, rel), info)
+	})
+}
+
+func (a *artifactsArchive) addFile(path string, name string, info os.FileInfo) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(name)
+	if err := a.tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	// Log files may grow while they're read
+	if _, err := io.CopyN(a.tarWriter, f, info.Size()); err != nil {
+		return fmt.Errorf("couldn't read %s: %w", path, err)
+	}
+	a.paths = append(a.paths, header.Name)
+	return nil
+}
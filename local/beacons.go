@@ -0,0 +1,125 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/config"
+	"github.com/ava-labs/avalanchego/utils/beacon"
+	"github.com/ava-labs/avalanchego/utils/ips"
+)
+
+// Returns the IP and P2P port other nodes bootstrap from if [node] is a beacon
+func (node *localNode) beaconIP() ips.IPPort {
+	return ips.IPPort{
+		IP:   advertisedIP(&node.config),
+		Port: node.p2pPort,
+	}
+}
+
+// Returns the IP a node with [nodeConfig] advertises to its peers
+func advertisedIP(nodeConfig *node.Config) net.IP {
+	if nodeConfig.PublicIP == "" {
+		return net.IPv6loopback
+	}
+	return node.ParseIP(nodeConfig.PublicIP)
+}
+
+// See network.Network
+func (ln *localNetwork) GetBootstrapBeacons() ([]network.Beacon, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+	return ln.beacons(), nil
+}
+
+// Returns the beacons of the network, sorted by name.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) beacons() []network.Beacon {
+	beacons := []network.Beacon{}
+	for _, node := range ln.nodes {
+		if node.config.IsBeacon {
+			beacons = append(beacons, network.Beacon{
+				Name:   node.name,
+				NodeID: node.nodeID,
+				IP:     node.beaconIP(),
+			})
+		}
+	}
+	sort.Slice(beacons, func(i, j int) bool {
+		return beacons[i].Name < beacons[j].Name
+	})
+	return beacons
+}
+
+// Makes the running validator node first by name a beacon.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) promoteBeacon() error {
+	nodeNames := make([]string, 0, len(ln.nodes))
+	for nodeName, node := range ln.nodes {
+		if !node.config.NonValidator {
+			nodeNames = append(nodeNames, nodeName)
+		}
+	}
+	if len(nodeNames) == 0 {
+		return errors.New("no beacons left and no validator node to make a beacon")
+	}
+	sort.Strings(nodeNames)
+	node := ln.nodes[nodeNames[0]]
+	if err := ln.bootstraps.Add(beacon.New(node.nodeID, node.beaconIP())); err != nil {
+		return fmt.Errorf("couldn't make node %q a beacon: %w", node.name, err)
+	}
+	node.config.IsBeacon = true
+	ln.log.Info("no beacons left, node %q is now a beacon", node.name)
+	return nil
+}
+
+// See network.Network
+func (ln *localNetwork) RefreshBeacons(ctx context.Context) ([]string, error) {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+	start := time.Now()
+	restarted, err := ln.refreshBeacons(ctx)
+	ln.history.record(network.OpRefreshBeacons, "", start, err)
+	return restarted, err
+}
+
+// Assumes [ln.lock] is held and [ln.Stop] hasn't been called.
+func (ln *localNetwork) refreshBeacons(ctx context.Context) ([]string, error) {
+	beaconIDs := map[string]struct{}{}
+	for _, beacon := range ln.beacons() {
+		beaconIDs[beacon.NodeID.String()] = struct{}{}
+	}
+	nodeNames := make([]string, 0, len(ln.nodes))
+	for nodeName, node := range ln.nodes {
+		bootstrapIDs, _ := node.flags.Get(config.BootstrapIDsKey)
+		for _, bootstrapID := range strings.Split(bootstrapIDs, ",") {
+			if _, ok := beaconIDs[bootstrapID]; bootstrapID != "" && !ok {
+				nodeNames = append(nodeNames, nodeName)
+				break
+			}
+		}
+	}
+	sort.Strings(nodeNames)
+	for i, nodeName := range nodeNames {
+		if err := ctx.Err(); err != nil {
+			return nodeNames[:i], err
+		}
+		if err := ln.restartNode(nodeName, func(*node.Config) {}); err != nil {
+			return nodeNames[:i], err
+		}
+	}
+	return nodeNames, nil
+}
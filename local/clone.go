@@ -0,0 +1,174 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanche-network-runner/utils"
+	"github.com/ava-labs/avalanchego/config"
+)
+
+// Flags of a node that are specific to the node's process,
+// so they aren't carried over to the node's clone
+var cloneDroppedFlags = []string{
+	config.HTTPPortKey,
+	config.StakingPortKey,
+	config.DBPathKey,
+	config.LogsDirKey,
+}
+
+// See network.Network
+func (ln *localNetwork) Clone(ctx context.Context, overrides network.CloneOverrides) (network.Network, error) {
+	start := time.Now()
+	clone, err := ln.clone(ctx, overrides)
+	ln.history.record(network.OpClone, "", start, err)
+	return clone, err
+}
+
+func (ln *localNetwork) clone(ctx context.Context, overrides network.CloneOverrides) (network.Network, error) {
+	if err := ln.healthy(ctx); err != nil {
+		return nil, fmt.Errorf("can't clone unhealthy network: %w", err)
+	}
+
+	// The databases are copied here, then imported by the clone's nodes
+	dbsDir, err := os.MkdirTemp("", rootDirPrefix+"clone")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dbsDir)
+
+	networkConfig, err := ln.cloneConfig(dbsDir, overrides)
+	if err != nil {
+		return nil, err
+	}
+	clone, err := newNetwork(ln.log, ln.newAPIClientF, ln.nodeProcessCreator, "", ln.snapshotsDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := clone.loadConfig(ctx, networkConfig); err != nil {
+		if stopErr := clone.stop(ctx); stopErr != nil {
+			ln.log.Warn("couldn't stop clone: %s", stopErr)
+		}
+		return nil, fmt.Errorf("couldn't start clone: %w", err)
+	}
+	return clone, nil
+}
+
+// Copies the database of each node to a dir of [dbsDir] while the
+// nodes are paused, and returns the config of a network whose nodes
+// start from these databases, with [overrides] applied
+func (ln *localNetwork) cloneConfig(dbsDir string, overrides network.CloneOverrides) (network.Config, error) {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() {
+		return network.Config{}, network.ErrStopped
+	}
+	for name := range overrides.NodeFlags {
+		if _, ok := ln.nodes[name]; !ok {
+			return network.Config{}, fmt.Errorf("%w: %q", network.ErrNodeNotFound, name)
+		}
+	}
+
+	// Pause every node, so that the copied databases are of the same state
+	for name, node := range ln.nodes {
+		if node.paused {
+			continue
+		}
+		if err := ln.setNodePaused(name, true); err != nil {
+			return network.Config{}, err
+		}
+		defer func(name string) {
+			if err := ln.setNodePaused(name, false); err != nil {
+				ln.log.Warn("couldn't resume node %q after cloning: %s", name, err)
+			}
+		}(name)
+	}
+
+	// The clone has the settings of this network, including the ones
+	// added after it was loaded, except for its nodes and the resources
+	// it can't share with this network
+	networkConfig := ln.config
+	networkConfig.NodeConfigs = nil
+	networkConfig.Flags = make(map[string]interface{}, len(ln.flags))
+	for k, v := range ln.flags {
+		networkConfig.Flags[k] = v
+	}
+	for _, key := range cloneDroppedFlags {
+		delete(networkConfig.Flags, key)
+	}
+	networkConfig.BeforeNodeStart = append([]func(*node.Config) error(nil), ln.beforeNodeStartHooks...)
+	networkConfig.HealthChecks = append([]network.HealthCheck(nil), ln.healthChecks...)
+	// The subnets are already in the copied databases
+	networkConfig.SubnetSpecs = nil
+	// These would be written to by both networks
+	networkConfig.RootDataDir = ""
+	networkConfig.HistoryFile = ""
+	networkConfig.ArtifactsPath = ""
+	networkConfig.ResourceUsageFile = ""
+	networkConfig.HostsRegistry = nil
+	// The sidecars listen on free ports rather than on this network's,
+	// except for the explorer, whose port must be given
+	if networkConfig.Faucet != nil {
+		faucetConfig := *networkConfig.Faucet
+		faucetConfig.ListenAddr = ""
+		networkConfig.Faucet = &faucetConfig
+	}
+	if networkConfig.Observability != nil {
+		observabilityConfig := *networkConfig.Observability
+		observabilityConfig.PrometheusPort = 0
+		observabilityConfig.GrafanaPort = 0
+		networkConfig.Observability = &observabilityConfig
+	}
+	networkConfig.Explorer = nil
+
+	for name, node := range ln.nodes {
+		dbDir := filepath.Join(dbsDir, name)
+		if err := node.ExportDB(dbDir); err != nil {
+			return network.Config{}, err
+		}
+		nodeConfig, err := cloneNodeConfig(node.config, overrides)
+		if err != nil {
+			return network.Config{}, fmt.Errorf("couldn't clone config of node %q: %w", name, err)
+		}
+		nodeConfig.DBPath = dbDir
+		networkConfig.NodeConfigs = append(networkConfig.NodeConfigs, nodeConfig)
+	}
+	return networkConfig, nil
+}
+
+// Returns a copy of [nodeConfig] without the flags specific to
+// the node's process, and with [overrides] applied
+func cloneNodeConfig(nodeConfig node.Config, overrides network.CloneOverrides) (node.Config, error) {
+	// The flags may be shared with other node configs, so they're copied
+	flags := make(map[string]interface{}, len(nodeConfig.Flags))
+	for k, v := range nodeConfig.Flags {
+		flags[k] = v
+	}
+	for k, v := range overrides.Flags {
+		flags[k] = v
+	}
+	for k, v := range overrides.NodeFlags[nodeConfig.Name] {
+		flags[k] = v
+	}
+	for _, key := range cloneDroppedFlags {
+		delete(flags, key)
+		if len(nodeConfig.ConfigFile) == 0 {
+			continue
+		}
+		configFile, err := utils.SetJSONKey(nodeConfig.ConfigFile, key, "")
+		if err != nil {
+			return node.Config{}, err
+		}
+		nodeConfig.ConfigFile = configFile
+	}
+	nodeConfig.Flags = flags
+	if overrides.BinaryPath != "" {
+		nodeConfig.BinaryPath = overrides.BinaryPath
+	}
+	return nodeConfig, nil
+}
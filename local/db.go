@@ -0,0 +1,51 @@
+package local
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	dircopy "github.com/otiai10/copy"
+)
+
+// See node.Node
+func (node *localNode) ExportDB(destDir string) error {
+	networkName := constants.NetworkName(node.networkID)
+	sourceDir := filepath.Join(node.dbDir, networkName)
+	if _, err := os.Stat(sourceDir); err != nil {
+		return fmt.Errorf("node %q has no database: %w", node.name, err)
+	}
+	entries, err := os.ReadDir(destDir)
+	switch {
+	case err != nil && !os.IsNotExist(err):
+		return err
+	case len(entries) != 0:
+		return fmt.Errorf("destination dir %q isn't empty", destDir)
+	}
+	if err := dircopy.Copy(sourceDir, filepath.Join(destDir, networkName)); err != nil {
+		return fmt.Errorf("couldn't export database of node %q: %w", node.name, err)
+	}
+	return nil
+}
+
+// Copies the database of network [networkID] in [nodeConfig]'s
+// DB path, if any, to [dbDir], unless [dbDir] already has it
+func importDB(nodeConfig *node.Config, dbDir string, networkID uint32) error {
+	if nodeConfig.DBPath == "" {
+		return nil
+	}
+	networkName := constants.NetworkName(networkID)
+	targetDir := filepath.Join(dbDir, networkName)
+	if _, err := os.Stat(targetDir); err == nil {
+		return nil
+	}
+	if err := node.CheckDBNetwork(nodeConfig.DBPath, networkID); err != nil {
+		return err
+	}
+	if err := dircopy.Copy(filepath.Join(nodeConfig.DBPath, networkName), targetDir); err != nil {
+		return fmt.Errorf("couldn't import database of node %q: %w", nodeConfig.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+)
+
+// Size of the sectors of a device-mapper table
+const dmSectorSize = 512
+
+// Runs command [name] with [args], and returns its trimmed output.
+// Replaced in tests.
+var runDiskFaultCmd = func(name string, args ...string) (string, error) {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Mounts at [dbDir] the database filesystem of [nodeConfig]'s disk fault,
+// if any, creating its image file if it doesn't exist yet. Returns the
+// func that unmounts it, which is a no-op if there's no disk fault.
+func (ln *localNetwork) setupDiskFault(nodeConfig *node.Config, dbDir string) (func(), error) {
+	diskFault := nodeConfig.DiskFault
+	if diskFault == nil {
+		return func() {}, nil
+	}
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("disk faults are only supported on linux")
+	}
+	// Undone in reverse order if a later step fails, or on teardown
+	var undos []func() error
+	teardown := func() {
+		for i := len(undos) - 1; i >= 0; i-- {
+			if err := undos[i](); err != nil {
+				ln.log.Warn("couldn't tear down disk fault of node %q: %s", nodeConfig.Name, err)
+			}
+		}
+	}
+
+	imagePath := dbDir + ".img"
+	if _, err := os.Stat(imagePath); os.IsNotExist(err) {
+		if err := createDiskImage(imagePath, diskFault.MaxDBSize); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dbDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	device := imagePath
+	mountOpts := []string{"-o", "loop"}
+	if diskFault.IOLatency > 0 {
+		loopDevice, err := runDiskFaultCmd("losetup", "--find", "--show", imagePath)
+		if err != nil {
+			return nil, err
+		}
+		undos = append(undos, func() error {
+			_, err := runDiskFaultCmd("losetup", "--detach", loopDevice)
+			return err
+		})
+		dmName := fmt.Sprintf("anr-%d-%s", os.Getpid(), nodeConfig.Name)
+		table := fmt.Sprintf("0 %d delay %s 0 %d", diskFault.MaxDBSize/dmSectorSize, loopDevice, diskFault.IOLatency/time.Millisecond)
+		if _, err := runDiskFaultCmd("dmsetup", "create", dmName, "--table", table); err != nil {
+			teardown()
+			return nil, err
+		}
+		undos = append(undos, func() error {
+			_, err := runDiskFaultCmd("dmsetup", "remove", dmName)
+			return err
+		})
+		device = "/dev/mapper/" + dmName
+		mountOpts = nil
+	}
+	if _, err := runDiskFaultCmd("mount", append(mountOpts, device, dbDir)...); err != nil {
+		teardown()
+		return nil, err
+	}
+	undos = append(undos, func() error {
+		_, err := runDiskFaultCmd("umount", dbDir)
+		return err
+	})
+	ln.log.Info("mounted %d byte database filesystem of node %q with %s IO latency", diskFault.MaxDBSize, nodeConfig.Name, diskFault.IOLatency)
+	return teardown, nil
+}
+
+// Creates at [imagePath] an image file of an
+// ext4 filesystem of [size] bytes
+func createDiskImage(imagePath string, size uint64) error {
+	f, err := os.Create(imagePath)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(int64(size)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if _, err := runDiskFaultCmd("mkfs.ext4", "-q", "-F", imagePath); err != nil {
+		_ = os.Remove(imagePath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/api"
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/config"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
+)
+
+const (
+	// How often the nodes' indexes are checked for new containers
+	acceptedEventsPollFreq = 500 * time.Millisecond
+	// Max number of containers fetched from an index per request
+	acceptedEventsBatchSize = 256
+)
+
+// Index --> accessor of the node API client that serves it
+var indexAPIs = map[network.Index]func(api.Client) indexer.Client{
+	network.IndexPChainBlocks:   api.Client.PChainIndexAPI,
+	network.IndexCChainBlocks:   api.Client.CChainIndexAPI,
+	network.IndexXChainTxs:      api.Client.XChainIndexAPI,
+	network.IndexXChainVertices: api.Client.XChainVertexIndexAPI,
+}
+
+// Position in a node's index of the next container to stream
+type indexCursor struct {
+	// ID of the node the position is in, so that the cursor is
+	// reset if a node with the same name but a new index replaces it
+	nodeID   ids.NodeID
+	position uint64
+}
+
+// See network.Network
+func (ln *localNetwork) AcceptedEvents(ctx context.Context, index network.Index) (<-chan network.AcceptedEvent, error) {
+	indexAPI, ok := indexAPIs[index]
+	if !ok {
+		return nil, fmt.Errorf("unknown index %q", index)
+	}
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+	if enabled, _ := ln.flags[config.IndexEnabledKey].(bool); !enabled {
+		return nil, errors.New("indexing isn't enabled on the network")
+	}
+
+	eventsCh := make(chan network.AcceptedEvent)
+	go func() {
+		defer close(eventsCh)
+		// Node name --> cursor
+		cursors := map[string]*indexCursor{}
+		for {
+			for _, event := range ln.pollAcceptedEvents(ctx, index, indexAPI, cursors) {
+				select {
+				case eventsCh <- event:
+				case <-ctx.Done():
+					return
+				case <-ln.onStopCh:
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ln.onStopCh:
+				return
+			case <-time.After(acceptedEventsPollFreq):
+			}
+		}
+	}()
+	return eventsCh, nil
+}
+
+// Returns the containers accepted in [index] by the current nodes
+// since their [cursors], in timestamp order, and advances the cursors.
+// Nodes that aren't in [cursors] are streamed from their first container.
+func (ln *localNetwork) pollAcceptedEvents(
+	ctx context.Context,
+	index network.Index,
+	indexAPI func(api.Client) indexer.Client,
+	cursors map[string]*indexCursor,
+) []network.AcceptedEvent {
+	// Node name --> index client
+	clients := map[string]indexer.Client{}
+	ln.lock.RLock()
+	for name := range cursors {
+		if _, ok := ln.nodes[name]; !ok {
+			delete(cursors, name)
+		}
+	}
+	for name, node := range ln.nodes {
+		if cursor, ok := cursors[name]; !ok || cursor.nodeID != node.nodeID {
+			cursors[name] = &indexCursor{nodeID: node.nodeID}
+		}
+		if !node.paused {
+			clients[name] = indexAPI(node.client)
+		}
+	}
+	ln.lock.RUnlock()
+
+	var (
+		lock   sync.Mutex
+		wg     sync.WaitGroup
+		events []network.AcceptedEvent
+	)
+	for name, client := range clients {
+		name, client, cursor := name, client, cursors[name]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			containers, err := client.GetContainerRange(ctx, cursor.position, acceptedEventsBatchSize)
+			if err != nil {
+				// Also returned if no container was accepted since the cursor
+				ln.log.Debug("couldn't get containers accepted by node %q from index %s: %s", name, index, err)
+				return
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			for i, container := range containers {
+				events = append(events, network.AcceptedEvent{
+					Node:        name,
+					Index:       index,
+					Position:    cursor.position + uint64(i),
+					ContainerID: container.ID,
+					Timestamp:   time.Unix(0, container.Timestamp),
+				})
+			}
+			cursor.position += uint64(len(containers))
+		}()
+	}
+	wg.Wait()
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	return events
+}
@@ -0,0 +1,146 @@
+package local
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+)
+
+const (
+	// Number of lines of a node's stderr kept for its exit status
+	stderrTailLines = 20
+	// Max number of bytes read from the end of a node's
+	// stderr file to find its last lines
+	stderrTailMaxBytes = 64 * 1024
+)
+
+// Keeps the last [size] lines written to it
+type tailBuffer struct {
+	lock  sync.Mutex
+	size  int
+	lines []string
+	// The unterminated last line written, if any
+	partial []byte
+}
+
+func newTailBuffer(size int) *tailBuffer {
+	return &tailBuffer{size: size}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	data := append(b.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		b.addLine(string(data[:i]))
+		data = data[i+1:]
+	}
+	b.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// Assumes [b.lock] is held
+func (b *tailBuffer) addLine(line string) {
+	b.lines = append(b.lines, strings.TrimSuffix(line, "\r"))
+	if len(b.lines) > b.size {
+		b.lines = b.lines[len(b.lines)-b.size:]
+	}
+}
+
+// Returns the last lines written, oldest first
+func (b *tailBuffer) Lines() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	lines := append([]string(nil), b.lines...)
+	if len(b.partial) > 0 {
+		lines = append(lines, string(b.partial))
+		if len(lines) > b.size {
+			lines = lines[1:]
+		}
+	}
+	return lines
+}
+
+// Returns the last [n] lines of the file at [path], oldest first
+func tailFile(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - stderrTailMaxBytes
+	if offset < 0 {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	// The first line may have been cut by the seek
+	if offset > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	buf := newTailBuffer(n)
+	_, _ = buf.Write(data)
+	return buf.Lines(), nil
+}
+
+// Returns how [p] exited. Must be called once [p.cmd.Wait] returned.
+func (p *nodeProcessImpl) newExitStatus() node.ExitStatus {
+	status := node.ExitStatus{
+		Time:     time.Now(),
+		ExitCode: -1,
+	}
+	if state := p.cmd.ProcessState; state != nil {
+		status.ExitCode = state.ExitCode()
+		if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			status.Signal = ws.Signal().String()
+		}
+	}
+	switch {
+	case p.stderrTail != nil:
+		status.StderrTail = p.stderrTail.Lines()
+	case p.stderrPath != "":
+		// Best effort: the tail is only informative
+		status.StderrTail, _ = tailFile(p.stderrPath, stderrTailLines)
+	}
+	return status
+}
+
+// Returns how [p] exited, and true, or false if it hasn't exited
+func (p *nodeProcessImpl) exitStatus() (node.ExitStatus, bool) {
+	p.exitLock.Lock()
+	defer p.exitLock.Unlock()
+	if p.exit == nil {
+		return node.ExitStatus{}, false
+	}
+	return *p.exit, true
+}
+
+// See node.Node
+func (n *localNode) GetExitStatus() (node.ExitStatus, bool) {
+	p, ok := n.process.(*nodeProcessImpl)
+	if !ok {
+		return node.ExitStatus{}, false
+	}
+	return p.exitStatus()
+}
@@ -0,0 +1,29 @@
+package local
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/ava-labs/avalanche-network-runner/pkg/explorer"
+)
+
+// Starts a block explorer against the APIs of the first node by name.
+// Assumes [ln.lock] is held, or not needed.
+func (ln *localNetwork) startExplorer(config explorer.Config) error {
+	if len(ln.nodes) == 0 {
+		return errors.New("network has no node")
+	}
+	nodeNames := make([]string, 0, len(ln.nodes))
+	for nodeName := range ln.nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	node := ln.nodes[nodeNames[0]]
+	e, err := explorer.Start(ln.log, ln.uuid, config, explorer.NewEndpoints(ln.networkID, node.GetURI(), config.Port))
+	if err != nil {
+		return err
+	}
+	ln.explorer = e
+	ln.log.Info("explorer of node %q listening at %s", node.name, e.URL())
+	return nil
+}
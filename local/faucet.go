@@ -0,0 +1,44 @@
+package local
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/pkg/faucet"
+	"github.com/ava-labs/avalanche-network-runner/pkg/wallet"
+)
+
+var (
+	_ faucet.Funder = (*wallet.Wallet)(nil)
+
+	errNoFaucet = errors.New("network has no faucet")
+)
+
+// See network.Network
+func (ln *localNetwork) GetFaucetURL() (string, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	if ln.stopCalled() {
+		return "", network.ErrStopped
+	}
+	if ln.faucet == nil {
+		return "", errNoFaucet
+	}
+	return ln.faucet.URL(), nil
+}
+
+// Starts a faucet that funds addresses from genesis.EWOQKey,
+// sending the txs to a node of the network.
+// Assumes [ln.lock] is held, or not needed.
+func (ln *localNetwork) startFaucet(config faucet.Config) error {
+	f, err := faucet.New(config, func(ctx context.Context) (faucet.Funder, error) {
+		return wallet.NewFromNetwork(ctx, ln)
+	})
+	if err != nil {
+		return err
+	}
+	ln.faucet = f
+	ln.log.Info("faucet listening at %s", f.URL())
+	return nil
+}
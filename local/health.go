@@ -0,0 +1,282 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/api/info"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"golang.org/x/sync/errgroup"
+)
+
+// Chains of the primary network, which every node runs
+var primaryNetworkChains = []string{"P", "X", node.CChainAlias}
+
+// See network.Network
+func (ln *localNetwork) AwaitBootstrapped(ctx context.Context, chains []string) error {
+	start := time.Now()
+	err := ln.awaitBootstrapped(ctx, chains)
+	ln.history.record(network.OpBootstrapped, strings.Join(chains, ","), start, err)
+	return err
+}
+
+func (ln *localNetwork) awaitBootstrapped(ctx context.Context, chains []string) error {
+	// The nodes are polled without holding the lock, so that
+	// the network can be changed or stopped in the meantime
+	ln.lock.RLock()
+	if ln.stopCalled() {
+		ln.lock.RUnlock()
+		return network.ErrStopped
+	}
+	nodes := make([]*localNode, 0, len(ln.nodes))
+	nodeChains := make([][]string, 0, len(ln.nodes))
+	for nodeName, node := range ln.nodes {
+		nodes = append(nodes, node)
+		nodeChains = append(nodeChains, ln.bootstrappedChains(nodeName, chains))
+	}
+	ln.lock.RUnlock()
+
+	// Derive a new context that's cancelled when Stop is called
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func(ctx context.Context) {
+		select {
+		case <-ln.onStopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}(ctx)
+
+	errGr, ctx := errgroup.WithContext(ctx)
+	for i, node := range nodes {
+		node, chains := node, nodeChains[i]
+		errGr.Go(func() error {
+			return awaitNodeBootstrapped(ctx, node, chains)
+		})
+	}
+	return errGr.Wait()
+}
+
+// Returns the chains of [chains] that [nodeName] must bootstrap, or
+// if [chains] is empty, all the chains it runs. The names of the chains
+// of the network's subnets are replaced by their IDs.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) bootstrappedChains(nodeName string, chains []string) []string {
+	if len(chains) == 0 {
+		nodeChains := append([]string(nil), primaryNetworkChains...)
+		for _, subnet := range ln.subnets {
+			if !containsString(subnet.Validators, nodeName) {
+				continue
+			}
+			for _, blockchain := range subnet.Blockchains {
+				nodeChains = append(nodeChains, blockchain.ID.String())
+			}
+		}
+		return nodeChains
+	}
+	nodeChains := make([]string, 0, len(chains))
+	for _, chain := range chains {
+		subnet, blockchain, ok := ln.findBlockchain(chain)
+		switch {
+		case !ok:
+			nodeChains = append(nodeChains, chain)
+		case containsString(subnet.Validators, nodeName):
+			nodeChains = append(nodeChains, blockchain.ID.String())
+		}
+	}
+	return nodeChains
+}
+
+// Returns the blockchain, of the subnets created from the network's config,
+// whose ID or name is [chain], and its subnet.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) findBlockchain(chain string) (network.Subnet, network.Blockchain, bool) {
+	for _, subnet := range ln.subnets {
+		for _, blockchain := range subnet.Blockchains {
+			if blockchain.ID.String() == chain || blockchain.Name == chain {
+				return subnet, blockchain, true
+			}
+		}
+	}
+	return network.Subnet{}, network.Blockchain{}, false
+}
+
+// About every [healthCheckFreq], queries [node] for whether it bootstrapped
+// each of [chains], until it has or [ctx] is done.
+func awaitNodeBootstrapped(ctx context.Context, node *localNode, chains []string) error {
+	for _, chain := range chains {
+		for {
+			bootstrapped, err := node.client.InfoAPI().IsBootstrapped(ctx, chain)
+			if err == nil && bootstrapped {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("node %q didn't bootstrap chain %s within timeout, or network stopped", node.name, chain)
+			case <-time.After(jittered(healthCheckFreq)):
+			}
+		}
+	}
+	return nil
+}
+
+// checkValidatorPeers returns nil if [node] is connected to at least
+// [ln.healthyMinValidatorPeers] validators of the primary network.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) checkValidatorPeers(ctx context.Context, node *localNode) error {
+	if ln.healthyMinValidatorPeers == 0 {
+		return nil
+	}
+	peers, err := node.client.InfoAPI().Peers(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get peers: %w", err)
+	}
+	validators, err := node.client.PChainAPI().GetCurrentValidators(ctx, constants.PrimaryNetworkID, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't get current validators: %w", err)
+	}
+	validatorIDs := ids.NewNodeIDSet(len(validators))
+	for _, validator := range validators {
+		validatorIDs.Add(validator.NodeID)
+	}
+	numValidatorPeers := 0
+	for _, peer := range peers {
+		if validatorIDs.Contains(peer.ID) {
+			numValidatorPeers++
+		}
+	}
+	if numValidatorPeers < int(ln.healthyMinValidatorPeers) {
+		return fmt.Errorf("connected to %d validators, want at least %d", numValidatorPeers, ln.healthyMinValidatorPeers)
+	}
+	return nil
+}
+
+// Runs the health checks of [checks] that apply to [node], and returns
+// the failures of those it doesn't pass, by check name. A check that
+// panics fails.
+func runHealthChecks(ctx context.Context, node *localNode, checks []network.HealthCheck) map[string]network.HealthCheckFailure {
+	var failures map[string]network.HealthCheckFailure
+	for _, check := range checks {
+		if !check.AppliesTo(node.name) {
+			continue
+		}
+		if err := runHealthCheck(ctx, node, check); err != nil {
+			if failures == nil {
+				failures = map[string]network.HealthCheckFailure{}
+			}
+			failures[check.Name] = network.HealthCheckFailure{Error: err.Error()}
+		}
+	}
+	return failures
+}
+
+// Runs [check] on [node]. Returns an error if it panics.
+func runHealthCheck(ctx context.Context, node *localNode, check network.HealthCheck) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("check panicked: %v", r)
+		}
+	}()
+	return check.Check(ctx, node)
+}
+
+// See network.Network
+func (ln *localNetwork) AwaitFullMesh(ctx context.Context) error {
+	start := time.Now()
+	err := ln.awaitFullMesh(ctx)
+	ln.history.record(network.OpAwaitFullMesh, "", start, err)
+	return err
+}
+
+func (ln *localNetwork) awaitFullMesh(ctx context.Context) error {
+	// The nodes are polled without holding the lock, so that
+	// the network can be changed or stopped in the meantime
+	ln.lock.RLock()
+	if ln.stopCalled() {
+		ln.lock.RUnlock()
+		return network.ErrStopped
+	}
+	nodeNames := ln.nodeNamesByID()
+	nodes := make([]*localNode, 0, len(ln.nodes))
+	for _, node := range ln.nodes {
+		nodes = append(nodes, node)
+	}
+	ln.lock.RUnlock()
+
+	// Derive a new context that's cancelled when Stop is called
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func(ctx context.Context) {
+		select {
+		case <-ln.onStopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}(ctx)
+
+	errGr, ctx := errgroup.WithContext(ctx)
+	for _, node := range nodes {
+		node := node
+		errGr.Go(func() error {
+			for {
+				err := checkFullMesh(ctx, node, nodeNames)
+				if err == nil {
+					return nil
+				}
+				select {
+				case <-ctx.Done():
+					return fmt.Errorf("node %q %s within timeout, or network stopped", node.name, err)
+				case <-time.After(jittered(healthCheckFreq)):
+				}
+			}
+		})
+	}
+	return errGr.Wait()
+}
+
+// Returns node ID --> name of the network's nodes.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) nodeNamesByID() map[ids.NodeID]string {
+	nodeNames := make(map[ids.NodeID]string, len(ln.nodes))
+	for nodeName, node := range ln.nodes {
+		nodeNames[node.nodeID] = nodeName
+	}
+	return nodeNames
+}
+
+// checkFullMesh returns nil if [node] is connected
+// to every other node of [nodeNames] (node ID --> name)
+func checkFullMesh(ctx context.Context, node *localNode, nodeNames map[ids.NodeID]string) error {
+	peers, err := node.client.InfoAPI().Peers(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get peers: %w", err)
+	}
+	missing := missingPeers(node, peers, nodeNames)
+	if len(missing) > 0 {
+		return fmt.Errorf("isn't connected to %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Returns the sorted names of the nodes of [nodeNames]
+// (node ID --> name), other than [node], not among [peers]
+func missingPeers(node *localNode, peers []info.Peer, nodeNames map[ids.NodeID]string) []string {
+	connected := ids.NewNodeIDSet(len(peers))
+	for _, peer := range peers {
+		connected.Add(peer.ID)
+	}
+	var missing []string
+	for nodeID, nodeName := range nodeNames {
+		if nodeID != node.nodeID && !connected.Contains(nodeID) {
+			missing = append(missing, nodeName)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,95 @@
+package local
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+// operationHistory keeps an in-memory record of the operations
+// done on a network, optionally persisting it to a file
+type operationHistory struct {
+	lock sync.Mutex
+	log  logging.Logger
+	ops  []network.Operation
+	// If non-empty, each operation is appended to the file
+	// at this path as a JSON line
+	path string
+}
+
+func newOperationHistory(log logging.Logger) *operationHistory {
+	return &operationHistory{log: log}
+}
+
+// setPath sets the file operations are persisted to.
+// Operations already recorded are written to it.
+func (h *operationHistory) setPath(path string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.path = path
+	for _, op := range h.ops {
+		h.persist(op)
+	}
+}
+
+// record adds an operation named [name], done on [target],
+// which started at [start] and returned [err]
+func (h *operationHistory) record(name string, target string, start time.Time, err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	op := network.Operation{
+		Name:     name,
+		Target:   target,
+		Start:    start,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		op.Err = err.Error()
+	}
+	h.ops = append(h.ops, op)
+	h.persist(op)
+}
+
+// Assumes [h.lock] is held
+func (h *operationHistory) persist(op network.Operation) {
+	if h.path == "" {
+		return
+	}
+	opJSON, err := json.Marshal(op)
+	if err != nil {
+		h.log.Warn("couldn't marshal operation %q: %s", op.Name, err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o750); err != nil {
+		h.log.Warn("couldn't create history dir: %s", err)
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		h.log.Warn("couldn't open history file %q: %s", h.path, err)
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if _, err := f.Write(append(opJSON, '\n')); err != nil {
+		h.log.Warn("couldn't write history file %q: %s", h.path, err)
+	}
+}
+
+// get returns a copy of the recorded operations, oldest first
+func (h *operationHistory) get() []network.Operation {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	ops := make([]network.Operation, len(h.ops))
+	copy(ops, h.ops)
+	return ops
+}
@@ -0,0 +1,46 @@
+package local
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanche-network-runner/pkg/hosts"
+)
+
+var (
+	_ network.HostsRegistry = (*hosts.File)(nil)
+
+	// A host name label, see RFC 1123
+	hostnameLabelRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+)
+
+// Registers the host name of the node with [nodeConfig], if
+// the network has a hosts registry, and returns the host name.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) registerHostname(nodeConfig *node.Config) (string, error) {
+	if ln.hostsRegistry == nil {
+		return "", nil
+	}
+	if !hostnameLabelRegex.MatchString(nodeConfig.Name) {
+		return "", fmt.Errorf("node name %q isn't a valid host name", nodeConfig.Name)
+	}
+	hostname := nodeConfig.Name + "." + ln.hostsDomain
+	ip := node.ParseIP(apiIP(nodeConfig.BindAddr))
+	if err := ln.hostsRegistry.Register(hostname, ip); err != nil {
+		return "", fmt.Errorf("couldn't register host name %s: %w", hostname, err)
+	}
+	return hostname, nil
+}
+
+// Unregisters [hostname], if non-empty. Errors are logged.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) unregisterHostname(hostname string) {
+	if hostname == "" {
+		return
+	}
+	if err := ln.hostsRegistry.Unregister(hostname); err != nil {
+		ln.log.Warn("couldn't unregister host name %s: %s", hostname, err)
+	}
+}
@@ -0,0 +1,43 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ava-labs/avalanche-network-runner/api"
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"golang.org/x/sync/errgroup"
+)
+
+// See network.Network
+func (ln *localNetwork) SeedKeystoreUsers(ctx context.Context, users []api.KeystoreUser) error {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	if ln.stopCalled() {
+		return network.ErrStopped
+	}
+	nodeNames := make([]string, 0, len(ln.nodes))
+	for nodeName := range ln.nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	errGr, ctx := errgroup.WithContext(ctx)
+	for _, nodeName := range nodeNames {
+		nodeName := nodeName
+		node := ln.nodes[nodeName]
+		errGr.Go(func() error {
+			for _, user := range users {
+				if err := api.CreateKeystoreUser(ctx, node.client, user); err != nil {
+					return fmt.Errorf("node %q: %w", nodeName, err)
+				}
+			}
+			return nil
+		})
+	}
+	if err := errGr.Wait(); err != nil {
+		return err
+	}
+	ln.log.Info("seeded %d keystore users on %d nodes", len(users), len(nodeNames))
+	return nil
+}
@@ -0,0 +1,228 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+const (
+	// How often log files are checked for new lines when following
+	logPollFreq = 250 * time.Millisecond
+	// When following, lines are held back for this long so that
+	// lines written around the same time by different nodes
+	// are returned in timestamp order
+	logReorderWindow = time.Second
+	// Timestamp format of avalanchego plain log lines
+	logTimeFormat = "[01-02|15:04:05.000]"
+)
+
+// See network.Network
+func (ln *localNetwork) AggregatedLogs(ctx context.Context, filter network.LogFilter) <-chan network.LogLine {
+	linesCh := make(chan network.LogLine)
+
+	// Node name --> logs dir
+	logsDirs := map[string]string{}
+	ln.lock.RLock()
+	for name, node := range ln.nodes {
+		if len(filter.Nodes) == 0 || containsString(filter.Nodes, name) {
+			logsDirs[name] = node.GetLogsDir()
+		}
+	}
+	ln.lock.RUnlock()
+
+	go func() {
+		defer close(linesCh)
+		// Log file path --> tailer
+		tailers := map[string]*logTailer{}
+		var pending []network.LogLine
+		for {
+			// Pick up log files created since the last iteration
+			for nodeName, logsDir := range logsDirs {
+				paths, err := filepath.Glob(filepath.Join(logsDir, "*.log"))
+				if err != nil {
+					ln.log.Debug("couldn't list logs of node %q: %s", nodeName, err)
+					continue
+				}
+				for _, path := range paths {
+					if _, ok := tailers[path]; !ok {
+						tailers[path] = &logTailer{node: nodeName, path: path}
+					}
+				}
+			}
+			for _, tailer := range tailers {
+				lines, err := tailer.readLines()
+				if err != nil {
+					ln.log.Debug("couldn't read log file %q: %s", tailer.path, err)
+				}
+				for _, line := range lines {
+					if filter.MinLevel != 0 && line.Level < filter.MinLevel {
+						continue
+					}
+					if filter.Regex != nil && !filter.Regex.MatchString(line.Line) {
+						continue
+					}
+					pending = append(pending, line)
+				}
+			}
+			sort.SliceStable(pending, func(i, j int) bool {
+				return pending[i].Timestamp.Before(pending[j].Timestamp)
+			})
+
+			// Send the lines that can't be preceded by lines not read yet
+			numReady := len(pending)
+			if filter.Follow {
+				cutoff := time.Now().Add(-logReorderWindow)
+				numReady = sort.Search(len(pending), func(i int) bool {
+					return pending[i].Timestamp.After(cutoff)
+				})
+			}
+			for _, line := range pending[:numReady] {
+				select {
+				case linesCh <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			pending = pending[numReady:]
+
+			if !filter.Follow {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(logPollFreq):
+			}
+		}
+	}()
+	return linesCh
+}
+
+// logTailer reads the lines appended to a log file
+type logTailer struct {
+	node string
+	path string
+	// Number of bytes of the file already read
+	offset int64
+	// Incomplete last line read from the file
+	partial []byte
+	// Timestamp and level of the last log entry read
+	lastTimestamp time.Time
+	lastLevel     logging.Level
+}
+
+// Returns the complete lines written to the log file since the last call
+func (t *logTailer) readLines() ([]network.LogLine, error) {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < t.offset {
+		// The file was rotated. Start over.
+		t.offset = 0
+		t.partial = nil
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	t.offset += int64(len(data))
+	data = append(t.partial, data...)
+
+	var lines []network.LogLine
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSuffix(string(data[:i]), "\r")
+		data = data[i+1:]
+		if line == "" {
+			continue
+		}
+		if timestamp, level, ok := parseLogLine(line); ok {
+			t.lastTimestamp = timestamp
+			t.lastLevel = level
+		}
+		lines = append(lines, network.LogLine{
+			Node:      t.node,
+			File:      filepath.Base(t.path),
+			Timestamp: t.lastTimestamp,
+			Level:     t.lastLevel,
+			Line:      line,
+		})
+	}
+	t.partial = append([]byte(nil), data...)
+	return lines, nil
+}
+
+// Returns the timestamp and level of the avalanchego log entry [line],
+// which may be in plain or JSON format.
+// Returns false if [line] isn't the start of a log entry.
+func parseLogLine(line string) (time.Time, logging.Level, bool) {
+	if strings.HasPrefix(line, "{") {
+		var entry struct {
+			Timestamp time.Time `json:"timestamp"`
+			Level     string    `json:"level"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return time.Time{}, 0, false
+		}
+		level, err := logging.ToLevel(entry.Level)
+		if err != nil {
+			return time.Time{}, 0, false
+		}
+		return entry.Timestamp, level, true
+	}
+
+	// e.g. "[06-01|12:00:00.000] INFO <C Chain> vm.go:123 message"
+	if len(line) < len(logTimeFormat)+1 {
+		return time.Time{}, 0, false
+	}
+	timestamp, err := time.ParseInLocation(logTimeFormat, line[:len(logTimeFormat)], time.Local)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	// The year isn't logged. Assume the entry was written in the last year.
+	now := time.Now()
+	timestamp = timestamp.AddDate(now.Year(), 0, 0)
+	if timestamp.After(now.Add(24 * time.Hour)) {
+		timestamp = timestamp.AddDate(-1, 0, 0)
+	}
+	fields := strings.Fields(line[len(logTimeFormat):])
+	if len(fields) == 0 {
+		return time.Time{}, 0, false
+	}
+	level, err := logging.ToLevel(fields[0])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return timestamp, level, true
+}
+
+func containsString(s []string, e string) bool {
+	for _, elt := range s {
+		if elt == e {
+			return true
+		}
+	}
+	return false
+}
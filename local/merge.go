@@ -0,0 +1,121 @@
+package local
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/config"
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+// A node's config is merged from layers. From highest to lowest
+// precedence, a flag is taken from:
+// 1. the node's config (node.Config.Flags)
+// 2. the network's config (network.Config.Flags)
+// 3. the node's config file (node.Config.ConfigFile)
+// A chain's or subnet's config file given in the node's config takes
+// precedence over the one given in the network's config for the same
+// chain or subnet. The runner writes these files to dirs of its own, so
+// the chain and subnet config dir flags can't be given in any layer
+// when there are files to write to them.
+
+// The part of a network's config that's merged
+// into the config of each of its nodes
+type networkConfigLayer struct {
+	flags             map[string]interface{}
+	chainConfigFiles  map[string]string
+	subnetConfigFiles map[string]string
+}
+
+// Merges this layer into [nodeConfig]. The maps of [nodeConfig]
+// are replaced rather than modified, since they may be shared.
+func (l networkConfigLayer) apply(log logging.Logger, nodeConfig *node.Config) {
+	flags := make(map[string]interface{}, len(l.flags)+len(nodeConfig.Flags))
+	for flagName, flagVal := range l.flags {
+		if val, ok := nodeConfig.Flags[flagName]; ok {
+			log.Info(
+				"not overwriting node config flag %s (value %v) with network config flag (value %v)",
+				flagName, val, flagVal,
+			)
+			continue
+		}
+		flags[flagName] = flagVal
+	}
+	for flagName, flagVal := range nodeConfig.Flags {
+		flags[flagName] = flagVal
+	}
+	nodeConfig.Flags = flags
+
+	chainConfigFiles := l.chainConfigFiles
+	if len(nodeConfig.CChainConfigFile) != 0 {
+		// The node's C-Chain config file must be given once
+		chainConfigFiles = make(map[string]string, len(l.chainConfigFiles))
+		for chain, contents := range l.chainConfigFiles {
+			if chain != node.CChainAlias {
+				chainConfigFiles[chain] = contents
+			}
+		}
+	}
+	nodeConfig.ChainConfigFiles = mergeConfigFiles(chainConfigFiles, nodeConfig.ChainConfigFiles)
+	nodeConfig.SubnetConfigFiles = mergeConfigFiles(l.subnetConfigFiles, nodeConfig.SubnetConfigFiles)
+}
+
+// Returns the config files in [networkFiles] and [nodeFiles].
+// Files in [nodeFiles] take precedence.
+func mergeConfigFiles(networkFiles map[string]string, nodeFiles map[string]string) map[string]string {
+	if len(networkFiles) == 0 {
+		return nodeFiles
+	}
+	merged := make(map[string]string, len(networkFiles)+len(nodeFiles))
+	for name, contents := range networkFiles {
+		merged[name] = contents
+	}
+	for name, contents := range nodeFiles {
+		merged[name] = contents
+	}
+	return merged
+}
+
+// The flags of a node merged from all the layers of its config.
+// Values from the config file are as decoded from JSON.
+type mergedFlags map[string]interface{}
+
+// Returns the flags of [nodeConfig], which the network's layer
+// was applied to, merged with the flags of its config file.
+// Returns an error if the config file isn't a JSON object, or if a
+// flag conflicts with the config dirs the runner manages.
+func mergeConfigFile(nodeConfig node.Config) (mergedFlags, error) {
+	flags := mergedFlags{}
+	if len(nodeConfig.ConfigFile) != 0 {
+		if err := json.Unmarshal([]byte(nodeConfig.ConfigFile), &flags); err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal config file: %w", err)
+		}
+	}
+	for flagName, flagVal := range nodeConfig.Flags {
+		flags[flagName] = flagVal
+	}
+	hasChainFiles := len(nodeConfig.CChainConfigFile) != 0 ||
+		len(nodeConfig.ChainConfigFiles) != 0 ||
+		len(nodeConfig.UpgradeConfigFiles) != 0
+	if _, ok := flags[config.ChainConfigDirKey]; ok && hasChainFiles {
+		return nil, fmt.Errorf("flag %q can't be given with chain config files, which the runner writes to its own dir", config.ChainConfigDirKey)
+	}
+	if _, ok := flags[config.SubnetConfigDirKey]; ok && len(nodeConfig.SubnetConfigFiles) != 0 {
+		return nil, fmt.Errorf("flag %q can't be given with subnet config files, which the runner writes to its own dir", config.SubnetConfigDirKey)
+	}
+	return flags, nil
+}
+
+// Returns the value of string flag [key], or [defaultVal] if it isn't set
+func (f mergedFlags) stringFlag(key string, defaultVal string) (string, error) {
+	val, ok := f[key]
+	if !ok {
+		return defaultVal, nil
+	}
+	entry, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("expected flag %q to be string but got %T", key, val)
+	}
+	return entry, nil
+}
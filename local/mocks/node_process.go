@@ -2,13 +2,61 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+
+	os "os"
+)
 
 // NodeProcess is an autogenerated mock type for the NodeProcess type
 type NodeProcess struct {
 	mock.Mock
 }
 
+// Pause provides a mock function with given fields:
+func (_m *NodeProcess) Pause() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Resume provides a mock function with given fields:
+func (_m *NodeProcess) Resume() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Signal provides a mock function with given fields: sig
+func (_m *NodeProcess) Signal(sig os.Signal) error {
+	ret := _m.Called(sig)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(os.Signal) error); ok {
+		r0 = rf(sig)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Start provides a mock function with given fields:
 func (_m *NodeProcess) Start() error {
 	ret := _m.Called()
@@ -23,6 +71,22 @@ func (_m *NodeProcess) Start() error {
 	return r0
 }
 
+// Stdin provides a mock function with given fields:
+func (_m *NodeProcess) Stdin() io.WriteCloser {
+	ret := _m.Called()
+
+	var r0 io.WriteCloser
+	if rf, ok := ret.Get(0).(func() io.WriteCloser); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(io.WriteCloser)
+		}
+	}
+
+	return r0
+}
+
 // Stop provides a mock function with given fields:
 func (_m *NodeProcess) Stop() error {
 	ret := _m.Called()
@@ -2,17 +2,21 @@ package local
 
 import (
 	"context"
+	"crypto/x509"
 	"embed"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
-	"net"
+	"math/rand"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -20,8 +24,18 @@ import (
 	"github.com/ava-labs/avalanche-network-runner/api"
 	"github.com/ava-labs/avalanche-network-runner/network"
 	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanche-network-runner/pkg/binutils"
+	"github.com/ava-labs/avalanche-network-runner/pkg/explorer"
+	"github.com/ava-labs/avalanche-network-runner/pkg/faucet"
+	"github.com/ava-labs/avalanche-network-runner/pkg/observability"
+	"github.com/ava-labs/avalanche-network-runner/pkg/webhook"
 	"github.com/ava-labs/avalanche-network-runner/utils"
+	"github.com/ava-labs/avalanchego/api/health"
 	"github.com/ava-labs/avalanchego/config"
+	avagenesis "github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/network/peer"
+	"github.com/ava-labs/avalanchego/snow/networking/router"
 	"github.com/ava-labs/avalanchego/staking"
 	"github.com/ava-labs/avalanchego/utils/beacon"
 	"github.com/ava-labs/avalanchego/utils/constants"
@@ -34,18 +48,36 @@ import (
 )
 
 const (
-	defaultNodeNamePrefix = "node"
-	configFileName        = "config.json"
-	stakingKeyFileName    = "staking.key"
-	stakingCertFileName   = "staking.crt"
-	genesisFileName       = "genesis.json"
-	stopTimeout           = 30 * time.Second
-	healthCheckFreq       = 3 * time.Second
-	DefaultNumNodes       = 5
-	snapshotPrefix        = "anr-snapshot-"
-	rootDirPrefix         = "avalanche-network-runner-"
-	defaultDbSubdir       = "db"
-	defaultLogsSubdir     = "logs"
+	defaultNodeNamePrefix   = "node"
+	configFileName          = "config.json"
+	stakingKeyFileName      = "staking.key"
+	stakingCertFileName     = "staking.crt"
+	apiTLSKeyFileName       = "api-tls.key"
+	apiTLSCertFileName      = "api-tls.crt"
+	apiAuthPasswordFileName = "api-auth-password"
+	genesisFileName         = "genesis.json"
+	upgradeFileName         = "upgrade.json"
+	stopTimeout             = 30 * time.Second
+	healthCheckFreq         = 3 * time.Second
+	healthCheckJitter       = 0.2
+	DefaultNumNodes         = 5
+	snapshotPrefix          = "anr-snapshot-"
+	rootDirPrefix           = "avalanche-network-runner-"
+	defaultDbSubdir         = "db"
+	defaultLogsSubdir       = "logs"
+	// IP nodes listen on and advertise if not given in their config
+	defaultNodeIP = "127.0.0.1"
+	// API port of the first default node. The default
+	// nodes' API ports are 2 apart (9650, 9652, ...)
+	defaultBaseAPIPort = 9650
+	// Max number of health API requests in flight
+	// if not given in the network's config
+	defaultHealthCheckParallelism = 10
+	// Max number of nodes started at a time if not given
+	// in the network's config, and no start delay is given
+	defaultNodeStartParallelism = 8
+	// Max number of nodes stopped at a time
+	nodeStopParallelism = 8
 )
 
 // interface compliance
@@ -59,7 +91,8 @@ var (
 		config.BootstrapIDsKey: {},
 	}
 	chainConfigSubDir  = "chainConfigs"
-	cChainConfigSubDir = filepath.Join(chainConfigSubDir, "C")
+	subnetConfigSubDir = "subnetConfigs"
+	cChainConfigSubDir = filepath.Join(chainConfigSubDir, node.CChainAlias)
 
 	snapshotsRelPath = filepath.Join(".avalanche-network-runner", "snapshots")
 )
@@ -93,6 +126,79 @@ type localNetwork struct {
 	flags map[string]interface{}
 	// directory where networks can be persistently saved
 	snapshotsDir string
+	// Operations done on this network
+	history *operationHistory
+	// Minimum number of validators a node must be
+	// connected to in order to be considered healthy
+	healthyMinValidatorPeers uint32
+	// If true, a node must be connected to every
+	// other node in order to be considered healthy
+	healthyRequireFullMesh bool
+	// Bounds the number of health API requests in flight
+	healthCheckSem chan struct{}
+	// Chain and subnet config files written for every
+	// node, unless overridden by the node's config
+	chainConfigFiles  map[string]string
+	subnetConfigFiles map[string]string
+	// Source of randomness for port assignment.
+	// Seeded with the config's random seed, if any.
+	rng *rand.Rand
+	// Node name --> resources used by the last node
+	// with that name that ran in this network.
+	// Kept after the node is removed so that
+	// teardown can be verified.
+	manifest map[string]*nodeManifest
+	// Called on the config of each node before it's started
+	beforeNodeStartHooks []func(*node.Config) error
+	// Names of the nodes whose BeforeNodeStart hooks are running
+	startingNodes map[string]struct{}
+	// Conditions nodes must meet to be healthy, on top of their Health API
+	healthChecks []network.HealthCheck
+	// Node name --> test peers attached to that node
+	attachedPeers map[string][]peer.Peer
+	// Subnets created from the config's subnet specs
+	subnets []network.Subnet
+	// VM binaries installed in the plugin dir of every node
+	customVMs []network.CustomVM
+	// If non-nil, closed once the resource usage sampler
+	// has closed its file after Stop is called
+	resourceSamplerDone chan struct{}
+	// What's done with the node dirs when the network is stopped
+	dataDirCleanup network.DataDirCleanup
+	// True if the node dirs were deleted when the network was stopped
+	dataDirsDeleted bool
+	// HTTP requests of the nodes' API clients go through this middleware
+	apiMiddleware []api.Middleware
+	// Called on the lifecycle events of the network and its nodes
+	hooks network.Hooks
+	// Funds addresses on request, if the network's config has a faucet
+	faucet *faucet.Faucet
+	// Block explorer of the network, if its config has one
+	explorer *explorer.Explorer
+	// Posts the network's events, if its config has webhooks
+	webhooks *webhook.Notifier
+	// Ensures the network_healthy webhook event is posted once
+	webhooksHealthyOnce sync.Once
+	// Scrapes the nodes' metrics, if the network's config has observability
+	observability *observability.Stack
+	// If non-empty, the artifacts are written here on Stop
+	artifactsPath string
+	// If non-nil, node host names are registered in it
+	hostsRegistry network.HostsRegistry
+	hostsDomain   string
+	// If non-nil, applied to the C-Chain config of every node
+	stateSync *network.StateSyncConfig
+	// Binary path --> version, as printed by the binary
+	binaryVersions map[string]versionCacheEntry
+	// If non-nil, the startup timings of the nodes are recorded in it
+	timings *timingRecorder
+	// ID of the network in the run registry. See RegistryDir.
+	uuid string
+	// True if the network is in the run registry
+	registered bool
+	// Config the network was loaded with, with the genesis
+	// mutators applied. The base of the config of its clones.
+	config network.Config
 }
 
 var (
@@ -160,7 +266,8 @@ func init() {
 	}
 }
 
-// NodeProcessCreator is an interface for new node process creation
+// NodeProcessCreator is an interface for new node process creation.
+// NewNodeProcess may be called concurrently, as nodes are started in parallel.
 type NodeProcessCreator interface {
 	NewNodeProcess(config node.Config, args ...string) (NodeProcess, error)
 }
@@ -182,7 +289,45 @@ type nodeProcessCreator struct {
 // the output will be redirected and colored
 func (npc *nodeProcessCreator) NewNodeProcess(config node.Config, args ...string) (NodeProcess, error) {
 	// Start the AvalancheGo node and pass it the flags defined above
-	cmd := exec.Command(config.BinaryPath, args...)
+	wrapper, err := priorityWrapper(config)
+	if err != nil {
+		return nil, err
+	}
+	var cmd *exec.Cmd
+	if len(wrapper) > 0 {
+		cmd = exec.Command(wrapper[0], append(append(wrapper[1:], config.BinaryPath), args...)...)
+	} else {
+		cmd = exec.Command(config.BinaryPath, args...)
+	}
+	if len(config.Env) != 0 {
+		cmd.Env = append(os.Environ(), config.Env...)
+	}
+	cmd.Dir = config.WorkingDir
+	setProcessGroup(cmd)
+	process := &nodeProcessImpl{cmd: cmd}
+	// Optionally write stdout and stderr to files
+	if config.StdoutPath != "" {
+		f, err := openOutputFile(config.StdoutPath)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open stdout file: %w", err)
+		}
+		cmd.Stdout = f
+		process.outputFiles = append(process.outputFiles, f)
+	}
+	if config.StderrPath != "" {
+		if config.StderrPath == config.StdoutPath {
+			cmd.Stderr = cmd.Stdout
+		} else {
+			f, err := openOutputFile(config.StderrPath)
+			if err != nil {
+				process.closeOutputFiles()
+				return nil, fmt.Errorf("couldn't open stderr file: %w", err)
+			}
+			cmd.Stderr = f
+			process.outputFiles = append(process.outputFiles, f)
+		}
+		process.stderrPath = config.StderrPath
+	}
 	// assign a new color to this process (might not be used if the config isn't set for it)
 	color := npc.colorPicker.NextColor()
 	// Optionally redirect stdout and stderr
@@ -194,15 +339,36 @@ func (npc *nodeProcessCreator) NewNodeProcess(config node.Config, args ...string
 		// redirect stdout and assign a color to the text
 		utils.ColorAndPrepend(stdout, npc.stdout, config.Name, color)
 	}
+	if config.StderrPath == "" {
+		// Keep the last lines of stderr for the node's exit status.
+		// The tail is written to by the goroutine copying the process's
+		// stderr, which Wait waits for, so it's complete once Wait returns.
+		process.stderrTail = newTailBuffer(stderrTailLines)
+		cmd.Stderr = process.stderrTail
+	}
 	if config.RedirectStderr {
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			return nil, fmt.Errorf("couldn't create stderr pipe: %s", err)
-		}
 		// redirect stderr and assign a color to the text
+		stderr, stderrWriter := io.Pipe()
+		process.stderrWriter = stderrWriter
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, stderrWriter)
 		utils.ColorAndPrepend(stderr, npc.stderr, config.Name, color)
 	}
-	return &nodeProcessImpl{cmd: cmd}, nil
+	if config.Stdin {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create stdin pipe: %s", err)
+		}
+		process.stdin = stdin
+	}
+	return process, nil
+}
+
+// Opens the file at [path] for appending, creating it and its dir if needed
+func openOutputFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
 }
 
 // NewNetwork returns a new network that uses the given log.
@@ -233,6 +399,24 @@ func NewNetwork(
 	return net, net.loadConfig(context.Background(), networkConfig)
 }
 
+// NewNetworkWithProcessCreator is like NewNetwork, but the processes of
+// the nodes are created by [nodeProcessCreator], e.g. to run them on
+// other hosts (see package remote). The runner still writes the nodes'
+// files under [rootDir], on this host.
+func NewNetworkWithProcessCreator(
+	log logging.Logger,
+	networkConfig network.Config,
+	rootDir string,
+	snapshotsDir string,
+	nodeProcessCreator NodeProcessCreator,
+) (network.Network, error) {
+	net, err := newNetwork(log, api.NewAPIClient, nodeProcessCreator, rootDir, snapshotsDir)
+	if err != nil {
+		return net, err
+	}
+	return net, net.loadConfig(context.Background(), networkConfig)
+}
+
 // See NewNetwork.
 // [newAPIClientF] is used to create new API clients.
 // [nodeProcessCreator] is used to launch new avalanchego processes.
@@ -253,10 +437,20 @@ func newNetwork(
 	if snapshotsDir == "" {
 		snapshotsDir = defaultSnapshotsDir
 	}
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
 	// Create the network
 	net := &localNetwork{
+		uuid:               uuid,
 		nextNodeSuffix:     1,
+		rng:                utils.NewRand(0),
 		nodes:              map[string]*localNode{},
+		manifest:           map[string]*nodeManifest{},
+		startingNodes:      map[string]struct{}{},
+		attachedPeers:      map[string][]peer.Peer{},
+		binaryVersions:     map[string]versionCacheEntry{},
 		onStopCh:           make(chan struct{}),
 		log:                log,
 		bootstraps:         beacon.NewSet(),
@@ -264,6 +458,8 @@ func newNetwork(
 		nodeProcessCreator: nodeProcessCreator,
 		rootDir:            rootDir,
 		snapshotsDir:       snapshotsDir,
+		history:            newOperationHistory(log),
+		healthCheckSem:     make(chan struct{}, defaultHealthCheckParallelism),
 	}
 	return net, nil
 }
@@ -332,22 +528,160 @@ func NewDefaultConfig(binaryPath string) network.Config {
 	return config
 }
 
+// NewDefaultConfigFromRelease is like NewDefaultConfig, except that the
+// nodes run the avalanchego release [version] (e.g. v1.7.11 or v1.7.x),
+// which is downloaded if it isn't cached already.
+// See binutils.ResolveBinaryPath.
+func NewDefaultConfigFromRelease(ctx context.Context, version string) (network.Config, error) {
+	binaryPath, err := binutils.ResolveBinaryPath(ctx, version)
+	if err != nil {
+		return network.Config{}, fmt.Errorf("couldn't get avalanchego %s: %w", version, err)
+	}
+	return NewDefaultConfig(binaryPath), nil
+}
+
+// NewDefaultConfigWithCChainGenesis creates a new default network config
+// where the C-Chain genesis is replaced by [cChainGenesis]
+func NewDefaultConfigWithCChainGenesis(binaryPath string, cChainGenesis string) (network.Config, error) {
+	config := NewDefaultConfig(binaryPath)
+	if err := config.SetCChainGenesis(cChainGenesis); err != nil {
+		return config, fmt.Errorf("couldn't set C-Chain genesis: %w", err)
+	}
+	return config, nil
+}
+
+// NewDefaultConfigWithCChainOverrides creates a new default network config
+// where [overrides] (e.g. chain ID, pre-funded accounts) are applied to the C-Chain genesis
+func NewDefaultConfigWithCChainOverrides(binaryPath string, overrides network.CChainGenesisOverrides) (network.Config, error) {
+	config := NewDefaultConfig(binaryPath)
+	if err := config.OverrideCChainGenesis(overrides); err != nil {
+		return config, fmt.Errorf("couldn't override C-Chain genesis: %w", err)
+	}
+	return config, nil
+}
+
 // NewDefaultConfigNNodes creates a new default network config, with an arbitrary number of nodes
 func NewDefaultConfigNNodes(binaryPath string, numNodes uint32) (network.Config, error) {
+	return newDefaultConfigNNodes(binaryPath, numNodes, utils.NewRand(0), staking.NewCertAndKeyBytes)
+}
+
+// NewDeterministicConfigNNodes is like NewDefaultConfigNNodes, but the staking
+// keys/certs of the extra nodes and the ports of the network are derived
+// from [seed], so that node IDs are the same on every run.
+// [seed] must be non-zero.
+func NewDeterministicConfigNNodes(binaryPath string, numNodes uint32, seed int64) (network.Config, error) {
+	if seed == 0 {
+		return network.Config{}, errors.New("random seed must be non-zero")
+	}
+	rng := utils.NewRand(seed)
+	netConfig, err := newDefaultConfigNNodes(binaryPath, numNodes, rng, func() ([]byte, []byte, error) {
+		return utils.NewDeterministicCertAndKeyBytes(rng)
+	})
+	netConfig.RandomSeed = seed
+	return netConfig, err
+}
+
+// NewDefaultConfigN creates a new default network config with [n] nodes,
+// which are all beacons and genesis validators. The first nodes are the
+// nodes of NewDefaultConfig, and the staking keys/certs of the others are
+// derived from their index, so that node IDs are the same on every run.
+// Unlike NewDefaultConfigNNodes, the genesis validators are exactly the
+// config's nodes, so networks of any size can reach consensus.
+func NewDefaultConfigN(binaryPath string, n int) (network.Config, error) {
+	if n < 1 {
+		return network.Config{}, fmt.Errorf("number of nodes must be positive, got %d", n)
+	}
+	netConfig := NewDefaultConfig(binaryPath)
+	refNodeConfig := netConfig.NodeConfigs[0]
+	for i := len(netConfig.NodeConfigs); i < n; i++ {
+		stakingCert, stakingKey, err := defaultStakingIdentity(i)
+		if err != nil {
+			return netConfig, fmt.Errorf("couldn't generate staking cert/key of node %d: %w", i, err)
+		}
+		nodeConfig := refNodeConfig
+		nodeConfig.StakingCert = string(stakingCert)
+		nodeConfig.StakingKey = string(stakingKey)
+		nodeConfig.Flags = map[string]interface{}{
+			config.HTTPPortKey: defaultBaseAPIPort + 2*i,
+		}
+		netConfig.NodeConfigs = append(netConfig.NodeConfigs, nodeConfig)
+	}
+	netConfig.NodeConfigs = netConfig.NodeConfigs[:n]
+
+	nodeIDs := make([]ids.NodeID, n)
+	for i, nodeConfig := range netConfig.NodeConfigs {
+		nodeID, err := utils.ToNodeID([]byte(nodeConfig.StakingKey), []byte(nodeConfig.StakingCert))
+		if err != nil {
+			return netConfig, fmt.Errorf("couldn't get ID of node %d: %w", i, err)
+		}
+		nodeIDs[i] = nodeID
+	}
+	genesis, err := network.UnparsedGenesisMutator(func(genesisConfig *avagenesis.UnparsedConfig) error {
+		stakers := genesisConfig.InitialStakers
+		genesisConfig.InitialStakers = make([]avagenesis.UnparsedStaker, n)
+		for i, nodeID := range nodeIDs {
+			if i < len(stakers) {
+				genesisConfig.InitialStakers[i] = stakers[i]
+				continue
+			}
+			genesisConfig.InitialStakers[i] = avagenesis.UnparsedStaker{
+				NodeID:        nodeID,
+				RewardAddress: stakers[0].RewardAddress,
+				DelegationFee: network.DefaultGenesisDelegationFee,
+			}
+		}
+		return nil
+	})([]byte(netConfig.Genesis))
+	if err != nil {
+		return netConfig, err
+	}
+	netConfig.Genesis = string(genesis)
+	return netConfig, nil
+}
+
+var (
+	defaultStakingIdentitiesLock sync.Mutex
+	// Node index --> staking cert and key of the node
+	defaultStakingIdentities = map[int][2][]byte{}
+)
+
+// Returns the staking cert and key of the [i]th node of NewDefaultConfigN,
+// for [i] beyond the nodes of the default config.
+// Identities are cached since generating them is slow.
+func defaultStakingIdentity(i int) ([]byte, []byte, error) {
+	defaultStakingIdentitiesLock.Lock()
+	defer defaultStakingIdentitiesLock.Unlock()
+	if identity, ok := defaultStakingIdentities[i]; ok {
+		return identity[0], identity[1], nil
+	}
+	stakingCert, stakingKey, err := utils.NewDeterministicCertAndKeyBytes(utils.NewRand(int64(i)))
+	if err != nil {
+		return nil, nil, err
+	}
+	defaultStakingIdentities[i] = [2][]byte{stakingCert, stakingKey}
+	return stakingCert, stakingKey, nil
+}
+
+func newDefaultConfigNNodes(
+	binaryPath string,
+	numNodes uint32,
+	rng *rand.Rand,
+	newCertAndKeyBytes func() ([]byte, []byte, error),
+) (network.Config, error) {
 	netConfig := NewDefaultConfig(binaryPath)
 	if int(numNodes) > len(netConfig.NodeConfigs) {
 		toAdd := int(numNodes) - len(netConfig.NodeConfigs)
 		refNodeConfig := netConfig.NodeConfigs[0]
 		for i := 0; i < toAdd; i++ {
 			nodeConfig := refNodeConfig
-			stakingCert, stakingKey, err := staking.NewCertAndKeyBytes()
+			stakingCert, stakingKey, err := newCertAndKeyBytes()
 			if err != nil {
 				return netConfig, fmt.Errorf("couldn't generate staking Cert/Key: %w", err)
 			}
 			nodeConfig.StakingKey = string(stakingKey)
 			nodeConfig.StakingCert = string(stakingCert)
 			// replace api port in refNodeConfig.ConfigFile
-			apiPort, err := getFreePort()
+			apiPort, err := getFreePort(rng)
 			if err != nil {
 				return netConfig, fmt.Errorf("couldn't get free API port: %w", err)
 			}
@@ -363,21 +697,109 @@ func NewDefaultConfigNNodes(binaryPath string, numNodes uint32) (network.Config,
 	return netConfig, nil
 }
 
+// Assumes [ln.lock] isn't held, as the network is
+// stopped if it can't be loaded
 func (ln *localNetwork) loadConfig(ctx context.Context, networkConfig network.Config) error {
-	if err := networkConfig.Validate(); err != nil {
+	start := time.Now()
+	err := ln.loadConfigImpl(ctx, networkConfig)
+	ln.history.record(network.OpLoadConfig, "", start, err)
+	return err
+}
+
+func (ln *localNetwork) loadConfigImpl(ctx context.Context, networkConfig network.Config) error {
+	if networkConfig.HistoryFile != "" {
+		ln.history.setPath(networkConfig.HistoryFile)
+	}
+	// Apply the mutators once, so the validated genesis is the one used
+	genesis, err := networkConfig.MutatedGenesis()
+	if err != nil {
+		return fmt.Errorf("config failed validation: %w", network.NewErrInvalidGenesis(err))
+	}
+	networkConfig.Genesis = string(genesis)
+	networkConfig.GenesisMutators = nil
+	if err := network.NewValidationError(networkConfig.Validate()); err != nil {
 		return fmt.Errorf("config failed validation: %w", err)
 	}
 	ln.log.Info("creating network with %d nodes", len(networkConfig.NodeConfigs))
 
-	ln.genesis = []byte(networkConfig.Genesis)
+	ln.config = networkConfig
+	ln.genesis = genesis
 
-	var err error
-	ln.networkID, err = utils.NetworkIDFromGenesis([]byte(networkConfig.Genesis))
+	ln.networkID, err = utils.NetworkIDFromGenesis(genesis)
 	if err != nil {
-		return fmt.Errorf("couldn't get network ID from genesis: %w", err)
+		return network.NewErrInvalidGenesis(fmt.Errorf("couldn't get network ID: %w", err))
 	}
 
 	ln.flags = networkConfig.Flags
+	if networkConfig.IndexEnabled {
+		// Copy the flags so the caller's map isn't modified
+		ln.flags = make(map[string]interface{}, len(networkConfig.Flags)+1)
+		for fk, fv := range networkConfig.Flags {
+			ln.flags[fk] = fv
+		}
+		ln.flags[config.IndexEnabledKey] = true
+	}
+	ln.chainConfigFiles = networkConfig.ChainConfigFiles
+	ln.subnetConfigFiles = networkConfig.SubnetConfigFiles
+	ln.healthyMinValidatorPeers = networkConfig.HealthyMinValidatorPeers
+	ln.healthyRequireFullMesh = networkConfig.HealthyRequireFullMesh
+	if networkConfig.HealthCheckParallelism > 0 {
+		ln.healthCheckSem = make(chan struct{}, networkConfig.HealthCheckParallelism)
+	}
+	ln.beforeNodeStartHooks = append(ln.beforeNodeStartHooks, networkConfig.BeforeNodeStart...)
+	ln.healthChecks = append(ln.healthChecks, networkConfig.HealthChecks...)
+	ln.customVMs = networkConfig.CustomVMs
+	ln.dataDirCleanup = networkConfig.DataDirCleanup
+	ln.apiMiddleware = networkConfig.APIMiddleware
+	ln.hooks = networkConfig.Hooks
+	ln.artifactsPath = networkConfig.ArtifactsPath
+	ln.hostsRegistry = networkConfig.HostsRegistry
+	ln.stateSync = networkConfig.StateSync
+	if networkConfig.RecordTimings {
+		ln.timings = newTimingRecorder()
+	}
+	ln.hostsDomain = networkConfig.HostsDomain
+	if ln.hostsDomain == "" {
+		ln.hostsDomain = network.DefaultHostsDomain
+	}
+	if networkConfig.RootDataDir != "" {
+		if err := os.MkdirAll(networkConfig.RootDataDir, 0o755); err != nil {
+			return fmt.Errorf("couldn't create root data dir: %w", err)
+		}
+		ln.rootDir = networkConfig.RootDataDir
+	}
+	if networkConfig.RandomSeed != 0 {
+		ln.rng = utils.NewRand(networkConfig.RandomSeed)
+	}
+	if networkConfig.ResourceUsageInterval > 0 && networkConfig.ResourceUsageFile != "" {
+		w, err := newResourceSampleWriter(networkConfig.ResourceUsageFile)
+		if err != nil {
+			return fmt.Errorf("couldn't create resource usage file: %w", err)
+		}
+		ln.resourceSamplerDone = make(chan struct{})
+		go ln.sampleResourceUsage(networkConfig.ResourceUsageInterval, w, ln.resourceSamplerDone)
+	}
+
+	// Started before the nodes, so that it scrapes them from the start
+	if networkConfig.Observability != nil {
+		if err := ln.startObservability(*networkConfig.Observability); err != nil {
+			// Stops the resource sampler too
+			if err := ln.Stop(ctx); err != nil {
+				ln.log.Debug("error stopping network: %s", err)
+			}
+			return fmt.Errorf("couldn't start observability: %w", err)
+		}
+	}
+
+	// Started before the nodes, so that their events are posted
+	if networkConfig.Webhooks != nil {
+		if err := ln.startWebhooks(*networkConfig.Webhooks); err != nil {
+			if err := ln.Stop(ctx); err != nil {
+				ln.log.Debug("error stopping network: %s", err)
+			}
+			return fmt.Errorf("couldn't start webhooks: %w", err)
+		}
+	}
 
 	// Sort node configs so beacons start first
 	var nodeConfigs []node.Config
@@ -392,16 +814,42 @@ func (ln *localNetwork) loadConfig(ctx context.Context, networkConfig network.Co
 		}
 	}
 
-	for _, nodeConfig := range nodeConfigs {
-		if _, err := ln.addNode(nodeConfig); err != nil {
-			if err := ln.stop(ctx); err != nil {
-				// Clean up nodes already created
+	if err := ln.startNodes(ctx, nodeConfigs, networkConfig); err != nil {
+		if err := ln.Stop(ctx); err != nil {
+			// Clean up nodes already created
+			ln.log.Debug("error stopping network: %s", err)
+		}
+		return err
+	}
+
+	if len(networkConfig.SubnetSpecs) > 0 {
+		if err := ln.createSubnets(ctx, networkConfig.SubnetSpecs); err != nil {
+			if err := ln.Stop(ctx); err != nil {
 				ln.log.Debug("error stopping network: %s", err)
 			}
-			return fmt.Errorf("error adding node %s: %s", nodeConfig.Name, err)
+			return fmt.Errorf("couldn't create subnets: %w", err)
 		}
 	}
 
+	if networkConfig.Faucet != nil {
+		if err := ln.startFaucet(*networkConfig.Faucet); err != nil {
+			if err := ln.Stop(ctx); err != nil {
+				ln.log.Debug("error stopping network: %s", err)
+			}
+			return fmt.Errorf("couldn't start faucet: %w", err)
+		}
+	}
+
+	if networkConfig.Explorer != nil {
+		if err := ln.startExplorer(*networkConfig.Explorer); err != nil {
+			if err := ln.Stop(ctx); err != nil {
+				ln.log.Debug("error stopping network: %s", err)
+			}
+			return fmt.Errorf("couldn't start explorer: %w", err)
+		}
+	}
+
+	ln.notifyWebhooks(webhook.EventNetworkStarted, nil, nil)
 	return nil
 }
 
@@ -414,11 +862,61 @@ func (ln *localNetwork) AddNode(nodeConfig node.Config) (node.Node, error) {
 		return nil, network.ErrStopped
 	}
 
-	return ln.addNode(nodeConfig)
+	start := time.Now()
+	node, err := ln.addNode(nodeConfig)
+	ln.history.record(network.OpAddNode, nodeConfig.Name, start, err)
+	return node, err
 }
 
 // Assumes [ln.lock] is held and [ln.Stop] hasn't been called.
+// [ln.lock] is released while the node's BeforeNodeStart hooks run.
 func (ln *localNetwork) addNode(nodeConfig node.Config) (node.Node, error) {
+	pending, err := ln.prepareNode(nodeConfig, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := ln.launchNode(pending); err != nil {
+		ln.abortNode(pending)
+		return nil, err
+	}
+	node := ln.registerLaunchedNode(pending)
+	return node, ln.addBeacon(pending)
+}
+
+// A node that's being added to the network. Nodes are added in three
+// steps: prepareNode and registerLaunchedNode update the network's
+// state, while launchNode, which may take a while, only touches the
+// node's files and process, so that nodes can be launched concurrently.
+// A beacon is added to the beacon lists with addBeacon.
+type pendingNode struct {
+	config            node.Config
+	dir               string
+	dbDir             string
+	logsDir           string
+	flags             []string
+	apiPort           uint16
+	p2pPort           uint16
+	nodeID            ids.NodeID
+	stakingCert       *x509.Certificate
+	hostname          string
+	teardownDiskFault func()
+	process           NodeProcess
+	// Made before the process is launched, so that
+	// a launched node can't fail to be added
+	nodeFlags  node.Flags
+	client     api.Client
+	httpClient *http.Client
+	// Whether it was added to the beacon lists
+	beaconAdded bool
+}
+
+// Makes the dir and flags of a node with [nodeConfig],
+// and reserves its name and host name. Its ports are assigned
+// before its BeforeNodeStart hooks run, which is done without
+// holding [ln.lock] if [lockHeld].
+// Assumes [ln.lock] is held if [lockHeld], or not needed, and
+// [ln.Stop] hasn't been called.
+func (ln *localNetwork) prepareNode(nodeConfig node.Config, lockHeld bool) (*pendingNode, error) {
 	if nodeConfig.Flags == nil {
 		nodeConfig.Flags = make(map[string]interface{})
 	}
@@ -426,244 +924,967 @@ func (ln *localNetwork) addNode(nodeConfig node.Config) (node.Node, error) {
 	if err := ln.setNodeName(&nodeConfig); err != nil {
 		return nil, err
 	}
+	if err := ln.checkVersionCompat(&nodeConfig); err != nil {
+		return nil, err
+	}
+	if nodeConfig.NonValidator {
+		if err := network.CheckNotGenesisValidator(ln.genesis, &nodeConfig); err != nil {
+			return nil, err
+		}
+	}
+	if ln.stateSync != nil {
+		if err := ln.stateSync.Apply(&nodeConfig, false); err != nil {
+			return nil, err
+		}
+	}
+
+	// If the beacons were removed, the new node bootstraps from
+	// a running node instead of from no node
+	if !nodeConfig.IsBeacon && ln.bootstraps.Len() == 0 && len(ln.nodes) > 0 {
+		if err := ln.promoteBeacon(); err != nil {
+			return nil, err
+		}
+	}
 
 	nodeDir, err := makeNodeDir(ln.log, ln.rootDir, nodeConfig.Name)
 	if err != nil {
 		return nil, err
 	}
 
-	// If config file is given, don't overwrite API port, P2P port, DB path, logs path
-	var configFile map[string]interface{}
-	if len(nodeConfig.ConfigFile) != 0 {
-		if err := json.Unmarshal([]byte(nodeConfig.ConfigFile), &configFile); err != nil {
-			return nil, fmt.Errorf("couldn't unmarshal config file: %w", err)
-		}
+	ln.configLayer().apply(ln.log, &nodeConfig)
+	apiPort, p2pPort, err := ln.pickPorts(nodeConfig)
+	if err != nil {
+		return nil, err
+	}
+	// The name is reserved while [ln.lock] is released for the hooks
+	ln.startingNodes[nodeConfig.Name] = struct{}{}
+	err = ln.runBeforeNodeStartHooks(&nodeConfig, apiPort, p2pPort, lockHeld)
+	delete(ln.startingNodes, nodeConfig.Name)
+	if err != nil {
+		return nil, err
+	}
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
 	}
 
-	flags, apiPort, p2pPort, dbDir, logsDir, err := ln.buildFlags(configFile, nodeDir, &nodeConfig)
+	flags, apiPort, p2pPort, dbDir, logsDir, err := ln.buildFlags(nodeDir, &nodeConfig, apiPort, p2pPort)
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse this node's ID
-	nodeID, err := utils.ToNodeID([]byte(nodeConfig.StakingKey), []byte(nodeConfig.StakingCert))
+	stakingCert, err := utils.ToStakingCert([]byte(nodeConfig.StakingKey), []byte(nodeConfig.StakingCert))
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get node ID: %w", err)
 	}
+	nodeID := ids.NodeIDFromCert(stakingCert)
+
+	nodeFlags, err := parseNodeFlags(flags, nodeConfig.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	client, httpClient, err := ln.newNodeAPIClient(&nodeConfig, apiPort)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := ln.registerHostname(&nodeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &pendingNode{
+		config:      nodeConfig,
+		dir:         nodeDir,
+		dbDir:       dbDir,
+		logsDir:     logsDir,
+		flags:       flags,
+		apiPort:     apiPort,
+		p2pPort:     p2pPort,
+		nodeID:      nodeID,
+		stakingCert: stakingCert,
+		hostname:    hostname,
+		nodeFlags:   nodeFlags,
+		client:      client,
+		httpClient:  httpClient,
+	}, nil
+}
+
+// If [pending] is a beacon, adds its IP/ID to the beacon lists, so that
+// the nodes prepared after it bootstrap from it. Note that this must be
+// done *after* its bootstrap IPs/IDs are set so it won't try to use
+// itself as a beacon.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) addBeacon(pending *pendingNode) error {
+	if !pending.config.IsBeacon {
+		return nil
+	}
+	beaconIP := ips.IPPort{IP: advertisedIP(&pending.config), Port: pending.p2pPort}
+	if err := ln.bootstraps.Add(beacon.New(pending.nodeID, beaconIP)); err != nil {
+		return err
+	}
+	pending.beaconAdded = true
+	return nil
+}
+
+// Sets up the disk and database of [pending], and starts its process.
+// Doesn't touch the network's state, so it may be called concurrently,
+// and without holding [ln.lock].
+func (ln *localNetwork) launchNode(pending *pendingNode) error {
+	nodeConfig := pending.config
+	teardownDiskFault, err := ln.setupDiskFault(&nodeConfig, pending.dbDir)
+	if err != nil {
+		return err
+	}
+	if err := importDB(&nodeConfig, pending.dbDir, ln.networkID); err != nil {
+		teardownDiskFault()
+		return err
+	}
 
 	// Start the AvalancheGo node and pass it the flags defined above
-	nodeProcess, err := ln.nodeProcessCreator.NewNodeProcess(nodeConfig, flags...)
+	nodeProcess, err := ln.nodeProcessCreator.NewNodeProcess(nodeConfig, pending.flags...)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't create new node process: %s", err)
+		teardownDiskFault()
+		return fmt.Errorf("couldn't create new node process: %s", err)
 	}
-	ln.log.Debug("starting node %q with \"%s %s\"", nodeConfig.Name, nodeConfig.BinaryPath, flags)
+	ln.log.Debug("starting node %q with \"%s %s\"", nodeConfig.Name, nodeConfig.BinaryPath, pending.flags)
 	if err := nodeProcess.Start(); err != nil {
-		return nil, fmt.Errorf("could not execute cmd \"%s %s\": %w", nodeConfig.BinaryPath, flags, err)
+		teardownDiskFault()
+		return fmt.Errorf("could not execute cmd \"%s %s\": %w", nodeConfig.BinaryPath, pending.flags, err)
+	}
+	pending.teardownDiskFault = teardownDiskFault
+	pending.process = nodeProcess
+	return nil
+}
+
+// Releases what prepareNode reserved for [pending], which wasn't launched.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) abortNode(pending *pendingNode) {
+	ln.unregisterHostname(pending.hostname)
+	if pending.beaconAdded {
+		_ = ln.bootstraps.RemoveByID(pending.nodeID)
+	}
+}
+
+// Adds [pending], whose process was started, to the network.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) registerLaunchedNode(pending *pendingNode) node.Node {
+	nodeConfig := pending.config
+	registryFile, err := ln.registerNode(nodeConfig.Name, nodeConfig.BinaryPath, pending.process)
+	if err != nil {
+		ln.log.Warn("couldn't register process of node %q: %s", nodeConfig.Name, err)
 	}
 
 	// Create a wrapper for this node so we can reference it later
 	node := &localNode{
-		name:        nodeConfig.Name,
-		nodeID:      nodeID,
-		networkID:   ln.networkID,
-		client:      ln.newAPIClientF("localhost", apiPort),
-		process:     nodeProcess,
-		apiPort:     apiPort,
-		p2pPort:     p2pPort,
-		getConnFunc: defaultGetConnFunc,
-		dbDir:       dbDir,
-		logsDir:     logsDir,
-		config:      nodeConfig,
+		name:              nodeConfig.Name,
+		nodeID:            pending.nodeID,
+		networkID:         ln.networkID,
+		client:            pending.client,
+		httpClient:        pending.httpClient,
+		process:           pending.process,
+		apiPort:           pending.apiPort,
+		p2pPort:           pending.p2pPort,
+		getConnFunc:       defaultGetConnFunc,
+		dbDir:             pending.dbDir,
+		logsDir:           pending.logsDir,
+		config:            nodeConfig,
+		flags:             pending.nodeFlags,
+		args:              pending.flags,
+		startTime:         time.Now(),
+		stakingCert:       pending.stakingCert,
+		hostname:          pending.hostname,
+		teardownDiskFault: pending.teardownDiskFault,
+		registryFile:      registryFile,
 	}
 	ln.nodes[node.name] = node
-	// If this node is a beacon, add its IP/ID to the beacon lists.
-	// Note that we do this *after* we set this node's bootstrap IPs/IDs
-	// so this node won't try to use itself as a beacon.
-	if nodeConfig.IsBeacon {
-		err = ln.bootstraps.Add(beacon.New(nodeID, ips.IPPort{
-			IP:   net.IPv6loopback,
-			Port: p2pPort,
-		}))
+	ln.updateObservabilityTargets()
+	// The manifest keeps the nodes removed, e.g. to be restarted
+	_, restarted := ln.manifest[node.name]
+	ln.manifest[node.name] = &nodeManifest{
+		nodeID:     pending.nodeID,
+		dir:        pending.dir,
+		dbDir:      pending.dbDir,
+		logsDir:    pending.logsDir,
+		apiPort:    pending.apiPort,
+		p2pPort:    pending.p2pPort,
+		pid:        processPID(pending.process),
+		binaryPath: resolveBinaryPath(nodeConfig.BinaryPath),
+	}
+	ln.recordNodeTimings(node)
+	if ln.hooks.OnNodeStarted != nil {
+		ln.hooks.OnNodeStarted(node.event(nil))
+	}
+	if restarted {
+		ln.notifyWebhooks(webhook.EventNodeRestarted, node, nil)
+	} else {
+		ln.notifyWebhooks(webhook.EventNodeStarted, node, nil)
 	}
-	return node, err
+	// Real processes are always waited on, so that their exit status
+	// is known even if they exit without being removed
+	if _, ok := node.process.(*nodeProcessImpl); ok || ln.hooks.OnNodeCrashed != nil || ln.webhooks != nil {
+		go ln.watchNodeExit(node)
+	}
+	return node
+}
+
+// Returns an API client of the node with config [nodeConfig], whose API
+// is on [apiPort], and the HTTP client its requests are sent with. Requests
+// are sent as given by the node's API TLS and auth settings, through the
+// network's API middleware.
+func (ln *localNetwork) newNodeAPIClient(nodeConfig *node.Config, apiPort uint16) (api.Client, *http.Client, error) {
+	clientConfig := api.ClientConfig{
+		AuthToken:    nodeConfig.APIAuthToken,
+		AuthPassword: nodeConfig.APIAuthPassword,
+		Middleware:   ln.apiMiddleware,
+	}
+	if nodeConfig.APITLSCert != "" {
+		clientConfig.TLSRootCAs = x509.NewCertPool()
+		if !clientConfig.TLSRootCAs.AppendCertsFromPEM([]byte(nodeConfig.APITLSCert)) {
+			return nil, nil, errors.New("couldn't parse API TLS cert")
+		}
+	}
+	apiHost := "localhost"
+	if nodeConfig.BindAddr != "" {
+		apiHost = apiIP(nodeConfig.BindAddr)
+	}
+	client := ln.newAPIClientF(apiHost, apiPort)
+	httpClient := http.DefaultClient
+	if clientConfig.TLSRootCAs != nil || clientConfig.AuthToken != "" || clientConfig.AuthPassword != "" || len(clientConfig.Middleware) > 0 {
+		httpClient = api.NewHTTPClient(clientConfig)
+		if err := api.SetHTTPClient(client, httpClient); err != nil {
+			return nil, nil, err
+		}
+	}
+	ethTransport := nodeConfig.CChainEthTransport
+	if ethTransport == "" && nodeConfig.UsesAPIAuthOrTLS() {
+		// Websocket connections don't go through the client config
+		ethTransport = api.EthTransportHTTP
+	}
+	switch {
+	case ethTransport == api.EthTransportHTTP && httpClient != http.DefaultClient:
+		client = api.WithEthClient(client, api.NewEthClientWithHTTPClient(apiHost, uint(apiPort), httpClient))
+	case ethTransport != "":
+		client = api.WithEthClient(client, api.NewEthClientWithTransport(apiHost, uint(apiPort), ethTransport))
+	}
+	return client, httpClient, nil
+}
+
+// See network.Network
+func (ln *localNetwork) Healthy(ctx context.Context) error {
+	start := time.Now()
+	err := ln.healthy(ctx)
+	ln.history.record(network.OpHealthy, "", start, err)
+	if err == nil {
+		ln.webhooksHealthyOnce.Do(func() {
+			ln.notifyWebhooks(webhook.EventNetworkHealthy, nil, nil)
+		})
+	}
+	return err
+}
+
+// Waits until the network's nodes are healthy. The nodes are polled
+// without holding [ln.lock], so that health checks can't deadlock with
+// operations waiting for it.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) healthy(ctx context.Context) error {
+	ln.lock.RLock()
+	zap.L().Info("checking local network healthiness", zap.Int("nodes", len(ln.nodes)))
+
+	// Return unhealthy if the network is stopped
+	if ln.stopCalled() {
+		ln.lock.RUnlock()
+		return network.ErrStopped
+	}
+	nodes := make([]*localNode, 0, len(ln.nodes))
+	for _, node := range ln.nodes {
+		nodes = append(nodes, node)
+	}
+	ln.lock.RUnlock()
+
+	// Derive a new context that's cancelled when Stop is called,
+	// so that we calls to Healthy() below immediately return.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func(ctx context.Context) {
+		// This goroutine runs until [ln.Stop] is called
+		// or this function returns.
+		select {
+		case <-ln.onStopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}(ctx)
+
+	errGr, ctx := errgroup.WithContext(ctx)
+	for _, node := range nodes {
+		node := node
+		errGr.Go(func() error {
+			return ln.awaitNodeHealthy(ctx, node)
+		})
+	}
+	// Wait until all nodes are ready or timeout
+	return errGr.Wait()
+}
+
+// About every [healthCheckFreq], queries [node] for health status,
+// until it's healthy, it's removed from the network, or [ctx] is done.
+// In the latter case, returns a *network.UnhealthyError with the last
+// failing checks.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) awaitNodeHealthy(ctx context.Context, node *localNode) error {
+	unhealthyErr := &network.UnhealthyError{NodeName: node.name}
+	for {
+		ln.lock.RLock()
+		removed := ln.nodes[node.name] != node
+		nodeNames := ln.nodeNamesByID()
+		checks := append([]network.HealthCheck(nil), ln.healthChecks...)
+		ln.lock.RUnlock()
+		if removed {
+			// Only the network's nodes must be healthy
+			return nil
+		}
+
+		health, latency, err := ln.getNodeHealth(ctx, node)
+		switch {
+		case err != nil:
+			unhealthyErr.Checks = nil
+			unhealthyErr.Reason = fmt.Sprintf("couldn't get health: %s", err)
+		case !health.Healthy:
+			unhealthyErr.Checks = failingChecks(health)
+			unhealthyErr.Reason = "node reports unhealthy"
+		default:
+			err = ln.checkValidatorPeers(ctx, node)
+			if err == nil && ln.healthyRequireFullMesh {
+				err = checkFullMesh(ctx, node, nodeNames)
+			}
+			if err == nil {
+				if failures := runHealthChecks(ctx, node, checks); len(failures) > 0 {
+					ln.log.Debug("node %q reports healthy but fails %d health checks", node.name, len(failures))
+					unhealthyErr.Checks = failures
+					unhealthyErr.Reason = ""
+					break
+				}
+			}
+			if err == nil {
+				ln.log.Debug("node %q became healthy (health API latency %s)", node.name, latency)
+				if ln.hooks.OnNodeHealthy != nil {
+					node.healthyOnce.Do(func() {
+						ln.hooks.OnNodeHealthy(node.event(nil))
+					})
+				}
+				return nil
+			}
+			ln.log.Debug("node %q reports healthy but %s", node.name, err)
+			unhealthyErr.Checks = nil
+			unhealthyErr.Reason = fmt.Sprintf("node reports healthy but %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return unhealthyErr
+		case <-time.After(jittered(healthCheckFreq)):
+		}
+	}
+}
+
+// Returns the checks of [reply] that are failing
+func failingChecks(reply *health.APIHealthReply) map[string]network.HealthCheckFailure {
+	checks := map[string]network.HealthCheckFailure{}
+	for checkName, result := range reply.Checks {
+		if result.Error == nil {
+			continue
+		}
+		checks[checkName] = network.HealthCheckFailure{
+			Error:              *result.Error,
+			Details:            result.Details,
+			ContiguousFailures: result.ContiguousFailures,
+		}
+	}
+	return checks
+}
+
+// Returns the health of [node] and how long the Health API took to answer.
+// At most [ln.healthCheckSem]'s capacity requests are sent at a time.
+func (ln *localNetwork) getNodeHealth(ctx context.Context, node *localNode) (*health.APIHealthReply, time.Duration, error) {
+	select {
+	case ln.healthCheckSem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	}
+	defer func() { <-ln.healthCheckSem }()
+	start := time.Now()
+	reply, err := node.client.HealthAPI().Health(ctx)
+	return reply, time.Since(start), err
+}
+
+// Returns [d] give or take up to [healthCheckJitter] of it, so
+// that the nodes of large networks aren't polled in lockstep
+func jittered(d time.Duration) time.Duration {
+	maxJitter := int64(float64(d) * healthCheckJitter)
+	return d + time.Duration(rand.Int63n(2*maxJitter+1)-maxJitter)
+}
+
+// See network.Network
+func (ln *localNetwork) GetNode(nodeName string) (node.Node, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+
+	node, ok := ln.nodes[nodeName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+	}
+	return node, nil
+}
+
+// See network.Network
+func (ln *localNetwork) GetNodeByID(nodeID ids.NodeID) (node.Node, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+
+	for _, node := range ln.nodes {
+		if node.nodeID == nodeID {
+			return node, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", network.ErrNodeNotFound, nodeID)
+}
+
+// See network.Network
+func (ln *localNetwork) GetGenesis() (*network.Genesis, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+
+	return network.ParseGenesis(ln.genesis)
+}
+
+// See network.Network
+func (ln *localNetwork) GetNodeNames() ([]string, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+
+	names := make([]string, len(ln.nodes))
+	i := 0
+	for name := range ln.nodes {
+		names[i] = name
+		i++
+	}
+	return names, nil
+}
+
+// See network.Network
+func (ln *localNetwork) GetURIs() ([]string, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+
+	names := make([]string, 0, len(ln.nodes))
+	for name := range ln.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	uris := make([]string, len(names))
+	for i, name := range names {
+		uris[i] = ln.nodes[name].GetURI()
+	}
+	return uris, nil
+}
+
+// See network.Network
+func (ln *localNetwork) GetAllNodes() (map[string]node.Node, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+
+	nodesCopy := make(map[string]node.Node, len(ln.nodes))
+	for name, node := range ln.nodes {
+		nodesCopy[name] = node
+	}
+	return nodesCopy, nil
+}
+
+// See network.Network
+func (ln *localNetwork) GetAllNodesByID() (map[ids.NodeID]node.Node, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+
+	nodes := make(map[ids.NodeID]node.Node, len(ln.nodes))
+	for _, node := range ln.nodes {
+		nodes[node.nodeID] = node
+	}
+	return nodes, nil
+}
+
+// See network.Network
+func (ln *localNetwork) RenderNodeConfigs() (map[string]node.RenderedConfig, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+
+	configs := make(map[string]node.RenderedConfig, len(ln.nodes))
+	for name, node := range ln.nodes {
+		configs[name] = node.GetRenderedConfig()
+	}
+	return configs, nil
+}
+
+func (ln *localNetwork) Stop(ctx context.Context) error {
+	err := network.ErrStopped
+	start := time.Now()
+	stopped := false
+	ln.stopOnce.Do(
+		func() {
+			close(ln.onStopCh)
+
+			// Collected while the nodes still run, so that
+			// their metrics are collected too
+			if ln.artifactsPath != "" {
+				if err := ln.CollectArtifacts(ctx, ln.artifactsPath); err != nil {
+					ln.log.Warn("couldn't collect artifacts: %s", err)
+				}
+			}
+
+			ln.lock.Lock()
+			defer ln.lock.Unlock()
+
+			err = ln.stop(ctx)
+			stopped = true
+		},
+	)
+	if ln.resourceSamplerDone != nil {
+		<-ln.resourceSamplerDone
+	}
+	ln.history.record(network.OpStop, "", start, err)
+	ln.cleanupDataDirs()
+	if stopped && ln.hooks.OnNetworkStopped != nil {
+		ln.hooks.OnNetworkStopped()
+	}
+	return err
+}
+
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) stop(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, stopTimeout)
+	defer cancel()
+	errs := wrappers.Errs{}
+	if ln.faucet != nil {
+		errs.Add(ln.faucet.Close())
+	}
+	// Stopped before the nodes it reads from
+	if ln.explorer != nil {
+		errs.Add(ln.explorer.Close())
+	}
+	errs.Add(ln.removeAllNodes(ctx))
+	if ln.observability != nil {
+		errs.Add(ln.observability.Close())
+	}
+	errs.Add(ln.closeWebhooks())
+	ln.unregisterNetwork()
+	ln.log.Info("done stopping network")
+	return errs.Err
+}
+
+// Sends a SIGTERM to the given node and removes it from this network.
+func (ln *localNetwork) RemoveNode(nodeName string) error {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() {
+		return network.ErrStopped
+	}
+	start := time.Now()
+	err := ln.removeNode(nodeName)
+	ln.history.record(network.OpRemoveNode, nodeName, start, err)
+	return err
+}
+
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) removeNode(nodeName string) error {
+	node, err := ln.detachNode(nodeName)
+	if err != nil {
+		return err
+	}
+	exited, err := ln.stopNodeProcess(node)
+	if !exited {
+		return err
+	}
+	return ln.finishRemoveNode(node, err)
+}
+
+// Removes the node named [nodeName] from the network, so that it's no
+// longer used, but doesn't stop its process. See stopNodeProcess.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) detachNode(nodeName string) (*localNode, error) {
+	ln.log.Debug("removing node %q", nodeName)
+	node, ok := ln.nodes[nodeName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+	}
+
+	// If the node wasn't a beacon, we don't care
+	_ = ln.bootstraps.RemoveByID(node.nodeID)
+
+	delete(ln.nodes, nodeName)
+	node.removed = true
+	ln.updateObservabilityTargets()
+	ln.unregisterHostname(node.hostname)
+	for _, attachedPeer := range ln.attachedPeers[nodeName] {
+		attachedPeer.StartClose()
+	}
+	delete(ln.attachedPeers, nodeName)
+	// cchain eth api uses a websocket connection and must be closed before stopping the node,
+	// to avoid errors logs at client
+	node.client.CChainEthAPI().Close()
+	return node, nil
+}
+
+// Stops the process of [node], which was detached, and waits for it to
+// exit. Returns whether it exited and, if so, the error it exited with.
+// Doesn't touch the network's state, so it may be called concurrently,
+// and without holding [ln.lock].
+func (ln *localNetwork) stopNodeProcess(node *localNode) (bool, error) {
+	switch {
+	case node.frozen:
+		// Resuming the node would let it go past the height it was
+		// frozen at, so it's killed while it's suspended
+		if err := node.process.Signal(os.Kill); err != nil {
+			return false, fmt.Errorf("error killing frozen node %s: %w", node.name, err)
+		}
+	case node.paused:
+		// A suspended process doesn't handle SIGTERM. If it can't be
+		// resumed, it's killed rather than left behind.
+		if err := node.process.Resume(); err != nil {
+			ln.log.Warn("couldn't resume paused node %s, killing it: %s", node.name, err)
+			if err := node.process.Signal(os.Kill); err != nil {
+				return false, fmt.Errorf("error killing paused node %s: %w", node.name, err)
+			}
+			break
+		}
+		fallthrough
+	default:
+		if err := node.process.Stop(); err != nil {
+			return false, fmt.Errorf("error sending SIGTERM to node %s: %w", node.name, err)
+		}
+	}
+	return true, waitNodeProcess(node)
+}
+
+// Kills the process of [node], which was detached, without letting it
+// stop gracefully, and waits for it to exit. Returns whether it exited
+// and, if so, the error it exited with. Like stopNodeProcess, may be
+// called concurrently, and without holding [ln.lock].
+func (ln *localNetwork) killNodeProcess(node *localNode) (bool, error) {
+	// A suspended process is killed too
+	if err := node.process.Signal(os.Kill); err != nil {
+		return false, fmt.Errorf("error killing node %s: %w", node.name, err)
+	}
+	return true, waitNodeProcess(node)
+}
+
+// Waits for the process of [node], which was signaled to stop, to
+// exit, and cleans up after it. Returns the error it exited with.
+func waitNodeProcess(node *localNode) error {
+	err := node.process.Wait()
+	if node.teardownDiskFault != nil {
+		node.teardownDiskFault()
+	}
+	if node.registryFile != "" {
+		_ = os.Remove(node.registryFile)
+	}
+	return err
+}
+
+// Records that the process of [node], which was detached,
+// exited with [exitErr]. Returns [exitErr], wrapped.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) finishRemoveNode(node *localNode, exitErr error) error {
+	if manifest, ok := ln.manifest[node.name]; ok {
+		manifest.exited = true
+		manifest.exitErr = exitErr
+	}
+	if ln.hooks.OnNodeStopped != nil {
+		ln.hooks.OnNodeStopped(node.event(exitErr))
+	}
+	ln.notifyWebhooks(webhook.EventNodeStopped, node, exitErr)
+	if exitErr != nil {
+		return fmt.Errorf("node %q stopped with error: %w", node.name, exitErr)
+	}
+	return nil
+}
+
+// Calls the OnNodeCrashed hook if [node]'s
+// process exits without the node being removed.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) watchNodeExit(node *localNode) {
+	err := node.process.Wait()
+	ln.lock.RLock()
+	removed := node.removed
+	ln.lock.RUnlock()
+	if removed {
+		return
+	}
+	event := node.event(err)
+	if event.Exit != nil && len(event.Exit.StderrTail) > 0 {
+		ln.log.Warn("node %q exited unexpectedly: %v. Last lines of its stderr:\n%s", node.name, err, strings.Join(event.Exit.StderrTail, "\n"))
+	} else {
+		ln.log.Warn("node %q exited unexpectedly: %v", node.name, err)
+	}
+	if ln.hooks.OnNodeCrashed != nil {
+		ln.hooks.OnNodeCrashed(event)
+	}
+	ln.notifyWebhooks(webhook.EventNodeCrashed, node, err)
+}
+
+// See network.Network
+func (ln *localNetwork) PauseNode(nodeName string) error {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() {
+		return network.ErrStopped
+	}
+	start := time.Now()
+	err := ln.setNodePaused(nodeName, true)
+	ln.history.record(network.OpPauseNode, nodeName, start, err)
+	return err
+}
+
+// See network.Network
+func (ln *localNetwork) ResumeNode(nodeName string) error {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() {
+		return network.ErrStopped
+	}
+	start := time.Now()
+	err := ln.setNodePaused(nodeName, false)
+	ln.history.record(network.OpResumeNode, nodeName, start, err)
+	return err
 }
 
 // See network.Network
-func (ln *localNetwork) Healthy(ctx context.Context) error {
+func (ln *localNetwork) SignalNode(nodeName string, sig os.Signal) error {
 	ln.lock.RLock()
 	defer ln.lock.RUnlock()
-
-	zap.L().Info("checking local network healthiness", zap.Int("nodes", len(ln.nodes)))
-
-	// Return unhealthy if the network is stopped
 	if ln.stopCalled() {
 		return network.ErrStopped
 	}
+	start := time.Now()
+	err := ln.signalNode(nodeName, sig)
+	ln.history.record(network.OpSignalNode, nodeName, start, err)
+	return err
+}
 
-	// Derive a new context that's cancelled when Stop is called,
-	// so that we calls to Healthy() below immediately return.
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-	go func(ctx context.Context) {
-		// This goroutine runs until [ln.Stop] is called
-		// or this function returns.
-		select {
-		case <-ln.onStopCh:
-			cancel()
-		case <-ctx.Done():
-		}
-	}(ctx)
-
-	errGr, ctx := errgroup.WithContext(ctx)
-	for _, node := range ln.nodes {
-		node := node
-		errGr.Go(func() error {
-			// Every [healthCheckFreq], query node for health status.
-			// Do this until ctx timeout or network closed.
-			for {
-				health, err := node.client.HealthAPI().Health(ctx)
-				if err == nil && health.Healthy {
-					ln.log.Debug("node %q became healthy", node.name)
-					return nil
-				}
-				select {
-				case <-ctx.Done():
-					return fmt.Errorf("node %q failed to become healthy within timeout, or network stopped", node.GetName())
-				case <-time.After(healthCheckFreq):
-				}
-			}
-		})
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) signalNode(nodeName string, sig os.Signal) error {
+	node, ok := ln.nodes[nodeName]
+	if !ok {
+		return fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
 	}
-	// Wait until all nodes are ready or timeout
-	return errGr.Wait()
+	if err := node.process.Signal(sig); err != nil {
+		return fmt.Errorf("couldn't send signal %s to node %q: %w", sig, nodeName, err)
+	}
+	ln.log.Info("sent signal %s to node %q", sig, nodeName)
+	return nil
 }
 
 // See network.Network
-func (ln *localNetwork) GetNode(nodeName string) (node.Node, error) {
+func (ln *localNetwork) NodeStdin(nodeName string) (io.Writer, error) {
 	ln.lock.RLock()
 	defer ln.lock.RUnlock()
-
 	if ln.stopCalled() {
 		return nil, network.ErrStopped
 	}
-
 	node, ok := ln.nodes[nodeName]
 	if !ok {
-		return nil, fmt.Errorf("node %q not found in network", nodeName)
+		return nil, fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
 	}
-	return node, nil
+	stdin := node.process.Stdin()
+	if stdin == nil {
+		return nil, fmt.Errorf("stdin of node %q isn't a pipe: node.Config.Stdin isn't set", nodeName)
+	}
+	return stdin, nil
 }
 
 // See network.Network
-func (ln *localNetwork) GetNodeNames() ([]string, error) {
-	ln.lock.RLock()
-	defer ln.lock.RUnlock()
-
+func (ln *localNetwork) Pause(context.Context) error {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
 	if ln.stopCalled() {
-		return nil, network.ErrStopped
-	}
-
-	names := make([]string, len(ln.nodes))
-	i := 0
-	for name := range ln.nodes {
-		names[i] = name
-		i++
+		return network.ErrStopped
 	}
-	return names, nil
+	start := time.Now()
+	err := ln.setNetworkPaused(true)
+	ln.history.record(network.OpPause, "", start, err)
+	return err
 }
 
 // See network.Network
-func (ln *localNetwork) GetAllNodes() (map[string]node.Node, error) {
-	ln.lock.RLock()
-	defer ln.lock.RUnlock()
+func (ln *localNetwork) Resume(ctx context.Context) error {
+	start := time.Now()
+	err := ln.resume(ctx)
+	ln.history.record(network.OpResume, "", start, err)
+	return err
+}
 
+func (ln *localNetwork) resume(ctx context.Context) error {
+	ln.lock.Lock()
 	if ln.stopCalled() {
-		return nil, network.ErrStopped
+		ln.lock.Unlock()
+		return network.ErrStopped
 	}
-
-	nodesCopy := make(map[string]node.Node, len(ln.nodes))
-	for name, node := range ln.nodes {
-		nodesCopy[name] = node
+	err := ln.setNetworkPaused(false)
+	ln.lock.Unlock()
+	if err != nil {
+		return err
 	}
-	return nodesCopy, nil
+	return ln.healthy(ctx)
 }
 
-func (ln *localNetwork) Stop(ctx context.Context) error {
-	err := network.ErrStopped
-	ln.stopOnce.Do(
-		func() {
-			close(ln.onStopCh)
-
-			ln.lock.Lock()
-			defer ln.lock.Unlock()
-
-			err = ln.stop(ctx)
-		},
-	)
-	return err
+// Suspends or resumes the processes of all the nodes, in name order.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) setNetworkPaused(paused bool) error {
+	nodeNames := make([]string, 0, len(ln.nodes))
+	for nodeName := range ln.nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	for _, nodeName := range nodeNames {
+		if err := ln.setNodePaused(nodeName, paused); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// Suspends or resumes the process of the given node.
 // Assumes [ln.lock] is held.
-func (ln *localNetwork) stop(ctx context.Context) error {
-	ctx, cancel := context.WithTimeout(ctx, stopTimeout)
-	defer cancel()
-	errs := wrappers.Errs{}
-	for nodeName := range ln.nodes {
-		select {
-		case <-ctx.Done():
-			// In practice we'll probably never time out here,
-			// and the caller probably won't cancel a call
-			// to stop(), but we include this to respect the
-			// network.Network interface.
-			return ctx.Err()
-		default:
+func (ln *localNetwork) setNodePaused(nodeName string, paused bool) error {
+	node, ok := ln.nodes[nodeName]
+	if !ok {
+		return fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+	}
+	if node.paused == paused {
+		return nil
+	}
+	if paused {
+		if err := node.process.Pause(); err != nil {
+			return fmt.Errorf("couldn't pause node %q: %w", nodeName, err)
 		}
-		if err := ln.removeNode(nodeName); err != nil {
-			ln.log.Error("error stopping node %q: %s", nodeName, err)
-			errs.Add(err)
+		ln.log.Info("paused node %q", nodeName)
+	} else {
+		if err := node.process.Resume(); err != nil {
+			return fmt.Errorf("couldn't resume node %q: %w", nodeName, err)
 		}
+		ln.log.Info("resumed node %q", nodeName)
 	}
-	ln.log.Info("done stopping network")
-	return errs.Err
+	node.paused = paused
+	if !paused {
+		node.frozen = false
+	}
+	return nil
 }
 
-// Sends a SIGTERM to the given node and removes it from this network.
-func (ln *localNetwork) RemoveNode(nodeName string) error {
+// See network.Network
+func (ln *localNetwork) UpdateNodeFlags(nodeName string, flags map[string]interface{}) error {
 	ln.lock.Lock()
 	defer ln.lock.Unlock()
 	if ln.stopCalled() {
 		return network.ErrStopped
 	}
-	return ln.removeNode(nodeName)
+	start := time.Now()
+	err := ln.restartNode(nodeName, func(nodeConfig *node.Config) {
+		updateFlags(nodeConfig.Flags, flags)
+	})
+	ln.history.record(network.OpUpdateFlags, nodeName, start, err)
+	return err
 }
 
+// Stops the node named [nodeName] and starts it again with its config
+// updated by [updateConfigF]. The node keeps its name, database, logs
+// dir, staking key/cert and ports. If the node doesn't start with the
+// updated config, it's started again with its previous config.
 // Assumes [ln.lock] is held.
-func (ln *localNetwork) removeNode(nodeName string) error {
-	ln.log.Debug("removing node %q", nodeName)
-	node, ok := ln.nodes[nodeName]
+func (ln *localNetwork) restartNode(nodeName string, updateConfigF func(*node.Config)) error {
+	n, ok := ln.nodes[nodeName]
 	if !ok {
-		return fmt.Errorf("node %q not found", nodeName)
+		return fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
 	}
+	nodeConfig := restartConfig(n, updateConfigF)
+	prevConfig := restartConfig(n, func(*node.Config) {})
+	if err := ln.removeNode(nodeName); err != nil {
+		return err
+	}
+	return ln.addRestartedNode(nodeConfig, prevConfig)
+}
 
-	// If the node wasn't a beacon, we don't care
-	_ = ln.bootstraps.RemoveByID(node.nodeID)
-
-	delete(ln.nodes, nodeName)
-	// cchain eth api uses a websocket connection and must be closed before stopping the node,
-	// to avoid errors logs at client
-	node.client.CChainEthAPI().Close()
-	if err := node.process.Stop(); err != nil {
-		return fmt.Errorf("error sending SIGTERM to node %s: %w", nodeName, err)
+// Starts the removed node of [nodeConfig]. If it doesn't start, starts
+// it with [prevConfig], the config it had, so that it isn't lost.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) addRestartedNode(nodeConfig node.Config, prevConfig node.Config) error {
+	_, err := ln.addNode(nodeConfig)
+	if err == nil {
+		return nil
 	}
-	if err := node.process.Wait(); err != nil {
-		return fmt.Errorf("node %q stopped with error: %w", nodeName, err)
+	ln.log.Warn("couldn't restart node %q, starting it with its previous config: %s", nodeConfig.Name, err)
+	if _, prevErr := ln.addNode(prevConfig); prevErr != nil {
+		return fmt.Errorf("couldn't restart node %q: %w, nor start it with its previous config: %s", nodeConfig.Name, err, prevErr)
 	}
-	return nil
+	return fmt.Errorf("couldn't restart node %q, so it was started with its previous config: %w", nodeConfig.Name, err)
+}
+
+// Returns the config to start [n] again with, updated by [updateConfigF],
+// such that the node keeps its database, logs dir and ports.
+func restartConfig(n *localNode, updateConfigF func(*node.Config)) node.Config {
+	nodeConfig := n.config
+	// Don't modify the flags of the running node's config
+	nodeConfig.Flags = make(map[string]interface{}, len(n.config.Flags))
+	for flagName, flagVal := range n.config.Flags {
+		nodeConfig.Flags[flagName] = flagVal
+	}
+	updateConfigF(&nodeConfig)
+	nodeConfig.Flags[config.DBPathKey] = n.dbDir
+	nodeConfig.Flags[config.LogsDirKey] = n.logsDir
+	nodeConfig.Flags[config.HTTPPortKey] = int(n.apiPort)
+	nodeConfig.Flags[config.StakingPortKey] = int(n.p2pPort)
+	return nodeConfig
 }
 
 // Save network snapshot
 // Network is stopped in order to do a safe preservation
 func (ln *localNetwork) SaveSnapshot(ctx context.Context, snapshotName string) (string, error) {
+	start := time.Now()
+	snapshotDir, err := ln.saveSnapshot(ctx, snapshotName)
+	ln.history.record(network.OpSaveSnapshot, snapshotName, start, err)
+	return snapshotDir, err
+}
+
+func (ln *localNetwork) saveSnapshot(ctx context.Context, snapshotName string) (string, error) {
 	ln.lock.Lock()
 	defer ln.lock.Unlock()
 	if ln.stopCalled() {
@@ -739,6 +1960,7 @@ func (ln *localNetwork) SaveSnapshot(ctx context.Context, snapshotName string) (
 	// save network conf
 	networkConfig := network.Config{
 		Genesis:     string(ln.genesis),
+		CustomVMs:   ln.customVMs,
 		Flags:       networkConfigFlags,
 		NodeConfigs: []node.Config{},
 	}
@@ -759,6 +1981,25 @@ func (ln *localNetwork) SaveSnapshot(ctx context.Context, snapshotName string) (
 
 // start network from snapshot
 func (ln *localNetwork) loadSnapshot(ctx context.Context, snapshotName string) error {
+	start := time.Now()
+	err := ln.loadSnapshotImpl(ctx, snapshotName)
+	ln.history.record(network.OpLoadSnapshot, snapshotName, start, err)
+	return err
+}
+
+func (ln *localNetwork) loadSnapshotImpl(ctx context.Context, snapshotName string) error {
+	networkConfig, err := ln.readSnapshot(snapshotName)
+	if err != nil {
+		return err
+	}
+	// Not called with the lock held, as loadConfig
+	// stops the network if its nodes fail to start
+	return ln.loadConfig(ctx, networkConfig)
+}
+
+// Copies the node databases of snapshot [snapshotName] to the
+// network's root dir, and returns the config of the snapshot
+func (ln *localNetwork) readSnapshot(snapshotName string) (network.Config, error) {
 	ln.lock.Lock()
 	defer ln.lock.Unlock()
 	snapshotDir := filepath.Join(ln.snapshotsDir, snapshotPrefix+snapshotName)
@@ -766,35 +2007,42 @@ func (ln *localNetwork) loadSnapshot(ctx context.Context, snapshotName string) e
 	_, err := os.Stat(snapshotDir)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("snapshot %q does not exists", snapshotName)
+			return network.Config{}, fmt.Errorf("snapshot %q does not exists", snapshotName)
 		} else {
-			return fmt.Errorf("failure accessing snapshot %q: %w", snapshotName, err)
+			return network.Config{}, fmt.Errorf("failure accessing snapshot %q: %w", snapshotName, err)
 		}
 	}
 	// load network config
 	networkConfigJSON, err := os.ReadFile(filepath.Join(snapshotDir, "network.json"))
 	if err != nil {
-		return fmt.Errorf("failure reading network config file from snapshot: %w", err)
+		return network.Config{}, fmt.Errorf("failure reading network config file from snapshot: %w", err)
 	}
 	networkConfig := network.Config{}
 	err = json.Unmarshal(networkConfigJSON, &networkConfig)
 	if err != nil {
-		return fmt.Errorf("failure unmarshaling network config from snapshot: %w", err)
+		return network.Config{}, fmt.Errorf("failure unmarshaling network config from snapshot: %w", err)
 	}
 	// load db
 	for _, nodeConfig := range networkConfig.NodeConfigs {
 		sourceDbDir := filepath.Join(snapshotDbDir, nodeConfig.Name)
 		targetDbDir := filepath.Join(filepath.Join(ln.rootDir, nodeConfig.Name), defaultDbSubdir)
 		if err := dircopy.Copy(sourceDbDir, targetDbDir); err != nil {
-			return fmt.Errorf("failure loading node %q db dir: %w", nodeConfig.Name, err)
+			return network.Config{}, fmt.Errorf("failure loading node %q db dir: %w", nodeConfig.Name, err)
 		}
 		nodeConfig.Flags[config.DBPathKey] = targetDbDir
 	}
-	return ln.loadConfig(ctx, networkConfig)
+	return networkConfig, nil
 }
 
 // Remove network snapshot
 func (ln *localNetwork) RemoveSnapshot(snapshotName string) error {
+	start := time.Now()
+	err := ln.removeSnapshot(snapshotName)
+	ln.history.record(network.OpRemoveSnapshot, snapshotName, start, err)
+	return err
+}
+
+func (ln *localNetwork) removeSnapshot(snapshotName string) error {
 	snapshotDir := filepath.Join(ln.snapshotsDir, snapshotPrefix+snapshotName)
 	_, err := os.Stat(snapshotDir)
 	if err != nil {
@@ -831,6 +2079,131 @@ func (ln *localNetwork) GetSnapshotNames() ([]string, error) {
 	return snapshots, nil
 }
 
+// See network.Network
+func (ln *localNetwork) History() []network.Operation {
+	return ln.history.get()
+}
+
+// See network.Network
+func (ln *localNetwork) Capabilities() network.Capabilities {
+	return network.Capabilities{
+		Pause:          runtime.GOOS != "windows",
+		Snapshots:      true,
+		Nice:           niceSupported(),
+		ResourceLimits: cpuAffinitySupported(),
+		DiskFaults:     runtime.GOOS == "linux",
+	}
+}
+
+// See network.Network
+func (ln *localNetwork) AddHealthCheck(check network.HealthCheck) error {
+	if err := check.Validate(); err != nil {
+		return fmt.Errorf("invalid health check: %w", err)
+	}
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	ln.healthChecks = append(ln.healthChecks, check)
+	return nil
+}
+
+// See network.Network
+func (ln *localNetwork) BeforeNodeStart(hook func(*node.Config) error) {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	ln.beforeNodeStartHooks = append(ln.beforeNodeStartHooks, hook)
+}
+
+// Calls the BeforeNodeStart hooks on [nodeConfig], whose ports are
+// [apiPort] and [p2pPort], and checks that it's still valid afterwards.
+// If [lockHeld], [ln.lock] is released while they run, so that they
+// can call the network's methods.
+// Assumes [ln.lock] is held if [lockHeld], or not needed.
+func (ln *localNetwork) runBeforeNodeStartHooks(nodeConfig *node.Config, apiPort uint16, p2pPort uint16, lockHeld bool) error {
+	if len(ln.beforeNodeStartHooks) == 0 {
+		return nil
+	}
+	hooks := append([]func(*node.Config) error(nil), ln.beforeNodeStartHooks...)
+	if lockHeld {
+		ln.lock.Unlock()
+		defer ln.lock.Lock()
+	}
+
+	// The hooks see the ports in the flags, which
+	// keep only the ports given or set by the hooks
+	portFlags := map[string]int{
+		config.HTTPPortKey:    int(apiPort),
+		config.StakingPortKey: int(p2pPort),
+	}
+	for flagName, port := range portFlags {
+		if _, ok := nodeConfig.Flags[flagName]; ok {
+			delete(portFlags, flagName)
+		} else {
+			nodeConfig.Flags[flagName] = port
+		}
+	}
+	for _, hook := range hooks {
+		if err := hook(nodeConfig); err != nil {
+			return fmt.Errorf("before start hook failed for node %q: %w", nodeConfig.Name, err)
+		}
+	}
+	for flagName, port := range portFlags {
+		if nodeConfig.Flags != nil && nodeConfig.Flags[flagName] == port {
+			delete(nodeConfig.Flags, flagName)
+		}
+	}
+	if nodeConfig.Flags == nil {
+		nodeConfig.Flags = map[string]interface{}{}
+	}
+	if err := nodeConfig.Validate(ln.networkID); err != nil {
+		return fmt.Errorf("config of node %q invalid after before start hooks: %w", nodeConfig.Name, err)
+	}
+	return nil
+}
+
+// See network.Network
+func (ln *localNetwork) AttachPeer(ctx context.Context, nodeName string, handler router.InboundHandler) (peer.Peer, error) {
+	ln.lock.RLock()
+	if ln.stopCalled() {
+		ln.lock.RUnlock()
+		return nil, network.ErrStopped
+	}
+	node, ok := ln.nodes[nodeName]
+	ln.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+	}
+
+	// The handshake is done without holding the lock, as it may be slow
+	attachedPeer, err := node.AttachPeer(ctx, handler)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't attach peer to node %q: %w", nodeName, err)
+	}
+	if err := attachedPeer.AwaitReady(ctx); err != nil {
+		attachedPeer.StartClose()
+		return nil, fmt.Errorf("peer attached to node %q not ready: %w", nodeName, err)
+	}
+
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() || ln.nodes[nodeName] != node {
+		// The node was removed during the handshake
+		attachedPeer.StartClose()
+		return nil, fmt.Errorf("node %q removed while attaching peer", nodeName)
+	}
+	ln.attachedPeers[nodeName] = append(ln.attachedPeers[nodeName], attachedPeer)
+	ln.log.Debug("attached peer %s to node %q", attachedPeer.ID(), nodeName)
+	return attachedPeer, nil
+}
+
+// Returns the part of the network's config merged into its nodes' configs
+func (ln *localNetwork) configLayer() networkConfigLayer {
+	return networkConfigLayer{
+		flags:             ln.flags,
+		chainConfigFiles:  ln.chainConfigFiles,
+		subnetConfigFiles: ln.subnetConfigFiles,
+	}
+}
+
 // Returns whether Stop has been called.
 func (ln *localNetwork) stopCalled() bool {
 	select {
@@ -860,23 +2233,6 @@ func createFileAndWrite(path string, contents []byte) error {
 	return nil
 }
 
-// addNetworkFlags adds the flags in [networkFlags] to [nodeConfig.Flags].
-// [nodeFlags] must not be nil.
-func addNetworkFlags(log logging.Logger, networkFlags map[string]interface{}, nodeFlags map[string]interface{}) {
-	for flagName, flagVal := range networkFlags {
-		// If the same flag is given in network config and node config,
-		// the flag in the node config takes precedence
-		if val, ok := nodeFlags[flagName]; !ok {
-			nodeFlags[flagName] = flagVal
-		} else {
-			log.Info(
-				"not overwriting node config flag %s (value %v) with network config flag (value %v)",
-				flagName, val, flagVal,
-			)
-		}
-	}
-}
-
 // Set [nodeConfig].Name if it isn't given and assert it's unique.
 func (ln *localNetwork) setNodeName(nodeConfig *node.Config) error {
 	// If no name was given, use default name pattern
@@ -884,19 +2240,26 @@ func (ln *localNetwork) setNodeName(nodeConfig *node.Config) error {
 		for {
 			nodeConfig.Name = fmt.Sprintf("%s%d", defaultNodeNamePrefix, ln.nextNodeSuffix)
 			ln.nextNodeSuffix++
-			_, ok := ln.nodes[nodeConfig.Name]
-			if !ok {
+			if !ln.nodeNameTaken(nodeConfig.Name) {
 				break
 			}
 		}
 	}
 	// Enforce name uniqueness
-	if _, ok := ln.nodes[nodeConfig.Name]; ok {
-		return fmt.Errorf("repeated node name %q", nodeConfig.Name)
+	if ln.nodeNameTaken(nodeConfig.Name) {
+		return fmt.Errorf("%w: %q", network.ErrDuplicateNodeName, nodeConfig.Name)
 	}
 	return nil
 }
 
+// Returns true if a node named [nodeName] is running or being started.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) nodeNameTaken(nodeName string) bool {
+	_, running := ln.nodes[nodeName]
+	_, starting := ln.startingNodes[nodeName]
+	return running || starting
+}
+
 func makeNodeDir(log logging.Logger, rootDir, nodeName string) (string, error) {
 	if rootDir == "" {
 		log.Warn("no network root directory defined; will create this node's runtime directory in working directory")
@@ -916,33 +2279,11 @@ func makeNodeDir(log logging.Logger, rootDir, nodeName string) (string, error) {
 	return nodeRootDir, nil
 }
 
-// getConfigEntry returns an entry in the config file if it is found, otherwise returns the default value
-func getConfigEntry(
-	nodeConfigFlags map[string]interface{},
-	configFile map[string]interface{},
-	flag string,
-	defaultVal string,
-) (string, error) {
-	var entry string
-	if val, ok := nodeConfigFlags[flag]; ok {
-		if entry, ok := val.(string); ok {
-			return entry, nil
-		}
-		return "", fmt.Errorf("expected node config flag %q to be string but got %T", flag, entry)
-	}
-	if val, ok := configFile[flag]; ok {
-		if entry, ok := val.(string); ok {
-			return entry, nil
-		}
-		return "", fmt.Errorf("expected config file flag %q to be string but got %T", flag, entry)
-	}
-	return defaultVal, nil
-}
-
-// getPort looks up the port config in the config file, if there is none, it tries to get a random free port from the OS
+// getPort looks up the port in [flags], if it's not there, it tries to get a random free port from the OS.
+// Returns a *network.ErrPortInUse if the given port is already bound.
 func getPort(
-	flags map[string]interface{},
-	configFile map[string]interface{},
+	rng *rand.Rand,
+	flags mergedFlags,
 	portKey string,
 ) (port uint16, err error) {
 	if portIntf, ok := flags[portKey]; ok {
@@ -953,16 +2294,16 @@ func getPort(
 		} else {
 			return 0, fmt.Errorf("expected flag %q to be int/float64 but got %T", portKey, portIntf)
 		}
-	} else if portIntf, ok := configFile[portKey]; ok {
-		if portFromConfigFile, ok := portIntf.(float64); ok {
-			port = uint16(portFromConfigFile)
-		} else {
-			return 0, fmt.Errorf("expected flag %q to be float64 but got %T", portKey, portIntf)
+	}
+	if port != 0 {
+		// The port was given, so it must not be bound already
+		if err := bindPort(port); err != nil {
+			return 0, &network.ErrPortInUse{Port: port}
 		}
 	} else {
 		// Use a random free port.
 		// Note: it is possible but unlikely for getFreePort to return the same port multiple times.
-		port, err = getFreePort()
+		port, err = getFreePort(rng)
 		if err != nil {
 			return 0, fmt.Errorf("couldn't get free API port: %w", err)
 		}
@@ -970,45 +2311,69 @@ func getPort(
 	return port, nil
 }
 
+// Returns the API and P2P ports of [nodeConfig]: those given in its
+// config, or else random free ports.
+// Assumes [ln.lock] is held, or not needed.
+func (ln *localNetwork) pickPorts(nodeConfig node.Config) (uint16, uint16, error) {
+	mergedFlags, err := mergeConfigFile(nodeConfig)
+	if err != nil {
+		return 0, 0, err
+	}
+	apiPort, err := getPort(ln.rng, mergedFlags, config.HTTPPortKey)
+	if err != nil {
+		return 0, 0, err
+	}
+	p2pPort, err := getPort(ln.rng, mergedFlags, config.StakingPortKey)
+	if err != nil {
+		return 0, 0, err
+	}
+	return apiPort, p2pPort, nil
+}
+
 // buildFlags returns the:
 // 1) Flags
 // 2) API port
 // 3) P2P port
-// of the node being added with config [nodeConfig], config file [configFile],
+// of the node being added with config [nodeConfig],
 // and directory at [nodeDir].
-// [nodeConfig.Flags] must not be nil
+// The network's config must have been merged into [nodeConfig]. See
+// networkConfigLayer. The node gets the ports picked for it, [apiPort]
+// and [p2pPort], unless its config gives others.
 func (ln *localNetwork) buildFlags(
-	configFile map[string]interface{},
 	nodeDir string,
 	nodeConfig *node.Config,
+	apiPort uint16,
+	p2pPort uint16,
 ) ([]string, uint16, uint16, string, string, error) {
-	// Add flags in [ln.Flags] to [nodeConfig.Flags]
-	// Assumes [nodeConfig.Flags] is non-nil
-	addNetworkFlags(ln.log, ln.flags, nodeConfig.Flags)
-
-	// Tell the node to put the database in [nodeDir] unless given in config file
-	dbDir, err := getConfigEntry(nodeConfig.Flags, configFile, config.DBPathKey, filepath.Join(nodeDir, defaultDbSubdir))
+	mergedFlags, err := mergeConfigFile(*nodeConfig)
 	if err != nil {
 		return nil, 0, 0, "", "", err
 	}
 
-	// Tell the node to put the log directory in [nodeDir/logs] unless given in config file
-	logsDir, err := getConfigEntry(nodeConfig.Flags, configFile, config.LogsDirKey, filepath.Join(nodeDir, defaultLogsSubdir))
+	// Tell the node to put the database in [nodeDir] unless given in its config
+	dbDir, err := mergedFlags.stringFlag(config.DBPathKey, filepath.Join(nodeDir, defaultDbSubdir))
 	if err != nil {
 		return nil, 0, 0, "", "", err
 	}
 
-	// Use random free API port unless given in config file
-	apiPort, err := getPort(nodeConfig.Flags, configFile, config.HTTPPortKey)
+	// Tell the node to put the log directory in [nodeDir/logs] unless given in its config
+	logsDir, err := mergedFlags.stringFlag(config.LogsDirKey, filepath.Join(nodeDir, defaultLogsSubdir))
 	if err != nil {
 		return nil, 0, 0, "", "", err
 	}
 
-	// Use a random free P2P (staking) port unless given in config file
-	// Use random free API port unless given in config file
-	p2pPort, err := getPort(nodeConfig.Flags, configFile, config.StakingPortKey)
-	if err != nil {
-		return nil, 0, 0, "", "", err
+	// Use the API port picked unless given in its config
+	if _, ok := mergedFlags[config.HTTPPortKey]; ok {
+		if apiPort, err = getPort(ln.rng, mergedFlags, config.HTTPPortKey); err != nil {
+			return nil, 0, 0, "", "", err
+		}
+	}
+
+	// Use the P2P (staking) port picked unless given in its config
+	if _, ok := mergedFlags[config.StakingPortKey]; ok {
+		if p2pPort, err = getPort(ln.rng, mergedFlags, config.StakingPortKey); err != nil {
+			return nil, 0, 0, "", "", err
+		}
 	}
 
 	// Flags for AvalancheGo
@@ -1028,10 +2393,41 @@ func (ln *localNetwork) buildFlags(
 		return nil, 0, 0, "", "", err
 	}
 	flags = append(flags, fileFlags...)
+	// avalanchego doesn't accept bracketed IPv6 IPs
+	if nodeConfig.PublicIP != "" {
+		flags = append(flags, fmt.Sprintf("--%s=%s", config.PublicIPKey, node.ParseIP(nodeConfig.PublicIP)))
+	}
+	if nodeConfig.BindAddr != "" {
+		flags = append(flags, fmt.Sprintf("--%s=%s", config.HTTPHostKey, node.ParseIP(nodeConfig.BindAddr)))
+	}
+	if nodeConfig.APITLSCert != "" {
+		flags = append(flags, fmt.Sprintf("--%s=true", config.HTTPSEnabledKey))
+	}
+	if nodeConfig.APIAuthPassword != "" {
+		flags = append(flags, fmt.Sprintf("--%s=true", config.APIAuthRequiredKey))
+	}
+
+	// Give the node a build dir with the custom VMs in its plugin dir,
+	// along with the plugins of the build dir it would otherwise use
+	if len(ln.customVMs) > 0 {
+		srcBuildDir, err := mergedFlags.stringFlag(config.BuildDirKey, filepath.Dir(nodeConfig.BinaryPath))
+		if err != nil {
+			return nil, 0, 0, "", "", err
+		}
+		buildDir, err := assemblePluginDir(nodeDir, srcBuildDir, ln.customVMs)
+		if err != nil {
+			return nil, 0, 0, "", "", fmt.Errorf("couldn't assemble plugin dir: %w", err)
+		}
+		flags = append(flags, fmt.Sprintf("--%s=%s", config.BuildDirKey, buildDir))
+	}
 
 	// Add flags given in node config.
 	// Note these will overwrite existing flags if the same flag is given twice.
 	for flagName, flagVal := range nodeConfig.Flags {
+		if flagName == config.BuildDirKey && len(ln.customVMs) > 0 {
+			// Replaced by the assembled build dir
+			continue
+		}
 		if _, ok := warnFlags[flagName]; ok {
 			ln.log.Warn("The flag %s has been provided. This can create conflicts with the runner. The suggestion is to remove this flag", flagName)
 		}
@@ -1074,6 +2470,30 @@ func writeFiles(genesis []byte, nodeRootDir string, nodeConfig *node.Config) ([]
 			contents:  genesis,
 		},
 	}
+	if len(nodeConfig.APITLSCert) != 0 {
+		files = append(files,
+			file{
+				flagValue: filepath.Join(nodeRootDir, apiTLSKeyFileName),
+				path:      filepath.Join(nodeRootDir, apiTLSKeyFileName),
+				pathKey:   config.HTTPSKeyFileKey,
+				contents:  []byte(nodeConfig.APITLSKey),
+			},
+			file{
+				flagValue: filepath.Join(nodeRootDir, apiTLSCertFileName),
+				path:      filepath.Join(nodeRootDir, apiTLSCertFileName),
+				pathKey:   config.HTTPSCertFileKey,
+				contents:  []byte(nodeConfig.APITLSCert),
+			},
+		)
+	}
+	if len(nodeConfig.APIAuthPassword) != 0 {
+		files = append(files, file{
+			flagValue: filepath.Join(nodeRootDir, apiAuthPasswordFileName),
+			path:      filepath.Join(nodeRootDir, apiAuthPasswordFileName),
+			pathKey:   config.APIAuthPasswordFileKey,
+			contents:  []byte(nodeConfig.APIAuthPassword),
+		})
+	}
 	if len(nodeConfig.ConfigFile) != 0 {
 		files = append(files, file{
 			flagValue: filepath.Join(nodeRootDir, configFileName),
@@ -1082,19 +2502,50 @@ func writeFiles(genesis []byte, nodeRootDir string, nodeConfig *node.Config) ([]
 			contents:  []byte(nodeConfig.ConfigFile),
 		})
 	}
+	// Chain config and upgrade files go to a single chain config dir
+	chainFiles := map[string][]byte{}
 	if len(nodeConfig.CChainConfigFile) != 0 {
+		chainFiles[filepath.Join(cChainConfigSubDir, configFileName)] = []byte(nodeConfig.CChainConfigFile)
+	}
+	for chain, contents := range nodeConfig.ChainConfigFiles {
+		chainFiles[filepath.Join(chainConfigSubDir, chain, configFileName)] = []byte(contents)
+	}
+	for chain, contents := range nodeConfig.UpgradeConfigFiles {
+		chainFiles[filepath.Join(chainConfigSubDir, chain, upgradeFileName)] = []byte(contents)
+	}
+	if len(chainFiles) != 0 {
+		for chainFilePath, contents := range chainFiles {
+			files = append(files, file{
+				path:     filepath.Join(nodeRootDir, chainFilePath),
+				contents: contents,
+			})
+		}
 		files = append(files, file{
 			flagValue: filepath.Join(nodeRootDir, chainConfigSubDir),
-			path:      filepath.Join(nodeRootDir, cChainConfigSubDir, configFileName),
 			pathKey:   config.ChainConfigDirKey,
-			contents:  []byte(nodeConfig.CChainConfigFile),
+		})
+	}
+	if len(nodeConfig.SubnetConfigFiles) != 0 {
+		for subnetID, contents := range nodeConfig.SubnetConfigFiles {
+			files = append(files, file{
+				path:     filepath.Join(nodeRootDir, subnetConfigSubDir, subnetID+".json"),
+				contents: []byte(contents),
+			})
+		}
+		files = append(files, file{
+			flagValue: filepath.Join(nodeRootDir, subnetConfigSubDir),
+			pathKey:   config.SubnetConfigDirKey,
 		})
 	}
 	flags := []string{}
 	for _, f := range files {
-		flags = append(flags, fmt.Sprintf("--%s=%s", f.pathKey, f.flagValue))
-		if err := createFileAndWrite(f.path, f.contents); err != nil {
-			return nil, fmt.Errorf("couldn't write file at %q: %w", f.path, err)
+		if len(f.pathKey) != 0 {
+			flags = append(flags, fmt.Sprintf("--%s=%s", f.pathKey, f.flagValue))
+		}
+		if len(f.path) != 0 {
+			if err := createFileAndWrite(f.path, f.contents); err != nil {
+				return nil, fmt.Errorf("couldn't write file at %q: %w", f.path, err)
+			}
 		}
 	}
 	return flags, nil
@@ -1,14 +1,32 @@
 package local
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -17,15 +35,35 @@ import (
 	"github.com/ava-labs/avalanche-network-runner/local/mocks"
 	"github.com/ava-labs/avalanche-network-runner/network"
 	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanche-network-runner/pkg/explorer"
+	"github.com/ava-labs/avalanche-network-runner/pkg/faucet"
+	"github.com/ava-labs/avalanche-network-runner/pkg/hosts"
+	"github.com/ava-labs/avalanche-network-runner/pkg/observability"
+	"github.com/ava-labs/avalanche-network-runner/pkg/webhook"
 	"github.com/ava-labs/avalanche-network-runner/utils"
+	avaapi "github.com/ava-labs/avalanchego/api"
+	"github.com/ava-labs/avalanchego/api/admin"
 	"github.com/ava-labs/avalanchego/api/health"
 	healthmocks "github.com/ava-labs/avalanchego/api/health/mocks"
+	"github.com/ava-labs/avalanchego/api/info"
+	"github.com/ava-labs/avalanchego/api/keystore"
 	"github.com/ava-labs/avalanchego/config"
+	avagenesis "github.com/ava-labs/avalanchego/genesis"
 	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/indexer"
 	"github.com/ava-labs/avalanchego/message"
+	"github.com/ava-labs/avalanchego/snow/choices"
 	"github.com/ava-labs/avalanchego/snow/networking/router"
+	"github.com/ava-labs/avalanchego/staking"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/crypto"
 	"github.com/ava-labs/avalanchego/utils/logging"
 	"github.com/ava-labs/avalanchego/utils/rpc"
+	"github.com/ava-labs/avalanchego/utils/units"
+	"github.com/ava-labs/avalanchego/vms/avm"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/coreth/plugin/evm"
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -48,6 +86,33 @@ func (*localTestSuccessfulNodeProcessCreator) NewNodeProcess(config node.Config,
 	return newMockProcessSuccessful(config, flags...)
 }
 
+// Flag of the configs of the nodes that failStartProcessCreator fails to create
+const failStartFlag = "test-fail-start"
+
+// Creates successful mock processes, but fails to create
+// those of nodes whose config has [failStartFlag]
+type failStartProcessCreator struct{}
+
+func (*failStartProcessCreator) NewNodeProcess(config node.Config, flags ...string) (NodeProcess, error) {
+	if _, ok := config.Flags[failStartFlag]; ok {
+		return nil, errors.New("couldn't create process")
+	}
+	return newMockProcessSuccessful(config, flags...)
+}
+
+// Creates successful mock processes, but fails to create
+// those of nodes whose staking key isn't [stakingKey]
+type stakingKeyProcessCreator struct {
+	stakingKey string
+}
+
+func (c *stakingKeyProcessCreator) NewNodeProcess(config node.Config, flags ...string) (NodeProcess, error) {
+	if config.StakingKey != c.stakingKey {
+		return nil, errors.New("couldn't create process")
+	}
+	return newMockProcessSuccessful(config, flags...)
+}
+
 type localTestFailedStartProcessCreator struct{}
 
 func (*localTestFailedStartProcessCreator) NewNodeProcess(config node.Config, flags ...string) (NodeProcess, error) {
@@ -95,9 +160,17 @@ func newMockAPISuccessful(ipAddr string, port uint16) api.Client {
 	return client
 }
 
-// Returns an API client where the Health API's Health method always returns unhealthy
+// Returns an API client where the Health API's Health method always
+// returns unhealthy, with a failing "bootstrapped" check
 func newMockAPIUnhealthy(ipAddr string, port uint16) api.Client {
-	healthReply := &health.APIHealthReply{Healthy: false}
+	checkErr := "not yet bootstrapped"
+	healthReply := &health.APIHealthReply{
+		Healthy: false,
+		Checks: map[string]health.Result{
+			"bootstrapped": {Error: &checkErr, Details: []string{"X"}, ContiguousFailures: 3},
+			"network":      {},
+		},
+	}
 	healthClient := &healthmocks.Client{}
 	healthClient.On("Health", mock.Anything).Return(healthReply, nil)
 	client := &apimocks.Client{}
@@ -110,14 +183,29 @@ func newMockProcessUndef(node.Config, ...string) (NodeProcess, error) {
 }
 
 // Returns a NodeProcess that always returns nil
-func newMockProcessSuccessful(node.Config, ...string) (NodeProcess, error) {
+func newMockProcessSuccessful(config node.Config, _ ...string) (NodeProcess, error) {
 	process := &mocks.NodeProcess{}
 	process.On("Start").Return(nil)
 	process.On("Wait").Return(nil)
 	process.On("Stop").Return(nil)
+	process.On("Pause").Return(nil)
+	process.On("Resume").Return(nil)
+	process.On("Signal", mock.Anything).Return(nil)
+	var stdin io.WriteCloser
+	if config.Stdin {
+		stdin = &bufferWriteCloser{}
+	}
+	process.On("Stdin").Return(stdin)
 	return process, nil
 }
 
+// Buffer that's the stdin of mock processes
+type bufferWriteCloser struct {
+	bytes.Buffer
+}
+
+func (*bufferWriteCloser) Close() error { return nil }
+
 type noOpInboundHandler struct{}
 
 func (*noOpInboundHandler) HandleInbound(message.InboundMessage) {}
@@ -225,12 +313,51 @@ func TestNewNetworkFailToStartNode(t *testing.T) {
 	assert.Error(err)
 }
 
+// Test that loading a snapshot returns an error, rather than
+// hanging, when starting a node returns an error
+func TestLoadSnapshotFailToStartNode(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	snapshotsDir := t.TempDir()
+	snapshotDir := filepath.Join(snapshotsDir, snapshotPrefix+"snapshot")
+	networkConfig := testNetworkConfig(t)
+	for i := range networkConfig.NodeConfigs {
+		networkConfig.NodeConfigs[i].Flags = map[string]interface{}{}
+		assert.NoError(os.MkdirAll(filepath.Join(snapshotDir, defaultDbSubdir, networkConfig.NodeConfigs[i].Name), 0o755))
+	}
+	networkConfigJSON, err := json.Marshal(networkConfig)
+	assert.NoError(err)
+	assert.NoError(os.WriteFile(filepath.Join(snapshotDir, "network.json"), networkConfigJSON, 0o600))
+
+	net, err := newNetwork(
+		logging.NoLog{},
+		newMockAPISuccessful,
+		&localTestFailedStartProcessCreator{},
+		t.TempDir(),
+		snapshotsDir,
+	)
+	assert.NoError(err)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- net.loadSnapshot(context.Background(), "snapshot")
+	}()
+	select {
+	case err := <-errCh:
+		assert.Error(err)
+	case <-time.After(10 * time.Second):
+		assert.FailNow("loading the snapshot hung")
+	}
+	assert.True(net.stopCalled())
+}
+
 // Check configs that are expected to be invalid at network creation time
 func TestWrongNetworkConfigs(t *testing.T) {
 	t.Parallel()
 	refNetworkConfig := testNetworkConfig(t)
 	tests := map[string]struct {
 		config network.Config
+		// If non-nil, the error must match it
+		expectedErr error
 	}{
 		"config file unmarshal": {
 			config: network.Config{
@@ -342,6 +469,7 @@ func TestWrongNetworkConfigs(t *testing.T) {
 					},
 				},
 			},
+			expectedErr: &network.ErrInvalidGenesis{},
 		},
 		"no network id in genesis": {
 			config: network.Config{
@@ -355,6 +483,7 @@ func TestWrongNetworkConfigs(t *testing.T) {
 					},
 				},
 			},
+			expectedErr: &network.ErrInvalidGenesis{},
 		},
 		"wrong network id type in genesis": {
 			config: network.Config{
@@ -368,6 +497,7 @@ func TestWrongNetworkConfigs(t *testing.T) {
 					},
 				},
 			},
+			expectedErr: &network.ErrInvalidGenesis{},
 		},
 		"no Genesis": {
 			config: network.Config{
@@ -380,6 +510,7 @@ func TestWrongNetworkConfigs(t *testing.T) {
 					},
 				},
 			},
+			expectedErr: &network.ErrInvalidGenesis{},
 		},
 		"StakingKey but no StakingCert": {
 			config: network.Config{
@@ -450,6 +581,7 @@ func TestWrongNetworkConfigs(t *testing.T) {
 					},
 				},
 			},
+			expectedErr: network.ErrDuplicateNodeName,
 		},
 	}
 	assert := assert.New(t)
@@ -459,6 +591,9 @@ func TestWrongNetworkConfigs(t *testing.T) {
 			assert.NoError(err)
 			err = net.loadConfig(context.Background(), tt.config)
 			assert.Error(err)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(err, tt.expectedErr)
+			}
 		})
 	}
 }
@@ -473,7 +608,15 @@ func TestUnhealthyNetwork(t *testing.T) {
 	assert.NoError(err)
 	err = net.loadConfig(context.Background(), networkConfig)
 	assert.NoError(err)
-	assert.Error(awaitNetworkHealthy(net, defaultHealthyTimeout))
+	err = awaitNetworkHealthy(net, defaultHealthyTimeout)
+	assert.ErrorIs(err, &network.UnhealthyError{})
+	var unhealthyErr *network.UnhealthyError
+	assert.True(errors.As(err, &unhealthyErr))
+	assert.Contains(net.nodes, unhealthyErr.NodeName)
+	assert.Equal(map[string]network.HealthCheckFailure{
+		"bootstrapped": {Error: "not yet bootstrapped", Details: []string{"X"}, ContiguousFailures: 3},
+	}, unhealthyErr.Checks)
+	assert.Contains(err.Error(), "bootstrapped: not yet bootstrapped")
 }
 
 // Create a network without giving names to nodes.
@@ -631,13 +774,13 @@ func TestNodeNotFound(t *testing.T) {
 	assert.Error(err)
 	// remove non-existent node
 	err = net.RemoveNode(networkConfig.NodeConfigs[1].Name)
-	assert.Error(err)
+	assert.ErrorIs(err, network.ErrNodeNotFound)
 	// remove node
 	err = net.RemoveNode(networkConfig.NodeConfigs[0].Name)
 	assert.NoError(err)
 	// get removed node
 	_, err = net.GetNode(networkConfig.NodeConfigs[0].Name)
-	assert.Error(err)
+	assert.ErrorIs(err, network.ErrNodeNotFound)
 	// remove already-removed node
 	err = net.RemoveNode(networkConfig.NodeConfigs[0].Name)
 	assert.Error(err)
@@ -680,6 +823,36 @@ func TestStoppedNetwork(t *testing.T) {
 	assert.EqualValues(err, network.ErrStopped)
 }
 
+// TestStopCancelled checks that Stop doesn't leave nodes
+// running when its context is done
+func TestStopCancelled(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	nodes := make([]*localNode, 0, len(net.nodes))
+	for _, node := range net.nodes {
+		nodes = append(nodes, node)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NoError(net.Stop(ctx))
+	for _, node := range nodes {
+		process := node.process.(*mocks.NodeProcess)
+		// Each node is stopped or killed, and then waited for
+		stopped := false
+		for _, call := range process.Calls {
+			if call.Method == "Stop" || (call.Method == "Signal" && call.Arguments[0] == os.Kill) {
+				stopped = true
+			}
+		}
+		assert.True(stopped, node.name)
+		process.AssertCalled(t, "Wait")
+	}
+}
+
 func TestGetAllNodes(t *testing.T) {
 	t.Parallel()
 	assert := assert.New(t)
@@ -697,6 +870,33 @@ func TestGetAllNodes(t *testing.T) {
 	}
 }
 
+func TestGetNodeByID(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	nodesByID, err := net.GetAllNodesByID()
+	assert.NoError(err)
+	assert.Len(nodesByID, len(net.nodes))
+	for _, node := range net.nodes {
+		assert.EqualValues(node, nodesByID[node.nodeID])
+		gotNode, err := net.GetNodeByID(node.nodeID)
+		assert.NoError(err)
+		assert.EqualValues(node, gotNode)
+	}
+	_, err = net.GetNodeByID(ids.GenerateTestNodeID())
+	assert.ErrorIs(err, network.ErrNodeNotFound)
+
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.GetNodeByID(ids.GenerateTestNodeID())
+	assert.ErrorIs(err, network.ErrStopped)
+	_, err = net.GetAllNodesByID()
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
 // TestFlags tests that we can pass flags through the network.Config
 // but also via node.Config and that the latter overrides the former
 // if same keys exist.
@@ -791,6 +991,55 @@ func TestFlags(t *testing.T) {
 	assert.NoError(err)
 }
 
+// TestRenderNodeConfigs checks that the rendered config of each node
+// has the merged network flags, node flags and config file
+func TestRenderNodeConfigs(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.Flags = map[string]interface{}{
+		"test-network-config-flag": "network",
+		"common-config-flag":       "network",
+	}
+	for i := range networkConfig.NodeConfigs {
+		networkConfig.NodeConfigs[i].Flags = map[string]interface{}{
+			"common-config-flag": "node",
+		}
+	}
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	configs, err := net.RenderNodeConfigs()
+	assert.NoError(err)
+	assert.Len(configs, len(networkConfig.NodeConfigs))
+	for _, nodeConfig := range networkConfig.NodeConfigs {
+		rendered, ok := configs[nodeConfig.Name]
+		assert.True(ok)
+		assert.Equal(nodeConfig.BinaryPath, rendered.BinaryPath)
+		assert.Equal(nodeConfig.ConfigFile, rendered.ConfigFile)
+		assert.Contains(rendered.Args, "--common-config-flag=node")
+		assert.Contains(rendered.Args, "--test-network-config-flag=network")
+		commonFlag, _ := rendered.Flags.Get("common-config-flag")
+		assert.Equal("node", commonFlag)
+		// Flags of the config file are merged, and
+		// overridden by the command line flags
+		var configFile map[string]interface{}
+		assert.NoError(json.Unmarshal([]byte(nodeConfig.ConfigFile), &configFile))
+		for key := range configFile {
+			_, ok := rendered.Flags.Get(key)
+			assert.True(ok, key)
+		}
+		apiPort, err := rendered.Flags.Int(config.HTTPPortKey)
+		assert.NoError(err)
+		assert.EqualValues(net.nodes[nodeConfig.Name].GetAPIPort(), apiPort)
+	}
+
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.RenderNodeConfigs()
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
 // for the TestChildCmdRedirection we need to be able to wait
 // until the buffer is written to or else there is a race condition
 type lockedBuffer struct {
@@ -876,6 +1125,193 @@ func TestChildCmdRedirection(t *testing.T) {
 	}
 }
 
+// TestNodeProcessEnv tests that nodes are started with the
+// environment variables and working dir of their config
+func TestNodeProcessEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	assert := assert.New(t)
+	npc := &nodeProcessCreator{
+		colorPicker: utils.NewColorPicker(),
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+	}
+	workingDir := t.TempDir()
+	testConfig := node.Config{
+		BinaryPath: "sh",
+		Env:        []string{"ANR_TEST_VAR=value"},
+		WorkingDir: workingDir,
+	}
+	proc, err := npc.NewNodeProcess(testConfig, "-c", `[ "$ANR_TEST_VAR" = value ] && [ "$(pwd -P)" = "$(cd "$1" && pwd -P)" ]`, "sh", workingDir)
+	assert.NoError(err)
+	assert.NoError(proc.Start())
+	assert.NoError(proc.Wait())
+}
+
+// TestNodeProcessPriority tests that nodes are started with
+// the niceness and CPU affinity of their config
+func TestNodeProcessPriority(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("CPU affinity is only supported on linux")
+	}
+	assert := assert.New(t)
+	npc := &nodeProcessCreator{
+		colorPicker: utils.NewColorPicker(),
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+	}
+	testConfig := node.Config{
+		BinaryPath:  "sh",
+		Nice:        5,
+		CPUAffinity: []int{0},
+	}
+	// The 19th field of /proc/<pid>/stat is the niceness
+	proc, err := npc.NewNodeProcess(testConfig, "-c", `[ "$(cut -d' ' -f19 /proc/$$/stat)" = 5 ] && grep -q "^Cpus_allowed_list:[[:space:]]*0$" /proc/$$/status`)
+	assert.NoError(err)
+	assert.NoError(proc.Start())
+	assert.NoError(proc.Wait())
+
+	// Without nice and taskset, neither can be set
+	shPath, err := exec.LookPath("sh")
+	assert.NoError(err)
+	t.Setenv("PATH", t.TempDir())
+	assert.False(niceSupported())
+	assert.False(cpuAffinitySupported())
+	_, err = npc.NewNodeProcess(node.Config{BinaryPath: shPath, Nice: 5})
+	assert.ErrorIs(err, exec.ErrNotFound)
+	assert.Contains(err.Error(), "nice wasn't found")
+	_, err = npc.NewNodeProcess(node.Config{BinaryPath: shPath, CPUAffinity: []int{0}})
+	assert.ErrorIs(err, exec.ErrNotFound)
+	assert.Contains(err.Error(), "taskset (from util-linux) wasn't found")
+}
+
+// TestNodeProcessExitStatus tests that the exit code, signal and
+// last stderr lines of exited nodes are recorded
+func TestNodeProcessExitStatus(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	assert := assert.New(t)
+	npc := &nodeProcessCreator{
+		colorPicker: utils.NewColorPicker(),
+		stdout:      io.Discard,
+		stderr:      io.Discard,
+	}
+	stderrPath := filepath.Join(t.TempDir(), "stderr.log")
+	for _, testConfig := range []node.Config{
+		{BinaryPath: "sh", StderrPath: stderrPath},
+		{BinaryPath: "sh", RedirectStderr: true},
+		{BinaryPath: "sh"},
+	} {
+		proc, err := npc.NewNodeProcess(testConfig, "-c", `for i in $(seq 1 30); do echo "line $i" >&2; done; exit 3`)
+		assert.NoError(err)
+		n := &localNode{process: proc}
+		_, exited := n.GetExitStatus()
+		assert.False(exited)
+		assert.NoError(proc.Start())
+		assert.Error(proc.Wait())
+		status, exited := n.GetExitStatus()
+		assert.True(exited)
+		assert.Equal(3, status.ExitCode)
+		assert.Empty(status.Signal)
+		assert.Len(status.StderrTail, stderrTailLines)
+		assert.Equal("line 30", status.StderrTail[len(status.StderrTail)-1])
+		// Each line of the tail is a whole line
+		for _, line := range status.StderrTail {
+			assert.Regexp(`^line \d+$`, line)
+		}
+	}
+
+	proc, err := npc.NewNodeProcess(node.Config{BinaryPath: "sh"}, "-c", "kill -TERM $$")
+	assert.NoError(err)
+	assert.NoError(proc.Start())
+	assert.Error(proc.Wait())
+	status, exited := (&localNode{process: proc}).GetExitStatus()
+	assert.True(exited)
+	assert.Equal(-1, status.ExitCode)
+	assert.Equal(syscall.SIGTERM.String(), status.Signal)
+}
+
+// TestNodeProcessOutputFiles tests that node output
+// is appended to the files of its config
+func TestNodeProcessOutputFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	assert := assert.New(t)
+	npc := &nodeProcessCreator{
+		colorPicker: utils.NewColorPicker(),
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+	}
+	dir := t.TempDir()
+	testConfig := node.Config{
+		BinaryPath: "sh",
+		StdoutPath: filepath.Join(dir, "output", "stdout.log"),
+		StderrPath: filepath.Join(dir, "output", "stderr.log"),
+	}
+	// Output is appended, e.g. across restarts
+	for i := 0; i < 2; i++ {
+		proc, err := npc.NewNodeProcess(testConfig, "-c", "echo out; echo err >&2")
+		assert.NoError(err)
+		assert.NoError(proc.Start())
+		assert.NoError(proc.Wait())
+	}
+	stdout, err := os.ReadFile(testConfig.StdoutPath)
+	assert.NoError(err)
+	assert.Equal("out\nout\n", string(stdout))
+	stderr, err := os.ReadFile(testConfig.StderrPath)
+	assert.NoError(err)
+	assert.Equal("err\nerr\n", string(stderr))
+
+	// Both to the same file
+	testConfig.StdoutPath = filepath.Join(dir, "output.log")
+	testConfig.StderrPath = testConfig.StdoutPath
+	proc, err := npc.NewNodeProcess(testConfig, "-c", "echo out; echo err >&2")
+	assert.NoError(err)
+	assert.NoError(proc.Start())
+	assert.NoError(proc.Wait())
+	output, err := os.ReadFile(testConfig.StdoutPath)
+	assert.NoError(err)
+	assert.Equal("out\nerr\n", string(output))
+}
+
+// TestNodeProcessSignalStdin tests that nodes can be
+// sent signals, and written to if their stdin is a pipe
+func TestNodeProcessSignalStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	assert := assert.New(t)
+	npc := &nodeProcessCreator{
+		colorPicker: utils.NewColorPicker(),
+		stdout:      io.Discard,
+		stderr:      io.Discard,
+	}
+	proc, err := npc.NewNodeProcess(node.Config{BinaryPath: "sh"}, "-c", "exit 0")
+	assert.NoError(err)
+	assert.Nil(proc.Stdin())
+
+	// The line read is written to the file $1, then the
+	// process exits successfully once it gets a SIGHUP
+	outputPath := filepath.Join(t.TempDir(), "output")
+	proc, err = npc.NewNodeProcess(
+		node.Config{BinaryPath: "sh", Stdin: true},
+		"-c", `trap 'exit 0' HUP; read line; echo "$line" > "$1"; while :; do sleep 0.1; done`, "sh", outputPath,
+	)
+	assert.NoError(err)
+	assert.NoError(proc.Start())
+	_, err = io.WriteString(proc.Stdin(), "hello\n")
+	assert.NoError(err)
+	assert.Eventually(func() bool {
+		output, err := os.ReadFile(outputPath)
+		return err == nil && string(output) == "hello\n"
+	}, 10*time.Second, 50*time.Millisecond)
+	assert.NoError(proc.Signal(syscall.SIGHUP))
+	assert.NoError(proc.Wait())
+}
+
 // checkNetwork receives a network, a set of running nodes (started and not removed yet), and
 // a set of removed nodes, checking:
 // - GetNodeNames retrieves the correct number of running nodes
@@ -941,7 +1377,7 @@ func awaitNetworkHealthy(net network.Network, timeout time.Duration) error {
 	return net.Healthy(ctx)
 }
 
-func TestAddNetworkFlags(t *testing.T) {
+func TestApplyNetworkConfigLayer(t *testing.T) {
 	t.Parallel()
 	type test struct {
 		name            string
@@ -974,14 +1410,32 @@ func TestAddNetworkFlags(t *testing.T) {
 			beforeNodeFlags: map[string]interface{}{"2": 2},
 			afterNodeFlags:  map[string]interface{}{"2": 2},
 		},
+		{
+			name:            "node flags take precedence",
+			netFlags:        map[string]interface{}{"1": 1},
+			beforeNodeFlags: map[string]interface{}{"1": 2},
+			afterNodeFlags:  map[string]interface{}{"1": 2},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			assert := assert.New(t)
-			addNetworkFlags(logging.NoLog{}, tt.netFlags, tt.beforeNodeFlags)
-			assert.Equal(tt.afterNodeFlags, tt.beforeNodeFlags)
+			nodeConfig := node.Config{Flags: tt.beforeNodeFlags}
+			networkConfigLayer{flags: tt.netFlags}.apply(logging.NoLog{}, &nodeConfig)
+			assert.Equal(tt.afterNodeFlags, nodeConfig.Flags)
 		})
 	}
+
+	// The node's C-Chain config file isn't given twice
+	assert := assert.New(t)
+	nodeConfig := node.Config{
+		CChainConfigFile: "node C",
+		ChainConfigFiles: map[string]string{"X": "node X"},
+	}
+	networkConfigLayer{
+		chainConfigFiles: map[string]string{"C": "network C", "X": "network X", "P": "network P"},
+	}.apply(logging.NoLog{}, &nodeConfig)
+	assert.Equal(map[string]string{"X": "node X", "P": "network P"}, nodeConfig.ChainConfigFiles)
 }
 
 func TestSetNodeName(t *testing.T) {
@@ -1021,81 +1475,124 @@ func TestSetNodeName(t *testing.T) {
 	config.Name = "hi"
 	ln.nodes = map[string]*localNode{"hi": nil}
 	err = ln.setNodeName(config)
-	assert.Error(err)
+	assert.ErrorIs(err, network.ErrDuplicateNodeName)
 }
 
-func TestGetConfigEntry(t *testing.T) {
+func TestMergeConfigFile(t *testing.T) {
 	t.Parallel()
 	assert := assert.New(t)
 
+	// Flags of the node's config take precedence over its config file
+	flags, err := mergeConfigFile(node.Config{
+		ConfigFile: `{"1":"file","2":"file"}`,
+		Flags:      map[string]interface{}{"1": "node"},
+	})
+	assert.NoError(err)
+	assert.Equal(mergedFlags{"1": "node", "2": "file"}, flags)
+
 	// case: key not present
-	val, err := getConfigEntry(
-		map[string]interface{}{},
-		map[string]interface{}{"2": "2"},
-		"1",
-		"1",
-	)
+	val, err := flags.stringFlag("3", "default")
 	assert.NoError(err)
-	assert.Equal("1", val)
+	assert.Equal("default", val)
 
 	// case: key present
-	val, err = getConfigEntry(
-		map[string]interface{}{},
-		map[string]interface{}{"1": "hi", "2": "2"},
-		"1",
-		"1",
-	)
+	val, err = flags.stringFlag("2", "default")
 	assert.NoError(err)
-	assert.Equal("hi", val)
+	assert.Equal("file", val)
 
 	// case: key present wrong type
-	_, err = getConfigEntry(
-		map[string]interface{}{},
-		map[string]interface{}{"1": 1, "2": "2"},
-		"1",
-		"1",
-	)
+	_, err = mergedFlags{"1": 1}.stringFlag("1", "default")
+	assert.Error(err)
+
+	// case: config file isn't a JSON object
+	_, err = mergeConfigFile(node.Config{ConfigFile: "not json"})
 	assert.Error(err)
+
+	// case: config dirs managed by the runner
+	_, err = mergeConfigFile(node.Config{
+		ConfigFile:       fmt.Sprintf(`{"%s":"dir"}`, config.ChainConfigDirKey),
+		CChainConfigFile: "{}",
+	})
+	assert.Error(err)
+	_, err = mergeConfigFile(node.Config{
+		Flags:             map[string]interface{}{config.SubnetConfigDirKey: "dir"},
+		SubnetConfigFiles: map[string]string{ids.GenerateTestID().String(): "{}"},
+	})
+	assert.Error(err)
+	_, err = mergeConfigFile(node.Config{
+		Flags: map[string]interface{}{config.ChainConfigDirKey: "dir", config.SubnetConfigDirKey: "dir"},
+	})
+	assert.NoError(err)
+}
+
+func TestNewDeterministicConfigNNodes(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	_, err := NewDeterministicConfigNNodes("pepito", 6, 0)
+	assert.Error(err)
+
+	config1, err := NewDeterministicConfigNNodes("pepito", 6, 1)
+	assert.NoError(err)
+	assert.EqualValues(1, config1.RandomSeed)
+	assert.Len(config1.NodeConfigs, 6)
+	config2, err := NewDeterministicConfigNNodes("pepito", 6, 1)
+	assert.NoError(err)
+	assert.Equal(config1, config2)
+
+	nodeID, err := utils.ToNodeID([]byte(config1.NodeConfigs[5].StakingKey), []byte(config1.NodeConfigs[5].StakingCert))
+	assert.NoError(err)
+	for _, nodeConfig := range config1.NodeConfigs[:5] {
+		otherNodeID, err := utils.ToNodeID([]byte(nodeConfig.StakingKey), []byte(nodeConfig.StakingCert))
+		assert.NoError(err)
+		assert.NotEqual(nodeID, otherNodeID)
+	}
 }
 
 func TestGetPort(t *testing.T) {
 	t.Parallel()
 	assert := assert.New(t)
+	rng := utils.NewRand(0)
 
-	// Case: port key present in config file
+	// Case: port key present, as decoded from a config file
 	port, err := getPort(
-		map[string]interface{}{},
-		map[string]interface{}{"flag": float64(13)},
+		rng,
+		mergedFlags{"flag": float64(19613)},
 		"flag",
 	)
 	assert.NoError(err)
-	assert.Equal(uint16(13), port)
+	assert.Equal(uint16(19613), port)
 
-	// Case: port key present in flags
+	// Case: port key present, as given in node.Config.Flags
 	port, err = getPort(
-		map[string]interface{}{"flag": 13},
-		map[string]interface{}{},
+		rng,
+		mergedFlags{"flag": 19613},
 		"flag",
 	)
 	assert.NoError(err)
-	assert.Equal(uint16(13), port)
+	assert.Equal(uint16(19613), port)
 
-	// Case: port key present in config file and flags
-	port, err = getPort(
-		map[string]interface{}{"flag": 13},
-		map[string]interface{}{"flag": float64(14)},
+	// Case: port key not present
+	_, err = getPort(
+		rng,
+		mergedFlags{},
 		"flag",
 	)
 	assert.NoError(err)
-	assert.Equal(uint16(13), port)
 
-	// Case: port key not present
+	// Case: given port already bound
+	listener, err := net.Listen("tcp", ":0")
+	assert.NoError(err)
+	defer listener.Close()
+	boundPort := uint16(listener.Addr().(*net.TCPAddr).Port)
 	_, err = getPort(
-		map[string]interface{}{},
-		map[string]interface{}{},
+		rng,
+		mergedFlags{"flag": int(boundPort)},
 		"flag",
 	)
-	assert.NoError(err)
+	assert.ErrorIs(err, &network.ErrPortInUse{})
+	assert.ErrorIs(err, &network.ErrPortInUse{Port: boundPort})
+	assert.False(errors.Is(err, &network.ErrPortInUse{Port: boundPort + 1}))
 }
 
 func TestCreateFileAndWrite(t *testing.T) {
@@ -1134,6 +1631,9 @@ func TestWriteFiles(t *testing.T) {
 	chainConfigDir := filepath.Join(tmpDir, chainConfigSubDir)
 	cChainConfigPath := filepath.Join(tmpDir, chainConfigSubDir, "C", configFileName)
 	chainConfigDirFlag := fmt.Sprintf("--%s=%v", config.ChainConfigDirKey, chainConfigDir)
+	subnetConfigDir := filepath.Join(tmpDir, subnetConfigSubDir)
+	subnetConfigDirFlag := fmt.Sprintf("--%s=%v", config.SubnetConfigDirKey, subnetConfigDir)
+	subnetID := ids.GenerateTestID().String()
 
 	type test struct {
 		name          string
@@ -1192,6 +1692,40 @@ func TestWriteFiles(t *testing.T) {
 				chainConfigDirFlag,
 			},
 		},
+		{
+			name:      "c-chain config, chain config and upgrade files given",
+			shouldErr: false,
+			genesis:   genesis,
+			nodeConfig: node.Config{
+				StakingKey:         stakingKey,
+				StakingCert:        stakingCert,
+				CChainConfigFile:   cChainConfigFile,
+				ChainConfigFiles:   map[string]string{"X": "x-chain-config"},
+				UpgradeConfigFiles: map[string]string{"C": "c-chain-upgrade"},
+			},
+			expectedFlags: []string{
+				stakingKeyFlag,
+				stakingCertFlag,
+				genesisFlag,
+				chainConfigDirFlag,
+			},
+		},
+		{
+			name:      "subnet config file given",
+			shouldErr: false,
+			genesis:   genesis,
+			nodeConfig: node.Config{
+				StakingKey:        stakingKey,
+				StakingCert:       stakingCert,
+				SubnetConfigFiles: map[string]string{subnetID: "subnet-config"},
+			},
+			expectedFlags: []string{
+				stakingKeyFlag,
+				stakingCertFlag,
+				genesisFlag,
+				subnetConfigDirFlag,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1225,6 +1759,21 @@ func TestWriteFiles(t *testing.T) {
 				assert.NoError(err)
 				assert.Equal([]byte(cChainConfigFile), gotCChainConfigFile)
 			}
+			for chain, contents := range tt.nodeConfig.ChainConfigFiles {
+				gotChainConfigFile, err := os.ReadFile(filepath.Join(chainConfigDir, chain, configFileName))
+				assert.NoError(err)
+				assert.Equal([]byte(contents), gotChainConfigFile)
+			}
+			for chain, contents := range tt.nodeConfig.UpgradeConfigFiles {
+				gotUpgradeFile, err := os.ReadFile(filepath.Join(chainConfigDir, chain, upgradeFileName))
+				assert.NoError(err)
+				assert.Equal([]byte(contents), gotUpgradeFile)
+			}
+			for subnetID, contents := range tt.nodeConfig.SubnetConfigFiles {
+				gotSubnetConfigFile, err := os.ReadFile(filepath.Join(subnetConfigDir, subnetID+".json"))
+				assert.NoError(err)
+				assert.Equal([]byte(contents), gotSubnetConfigFile)
+			}
 		})
 	}
 }
@@ -1254,10 +1803,10 @@ func TestRemoveBeacon(t *testing.T) {
 }
 
 // Returns an API client where:
-// * The Health API's Health method always returns an error after the
-//   given context is cancelled.
-// * The CChainEthAPI's Close method may be called
-// * Only the above 2 methods may be called
+//   - The Health API's Health method always returns an error after the
+//     given context is cancelled.
+//   - The CChainEthAPI's Close method may be called
+//   - Only the above 2 methods may be called
 func newMockAPIHealthyBlocks(ipAddr string, port uint16) api.Client {
 	healthClient := &healthmocks.Client{}
 	healthClient.On("Health", mock.MatchedBy(func(_ context.Context) bool { return true }), mock.Anything).Return(
@@ -1307,3 +1856,3124 @@ func TestHealthyDuringNetworkStop(t *testing.T) {
 		assert.Fail("Healthy should've returned immediately because network closed")
 	}
 }
+
+// TestHistory checks that network operations and their outcomes are recorded
+func TestHistory(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	historyFile := filepath.Join(t.TempDir(), "history.json")
+	networkConfig.HistoryFile = historyFile
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	assert.NoError(net.RemoveNode(networkConfig.NodeConfigs[0].Name))
+	assert.Error(net.RemoveNode("not a node"))
+	assert.NoError(net.Stop(context.Background()))
+
+	history := net.History()
+	assert.Len(history, 4)
+	assert.Equal(network.OpLoadConfig, history[0].Name)
+	assert.True(history[0].Succeeded())
+	assert.Equal(network.OpRemoveNode, history[1].Name)
+	assert.Equal(networkConfig.NodeConfigs[0].Name, history[1].Target)
+	assert.True(history[1].Succeeded())
+	assert.Equal(network.OpRemoveNode, history[2].Name)
+	assert.False(history[2].Succeeded())
+	assert.Equal(network.OpStop, history[3].Name)
+
+	// the history file has one line per operation
+	historyBytes, err := os.ReadFile(historyFile)
+	assert.NoError(err)
+	assert.Len(strings.Split(strings.TrimSpace(string(historyBytes)), "\n"), 4)
+}
+
+func TestAggregatedLogs(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	defer func() {
+		assert.NoError(net.Stop(context.Background()))
+	}()
+	names, err := net.GetNodeNames()
+	assert.NoError(err)
+	sort.Strings(names)
+	node1, err := net.GetNode(names[0])
+	assert.NoError(err)
+	node2, err := net.GetNode(names[1])
+	assert.NoError(err)
+
+	assert.NoError(os.MkdirAll(node1.GetLogsDir(), 0o755))
+	assert.NoError(os.MkdirAll(node2.GetLogsDir(), 0o755))
+	assert.NoError(os.WriteFile(filepath.Join(node1.GetLogsDir(), "main.log"), []byte(
+		"[06-01|12:00:00.000] INFO node/node.go:1 first\n"+
+			"[06-01|12:00:02.000] WARN node/node.go:2 third\n"+
+			"[06-01|12:00:03.000] DEBUG node/node.go:3 fourth\n",
+	), 0o600))
+	assert.NoError(os.WriteFile(filepath.Join(node2.GetLogsDir(), "C.log"), []byte(
+		"[06-01|12:00:01.000] ERROR <C Chain> vm.go:1 second\n"+
+			"stack trace of second\n"+
+			"[06-01|12:00:04.000] INFO <C Chain> vm.go:1 incomplete",
+	), 0o600))
+
+	collect := func(filter network.LogFilter) []network.LogLine {
+		var lines []network.LogLine
+		for line := range net.AggregatedLogs(context.Background(), filter) {
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	// Lines are merged in timestamp order, and incomplete lines are skipped
+	lines := collect(network.LogFilter{Nodes: names[:2]})
+	assert.Len(lines, 5)
+	for i, expected := range []struct {
+		node string
+		file string
+		text string
+	}{
+		{names[0], "main.log", "first"},
+		{names[1], "C.log", "second"},
+		{names[1], "C.log", "stack trace of second"},
+		{names[0], "main.log", "third"},
+		{names[0], "main.log", "fourth"},
+	} {
+		assert.Equal(expected.node, lines[i].Node)
+		assert.Equal(expected.file, lines[i].File)
+		assert.Contains(lines[i].Line, expected.text)
+	}
+	assert.Equal(lines[1].Timestamp, lines[2].Timestamp)
+	assert.Equal(logging.Error, lines[2].Level)
+
+	// Filter by level
+	lines = collect(network.LogFilter{MinLevel: logging.Warn})
+	assert.Len(lines, 3)
+
+	// Filter by node and regex
+	lines = collect(network.LogFilter{Nodes: names[:1], Regex: regexp.MustCompile("th")})
+	assert.Len(lines, 2)
+}
+
+func TestGetFlags(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.NodeConfigs[0].ConfigFile = `{"log-level": "debug", "http-port": 1}`
+	networkConfig.NodeConfigs[0].Flags = map[string]interface{}{
+		config.HTTPPortKey:     9650,
+		config.IndexEnabledKey: true,
+	}
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	defer func() {
+		assert.NoError(net.Stop(context.Background()))
+	}()
+	node, err := net.GetNode(networkConfig.NodeConfigs[0].Name)
+	assert.NoError(err)
+	flags := node.GetFlags()
+
+	// From the config file
+	logLevel, ok := flags.Get(config.LogLevelKey)
+	assert.True(ok)
+	assert.Equal("debug", logLevel)
+	// From the node config, overriding the config file
+	httpPort, err := flags.Int(config.HTTPPortKey)
+	assert.NoError(err)
+	assert.EqualValues(9650, httpPort)
+	indexEnabled, err := flags.Bool(config.IndexEnabledKey)
+	assert.NoError(err)
+	assert.True(indexEnabled)
+	// Set by the runner
+	networkID, err := flags.Int(config.NetworkNameKey)
+	assert.NoError(err)
+	assert.EqualValues(net.networkID, networkID)
+	_, err = flags.Duration("not-a-flag")
+	assert.Error(err)
+}
+
+// TestConfigPrecedence tests that a node's flags and chain config files are
+// merged from its config, its network's config and its config file
+func TestConfigPrecedence(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.Flags = map[string]interface{}{
+		config.LogLevelKey:        "info",
+		config.LogDisplayLevelKey: "info",
+	}
+	networkConfig.ChainConfigFiles = map[string]string{"X": "network X", "P": "network P"}
+	nodeConfig := &networkConfig.NodeConfigs[0]
+	nodeConfig.ConfigFile = `{"log-level": "debug", "log-display-level": "debug", "log-format": "json"}`
+	nodeConfig.Flags = map[string]interface{}{config.LogLevelKey: "warn"}
+	nodeConfig.ChainConfigFiles = map[string]string{"X": "node X"}
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	defer func() {
+		assert.NoError(net.Stop(context.Background()))
+	}()
+	node, err := net.GetNode(nodeConfig.Name)
+	assert.NoError(err)
+	flags := node.GetFlags()
+
+	for flagName, expected := range map[string]string{
+		// From the node config
+		config.LogLevelKey: "warn",
+		// From the network config, overriding the config file
+		config.LogDisplayLevelKey: "info",
+		// From the config file
+		config.LogFormatKey: "json",
+	} {
+		val, ok := flags.Get(flagName)
+		assert.True(ok)
+		assert.Equal(expected, val, flagName)
+	}
+	// The node's chain config file overrides the network's
+	for chain, expected := range map[string]string{"X": "node X", "P": "network P"} {
+		contents, err := os.ReadFile(filepath.Join(net.manifest[nodeConfig.Name].dir, chainConfigSubDir, chain, configFileName))
+		assert.NoError(err)
+		assert.Equal(expected, string(contents))
+	}
+	// The network's flags aren't added to the given node config
+	assert.Equal(map[string]interface{}{config.LogLevelKey: "warn"}, nodeConfig.Flags)
+}
+
+func TestMergeConfigFiles(t *testing.T) {
+	assert := assert.New(t)
+	subnetID1, subnetID2 := ids.GenerateTestID().String(), ids.GenerateTestID().String()
+	networkFiles := map[string]string{subnetID1: "network1", subnetID2: "network2"}
+	nodeFiles := map[string]string{subnetID2: "node2"}
+	merged := mergeConfigFiles(networkFiles, nodeFiles)
+	assert.Equal(map[string]string{subnetID1: "network1", subnetID2: "node2"}, merged)
+	// The given maps aren't modified
+	assert.Equal(map[string]string{subnetID2: "node2"}, nodeFiles)
+	assert.Equal(nodeFiles, mergeConfigFiles(nil, nodeFiles))
+}
+
+func TestUpdateNodeFlags(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &failStartProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	nodeName := networkConfig.NodeConfigs[0].Name
+	oldNode, err := net.GetNode(nodeName)
+	assert.NoError(err)
+
+	assert.NoError(net.UpdateNodeFlags(nodeName, map[string]interface{}{config.SnowSampleSizeKey: 10}))
+	newNode, err := net.GetNode(nodeName)
+	assert.NoError(err)
+	sampleSize, err := newNode.GetFlags().Int(config.SnowSampleSizeKey)
+	assert.NoError(err)
+	assert.EqualValues(10, sampleSize)
+	// The node keeps its identity, ports and directories
+	assert.Equal(oldNode.GetNodeID(), newNode.GetNodeID())
+	assert.Equal(oldNode.GetAPIPort(), newNode.GetAPIPort())
+	assert.Equal(oldNode.GetP2PPort(), newNode.GetP2PPort())
+	assert.Equal(oldNode.GetDbDir(), newNode.GetDbDir())
+	assert.Equal(oldNode.GetLogsDir(), newNode.GetLogsDir())
+
+	// A nil value removes the flag
+	assert.NoError(net.UpdateNodeFlags(nodeName, map[string]interface{}{config.SnowSampleSizeKey: nil}))
+	newNode, err = net.GetNode(nodeName)
+	assert.NoError(err)
+	_, ok := newNode.GetFlags().Get(config.SnowSampleSizeKey)
+	assert.False(ok)
+
+	// A node that doesn't start with its new flags
+	// is started again with its previous ones
+	assert.Error(net.UpdateNodeFlags(nodeName, map[string]interface{}{failStartFlag: true}))
+	newNode, err = net.GetNode(nodeName)
+	assert.NoError(err)
+	_, ok = newNode.GetFlags().Get(failStartFlag)
+	assert.False(ok)
+	assert.Equal(oldNode.GetNodeID(), newNode.GetNodeID())
+	assert.Equal(oldNode.GetAPIPort(), newNode.GetAPIPort())
+
+	assert.Error(net.UpdateNodeFlags("not a node", nil))
+	assert.NoError(net.Stop(context.Background()))
+	assert.ErrorIs(net.UpdateNodeFlags(nodeName, nil), network.ErrStopped)
+}
+
+// A P-Chain client with no validators
+type noValidatorsTestPClient struct {
+	platformvm.Client
+}
+
+func (*noValidatorsTestPClient) GetCurrentValidators(context.Context, ids.ID, []ids.NodeID, ...rpc.Option) ([]platformvm.ClientPrimaryValidator, error) {
+	return nil, nil
+}
+
+func TestRotateStakingKey(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	newAPIClientF := func(ipAddr string, port uint16) api.Client {
+		client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+		client.On("PChainAPI").Return(&noValidatorsTestPClient{})
+		return client
+	}
+	networkConfig := testNetworkConfig(t)
+	net, err := newNetwork(logging.NoLog{}, newAPIClientF, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	nodeName := networkConfig.NodeConfigs[0].Name
+	oldNode, err := net.GetNode(nodeName)
+	assert.NoError(err)
+
+	nodeID, err := net.RotateStakingKey(context.Background(), nodeName, network.RotateStakingKeyOptions{})
+	assert.NoError(err)
+	newNode, err := net.GetNode(nodeName)
+	assert.NoError(err)
+	assert.NotEqual(oldNode.GetNodeID(), nodeID)
+	assert.Equal(nodeID, newNode.GetNodeID())
+	// The new key/cert is the node's
+	stakingKey, _ := newNode.GetFlags().Get(config.StakingKeyPathKey)
+	stakingCert, _ := newNode.GetFlags().Get(config.StakingCertPathKey)
+	keyBytes, err := os.ReadFile(stakingKey)
+	assert.NoError(err)
+	certBytes, err := os.ReadFile(stakingCert)
+	assert.NoError(err)
+	fileNodeID, err := utils.ToNodeID(keyBytes, certBytes)
+	assert.NoError(err)
+	assert.Equal(nodeID, fileNodeID)
+	// The node keeps its ports and directories
+	assert.Equal(oldNode.GetAPIPort(), newNode.GetAPIPort())
+	assert.Equal(oldNode.GetP2PPort(), newNode.GetP2PPort())
+	assert.Equal(oldNode.GetDbDir(), newNode.GetDbDir())
+	_, err = net.GetNodeByID(oldNode.GetNodeID())
+	assert.Error(err)
+
+	// The old node ID must be a validator to be replaced
+	_, err = net.RotateStakingKey(context.Background(), nodeName, network.RotateStakingKeyOptions{AddValidator: true})
+	assert.Error(err)
+	assert.Contains(err.Error(), "isn't a primary network validator")
+	sameNode, err := net.GetNode(nodeName)
+	assert.NoError(err)
+	assert.Equal(nodeID, sameNode.GetNodeID())
+
+	// A node that doesn't start with its new key keeps its old one
+	keyBefore := net.nodes[nodeName].config.StakingKey
+	net.nodeProcessCreator = &stakingKeyProcessCreator{stakingKey: keyBefore}
+	_, err = net.RotateStakingKey(context.Background(), nodeName, network.RotateStakingKeyOptions{})
+	assert.Error(err)
+	sameNode, err = net.GetNode(nodeName)
+	assert.NoError(err)
+	assert.Equal(nodeID, sameNode.GetNodeID())
+	assert.Equal(keyBefore, net.nodes[nodeName].config.StakingKey)
+	net.nodeProcessCreator = &localTestSuccessfulNodeProcessCreator{}
+
+	_, err = net.RotateStakingKey(context.Background(), "not a node", network.RotateStakingKeyOptions{})
+	assert.ErrorIs(err, network.ErrNodeNotFound)
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.RotateStakingKey(context.Background(), nodeName, network.RotateStakingKeyOptions{})
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+func TestGetGenesis(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	genesis, err := net.GetGenesis()
+	assert.NoError(err)
+	assert.EqualValues(net.networkID, genesis.NetworkID)
+	assert.Equal(net.nodes["node0"].nodeID, genesis.InitialStakers[0].NodeID)
+	// The C-Chain address of genesis.EWOQKey is funded
+	ewoqAddr := ethcommon.HexToAddress("0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC")
+	assert.Positive(genesis.CChain.Balances[ewoqAddr].Sign())
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.GetGenesis()
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+func TestVerifyTeardown(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	ln, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(ln.loadConfig(context.Background(), networkConfig))
+	_, err = ln.VerifyTeardown()
+	assert.ErrorIs(err, network.ErrNotStopped)
+
+	assert.NoError(ln.Stop(context.Background()))
+	report, err := ln.VerifyTeardown()
+	assert.NoError(err)
+	assert.Empty(report.Violations)
+	assert.NoError(report.Err())
+
+	// A port still bound and a data dir removed are reported
+	nodeName := networkConfig.NodeConfigs[0].Name
+	manifest := ln.manifest[nodeName]
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", manifest.apiPort))
+	assert.NoError(err)
+	defer l.Close()
+	assert.NoError(os.RemoveAll(manifest.dir))
+	report, err = ln.VerifyTeardown()
+	assert.NoError(err)
+	assert.Len(report.Violations, 2)
+	assert.Equal(network.ViolationPortBound, report.Violations[0].Kind)
+	assert.Equal(network.ViolationDataDir, report.Violations[1].Kind)
+	assert.Error(report.Err())
+
+	// A process still alive is reported, even if its exit was recorded
+	if runtime.GOOS == "windows" {
+		return
+	}
+	sleepPath, err := exec.LookPath("sleep")
+	assert.NoError(err)
+	process := exec.Command(sleepPath, "60")
+	assert.NoError(process.Start())
+	defer func() {
+		_ = process.Process.Kill()
+		_ = process.Wait()
+	}()
+	manifest = ln.manifest[networkConfig.NodeConfigs[1].Name]
+	manifest.pid = process.Process.Pid
+	manifest.binaryPath = resolveBinaryPath(sleepPath)
+	assert.True(manifest.exited)
+	report, err = ln.VerifyTeardown()
+	assert.NoError(err)
+	assert.Len(report.Violations, 3)
+	assert.Equal(network.TeardownViolation{
+		Node:    networkConfig.NodeConfigs[1].Name,
+		Kind:    network.ViolationProcessRunning,
+		Details: fmt.Sprintf("process %d is still alive", process.Process.Pid),
+	}, report.Violations[2])
+}
+
+func TestDataDirCleanup(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	// Node dirs are created under the root data dir
+	networkConfig := testNetworkConfig(t)
+	networkConfig.RootDataDir = filepath.Join(t.TempDir(), "data")
+	networkConfig.DataDirCleanup = network.DataDirAlwaysDelete
+	ln, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(ln.loadConfig(context.Background(), networkConfig))
+	nodeName := networkConfig.NodeConfigs[0].Name
+	nodeDir := filepath.Join(networkConfig.RootDataDir, nodeName)
+	assert.Equal(nodeDir, ln.manifest[nodeName].dir)
+	_, err = os.Stat(nodeDir)
+	assert.NoError(err)
+	assert.NoError(ln.Stop(context.Background()))
+	_, err = os.Stat(nodeDir)
+	assert.True(os.IsNotExist(err))
+	report, err := ln.VerifyTeardown()
+	assert.NoError(err)
+	assert.NoError(report.Err())
+
+	// Node dirs are kept if an operation failed
+	networkConfig = testNetworkConfig(t)
+	networkConfig.RootDataDir = t.TempDir()
+	networkConfig.DataDirCleanup = network.DataDirRetainOnFailure
+	ln, err = newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(ln.loadConfig(context.Background(), networkConfig))
+	assert.Error(ln.RemoveNode("not a node"))
+	assert.NoError(ln.Stop(context.Background()))
+	_, err = os.Stat(filepath.Join(networkConfig.RootDataDir, nodeName))
+	assert.NoError(err)
+
+	networkConfig.DataDirCleanup = "sometimes"
+	assert.NotEmpty(networkConfig.Validate())
+}
+
+// TestFlagsBuilder checks that flags built with a node.FlagsBuilder
+// are accepted by the network and passed to the node
+func TestFlagsBuilder(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	apiPort, err := getFreePort(net.rng)
+	assert.NoError(err)
+	subnetID := ids.GenerateTestID()
+	nodeConfig := networkConfig.NodeConfigs[0]
+	nodeConfig.Name = "built"
+	nodeConfig.IsBeacon = false
+	nodeConfig.ConfigFile = ""
+	stakingCert, stakingKey, err := staking.NewCertAndKeyBytes()
+	assert.NoError(err)
+	nodeConfig.StakingCert, nodeConfig.StakingKey = string(stakingCert), string(stakingKey)
+	nodeConfig.Flags = node.NewFlagsBuilder(map[string]interface{}{"custom-flag": "value"}).
+		HTTPPort(apiPort).
+		LogLevel(logging.Debug).
+		HealthCheckFrequency(2 * time.Second).
+		SnowSampleSize(3).
+		WhitelistedSubnets(subnetID).
+		Build()
+	newNode, err := net.AddNode(nodeConfig)
+	assert.NoError(err)
+	assert.Equal(apiPort, newNode.GetAPIPort())
+	flags := newNode.GetFlags()
+	logLevel, _ := flags.Get(config.LogLevelKey)
+	assert.Equal("DEBUG", logLevel)
+	healthCheckFreq, err := flags.Duration(config.HealthCheckFreqKey)
+	assert.NoError(err)
+	assert.Equal(2*time.Second, healthCheckFreq)
+	sampleSize, err := flags.Int(config.SnowSampleSizeKey)
+	assert.NoError(err)
+	assert.EqualValues(3, sampleSize)
+	subnets, _ := flags.Get(config.WhitelistedSubnetsKey)
+	assert.Equal(subnetID.String(), subnets)
+	custom, _ := flags.Get("custom-flag")
+	assert.Equal("value", custom)
+}
+
+func TestBeforeNodeStart(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	var started []string
+	networkConfig.BeforeNodeStart = []func(*node.Config) error{
+		func(nodeConfig *node.Config) error {
+			started = append(started, nodeConfig.Name)
+			return nil
+		},
+	}
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	assert.Len(started, len(networkConfig.NodeConfigs))
+
+	// Hooks see the merged flags and their changes are applied
+	net.BeforeNodeStart(func(nodeConfig *node.Config) error {
+		nodeConfig.Flags[config.SnowSampleSizeKey] = 7
+		return nil
+	})
+	nodeConfig := networkConfig.NodeConfigs[0]
+	nodeConfig.Name = "hooked"
+	nodeConfig.IsBeacon = false
+	nodeConfig.Flags = map[string]interface{}{}
+	newNode, err := net.AddNode(nodeConfig)
+	assert.NoError(err)
+	sampleSize, err := newNode.GetFlags().Int(config.SnowSampleSizeKey)
+	assert.NoError(err)
+	assert.EqualValues(7, sampleSize)
+	assert.Equal("hooked", started[len(started)-1])
+
+	// Hooks see the node's ports, and can call the network
+	var hookAPIPort, hookP2PPort interface{}
+	var hookNodeNames []string
+	net.BeforeNodeStart(func(nodeConfig *node.Config) error {
+		hookAPIPort = nodeConfig.Flags[config.HTTPPortKey]
+		hookP2PPort = nodeConfig.Flags[config.StakingPortKey]
+		var err error
+		hookNodeNames, err = net.GetNodeNames()
+		return err
+	})
+	nodeConfig.Name = "ports"
+	newNode, err = net.AddNode(nodeConfig)
+	assert.NoError(err)
+	assert.Equal(int(newNode.GetAPIPort()), hookAPIPort)
+	assert.Equal(int(newNode.GetP2PPort()), hookP2PPort)
+	assert.Len(hookNodeNames, len(networkConfig.NodeConfigs)+1)
+	assert.NotContains(net.nodes["ports"].config.Flags, config.HTTPPortKey)
+	// A node being started can't be added again meanwhile
+	net.BeforeNodeStart(func(nodeConfig *node.Config) error {
+		if nodeConfig.Name != "reserved" {
+			return nil
+		}
+		otherConfig := *nodeConfig
+		otherConfig.Flags = map[string]interface{}{}
+		_, err := net.AddNode(otherConfig)
+		assert.ErrorIs(err, network.ErrDuplicateNodeName)
+		return nil
+	})
+	nodeConfig.Name = "reserved"
+	_, err = net.AddNode(nodeConfig)
+	assert.NoError(err)
+
+	// A failing hook prevents the node from starting
+	net.BeforeNodeStart(func(*node.Config) error {
+		return errors.New("hook error")
+	})
+	nodeConfig.Name = "failed"
+	_, err = net.AddNode(nodeConfig)
+	assert.Error(err)
+	_, err = net.GetNode("failed")
+	assert.Error(err)
+}
+
+func TestHealthChecks(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	failingNode := networkConfig.NodeConfigs[1].Name
+	var passing int32
+	networkConfig.HealthChecks = []network.HealthCheck{
+		{
+			Name: "always",
+			Check: func(context.Context, node.Node) error {
+				return nil
+			},
+		},
+	}
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	assert.NoError(awaitNetworkHealthy(net, defaultHealthyTimeout))
+
+	assert.Error(net.AddHealthCheck(network.HealthCheck{Name: "no check"}))
+	assert.NoError(net.AddHealthCheck(network.HealthCheck{
+		Name:      "vm",
+		NodeNames: []string{failingNode},
+		Check: func(_ context.Context, node node.Node) error {
+			assert.Equal(failingNode, node.GetName())
+			if atomic.LoadInt32(&passing) == 0 {
+				return errors.New("vm not ready")
+			}
+			return nil
+		},
+	}))
+	// The check's failures are reported in place of the Health API's
+	err = awaitNetworkHealthy(net, time.Second)
+	var unhealthyErr *network.UnhealthyError
+	assert.True(errors.As(err, &unhealthyErr))
+	assert.Equal(failingNode, unhealthyErr.NodeName)
+	assert.Equal(map[string]network.HealthCheckFailure{
+		"vm": {Error: "vm not ready"},
+	}, unhealthyErr.Checks)
+
+	atomic.StoreInt32(&passing, 1)
+	assert.NoError(awaitNetworkHealthy(net, defaultHealthyTimeout))
+
+	// Checks run without holding the network's lock,
+	// so operations aren't blocked while they run
+	running := make(chan struct{})
+	release := make(chan struct{})
+	var runningOnce sync.Once
+	assert.NoError(net.AddHealthCheck(network.HealthCheck{
+		Name:      "blocking",
+		NodeNames: []string{failingNode},
+		Check: func(context.Context, node.Node) error {
+			runningOnce.Do(func() { close(running) })
+			<-release
+			return nil
+		},
+	}))
+	healthyErrCh := make(chan error, 1)
+	go func() {
+		healthyErrCh <- awaitNetworkHealthy(net, defaultHealthyTimeout)
+	}()
+	<-running
+	net.BeforeNodeStart(func(*node.Config) error { return nil })
+	close(release)
+	assert.NoError(<-healthyErrCh)
+
+	// A check that panics fails
+	assert.NoError(net.AddHealthCheck(network.HealthCheck{
+		Name:      "panicking",
+		NodeNames: []string{failingNode},
+		Check: func(context.Context, node.Node) error {
+			panic("check bug")
+		},
+	}))
+	err = awaitNetworkHealthy(net, time.Second)
+	assert.True(errors.As(err, &unhealthyErr))
+	assert.Contains(unhealthyErr.Checks["panicking"].Error, "check bug")
+	assert.NoError(net.Stop(context.Background()))
+}
+
+func TestAttachPeerErrors(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	_, err = net.AttachPeer(context.Background(), "not a node", &noOpInboundHandler{})
+	assert.Error(err)
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.AttachPeer(context.Background(), "node0", &noOpInboundHandler{})
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+func TestPauseResumeNode(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	assert.Error(net.PauseNode("not a node"))
+	assert.NoError(net.PauseNode("node0"))
+	// Pausing a paused node is a no-op
+	assert.NoError(net.PauseNode("node0"))
+	process := net.nodes["node0"].process.(*mocks.NodeProcess)
+	process.AssertNumberOfCalls(t, "Pause", 1)
+	assert.NoError(net.ResumeNode("node0"))
+	assert.NoError(net.ResumeNode("node0"))
+	process.AssertNumberOfCalls(t, "Resume", 1)
+	// A paused node is resumed before it's stopped
+	assert.NoError(net.PauseNode("node0"))
+	assert.NoError(net.Stop(context.Background()))
+	process.AssertNumberOfCalls(t, "Resume", 2)
+	assert.ErrorIs(net.PauseNode("node0"), network.ErrStopped)
+
+	// A paused node that can't be resumed is killed
+	net, err = newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	assert.NoError(net.PauseNode("node0"))
+	process = net.nodes["node0"].process.(*mocks.NodeProcess)
+	for _, call := range process.ExpectedCalls {
+		if call.Method == "Resume" {
+			call.ReturnArguments = mock.Arguments{errors.New("no such process")}
+		}
+	}
+	assert.NoError(net.Stop(context.Background()))
+	process.AssertCalled(t, "Signal", os.Kill)
+	process.AssertNotCalled(t, "Stop")
+}
+
+func TestSignalNode(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	assert.ErrorIs(net.SignalNode("not a node", syscall.SIGHUP), network.ErrNodeNotFound)
+	assert.NoError(net.SignalNode("node0", syscall.SIGHUP))
+	net.nodes["node0"].process.(*mocks.NodeProcess).AssertCalled(t, "Signal", syscall.SIGHUP)
+	history := net.History()
+	assert.Equal(network.OpSignalNode, history[len(history)-1].Name)
+	assert.Equal("node0", history[len(history)-1].Target)
+	assert.NoError(net.Stop(context.Background()))
+	assert.ErrorIs(net.SignalNode("node0", syscall.SIGHUP), network.ErrStopped)
+}
+
+func TestNodeStdin(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.NodeConfigs[0].Stdin = true
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	stdin, err := net.NodeStdin(networkConfig.NodeConfigs[0].Name)
+	assert.NoError(err)
+	_, err = io.WriteString(stdin, "input")
+	assert.NoError(err)
+	assert.Equal("input", stdin.(*bufferWriteCloser).String())
+	// Nodes whose stdin isn't a pipe
+	_, err = net.NodeStdin(networkConfig.NodeConfigs[1].Name)
+	assert.Error(err)
+	_, err = net.NodeStdin("not a node")
+	assert.ErrorIs(err, network.ErrNodeNotFound)
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.NodeStdin(networkConfig.NodeConfigs[0].Name)
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+func TestPauseResumeNetwork(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	// Nodes paused on their own are left paused
+	assert.NoError(net.PauseNode("node0"))
+	assert.NoError(net.Pause(context.Background()))
+	for _, node := range net.nodes {
+		assert.True(node.paused)
+		node.process.(*mocks.NodeProcess).AssertNumberOfCalls(t, "Pause", 1)
+	}
+	assert.NoError(net.Resume(context.Background()))
+	for _, node := range net.nodes {
+		assert.False(node.paused)
+		node.process.(*mocks.NodeProcess).AssertNumberOfCalls(t, "Resume", 1)
+	}
+	assert.NoError(net.Stop(context.Background()))
+	assert.ErrorIs(net.Pause(context.Background()), network.ErrStopped)
+	assert.ErrorIs(net.Resume(context.Background()), network.ErrStopped)
+}
+
+// admin.Client that records the chain aliases it's given
+type aliasTestAdminClient struct {
+	admin.Client
+	lock    sync.Mutex
+	aliases map[string]string
+}
+
+func (c *aliasTestAdminClient) AliasChain(_ context.Context, chain string, alias string, _ ...rpc.Option) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.aliases[alias] = chain
+	return true, nil
+}
+
+func TestAddBlockchainAlias(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	var adminClients []*aliasTestAdminClient
+	newAPIClientF := func(ipAddr string, port uint16) api.Client {
+		adminClient := &aliasTestAdminClient{aliases: map[string]string{}}
+		adminClients = append(adminClients, adminClient)
+		client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+		client.On("AdminAPI").Return(adminClient)
+		return client
+	}
+	networkConfig := testNetworkConfig(t)
+	net, err := newNetwork(logging.NoLog{}, newAPIClientF, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	chainID := ids.GenerateTestID()
+	assert.NoError(net.AddBlockchainAlias(context.Background(), chainID, "myvm"))
+	assert.Len(adminClients, len(networkConfig.NodeConfigs))
+	for _, adminClient := range adminClients {
+		assert.Equal(chainID.String(), adminClient.aliases["myvm"])
+	}
+	assert.NoError(net.Stop(context.Background()))
+	assert.ErrorIs(net.AddBlockchainAlias(context.Background(), chainID, "myvm"), network.ErrStopped)
+}
+
+// The keystore of a node, which records the users and
+// the keys imported on each chain
+type keystoreTestNode struct {
+	keystore.Client
+	lock sync.Mutex
+	// Username --> password
+	users map[string]string
+	// Chain --> addresses of the keys imported on it
+	keys map[string][]ids.ShortID
+}
+
+func (n *keystoreTestNode) ListUsers(context.Context, ...rpc.Option) ([]string, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	usernames := []string{}
+	for username := range n.users {
+		usernames = append(usernames, username)
+	}
+	return usernames, nil
+}
+
+func (n *keystoreTestNode) CreateUser(_ context.Context, user avaapi.UserPass, _ ...rpc.Option) (bool, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if _, ok := n.users[user.Username]; ok {
+		return false, errors.New("user already exists")
+	}
+	n.users[user.Username] = user.Password
+	return true, nil
+}
+
+func (n *keystoreTestNode) importKey(chain string, user avaapi.UserPass, key *crypto.PrivateKeySECP256K1R) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if password, ok := n.users[user.Username]; !ok || password != user.Password {
+		return errors.New("wrong username or password")
+	}
+	n.keys[chain] = append(n.keys[chain], key.PublicKey().Address())
+	return nil
+}
+
+type keystoreTestXClient struct {
+	avm.Client
+	node *keystoreTestNode
+}
+
+func (c *keystoreTestXClient) ImportKey(_ context.Context, user avaapi.UserPass, key *crypto.PrivateKeySECP256K1R, _ ...rpc.Option) (ids.ShortID, error) {
+	return key.PublicKey().Address(), c.node.importKey("X", user, key)
+}
+
+type keystoreTestPClient struct {
+	platformvm.Client
+	node *keystoreTestNode
+}
+
+func (c *keystoreTestPClient) ImportKey(_ context.Context, user avaapi.UserPass, key *crypto.PrivateKeySECP256K1R, _ ...rpc.Option) (ids.ShortID, error) {
+	return key.PublicKey().Address(), c.node.importKey("P", user, key)
+}
+
+type keystoreTestCClient struct {
+	evm.Client
+	node *keystoreTestNode
+}
+
+func (c *keystoreTestCClient) ImportKey(_ context.Context, user avaapi.UserPass, key *crypto.PrivateKeySECP256K1R) (string, error) {
+	return "", c.node.importKey("C", user, key)
+}
+
+func TestSeedKeystoreUsers(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	var keystores []*keystoreTestNode
+	newAPIClientF := func(ipAddr string, port uint16) api.Client {
+		keystore := &keystoreTestNode{users: map[string]string{}, keys: map[string][]ids.ShortID{}}
+		keystores = append(keystores, keystore)
+		client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+		client.On("KeystoreAPI").Return(keystore)
+		client.On("XChainAPI").Return(&keystoreTestXClient{node: keystore})
+		client.On("PChainAPI").Return(&keystoreTestPClient{node: keystore})
+		client.On("CChainAPI").Return(&keystoreTestCClient{node: keystore})
+		return client
+	}
+	networkConfig := testNetworkConfig(t)
+	net, err := newNetwork(logging.NoLog{}, newAPIClientF, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	key, err := (&crypto.FactorySECP256K1R{}).NewPrivateKey()
+	assert.NoError(err)
+	users := []api.KeystoreUser{
+		{Username: "funded", Password: "password1"},
+		{Username: "other", Password: "password2", Keys: []*crypto.PrivateKeySECP256K1R{key.(*crypto.PrivateKeySECP256K1R)}},
+	}
+	assert.NoError(net.SeedKeystoreUsers(context.Background(), users))
+	// Seeding is idempotent
+	assert.NoError(net.SeedKeystoreUsers(context.Background(), users[:1]))
+	assert.Len(keystores, len(networkConfig.NodeConfigs))
+	for _, keystore := range keystores {
+		assert.Equal(map[string]string{"funded": "password1", "other": "password2"}, keystore.users)
+		ewoqAddr := avagenesis.EWOQKey.PublicKey().Address()
+		for _, chain := range []string{"X", "P", "C"} {
+			assert.Equal([]ids.ShortID{ewoqAddr, key.PublicKey().Address(), ewoqAddr}, keystore.keys[chain])
+		}
+	}
+
+	// A user whose password is wrong isn't seeded
+	assert.Error(net.SeedKeystoreUsers(context.Background(), []api.KeystoreUser{{Username: "funded", Password: "wrong"}}))
+
+	assert.NoError(net.Stop(context.Background()))
+	assert.ErrorIs(net.SeedKeystoreUsers(context.Background(), users), network.ErrStopped)
+}
+
+// A P-Chain client whose height grows each time it's polled
+type heightTestPClient struct {
+	platformvm.Client
+	height uint64
+}
+
+func (c *heightTestPClient) GetHeight(context.Context, ...rpc.Option) (uint64, error) {
+	return atomic.AddUint64(&c.height, 1), nil
+}
+
+func TestStopAtHeight(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	var clients int32
+	newAPIClientF := func(ipAddr string, port uint16) api.Client {
+		client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+		pClient := &heightTestPClient{}
+		if atomic.AddInt32(&clients, 1) == 1 {
+			// This node is far ahead of the others
+			pClient.height = 1000
+		}
+		client.On("PChainAPI").Return(pClient)
+		return client
+	}
+	networkConfig := testNetworkConfig(t)
+	net, err := newNetwork(logging.NoLog{}, newAPIClientF, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	processes := []*mocks.NodeProcess{}
+	pClients := map[string]*heightTestPClient{}
+	for nodeName, node := range net.nodes {
+		processes = append(processes, node.process.(*mocks.NodeProcess))
+		pClients[nodeName] = node.client.PChainAPI().(*heightTestPClient)
+	}
+
+	_, err = net.StopAtHeight(context.Background(), "X", 3)
+	assert.Error(err)
+
+	// The nodes don't reach the height in time
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, err = net.StopAtHeight(ctx, "P", 500)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Contains(err.Error(), "nodes below height 500")
+
+	heights, err := net.StopAtHeight(context.Background(), "P", 20)
+	assert.NoError(err)
+	assert.Len(heights, len(networkConfig.NodeConfigs))
+	// Each node is frozen as soon as it reaches the height, so the node
+	// ahead isn't polled again while the others catch up
+	for nodeName, height := range heights {
+		assert.Equal(atomic.LoadUint64(&pClients[nodeName].height), height)
+		if height != 20 {
+			assert.Greater(height, uint64(1000))
+		}
+	}
+	// The nodes are killed while they're frozen. The node ahead was
+	// resumed when the first call failed.
+	for _, process := range processes {
+		pauses, resumes := 0, 0
+		for _, call := range process.Calls {
+			switch call.Method {
+			case "Pause":
+				pauses++
+			case "Resume":
+				resumes++
+			}
+		}
+		assert.Equal(pauses, resumes+1)
+		process.AssertCalled(t, "Signal", os.Kill)
+		process.AssertNotCalled(t, "Stop")
+	}
+	_, err = net.StopAtHeight(context.Background(), "P", 10)
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+// TestStopAtHeightRestartedNode tests that a node restarted while its
+// height was polled isn't frozen in place of the node that was polled
+func TestStopAtHeightRestartedNode(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	polled := &localNode{name: "node0"}
+	assert.NoError(net.freezeNode(polled, 10))
+	assert.False(polled.frozen)
+	assert.False(net.nodes["node0"].paused)
+	net.nodes["node0"].process.(*mocks.NodeProcess).AssertNotCalled(t, "Pause")
+	assert.NoError(net.Stop(context.Background()))
+}
+
+// TestStopAtHeightFreezeFails tests that StopAtHeight fails, and
+// leaves the network running, if a node can't be frozen
+func TestStopAtHeightFreezeFails(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	newAPIClientF := func(ipAddr string, port uint16) api.Client {
+		client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+		client.On("PChainAPI").Return(&heightTestPClient{})
+		return client
+	}
+	net, err := newNetwork(logging.NoLog{}, newAPIClientF, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	for _, call := range net.nodes["node0"].process.(*mocks.NodeProcess).ExpectedCalls {
+		if call.Method == "Pause" {
+			call.ReturnArguments = mock.Arguments{errors.New("pause failed")}
+		}
+	}
+
+	_, err = net.StopAtHeight(context.Background(), "P", 5)
+	assert.Error(err)
+	assert.Contains(err.Error(), "node0 at 5 (couldn't freeze node at height 5")
+	assert.False(net.stopCalled())
+	for _, node := range net.nodes {
+		assert.False(node.frozen)
+		assert.False(node.paused)
+	}
+	assert.NoError(net.Stop(context.Background()))
+}
+
+// TestGetChainHeightTLS tests that the heights of EVM chains
+// are queried with the node's HTTP client
+func TestGetChainHeightTLS(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/ext/bc/mychain/rpc" || req.Method != "eth_blockNumber" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x2a"}`, req.ID)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	assert.NoError(err)
+	port, err := strconv.Atoi(serverURL.Port())
+	assert.NoError(err)
+	n := &localNode{
+		name:    "node0",
+		apiPort: uint16(port),
+		config:  node.Config{APITLSCert: "cert"},
+		// Trusts the server's cert
+		httpClient: server.Client(),
+	}
+	height, err := getChainHeight(context.Background(), n, "mychain")
+	assert.NoError(err)
+	assert.EqualValues(42, height)
+}
+
+// An X-Chain client on which a tx is accepted once its status has been
+// polled [pollsToAccept] times, or never if it's negative
+type txStatusTestXClient struct {
+	avm.Client
+	pollsToAccept int32
+	polls         int32
+	rejected      bool
+}
+
+func (c *txStatusTestXClient) GetTxStatus(context.Context, ids.ID, ...rpc.Option) (choices.Status, error) {
+	polls := atomic.AddInt32(&c.polls, 1)
+	switch {
+	case c.rejected:
+		return choices.Rejected, nil
+	case c.pollsToAccept >= 0 && polls >= c.pollsToAccept:
+		return choices.Accepted, nil
+	}
+	return choices.Processing, nil
+}
+
+func TestAwaitTxAccepted(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	var clients []*txStatusTestXClient
+	newAPIClientF := func(ipAddr string, port uint16) api.Client {
+		// The first node never accepts the tx
+		xClient := &txStatusTestXClient{pollsToAccept: int32(len(clients)*2 - 1)}
+		clients = append(clients, xClient)
+		client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+		client.On("XChainAPI").Return(xClient)
+		return client
+	}
+	networkConfig := testNetworkConfig(t)
+	net, err := newNetwork(logging.NoLog{}, newAPIClientF, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	assert.Len(clients, len(networkConfig.NodeConfigs))
+
+	txID := ids.GenerateTestID()
+	err = net.AwaitTxAccepted(context.Background(), "X", txID, 500*time.Millisecond)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Regexp(`not accepted by nodes [^,]+ \(Processing\)`, err.Error())
+	for _, client := range clients[1:] {
+		assert.GreaterOrEqual(atomic.LoadInt32(&client.polls), client.pollsToAccept)
+	}
+
+	clients[0].pollsToAccept = 1
+	assert.NoError(net.AwaitTxAccepted(context.Background(), "X", txID, 0))
+
+	// A rejected tx isn't awaited
+	clients[1].rejected = true
+	err = net.AwaitTxAccepted(context.Background(), "X", txID, time.Minute)
+	assert.ErrorIs(err, network.ErrTxRejected)
+
+	assert.NoError(net.Stop(context.Background()))
+	assert.ErrorIs(net.AwaitTxAccepted(context.Background(), "X", txID, 0), network.ErrStopped)
+}
+
+func TestGetStakingCert(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	networkConfig := testNetworkConfig(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	node, err := net.GetNode(networkConfig.NodeConfigs[0].Name)
+	assert.NoError(err)
+
+	cert := node.GetStakingCert()
+	assert.Equal(node.GetNodeID(), ids.NodeIDFromCert(cert))
+	block, _ := pem.Decode([]byte(networkConfig.NodeConfigs[0].StakingCert))
+	assert.Equal(block.Bytes, cert.Raw)
+	fingerprint := sha256.Sum256(block.Bytes)
+	assert.Equal(hex.EncodeToString(fingerprint[:]), node.GetStakingCertFingerprint())
+}
+
+func TestAssemblePluginDir(t *testing.T) {
+	assert := assert.New(t)
+	srcBuildDir := t.TempDir()
+	srcPluginDir := filepath.Join(srcBuildDir, pluginsDirName)
+	assert.NoError(os.MkdirAll(srcPluginDir, 0o750))
+	evmID, err := utils.VMID("evm")
+	assert.NoError(err)
+	assert.NoError(os.WriteFile(filepath.Join(srcPluginDir, evmID.String()), []byte("evm"), 0o700))
+	vmBinary := filepath.Join(t.TempDir(), "vm")
+	assert.NoError(os.WriteFile(vmBinary, []byte("custom vm"), 0o700))
+	customVMs := []network.CustomVM{{Name: "customvm", BinaryPath: vmBinary}}
+
+	nodeDir := t.TempDir()
+	// Assembling twice, as when a node is restarted, gives the same plugin dir
+	for i := 0; i < 2; i++ {
+		buildDir, err := assemblePluginDir(nodeDir, srcBuildDir, customVMs)
+		assert.NoError(err)
+		assert.Equal(filepath.Join(nodeDir, buildSubdir), buildDir)
+		customVMID, err := customVMs[0].VMID()
+		assert.NoError(err)
+		contents, err := os.ReadFile(filepath.Join(buildDir, pluginsDirName, customVMID.String()))
+		assert.NoError(err)
+		assert.Equal("custom vm", string(contents))
+		contents, err = os.ReadFile(filepath.Join(buildDir, pluginsDirName, evmID.String()))
+		assert.NoError(err)
+		assert.Equal("evm", string(contents))
+	}
+
+	// A custom VM replaces a plugin with the same VM ID
+	customVMs = []network.CustomVM{{Name: "evm", BinaryPath: vmBinary}}
+	buildDir, err := assemblePluginDir(nodeDir, srcBuildDir, customVMs)
+	assert.NoError(err)
+	entries, err := os.ReadDir(filepath.Join(buildDir, pluginsDirName))
+	assert.NoError(err)
+	assert.Len(entries, 1)
+	contents, err := os.ReadFile(filepath.Join(buildDir, pluginsDirName, evmID.String()))
+	assert.NoError(err)
+	assert.Equal("custom vm", string(contents))
+}
+
+func TestRestart(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	nodesBefore := map[string]*localNode{}
+	for name, node := range net.nodes {
+		nodesBefore[name] = node
+	}
+
+	assert.NoError(net.Restart(context.Background(), network.RestartOptions{
+		BinaryPath: "other-avalanchego",
+		Flags:      map[string]interface{}{"log-level": "debug"},
+	}))
+	assert.Len(net.nodes, len(nodesBefore))
+	for name, nodeBefore := range nodesBefore {
+		node, ok := net.nodes[name]
+		assert.True(ok)
+		assert.NotSame(nodeBefore, node)
+		nodeBefore.process.(*mocks.NodeProcess).AssertCalled(t, "Stop")
+		assert.Equal(nodeBefore.nodeID, node.nodeID)
+		assert.Equal(nodeBefore.apiPort, node.apiPort)
+		assert.Equal(nodeBefore.p2pPort, node.p2pPort)
+		assert.Equal(nodeBefore.dbDir, node.dbDir)
+		assert.Equal("other-avalanchego", node.config.BinaryPath)
+		assert.Equal("debug", node.config.Flags["log-level"])
+	}
+	history := net.History()
+	assert.Equal(network.OpRestart, history[len(history)-1].Name)
+	assert.Empty(history[len(history)-1].Err)
+
+	// Nodes that don't start with the new flags
+	// are started again with their previous ones
+	net.nodeProcessCreator = &failStartProcessCreator{}
+	assert.Error(net.restartNodes(network.RestartOptions{
+		Flags: map[string]interface{}{failStartFlag: true},
+	}))
+	assert.Len(net.nodes, len(nodesBefore))
+	for name, nodeBefore := range nodesBefore {
+		node, ok := net.nodes[name]
+		assert.True(ok)
+		assert.Equal(nodeBefore.nodeID, node.nodeID)
+		assert.Equal("debug", node.config.Flags["log-level"])
+		_, ok = node.config.Flags[failStartFlag]
+		assert.False(ok)
+	}
+
+	assert.NoError(net.Stop(context.Background()))
+	assert.ErrorIs(net.Restart(context.Background(), network.RestartOptions{}), network.ErrStopped)
+}
+
+// info.Client whose chains are always bootstrapped
+type bootstrappedInfoClient struct {
+	info.Client
+}
+
+func (*bootstrappedInfoClient) IsBootstrapped(context.Context, string, ...rpc.Option) (bool, error) {
+	return true, nil
+}
+
+// Like newMockAPISuccessful, but the Info API's IsBootstrapped may be called
+func newMockAPIBootstrapped(ipAddr string, port uint16) api.Client {
+	client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+	client.On("InfoAPI").Return(&bootstrappedInfoClient{})
+	return client
+}
+
+func TestUpgradeNodes(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPIBootstrapped, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	binaryPath := net.nodes["node0"].config.BinaryPath
+
+	ctx := context.Background()
+	assert.Error(net.UpgradeNodes(ctx, "", network.UpgradeOptions{}))
+	assert.Error(net.UpgradeNodes(ctx, "new-avalanchego", network.UpgradeOptions{NodeNames: []string{"not a node"}}))
+	assert.Error(net.UpgradeNodes(ctx, "new-avalanchego", network.UpgradeOptions{NodeNames: []string{"node1", "node1"}}))
+
+	// Upgrade some nodes
+	nodeID := net.nodes["node1"].nodeID
+	assert.NoError(net.UpgradeNodes(ctx, "new-avalanchego", network.UpgradeOptions{
+		NodeNames: []string{"node1", "node0"},
+		Flags:     map[string]interface{}{"log-level": "debug"},
+	}))
+	assert.Equal("new-avalanchego", net.nodes["node0"].config.BinaryPath)
+	assert.Equal("new-avalanchego", net.nodes["node1"].config.BinaryPath)
+	assert.Equal("debug", net.nodes["node1"].config.Flags["log-level"])
+	assert.Equal(nodeID, net.nodes["node1"].nodeID)
+	assert.Equal(binaryPath, net.nodes["node2"].config.BinaryPath)
+
+	// Upgrade all nodes
+	assert.NoError(net.UpgradeNodes(ctx, "newer-avalanchego", network.UpgradeOptions{}))
+	for _, node := range net.nodes {
+		assert.Equal("newer-avalanchego", node.config.BinaryPath)
+	}
+
+	assert.NoError(net.Stop(ctx))
+	assert.ErrorIs(net.UpgradeNodes(ctx, "new-avalanchego", network.UpgradeOptions{}), network.ErrStopped)
+}
+
+func TestUpgradeVM(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	dir := t.TempDir()
+	oldBinary, newBinary := filepath.Join(dir, "vm-v1"), filepath.Join(dir, "vm-v2")
+	assert.NoError(os.WriteFile(oldBinary, []byte("v1"), 0o755))
+	assert.NoError(os.WriteFile(newBinary, []byte("v2"), 0o755))
+	net, err := newNetwork(logging.NoLog{}, newMockAPIBootstrapped, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.CustomVMs = []network.CustomVM{{Name: "vm", BinaryPath: oldBinary}}
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	vmID, err := networkConfig.CustomVMs[0].VMID()
+	assert.NoError(err)
+	pluginContents := func(nodeName string) string {
+		contents, err := os.ReadFile(filepath.Join(net.manifest[nodeName].dir, buildSubdir, pluginsDirName, vmID.String()))
+		assert.NoError(err)
+		return string(contents)
+	}
+
+	ctx := context.Background()
+	assert.Error(net.UpgradeVM(ctx, ids.GenerateTestID(), newBinary, network.UpgradeOptions{}))
+	assert.Error(net.UpgradeVM(ctx, vmID, filepath.Join(dir, "missing"), network.UpgradeOptions{}))
+	assert.Equal("v1", pluginContents("node0"))
+
+	// Only the given nodes are restarted
+	assert.NoError(net.UpgradeVM(ctx, vmID, newBinary, network.UpgradeOptions{NodeNames: []string{"node1"}}))
+	assert.Equal("v2", pluginContents("node1"))
+	assert.Equal("v1", pluginContents("node0"))
+	// The config of the network isn't modified
+	assert.Equal(oldBinary, networkConfig.CustomVMs[0].BinaryPath)
+
+	assert.NoError(net.UpgradeVM(ctx, vmID, newBinary, network.UpgradeOptions{}))
+	for nodeName := range net.nodes {
+		assert.Equal("v2", pluginContents(nodeName))
+	}
+
+	assert.NoError(net.Stop(ctx))
+	assert.ErrorIs(net.UpgradeVM(ctx, vmID, newBinary, network.UpgradeOptions{}), network.ErrStopped)
+}
+
+// Info API client whose IsBootstrapped records the chains it's called with,
+// and returns false for the chains in [notBootstrapped]
+type recordingInfoClient struct {
+	info.Client
+	lock            *sync.Mutex
+	port            uint16
+	calls           map[uint16][]string
+	notBootstrapped map[string]bool
+}
+
+func (c *recordingInfoClient) IsBootstrapped(_ context.Context, chain string, _ ...rpc.Option) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.calls[c.port] = append(c.calls[c.port], chain)
+	return !c.notBootstrapped[chain], nil
+}
+
+func TestAwaitBootstrapped(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	lock := &sync.Mutex{}
+	calls := map[uint16][]string{}
+	newAPI := func(ipAddr string, port uint16) api.Client {
+		client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+		client.On("InfoAPI").Return(&recordingInfoClient{
+			lock:            lock,
+			port:            port,
+			calls:           calls,
+			notBootstrapped: map[string]bool{"never": true},
+		})
+		return client
+	}
+	net, err := newNetwork(logging.NoLog{}, newAPI, &localTestSuccessfulNodeProcessCreator{}, "", "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	blockchainID := ids.GenerateTestID()
+	net.subnets = []network.Subnet{{
+		ID:          ids.GenerateTestID(),
+		Validators:  []string{"node1"},
+		Blockchains: []network.Blockchain{{ID: blockchainID, Name: "mychain"}},
+	}}
+	chainsOf := func(nodeName string) []string {
+		lock.Lock()
+		defer lock.Unlock()
+		chains := calls[net.nodes[nodeName].apiPort]
+		delete(calls, net.nodes[nodeName].apiPort)
+		return chains
+	}
+
+	// By default, the primary network's chains and the subnets' chains
+	ctx := context.Background()
+	assert.NoError(net.AwaitBootstrapped(ctx, nil))
+	assert.Equal([]string{"P", "X", "C"}, chainsOf("node0"))
+	assert.Equal([]string{"P", "X", "C", blockchainID.String()}, chainsOf("node1"))
+
+	// A subnet's chain is only awaited on its validators
+	assert.NoError(net.AwaitBootstrapped(ctx, []string{"X", "mychain"}))
+	assert.Equal([]string{"X"}, chainsOf("node0"))
+	assert.Equal([]string{"X", blockchainID.String()}, chainsOf("node1"))
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	assert.Error(net.AwaitBootstrapped(timeoutCtx, []string{"never"}))
+
+	// The network isn't locked while it's awaited, so it can be stopped
+	bootstrappedErrCh := make(chan error, 1)
+	go func() {
+		bootstrappedErrCh <- net.AwaitBootstrapped(ctx, []string{"never"})
+	}()
+	time.Sleep(100 * time.Millisecond)
+	locked := make(chan struct{})
+	go func() {
+		net.lock.Lock()
+		net.lock.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		assert.FailNow("network locked while awaiting bootstrapping")
+	}
+	assert.NoError(net.Stop(ctx))
+	assert.Error(<-bootstrappedErrCh)
+	assert.ErrorIs(net.AwaitBootstrapped(ctx, nil), network.ErrStopped)
+}
+
+func TestAPITLSAndAuth(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	// The API clients must hold HTTP clients to send requests with TLS and auth
+	net, err := newNetwork(logging.NoLog{}, api.NewAPIClient, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	tlsCert, tlsKey, err := staking.NewCertAndKeyBytes()
+	assert.NoError(err)
+	networkConfig.NodeConfigs[0].APITLSCert = string(tlsCert)
+	networkConfig.NodeConfigs[0].APITLSKey = string(tlsKey)
+	networkConfig.NodeConfigs[0].APIAuthPassword = "password"
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	nodeName := networkConfig.NodeConfigs[0].Name
+	flags := net.nodes[nodeName].flags
+	for _, key := range []string{config.HTTPSEnabledKey, config.APIAuthRequiredKey} {
+		enabled, err := flags.Bool(key)
+		assert.NoError(err)
+		assert.True(enabled)
+	}
+	for key, contents := range map[string][]byte{
+		config.HTTPSCertFileKey:       tlsCert,
+		config.HTTPSKeyFileKey:        tlsKey,
+		config.APIAuthPasswordFileKey: []byte("password"),
+	} {
+		path, ok := flags.Get(key)
+		assert.True(ok)
+		fileContents, err := os.ReadFile(path)
+		assert.NoError(err)
+		assert.Equal(contents, fileContents)
+	}
+	// Nodes without API TLS or auth aren't affected
+	_, ok := net.nodes[networkConfig.NodeConfigs[1].Name].flags.Get(config.HTTPSEnabledKey)
+	assert.False(ok)
+
+	// Invalid configs
+	nodeConfig := networkConfig.NodeConfigs[0]
+	nodeConfig.APITLSKey = ""
+	assert.Error(nodeConfig.Validate(0))
+	nodeConfig = networkConfig.NodeConfigs[0]
+	nodeConfig.APIAuthToken = "token"
+	assert.Error(nodeConfig.Validate(0))
+	nodeConfig = networkConfig.NodeConfigs[0]
+	nodeConfig.CChainEthTransport = api.EthTransportWS
+	assert.Error(nodeConfig.Validate(0))
+	assert.NoError(net.Stop(context.Background()))
+}
+
+func TestNodeIPs(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	assert.True(networkConfig.NodeConfigs[0].IsBeacon)
+	networkConfig.NodeConfigs[0].PublicIP = "127.0.0.2"
+	networkConfig.NodeConfigs[0].BindAddr = "127.0.0.3"
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	node0 := net.nodes["node0"]
+	publicIP, ok := node0.flags.Get(config.PublicIPKey)
+	assert.True(ok)
+	assert.Equal("127.0.0.2", publicIP)
+	httpHost, ok := node0.flags.Get(config.HTTPHostKey)
+	assert.True(ok)
+	assert.Equal("127.0.0.3", httpHost)
+	assert.Equal("127.0.0.3", node0.GetURL())
+	assert.Equal(defaultNodeIP, net.nodes["node1"].GetURL())
+	// Other nodes bootstrap from the public IP
+	assert.Contains(net.bootstraps.IPsArg(), fmt.Sprintf("127.0.0.2:%d", node0.p2pPort))
+
+	nodeConfig := networkConfig.NodeConfigs[0]
+	nodeConfig.PublicIP = "not an IP"
+	assert.Error(nodeConfig.Validate(0))
+	nodeConfig = networkConfig.NodeConfigs[0]
+	nodeConfig.BindAddr = "not an IP"
+	assert.Error(nodeConfig.Validate(0))
+	assert.NoError(net.Stop(context.Background()))
+}
+
+func TestNodeIPv6(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	// API host --> ports
+	apiHosts := map[string][]uint16{}
+	lock := &sync.Mutex{}
+	newAPI := func(ipAddr string, port uint16) api.Client {
+		lock.Lock()
+		apiHosts[ipAddr] = append(apiHosts[ipAddr], port)
+		lock.Unlock()
+		return newMockAPISuccessful(ipAddr, port)
+	}
+	net, err := newNetwork(logging.NoLog{}, newAPI, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.NodeConfigs[0].PublicIP = "[fd00::1]"
+	networkConfig.NodeConfigs[0].BindAddr = "[::1]"
+	networkConfig.NodeConfigs[1].BindAddr = "::"
+	networkConfig.NodeConfigs[2].BindAddr = "0.0.0.0"
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	// avalanchego is given the IPs without brackets
+	node0 := net.nodes["node0"]
+	publicIP, _ := node0.flags.Get(config.PublicIPKey)
+	assert.Equal("fd00::1", publicIP)
+	httpHost, _ := node0.flags.Get(config.HTTPHostKey)
+	assert.Equal("::1", httpHost)
+	assert.Equal("::1", node0.GetURL())
+	assert.Equal(fmt.Sprintf("http://[::1]:%d", node0.apiPort), node0.GetURI())
+	assert.Contains(apiHosts["::1"], node0.apiPort)
+	assert.Contains(net.bootstraps.IPsArg(), fmt.Sprintf("[fd00::1]:%d", node0.p2pPort))
+	// Nodes listening on every interface are reached on the loopback IP
+	assert.Equal("::1", net.nodes["node1"].GetURL())
+	assert.Equal(defaultNodeIP, net.nodes["node2"].GetURL())
+	assert.Contains(apiHosts["::1"], net.nodes["node1"].apiPort)
+	assert.Equal([]uint16{net.nodes["node2"].apiPort}, apiHosts[defaultNodeIP])
+	assert.NoError(net.Stop(context.Background()))
+}
+
+// Process whose Wait returns once it's stopped or crashes
+type blockingProcess struct {
+	exitOnce sync.Once
+	exitCh   chan struct{}
+	err      error
+}
+
+func newBlockingProcess() *blockingProcess {
+	return &blockingProcess{exitCh: make(chan struct{})}
+}
+
+func (*blockingProcess) Start() error  { return nil }
+func (*blockingProcess) Pause() error  { return nil }
+func (*blockingProcess) Resume() error { return nil }
+
+func (*blockingProcess) Signal(os.Signal) error { return nil }
+func (*blockingProcess) Stdin() io.WriteCloser  { return nil }
+
+func (p *blockingProcess) Stop() error {
+	p.exit(nil)
+	return nil
+}
+
+func (p *blockingProcess) Wait() error {
+	<-p.exitCh
+	return p.err
+}
+
+func (p *blockingProcess) exit(err error) {
+	p.exitOnce.Do(func() {
+		p.err = err
+		close(p.exitCh)
+	})
+}
+
+// Creates blockingProcesses, keeping the last one of each node
+type blockingProcessCreator struct {
+	lock      sync.Mutex
+	processes map[string]*blockingProcess
+}
+
+func (c *blockingProcessCreator) NewNodeProcess(config node.Config, _ ...string) (NodeProcess, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	process := newBlockingProcess()
+	c.processes[config.Name] = process
+	return process, nil
+}
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	var (
+		lock           sync.Mutex
+		events         = map[string][]string{}
+		networkStopped int
+		crashed        = make(chan network.NodeEvent, 1)
+	)
+	record := func(kind string) func(network.NodeEvent) {
+		return func(event network.NodeEvent) {
+			lock.Lock()
+			defer lock.Unlock()
+			events[kind] = append(events[kind], event.Name)
+		}
+	}
+	eventsOf := func(kind string) []string {
+		lock.Lock()
+		defer lock.Unlock()
+		nodeNames := append([]string(nil), events[kind]...)
+		sort.Strings(nodeNames)
+		return nodeNames
+	}
+	networkConfig := testNetworkConfig(t)
+	networkConfig.Hooks = network.Hooks{
+		OnNodeStarted: record("started"),
+		OnNodeHealthy: record("healthy"),
+		OnNodeStopped: record("stopped"),
+		OnNodeCrashed: func(event network.NodeEvent) {
+			crashed <- event
+		},
+		OnNetworkStopped: func() {
+			networkStopped++
+		},
+	}
+	processCreator := &blockingProcessCreator{processes: map[string]*blockingProcess{}}
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, processCreator, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	assert.Equal([]string{"node0", "node1", "node2"}, eventsOf("started"))
+
+	// OnNodeHealthy is called once per node process
+	for i := 0; i < 2; i++ {
+		assert.NoError(net.Healthy(context.Background()))
+	}
+	assert.Equal([]string{"node0", "node1", "node2"}, eventsOf("healthy"))
+
+	crashErr := errors.New("crashed")
+	processCreator.processes["node2"].exit(crashErr)
+	select {
+	case event := <-crashed:
+		assert.Equal("node2", event.Name)
+		assert.ErrorIs(event.Err, crashErr)
+	case <-time.After(5 * time.Second):
+		assert.Fail("OnNodeCrashed wasn't called")
+	}
+
+	assert.NoError(net.RemoveNode("node1"))
+	assert.Equal([]string{"node1"}, eventsOf("stopped"))
+
+	assert.Error(net.Stop(context.Background()))
+	assert.Equal([]string{"node0", "node1", "node2"}, eventsOf("stopped"))
+	assert.ErrorIs(net.Stop(context.Background()), network.ErrStopped)
+	assert.Equal(1, networkStopped)
+	select {
+	case event := <-crashed:
+		assert.Fail("unexpected crash", event.Name)
+	default:
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	var (
+		lock   sync.Mutex
+		events []webhook.Event
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event webhook.Event
+		assert.NoError(json.NewDecoder(r.Body).Decode(&event))
+		lock.Lock()
+		events = append(events, event)
+		lock.Unlock()
+	}))
+	defer server.Close()
+	// Returns the events of type [eventType] posted, as "node" or "node: error"
+	eventsOf := func(eventType webhook.EventType) []string {
+		lock.Lock()
+		defer lock.Unlock()
+		posted := []string{}
+		for _, event := range events {
+			if event.Type != eventType {
+				continue
+			}
+			if event.Error != "" {
+				posted = append(posted, event.Node+": "+event.Error)
+			} else {
+				posted = append(posted, event.Node)
+			}
+		}
+		sort.Strings(posted)
+		return posted
+	}
+
+	networkConfig := testNetworkConfig(t)
+	networkConfig.Webhooks = &webhook.Config{URLs: []string{server.URL}}
+	processCreator := &blockingProcessCreator{processes: map[string]*blockingProcess{}}
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, processCreator, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	for i := 0; i < 2; i++ {
+		assert.NoError(net.Healthy(context.Background()))
+	}
+	processCreator.processes["node2"].exit(errors.New("crashed"))
+	assert.Eventually(func() bool {
+		return len(eventsOf(webhook.EventNodeCrashed)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NoError(net.RemoveNode("node1"))
+	_, err = net.AddNode(networkConfig.NodeConfigs[1])
+	assert.NoError(err)
+	assert.Error(net.Stop(context.Background()))
+
+	// Stop waits for the events to be posted
+	assert.Equal([]string{""}, eventsOf(webhook.EventNetworkStarted))
+	assert.Equal([]string{""}, eventsOf(webhook.EventNetworkHealthy))
+	assert.Equal([]string{""}, eventsOf(webhook.EventNetworkStopped))
+	assert.Equal([]string{"node0", "node1", "node2"}, eventsOf(webhook.EventNodeStarted))
+	assert.Equal([]string{"node1"}, eventsOf(webhook.EventNodeRestarted))
+	assert.Equal([]string{"node2: crashed"}, eventsOf(webhook.EventNodeCrashed))
+	assert.Equal([]string{"node0", "node1", "node1", "node2: crashed"}, eventsOf(webhook.EventNodeStopped))
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(webhook.EventNetworkStarted, events[3].Type)
+	assert.Equal(webhook.EventNetworkStopped, events[len(events)-1].Type)
+	for _, event := range events {
+		assert.Equal(net.uuid, event.NetworkUUID)
+	}
+}
+
+func TestAddNodeFromTemplate(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.NodeConfigs[0].Flags = map[string]interface{}{config.SnowSampleSizeKey: 10}
+	networkConfig.NodeConfigs[0].ChainConfigFiles = map[string]string{"X": `{"a":1}`}
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	template := net.nodes["node0"]
+
+	_, err = net.AddNodeFromTemplate("not a node", nil)
+	assert.Error(err)
+
+	newNode, err := net.AddNodeFromTemplate("node0", func(nodeConfig *node.Config) {
+		nodeConfig.Name = "from-template"
+		nodeConfig.Flags[config.SnowQuorumSizeKey] = 6
+	})
+	assert.NoError(err)
+	fromTemplate := net.nodes["from-template"]
+	assert.Equal(newNode.GetNodeID(), fromTemplate.nodeID)
+	assert.NotEqual(template.nodeID, fromTemplate.nodeID)
+	assert.False(fromTemplate.config.IsBeacon)
+	assert.NotEqual(template.apiPort, fromTemplate.apiPort)
+	assert.NotEqual(template.p2pPort, fromTemplate.p2pPort)
+	assert.NotEqual(template.dbDir, fromTemplate.dbDir)
+	assert.Equal(template.config.BinaryPath, fromTemplate.config.BinaryPath)
+	assert.Equal(template.config.ChainConfigFiles, fromTemplate.config.ChainConfigFiles)
+	sampleSize, err := fromTemplate.flags.Int(config.SnowSampleSizeKey)
+	assert.NoError(err)
+	assert.EqualValues(10, sampleSize)
+	quorumSize, err := fromTemplate.flags.Int(config.SnowQuorumSizeKey)
+	assert.NoError(err)
+	assert.EqualValues(6, quorumSize)
+	// The template isn't changed
+	_, ok := template.config.Flags[config.SnowQuorumSizeKey]
+	assert.False(ok)
+
+	// Without overrides, the node gets a generated name
+	_, err = net.AddNodeFromTemplate("node0", nil)
+	assert.NoError(err)
+	assert.Len(net.nodes, 5)
+
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.AddNodeFromTemplate("node0", nil)
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+// TestNonValidators checks that non-validator nodes aren't made beacons,
+// and can't have the staking key of a genesis validator
+func TestNonValidators(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	for i := range networkConfig.NodeConfigs {
+		networkConfig.NodeConfigs[i].IsBeacon = i == 0
+	}
+	// node1 is an API node
+	stakingCert, stakingKey, err := staking.NewCertAndKeyBytes()
+	assert.NoError(err)
+	networkConfig.NodeConfigs[1].StakingCert, networkConfig.NodeConfigs[1].StakingKey = string(stakingCert), string(stakingKey)
+	networkConfig.NodeConfigs[1].NonValidator = true
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	// Once the last beacon is removed, adding a node
+	// promotes the first validator node
+	assert.NoError(net.RemoveNode("node0"))
+	nodeConfig := networkConfig.NodeConfigs[0]
+	nodeConfig.Name = "node3"
+	nodeConfig.IsBeacon = false
+	_, err = net.AddNode(nodeConfig)
+	assert.NoError(err)
+	beacons, err := net.GetBootstrapBeacons()
+	assert.NoError(err)
+	assert.Len(beacons, 1)
+	assert.Equal("node2", beacons[0].Name)
+
+	// A genesis validator can't be a non-validator
+	nodeConfig.Name = "node4"
+	nodeConfig.NonValidator = true
+	nodeConfig.Flags = map[string]interface{}{}
+	nodeConfig.ConfigFile = ""
+	_, err = net.AddNode(nodeConfig)
+	assert.Error(err)
+	assert.Contains(err.Error(), "is a genesis validator")
+}
+
+func TestBeacons(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	for i := range networkConfig.NodeConfigs {
+		networkConfig.NodeConfigs[i].IsBeacon = i == 0
+	}
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	beacons, err := net.GetBootstrapBeacons()
+	assert.NoError(err)
+	assert.Len(beacons, 1)
+	assert.Equal("node0", beacons[0].Name)
+	assert.Equal(net.nodes["node0"].nodeID, beacons[0].NodeID)
+	assert.Equal(net.nodes["node0"].p2pPort, beacons[0].IP.Port)
+
+	// Once the last beacon is removed, adding a node promotes a running node
+	assert.NoError(net.RemoveNode("node0"))
+	beacons, err = net.GetBootstrapBeacons()
+	assert.NoError(err)
+	assert.Empty(beacons)
+	nodeConfig := networkConfig.NodeConfigs[1]
+	nodeConfig.Name = "node3"
+	stakingCert, stakingKey, err := staking.NewCertAndKeyBytes()
+	assert.NoError(err)
+	nodeConfig.StakingCert, nodeConfig.StakingKey = string(stakingCert), string(stakingKey)
+	nodeConfig.Flags = map[string]interface{}{}
+	_, err = net.AddNode(nodeConfig)
+	assert.NoError(err)
+	beacons, err = net.GetBootstrapBeacons()
+	assert.NoError(err)
+	assert.Len(beacons, 1)
+	assert.Equal("node1", beacons[0].Name)
+	bootstrapIDs, ok := net.nodes["node3"].flags.Get(config.BootstrapIDsKey)
+	assert.True(ok)
+	assert.Equal(net.nodes["node1"].nodeID.String(), bootstrapIDs)
+
+	// The nodes that bootstrapped from node0 are restarted
+	restarted, err := net.RefreshBeacons(context.Background())
+	assert.NoError(err)
+	assert.Equal([]string{"node1", "node2"}, restarted)
+	bootstrapIDs, _ = net.nodes["node2"].flags.Get(config.BootstrapIDsKey)
+	assert.Equal(net.nodes["node1"].nodeID.String(), bootstrapIDs)
+	restarted, err = net.RefreshBeacons(context.Background())
+	assert.NoError(err)
+	assert.Empty(restarted)
+
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.GetBootstrapBeacons()
+	assert.ErrorIs(err, network.ErrStopped)
+	_, err = net.RefreshBeacons(context.Background())
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+// Records the nodes it creates processes for, and when
+type recordingProcessCreator struct {
+	lock   *sync.Mutex
+	events *[]string
+	starts map[string]time.Time
+}
+
+func (c *recordingProcessCreator) NewNodeProcess(config node.Config, flags ...string) (NodeProcess, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	*c.events = append(*c.events, "start "+config.Name)
+	c.starts[config.Name] = time.Now()
+	return newMockProcessSuccessful(config, flags...)
+}
+
+// TestAddNodeNotLaunched tests that what may fail in adding a
+// node is done before its process is launched, so that no process
+// is left running without being added to the network
+func TestAddNodeNotLaunched(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	lock := &sync.Mutex{}
+	events := []string{}
+	processCreator := &recordingProcessCreator{lock: lock, events: &events, starts: map[string]time.Time{}}
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, processCreator, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	nodeConfig := networkConfig.NodeConfigs[0]
+	nodeConfig.Name = "invalid"
+	nodeConfig.ConfigFile = "{"
+	_, err = net.AddNode(nodeConfig)
+	assert.Error(err)
+	assert.NotContains(events, "start invalid")
+
+	// The node's flags and API client are made before it's launched
+	nodeConfig = networkConfig.NodeConfigs[0]
+	nodeConfig.Name = "pending"
+	net.lock.Lock()
+	pending, err := net.prepareNode(nodeConfig, true)
+	assert.NoError(err)
+	assert.NotNil(pending.client)
+	assert.NotEmpty(pending.nodeFlags)
+	assert.Nil(pending.process)
+	net.abortNode(pending)
+	net.lock.Unlock()
+	assert.NoError(net.Stop(context.Background()))
+}
+
+func TestStagedStartup(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	lock := &sync.Mutex{}
+	events := []string{}
+	newAPI := func(ipAddr string, port uint16) api.Client {
+		client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+		client.On("InfoAPI").Return(&recordingInfoClient{
+			lock:  lock,
+			port:  port,
+			calls: map[uint16][]string{},
+		})
+		return client
+	}
+	processCreator := &recordingProcessCreator{lock: lock, events: &events, starts: map[string]time.Time{}}
+	net, err := newNetwork(logging.NoLog{}, newAPI, processCreator, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	for i := range networkConfig.NodeConfigs {
+		networkConfig.NodeConfigs[i].IsBeacon = i == 0
+	}
+	networkConfig.StagedStartup = true
+	networkConfig.NodeStartDelay = 50 * time.Millisecond
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	// node0 bootstrapped before the other nodes started
+	assert.Equal([]string{"start node0", "start node1", "start node2"}, events)
+	calls := net.nodes["node0"].client.InfoAPI().(*recordingInfoClient).calls
+	assert.Equal(primaryNetworkChains, calls[net.nodes["node0"].apiPort])
+	// Non-beacons are started one at a time, [NodeStartDelay] apart
+	assert.GreaterOrEqual(processCreator.starts["node2"].Sub(processCreator.starts["node1"]), networkConfig.NodeStartDelay)
+	assert.NoError(net.Stop(context.Background()))
+
+	networkConfig.NodeStartParallelism = -1
+	assert.NotEmpty(networkConfig.Validate())
+	networkConfig.NodeStartParallelism = 0
+	networkConfig.NodeStartDelay = -time.Second
+	assert.NotEmpty(networkConfig.Validate())
+}
+
+// Creates processes that take a while to start, recording how
+// many start at once, and that exit with an error when stopped
+// if their node is in [failing]
+type slowStartProcessCreator struct {
+	lock        sync.Mutex
+	inFlight    int
+	maxInFlight int
+	failing     map[string]bool
+}
+
+func (c *slowStartProcessCreator) NewNodeProcess(config node.Config, flags ...string) (NodeProcess, error) {
+	c.lock.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	c.lock.Unlock()
+	time.Sleep(100 * time.Millisecond)
+	c.lock.Lock()
+	c.inFlight--
+	c.lock.Unlock()
+
+	process := &mocks.NodeProcess{}
+	process.On("Start").Return(nil)
+	process.On("Stop").Return(nil)
+	if c.failing[config.Name] {
+		process.On("Wait").Return(errors.New("exit status 1"))
+	} else {
+		process.On("Wait").Return(nil)
+	}
+	return process, nil
+}
+
+// TestParallelStartStop checks that nodes are started and stopped
+// concurrently, and that the errors of all the nodes are reported
+func TestParallelStartStop(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	processCreator := &slowStartProcessCreator{failing: map[string]bool{"node1": true, "node2": true}}
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, processCreator, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	assert.Len(net.nodes, len(networkConfig.NodeConfigs))
+	assert.Greater(processCreator.maxInFlight, 1)
+
+	err = net.Stop(context.Background())
+	var nodesErr *network.NodesError
+	assert.ErrorAs(err, &nodesErr)
+	assert.Len(nodesErr.Errs, 2)
+	assert.Contains(nodesErr.Errs, "node1")
+	assert.Contains(nodesErr.Errs, "node2")
+	assert.Contains(err.Error(), "2 nodes failed")
+	assert.Empty(net.nodes)
+
+	// Nodes are started one at a time if asked to
+	processCreator = &slowStartProcessCreator{}
+	net, err = newNetwork(logging.NoLog{}, newMockAPISuccessful, processCreator, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig.NodeStartParallelism = 1
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	assert.Equal(1, processCreator.maxInFlight)
+	assert.NoError(net.Stop(context.Background()))
+}
+
+func TestNewDefaultConfigN(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	_, err := NewDefaultConfigN("pepito", 0)
+	assert.Error(err)
+
+	defaultConfig := NewDefaultConfig("pepito")
+	for _, n := range []int{1, DefaultNumNodes, DefaultNumNodes + 2} {
+		netConfig, err := NewDefaultConfigN("pepito", n)
+		assert.NoError(err)
+		assert.Empty(netConfig.Validate())
+		assert.Len(netConfig.NodeConfigs, n)
+		var genesisConfig avagenesis.UnparsedConfig
+		assert.NoError(json.Unmarshal([]byte(netConfig.Genesis), &genesisConfig))
+		assert.Len(genesisConfig.InitialStakers, n)
+		for i, nodeConfig := range netConfig.NodeConfigs {
+			assert.True(nodeConfig.IsBeacon)
+			if i < DefaultNumNodes {
+				assert.Equal(defaultConfig.NodeConfigs[i], nodeConfig)
+			} else {
+				assert.EqualValues(defaultBaseAPIPort+2*i, nodeConfig.Flags[config.HTTPPortKey])
+			}
+			nodeID, err := utils.ToNodeID([]byte(nodeConfig.StakingKey), []byte(nodeConfig.StakingCert))
+			assert.NoError(err)
+			assert.Equal(nodeID, genesisConfig.InitialStakers[i].NodeID)
+		}
+	}
+
+	// Node IDs are the same on every run
+	netConfig, err := NewDefaultConfigN("pepito", DefaultNumNodes+1)
+	assert.NoError(err)
+	stakingCert, _, err := utils.NewDeterministicCertAndKeyBytes(utils.NewRand(DefaultNumNodes))
+	assert.NoError(err)
+	assert.Equal(string(stakingCert), netConfig.NodeConfigs[DefaultNumNodes].StakingCert)
+}
+
+func TestGetFaucetURL(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	_, err = net.GetFaucetURL()
+	assert.ErrorIs(err, errNoFaucet)
+	assert.NoError(net.Stop(context.Background()))
+
+	net, err = newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.Faucet = &faucet.Config{}
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	faucetURL, err := net.GetFaucetURL()
+	assert.NoError(err)
+	assert.True(strings.HasPrefix(faucetURL, "http://127.0.0.1:"))
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.GetFaucetURL()
+	assert.ErrorIs(err, network.ErrStopped)
+	// The faucet stopped with the network
+	_, err = http.Post(faucetURL, "application/json", strings.NewReader("{}"))
+	assert.Error(err)
+}
+
+// TestObservability tests that the network's nodes are
+// scraped by its Prometheus, which stops with the network
+func TestObservability(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Parallel()
+	assert := assert.New(t)
+	rootDir := t.TempDir()
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, rootDir, "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	_, err = net.GetObservabilityURLs()
+	assert.ErrorIs(err, errNoObservability)
+	assert.NoError(net.Stop(context.Background()))
+
+	// Stands in for prometheus
+	prometheusPath := filepath.Join(t.TempDir(), "prometheus")
+	assert.NoError(os.WriteFile(prometheusPath, []byte("#!/bin/sh\ntrap 'exit 0' INT\nwhile true; do sleep 0.1; done\n"), 0o755))
+	net, err = newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, rootDir, "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.Observability = &observability.Config{PrometheusPath: prometheusPath}
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	urls, err := net.GetObservabilityURLs()
+	assert.NoError(err)
+	// Prometheus was given a free port
+	u, err := url.Parse(urls.Prometheus)
+	assert.NoError(err)
+	port, err := strconv.Atoi(u.Port())
+	assert.NoError(err)
+	assert.GreaterOrEqual(port, minPort)
+	assert.LessOrEqual(port, maxPort)
+
+	// Every node is a target, until removed
+	readTargets := func() string {
+		targets, err := os.ReadFile(filepath.Join(rootDir, observabilitySubDir, "targets.json"))
+		assert.NoError(err)
+		return string(targets)
+	}
+	for _, nodeConfig := range networkConfig.NodeConfigs {
+		assert.Contains(readTargets(), fmt.Sprintf("%q", nodeConfig.Name))
+	}
+	removedName := networkConfig.NodeConfigs[0].Name
+	assert.NoError(net.RemoveNode(removedName))
+	assert.NotContains(readTargets(), fmt.Sprintf("%q", removedName))
+
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.GetObservabilityURLs()
+	assert.ErrorIs(err, network.ErrStopped)
+
+	// The network is stopped, with its resource sampler,
+	// if the observability processes can't be started
+	net, err = newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig = testNetworkConfig(t)
+	networkConfig.ResourceUsageInterval = time.Hour
+	networkConfig.ResourceUsageFile = filepath.Join(t.TempDir(), "usage.json")
+	networkConfig.Observability = &observability.Config{PrometheusPath: filepath.Join(t.TempDir(), "missing")}
+	assert.Error(net.loadConfig(context.Background(), networkConfig))
+	assert.True(net.stopCalled())
+	select {
+	case <-net.resourceSamplerDone:
+	case <-time.After(5 * time.Second):
+		assert.Fail("resource sampler still running")
+	}
+}
+
+// TestExplorer tests that the network's explorer is given the APIs
+// of its first node, and stops with the network
+func TestExplorer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Parallel()
+	assert := assert.New(t)
+	// Stands in for an explorer, writing the node URI it's given,
+	// and then "stopped" once interrupted
+	dir := t.TempDir()
+	explorerPath := filepath.Join(dir, "explorer")
+	outPath := filepath.Join(dir, "out")
+	script := "#!/bin/sh\necho \"$EXPLORER_NODE_URI\" > " + outPath + "\ntrap 'echo stopped >> " + outPath + "; exit 0' INT\nwhile true; do sleep 0.1; done\n"
+	assert.NoError(os.WriteFile(explorerPath, []byte(script), 0o755))
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.Explorer = &explorer.Config{BinaryPath: explorerPath, Port: 4000}
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	// Its URL is reported by Status
+	assert.Equal("http://127.0.0.1:4000", net.explorer.URL())
+	nodeNames, err := net.GetNodeNames()
+	assert.NoError(err)
+	sort.Strings(nodeNames)
+	firstNode, err := net.GetNode(nodeNames[0])
+	assert.NoError(err)
+	assert.Eventually(func() bool {
+		out, err := os.ReadFile(outPath)
+		return err == nil && string(out) == firstNode.GetURI()+"\n"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NoError(net.Stop(context.Background()))
+	out, err := os.ReadFile(outPath)
+	assert.NoError(err)
+	assert.Equal(firstNode.GetURI()+"\nstopped\n", string(out))
+
+	// The network is stopped if the explorer doesn't start
+	net, err = newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig = testNetworkConfig(t)
+	networkConfig.Explorer = &explorer.Config{BinaryPath: filepath.Join(dir, "missing"), Port: 4000}
+	assert.Error(net.loadConfig(context.Background(), networkConfig))
+	assert.Empty(net.nodes)
+	assert.ErrorIs(net.Stop(context.Background()), network.ErrStopped)
+}
+
+// Returns the contents of the files in the tar.gz at [path]
+func readTarGz(t *testing.T, path string) map[string]string {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	gzipReader, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	files := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files
+		}
+		assert.NoError(t, err)
+		contents, err := io.ReadAll(tarReader)
+		assert.NoError(t, err)
+		files[header.Name] = string(contents)
+	}
+}
+
+func TestCollectArtifacts(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.ArtifactsPath = filepath.Join(t.TempDir(), "stop", "artifacts.tar.gz")
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	// Written by avalanchego
+	assert.NoError(os.MkdirAll(net.nodes["node0"].GetLogsDir(), 0o755))
+	assert.NoError(os.WriteFile(filepath.Join(net.nodes["node0"].GetLogsDir(), "main.log"), []byte("log line"), 0o644))
+
+	path := filepath.Join(t.TempDir(), "artifacts.tar.gz")
+	assert.NoError(net.CollectArtifacts(context.Background(), path))
+	files := readTarGz(t, path)
+	assert.Equal(string(net.genesis), files[genesisFileName])
+	assert.Contains(files, "history.json")
+	assert.Equal("log line", files["node0/"+defaultLogsSubdir+"/main.log"])
+	assert.Contains(files, "node0/"+configFileName)
+	assert.Contains(files, "node0/"+stakingCertFileName)
+	assert.NotContains(files, "node0/"+stakingKeyFileName)
+	var manifest network.ArtifactsManifest
+	assert.NoError(json.Unmarshal([]byte(files[network.ArtifactsManifestName]), &manifest))
+	assert.Equal(net.networkID, manifest.NetworkID)
+	assert.Len(manifest.Nodes, 3)
+	node0 := manifest.Nodes[0]
+	assert.Equal("node0", node0.Name)
+	assert.Equal(net.nodes["node0"].nodeID, node0.NodeID)
+	assert.True(node0.Running)
+	assert.False(node0.Exited)
+	// No metrics API in this test
+	assert.NotEmpty(node0.MetricsErr)
+	assert.Contains(node0.Files, "node0/"+configFileName)
+	assert.NotContains(node0.Files, "node0/"+stakingKeyFileName)
+
+	// Artifacts are collected on Stop, before the nodes are stopped
+	assert.NoError(net.Stop(context.Background()))
+	files = readTarGz(t, networkConfig.ArtifactsPath)
+	assert.NoError(json.Unmarshal([]byte(files[network.ArtifactsManifestName]), &manifest))
+	assert.Len(manifest.Nodes, 3)
+	for _, nodeArtifacts := range manifest.Nodes {
+		assert.True(nodeArtifacts.Running)
+		assert.False(nodeArtifacts.Exited)
+		assert.NotEmpty(nodeArtifacts.MetricsErr)
+	}
+	assert.Equal("log line", files["node0/"+defaultLogsSubdir+"/main.log"])
+}
+
+// info.Client whose peers are the nodes of [nodeIDs]
+type meshInfoClient struct {
+	info.Client
+	lock    *sync.Mutex
+	nodeIDs *[]ids.NodeID
+}
+
+func (c *meshInfoClient) Peers(context.Context, ...rpc.Option) ([]info.Peer, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	peers := make([]info.Peer, len(*c.nodeIDs))
+	for i, nodeID := range *c.nodeIDs {
+		peers[i].ID = nodeID
+	}
+	return peers, nil
+}
+
+func TestAwaitFullMesh(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	lock := &sync.Mutex{}
+	nodeIDs := []ids.NodeID{}
+	newAPI := func(ipAddr string, port uint16) api.Client {
+		client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+		client.On("InfoAPI").Return(&meshInfoClient{lock: lock, nodeIDs: &nodeIDs})
+		return client
+	}
+	net, err := newNetwork(logging.NoLog{}, newAPI, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.HealthyRequireFullMesh = true
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	// No node is connected to node2
+	lock.Lock()
+	nodeIDs = append(nodeIDs, net.nodes["node0"].nodeID, net.nodes["node1"].nodeID)
+	lock.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	err = net.AwaitFullMesh(ctx)
+	cancel()
+	assert.Error(err)
+	assert.Contains(err.Error(), "isn't connected to node2")
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	assert.Error(net.Healthy(ctx))
+	cancel()
+
+	// The network isn't locked while the mesh is awaited
+	meshErrCh := make(chan error, 1)
+	go func() {
+		meshErrCh <- net.AwaitFullMesh(context.Background())
+	}()
+	time.Sleep(100 * time.Millisecond)
+	locked := make(chan struct{})
+	go func() {
+		net.lock.Lock()
+		net.lock.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		assert.FailNow("network locked while awaiting the mesh")
+	}
+
+	lock.Lock()
+	nodeIDs = append(nodeIDs, net.nodes["node2"].nodeID)
+	lock.Unlock()
+	assert.NoError(<-meshErrCh)
+	assert.NoError(net.AwaitFullMesh(context.Background()))
+	assert.NoError(net.Healthy(context.Background()))
+
+	assert.NoError(net.Stop(context.Background()))
+	assert.ErrorIs(net.AwaitFullMesh(context.Background()), network.ErrStopped)
+}
+
+func TestGetTopology(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	lock := &sync.Mutex{}
+	nodeIDs := []ids.NodeID{}
+	newAPI := func(ipAddr string, port uint16) api.Client {
+		client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+		client.On("InfoAPI").Return(&meshInfoClient{lock: lock, nodeIDs: &nodeIDs})
+		return client
+	}
+	net, err := newNetwork(logging.NoLog{}, newAPI, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	node0, node1, node2 := net.nodes["node0"].nodeID, net.nodes["node1"].nodeID, net.nodes["node2"].nodeID
+
+	// Every node reports being connected to node0 and node1
+	external := ids.GenerateTestNodeID()
+	lock.Lock()
+	nodeIDs = append(nodeIDs, node0, node1, external)
+	lock.Unlock()
+	topology, err := net.GetTopology(context.Background())
+	assert.NoError(err)
+	assert.Empty(topology.Errors)
+	assert.Len(topology.Peers, 3)
+	assert.Equal("node2", topology.Names[node2])
+	assert.True(topology.Connected(node0, node1))
+	assert.True(topology.Connected(node2, node0))
+	assert.False(topology.Connected(node0, node2))
+	for _, peer := range topology.Peers[node2] {
+		if peer.NodeID == external {
+			assert.Empty(peer.Name)
+		} else {
+			assert.NotEmpty(peer.Name)
+		}
+	}
+
+	dot := &bytes.Buffer{}
+	assert.NoError(topology.WriteDOT(dot))
+	assert.Contains(dot.String(), fmt.Sprintf("%q [label=\"node0\"];", node0))
+	assert.Contains(dot.String(), fmt.Sprintf("%q -- %q;", node0, node1))
+	assert.Contains(dot.String(), fmt.Sprintf("%q -- %q [style=dashed];", node0, node2))
+	assert.NotContains(dot.String(), external.String())
+
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.GetTopology(context.Background())
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+func TestGetURIs(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+	uris, err := net.GetURIs()
+	assert.NoError(err)
+	assert.Equal([]string{
+		fmt.Sprintf("http://127.0.0.1:%d", net.nodes["node0"].apiPort),
+		fmt.Sprintf("http://127.0.0.1:%d", net.nodes["node1"].apiPort),
+		fmt.Sprintf("http://127.0.0.1:%d", net.nodes["node2"].apiPort),
+	}, uris)
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.GetURIs()
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+func TestHostsRegistry(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	hostsFile := hosts.NewFile(filepath.Join(t.TempDir(), "hosts"))
+	networkConfig := testNetworkConfig(t)
+	networkConfig.HostsRegistry = hostsFile
+	networkConfig.NodeConfigs[1].BindAddr = "::1"
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	entries, err := hostsFile.Entries()
+	assert.NoError(err)
+	assert.Equal(map[string]string{
+		"node0.avax.local": "127.0.0.1",
+		"node1.avax.local": "::1",
+		"node2.avax.local": "127.0.0.1",
+	}, entries)
+	node0, err := net.GetNode("node0")
+	assert.NoError(err)
+	assert.Equal("node0.avax.local", node0.GetHostname())
+
+	// Removed nodes are unregistered
+	assert.NoError(net.RemoveNode("node0"))
+	entries, err = hostsFile.Entries()
+	assert.NoError(err)
+	assert.NotContains(entries, "node0.avax.local")
+
+	nodeConfig := networkConfig.NodeConfigs[0]
+	nodeConfig.Name = "not_a_host_name"
+	_, err = net.AddNode(nodeConfig)
+	assert.Error(err)
+
+	assert.NoError(net.Stop(context.Background()))
+	entries, err = hostsFile.Entries()
+	assert.NoError(err)
+	assert.Empty(entries)
+}
+
+// health.Client that records the max number of concurrent Health calls
+type concurrencyHealthClient struct {
+	health.Client
+	inFlight    *int32
+	maxInFlight *int32
+}
+
+func (c *concurrencyHealthClient) Health(context.Context, ...rpc.Option) (*health.APIHealthReply, error) {
+	n := atomic.AddInt32(c.inFlight, 1)
+	defer atomic.AddInt32(c.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(c.maxInFlight)
+		if n <= max || atomic.CompareAndSwapInt32(c.maxInFlight, max, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return &health.APIHealthReply{Healthy: true}, nil
+}
+
+func TestHealthCheckParallelism(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	var inFlight, maxInFlight int32
+	newAPI := func(ipAddr string, port uint16) api.Client {
+		ethClient := &apimocks.EthClient{}
+		ethClient.On("Close").Return()
+		client := &apimocks.Client{}
+		client.On("HealthAPI").Return(&concurrencyHealthClient{inFlight: &inFlight, maxInFlight: &maxInFlight})
+		client.On("CChainEthAPI").Return(ethClient)
+		return client
+	}
+	net, err := newNetwork(logging.NoLog{}, newAPI, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.HealthCheckParallelism = 1
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	assert.NoError(net.Healthy(context.Background()))
+	assert.EqualValues(1, atomic.LoadInt32(&maxInFlight))
+	assert.NoError(net.Stop(context.Background()))
+
+	networkConfig.HealthCheckParallelism = -1
+	assert.NotEmpty(networkConfig.Validate())
+}
+
+func TestJittered(t *testing.T) {
+	assert := assert.New(t)
+	for i := 0; i < 100; i++ {
+		d := jittered(healthCheckFreq)
+		assert.GreaterOrEqual(d, healthCheckFreq-time.Duration(float64(healthCheckFreq)*healthCheckJitter))
+		assert.LessOrEqual(d, healthCheckFreq+time.Duration(float64(healthCheckFreq)*healthCheckJitter))
+	}
+}
+
+func TestExportImportDB(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	nodeConfig := networkConfig.NodeConfigs[2]
+	networkConfig.NodeConfigs = networkConfig.NodeConfigs[:2]
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	networkName := constants.NetworkName(net.networkID)
+
+	// The node has no database yet
+	node0 := net.nodes["node0"]
+	exportDir := filepath.Join(t.TempDir(), "export")
+	assert.Error(node0.ExportDB(exportDir))
+
+	dbFile := filepath.Join(networkName, "v1.4.5", "000001.log")
+	assert.NoError(os.MkdirAll(filepath.Dir(filepath.Join(node0.GetDbDir(), dbFile)), 0o755))
+	assert.NoError(os.WriteFile(filepath.Join(node0.GetDbDir(), dbFile), []byte("state"), 0o600))
+	assert.NoError(node0.ExportDB(exportDir))
+	// The destination dir must be empty
+	assert.Error(node0.ExportDB(exportDir))
+
+	nodeConfig.DBPath = exportDir
+	imported, err := net.AddNode(nodeConfig)
+	assert.NoError(err)
+	contents, err := os.ReadFile(filepath.Join(imported.GetDbDir(), dbFile))
+	assert.NoError(err)
+	assert.Equal("state", string(contents))
+
+	// The database must be of the network's ID
+	otherDir := t.TempDir()
+	assert.NoError(os.MkdirAll(filepath.Join(otherDir, constants.NetworkName(constants.FujiID)), 0o755))
+	nodeConfig.Name = "node3"
+	nodeConfig.DBPath = otherDir
+	_, err = net.AddNode(nodeConfig)
+	assert.Error(err)
+	assert.Contains(err.Error(), "is of network fuji")
+
+	assert.NoError(net.Stop(context.Background()))
+}
+
+// TestClone checks that a clone starts from a copy of the databases of
+// the network's nodes, with new ports and the overrides applied
+func TestClone(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), t.TempDir())
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.Flags = map[string]interface{}{
+		config.SnowSampleSizeKey: 2,
+	}
+	networkConfig.HealthCheckParallelism = 3
+	networkConfig.HistoryFile = filepath.Join(t.TempDir(), "history.jsonl")
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	networkName := constants.NetworkName(net.networkID)
+	dbFile := filepath.Join(networkName, "v1.4.5", "000001.log")
+	for name, node := range net.nodes {
+		assert.NoError(os.MkdirAll(filepath.Dir(filepath.Join(node.GetDbDir(), dbFile)), 0o755))
+		assert.NoError(os.WriteFile(filepath.Join(node.GetDbDir(), dbFile), []byte(name), 0o600))
+	}
+
+	_, err = net.Clone(context.Background(), network.CloneOverrides{
+		NodeFlags: map[string]map[string]interface{}{"not a node": {}},
+	})
+	assert.ErrorIs(err, network.ErrNodeNotFound)
+
+	cloneIntf, err := net.Clone(context.Background(), network.CloneOverrides{
+		Flags: map[string]interface{}{
+			config.SnowSampleSizeKey: 3,
+		},
+		NodeFlags: map[string]map[string]interface{}{
+			"node0": {config.SnowQuorumSizeKey: 2},
+		},
+	})
+	assert.NoError(err)
+	clone := cloneIntf.(*localNetwork)
+	assert.Len(clone.nodes, len(net.nodes))
+	// The network-level settings are cloned, except for the shared files
+	assert.Equal(3, cap(clone.healthCheckSem))
+	assert.Equal(net.genesis, clone.genesis)
+	assert.Empty(clone.config.HistoryFile)
+	for name, node := range net.nodes {
+		// The nodes were paused while their databases were copied
+		assert.False(node.paused)
+		node.process.(*mocks.NodeProcess).AssertCalled(t, "Pause")
+		node.process.(*mocks.NodeProcess).AssertCalled(t, "Resume")
+
+		cloneNode, ok := clone.nodes[name]
+		assert.True(ok)
+		assert.Equal(node.GetNodeID(), cloneNode.GetNodeID())
+		assert.NotEqual(node.GetAPIPort(), cloneNode.GetAPIPort())
+		assert.NotEqual(node.GetP2PPort(), cloneNode.GetP2PPort())
+		assert.NotEqual(node.GetDbDir(), cloneNode.GetDbDir())
+		contents, err := os.ReadFile(filepath.Join(cloneNode.GetDbDir(), dbFile))
+		assert.NoError(err)
+		assert.Equal(name, string(contents))
+		sampleSize, err := cloneNode.GetFlags().Int(config.SnowSampleSizeKey)
+		assert.NoError(err)
+		assert.EqualValues(3, sampleSize)
+	}
+	quorumSize, err := clone.nodes["node0"].GetFlags().Int(config.SnowQuorumSizeKey)
+	assert.NoError(err)
+	assert.EqualValues(2, quorumSize)
+	_, ok := clone.nodes["node1"].GetFlags().Get(config.SnowQuorumSizeKey)
+	assert.False(ok)
+
+	// The clone is independent of the network
+	assert.NoError(clone.Stop(context.Background()))
+	_, err = net.GetNode("node0")
+	assert.NoError(err)
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.Clone(context.Background(), network.CloneOverrides{})
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+func TestAwaitStateSync(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	networkConfig.StateSync = &network.StateSyncConfig{}
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+
+	// Every node serves state summaries
+	for _, node := range net.nodes {
+		var cChainConfig map[string]interface{}
+		assert.NoError(json.Unmarshal([]byte(node.config.CChainConfigFile), &cChainConfig))
+		assert.EqualValues(network.DefaultStateSyncCommitInterval, cChainConfig["commit-interval"])
+	}
+
+	writeCChainLog := func(nodeName string, lines ...string) {
+		logsDir := net.nodes[nodeName].GetLogsDir()
+		assert.NoError(os.MkdirAll(logsDir, 0o755))
+		assert.NoError(os.WriteFile(filepath.Join(logsDir, cChainLogFile), []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+	}
+	writeCChainLog("node0",
+		"[06-01|12:00:00.000] INFO <C Chain> syncervm_client.go:217: Starting state sync summary=...",
+		"[06-01|12:00:01.000] INFO <C Chain> syncervm_client.go:294: state sync: sync starting root=...",
+		"[06-01|12:00:05.500] INFO <C Chain> syncervm_client.go:227: stateSync completed, notifying engine err=<nil>",
+	)
+	report, err := net.AwaitStateSync(context.Background(), "node0")
+	assert.NoError(err)
+	assert.Equal(network.StateSyncReport{Node: "node0", Synced: true, Duration: 5500 * time.Millisecond}, report)
+
+	writeCChainLog("node1",
+		"[06-01|12:00:00.000] INFO <C Chain> syncervm_client.go:217: Starting state sync summary=...",
+		`[06-01|12:00:02.000] INFO <C Chain> syncervm_client.go:227: stateSync completed, notifying engine err="no peers"`,
+	)
+	report, err = net.AwaitStateSync(context.Background(), "node1")
+	assert.Error(err)
+	assert.Contains(err.Error(), "no peers")
+	assert.True(report.Synced)
+
+	writeCChainLog("node2",
+		"[06-01|12:00:00.000] INFO <C Chain> syncervm_client.go:179: last accepted too close to most recent syncable block, skipping state sync lastAccepted=0 syncableHeight=64",
+	)
+	report, err = net.AwaitStateSync(context.Background(), "node2")
+	assert.NoError(err)
+	assert.False(report.Synced)
+
+	_, err = net.AwaitStateSync(context.Background(), "nonexistent")
+	assert.Error(err)
+	assert.NoError(net.Stop(context.Background()))
+	_, err = net.AwaitStateSync(context.Background(), "node0")
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+func TestSetSubnetWhitelist(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	assert.NoError(net.loadConfig(context.Background(), testNetworkConfig(t)))
+
+	subnetIDs, err := net.GetTrackedSubnets("node0")
+	assert.NoError(err)
+	assert.Empty(subnetIDs)
+
+	subnetID0, subnetID1 := ids.GenerateTestID(), ids.GenerateTestID()
+	expected := []ids.ID{subnetID0, subnetID1}
+	ids.SortIDs(expected)
+	before := net.nodes["node0"]
+	assert.NoError(net.SetSubnetWhitelist("node0", []ids.ID{subnetID1, subnetID0, subnetID1}))
+	// The node was restarted
+	assert.NotSame(before, net.nodes["node0"])
+	subnetIDs, err = net.GetTrackedSubnets("node0")
+	assert.NoError(err)
+	assert.Equal(expected, subnetIDs)
+	subnetIDs, err = net.GetTrackedSubnets("node1")
+	assert.NoError(err)
+	assert.Empty(subnetIDs)
+
+	// The whitelist is replaced
+	assert.NoError(net.SetSubnetWhitelist("node0", nil))
+	subnetIDs, err = net.GetTrackedSubnets("node0")
+	assert.NoError(err)
+	assert.Empty(subnetIDs)
+
+	assert.Error(net.SetSubnetWhitelist("node0", []ids.ID{constants.PrimaryNetworkID}))
+	assert.Error(net.SetSubnetWhitelist("nonexistent", nil))
+	_, err = net.GetTrackedSubnets("nonexistent")
+	assert.Error(err)
+
+	assert.NoError(net.Stop(context.Background()))
+	assert.ErrorIs(net.SetSubnetWhitelist("node0", nil), network.ErrStopped)
+	_, err = net.GetTrackedSubnets("node0")
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+func TestDiskFault(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("disk faults are only supported on linux")
+	}
+	assert := assert.New(t)
+	var cmds []string
+	failCmd := ""
+	defer func(f func(string, ...string) (string, error)) { runDiskFaultCmd = f }(runDiskFaultCmd)
+	runDiskFaultCmd = func(name string, args ...string) (string, error) {
+		cmds = append(cmds, strings.Join(append([]string{name}, args...), " "))
+		if name == failCmd {
+			return "", errors.New("failed")
+		}
+		if name == "losetup" && args[0] == "--find" {
+			return "/dev/loop7", nil
+		}
+		return "", nil
+	}
+
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	nodeConfig := networkConfig.NodeConfigs[2]
+	networkConfig.NodeConfigs = networkConfig.NodeConfigs[:2]
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	assert.True(net.Capabilities().DiskFaults)
+
+	nodeConfig.DiskFault = &node.DiskFault{MaxDBSize: 64 * units.MiB, IOLatency: 5 * time.Millisecond}
+	_, err = net.AddNode(nodeConfig)
+	assert.NoError(err)
+	dbDir := net.nodes["node2"].GetDbDir()
+	dmName := fmt.Sprintf("anr-%d-node2", os.Getpid())
+	info, err := os.Stat(dbDir + ".img")
+	assert.NoError(err)
+	assert.EqualValues(64*units.MiB, info.Size())
+	assert.Equal([]string{
+		"mkfs.ext4 -q -F " + dbDir + ".img",
+		"losetup --find --show " + dbDir + ".img",
+		"dmsetup create " + dmName + " --table 0 131072 delay /dev/loop7 0 5",
+		"mount /dev/mapper/" + dmName + " " + dbDir,
+	}, cmds)
+
+	// The image is kept, and mounted again, when the node is restarted
+	cmds = nil
+	assert.NoError(net.UpdateNodeFlags("node2", map[string]interface{}{"log-level": "debug"}))
+	assert.Equal([]string{
+		"umount " + dbDir,
+		"dmsetup remove " + dmName,
+		"losetup --detach /dev/loop7",
+		"losetup --find --show " + dbDir + ".img",
+		"dmsetup create " + dmName + " --table 0 131072 delay /dev/loop7 0 5",
+		"mount /dev/mapper/" + dmName + " " + dbDir,
+	}, cmds)
+
+	// Without IO latency, the image is mounted through a loop device
+	cmds = nil
+	assert.NoError(net.RemoveNode("node2"))
+	nodeConfig.DiskFault.IOLatency = 0
+	_, err = net.AddNode(nodeConfig)
+	assert.NoError(err)
+	assert.Equal([]string{
+		"umount " + dbDir,
+		"dmsetup remove " + dmName,
+		"losetup --detach /dev/loop7",
+		"mount -o loop " + dbDir + ".img " + dbDir,
+	}, cmds)
+
+	// The steps done are undone if a later one fails
+	cmds = nil
+	failCmd = "mount"
+	assert.NoError(net.RemoveNode("node2"))
+	nodeConfig.DiskFault.IOLatency = time.Millisecond
+	_, err = net.AddNode(nodeConfig)
+	assert.Error(err)
+	assert.Equal([]string{
+		"umount " + dbDir,
+		"losetup --find --show " + dbDir + ".img",
+		"dmsetup create " + dmName + " --table 0 131072 delay /dev/loop7 0 1",
+		"mount /dev/mapper/" + dmName + " " + dbDir,
+		"dmsetup remove " + dmName,
+		"losetup --detach /dev/loop7",
+	}, cmds)
+
+	nodeConfig.DiskFault.MaxDBSize = units.MiB
+	_, err = net.AddNode(nodeConfig)
+	assert.Error(err)
+	assert.NoError(net.Stop(context.Background()))
+}
+
+// indexer.Client that serves the containers appended to it
+type fakeIndexClient struct {
+	indexer.Client
+	lock       sync.Mutex
+	containers []indexer.Container
+}
+
+func (c *fakeIndexClient) accept(container indexer.Container) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.containers = append(c.containers, container)
+}
+
+func (c *fakeIndexClient) GetContainerRange(_ context.Context, startIndex uint64, numToFetch int, _ ...rpc.Option) ([]indexer.Container, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if startIndex >= uint64(len(c.containers)) {
+		return nil, errors.New("start index is greater than the last accepted index")
+	}
+	end := startIndex + uint64(numToFetch)
+	if end > uint64(len(c.containers)) {
+		end = uint64(len(c.containers))
+	}
+	return append([]indexer.Container(nil), c.containers[startIndex:end]...), nil
+}
+
+func TestAcceptedEvents(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	indexClient := &fakeIndexClient{}
+	newAPIClient := func(ipAddr string, port uint16) api.Client {
+		client := newMockAPISuccessful(ipAddr, port).(*apimocks.Client)
+		client.On("PChainIndexAPI").Return(indexClient)
+		return client
+	}
+	net, err := newNetwork(logging.NoLog{}, newAPIClient, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	// Indexing isn't enabled
+	_, err = net.AcceptedEvents(context.Background(), network.IndexPChainBlocks)
+	assert.Error(err)
+	assert.NoError(net.Stop(context.Background()))
+
+	net, err = newNetwork(logging.NoLog{}, newAPIClient, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig.IndexEnabled = true
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	// The caller's flags aren't modified
+	assert.NotContains(networkConfig.Flags, config.IndexEnabledKey)
+	for _, node := range net.nodes {
+		assert.Equal(true, node.config.Flags[config.IndexEnabledKey])
+	}
+	_, err = net.AcceptedEvents(context.Background(), network.Index("nonexistent"))
+	assert.Error(err)
+
+	// Containers accepted before the call are streamed
+	container0 := indexer.Container{ID: ids.GenerateTestID(), Timestamp: time.Now().UnixNano()}
+	indexClient.accept(container0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	events, err := net.AcceptedEvents(ctx, network.IndexPChainBlocks)
+	assert.NoError(err)
+	// Node name --> events
+	received := func() map[string][]network.AcceptedEvent {
+		received := map[string][]network.AcceptedEvent{}
+		for i := 0; i < len(net.nodes); i++ {
+			event := <-events
+			received[event.Node] = append(received[event.Node], event)
+		}
+		return received
+	}
+	for name, nodeEvents := range received() {
+		assert.Contains(net.nodes, name)
+		assert.Len(nodeEvents, 1)
+		assert.Equal(network.IndexPChainBlocks, nodeEvents[0].Index)
+		assert.EqualValues(0, nodeEvents[0].Position)
+		assert.Equal(container0.ID, nodeEvents[0].ContainerID)
+		assert.Equal(container0.Timestamp, nodeEvents[0].Timestamp.UnixNano())
+	}
+	// Then the ones accepted afterwards
+	container1 := indexer.Container{ID: ids.GenerateTestID(), Timestamp: time.Now().UnixNano()}
+	indexClient.accept(container1)
+	for _, nodeEvents := range received() {
+		assert.Len(nodeEvents, 1)
+		assert.EqualValues(1, nodeEvents[0].Position)
+		assert.Equal(container1.ID, nodeEvents[0].ContainerID)
+	}
+
+	// The channel is closed when the network stops
+	assert.NoError(net.Stop(context.Background()))
+	for range events {
+	}
+	_, err = net.AcceptedEvents(context.Background(), network.IndexPChainBlocks)
+	assert.ErrorIs(err, network.ErrStopped)
+}
+
+func TestParseBinaryVersion(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	version, err := parseBinaryVersion("avalanche/1.7.11 [database=v1.4.5, commit=abc]")
+	assert.NoError(err)
+	assert.Equal(binaryVersion{app: "avalanche", major: 1, minor: 7, patch: 11}, version)
+	assert.Equal("avalanche/1.7.11", version.String())
+	version, err = parseBinaryVersion("Subnet-EVM/v0.2.4 [AvalancheGo=v1.7.14, rpcchainvm=15]")
+	assert.NoError(err)
+	assert.Equal(binaryVersion{app: "Subnet-EVM", major: 0, minor: 2, patch: 4, rpcChainVM: 15}, version)
+	_, err = parseBinaryVersion("This binary is a plugin")
+	assert.ErrorIs(err, errNoVersion)
+
+	assert.NoError(protocolCompatible(binaryVersion{major: 1, minor: 7}, binaryVersion{major: 1, minor: 8}))
+	assert.Error(protocolCompatible(binaryVersion{major: 1, minor: 7}, binaryVersion{major: 1, minor: 9}))
+	assert.Error(protocolCompatible(binaryVersion{major: 1, minor: 7}, binaryVersion{major: 2, minor: 7}))
+}
+
+func TestVersionCompat(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	// Binary path --> version output
+	versions := map[string]string{}
+	newBinary := func(name, version string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(os.WriteFile(path, nil, 0o755))
+		versions[path] = version
+		return path
+	}
+	var numCmds int
+	defer func(f func(string) (string, error)) { runVersionCmd = f }(runVersionCmd)
+	runVersionCmd = func(binaryPath string) (string, error) {
+		numCmds++
+		output, ok := versions[binaryPath]
+		if !ok {
+			return "", errors.New("failed")
+		}
+		return output, nil
+	}
+	nodeBinary := newBinary("avalanchego", "avalanche/1.7.14 [database=v1.4.5, rpcchainvm=15]")
+	newNodeBinary := newBinary("avalanchego-1.8", "avalanche/1.8.0 [database=v1.4.5, rpcchainvm=16]")
+	oldNodeBinary := newBinary("avalanchego-1.5", "avalanche/1.5.3 [database=v1.4.5]")
+	vmBinary := newBinary("vm", "Subnet-EVM/v0.2.5 [AvalancheGo=v1.7.14, rpcchainvm=15]")
+
+	net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	for i := range networkConfig.NodeConfigs {
+		networkConfig.NodeConfigs[i].BinaryPath = nodeBinary
+	}
+	networkConfig.CustomVMs = []network.CustomVM{{Name: "vm", BinaryPath: vmBinary}}
+	nodeConfig := networkConfig.NodeConfigs[2]
+	networkConfig.NodeConfigs = networkConfig.NodeConfigs[:2]
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	// Each binary is queried once
+	assert.Equal(2, numCmds)
+
+	// More than one minor version behind
+	nodeConfig.BinaryPath = oldNodeBinary
+	_, err = net.AddNode(nodeConfig)
+	assert.Error(err)
+	assert.Contains(err.Error(), "more than one minor version apart")
+	// Compatible with the other nodes, but not with the VM
+	nodeConfig.BinaryPath = newNodeBinary
+	_, err = net.AddNode(nodeConfig)
+	assert.Error(err)
+	assert.Contains(err.Error(), "RPC chain VM protocol")
+	// Binaries whose version can't be queried aren't checked
+	nodeConfig.BinaryPath = newBinary("wrapper", "")
+	delete(versions, nodeConfig.BinaryPath)
+	_, err = net.AddNode(nodeConfig)
+	assert.NoError(err)
+	assert.NoError(net.Stop(context.Background()))
+}
+
+func TestTimingReport(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	net, err := newNetwork(logging.NoLog{}, newMockAPIBootstrapped, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig := testNetworkConfig(t)
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	_, err = net.TimingReport()
+	assert.Error(err)
+	assert.NoError(net.Stop(context.Background()))
+
+	net, err = newNetwork(logging.NoLog{}, newMockAPIBootstrapped, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+	assert.NoError(err)
+	networkConfig.RecordTimings = true
+	assert.NoError(net.loadConfig(context.Background(), networkConfig))
+	done := func() bool {
+		report, err := net.TimingReport()
+		if err != nil || len(report.Nodes) != len(networkConfig.NodeConfigs) {
+			return false
+		}
+		for _, timing := range report.Nodes {
+			if timing.Healthy.IsZero() || len(timing.ChainsBootstrapped) != len(primaryNetworkChains) {
+				return false
+			}
+		}
+		return true
+	}
+	assert.Eventually(done, 10*time.Second, 50*time.Millisecond)
+	report, err := net.TimingReport()
+	assert.NoError(err)
+	for i, timing := range report.Nodes {
+		assert.Equal(fmt.Sprintf("node%d", i), timing.Node)
+		assert.False(timing.FirstAPIResponse.Before(timing.ProcessStarted))
+		assert.False(timing.Healthy.Before(timing.FirstAPIResponse))
+		assert.Equal(timing.Healthy.Sub(timing.ProcessStarted), timing.TimeToHealthy())
+		for _, chain := range primaryNetworkChains {
+			assert.Positive(timing.TimeToBootstrap(chain))
+		}
+		assert.Zero(timing.TimeToBootstrap("nonexistent"))
+	}
+
+	// Removed nodes are kept
+	assert.NoError(net.RemoveNode("node2"))
+	assert.NoError(net.Stop(context.Background()))
+	report, err = net.TimingReport()
+	assert.NoError(err)
+	assert.Len(report.Nodes, len(networkConfig.NodeConfigs))
+}
+
+func TestBindPort(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	// A port bound on the node IP alone isn't free
+	l, err := net.Listen("tcp", net.JoinHostPort(defaultNodeIP, "0"))
+	assert.NoError(err)
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+	assert.Error(bindPort(port))
+	assert.Error(checkPortFree(port))
+	assert.NoError(l.Close())
+	assert.NoError(bindPort(port))
+}
+
+func TestCleanupStaleNetworks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sleep")
+	}
+	assert := assert.New(t)
+	defer func(dir string) { RegistryDir = dir }(RegistryDir)
+	RegistryDir = t.TempDir()
+
+	sleepPath, err := exec.LookPath("sleep")
+	assert.NoError(err)
+	// A runner that's no longer running
+	deadRunner := exec.Command(sleepPath, "0")
+	assert.NoError(deadRunner.Run())
+	newProcess := func(name string, args ...string) *nodeProcessImpl {
+		process := &nodeProcessImpl{cmd: exec.Command(name, args...)}
+		assert.NoError(process.Start())
+		return process
+	}
+	newRegisteredNetwork := func() *localNetwork {
+		net, err := newNetwork(logging.NoLog{}, newMockAPISuccessful, &localTestSuccessfulNodeProcessCreator{}, t.TempDir(), "")
+		assert.NoError(err)
+		// Processes of unknown ID aren't registered
+		registryFile, err := net.registerNode("mock", sleepPath, &mocks.NodeProcess{})
+		assert.NoError(err)
+		assert.Empty(registryFile)
+		assert.False(net.registered)
+		return net
+	}
+
+	// A network of a running runner isn't cleaned up
+	liveNet := newRegisteredNetwork()
+	live := newProcess(sleepPath, "60")
+	_, err = liveNet.registerNode("live", sleepPath, live)
+	assert.NoError(err)
+
+	staleNet := newRegisteredNetwork()
+	orphan := newProcess(sleepPath, "60")
+	_, err = staleNet.registerNode("orphan", sleepPath, orphan)
+	assert.NoError(err)
+	// Nor is a process that reused the ID of an exited node
+	notNode := newProcess(sleepPath, "60")
+	registryFile, err := staleNet.registerNode("exited", sleepPath, notNode)
+	assert.NoError(err)
+	var rnode registeredNode
+	assert.NoError(readRegistryFile(registryFile, &rnode))
+	if runtime.GOOS == "linux" {
+		assert.NotZero(rnode.StartTime)
+		rnode.StartTime--
+		assert.NoError(writeRegistryFile(staleNet.registryDir(), filepath.Base(registryFile), rnode))
+	} else {
+		assert.Zero(rnode.StartTime)
+	}
+	networkFile := filepath.Join(staleNet.registryDir(), registryNetworkFileName)
+	var rn registeredNetwork
+	assert.NoError(readRegistryFile(networkFile, &rn))
+	assert.Equal(staleNet.uuid, rn.UUID)
+	rn.RunnerPID = deadRunner.Process.Pid
+	assert.NoError(writeRegistryFile(staleNet.registryDir(), registryNetworkFileName, rn))
+
+	staleNetworks, err := CleanupStaleNetworks(logging.NoLog{})
+	assert.NoError(err)
+	expectedNetwork := StaleNetwork{
+		UUID:      staleNet.uuid,
+		RunnerPID: deadRunner.Process.Pid,
+		RootDir:   staleNet.rootDir,
+	}
+	if runtime.GOOS == "linux" {
+		expectedNetwork.KilledNodes = []string{"orphan"}
+		assert.Error(orphan.Wait())
+	} else {
+		// Processes can't be told apart from
+		// the ones reusing their IDs, so none is killed
+		expectedNetwork.UnverifiedNodes = []string{"exited", "orphan"}
+		assert.NoError(orphan.cmd.Process.Kill())
+	}
+	assert.Equal([]StaleNetwork{expectedNetwork}, staleNetworks)
+	assert.True(processAlive(notNode.cmd.Process.Pid))
+	assert.True(processAlive(live.cmd.Process.Pid))
+	_, err = os.Stat(staleNet.registryDir())
+	assert.True(os.IsNotExist(err))
+
+	// A network is unregistered once its nodes are
+	liveNet.unregisterNetwork()
+	assert.DirExists(liveNet.registryDir())
+	entries, err := os.ReadDir(liveNet.registryDir())
+	assert.NoError(err)
+	for _, entry := range entries {
+		if entry.Name() != registryNetworkFileName {
+			assert.NoError(os.Remove(filepath.Join(liveNet.registryDir(), entry.Name())))
+		}
+	}
+	liveNet.unregisterNetwork()
+	_, err = os.Stat(liveNet.registryDir())
+	assert.True(os.IsNotExist(err))
+
+	assert.NoError(live.cmd.Process.Kill())
+	_ = live.Wait()
+	_ = notNode.cmd.Process.Kill()
+	_ = notNode.Wait()
+}
@@ -3,14 +3,23 @@ package local
 import (
 	"context"
 	"crypto"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"os"
 	"os/exec"
-	"syscall"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ava-labs/avalanche-network-runner/api"
+	"github.com/ava-labs/avalanche-network-runner/network"
 	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanche-network-runner/utils"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/message"
 	"github.com/ava-labs/avalanchego/network/peer"
@@ -42,10 +51,21 @@ type getConnFunc func(context.Context, node.Node) (net.Conn, error)
 type NodeProcess interface {
 	// Start this process
 	Start() error
-	// Send a SIGTERM to this process
+	// Ask this process to shut down: a SIGTERM, or
+	// a Ctrl-Break event on windows
 	Stop() error
 	// Returns when the process finishes exiting
 	Wait() error
+	// Suspend this process until Resume is called
+	Pause() error
+	// Continue running this process after Pause
+	Resume() error
+	// Send [sig] to this process, e.g. SIGHUP.
+	// Only os.Kill is supported on windows.
+	Signal(sig os.Signal) error
+	// Returns the stdin of this process, or nil if it
+	// isn't a pipe. See node.Config.Stdin.
+	Stdin() io.WriteCloser
 }
 
 const (
@@ -55,18 +75,74 @@ const (
 
 type nodeProcessImpl struct {
 	cmd *exec.Cmd
+	// Files the process's output is written to.
+	// The process has its own handles to them once started.
+	outputFiles []*os.File
+	// Wait may be called more than once, e.g. by the watcher
+	// of crashes and when the node is removed
+	waitOnce sync.Once
+	waitErr  error
+	// The process's stdin, if it's a pipe
+	stdin io.WriteCloser
+	// The last lines of the process's stderr,
+	// if it isn't written to a file
+	stderrTail *tailBuffer
+	// Writes the process's stderr to its redirection, if it's
+	// redirected. Closed once the process exits.
+	stderrWriter io.WriteCloser
+	// File the process's stderr is written to, if any
+	stderrPath string
+	// Guards [exit]
+	exitLock sync.Mutex
+	// How the process exited. Nil until Wait returns.
+	exit *node.ExitStatus
 }
 
 func (p *nodeProcessImpl) Start() error {
-	return p.cmd.Start()
+	err := p.cmd.Start()
+	p.closeOutputFiles()
+	if err != nil && p.stderrWriter != nil {
+		_ = p.stderrWriter.Close()
+	}
+	return err
+}
+
+func (p *nodeProcessImpl) Signal(sig os.Signal) error {
+	return p.cmd.Process.Signal(sig)
+}
+
+func (p *nodeProcessImpl) Stdin() io.WriteCloser {
+	return p.stdin
+}
+
+func (p *nodeProcessImpl) closeOutputFiles() {
+	for _, f := range p.outputFiles {
+		_ = f.Close()
+	}
+	p.outputFiles = nil
 }
 
 func (p *nodeProcessImpl) Wait() error {
-	return p.cmd.Wait()
+	p.waitOnce.Do(func() {
+		p.waitErr = p.cmd.Wait()
+		if p.stderrWriter != nil {
+			_ = p.stderrWriter.Close()
+		}
+		status := p.newExitStatus()
+		p.exitLock.Lock()
+		p.exit = &status
+		p.exitLock.Unlock()
+	})
+	return p.waitErr
 }
 
-func (p *nodeProcessImpl) Stop() error {
-	return p.cmd.Process.Signal(syscall.SIGTERM)
+// Returns the ID of [process], or 0 if it isn't known
+func processPID(process NodeProcess) int {
+	p, ok := process.(*nodeProcessImpl)
+	if !ok || p.cmd.Process == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
 }
 
 // Gives access to basic node info, and to most avalanchego apis
@@ -80,6 +156,8 @@ type localNode struct {
 	networkID uint32
 	// Allows user to make API calls to this node.
 	client api.Client
+	// The HTTP client [client]'s requests are sent with
+	httpClient *http.Client
 	// The process running this node.
 	process NodeProcess
 	// The API port
@@ -94,6 +172,30 @@ type localNode struct {
 	logsDir string
 	// The node config
 	config node.Config
+	// The flags this node was started with
+	flags node.Flags
+	// The command line flags this node's process was started with
+	args []string
+	// When this node's process was started
+	startTime time.Time
+	// The cert this node authenticates with on the P2P network
+	stakingCert *x509.Certificate
+	// Host name this node is registered under, if any
+	hostname string
+	// Unmounts this node's database filesystem, if it has a disk fault
+	teardownDiskFault func()
+	// Path of this node's file in the run registry, if any.
+	// See RegistryDir.
+	registryFile string
+	// True if this node's process is suspended
+	paused bool
+	// True if this node was paused by StopAtHeight, so that its
+	// process is killed without being resumed when it's stopped
+	frozen bool
+	// True once the node is removed from its network
+	removed bool
+	// Ensures OnNodeHealthy is called once
+	healthyOnce sync.Once
 }
 
 func defaultGetConnFunc(ctx context.Context, node node.Node) (net.Conn, error) {
@@ -209,7 +311,31 @@ func (node *localNode) GetAPIClient() api.Client {
 
 // See node.Node
 func (node *localNode) GetURL() string {
-	return "127.0.0.1"
+	return apiIP(node.config.BindAddr)
+}
+
+// See node.Node
+func (node *localNode) GetURI() string {
+	scheme := "http"
+	if node.config.APITLSCert != "" {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(node.GetURL(), strconv.Itoa(int(node.apiPort)))
+}
+
+// See node.Node
+func (node *localNode) GetHostname() string {
+	return node.hostname
+}
+
+// See node.Node
+func (node *localNode) GetStakingCert() *x509.Certificate {
+	return node.stakingCert
+}
+
+// See node.Node
+func (node *localNode) GetStakingCertFingerprint() string {
+	return utils.CertFingerprint(node.stakingCert)
 }
 
 // See node.Node
@@ -227,6 +353,28 @@ func (node *localNode) GetBinaryPath() string {
 	return node.config.BinaryPath
 }
 
+// Returns the event of [node] given to lifecycle hooks,
+// with the error its process exited with, if any
+func (node *localNode) event(err error) network.NodeEvent {
+	event := network.NodeEvent{
+		Name:    node.name,
+		NodeID:  node.nodeID,
+		APIPort: node.apiPort,
+		P2PPort: node.p2pPort,
+		PID:     processPID(node.process),
+		Err:     err,
+	}
+	if status, ok := node.GetExitStatus(); ok {
+		event.Exit = &status
+	}
+	return event
+}
+
+// See node.Node
+func (node *localNode) GetPID() int {
+	return processPID(node.process)
+}
+
 // See node.Node
 func (node *localNode) GetDbDir() string {
 	return node.dbDir
@@ -241,3 +389,81 @@ func (node *localNode) GetLogsDir() string {
 func (node *localNode) GetConfigFile() string {
 	return node.config.ConfigFile
 }
+
+// See node.Node
+// The receiver isn't named [node] since that would shadow the node package.
+func (n *localNode) GetFlags() node.Flags {
+	flags := make(node.Flags, len(n.flags))
+	for k, v := range n.flags {
+		flags[k] = v
+	}
+	return flags
+}
+
+// See node.Node
+// The receiver isn't named [node] since that would shadow the node package.
+func (n *localNode) GetRenderedConfig() node.RenderedConfig {
+	return node.RenderedConfig{
+		BinaryPath: n.config.BinaryPath,
+		Args:       append([]string(nil), n.args...),
+		ConfigFile: n.config.ConfigFile,
+		Flags:      n.GetFlags(),
+	}
+}
+
+// See node.Node
+func (node *localNode) GetRuntimeConfig(ctx context.Context) (map[string]interface{}, error) {
+	configIntf, err := node.client.AdminAPI().GetConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get config of node %q: %w", node.name, err)
+	}
+	config, ok := configIntf.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected config of node %q to be an object but got %T", node.name, configIntf)
+	}
+	return config, nil
+}
+
+// Returns the flags given by command line flags [flags] and
+// config file [configFile]. [flags] take precedence.
+func parseNodeFlags(flags []string, configFile string) (node.Flags, error) {
+	nodeFlags := node.Flags{}
+	if len(configFile) != 0 {
+		var configMap map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(configFile))
+		decoder.UseNumber()
+		if err := decoder.Decode(&configMap); err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal config file: %w", err)
+		}
+		for k, v := range configMap {
+			nodeFlags[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	for _, flag := range flags {
+		kv := strings.SplitN(strings.TrimLeft(flag, "-"), "=", 2)
+		if len(kv) == 1 {
+			// Boolean flag given without a value
+			nodeFlags[kv[0]] = "true"
+			continue
+		}
+		nodeFlags[kv[0]] = kv[1]
+	}
+	return nodeFlags, nil
+}
+
+// Returns the IP the API clients of a node whose
+// API listens on [bindAddr] connect to
+func apiIP(bindAddr string) string {
+	if bindAddr == "" {
+		return defaultNodeIP
+	}
+	ip := node.ParseIP(bindAddr)
+	switch {
+	case !ip.IsUnspecified():
+		return ip.String()
+	case ip.To4() != nil:
+		return defaultNodeIP
+	default:
+		return net.IPv6loopback.String()
+	}
+}
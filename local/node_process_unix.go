@@ -0,0 +1,88 @@
+//go:build !windows
+// +build !windows
+
+package local
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+)
+
+// Nodes get the signals sent to the runner's
+// process group, e.g. on Ctrl-C in a terminal
+func setProcessGroup(*exec.Cmd) {}
+
+// Returns the command, if any, [config]'s binary is run through to set
+// its niceness and CPU affinity. nice and taskset exec the binary, so
+// the node keeps their PID, and all its threads are constrained.
+func priorityWrapper(config node.Config) ([]string, error) {
+	var wrapper []string
+	if config.Nice != 0 {
+		nicePath, err := exec.LookPath("nice")
+		if err != nil {
+			return nil, fmt.Errorf("can't set niceness, as nice wasn't found: %w", err)
+		}
+		wrapper = append(wrapper, nicePath, "-n", strconv.Itoa(config.Nice))
+	}
+	if len(config.CPUAffinity) > 0 {
+		if runtime.GOOS != "linux" {
+			return nil, errors.New("CPU affinity is only supported on linux")
+		}
+		tasksetPath, err := exec.LookPath("taskset")
+		if err != nil {
+			return nil, fmt.Errorf("can't set CPU affinity, as taskset (from util-linux) wasn't found: %w", err)
+		}
+		cpus := make([]string, len(config.CPUAffinity))
+		for i, cpu := range config.CPUAffinity {
+			cpus[i] = strconv.Itoa(cpu)
+		}
+		wrapper = append(wrapper, tasksetPath, "--cpu-list", strings.Join(cpus, ","))
+	}
+	return wrapper, nil
+}
+
+// Returns true if node.Config.Nice can be set
+func niceSupported() bool {
+	_, err := exec.LookPath("nice")
+	return err == nil
+}
+
+// Returns true if node.Config.CPUAffinity can be set
+func cpuAffinitySupported() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	_, err := exec.LookPath("taskset")
+	return err == nil
+}
+
+func (p *nodeProcessImpl) Stop() error {
+	return p.cmd.Process.Signal(syscall.SIGTERM)
+}
+
+func (p *nodeProcessImpl) Pause() error {
+	return p.cmd.Process.Signal(syscall.SIGSTOP)
+}
+
+func (p *nodeProcessImpl) Resume() error {
+	return p.cmd.Process.Signal(syscall.SIGCONT)
+}
+
+// Returns true if process [pid] exists
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// Signal 0 only checks that the process can be signaled
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,65 @@
+package local
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"golang.org/x/sys/windows"
+)
+
+var errPauseUnsupported = errors.New("pausing nodes isn't supported on windows")
+
+// Starts the node in its own process group, so that
+// Stop can send a Ctrl-Break event to it alone
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: windows.CREATE_NEW_PROCESS_GROUP}
+}
+
+// Niceness and CPU affinity aren't supported on windows
+func priorityWrapper(config node.Config) ([]string, error) {
+	if config.Nice != 0 || len(config.CPUAffinity) > 0 {
+		return nil, errors.New("niceness and CPU affinity aren't supported on windows")
+	}
+	return nil, nil
+}
+
+func niceSupported() bool {
+	return false
+}
+
+func cpuAffinitySupported() bool {
+	return false
+}
+
+// Windows has no SIGTERM. The node gets a Ctrl-Break event instead,
+// which Go programs receive as os.Interrupt and avalanchego handles
+// by shutting down gracefully. The process is killed if the
+// event can't be sent, e.g. if the runner has no console.
+func (p *nodeProcessImpl) Stop() error {
+	if err := windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(p.cmd.Process.Pid)); err != nil {
+		return p.cmd.Process.Kill()
+	}
+	return nil
+}
+
+func (*nodeProcessImpl) Pause() error {
+	return errPauseUnsupported
+}
+
+func (*nodeProcessImpl) Resume() error {
+	return errPauseUnsupported
+}
+
+// Returns true if process [pid] exists
+func processAlive(pid int) bool {
+	// Only succeeds if the process exists
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
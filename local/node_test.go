@@ -6,22 +6,40 @@ import (
 	"crypto"
 	"crypto/tls"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"io"
 	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
+	apimocks "github.com/ava-labs/avalanche-network-runner/api/mocks"
+	"github.com/ava-labs/avalanche-network-runner/local/mocks"
+	"github.com/ava-labs/avalanche-network-runner/network"
 	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/api/health"
+	healthmocks "github.com/ava-labs/avalanchego/api/health/mocks"
+	"github.com/ava-labs/avalanchego/api/info"
+	"github.com/ava-labs/avalanchego/config"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/message"
 	"github.com/ava-labs/avalanchego/network/peer"
 	"github.com/ava-labs/avalanchego/staking"
 	"github.com/ava-labs/avalanchego/utils/constants"
 	"github.com/ava-labs/avalanchego/utils/ips"
+	"github.com/ava-labs/avalanchego/utils/rpc"
 	"github.com/ava-labs/avalanchego/utils/wrappers"
 	"github.com/ava-labs/avalanchego/version"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/platformvm/status"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func upgradeConn(myTLSCert *tls.Certificate, conn net.Conn) (ids.NodeID, net.Conn, error) {
@@ -242,3 +260,178 @@ func TestAttachPeer(t *testing.T) {
 	// also ensures that [assert] calls will be reflected in test results if failed
 	assert.NoError(<-errCh)
 }
+
+// platformvm.Client that only implements the methods used to get a node's status
+type statusTestPChainClient struct {
+	platformvm.Client
+	blockchains []platformvm.APIBlockchain
+	statuses    map[string]status.BlockchainStatus
+}
+
+func (c *statusTestPChainClient) GetBlockchains(context.Context, ...rpc.Option) ([]platformvm.APIBlockchain, error) {
+	return c.blockchains, nil
+}
+
+func (c *statusTestPChainClient) GetBlockchainStatus(_ context.Context, blockchainID string, _ ...rpc.Option) (status.BlockchainStatus, error) {
+	return c.statuses[blockchainID], nil
+}
+
+// info.Client whose GetNodeVersion always fails
+type statusTestInfoClient struct {
+	info.Client
+	peers []info.Peer
+}
+
+func (c *statusTestInfoClient) Peers(context.Context, ...rpc.Option) ([]info.Peer, error) {
+	return c.peers, nil
+}
+
+func (*statusTestInfoClient) GetNodeVersion(context.Context, ...rpc.Option) (*info.GetNodeVersionReply, error) {
+	return nil, errors.New("unreachable")
+}
+
+func TestNodeStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	healthClient := &healthmocks.Client{}
+	healthClient.On("Health", mock.Anything).Return(&health.APIHealthReply{Healthy: true}, nil)
+	connectedID, missingID := ids.GenerateTestNodeID(), ids.GenerateTestNodeID()
+	infoClient := &statusTestInfoClient{
+		peers: []info.Peer{{Info: peer.Info{ID: connectedID}}, {Info: peer.Info{ID: ids.GenerateTestNodeID()}}},
+	}
+	validated := platformvm.APIBlockchain{ID: ids.GenerateTestID(), Name: "validated", SubnetID: ids.GenerateTestID()}
+	notRun := platformvm.APIBlockchain{ID: ids.GenerateTestID(), Name: "not run", SubnetID: ids.GenerateTestID()}
+	pChainClient := &statusTestPChainClient{
+		blockchains: []platformvm.APIBlockchain{validated, notRun},
+		statuses: map[string]status.BlockchainStatus{
+			validated.ID.String(): status.Validating,
+			notRun.ID.String():    status.Created,
+		},
+	}
+	client := &apimocks.Client{}
+	client.On("HealthAPI").Return(healthClient)
+	client.On("InfoAPI").Return(infoClient)
+	client.On("PChainAPI").Return(pChainClient)
+
+	node := &localNode{
+		name:      "node",
+		nodeID:    ids.GenerateTestNodeID(),
+		client:    client,
+		process:   &mocks.NodeProcess{},
+		apiPort:   9650,
+		p2pPort:   9651,
+		flags:     node.Flags{config.WhitelistedSubnetsKey: validated.SubnetID.String()},
+		startTime: time.Now().Add(-time.Minute),
+	}
+	nodeStatus := node.status(context.Background(), map[ids.NodeID]string{
+		node.nodeID: node.name,
+		connectedID: "connected",
+		missingID:   "missing",
+	})
+	assert.Equal("http://127.0.0.1:9650", nodeStatus.URI)
+	assert.True(nodeStatus.Healthy)
+	assert.Greater(nodeStatus.HealthLatency, time.Duration(0))
+	assert.GreaterOrEqual(nodeStatus.Uptime, time.Minute)
+	assert.Empty(nodeStatus.Version)
+	assert.Len(nodeStatus.Errors, 1)
+	assert.Equal([]string{validated.SubnetID.String()}, nodeStatus.WhitelistedSubnets)
+	assert.Equal(2, nodeStatus.NumPeers)
+	assert.Equal([]string{"missing"}, nodeStatus.MissingPeers)
+	assert.Equal([]network.BlockchainStatus{{
+		ID:       validated.ID,
+		Name:     validated.Name,
+		SubnetID: validated.SubnetID,
+		Status:   status.Validating.String(),
+	}}, nodeStatus.Blockchains)
+
+	var buf bytes.Buffer
+	assert.NoError(network.Status{Nodes: []network.NodeStatus{nodeStatus}}.WriteJSON(&buf))
+	var decoded network.Status
+	assert.NoError(json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(nodeStatus.Blockchains, decoded.Nodes[0].Blockchains)
+}
+
+func TestGetResourceUsage(t *testing.T) {
+	assert := assert.New(t)
+	dbDir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(dbDir, "db"), make([]byte, 1000), 0o600))
+	logsDir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(logsDir, "main.log"), make([]byte, 100), 0o600))
+
+	// A node whose process isn't running
+	node := &localNode{name: "node", process: &mocks.NodeProcess{}, dbDir: dbDir, logsDir: logsDir}
+	_, err := node.GetResourceUsage()
+	assert.ErrorIs(err, errNoProcess)
+
+	if runtime.GOOS != "linux" {
+		t.Skip("resource usage is only supported on linux")
+	}
+	// A node whose process is a running command
+	cmd := exec.Command("sleep", "10")
+	assert.NoError(cmd.Start())
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	node.process = &nodeProcessImpl{cmd: cmd}
+	node.startTime = time.Now()
+	usage, err := node.GetResourceUsage()
+	assert.NoError(err)
+	assert.Greater(usage.MemoryBytes, uint64(0))
+	assert.EqualValues(1100, usage.DiskBytes)
+	assert.GreaterOrEqual(usage.CPUPercent, 0.0)
+}
+
+func TestWriteResourceSamples(t *testing.T) {
+	assert := assert.New(t)
+	samples := []resourceSample{
+		{Node: "node0", ResourceUsage: node.ResourceUsage{Time: time.Unix(0, 0), CPUTime: 1500 * time.Millisecond, CPUPercent: 12.5, MemoryBytes: 2, DiskBytes: 3}},
+		{Node: "node1", ResourceUsage: node.ResourceUsage{Time: time.Unix(1, 0), MemoryBytes: 4}},
+	}
+	// Samples are written as they're appended
+	csvPath := filepath.Join(t.TempDir(), "usage.csv")
+	w, err := newResourceSampleWriter(csvPath)
+	assert.NoError(err)
+	assert.NoError(w.write(samples[:1]))
+	contents, err := os.ReadFile(csvPath)
+	assert.NoError(err)
+	assert.Equal(
+		"time,node,cpu_time_seconds,cpu_percent,memory_bytes,disk_bytes\n"+
+			"1970-01-01T00:00:00Z,node0,1.500,12.50,2,3\n",
+		string(contents),
+	)
+	assert.NoError(w.write(samples[1:]))
+	assert.NoError(w.close())
+	contents, err = os.ReadFile(csvPath)
+	assert.NoError(err)
+	assert.Equal(
+		"time,node,cpu_time_seconds,cpu_percent,memory_bytes,disk_bytes\n"+
+			"1970-01-01T00:00:00Z,node0,1.500,12.50,2,3\n"+
+			"1970-01-01T00:00:01Z,node1,0.000,0.00,4,0\n",
+		string(contents),
+	)
+
+	jsonPath := filepath.Join(t.TempDir(), "usage.json")
+	w, err = newResourceSampleWriter(jsonPath)
+	assert.NoError(err)
+	assert.NoError(w.write(samples[:1]))
+	assert.NoError(w.write(samples[1:]))
+	assert.NoError(w.close())
+	contents, err = os.ReadFile(jsonPath)
+	assert.NoError(err)
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	assert.Len(lines, 2)
+	var gotSample resourceSample
+	assert.NoError(json.Unmarshal([]byte(lines[0]), &gotSample))
+	assert.Equal("node0", gotSample.Node)
+	assert.Equal(1500*time.Millisecond, gotSample.CPUTime)
+}
+
+func TestNodeGetURI(t *testing.T) {
+	assert := assert.New(t)
+	node := &localNode{apiPort: 9650}
+	assert.Equal("http://127.0.0.1:9650", node.GetURI())
+	node.config.BindAddr = "::1"
+	node.config.APITLSCert = "cert"
+	assert.Equal("https://[::1]:9650", node.GetURI())
+}
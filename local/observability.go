@@ -0,0 +1,95 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/pkg/observability"
+)
+
+// Name of the dir in the network's root dir
+// the observability processes' files go in
+const observabilitySubDir = "observability"
+
+var errNoObservability = errors.New("network has no observability")
+
+// See network.Network
+func (ln *localNetwork) GetObservabilityURLs() (observability.URLs, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	if ln.stopCalled() {
+		return observability.URLs{}, network.ErrStopped
+	}
+	if ln.observability == nil {
+		return observability.URLs{}, errNoObservability
+	}
+	return ln.observability.URLs(), nil
+}
+
+// Starts the observability processes, which scrape the
+// nodes added from then on.
+// Assumes [ln.lock] is held, or not needed.
+func (ln *localNetwork) startObservability(config observability.Config) error {
+	var err error
+	// Ports not given are picked like the nodes' ports
+	config.PrometheusPort, err = getObservabilityPort(ln.rng, config.PrometheusPort)
+	if err != nil {
+		return fmt.Errorf("couldn't get prometheus port: %w", err)
+	}
+	if config.Grafana {
+		config.GrafanaPort, err = getObservabilityPort(ln.rng, config.GrafanaPort)
+		if err != nil {
+			return fmt.Errorf("couldn't get grafana port: %w", err)
+		}
+	}
+	stack, err := observability.Start(ln.log, filepath.Join(ln.rootDir, observabilitySubDir), config)
+	if err != nil {
+		return err
+	}
+	ln.observability = stack
+	urls := stack.URLs()
+	ln.log.Info("prometheus listening at %s", urls.Prometheus)
+	if urls.Grafana != "" {
+		ln.log.Info("grafana listening at %s", urls.Grafana)
+	}
+	return nil
+}
+
+// Returns [port] if it's non-zero and free, or else a free port
+func getObservabilityPort(rng *rand.Rand, port uint16) (uint16, error) {
+	if port == 0 {
+		return getFreePort(rng)
+	}
+	if err := bindPort(port); err != nil {
+		return 0, &network.ErrPortInUse{Port: port}
+	}
+	return port, nil
+}
+
+// Sets the nodes scraped by the observability processes, if
+// any, to the network's nodes.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) updateObservabilityTargets() {
+	if ln.observability == nil {
+		return
+	}
+	targets := make([]observability.Target, 0, len(ln.nodes))
+	for name, node := range ln.nodes {
+		targets = append(targets, observability.Target{
+			Node: name,
+			Addr: net.JoinHostPort(node.GetURL(), strconv.Itoa(int(node.GetAPIPort()))),
+		})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Node < targets[j].Node
+	})
+	if err := ln.observability.SetTargets(targets); err != nil {
+		ln.log.Warn("couldn't update observability targets: %s", err)
+	}
+}
@@ -0,0 +1,76 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	dircopy "github.com/otiai10/copy"
+)
+
+const (
+	// Under a node's dir, the build dir given to the
+	// node when its plugin dir is assembled by the runner
+	buildSubdir    = "build"
+	pluginsDirName = "plugins"
+)
+
+// Creates a build dir under [nodeDir] whose plugin dir has the plugins
+// in [srcBuildDir]'s plugin dir, if any, and [customVMs] named by their
+// VM IDs. Plugins are symlinked, or copied if they can't be.
+// Returns the path of the build dir.
+func assemblePluginDir(nodeDir string, srcBuildDir string, customVMs []network.CustomVM) (string, error) {
+	buildDir := filepath.Join(nodeDir, buildSubdir)
+	// Remove the plugins of a previous run of the node
+	if err := os.RemoveAll(buildDir); err != nil {
+		return "", err
+	}
+	pluginDir := filepath.Join(buildDir, pluginsDirName)
+	if err := os.MkdirAll(pluginDir, 0o750); err != nil {
+		return "", err
+	}
+
+	// Plugin file name --> path of the plugin
+	plugins := map[string]string{}
+	if srcBuildDir != "" {
+		srcPluginDir := filepath.Join(srcBuildDir, pluginsDirName)
+		entries, err := os.ReadDir(srcPluginDir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("couldn't read plugin dir %q: %w", srcPluginDir, err)
+		}
+		for _, entry := range entries {
+			plugins[entry.Name()] = filepath.Join(srcPluginDir, entry.Name())
+		}
+	}
+	// Custom VMs replace plugins of the same VM
+	for _, vm := range customVMs {
+		vmID, err := vm.VMID()
+		if err != nil {
+			return "", err
+		}
+		name := vmID.String()
+		if runtime.GOOS == "windows" {
+			// Windows needs the extension to run the binary.
+			// avalanchego strips it from the VM ID.
+			name += filepath.Ext(vm.BinaryPath)
+		}
+		plugins[name] = vm.BinaryPath
+	}
+
+	for name, path := range plugins {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		dst := filepath.Join(pluginDir, name)
+		if err := os.Symlink(absPath, dst); err != nil {
+			if err := dircopy.Copy(absPath, dst); err != nil {
+				return "", fmt.Errorf("couldn't install plugin %q: %w", path, err)
+			}
+		}
+	}
+	return buildDir, nil
+}
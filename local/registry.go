@@ -0,0 +1,280 @@
+package local
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+// RegistryDir is the run registry, where each network whose nodes run
+// as processes of this machine records the runner process that created
+// it and the processes of its nodes, so that the nodes left running by a
+// runner that crashed can be found and killed (see CleanupStaleNetworks).
+// Defaults to a dir next to the networks' default root dirs.
+var RegistryDir = filepath.Join(os.TempDir(), rootDirPrefix+"registry")
+
+const (
+	// Under a network's registry dir, the file of the network.
+	// The other files are those of its nodes.
+	registryNetworkFileName = "network.json"
+	registryFileExt         = ".json"
+)
+
+// registeredNetwork is the registry file of a network
+type registeredNetwork struct {
+	UUID string `json:"uuid"`
+	// Process of the runner that created the network
+	RunnerPID int       `json:"runnerPID"`
+	RootDir   string    `json:"rootDir"`
+	Created   time.Time `json:"created"`
+}
+
+// registeredNode is the registry file of a node process
+type registeredNode struct {
+	Name       string `json:"name"`
+	PID        int    `json:"pid"`
+	BinaryPath string `json:"binaryPath"`
+	// Start time of the process (see processStartTime), which tells it
+	// apart from a later process reusing its ID. 0 if unknown.
+	StartTime uint64 `json:"startTime"`
+}
+
+// StaleNetwork is a network whose runner process is no
+// longer running, as found by CleanupStaleNetworks
+type StaleNetwork struct {
+	UUID      string `json:"uuid"`
+	RunnerPID int    `json:"runnerPID"`
+	RootDir   string `json:"rootDir"`
+	// Names of the nodes whose processes were killed
+	KilledNodes []string `json:"killedNodes"`
+	// Names of the nodes whose process IDs are used by a process that
+	// wasn't killed, as it couldn't be verified to be the node's
+	UnverifiedNodes []string `json:"unverifiedNodes"`
+}
+
+// Returns a random version 4 UUID
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// Returns the registry dir of the network
+func (ln *localNetwork) registryDir() string {
+	return filepath.Join(RegistryDir, ln.uuid)
+}
+
+// Registers the process of node [nodeName], started from [binaryPath],
+// registering the network first if it isn't yet. Returns the path of
+// the node's registry file, or empty if the process ID isn't known.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) registerNode(nodeName string, binaryPath string, process NodeProcess) (string, error) {
+	pid := processPID(process)
+	if pid == 0 {
+		return "", nil
+	}
+	if !ln.registered {
+		if err := writeRegistryFile(ln.registryDir(), registryNetworkFileName, registeredNetwork{
+			UUID:      ln.uuid,
+			RunnerPID: os.Getpid(),
+			RootDir:   ln.rootDir,
+			Created:   time.Now(),
+		}); err != nil {
+			return "", err
+		}
+		ln.registered = true
+	}
+	// Left unknown if it can't be read,
+	// so that the process is never killed
+	startTime, err := processStartTime(pid)
+	if err != nil {
+		ln.log.Debug("couldn't get start time of node %q (pid %d): %s", nodeName, pid, err)
+	}
+	fileName := fmt.Sprintf("%s-%d%s", nodeName, pid, registryFileExt)
+	if err := writeRegistryFile(ln.registryDir(), fileName, registeredNode{
+		Name:       nodeName,
+		PID:        pid,
+		BinaryPath: resolveBinaryPath(binaryPath),
+		StartTime:  startTime,
+	}); err != nil {
+		return "", err
+	}
+	return filepath.Join(ln.registryDir(), fileName), nil
+}
+
+// Returns [binaryPath] absolute and with its symlinks evaluated,
+// as the executable of a process is resolved on linux
+func resolveBinaryPath(binaryPath string) string {
+	if absPath, err := filepath.Abs(binaryPath); err == nil {
+		binaryPath = absPath
+	}
+	if resolvedPath, err := filepath.EvalSymlinks(binaryPath); err == nil {
+		binaryPath = resolvedPath
+	}
+	return binaryPath
+}
+
+// Removes the network from the registry, unless the
+// processes of some of its nodes are still registered.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) unregisterNetwork() {
+	if !ln.registered {
+		return
+	}
+	entries, err := os.ReadDir(ln.registryDir())
+	if err != nil {
+		ln.log.Warn("couldn't read registry of network %s: %s", ln.uuid, err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.Name() != registryNetworkFileName {
+			ln.log.Warn("keeping network %s registered, as node file %q is left", ln.uuid, entry.Name())
+			return
+		}
+	}
+	if err := os.RemoveAll(ln.registryDir()); err != nil {
+		ln.log.Warn("couldn't unregister network %s: %s", ln.uuid, err)
+		return
+	}
+	ln.registered = false
+}
+
+// Writes [v] as JSON to file [fileName] of [dir], creating [dir] if needed
+func writeRegistryFile(dir string, fileName string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, fileName), b, 0o644)
+}
+
+// CleanupStaleNetworks kills the node processes of the networks in
+// [RegistryDir] whose runner process is no longer running, and removes
+// these networks from the registry. The root dirs of the networks are
+// kept, so that their logs can be inspected.
+// A process is only killed if it's verified to be the node's, rather
+// than a process that reused its ID, which is only possible on linux.
+// Otherwise it's reported in StaleNetwork.UnverifiedNodes.
+// Returns the networks cleaned up, sorted by UUID.
+func CleanupStaleNetworks(log logging.Logger) ([]StaleNetwork, error) {
+	entries, err := os.ReadDir(RegistryDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var (
+		staleNetworks []StaleNetwork
+		errs          []string
+	)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		staleNetwork, stale, err := cleanupStaleNetwork(log, filepath.Join(RegistryDir, entry.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("network %s: %s", entry.Name(), err))
+		}
+		if stale {
+			staleNetworks = append(staleNetworks, staleNetwork)
+		}
+	}
+	sort.Slice(staleNetworks, func(i, j int) bool {
+		return staleNetworks[i].UUID < staleNetworks[j].UUID
+	})
+	if len(errs) > 0 {
+		return staleNetworks, errors.New(strings.Join(errs, "; "))
+	}
+	return staleNetworks, nil
+}
+
+// Cleans up the network registered in [dir] if its runner isn't running.
+// Returns the network, and false if it isn't stale.
+func cleanupStaleNetwork(log logging.Logger, dir string) (StaleNetwork, bool, error) {
+	var rn registeredNetwork
+	if err := readRegistryFile(filepath.Join(dir, registryNetworkFileName), &rn); err != nil {
+		return StaleNetwork{}, false, err
+	}
+	if processAlive(rn.RunnerPID) {
+		return StaleNetwork{}, false, nil
+	}
+	staleNetwork := StaleNetwork{
+		UUID:      rn.UUID,
+		RunnerPID: rn.RunnerPID,
+		RootDir:   rn.RootDir,
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return staleNetwork, true, err
+	}
+	var errs []string
+	for _, entry := range entries {
+		if entry.Name() == registryNetworkFileName || !strings.HasSuffix(entry.Name(), registryFileExt) {
+			continue
+		}
+		var rnode registeredNode
+		if err := readRegistryFile(filepath.Join(dir, entry.Name()), &rnode); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if !processAlive(rnode.PID) {
+			continue
+		}
+		// The process ID may have been reused since the node exited,
+		// so the process is only killed if it started when the node did
+		startTime, err := processStartTime(rnode.PID)
+		if err != nil || rnode.StartTime == 0 {
+			log.Warn("not killing process %d, as it can't be verified to be node %q of network %s", rnode.PID, rnode.Name, rn.UUID)
+			staleNetwork.UnverifiedNodes = append(staleNetwork.UnverifiedNodes, rnode.Name)
+			continue
+		}
+		if startTime != rnode.StartTime {
+			continue
+		}
+		process, err := os.FindProcess(rnode.PID)
+		if err == nil {
+			err = process.Kill()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("couldn't kill node %q (pid %d): %s", rnode.Name, rnode.PID, err))
+			continue
+		}
+		log.Info("killed node %q (pid %d) of network %s left running by runner %d", rnode.Name, rnode.PID, rn.UUID, rn.RunnerPID)
+		staleNetwork.KilledNodes = append(staleNetwork.KilledNodes, rnode.Name)
+	}
+	sort.Strings(staleNetwork.KilledNodes)
+	sort.Strings(staleNetwork.UnverifiedNodes)
+	if len(errs) > 0 {
+		// Kept registered, so that the cleanup can be retried
+		return staleNetwork, true, errors.New(strings.Join(errs, "; "))
+	}
+	return staleNetwork, true, os.RemoveAll(dir)
+}
+
+// Reads the JSON of file [path] into [v]
+func readRegistryFile(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("invalid registry file %q: %w", path, err)
+	}
+	return nil
+}
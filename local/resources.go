@@ -0,0 +1,203 @@
+package local
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+)
+
+var errNoProcess = errors.New("node has no running process")
+
+// Node name and resource usage of a node at some point in time
+type resourceSample struct {
+	Node string `json:"node"`
+	node.ResourceUsage
+}
+
+// See node.Node
+func (n *localNode) GetResourceUsage() (node.ResourceUsage, error) {
+	usage, err := n.measureResourceUsage()
+	if err != nil {
+		return node.ResourceUsage{}, err
+	}
+	setCPUPercent(&usage, n.startTime, 0)
+	return usage, nil
+}
+
+// Returns the resource usage of [n], without its CPUPercent
+func (n *localNode) measureResourceUsage() (node.ResourceUsage, error) {
+	pid := processPID(n.process)
+	if pid == 0 {
+		return node.ResourceUsage{}, errNoProcess
+	}
+	now := time.Now()
+	cpuTime, memoryBytes, err := processUsage(pid)
+	if err != nil {
+		return node.ResourceUsage{}, fmt.Errorf("couldn't get resource usage of node %q: %w", n.name, err)
+	}
+	return node.ResourceUsage{
+		Time:        now,
+		CPUTime:     cpuTime,
+		MemoryBytes: memoryBytes,
+		DiskBytes:   dirSize(n.dbDir) + dirSize(n.logsDir),
+	}, nil
+}
+
+// Sets the CPUPercent of [usage] to the CPU used since
+// [prevTime], when the CPU time used was [prevCPUTime]
+func setCPUPercent(usage *node.ResourceUsage, prevTime time.Time, prevCPUTime time.Duration) {
+	if elapsed := usage.Time.Sub(prevTime); elapsed > 0 {
+		usage.CPUPercent = 100 * float64(usage.CPUTime-prevCPUTime) / float64(elapsed)
+	}
+}
+
+// Returns the total size of the files under [dir],
+// skipping the ones that can't be read
+func dirSize(dir string) uint64 {
+	var size uint64
+	_ = filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Files may be removed while walking, e.g. by compaction
+			return nil
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += uint64(info.Size())
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// Samples the resource usage of the network's nodes every [interval]
+// until the network is stopped, and appends the samples to [w] as
+// they're taken. The CPUPercent of a sample is the CPU the node used
+// since its previous sample. Closes [w], and then [done].
+func (ln *localNetwork) sampleResourceUsage(interval time.Duration, w *resourceSampleWriter, done chan struct{}) {
+	defer close(done)
+	defer func() {
+		if err := w.close(); err != nil {
+			ln.log.Warn("couldn't write resource usage to %s: %s", w.path, err)
+		}
+	}()
+	// Node --> its previous sample. Keyed by node rather than by
+	// name, so that a restarted node's CPU is measured from its start.
+	prevUsages := map[*localNode]node.ResourceUsage{}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ln.onStopCh:
+			return
+		case <-ticker.C:
+		}
+		// Sample without holding the lock, as
+		// measuring disk usage may be slow
+		ln.lock.RLock()
+		nodes := make([]*localNode, 0, len(ln.nodes))
+		for _, node := range ln.nodes {
+			nodes = append(nodes, node)
+		}
+		ln.lock.RUnlock()
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].name < nodes[j].name
+		})
+		samples := make([]resourceSample, 0, len(nodes))
+		sampled := make(map[*localNode]node.ResourceUsage, len(nodes))
+		for _, n := range nodes {
+			usage, err := n.measureResourceUsage()
+			if err != nil {
+				ln.log.Debug("couldn't sample resource usage: %s", err)
+				continue
+			}
+			if prev, ok := prevUsages[n]; ok {
+				setCPUPercent(&usage, prev.Time, prev.CPUTime)
+			} else {
+				setCPUPercent(&usage, n.startTime, 0)
+			}
+			sampled[n] = usage
+			samples = append(samples, resourceSample{Node: n.name, ResourceUsage: usage})
+		}
+		// Forgets the nodes that were removed
+		prevUsages = sampled
+		if err := w.write(samples); err != nil {
+			ln.log.Warn("couldn't write resource usage to %s: %s", w.path, err)
+		}
+	}
+}
+
+// Appends resource samples to a file, as CSV if its
+// path ends with ".csv", or else as JSON lines
+type resourceSampleWriter struct {
+	path string
+	f    *os.File
+	// Nil if writing JSON lines
+	csv *csv.Writer
+}
+
+// Creates the file at [path], truncating it if it exists
+func newResourceSampleWriter(path string) (*resourceSampleWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &resourceSampleWriter{path: path, f: f}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		w.csv = csv.NewWriter(f)
+		if err := w.writeCSV([]string{"time", "node", "cpu_time_seconds", "cpu_percent", "memory_bytes", "disk_bytes"}); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Appends [samples] to the file
+func (w *resourceSampleWriter) write(samples []resourceSample) error {
+	for _, sample := range samples {
+		if w.csv == nil {
+			sampleJSON, err := json.Marshal(sample)
+			if err != nil {
+				return err
+			}
+			if _, err := w.f.Write(append(sampleJSON, '\n')); err != nil {
+				return err
+			}
+			continue
+		}
+		err := w.writeCSV([]string{
+			sample.Time.UTC().Format(time.RFC3339Nano),
+			sample.Node,
+			strconv.FormatFloat(sample.CPUTime.Seconds(), 'f', 3, 64),
+			strconv.FormatFloat(sample.CPUPercent, 'f', 2, 64),
+			strconv.FormatUint(sample.MemoryBytes, 10),
+			strconv.FormatUint(sample.DiskBytes, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes and flushes the CSV [record]
+func (w *resourceSampleWriter) writeCSV(record []string) error {
+	_ = w.csv.Write(record)
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *resourceSampleWriter) close() error {
+	return w.f.Close()
+}
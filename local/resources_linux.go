@@ -0,0 +1,85 @@
+package local
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Clock ticks per second of the CPU times in /proc/<pid>/stat.
+// 100 on all the architectures Go supports on Linux.
+const clockTicksPerSec = 100
+
+// Returns the CPU time used by process [pid], and its resident memory
+func processUsage(pid int) (time.Duration, uint64, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	// The command name, in parentheses, may have spaces, so
+	// the fields are split after its closing parenthesis
+	i := strings.LastIndexByte(string(stat), ')')
+	if i < 0 {
+		return 0, 0, fmt.Errorf("unexpected /proc/%d/stat format", pid)
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	// utime and stime are the 14th and 15th fields,
+	// and [fields] starts at the 3rd one
+	if len(fields) < 13 {
+		return 0, 0, fmt.Errorf("unexpected /proc/%d/stat format", pid)
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	cpuTime := time.Duration(utime+stime) * time.Second / clockTicksPerSec
+
+	statm, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	statmFields := strings.Fields(string(statm))
+	if len(statmFields) < 2 {
+		return 0, 0, fmt.Errorf("unexpected /proc/%d/statm format", pid)
+	}
+	residentPages, err := strconv.ParseUint(statmFields[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return cpuTime, residentPages * uint64(os.Getpagesize()), nil
+}
+
+// Returns the start time of process [pid], in clock ticks since boot,
+// which tells it apart from the processes that reuse its ID later
+func processStartTime(pid int) (uint64, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	i := strings.LastIndexByte(string(stat), ')')
+	if i < 0 {
+		return 0, fmt.Errorf("unexpected /proc/%d/stat format", pid)
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	// starttime is the 22nd field, and [fields] starts at the 3rd one
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("unexpected /proc/%d/stat format", pid)
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// Returns true if process [pid] runs the binary at [binaryPath]
+func processRuns(pid int, binaryPath string) bool {
+	exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil {
+		// Not readable if the process is another user's
+		return true
+	}
+	return exe == binaryPath
+}
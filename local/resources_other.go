@@ -0,0 +1,26 @@
+//go:build !linux
+// +build !linux
+
+package local
+
+import (
+	"errors"
+	"time"
+)
+
+// Returns the CPU time used by process [pid], and its resident memory
+func processUsage(int) (time.Duration, uint64, error) {
+	return 0, 0, errors.New("resource usage is only supported on linux")
+}
+
+// Returns the start time of process [pid], which
+// can't be read on this platform
+func processStartTime(int) (uint64, error) {
+	return 0, errors.New("process start times are only supported on linux")
+}
+
+// Returns true if process [pid] may run the binary at [binaryPath],
+// which can't be checked on this platform
+func processRuns(int, string) bool {
+	return true
+}
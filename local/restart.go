@@ -0,0 +1,86 @@
+package local
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+)
+
+// See network.Network
+func (ln *localNetwork) Restart(ctx context.Context, opts network.RestartOptions) error {
+	start := time.Now()
+	err := ln.restart(ctx, opts)
+	ln.history.record(network.OpRestart, "", start, err)
+	return err
+}
+
+func (ln *localNetwork) restart(ctx context.Context, opts network.RestartOptions) error {
+	if err := ln.restartNodes(opts); err != nil {
+		return err
+	}
+	return ln.healthy(ctx)
+}
+
+// Stops every node, and then starts them again with [opts] applied.
+// No node is started before all are stopped, so that restarted nodes
+// don't connect to nodes that weren't restarted yet.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) restartNodes(opts network.RestartOptions) error {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() {
+		return network.ErrStopped
+	}
+
+	nodes := make([]*localNode, 0, len(ln.nodes))
+	for _, node := range ln.nodes {
+		nodes = append(nodes, node)
+	}
+	// Beacons start first, as the other nodes bootstrap from them
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].config.IsBeacon != nodes[j].config.IsBeacon {
+			return nodes[i].config.IsBeacon
+		}
+		return nodes[i].name < nodes[j].name
+	})
+	nodeConfigs := make([]node.Config, len(nodes))
+	prevConfigs := make([]node.Config, len(nodes))
+	for i, n := range nodes {
+		nodeConfigs[i] = restartConfig(n, func(nodeConfig *node.Config) {
+			if opts.BinaryPath != "" {
+				nodeConfig.BinaryPath = opts.BinaryPath
+			}
+			updateFlags(nodeConfig.Flags, opts.Flags)
+		})
+		prevConfigs[i] = restartConfig(n, func(*node.Config) {})
+	}
+
+	ln.log.Info("restarting %d nodes", len(nodes))
+	for _, n := range nodes {
+		if err := ln.removeNode(n.name); err != nil {
+			return err
+		}
+	}
+	// A node that doesn't start with [opts] is started with its previous
+	// config, and the other nodes are still restarted
+	errs := wrappers.Errs{}
+	for i, nodeConfig := range nodeConfigs {
+		errs.Add(ln.addRestartedNode(nodeConfig, prevConfigs[i]))
+	}
+	return errs.Err
+}
+
+// Sets [flags] in [nodeFlags]. A nil value removes the flag.
+func updateFlags(nodeFlags map[string]interface{}, flags map[string]interface{}) {
+	for flagName, flagVal := range flags {
+		if flagVal == nil {
+			delete(nodeFlags, flagName)
+		} else {
+			nodeFlags[flagName] = flagVal
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/config"
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/staking"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/platformvm/validator"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/ava-labs/avalanchego/wallet/subnet/primary/common"
+)
+
+const (
+	// How long after being issued the validators of
+	// rotated node IDs start validating
+	rotatedValidatorStartDelay = 30 * time.Second
+	// Max time to add the validator of a rotated node ID
+	rotatedValidatorTimeout = 2 * time.Minute
+)
+
+// Flags that point a node to a staking key/cert other than its config's
+var stakingIdentityFlags = []string{
+	config.StakingKeyPathKey,
+	config.StakingKeyContentKey,
+	config.StakingCertPathKey,
+	config.StakingCertContentKey,
+}
+
+// See network.Network
+func (ln *localNetwork) RotateStakingKey(ctx context.Context, nodeName string, opts network.RotateStakingKeyOptions) (ids.NodeID, error) {
+	start := time.Now()
+	nodeID, err := ln.rotateStakingKey(ctx, nodeName, opts)
+	ln.history.record(network.OpRotateStakingKey, nodeName, start, err)
+	return nodeID, err
+}
+
+func (ln *localNetwork) rotateStakingKey(ctx context.Context, nodeName string, opts network.RotateStakingKeyOptions) (ids.NodeID, error) {
+	ln.lock.RLock()
+	if ln.stopCalled() {
+		ln.lock.RUnlock()
+		return ids.EmptyNodeID, network.ErrStopped
+	}
+	oldNode, ok := ln.nodes[nodeName]
+	ln.lock.RUnlock()
+	if !ok {
+		return ids.EmptyNodeID, fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+	}
+	oldNodeID := oldNode.nodeID
+
+	// Looked up before the restart, in case the node is the only one
+	var oldValidator *platformvm.ClientPrimaryValidator
+	if opts.AddValidator {
+		validators, err := oldNode.client.PChainAPI().GetCurrentValidators(ctx, constants.PrimaryNetworkID, []ids.NodeID{oldNodeID})
+		if err != nil {
+			return ids.EmptyNodeID, fmt.Errorf("couldn't get validator of node %q: %w", nodeName, err)
+		}
+		if len(validators) == 0 {
+			return ids.EmptyNodeID, fmt.Errorf("node %q (%s) isn't a primary network validator", nodeName, oldNodeID)
+		}
+		oldValidator = &validators[0]
+	}
+
+	stakingCert, stakingKey, err := staking.NewCertAndKeyBytes()
+	if err != nil {
+		return ids.EmptyNodeID, fmt.Errorf("couldn't generate staking cert/key: %w", err)
+	}
+	ln.lock.Lock()
+	if ln.stopCalled() {
+		ln.lock.Unlock()
+		return ids.EmptyNodeID, network.ErrStopped
+	}
+	err = ln.restartNode(nodeName, func(nodeConfig *node.Config) {
+		nodeConfig.StakingCert = string(stakingCert)
+		nodeConfig.StakingKey = string(stakingKey)
+		for _, flagName := range stakingIdentityFlags {
+			delete(nodeConfig.Flags, flagName)
+		}
+	})
+	var nodeID ids.NodeID
+	if err == nil {
+		nodeID = ln.nodes[nodeName].nodeID
+	}
+	ln.lock.Unlock()
+	if err != nil {
+		return ids.EmptyNodeID, err
+	}
+	ln.log.Info("rotated staking key of node %q: node ID %s is now %s", nodeName, oldNodeID, nodeID)
+
+	if oldValidator != nil {
+		if err := ln.addRotatedValidator(ctx, nodeID, oldValidator); err != nil {
+			return nodeID, fmt.Errorf("couldn't add node %q as validator: %w", nodeName, err)
+		}
+	}
+	return nodeID, nil
+}
+
+// Adds [nodeID] as a validator of the primary network, with the
+// stake, end time and delegation fee of [oldValidator]
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) addRotatedValidator(ctx context.Context, nodeID ids.NodeID, oldValidator *platformvm.ClientPrimaryValidator) error {
+	ctx, cancel := context.WithTimeout(ctx, rotatedValidatorTimeout)
+	defer cancel()
+	// The wallet is sent to any node, which must be up
+	if err := ln.healthy(ctx); err != nil {
+		return err
+	}
+	wallet, err := ln.newPWallet(ctx)
+	if err != nil {
+		return err
+	}
+	stake := oldValidator.Weight
+	if oldValidator.StakeAmount != nil {
+		stake = oldValidator.StakeAmount
+	}
+	if stake == nil {
+		return fmt.Errorf("stake of validator %s unknown", oldValidator.NodeID)
+	}
+	txID, err := wallet.IssueAddValidatorTx(
+		&validator.Validator{
+			NodeID: nodeID,
+			Start:  uint64(time.Now().Add(rotatedValidatorStartDelay).Unix()),
+			End:    oldValidator.EndTime,
+			Wght:   *stake,
+		},
+		&secp256k1fx.OutputOwners{
+			Threshold: 1,
+			Addrs:     []ids.ShortID{genesis.EWOQKey.PublicKey().Address()},
+		},
+		// The fee is a percentage, and shares are out of 1,000,000
+		uint32(oldValidator.DelegationFee*10000),
+		common.WithContext(ctx),
+		common.WithPollFrequency(subnetTxPollFreq),
+	)
+	if err != nil {
+		return err
+	}
+	ln.log.Info("added node ID %s as validator in tx %s", nodeID, txID)
+	return nil
+}
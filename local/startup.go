@@ -0,0 +1,186 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"golang.org/x/sync/errgroup"
+)
+
+// Max time for the beacons to bootstrap in a staged startup
+const beaconsBootstrapTimeout = 5 * time.Minute
+
+// Adds the nodes of [nodeConfigs], in which beacons come first, as
+// given by the startup options of [networkConfig]. See network.Config.
+// Nodes are started in batches, whose processes are launched concurrently.
+// Assumes [ln.lock] is held, or not needed.
+func (ln *localNetwork) startNodes(ctx context.Context, nodeConfigs []node.Config, networkConfig network.Config) error {
+	delay := networkConfig.NodeStartDelay
+	parallelism := networkConfig.NodeStartParallelism
+	if parallelism == 0 {
+		parallelism = defaultNodeStartParallelism
+		if delay > 0 {
+			parallelism = 1
+		}
+	}
+	numBeacons := 0
+	for _, nodeConfig := range nodeConfigs {
+		if nodeConfig.IsBeacon {
+			numBeacons++
+		}
+	}
+
+	for start := 0; start < len(nodeConfigs); {
+		if networkConfig.StagedStartup && start == numBeacons && start > 0 {
+			if err := ln.awaitBeaconsBootstrapped(ctx); err != nil {
+				return err
+			}
+		} else if delay > 0 && start > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("couldn't start node %s: %w", nodeConfigs[start].Name, ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+		end := start + parallelism
+		if end > len(nodeConfigs) {
+			end = len(nodeConfigs)
+		}
+		// In a staged startup, the beacons are started apart from the other nodes
+		if networkConfig.StagedStartup && start < numBeacons && end > numBeacons {
+			end = numBeacons
+		}
+		if err := ln.startNodeBatch(nodeConfigs[start:end]); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// Adds the nodes of [nodeConfigs], launching their processes concurrently.
+// The nodes that were launched are added even if others failed. Returns a
+// *network.NodesError with the errors of the nodes that failed.
+// Assumes [ln.lock] is held, or not needed.
+func (ln *localNetwork) startNodeBatch(nodeConfigs []node.Config) error {
+	errs := make(map[string]error)
+	pendingNodes := make([]*pendingNode, 0, len(nodeConfigs))
+	for _, nodeConfig := range nodeConfigs {
+		pending, err := ln.prepareNode(nodeConfig, false)
+		if err != nil {
+			errs[nodeConfig.Name] = err
+			break
+		}
+		// The beacons of the batch are given to the nodes prepared after them
+		if err := ln.addBeacon(pending); err != nil {
+			ln.abortNode(pending)
+			errs[nodeConfig.Name] = err
+			break
+		}
+		pendingNodes = append(pendingNodes, pending)
+	}
+
+	launchErrs := make([]error, len(pendingNodes))
+	wg := sync.WaitGroup{}
+	for i, pending := range pendingNodes {
+		wg.Add(1)
+		go func(i int, pending *pendingNode) {
+			defer wg.Done()
+			launchErrs[i] = ln.launchNode(pending)
+		}(i, pending)
+	}
+	wg.Wait()
+
+	for i, pending := range pendingNodes {
+		if err := launchErrs[i]; err != nil {
+			ln.abortNode(pending)
+			errs[pending.config.Name] = err
+			continue
+		}
+		ln.registerLaunchedNode(pending)
+	}
+	for nodeName, err := range errs {
+		ln.log.Error("couldn't start node %q: %s", nodeName, err)
+	}
+	return network.NewNodesError(errs)
+}
+
+// Removes all the nodes of the network, stopping their processes
+// concurrently. The processes of the nodes not being stopped yet when
+// [ctx] is done are killed. Returns a *network.NodesError with the
+// errors of the nodes that didn't stop, or stopped with an error.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) removeAllNodes(ctx context.Context) error {
+	nodes := make([]*localNode, 0, len(ln.nodes))
+	for nodeName := range ln.nodes {
+		node, err := ln.detachNode(nodeName)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, node)
+	}
+
+	type stopResult struct {
+		exited bool
+		err    error
+	}
+	results := make([]stopResult, len(nodes))
+	sem := make(chan struct{}, nodeStopParallelism)
+	wg := sync.WaitGroup{}
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node *localNode) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				// The node is detached, so it's killed rather than
+				// left running once [ctx] is done
+				exited, err := ln.killNodeProcess(node)
+				results[i] = stopResult{exited: exited, err: err}
+				return
+			}
+			defer func() { <-sem }()
+			exited, err := ln.stopNodeProcess(node)
+			results[i] = stopResult{exited: exited, err: err}
+		}(i, node)
+	}
+	wg.Wait()
+
+	errs := make(map[string]error)
+	for i, node := range nodes {
+		err := results[i].err
+		if results[i].exited {
+			err = ln.finishRemoveNode(node, err)
+		}
+		if err != nil {
+			ln.log.Error("error stopping node %q: %s", node.name, err)
+			errs[node.name] = err
+		}
+	}
+	return network.NewNodesError(errs)
+}
+
+// Waits until the nodes started so far, which are the beacons,
+// bootstrapped the primary network's chains.
+// Assumes [ln.lock] is held, or not needed.
+func (ln *localNetwork) awaitBeaconsBootstrapped(ctx context.Context) error {
+	ln.log.Info("waiting for %d beacons to bootstrap", len(ln.nodes))
+	ctx, cancel := context.WithTimeout(ctx, beaconsBootstrapTimeout)
+	defer cancel()
+	errGr, ctx := errgroup.WithContext(ctx)
+	for _, node := range ln.nodes {
+		node := node
+		errGr.Go(func() error {
+			return awaitNodeBootstrapped(ctx, node, primaryNetworkChains)
+		})
+	}
+	if err := errGr.Wait(); err != nil {
+		return fmt.Errorf("beacons didn't bootstrap: %w", err)
+	}
+	return nil
+}
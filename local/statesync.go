@@ -0,0 +1,105 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+)
+
+const (
+	// Log file of the C-Chain, where its state sync progress is logged
+	cChainLogFile = "C.log"
+	// Logged by coreth when the node starts state syncing
+	stateSyncStartedMarker = "Starting state sync"
+	// Logged by coreth when state sync is done, with its error if any
+	stateSyncCompletedMarker = "stateSync completed"
+)
+
+var (
+	// C-Chain log lines that mark the progress of state sync. State
+	// sync is skipped by coreth if the node isn't far enough behind
+	// the latest summary, and by avalanchego if no summary is found.
+	stateSyncLogRegex = regexp.MustCompile(stateSyncStartedMarker + "|" + stateSyncCompletedMarker + "|skipping state sync|State syncing skipped")
+	// The error logged with the completion of state sync
+	stateSyncErrRegex = regexp.MustCompile(`\berr=("(?:[^"\\]|\\.)*"|\S+)`)
+)
+
+// See network.Network
+func (ln *localNetwork) AwaitStateSync(ctx context.Context, nodeName string) (network.StateSyncReport, error) {
+	start := time.Now()
+	report, err := ln.awaitStateSync(ctx, nodeName)
+	ln.history.record(network.OpAwaitStateSync, nodeName, start, err)
+	return report, err
+}
+
+func (ln *localNetwork) awaitStateSync(ctx context.Context, nodeName string) (network.StateSyncReport, error) {
+	ln.lock.RLock()
+	if ln.stopCalled() {
+		ln.lock.RUnlock()
+		return network.StateSyncReport{}, network.ErrStopped
+	}
+	if _, ok := ln.nodes[nodeName]; !ok {
+		ln.lock.RUnlock()
+		return network.StateSyncReport{}, fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+	}
+	ln.lock.RUnlock()
+
+	// Derive a new context that's cancelled when Stop is called
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-ln.onStopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	report := network.StateSyncReport{Node: nodeName}
+	var syncStart time.Time
+	lines := ln.AggregatedLogs(ctx, network.LogFilter{
+		Nodes:  []string{nodeName},
+		Regex:  stateSyncLogRegex,
+		Follow: true,
+	})
+	for line := range lines {
+		if line.File != cChainLogFile {
+			continue
+		}
+		switch {
+		case strings.Contains(line.Line, stateSyncStartedMarker):
+			syncStart = line.Timestamp
+		case strings.Contains(line.Line, stateSyncCompletedMarker):
+			report.Synced = true
+			if !syncStart.IsZero() {
+				report.Duration = line.Timestamp.Sub(syncStart)
+			}
+			if syncErr := stateSyncErr(line.Line); syncErr != "" {
+				return report, fmt.Errorf("node %q failed to state sync: %s", nodeName, syncErr)
+			}
+			ln.log.Info("node %q state synced in %s", nodeName, report.Duration)
+			return report, nil
+		default:
+			ln.log.Info("node %q skipped state sync", nodeName)
+			return report, nil
+		}
+	}
+	return report, fmt.Errorf("node %q didn't finish state sync within timeout, or network stopped", nodeName)
+}
+
+// Returns the error logged in [line], or empty if it's nil
+func stateSyncErr(line string) string {
+	match := stateSyncErrRegex.FindStringSubmatch(line)
+	if match == nil || match[1] == "<nil>" {
+		return ""
+	}
+	if unquoted, err := strconv.Unquote(match[1]); err == nil {
+		return unquoted
+	}
+	return match[1]
+}
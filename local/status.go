@@ -0,0 +1,122 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/config"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/status"
+)
+
+// See network.Network
+func (ln *localNetwork) Status(ctx context.Context) (network.Status, error) {
+	ln.lock.RLock()
+	if ln.stopCalled() {
+		ln.lock.RUnlock()
+		return network.Status{}, network.ErrStopped
+	}
+	nodes := make([]*localNode, 0, len(ln.nodes))
+	for _, node := range ln.nodes {
+		nodes = append(nodes, node)
+	}
+	nodeNames := ln.nodeNamesByID()
+	explorerURL := ""
+	if ln.explorer != nil {
+		explorerURL = ln.explorer.URL()
+	}
+	ln.lock.RUnlock()
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].name < nodes[j].name
+	})
+	networkStatus := network.Status{
+		UUID:        ln.uuid,
+		Nodes:       make([]network.NodeStatus, len(nodes)),
+		ExplorerURL: explorerURL,
+	}
+	// The nodes' APIs are queried without holding the lock,
+	// as they may be slow to answer
+	wg := sync.WaitGroup{}
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node *localNode) {
+			defer wg.Done()
+			networkStatus.Nodes[i] = node.status(ctx, nodeNames)
+		}(i, node)
+	}
+	wg.Wait()
+	return networkStatus, nil
+}
+
+// Returns the status of [node], where [nodeNames] (node ID --> name) are
+// the network's nodes. Errors are reported in the status.
+func (node *localNode) status(ctx context.Context, nodeNames map[ids.NodeID]string) network.NodeStatus {
+	nodeStatus := network.NodeStatus{
+		Name:         node.name,
+		NodeID:       node.nodeID,
+		URI:          node.GetURI(),
+		APIPort:      node.apiPort,
+		P2PPort:      node.p2pPort,
+		NonValidator: node.config.NonValidator,
+		PID:          processPID(node.process),
+		Uptime:       time.Since(node.startTime),
+	}
+	if whitelistedSubnets, ok := node.flags.Get(config.WhitelistedSubnetsKey); ok {
+		for _, subnetID := range strings.Split(whitelistedSubnets, ",") {
+			if subnetID = strings.TrimSpace(subnetID); subnetID != "" {
+				nodeStatus.WhitelistedSubnets = append(nodeStatus.WhitelistedSubnets, subnetID)
+			}
+		}
+	}
+
+	healthStart := time.Now()
+	if health, err := node.client.HealthAPI().Health(ctx); err != nil {
+		nodeStatus.Errors = append(nodeStatus.Errors, fmt.Sprintf("couldn't get health: %s", err))
+	} else {
+		nodeStatus.Healthy = health.Healthy
+		nodeStatus.HealthLatency = time.Since(healthStart)
+	}
+
+	if version, err := node.client.InfoAPI().GetNodeVersion(ctx); err != nil {
+		nodeStatus.Errors = append(nodeStatus.Errors, fmt.Sprintf("couldn't get version: %s", err))
+	} else {
+		nodeStatus.Version = version.Version
+	}
+
+	if peers, err := node.client.InfoAPI().Peers(ctx); err != nil {
+		nodeStatus.Errors = append(nodeStatus.Errors, fmt.Sprintf("couldn't get peers: %s", err))
+	} else {
+		nodeStatus.NumPeers = len(peers)
+		nodeStatus.MissingPeers = missingPeers(node, peers, nodeNames)
+	}
+
+	blockchains, err := node.client.PChainAPI().GetBlockchains(ctx)
+	if err != nil {
+		nodeStatus.Errors = append(nodeStatus.Errors, fmt.Sprintf("couldn't get blockchains: %s", err))
+		return nodeStatus
+	}
+	for _, blockchain := range blockchains {
+		blockchainStatus, err := node.client.PChainAPI().GetBlockchainStatus(ctx, blockchain.ID.String())
+		if err != nil {
+			nodeStatus.Errors = append(nodeStatus.Errors, fmt.Sprintf("couldn't get status of blockchain %s: %s", blockchain.ID, err))
+			continue
+		}
+		if blockchainStatus != status.Validating && blockchainStatus != status.Syncing {
+			// Not run by this node
+			continue
+		}
+		nodeStatus.Blockchains = append(nodeStatus.Blockchains, network.BlockchainStatus{
+			ID:       blockchain.ID,
+			Name:     blockchain.Name,
+			SubnetID: blockchain.SubnetID,
+			Status:   blockchainStatus.String(),
+		})
+	}
+	return nodeStatus
+}
@@ -0,0 +1,194 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/coreth/ethclient"
+	"github.com/ava-labs/coreth/rpc"
+)
+
+const (
+	// How often the heights of the nodes are polled by StopAtHeight
+	stopAtHeightPollFreq = 100 * time.Millisecond
+	pChainAlias          = "P"
+	xChainAlias          = "X"
+)
+
+// See network.Network
+func (ln *localNetwork) StopAtHeight(ctx context.Context, chainAlias string, height uint64) (map[string]uint64, error) {
+	start := time.Now()
+	heights, err := ln.stopAtHeight(ctx, chainAlias, height)
+	ln.history.record(network.OpStopAtHeight, chainAlias, start, err)
+	return heights, err
+}
+
+func (ln *localNetwork) stopAtHeight(ctx context.Context, chainAlias string, height uint64) (map[string]uint64, error) {
+	if chainAlias == xChainAlias {
+		return nil, errors.New("the X-Chain is a DAG, so it has no height")
+	}
+	ln.lock.RLock()
+	if ln.stopCalled() {
+		ln.lock.RUnlock()
+		return nil, network.ErrStopped
+	}
+	nodes := make(map[string]*localNode, len(ln.nodes))
+	for nodeName, node := range ln.nodes {
+		nodes[nodeName] = node
+	}
+	ln.lock.RUnlock()
+
+	heights, err := ln.freezeAtHeight(ctx, nodes, chainAlias, height)
+	if err != nil {
+		// Let the nodes that were frozen go on
+		ln.lock.Lock()
+		for nodeName, node := range nodes {
+			if !node.frozen || ln.nodes[nodeName] != node {
+				continue
+			}
+			if err := ln.setNodePaused(nodeName, false); err != nil {
+				ln.log.Warn("couldn't resume node %q: %s", nodeName, err)
+			}
+		}
+		ln.lock.Unlock()
+		return heights, err
+	}
+
+	for nodeName, nodeHeight := range heights {
+		if nodeHeight != height {
+			ln.log.Warn("node %q of chain %s stopped at height %d rather than %d", nodeName, chainAlias, nodeHeight, height)
+		}
+	}
+	ln.log.Info("stopping network at height %d of chain %s", height, chainAlias)
+	// The frozen nodes are killed without being resumed
+	return heights, ln.Stop(ctx)
+}
+
+// Polls the height of chain [chainAlias] on each of [nodes], and freezes
+// each node as soon as it reached [height]. Returns the last height seen
+// on each node. If [ctx] is done first, or a node couldn't be frozen,
+// the error lists the nodes lagging behind and the nodes that failed.
+func (ln *localNetwork) freezeAtHeight(ctx context.Context, nodes map[string]*localNode, chainAlias string, height uint64) (map[string]uint64, error) {
+	var (
+		lock    sync.Mutex
+		heights = make(map[string]uint64, len(nodes))
+		lagging = []string{}
+		failed  = []string{}
+		wg      sync.WaitGroup
+	)
+	for nodeName, node := range nodes {
+		nodeName, node := nodeName, node
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nodeHeight, err := ln.freezeNodeAtHeight(ctx, node, chainAlias, height)
+			lock.Lock()
+			defer lock.Unlock()
+			heights[nodeName] = nodeHeight
+			switch {
+			case err == nil:
+			case err == ctx.Err():
+				lagging = append(lagging, fmt.Sprintf("%s at %d", nodeName, nodeHeight))
+			case nodeHeight >= height:
+				failed = append(failed, fmt.Sprintf("%s at %d (%s)", nodeName, nodeHeight, err))
+			default:
+				lagging = append(lagging, fmt.Sprintf("%s at %d (%s)", nodeName, nodeHeight, err))
+			}
+		}()
+	}
+	wg.Wait()
+	if len(lagging) == 0 && len(failed) == 0 {
+		return heights, nil
+	}
+	if ln.stopCalled() {
+		return heights, network.ErrStopped
+	}
+	if len(lagging) == 0 {
+		sort.Strings(failed)
+		return heights, fmt.Errorf("nodes couldn't be stopped at height %d of chain %s: %s", height, chainAlias, strings.Join(failed, ", "))
+	}
+	sort.Strings(lagging)
+	msg := fmt.Sprintf("nodes didn't reach height %d of chain %s: nodes below height %d: %s", height, chainAlias, height, strings.Join(lagging, ", "))
+	if len(failed) != 0 {
+		sort.Strings(failed)
+		msg += "; nodes that couldn't be frozen: " + strings.Join(failed, ", ")
+	}
+	if ctx.Err() == nil {
+		return heights, errors.New(msg)
+	}
+	return heights, fmt.Errorf("%s: %w", msg, ctx.Err())
+}
+
+// Polls the height of chain [chainAlias] on [node] until it reached
+// [height], then freezes the node. Returns the last height seen.
+// If [ctx] is done first, returns the error of the last poll,
+// or ctx.Err() if it succeeded.
+func (ln *localNetwork) freezeNodeAtHeight(ctx context.Context, node *localNode, chainAlias string, height uint64) (uint64, error) {
+	var lastHeight uint64
+	for {
+		nodeHeight, err := getChainHeight(ctx, node, chainAlias)
+		if err == nil {
+			lastHeight = nodeHeight
+			if nodeHeight >= height {
+				return lastHeight, ln.freezeNode(node, nodeHeight)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = ctx.Err()
+			}
+			return lastHeight, err
+		case <-time.After(stopAtHeightPollFreq):
+		}
+	}
+}
+
+// Pauses [node], seen at [height], until it's killed by Stop.
+// A node that was removed or restarted meanwhile is left alone.
+func (ln *localNetwork) freezeNode(node *localNode, height uint64) error {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() {
+		return network.ErrStopped
+	}
+	if ln.nodes[node.name] != node {
+		return nil
+	}
+	if err := ln.setNodePaused(node.name, true); err != nil {
+		return fmt.Errorf("couldn't freeze node at height %d: %w", height, err)
+	}
+	node.frozen = true
+	return nil
+}
+
+// Returns the height of chain [chainAlias] on [n]. Chains other
+// than the P-Chain and X-Chain are assumed to be EVM chains.
+func getChainHeight(ctx context.Context, n *localNode, chainAlias string) (uint64, error) {
+	switch chainAlias {
+	case pChainAlias:
+		return n.client.PChainAPI().GetHeight(ctx)
+	case node.CChainAlias:
+		return n.client.CChainEthAPI().BlockNumber(ctx)
+	}
+	// Sent with the node's HTTP client, so that its API TLS and auth apply
+	httpClient := n.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	rpcClient, err := rpc.DialHTTPWithClient(n.GetURI()+"/ext/bc/"+chainAlias+"/rpc", httpClient)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't connect to chain %s of node %q: %w", chainAlias, n.name, err)
+	}
+	client := ethclient.NewClient(rpcClient)
+	defer client.Close()
+	return client.BlockNumber(ctx)
+}
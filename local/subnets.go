@@ -0,0 +1,314 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/config"
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/vms/platformvm"
+	"github.com/ava-labs/avalanchego/vms/platformvm/validator"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/ava-labs/avalanchego/wallet/chain/p"
+	"github.com/ava-labs/avalanchego/wallet/subnet/primary"
+	"github.com/ava-labs/avalanchego/wallet/subnet/primary/common"
+)
+
+const (
+	// Max time to create the subnets given in a network config
+	subnetsCreationTimeout = 5 * time.Minute
+	// How long after being issued subnet validators start validating
+	subnetValidatorStartDelay = 30 * time.Second
+	subnetValidatorWeight     = 1000
+	subnetTxPollFreq          = time.Second
+)
+
+// See network.Network
+func (ln *localNetwork) GetSubnets() ([]network.Subnet, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+	subnets := make([]network.Subnet, len(ln.subnets))
+	copy(subnets, ln.subnets)
+	return subnets, nil
+}
+
+// Creates the subnets and blockchains in [specs]. Once the nodes are
+// healthy, the subnets are created, their validators are restarted to
+// track them, and then added as validators, and the blockchains created.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) createSubnets(ctx context.Context, specs []network.SubnetSpec) error {
+	ctx, cancel := context.WithTimeout(ctx, subnetsCreationTimeout)
+	defer cancel()
+
+	if err := ln.healthy(ctx); err != nil {
+		return err
+	}
+	wallet, err := ln.newPWallet(ctx)
+	if err != nil {
+		return err
+	}
+	owner := &secp256k1fx.OutputOwners{
+		Threshold: 1,
+		Addrs:     []ids.ShortID{genesis.EWOQKey.PublicKey().Address()},
+	}
+	txOpts := func() []common.Option {
+		return []common.Option{common.WithContext(ctx), common.WithPollFrequency(subnetTxPollFreq)}
+	}
+
+	subnets := make([]network.Subnet, len(specs))
+	// Node name --> IDs of the subnets it validates
+	nodeSubnets := map[string][]string{}
+	for i, spec := range specs {
+		subnetID, err := wallet.IssueCreateSubnetTx(owner, txOpts()...)
+		if err != nil {
+			return fmt.Errorf("couldn't create subnet %d: %w", i, err)
+		}
+		ln.log.Info("created subnet %s", subnetID)
+		subnets[i] = network.Subnet{
+			ID:         subnetID,
+			Validators: spec.Validators,
+		}
+		for _, nodeName := range spec.Validators {
+			nodeSubnets[nodeName] = append(nodeSubnets[nodeName], subnetID.String())
+		}
+	}
+
+	if err := ln.whitelistSubnets(nodeSubnets); err != nil {
+		return err
+	}
+	if err := ln.healthy(ctx); err != nil {
+		return err
+	}
+	// The restarted nodes have new API clients
+	wallet, err = ln.newPWallet(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Subnet validators can't validate past the end of
+	// their primary network validation period
+	primaryValidators, err := ln.primaryValidatorEndTimes(ctx)
+	if err != nil {
+		return err
+	}
+	for i, spec := range specs {
+		for _, nodeName := range spec.Validators {
+			ln.lock.RLock()
+			node, ok := ln.nodes[nodeName]
+			ln.lock.RUnlock()
+			if !ok {
+				return fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+			}
+			nodeID := node.nodeID
+			endTime, ok := primaryValidators[nodeID]
+			if !ok {
+				return fmt.Errorf("node %q isn't a primary network validator", nodeName)
+			}
+			_, err := wallet.IssueAddSubnetValidatorTx(
+				&validator.SubnetValidator{
+					Validator: validator.Validator{
+						NodeID: nodeID,
+						Start:  uint64(time.Now().Add(subnetValidatorStartDelay).Unix()),
+						End:    endTime,
+						Wght:   subnetValidatorWeight,
+					},
+					Subnet: subnets[i].ID,
+				},
+				txOpts()...,
+			)
+			if err != nil {
+				return fmt.Errorf("couldn't add node %q as validator of subnet %s: %w", nodeName, subnets[i].ID, err)
+			}
+		}
+		for _, blockchainSpec := range spec.Blockchains {
+			vmID, err := network.VMIDFromName(blockchainSpec.VMName, ln.customVMs)
+			if err != nil {
+				return err
+			}
+			name := blockchainSpec.Name
+			if name == "" {
+				name = blockchainSpec.VMName
+			}
+			blockchainID, err := wallet.IssueCreateChainTx(
+				subnets[i].ID,
+				[]byte(blockchainSpec.Genesis),
+				vmID,
+				nil,
+				name,
+				txOpts()...,
+			)
+			if err != nil {
+				return fmt.Errorf("couldn't create blockchain %q in subnet %s: %w", name, subnets[i].ID, err)
+			}
+			ln.log.Info("created blockchain %s (%q) in subnet %s", blockchainID, name, subnets[i].ID)
+			subnets[i].Blockchains = append(subnets[i].Blockchains, network.Blockchain{
+				ID:     blockchainID,
+				Name:   name,
+				VMName: blockchainSpec.VMName,
+				VMID:   vmID,
+			})
+		}
+	}
+
+	ln.lock.Lock()
+	ln.subnets = append(ln.subnets, subnets...)
+	ln.lock.Unlock()
+	return nil
+}
+
+// Restarts the nodes in [nodeSubnets] so that they track the given subnets
+// on top of the ones they already track.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) whitelistSubnets(nodeSubnets map[string][]string) error {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() {
+		return network.ErrStopped
+	}
+	nodeNames := make([]string, 0, len(nodeSubnets))
+	for nodeName := range nodeSubnets {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	for _, nodeName := range nodeNames {
+		n, ok := ln.nodes[nodeName]
+		if !ok {
+			return fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+		}
+		whitelistedSubnets := nodeSubnets[nodeName]
+		if current, ok := n.flags.Get(config.WhitelistedSubnetsKey); ok && current != "" {
+			whitelistedSubnets = append(strings.Split(current, ","), whitelistedSubnets...)
+		}
+		err := ln.restartNode(nodeName, func(nodeConfig *node.Config) {
+			nodeConfig.Flags[config.WhitelistedSubnetsKey] = strings.Join(whitelistedSubnets, ",")
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// See network.Network
+func (ln *localNetwork) SetSubnetWhitelist(nodeName string, subnetIDs []ids.ID) error {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() {
+		return network.ErrStopped
+	}
+	start := time.Now()
+	err := ln.setSubnetWhitelist(nodeName, subnetIDs)
+	ln.history.record(network.OpSetSubnetWhitelist, nodeName, start, err)
+	return err
+}
+
+// Assumes [ln.lock] is held
+func (ln *localNetwork) setSubnetWhitelist(nodeName string, subnetIDs []ids.ID) error {
+	whitelist := ids.NewSet(len(subnetIDs))
+	for _, subnetID := range subnetIDs {
+		if subnetID == constants.PrimaryNetworkID {
+			return errors.New("the primary network can't be whitelisted")
+		}
+		whitelist.Add(subnetID)
+	}
+	sortedIDs := whitelist.List()
+	ids.SortIDs(sortedIDs)
+	subnetIDStrs := make([]string, len(sortedIDs))
+	for i, subnetID := range sortedIDs {
+		subnetIDStrs[i] = subnetID.String()
+	}
+	// Set even if empty, to override the node's config file
+	return ln.restartNode(nodeName, func(nodeConfig *node.Config) {
+		nodeConfig.Flags[config.WhitelistedSubnetsKey] = strings.Join(subnetIDStrs, ",")
+	})
+}
+
+// See network.Network
+func (ln *localNetwork) GetTrackedSubnets(nodeName string) ([]ids.ID, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+	node, ok := ln.nodes[nodeName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+	}
+	whitelistedSubnets, _ := node.flags.Get(config.WhitelistedSubnetsKey)
+	subnetIDs := []ids.ID{}
+	for _, subnetIDStr := range strings.Split(whitelistedSubnets, ",") {
+		if subnetIDStr = strings.TrimSpace(subnetIDStr); subnetIDStr == "" {
+			continue
+		}
+		subnetID, err := ids.FromString(subnetIDStr)
+		if err != nil {
+			return nil, fmt.Errorf("node %q tracks invalid subnet ID %q: %w", nodeName, subnetIDStr, err)
+		}
+		subnetIDs = append(subnetIDs, subnetID)
+	}
+	ids.SortIDs(subnetIDs)
+	return subnetIDs, nil
+}
+
+// Returns a P-Chain wallet of genesis.EWOQKey, using any node's API
+func (ln *localNetwork) newPWallet(ctx context.Context) (p.Wallet, error) {
+	apiNode, err := ln.anyNode()
+	if err != nil {
+		return nil, err
+	}
+	uri := apiNode.GetURI()
+
+	kc := secp256k1fx.NewKeychain(genesis.EWOQKey)
+	pCTX, _, utxos, err := primary.FetchState(ctx, uri, kc.Addrs)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't fetch wallet state: %w", err)
+	}
+	pUTXOs := primary.NewChainUTXOs(constants.PlatformChainID, utxos)
+	pBackend := p.NewBackend(pCTX, pUTXOs, map[ids.ID]*platformvm.Tx{})
+	return p.NewWallet(
+		p.NewBuilder(kc.Addrs, pBackend),
+		p.NewSigner(kc, pBackend),
+		platformvm.NewClient(uri),
+		pBackend,
+	), nil
+}
+
+// Returns the end of the validation period of
+// each current primary network validator
+func (ln *localNetwork) primaryValidatorEndTimes(ctx context.Context) (map[ids.NodeID]uint64, error) {
+	apiNode, err := ln.anyNode()
+	if err != nil {
+		return nil, err
+	}
+	validators, err := apiNode.client.PChainAPI().GetCurrentValidators(ctx, constants.PrimaryNetworkID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get current validators: %w", err)
+	}
+	endTimes := make(map[ids.NodeID]uint64, len(validators))
+	for _, v := range validators {
+		endTimes[v.NodeID] = v.EndTime
+	}
+	return endTimes, nil
+}
+
+// Returns a node of the network, to send API requests to.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) anyNode() (*localNode, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	for _, node := range ln.nodes {
+		return node, nil
+	}
+	return nil, errors.New("network has no nodes")
+}
@@ -0,0 +1,133 @@
+package local
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// nodeManifest records the resources used by a node, so that their
+// release can be checked, and its artifacts collected, after the node
+// is removed
+type nodeManifest struct {
+	nodeID ids.NodeID
+	// Root dir of the node
+	dir     string
+	dbDir   string
+	logsDir string
+	apiPort uint16
+	p2pPort uint16
+	// ID of the node's process, or 0 if unknown
+	pid int
+	// Binary of the node's process, resolved by resolveBinaryPath
+	binaryPath string
+	// True once the node's process has exited
+	exited bool
+	// Error the node's process exited with, if any
+	exitErr error
+}
+
+// See network.Network
+func (ln *localNetwork) VerifyTeardown() (network.TeardownReport, error) {
+	if !ln.stopCalled() {
+		return network.TeardownReport{}, network.ErrNotStopped
+	}
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+
+	nodeNames := make([]string, 0, len(ln.manifest))
+	for nodeName := range ln.manifest {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	report := network.TeardownReport{}
+	for _, nodeName := range nodeNames {
+		manifest := ln.manifest[nodeName]
+		// The process may have outlived its exit being recorded
+		switch {
+		case manifest.pid != 0 && processAlive(manifest.pid) && processRuns(manifest.pid, manifest.binaryPath):
+			report.Violations = append(report.Violations, network.TeardownViolation{
+				Node:    nodeName,
+				Kind:    network.ViolationProcessRunning,
+				Details: fmt.Sprintf("process %d is still alive", manifest.pid),
+			})
+		case !manifest.exited:
+			report.Violations = append(report.Violations, network.TeardownViolation{
+				Node:    nodeName,
+				Kind:    network.ViolationProcessRunning,
+				Details: "process wasn't seen exiting",
+			})
+		}
+		for _, port := range []uint16{manifest.apiPort, manifest.p2pPort} {
+			if err := checkPortFree(port); err != nil {
+				report.Violations = append(report.Violations, network.TeardownViolation{
+					Node:    nodeName,
+					Kind:    network.ViolationPortBound,
+					Details: err.Error(),
+				})
+			}
+		}
+		// Node data is preserved after Stop,
+		// unless the cleanup policy deleted it
+		_, err := os.Stat(manifest.dir)
+		switch {
+		case ln.dataDirsDeleted && err == nil:
+			report.Violations = append(report.Violations, network.TeardownViolation{
+				Node:    nodeName,
+				Kind:    network.ViolationDataDir,
+				Details: fmt.Sprintf("expected %s to be deleted", manifest.dir),
+			})
+		case !ln.dataDirsDeleted && err != nil:
+			report.Violations = append(report.Violations, network.TeardownViolation{
+				Node:    nodeName,
+				Kind:    network.ViolationDataDir,
+				Details: fmt.Sprintf("expected %s to be preserved: %s", manifest.dir, err),
+			})
+		}
+	}
+	return report, nil
+}
+
+// Deletes the node dirs if the data dir cleanup policy says so.
+// Called once the network is stopped.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) cleanupDataDirs() {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+
+	switch ln.dataDirCleanup {
+	case network.DataDirAlwaysDelete:
+	case network.DataDirRetainOnFailure:
+		for _, op := range ln.history.get() {
+			if op.Err != "" {
+				ln.log.Info("keeping node dirs under %s, as operation %q failed", ln.rootDir, op.Name)
+				return
+			}
+		}
+	default:
+		return
+	}
+	if ln.dataDirsDeleted {
+		return
+	}
+	for nodeName, manifest := range ln.manifest {
+		if err := os.RemoveAll(manifest.dir); err != nil {
+			ln.log.Warn("couldn't delete dir of node %q: %s", nodeName, err)
+		}
+	}
+	// Only removed if no other files were written to it
+	_ = os.Remove(ln.rootDir)
+	ln.dataDirsDeleted = true
+}
+
+// Returns an error if [port] is bound on any local address
+func checkPortFree(port uint16) error {
+	if err := bindPort(port); err != nil {
+		return fmt.Errorf("port %d is still bound: %w", port, err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package local
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/config"
+	"github.com/ava-labs/avalanchego/staking"
+)
+
+// Flags that each node has its own value of,
+// so aren't copied from a template node
+var perNodeFlags = []string{
+	config.HTTPPortKey,
+	config.StakingPortKey,
+	config.DBPathKey,
+	config.LogsDirKey,
+}
+
+// See network.Network
+func (ln *localNetwork) AddNodeFromTemplate(templateName string, overrides func(*node.Config)) (node.Node, error) {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+
+	start := time.Now()
+	newNode, err := ln.addNodeFromTemplate(templateName, overrides)
+	ln.history.record(network.OpAddNodeFromTemplate, templateName, start, err)
+	return newNode, err
+}
+
+// Assumes [ln.lock] is held and [ln.Stop] hasn't been called.
+func (ln *localNetwork) addNodeFromTemplate(templateName string, overrides func(*node.Config)) (node.Node, error) {
+	template, ok := ln.nodes[templateName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", network.ErrNodeNotFound, templateName)
+	}
+	nodeConfig, err := templateConfig(template.config)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't copy config of node %q: %w", templateName, err)
+	}
+	if overrides != nil {
+		overrides(&nodeConfig)
+	}
+	return ln.addNode(nodeConfig)
+}
+
+// Returns a copy of [templateConfig], without the name,
+// staking key/cert, ports and dirs of its node
+func templateConfig(templateConfig node.Config) (node.Config, error) {
+	nodeConfig := templateConfig
+	nodeConfig.Name = ""
+	nodeConfig.IsBeacon = false
+
+	stakingCert, stakingKey, err := staking.NewCertAndKeyBytes()
+	if err != nil {
+		return node.Config{}, fmt.Errorf("couldn't generate staking cert/key: %w", err)
+	}
+	nodeConfig.StakingCert = string(stakingCert)
+	nodeConfig.StakingKey = string(stakingKey)
+
+	nodeConfig.Flags = make(map[string]interface{}, len(templateConfig.Flags))
+	for flagName, flagVal := range templateConfig.Flags {
+		nodeConfig.Flags[flagName] = flagVal
+	}
+	for _, flagName := range perNodeFlags {
+		delete(nodeConfig.Flags, flagName)
+	}
+	if nodeConfig.ConfigFile != "" {
+		var configFile map[string]interface{}
+		if err := json.Unmarshal([]byte(nodeConfig.ConfigFile), &configFile); err != nil {
+			return node.Config{}, fmt.Errorf("couldn't unmarshal config file: %w", err)
+		}
+		for _, flagName := range perNodeFlags {
+			delete(configFile, flagName)
+		}
+		configFileBytes, err := json.Marshal(configFile)
+		if err != nil {
+			return node.Config{}, err
+		}
+		nodeConfig.ConfigFile = string(configFileBytes)
+	}
+
+	nodeConfig.ChainConfigFiles = copyStringMap(templateConfig.ChainConfigFiles)
+	nodeConfig.UpgradeConfigFiles = copyStringMap(templateConfig.UpgradeConfigFiles)
+	nodeConfig.SubnetConfigFiles = copyStringMap(templateConfig.SubnetConfigFiles)
+	nodeConfig.Env = append([]string(nil), templateConfig.Env...)
+	return nodeConfig, nil
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	mCopy := make(map[string]string, len(m))
+	for k, v := range m {
+		mCopy[k] = v
+	}
+	return mCopy
+}
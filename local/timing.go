@@ -0,0 +1,163 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+)
+
+const (
+	// How often a starting node is queried for its progress
+	// when recording timings
+	timingPollFreq = 250 * time.Millisecond
+	// Max time for a node to answer a progress query,
+	// so that a paused node doesn't block the recording
+	timingRequestTimeout = 2 * time.Second
+)
+
+// timingRecorder keeps the startup timings of a network's nodes
+type timingRecorder struct {
+	lock sync.Mutex
+	// Node name --> timings of its last start
+	nodes map[string]*network.NodeTiming
+}
+
+func newTimingRecorder() *timingRecorder {
+	return &timingRecorder{nodes: map[string]*network.NodeTiming{}}
+}
+
+// Replaces the timings of [timing.Node] with [timing]
+func (r *timingRecorder) start(timing *network.NodeTiming) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.nodes[timing.Node] = timing
+}
+
+// Calls [f] on [timing] with the recorder's lock held
+func (r *timingRecorder) update(timing *network.NodeTiming, f func(*network.NodeTiming)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	f(timing)
+}
+
+// Returns a copy of the timings, sorted by node name
+func (r *timingRecorder) report() network.TimingReport {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	report := network.TimingReport{Nodes: make([]network.NodeTiming, 0, len(r.nodes))}
+	for _, timing := range r.nodes {
+		timingCopy := *timing
+		timingCopy.ChainsBootstrapped = make(map[string]time.Time, len(timing.ChainsBootstrapped))
+		for chain, bootstrapped := range timing.ChainsBootstrapped {
+			timingCopy.ChainsBootstrapped[chain] = bootstrapped
+		}
+		report.Nodes = append(report.Nodes, timingCopy)
+	}
+	sort.Slice(report.Nodes, func(i, j int) bool {
+		return report.Nodes[i].Node < report.Nodes[j].Node
+	})
+	return report
+}
+
+// See network.Network
+func (ln *localNetwork) TimingReport() (network.TimingReport, error) {
+	if ln.timings == nil {
+		return network.TimingReport{}, errors.New("timings aren't recorded by the network")
+	}
+	return ln.timings.report(), nil
+}
+
+// Starts recording the timings of [node], which was just started,
+// if the network records timings.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) recordNodeTimings(node *localNode) {
+	if ln.timings == nil {
+		return
+	}
+	timing := &network.NodeTiming{
+		Node:               node.name,
+		ProcessStarted:     node.startTime,
+		ChainsBootstrapped: map[string]time.Time{},
+	}
+	ln.timings.start(timing)
+	go ln.pollNodeTimings(node, timing, ln.bootstrappedChains(node.name, nil))
+}
+
+// About every [timingPollFreq], queries [node] for its health and
+// whether it bootstrapped each of [chains], and records in [timing]
+// when it first did. Returns when [node] reached every milestone,
+// is no longer running, or the network is stopped.
+func (ln *localNetwork) pollNodeTimings(node *localNode, timing *network.NodeTiming, chains []string) {
+	// Derive a new context that's cancelled when Stop is called
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-ln.onStopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	healthy := false
+	// Chains not bootstrapped yet
+	pending := append([]string(nil), chains...)
+	for !healthy || len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(timingPollFreq):
+		}
+		ln.lock.RLock()
+		running := ln.nodes[node.name] == node
+		ln.lock.RUnlock()
+		if !running {
+			return
+		}
+
+		requestCtx, requestCancel := context.WithTimeout(ctx, timingRequestTimeout)
+		// The Health API may answer with an error while the node is
+		// unhealthy, so any answer of the Info API also counts
+		if !healthy {
+			reply, err := node.client.HealthAPI().Health(requestCtx)
+			healthy = err == nil && reply.Healthy
+			ln.recordNodeMilestone(timing, err == nil, func(timing *network.NodeTiming, now time.Time) {
+				if healthy {
+					timing.Healthy = now
+				}
+			})
+		}
+		stillPending := pending[:0]
+		for _, chain := range pending {
+			chain := chain
+			bootstrapped, err := node.client.InfoAPI().IsBootstrapped(requestCtx, chain)
+			ln.recordNodeMilestone(timing, err == nil, func(timing *network.NodeTiming, now time.Time) {
+				if bootstrapped {
+					timing.ChainsBootstrapped[chain] = now
+				}
+			})
+			if !bootstrapped {
+				stillPending = append(stillPending, chain)
+			}
+		}
+		pending = stillPending
+		requestCancel()
+	}
+}
+
+// Calls [f] on [timing] with the current time, after recording
+// it as the node's first API response if [responded] and the
+// node didn't respond before
+func (ln *localNetwork) recordNodeMilestone(timing *network.NodeTiming, responded bool, f func(*network.NodeTiming, time.Time)) {
+	now := time.Now()
+	ln.timings.update(timing, func(timing *network.NodeTiming) {
+		if responded && timing.FirstAPIResponse.IsZero() {
+			timing.FirstAPIResponse = now
+		}
+		f(timing, now)
+	})
+}
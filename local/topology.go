@@ -0,0 +1,68 @@
+package local
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// See network.Network
+func (ln *localNetwork) GetTopology(ctx context.Context) (network.Topology, error) {
+	ln.lock.RLock()
+	if ln.stopCalled() {
+		ln.lock.RUnlock()
+		return network.Topology{}, network.ErrStopped
+	}
+	nodes := make([]*localNode, 0, len(ln.nodes))
+	for _, node := range ln.nodes {
+		nodes = append(nodes, node)
+	}
+	nodeNames := ln.nodeNamesByID()
+	ln.lock.RUnlock()
+
+	topology := network.Topology{
+		Peers:  make(map[ids.NodeID][]network.PeerConnection, len(nodes)),
+		Names:  nodeNames,
+		Errors: map[string]string{},
+	}
+	// The nodes' APIs are queried without holding the lock,
+	// as they may be slow to answer
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+	)
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(node *localNode) {
+			defer wg.Done()
+			peers, err := node.client.InfoAPI().Peers(ctx)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				topology.Errors[node.name] = err.Error()
+				return
+			}
+			connections := make([]network.PeerConnection, len(peers))
+			for i, peer := range peers {
+				connections[i] = network.PeerConnection{
+					NodeID:         peer.ID,
+					Name:           nodeNames[peer.ID],
+					IP:             peer.IP,
+					LastSent:       peer.LastSent,
+					LastReceived:   peer.LastReceived,
+					ObservedUptime: uint8(peer.ObservedUptime),
+					Benched:        peer.Benched,
+				}
+			}
+			sort.Slice(connections, func(i, j int) bool {
+				return connections[i].NodeID.String() < connections[j].NodeID.String()
+			})
+			topology.Peers[node.nodeID] = connections
+		}(node)
+	}
+	wg.Wait()
+	return topology, nil
+}
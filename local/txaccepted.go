@@ -0,0 +1,166 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/vms/platformvm/status"
+	"github.com/ava-labs/coreth/ethclient"
+	"github.com/ava-labs/coreth/interfaces"
+	"github.com/ava-labs/coreth/plugin/evm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// How often the nodes are polled by AwaitTxAccepted
+const txAcceptedPollFreq = 100 * time.Millisecond
+
+// See network.Network
+func (ln *localNetwork) AwaitTxAccepted(ctx context.Context, chainAlias string, txID ids.ID, timeout time.Duration) error {
+	start := time.Now()
+	err := ln.awaitTxAccepted(ctx, chainAlias, txID, timeout)
+	ln.history.record(network.OpAwaitTxAccepted, chainAlias+":"+txID.String(), start, err)
+	return err
+}
+
+func (ln *localNetwork) awaitTxAccepted(ctx context.Context, chainAlias string, txID ids.ID, timeout time.Duration) error {
+	ln.lock.RLock()
+	if ln.stopCalled() {
+		ln.lock.RUnlock()
+		return network.ErrStopped
+	}
+	pending := make(map[string]*localNode, len(ln.nodes))
+	for nodeName, node := range ln.nodes {
+		pending[nodeName] = node
+	}
+	ln.lock.RUnlock()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	// Status of the tx on each node that hasn't accepted it yet
+	statuses := make(map[string]string, len(pending))
+	for {
+		var (
+			lock        sync.Mutex
+			wg          sync.WaitGroup
+			accepted    []string
+			rejectedErr error
+		)
+		for nodeName, node := range pending {
+			nodeName, node := nodeName, node
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				txStatus, isAccepted, err := getTxStatus(ctx, node, chainAlias, txID)
+				lock.Lock()
+				defer lock.Unlock()
+				switch {
+				case errors.Is(err, network.ErrTxRejected):
+					rejectedErr = fmt.Errorf("node %q: %w", nodeName, err)
+				case err != nil:
+					statuses[nodeName] = err.Error()
+				case isAccepted:
+					accepted = append(accepted, nodeName)
+				default:
+					statuses[nodeName] = txStatus
+				}
+			}()
+		}
+		wg.Wait()
+		if rejectedErr != nil {
+			return rejectedErr
+		}
+		for _, nodeName := range accepted {
+			delete(pending, nodeName)
+			delete(statuses, nodeName)
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			lagging := make([]string, 0, len(statuses))
+			for nodeName, txStatus := range statuses {
+				lagging = append(lagging, fmt.Sprintf("%s (%s)", nodeName, txStatus))
+			}
+			sort.Strings(lagging)
+			return fmt.Errorf("tx %s of chain %s not accepted by nodes %s: %w", txID, chainAlias, strings.Join(lagging, ", "), ctx.Err())
+		case <-time.After(txAcceptedPollFreq):
+		}
+	}
+}
+
+// Returns the status of tx [txID] of chain [chainAlias] on [n], and
+// whether it's accepted. Returns an error wrapping network.ErrTxRejected
+// if [n] rejected or dropped the tx. Chains other than the P-Chain,
+// X-Chain and C-Chain are assumed to be EVM chains.
+func getTxStatus(ctx context.Context, n *localNode, chainAlias string, txID ids.ID) (string, bool, error) {
+	switch chainAlias {
+	case xChainAlias:
+		txStatus, err := n.client.XChainAPI().GetTxStatus(ctx, txID)
+		if err != nil {
+			return "", false, err
+		}
+		if txStatus == choices.Rejected {
+			return "", false, fmt.Errorf("%w: tx %s is %s", network.ErrTxRejected, txID, txStatus)
+		}
+		return txStatus.String(), txStatus == choices.Accepted, nil
+	case pChainAlias:
+		resp, err := n.client.PChainAPI().GetTxStatus(ctx, txID, true)
+		if err != nil {
+			return "", false, err
+		}
+		switch resp.Status {
+		case status.Aborted, status.Dropped:
+			return "", false, fmt.Errorf("%w: tx %s is %s: %s", network.ErrTxRejected, txID, resp.Status, resp.Reason)
+		}
+		return resp.Status.String(), resp.Status == status.Committed, nil
+	case node.CChainAlias:
+		// [txID] is either the ID of an atomic tx or the hash of an
+		// Ethereum tx, which have the same length
+		txStatus, err := n.client.CChainAPI().GetAtomicTxStatus(ctx, txID)
+		if err != nil {
+			return "", false, err
+		}
+		switch txStatus {
+		case evm.Accepted:
+			return txStatus.String(), true, nil
+		case evm.Dropped:
+			return "", false, fmt.Errorf("%w: tx %s is %s", network.ErrTxRejected, txID, txStatus)
+		case evm.Processing:
+			return txStatus.String(), false, nil
+		}
+		_, err = n.client.CChainEthAPI().TransactionReceipt(ctx, common.Hash(txID))
+		return receiptStatus(err)
+	}
+	client, err := ethclient.DialContext(ctx, n.GetURI()+"/ext/bc/"+chainAlias+"/rpc")
+	if err != nil {
+		return "", false, fmt.Errorf("couldn't connect to chain %s of node %q: %w", chainAlias, n.name, err)
+	}
+	defer client.Close()
+	_, err = client.TransactionReceipt(ctx, common.Hash(txID))
+	return receiptStatus(err)
+}
+
+// Returns the status of an Ethereum tx given the error of getting its
+// receipt. The receipt of a tx is only found once its block is accepted.
+func receiptStatus(err error) (string, bool, error) {
+	switch {
+	case errors.Is(err, interfaces.NotFound):
+		return "no receipt", false, nil
+	case err != nil:
+		return "", false, err
+	}
+	return "accepted", true, nil
+}
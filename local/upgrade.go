@@ -0,0 +1,195 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// See network.Network
+func (ln *localNetwork) UpgradeNodes(ctx context.Context, binaryPath string, opts network.UpgradeOptions) error {
+	start := time.Now()
+	err := ln.upgradeNodes(ctx, binaryPath, opts)
+	ln.history.record(network.OpUpgradeNodes, binaryPath, start, err)
+	return err
+}
+
+func (ln *localNetwork) upgradeNodes(ctx context.Context, binaryPath string, opts network.UpgradeOptions) error {
+	if binaryPath == "" {
+		return errors.New("no binary path given")
+	}
+	nodeNames, err := ln.upgradeOrder(opts.NodeNames)
+	if err != nil {
+		return err
+	}
+	for i, nodeName := range nodeNames {
+		ln.log.Info("upgrading node %q (%d/%d) to %s", nodeName, i+1, len(nodeNames), binaryPath)
+		upgradedNode, err := ln.upgradeNode(nodeName, binaryPath, opts.Flags)
+		if err != nil {
+			return err
+		}
+		if err := ln.awaitNodeReady(ctx, upgradedNode, primaryNetworkChains, opts.NodeTimeout); err != nil {
+			return fmt.Errorf("upgraded node %q isn't ready: %w", nodeName, err)
+		}
+	}
+	return nil
+}
+
+// Returns the names of the nodes to upgrade, in order.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) upgradeOrder(nodeNames []string) ([]string, error) {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+	if len(nodeNames) == 0 {
+		nodeNames = make([]string, 0, len(ln.nodes))
+		for nodeName := range ln.nodes {
+			nodeNames = append(nodeNames, nodeName)
+		}
+		sort.Strings(nodeNames)
+		return nodeNames, nil
+	}
+	seen := make(map[string]struct{}, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		if _, ok := ln.nodes[nodeName]; !ok {
+			return nil, fmt.Errorf("%w: %q", network.ErrNodeNotFound, nodeName)
+		}
+		if _, ok := seen[nodeName]; ok {
+			return nil, fmt.Errorf("node %q given twice", nodeName)
+		}
+		seen[nodeName] = struct{}{}
+	}
+	return nodeNames, nil
+}
+
+// Restarts [nodeName] onto [binaryPath], with [flags] applied.
+// If [binaryPath] is empty, the node keeps its binary.
+// Returns the restarted node.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) upgradeNode(nodeName string, binaryPath string, flags map[string]interface{}) (*localNode, error) {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() {
+		return nil, network.ErrStopped
+	}
+	err := ln.restartNode(nodeName, func(nodeConfig *node.Config) {
+		if binaryPath != "" {
+			nodeConfig.BinaryPath = binaryPath
+		}
+		updateFlags(nodeConfig.Flags, flags)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ln.nodes[nodeName], nil
+}
+
+// Waits until [node] is healthy and has bootstrapped [chains],
+// or [ctx] is done, or [timeout] passes if it's non-zero,
+// or the network is stopped.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) awaitNodeReady(ctx context.Context, node *localNode, chains []string, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-ln.onStopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := ln.awaitNodeHealthy(ctx, node); err != nil {
+		return err
+	}
+	return awaitNodeBootstrapped(ctx, node, chains)
+}
+
+// See network.Network
+func (ln *localNetwork) UpgradeVM(ctx context.Context, vmID ids.ID, binaryPath string, opts network.UpgradeOptions) error {
+	start := time.Now()
+	err := ln.upgradeVM(ctx, vmID, binaryPath, opts)
+	ln.history.record(network.OpUpgradeVM, vmID.String(), start, err)
+	return err
+}
+
+func (ln *localNetwork) upgradeVM(ctx context.Context, vmID ids.ID, binaryPath string, opts network.UpgradeOptions) error {
+	vm, err := ln.replaceCustomVM(vmID, binaryPath)
+	if err != nil {
+		return err
+	}
+	nodeNames, err := ln.upgradeOrder(opts.NodeNames)
+	if err != nil {
+		return err
+	}
+	for i, nodeName := range nodeNames {
+		ln.log.Info("restarting node %q (%d/%d) onto VM %q binary %s", nodeName, i+1, len(nodeNames), vm.Name, binaryPath)
+		restartedNode, err := ln.upgradeNode(nodeName, "", opts.Flags)
+		if err != nil {
+			return err
+		}
+		chains := append(append([]string(nil), primaryNetworkChains...), ln.vmChains(nodeName, vmID)...)
+		if err := ln.awaitNodeReady(ctx, restartedNode, chains, opts.NodeTimeout); err != nil {
+			return fmt.Errorf("node %q isn't ready after loading VM %q: %w", nodeName, vm.Name, err)
+		}
+	}
+	return nil
+}
+
+// Replaces the binary of the custom VM [vmID] by [binaryPath], so that
+// nodes started from now on load it. Returns the updated custom VM.
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) replaceCustomVM(vmID ids.ID, binaryPath string) (network.CustomVM, error) {
+	ln.lock.Lock()
+	defer ln.lock.Unlock()
+	if ln.stopCalled() {
+		return network.CustomVM{}, network.ErrStopped
+	}
+	for i, vm := range ln.customVMs {
+		if id, err := vm.VMID(); err != nil || id != vmID {
+			continue
+		}
+		vm.BinaryPath = binaryPath
+		if err := vm.Validate(); err != nil {
+			return network.CustomVM{}, err
+		}
+		// Don't modify the slice given to clones and snapshots
+		customVMs := append([]network.CustomVM(nil), ln.customVMs...)
+		customVMs[i] = vm
+		ln.customVMs = customVMs
+		return vm, nil
+	}
+	return network.CustomVM{}, fmt.Errorf("VM %s isn't a custom VM of the network", vmID)
+}
+
+// Returns the IDs of the chains of VM [vmID] that [nodeName] validates
+// Assumes [ln.lock] isn't held.
+func (ln *localNetwork) vmChains(nodeName string, vmID ids.ID) []string {
+	ln.lock.RLock()
+	defer ln.lock.RUnlock()
+	var chains []string
+	for _, subnet := range ln.subnets {
+		if !containsString(subnet.Validators, nodeName) {
+			continue
+		}
+		for _, blockchain := range subnet.Blockchains {
+			if blockchain.VMID == vmID {
+				chains = append(chains, blockchain.ID.String())
+			}
+		}
+	}
+	return chains
+}
@@ -2,17 +2,13 @@ package local
 
 import (
 	"context"
-	"fmt"
 	"math"
 	"math/rand"
 	"net"
+	"strconv"
 	"time"
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
-
 const (
 	maxPort          = math.MaxUint16
 	minPort          = 10000
@@ -22,8 +18,9 @@ const (
 // getFreePort generates a random port number and then
 // verifies it is free. If it is, returns that port, otherwise retries.
 // Returns an error if no free port is found within [netListenTimeout].
+// Candidate ports are drawn from [rng].
 // Note that it is possible for [getFreePort] to return the same port twice.
-func getFreePort() (uint16, error) {
+func getFreePort(rng *rand.Rand) (uint16, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), netListenTimeout)
 	defer cancel()
 	for {
@@ -32,16 +29,29 @@ func getFreePort() (uint16, error) {
 			return 0, ctx.Err()
 		default:
 			// Generate random port in [minPort, maxPort]
-			port := uint16(rand.Intn(maxPort-minPort+1) + minPort)
+			port := uint16(rng.Intn(maxPort-minPort+1) + minPort)
 			// Verify it's free by binding to it
-			l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
-			if err != nil {
+			if err := bindPort(port); err != nil {
 				// Couldn't bind to this port. Try another.
 				continue
 			}
 			// We could bind to [port] so must be free.
-			_ = l.Close()
 			return port, nil
 		}
 	}
 }
+
+// Binds [port] on all local addresses and on [defaultNodeIP], then
+// releases it. Returns an error if either can't be bound. Both are
+// tried since windows lets a port bound on a specific address be
+// bound again on all addresses.
+func bindPort(port uint16) error {
+	for _, host := range []string{"", defaultNodeIP} {
+		l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+		if err != nil {
+			return err
+		}
+		_ = l.Close()
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+)
+
+// Max time for a binary to print its version
+const versionCmdTimeout = 5 * time.Second
+
+var (
+	// Application version printed by avalanchego and VMs with --version,
+	// e.g. "avalanche/1.7.11 [database=v1.4.5, commit=...]"
+	// or "Subnet-EVM/v0.2.4 [AvalancheGo=v1.7.11, rpcchainvm=15]"
+	appVersionRegex = regexp.MustCompile(`^(\S+?)/v?(\d+)\.(\d+)\.(\d+)`)
+	// RPC chain VM protocol version printed with the application version
+	rpcChainVMVersionRegex = regexp.MustCompile(`\brpcchainvm=(\d+)`)
+
+	errNoVersion = errors.New("no version in output")
+)
+
+// Runs [binaryPath] --version and returns its trimmed output.
+// Replaced in tests.
+var runVersionCmd = func(binaryPath string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionCmdTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, binaryPath, "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s --version failed: %w: %s", binaryPath, err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// binaryVersion is the version a binary prints with --version
+type binaryVersion struct {
+	app                 string
+	major, minor, patch int
+	// RPC chain VM protocol version, or 0 if not printed,
+	// which avalanchego doesn't before v1.7.14
+	rpcChainVM int
+}
+
+func (v binaryVersion) String() string {
+	return fmt.Sprintf("%s/%d.%d.%d", v.app, v.major, v.minor, v.patch)
+}
+
+// Returns the version in the --version [output] of a binary
+func parseBinaryVersion(output string) (binaryVersion, error) {
+	match := appVersionRegex.FindStringSubmatch(output)
+	if match == nil {
+		return binaryVersion{}, fmt.Errorf("%w: %q", errNoVersion, output)
+	}
+	// The regex only matches digits
+	major, _ := strconv.Atoi(match[2])
+	minor, _ := strconv.Atoi(match[3])
+	patch, _ := strconv.Atoi(match[4])
+	version := binaryVersion{app: match[1], major: major, minor: minor, patch: patch}
+	if match := rpcChainVMVersionRegex.FindStringSubmatch(output); match != nil {
+		version.rpcChainVM, _ = strconv.Atoi(match[1])
+	}
+	return version, nil
+}
+
+// Cached version of a binary
+type versionCacheEntry struct {
+	// Modification time of the binary when its version was
+	// queried, so a binary replaced in place is queried again
+	modTime time.Time
+	version binaryVersion
+	err     error
+}
+
+// Returns the version of the binary at [binaryPath], queried once
+// per modification of the binary.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) getBinaryVersion(binaryPath string) (binaryVersion, error) {
+	info, err := os.Stat(binaryPath)
+	if err != nil {
+		return binaryVersion{}, err
+	}
+	if entry, ok := ln.binaryVersions[binaryPath]; ok && entry.modTime.Equal(info.ModTime()) {
+		return entry.version, entry.err
+	}
+	entry := versionCacheEntry{modTime: info.ModTime()}
+	output, err := runVersionCmd(binaryPath)
+	if err == nil {
+		entry.version, entry.err = parseBinaryVersion(output)
+	} else {
+		entry.err = err
+	}
+	ln.binaryVersions[binaryPath] = entry
+	return entry.version, entry.err
+}
+
+// Returns an error if the binary of [nodeConfig] can't run in the same
+// network as the binaries of the running nodes, or can't run the
+// network's custom VMs. Binaries whose version can't be queried
+// are assumed compatible, since they may be wrappers or test doubles.
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) checkVersionCompat(nodeConfig *node.Config) error {
+	version, err := ln.getBinaryVersion(nodeConfig.BinaryPath)
+	if err != nil {
+		ln.log.Warn("couldn't get version of node %q's binary, skipping compatibility check: %s", nodeConfig.Name, err)
+		return nil
+	}
+	for _, node := range ln.nodes {
+		if node.config.BinaryPath == nodeConfig.BinaryPath {
+			continue
+		}
+		peerVersion, err := ln.getBinaryVersion(node.config.BinaryPath)
+		if err != nil {
+			continue
+		}
+		if err := protocolCompatible(version, peerVersion); err != nil {
+			return fmt.Errorf("node %q's binary %q can't connect to node %q's binary %q: %w",
+				nodeConfig.Name, nodeConfig.BinaryPath, node.name, node.config.BinaryPath, err)
+		}
+	}
+	if version.rpcChainVM == 0 {
+		return nil
+	}
+	for _, vm := range ln.customVMs {
+		vmVersion, err := ln.getBinaryVersion(vm.BinaryPath)
+		if err != nil {
+			ln.log.Debug("couldn't get version of VM %q, skipping compatibility check: %s", vm.Name, err)
+			continue
+		}
+		if vmVersion.rpcChainVM != 0 && vmVersion.rpcChainVM != version.rpcChainVM {
+			return fmt.Errorf("VM %q (%s) speaks RPC chain VM protocol %d but node %q's binary (%s) speaks protocol %d",
+				vm.Name, vmVersion, vmVersion.rpcChainVM, nodeConfig.Name, version, version.rpcChainVM)
+		}
+	}
+	return nil
+}
+
+// Returns an error if nodes running [a] and [b] can't connect to each
+// other. A node rejects peers of another major version, and peers more
+// than one minor version behind its own.
+func protocolCompatible(a, b binaryVersion) error {
+	minorDiff := a.minor - b.minor
+	switch {
+	case a.major != b.major:
+		return fmt.Errorf("%s and %s have different major versions", a, b)
+	case minorDiff > 1 || minorDiff < -1:
+		return fmt.Errorf("%s and %s are more than one minor version apart", a, b)
+	}
+	return nil
+}
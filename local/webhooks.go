@@ -0,0 +1,48 @@
+package local
+
+import (
+	"github.com/ava-labs/avalanche-network-runner/pkg/webhook"
+)
+
+// Starts posting the network's events to the webhooks of [config].
+// Assumes [ln.lock] is held, or not needed.
+func (ln *localNetwork) startWebhooks(config webhook.Config) error {
+	n, err := webhook.New(ln.log, config)
+	if err != nil {
+		return err
+	}
+	ln.webhooks = n
+	return nil
+}
+
+// Posts an event of type [eventType] to the network's webhooks, if any.
+// [node] and [err] are the node the event is about, and the error it
+// exited with, if any.
+func (ln *localNetwork) notifyWebhooks(eventType webhook.EventType, node *localNode, err error) {
+	if ln.webhooks == nil {
+		return
+	}
+	event := webhook.Event{
+		Type:        eventType,
+		NetworkUUID: ln.uuid,
+	}
+	if node != nil {
+		event.Node = node.name
+		event.NodeID = node.nodeID.String()
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	ln.webhooks.Notify(event)
+}
+
+// Posts the network_stopped event, and waits for
+// the pending events to be posted
+// Assumes [ln.lock] is held.
+func (ln *localNetwork) closeWebhooks() error {
+	if ln.webhooks == nil {
+		return nil
+	}
+	ln.notifyWebhooks(webhook.EventNetworkStopped, nil, nil)
+	return ln.webhooks.Close()
+}
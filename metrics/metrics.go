@@ -0,0 +1,336 @@
+// Package metrics scrapes the metrics of a network's nodes and
+// aggregates them, so that they can be served from a single
+// Prometheus endpoint or dumped to a file.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Path of the metrics endpoint of avalanchego nodes
+	MetricsPath = "/ext/metrics"
+	// Label added to aggregated metrics to tell nodes apart
+	NodeLabel = "node"
+
+	scrapeTimeout = 10 * time.Second
+)
+
+// Target is a node whose metrics are scraped
+type Target struct {
+	// Name of the node
+	Node string
+	// host:port of the node's API
+	Addr string
+}
+
+// TargetsFromNetwork returns the nodes of [nw] as targets
+func TargetsFromNetwork(nw network.Network) ([]Target, error) {
+	nodes, err := nw.GetAllNodes()
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]Target, 0, len(nodes))
+	for name, node := range nodes {
+		targets = append(targets, Target{
+			Node: name,
+			Addr: net.JoinHostPort(node.GetURL(), strconv.Itoa(int(node.GetAPIPort()))),
+		})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Node < targets[j].Node
+	})
+	return targets, nil
+}
+
+// Scraper periodically scrapes the metrics of a set of nodes,
+// and keeps the latest metrics of each.
+type Scraper struct {
+	log logging.Logger
+	// Returns the nodes to scrape.
+	// Called on every scrape, so nodes added to or removed
+	// from a network are picked up.
+	getTargets func() ([]Target, error)
+	interval   time.Duration
+	client     *http.Client
+
+	lock sync.RWMutex
+	// Node name --> latest metric families of that node,
+	// labeled with the node's name
+	families map[string][]*dto.MetricFamily
+}
+
+// NewScraper returns a scraper of the nodes given by [getTargets],
+// which scrapes every [interval] once started
+func NewScraper(log logging.Logger, getTargets func() ([]Target, error), interval time.Duration) *Scraper {
+	return &Scraper{
+		log:        log,
+		getTargets: getTargets,
+		interval:   interval,
+		client:     &http.Client{Timeout: scrapeTimeout},
+		families:   map[string][]*dto.MetricFamily{},
+	}
+}
+
+// NewNetworkScraper returns a scraper of the nodes of [nw]
+func NewNetworkScraper(log logging.Logger, nw network.Network, interval time.Duration) *Scraper {
+	return NewScraper(log, func() ([]Target, error) {
+		return TargetsFromNetwork(nw)
+	}, interval)
+}
+
+// Run scrapes the nodes every interval until [ctx] is done
+func (s *Scraper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Scrape(ctx); err != nil {
+			s.log.Debug("error scraping metrics: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scrape scrapes all nodes once.
+// Nodes that couldn't be scraped keep their previous metrics.
+// Returns the last error encountered, if any.
+func (s *Scraper) Scrape(ctx context.Context) error {
+	targets, err := s.getTargets()
+	if err != nil {
+		return fmt.Errorf("couldn't get targets: %w", err)
+	}
+	families := make(map[string][]*dto.MetricFamily, len(targets))
+	var lastErr error
+	for _, target := range targets {
+		nodeFamilies, err := s.scrape(ctx, target)
+		if err != nil {
+			lastErr = fmt.Errorf("couldn't scrape node %q: %w", target.Node, err)
+			s.lock.RLock()
+			nodeFamilies = s.families[target.Node]
+			s.lock.RUnlock()
+		}
+		families[target.Node] = nodeFamilies
+	}
+	// Nodes that aren't targets anymore are dropped
+	s.lock.Lock()
+	s.families = families
+	s.lock.Unlock()
+	return lastErr
+}
+
+func (s *Scraper) scrape(ctx context.Context, target Target) ([]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+target.Addr+MetricsPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %q", resp.Status)
+	}
+	var parser expfmt.TextParser
+	familiesByName, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse metrics: %w", err)
+	}
+	families := make([]*dto.MetricFamily, 0, len(familiesByName))
+	for _, family := range familiesByName {
+		for _, metric := range family.Metric {
+			metric.Label = append(metric.Label, &dto.LabelPair{
+				Name:  proto.String(NodeLabel),
+				Value: proto.String(target.Node),
+			})
+		}
+		families = append(families, family)
+	}
+	return families, nil
+}
+
+// Value returns the sum of the latest values of the series of metric
+// [metricName] of node [nodeName] that have all of [labels], and whether
+// there was any. The value of a summary or histogram is its sample count.
+func (s *Scraper) Value(nodeName string, metricName string, labels map[string]string) (float64, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	sum := 0.0
+	found := false
+	for _, family := range s.families[nodeName] {
+		if family.GetName() != metricName {
+			continue
+		}
+		for _, metric := range family.Metric {
+			if !hasLabels(metric, labels) {
+				continue
+			}
+			switch {
+			case metric.Gauge != nil:
+				sum += metric.Gauge.GetValue()
+			case metric.Counter != nil:
+				sum += metric.Counter.GetValue()
+			case metric.Untyped != nil:
+				sum += metric.Untyped.GetValue()
+			case metric.Summary != nil:
+				sum += float64(metric.Summary.GetSampleCount())
+			case metric.Histogram != nil:
+				sum += float64(metric.Histogram.GetSampleCount())
+			default:
+				continue
+			}
+			found = true
+		}
+	}
+	return sum, found
+}
+
+// Returns true if [metric] has all of [labels]
+func hasLabels(metric *dto.Metric, labels map[string]string) bool {
+	for name, value := range labels {
+		found := false
+		for _, label := range metric.Label {
+			if label.GetName() == name && label.GetValue() == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Write writes the latest metrics of all nodes to [w] in the Prometheus
+// text format. Metrics of the same name from different nodes are merged
+// in a single family, and told apart by their NodeLabel.
+func (s *Scraper) Write(w io.Writer) error {
+	s.lock.RLock()
+	nodeNames := make([]string, 0, len(s.families))
+	for nodeName := range s.families {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	merged := map[string]*dto.MetricFamily{}
+	for _, nodeName := range nodeNames {
+		for _, family := range s.families[nodeName] {
+			mergedFamily, ok := merged[family.GetName()]
+			if !ok {
+				mergedFamily = &dto.MetricFamily{
+					Name: family.Name,
+					Help: family.Help,
+					Type: family.Type,
+				}
+				merged[family.GetName()] = mergedFamily
+			}
+			mergedFamily.Metric = append(mergedFamily.Metric, family.Metric...)
+		}
+	}
+	s.lock.RUnlock()
+
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := expfmt.MetricFamilyToText(w, merged[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFile writes the latest metrics of all nodes to the file at [path].
+// See Write.
+func (s *Scraper) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ServeHTTP serves the latest metrics of all nodes. See Write.
+func (s *Scraper) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", string(expfmt.FmtText))
+	if err := s.Write(w); err != nil {
+		s.log.Debug("error writing metrics: %s", err)
+	}
+}
+
+// ListenAndServe serves the aggregated metrics at [MetricsPath]
+// on [addr] until [ctx] is done
+func (s *Scraper) ListenAndServe(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, s)
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+type scrapeConfig struct {
+	ScrapeConfigs []scrapeJob `yaml:"scrape_configs"`
+}
+
+type scrapeJob struct {
+	JobName       string         `yaml:"job_name"`
+	MetricsPath   string         `yaml:"metrics_path"`
+	StaticConfigs []staticConfig `yaml:"static_configs"`
+}
+
+type staticConfig struct {
+	Targets []string          `yaml:"targets"`
+	Labels  map[string]string `yaml:"labels"`
+}
+
+// WriteScrapeConfig writes to [path] a Prometheus config
+// that scrapes [targets] directly, under job [jobName]
+func WriteScrapeConfig(path string, jobName string, targets []Target) error {
+	job := scrapeJob{
+		JobName:     jobName,
+		MetricsPath: MetricsPath,
+	}
+	for _, target := range targets {
+		job.StaticConfigs = append(job.StaticConfigs, staticConfig{
+			Targets: []string{target.Addr},
+			Labels:  map[string]string{NodeLabel: target.Node},
+		})
+	}
+	configBytes, err := yaml.Marshal(scrapeConfig{ScrapeConfigs: []scrapeJob{job}})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, configBytes, 0o644)
+}
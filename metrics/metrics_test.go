@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func newTestNode(t *testing.T, value int) Target {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != MetricsPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, "# HELP avalanche_blocks Number of blocks\n# TYPE avalanche_blocks gauge\navalanche_blocks %d\n", value)
+	}))
+	t.Cleanup(server.Close)
+	return Target{Addr: strings.TrimPrefix(server.URL, "http://")}
+}
+
+func TestScraper(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	node1 := newTestNode(t, 1)
+	node1.Node = "node1"
+	node2 := newTestNode(t, 2)
+	node2.Node = "node2"
+	unreachable := Target{Node: "node3", Addr: "127.0.0.1:1"}
+
+	scraper := NewScraper(logging.NoLog{}, func() ([]Target, error) {
+		return []Target{node1, node2, unreachable}, nil
+	}, time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := scraper.Scrape(ctx)
+	assert.Error(err)
+	assert.Contains(err.Error(), "node3")
+
+	var buf bytes.Buffer
+	assert.NoError(scraper.Write(&buf))
+	out := buf.String()
+	assert.Equal(1, strings.Count(out, "# TYPE avalanche_blocks gauge"))
+	assert.Contains(out, `avalanche_blocks{node="node1"} 1`)
+	assert.Contains(out, `avalanche_blocks{node="node2"} 2`)
+
+	value, ok := scraper.Value("node2", "avalanche_blocks", nil)
+	assert.True(ok)
+	assert.Equal(2.0, value)
+	_, ok = scraper.Value("node2", "avalanche_blocks", map[string]string{NodeLabel: "node1"})
+	assert.False(ok)
+	_, ok = scraper.Value("node3", "avalanche_blocks", nil)
+	assert.False(ok)
+
+	rec := httptest.NewRecorder()
+	scraper.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+	assert.Equal(out, rec.Body.String())
+}
+
+func TestWriteScrapeConfig(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "prometheus.yml")
+	targets := []Target{
+		{Node: "node1", Addr: "127.0.0.1:9650"},
+		{Node: "node2", Addr: "127.0.0.1:9652"},
+	}
+	assert.NoError(WriteScrapeConfig(path, "avalanchego", targets))
+
+	configBytes, err := os.ReadFile(path)
+	assert.NoError(err)
+	var config scrapeConfig
+	assert.NoError(yaml.Unmarshal(configBytes, &config))
+	assert.Len(config.ScrapeConfigs, 1)
+	job := config.ScrapeConfigs[0]
+	assert.Equal("avalanchego", job.JobName)
+	assert.Equal(MetricsPath, job.MetricsPath)
+	assert.Len(job.StaticConfigs, 2)
+	assert.Equal([]string{"127.0.0.1:9652"}, job.StaticConfigs[1].Targets)
+	assert.Equal("node2", job.StaticConfigs[1].Labels[NodeLabel])
+}
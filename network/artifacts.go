@@ -0,0 +1,40 @@
+package network
+
+import (
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// Name of the manifest in an artifacts archive
+const ArtifactsManifestName = "manifest.json"
+
+// ArtifactsManifest describes the contents of an
+// archive written by Network.CollectArtifacts
+type ArtifactsManifest struct {
+	NetworkID   uint32    `json:"networkID"`
+	CollectedAt time.Time `json:"collectedAt"`
+	// Every node that ran in the network, sorted by name
+	Nodes []NodeArtifacts `json:"nodes"`
+}
+
+// NodeArtifacts describes the artifacts of a node.
+// The node's files are under <node name>/ in the archive.
+type NodeArtifacts struct {
+	Name    string     `json:"name"`
+	NodeID  ids.NodeID `json:"nodeID"`
+	APIPort uint16     `json:"apiPort"`
+	P2PPort uint16     `json:"p2pPort"`
+	// True if the node was running when the artifacts were collected
+	Running bool `json:"running"`
+	// True if the node's process exited
+	Exited bool `json:"exited"`
+	// Error the node's process exited with, if any
+	ExitErr string `json:"exitErr,omitempty"`
+	// Why the node's metrics couldn't be dumped, if a running node's
+	// metrics aren't in the archive (as <node name>/metrics.txt)
+	MetricsErr string `json:"metricsErr,omitempty"`
+	// Paths of the node's files in the archive, e.g. its
+	// logs and config files. Secret keys aren't included.
+	Files []string `json:"files"`
+}
@@ -0,0 +1,14 @@
+package network
+
+import (
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/ips"
+)
+
+// Beacon is a node that nodes added to a network bootstrap from
+type Beacon struct {
+	Name   string     `json:"name"`
+	NodeID ids.NodeID `json:"nodeID"`
+	// IP and P2P port the other nodes connect to
+	IP ips.IPPort `json:"ip"`
+}
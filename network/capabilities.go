@@ -0,0 +1,23 @@
+package network
+
+// Capabilities reports which optional features a Network supports.
+// Generic code can check these before calling into a feature, rather than
+// failing at runtime on a backend that doesn't support it.
+type Capabilities struct {
+	// Nodes can be paused and resumed
+	Pause bool `json:"pause"`
+	// The network can be saved to and loaded from snapshots
+	Snapshots bool `json:"snapshots"`
+	// The traffic between nodes can be shaped
+	// (e.g. bandwidth capped, delayed, dropped)
+	TrafficShaping bool `json:"trafficShaping"`
+	// The niceness of the nodes' processes can be set
+	// (see node.Config.Nice)
+	Nice bool `json:"nice"`
+	// The resources (e.g. CPU, memory) available to nodes can be limited
+	// (see node.Config.CPUAffinity)
+	ResourceLimits bool `json:"resourceLimits"`
+	// Storage faults can be injected into node databases
+	// (see node.Config.DiskFault)
+	DiskFaults bool `json:"diskFaults"`
+}
@@ -0,0 +1,97 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/genesis"
+)
+
+const (
+	cChainGenesisConfigKey  = "config"
+	cChainGenesisChainIDKey = "chainId"
+	cChainGenesisAllocKey   = "alloc"
+)
+
+// CChainGenesisOverrides are applied on top of a network's C-Chain genesis.
+type CChainGenesisOverrides struct {
+	// If non-zero, the EVM chain ID of the C-Chain.
+	ChainID uint64 `json:"chainID"`
+	// Pre-funded EVM accounts.
+	// An existing allocation for the same address is replaced.
+	Balances []AddrAndBalance `json:"balances"`
+}
+
+// SetCChainGenesis replaces the C-Chain genesis
+// in this network config's genesis with [cChainGenesis].
+func (c *Config) SetCChainGenesis(cChainGenesis string) error {
+	var cChainGenesisMap map[string]interface{}
+	if err := json.Unmarshal([]byte(cChainGenesis), &cChainGenesisMap); err != nil {
+		return fmt.Errorf("couldn't unmarshal C-Chain genesis: %w", err)
+	}
+	genesis, err := updateGenesis([]byte(c.Genesis), func(genesisConfig *genesis.UnparsedConfig) error {
+		genesisConfig.CChainGenesis = cChainGenesis
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.Genesis = string(genesis)
+	return nil
+}
+
+// OverrideCChainGenesis applies [overrides] to the C-Chain genesis
+// in this network config's genesis. Fields of the C-Chain genesis
+// not given in [overrides] are preserved.
+func (c *Config) OverrideCChainGenesis(overrides CChainGenesisOverrides) error {
+	genesis, err := updateGenesis([]byte(c.Genesis), func(genesisConfig *genesis.UnparsedConfig) error {
+		// Use json.Number so that big balances and chain
+		// config values are preserved exactly
+		var cChainGenesis map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader([]byte(genesisConfig.CChainGenesis)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&cChainGenesis); err != nil {
+			return fmt.Errorf("couldn't unmarshal C-Chain genesis: %w", err)
+		}
+		if overrides.ChainID != 0 {
+			cChainConfig, ok := cChainGenesis[cChainGenesisConfigKey].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("expected C-Chain genesis field %q to be an object", cChainGenesisConfigKey)
+			}
+			cChainConfig[cChainGenesisChainIDKey] = overrides.ChainID
+		}
+		if len(overrides.Balances) != 0 {
+			cChainAllocs, ok := cChainGenesis[cChainGenesisAllocKey].(map[string]interface{})
+			if !ok {
+				cChainAllocs = map[string]interface{}{}
+			}
+			for _, cChainBal := range overrides.Balances {
+				addrHex := cChainBal.Addr.Hex()
+				// Remove a previous allocation of the same address,
+				// which may be formatted differently
+				for existingAddr := range cChainAllocs {
+					if strings.EqualFold(strings.TrimPrefix(existingAddr, "0x"), addrHex) {
+						delete(cChainAllocs, existingAddr)
+					}
+				}
+				cChainAllocs[fmt.Sprintf("0x%s", addrHex)] = map[string]interface{}{
+					"balance": fmt.Sprintf("0x%x", cChainBal.Balance),
+				}
+			}
+			cChainGenesis[cChainGenesisAllocKey] = cChainAllocs
+		}
+		cChainGenesisBytes, err := json.Marshal(cChainGenesis)
+		if err != nil {
+			return err
+		}
+		genesisConfig.CChainGenesis = string(cChainGenesisBytes)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.Genesis = string(genesis)
+	return nil
+}
@@ -0,0 +1,29 @@
+package network
+
+import "fmt"
+
+// DataDirCleanup determines what's done with the
+// nodes' dirs (db, logs, config files...) when a network stops
+type DataDirCleanup string
+
+const (
+	// The node dirs are kept. The default.
+	DataDirRetain DataDirCleanup = "retain"
+	// The node dirs are deleted, unless an operation
+	// on the network failed (see Network.History), so
+	// that they can be inspected.
+	DataDirRetainOnFailure DataDirCleanup = "retain-on-failure"
+	// The node dirs are always deleted
+	DataDirAlwaysDelete DataDirCleanup = "always-delete"
+)
+
+// Validate returns an error if [c] isn't a known policy.
+// The empty policy is DataDirRetain.
+func (c DataDirCleanup) Validate() error {
+	switch c {
+	case "", DataDirRetain, DataDirRetainOnFailure, DataDirAlwaysDelete:
+		return nil
+	default:
+		return fmt.Errorf("unknown data dir cleanup policy %q", c)
+	}
+}
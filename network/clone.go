@@ -0,0 +1,15 @@
+package network
+
+// CloneOverrides are applied to the config of
+// a network's clone before its nodes are started.
+// The zero value clones the network as is.
+type CloneOverrides struct {
+	// Flags given to every node of the clone, which take precedence
+	// over the flags of the cloned network and of its nodes
+	Flags map[string]interface{} `json:"flags"`
+	// Node name --> flags given to the clone of that node,
+	// which take precedence over [Flags]
+	NodeFlags map[string]map[string]interface{} `json:"nodeFlags"`
+	// If non-empty, the avalanchego binary every node of the clone runs
+	BinaryPath string `json:"binaryPath"`
+}
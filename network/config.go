@@ -4,10 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"time"
 
+	"github.com/ava-labs/avalanche-network-runner/api"
 	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanche-network-runner/pkg/explorer"
+	"github.com/ava-labs/avalanche-network-runner/pkg/faucet"
+	"github.com/ava-labs/avalanche-network-runner/pkg/observability"
+	"github.com/ava-labs/avalanche-network-runner/pkg/webhook"
 	"github.com/ava-labs/avalanche-network-runner/utils"
 	"github.com/ava-labs/avalanchego/genesis"
 	"github.com/ava-labs/avalanchego/ids"
@@ -55,35 +59,269 @@ type Config struct {
 	// and the node's config file has flag W set to Z,
 	// then the node will be started with flag W set to Y.
 	Flags map[string]interface{} `json:"flags"`
+	// If non-empty, the operations done on the network
+	// are appended to this file as JSON lines.
+	// The history is always kept in memory, see Network.History.
+	HistoryFile string `json:"historyFile"`
+	// If non-zero, a node is only considered healthy once it's
+	// connected to at least this many primary network validators,
+	// in addition to reporting healthy through the Health API.
+	HealthyMinValidatorPeers uint32 `json:"healthyMinValidatorPeers"`
+	// If true, a node is only considered healthy once it's
+	// connected to every other node of the network.
+	// See Network.AwaitFullMesh.
+	HealthyRequireFullMesh bool `json:"healthyRequireFullMesh"`
+	// Max number of health API requests in flight at a time while
+	// awaiting the nodes' health. Defaults to 10.
+	HealthCheckParallelism int `json:"healthCheckParallelism"`
+	// If non-zero, ports not given in the node configs are
+	// assigned deterministically from this seed, so that test
+	// runs are reproducible.
+	// Node names, when not given, are always assigned deterministically.
+	// See local.NewDeterministicConfigNNodes to also derive staking keys/certs,
+	// and so node IDs, from a seed.
+	RandomSeed int64 `json:"randomSeed"`
+	// Chain alias or ID --> contents of that chain's config file,
+	// written to each node's chain config dir.
+	// A node's config may override the file of a given chain.
+	ChainConfigFiles map[string]string `json:"chainConfigFiles"`
+	// Subnet ID --> contents of that subnet's config file,
+	// written to each node's subnet config dir.
+	// A node's config may override the file of a given subnet.
+	SubnetConfigFiles map[string]string `json:"subnetConfigFiles"`
+	// Hooks called on the config of every node, including the
+	// ones in NodeConfigs, right before it's started.
+	// See Network.BeforeNodeStart.
+	BeforeNodeStart []func(*node.Config) error `json:"-"`
+	// Conditions the nodes must meet, on top of their Health API
+	// reporting healthy, to be healthy. See Network.AddHealthCheck.
+	HealthChecks []HealthCheck `json:"-"`
+	// Subnets, and their blockchains, created once the nodes are healthy.
+	// The nodes validating a subnet are restarted to track it.
+	// Transactions are paid for by genesis.EWOQKey, which
+	// must be funded on the P-Chain by [Genesis].
+	SubnetSpecs []SubnetSpec `json:"subnetSpecs"`
+	// Applied in order to [Genesis] before the network uses it, to change
+	// genesis fields that the genesis builders don't expose.
+	// See UnparsedGenesisMutator and CChainGenesisMutator.
+	GenesisMutators []GenesisMutator `json:"-"`
+	// VM binaries installed in the plugin dir of every node,
+	// alongside the plugins of the node's build dir.
+	CustomVMs []CustomVM `json:"customVMs"`
+	// If both are given, the resource usage of every node is sampled
+	// every [ResourceUsageInterval] (see node.Node.GetResourceUsage),
+	// and the samples are appended to [ResourceUsageFile] as they're
+	// taken, as CSV if it ends with ".csv" or else as JSON lines.
+	// The CPUPercent of a sample is the CPU the node used since its
+	// previous sample.
+	ResourceUsageInterval time.Duration `json:"resourceUsageInterval"`
+	ResourceUsageFile     string        `json:"resourceUsageFile"`
+	// If non-empty, each node's dirs are created under
+	// [RootDataDir]/<node name>, instead of under the root dir
+	// the network was created with.
+	RootDataDir string `json:"rootDataDir"`
+	// What's done with the node dirs when the network is stopped.
+	// Defaults to DataDirRetain.
+	DataDirCleanup DataDirCleanup `json:"dataDirCleanup"`
+	// HTTP requests sent by the nodes' API clients go through this
+	// middleware. See api.NewAPIClientWithMiddleware.
+	APIMiddleware []api.Middleware `json:"-"`
+	// Called on the lifecycle events of the network and its nodes
+	Hooks Hooks `json:"-"`
+	// If true, the beacons are started first, and the other nodes once
+	// the beacons have bootstrapped the primary network's chains,
+	// rather than all nodes at once. (Bootstrapping, rather than health,
+	// is awaited since a lone beacon isn't healthy until it has peers.)
+	StagedStartup bool `json:"stagedStartup"`
+	// If positive, nodes are started [NodeStartParallelism] at a time,
+	// waiting [NodeStartDelay] between each batch.
+	NodeStartDelay time.Duration `json:"nodeStartDelay"`
+	// Max number of nodes started at a time, and number of nodes
+	// started before waiting [NodeStartDelay]. Defaults to 1 if
+	// [NodeStartDelay] is positive, and to 8 otherwise.
+	NodeStartParallelism int `json:"nodeStartParallelism"`
+	// If non-nil, a faucet is started once the network's nodes and
+	// subnets are created, that sends the AVAX of genesis.EWOQKey on
+	// the X-Chain, P-Chain and C-Chain to the addresses requested.
+	// See Network.GetFaucetURL.
+	Faucet *faucet.Config `json:"faucet"`
+	// If non-nil, Prometheus, and optionally Grafana, are started
+	// before the network's nodes to scrape their metrics, and are
+	// stopped by Network.Stop. See Network.GetObservabilityURLs.
+	Observability *observability.Config `json:"observability"`
+	// If non-nil, a block explorer is started once the network's nodes
+	// and subnets are created, against the APIs of the first node by
+	// name. Its URL is given in Network.Status.
+	Explorer *explorer.Config `json:"explorer"`
+	// If non-nil, the lifecycle events of the network and its nodes
+	// (e.g. the network being healthy, or a node crashing) are posted
+	// as JSON to the webhook URLs it gives.
+	Webhooks *webhook.Config `json:"webhooks"`
+	// If non-empty, the network's artifacts are written to this
+	// path when it's stopped, before its nodes are stopped, so that
+	// their metrics are collected too. See Network.CollectArtifacts.
+	ArtifactsPath string `json:"artifactsPath"`
+	// If non-nil, each node's name is registered in it as
+	// <node name>.<[HostsDomain]>, resolving to the node's API IP.
+	// Node names must then be valid host name labels.
+	HostsRegistry HostsRegistry `json:"-"`
+	// Defaults to DefaultHostsDomain
+	HostsDomain string `json:"hostsDomain"`
+	// If non-nil, applied to the C-Chain config of every node, so that
+	// nodes joining the network can state sync the C-Chain.
+	// See StateSyncConfig.Apply and Network.AwaitStateSync.
+	StateSync *StateSyncConfig `json:"stateSync"`
+	// If true, the Index API is enabled on every node,
+	// so that Network.AcceptedEvents can be used.
+	IndexEnabled bool `json:"indexEnabled"`
+	// If true, the times at which each node started, answered its
+	// first API request, became healthy and bootstrapped its chains
+	// are recorded. See Network.TimingReport.
+	RecordTimings bool `json:"recordTimings"`
 }
 
-// Validate returns an error if this config is invalid
-func (c *Config) Validate() error {
-	var someNodeIsBeacon bool
-	switch {
-	case len(c.Genesis) == 0:
-		return errors.New("no genesis given")
+// ValidationIssue is a problem with a field of a network config
+type ValidationIssue = node.ValidationIssue
+
+// Validate returns all the problems with this config, or nil if it's valid.
+// Field paths are the JSON paths of the fields in the config.
+func (c *Config) Validate() []ValidationIssue {
+	var issues []ValidationIssue
+	addIssue := func(field string, err error) {
+		issues = append(issues, ValidationIssue{Field: field, Err: err})
 	}
-	networkID, err := utils.NetworkIDFromGenesis([]byte(c.Genesis))
-	if err != nil {
-		return fmt.Errorf("couldn't get network ID from genesis: %w", err)
+	var (
+		networkID    *uint32
+		genesisBytes []byte
+	)
+	if len(c.Genesis) == 0 {
+		addIssue("genesis", &ErrInvalidGenesis{Reason: "no genesis given"})
+	} else if mutated, err := c.MutatedGenesis(); err != nil {
+		addIssue("genesis", NewErrInvalidGenesis(err))
+	} else if id, err := utils.NetworkIDFromGenesis(mutated); err != nil {
+		addIssue("genesis", NewErrInvalidGenesis(fmt.Errorf("couldn't get network ID: %w", err)))
+	} else {
+		networkID = &id
+		genesisBytes = mutated
+	}
+	if err := node.ValidateSubnetConfigFiles(c.SubnetConfigFiles); err != nil {
+		addIssue("subnetConfigFiles", err)
+	}
+	if err := validateCustomVMs(c.CustomVMs); err != nil {
+		addIssue("customVMs", err)
+	}
+	if err := c.DataDirCleanup.Validate(); err != nil {
+		addIssue("dataDirCleanup", err)
 	}
+	for i, healthCheck := range c.HealthChecks {
+		if err := healthCheck.Validate(); err != nil {
+			addIssue(fmt.Sprintf("healthChecks[%d]", i), err)
+		}
+	}
+	if c.Faucet != nil {
+		if err := c.Faucet.Validate(); err != nil {
+			addIssue("faucet", fmt.Errorf("faucet config failed validation: %w", err))
+		}
+	}
+	if c.Explorer != nil {
+		if err := c.Explorer.Validate(); err != nil {
+			addIssue("explorer", fmt.Errorf("explorer config failed validation: %w", err))
+		}
+	}
+	if c.Webhooks != nil {
+		if err := c.Webhooks.Validate(); err != nil {
+			addIssue("webhooks", fmt.Errorf("webhooks config failed validation: %w", err))
+		}
+	}
+	if c.Observability != nil {
+		if err := c.Observability.Validate(); err != nil {
+			addIssue("observability", fmt.Errorf("observability config failed validation: %w", err))
+		}
+	}
+	if c.StateSync != nil {
+		if err := c.StateSync.Validate(); err != nil {
+			addIssue("stateSync", err)
+		}
+	}
+	if c.NodeStartDelay < 0 {
+		addIssue("nodeStartDelay", errors.New("negative node start delay given"))
+	}
+	if c.NodeStartParallelism < 0 {
+		addIssue("nodeStartParallelism", errors.New("negative node start parallelism given"))
+	}
+	if c.HealthCheckParallelism < 0 {
+		addIssue("healthCheckParallelism", errors.New("negative health check parallelism given"))
+	}
+
+	var someNodeIsBeacon bool
+	// Node name --> index of the first node config with it
+	nodeNames := make(map[string]int, len(c.NodeConfigs))
+	// Names of the non-validator nodes
+	nonValidators := map[string]struct{}{}
 	for i, nodeConfig := range c.NodeConfigs {
-		if err := nodeConfig.Validate(networkID); err != nil {
-			var nodeName string
-			if len(nodeConfig.Name) > 0 {
-				nodeName = nodeConfig.Name
+		path := fmt.Sprintf("nodeConfigs[%d]", i)
+		for _, issue := range nodeConfig.ValidationIssues(networkID) {
+			addIssue(path+"."+issue.Field, issue.Err)
+		}
+		if nodeConfig.Name != "" {
+			if first, ok := nodeNames[nodeConfig.Name]; ok {
+				addIssue(path+".name", fmt.Errorf("%w: %q is also the name of node config %d", ErrDuplicateNodeName, nodeConfig.Name, first))
 			} else {
-				nodeName = strconv.Itoa(i)
+				nodeNames[nodeConfig.Name] = i
 			}
-			return fmt.Errorf("node %q config failed validation: %w", nodeName, err)
 		}
 		if nodeConfig.IsBeacon {
 			someNodeIsBeacon = true
 		}
+		if nodeConfig.NonValidator {
+			nonValidators[nodeConfig.Name] = struct{}{}
+			if err := CheckNotGenesisValidator(genesisBytes, &nodeConfig); err != nil {
+				addIssue(path+".nonValidator", err)
+			}
+		}
 	}
 	if len(c.NodeConfigs) > 0 && !someNodeIsBeacon {
-		return errors.New("beacon nodes not given")
+		addIssue("nodeConfigs", errors.New("beacon nodes not given"))
+	}
+	if len(c.SubnetSpecs) > 0 {
+		names := make(map[string]struct{}, len(nodeNames))
+		for name := range nodeNames {
+			names[name] = struct{}{}
+		}
+		for i, subnetSpec := range c.SubnetSpecs {
+			if err := subnetSpec.Validate(names, c.CustomVMs); err != nil {
+				addIssue(fmt.Sprintf("subnetSpecs[%d]", i), err)
+			}
+			for j, validator := range subnetSpec.Validators {
+				if _, ok := nonValidators[validator]; ok {
+					addIssue(fmt.Sprintf("subnetSpecs[%d].validators[%d]", i, j), fmt.Errorf("node %q is a non-validator", validator))
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// CheckNotGenesisValidator returns an error if the node of [nodeConfig]
+// is a validator in [genesisBytes]. Returns nil if [genesisBytes] is nil,
+// or can't be parsed, or the node ID can't be computed, which are
+// reported by the rest of the validation.
+func CheckNotGenesisValidator(genesisBytes []byte, nodeConfig *node.Config) error {
+	if genesisBytes == nil {
+		return nil
+	}
+	nodeID, err := utils.ToNodeID([]byte(nodeConfig.StakingKey), []byte(nodeConfig.StakingCert))
+	if err != nil {
+		return nil
+	}
+	var genesisConfig genesis.UnparsedConfig
+	if err := json.Unmarshal(genesisBytes, &genesisConfig); err != nil {
+		return nil
+	}
+	for _, staker := range genesisConfig.InitialStakers {
+		if staker.NodeID == nodeID {
+			return fmt.Errorf("non-validator node %s is a genesis validator", nodeID)
+		}
 	}
 	return nil
 }
@@ -100,6 +338,49 @@ func NewAvalancheGoGenesis(
 	cChainBalances []AddrAndBalance,
 	genesisVdrs []ids.NodeID,
 ) ([]byte, error) {
+	genesisStakers := make([]GenesisStaker, len(genesisVdrs))
+	for i, genesisVdr := range genesisVdrs {
+		genesisStakers[i] = GenesisStaker{
+			NodeID:        genesisVdr,
+			DelegationFee: DefaultGenesisDelegationFee,
+		}
+	}
+	return NewAvalancheGoGenesisWithStakers(networkID, xChainBalances, cChainBalances, genesisStakers)
+}
+
+const (
+	// Delegation fee of genesis validators, unless given.
+	// In units of 1/10,000 of a percent, i.e. 1%.
+	DefaultGenesisDelegationFee = 10_000
+	maxDelegationFee            = 1_000_000
+)
+
+// GenesisStaker is a validator in the genesis
+type GenesisStaker struct {
+	NodeID ids.NodeID
+	// Address staking rewards and delegation fees are sent to.
+	// If empty, a random address shared by all such stakers is used.
+	RewardAddr ids.ShortID
+	// Fee charged to delegators, in units of 1/10,000 of a percent
+	// (e.g. 20,000 is 2%). Must be at most 1,000,000.
+	DelegationFee uint32
+}
+
+// Like NewAvalancheGoGenesis, but the genesis validators,
+// their delegation fees and reward addresses are given by [genesisStakers].
+func NewAvalancheGoGenesisWithStakers(
+	networkID uint32,
+	xChainBalances []AddrAndBalance,
+	cChainBalances []AddrAndBalance,
+	genesisStakers []GenesisStaker,
+) ([]byte, error) {
+	genesisVdrs := make([]ids.NodeID, len(genesisStakers))
+	for i, genesisStaker := range genesisStakers {
+		if genesisStaker.DelegationFee > maxDelegationFee {
+			return nil, fmt.Errorf("delegation fee %d of %s is more than %d", genesisStaker.DelegationFee, genesisStaker.NodeID, maxDelegationFee)
+		}
+		genesisVdrs[i] = genesisStaker.NodeID
+	}
 	switch networkID {
 	case constants.TestnetID, constants.MainnetID, constants.LocalID:
 		return nil, errors.New("network ID can't be mainnet, testnet or local network ID")
@@ -175,15 +456,19 @@ func NewAvalancheGoGenesis(
 	config.CChainGenesis = string(cChainConfigBytes)
 
 	// Set initial validators.
-	// Give staking rewards to random address.
-	rewardAddr, _ := address.Format("X", constants.GetHRP(networkID), ids.GenerateTestShortID().Bytes())
-	for _, genesisVdr := range genesisVdrs {
+	// Unless given, give staking rewards to random address.
+	defaultRewardAddr, _ := address.Format("X", constants.GetHRP(networkID), ids.GenerateTestShortID().Bytes())
+	for _, genesisStaker := range genesisStakers {
+		rewardAddr := defaultRewardAddr
+		if genesisStaker.RewardAddr != ids.ShortEmpty {
+			rewardAddr, _ = address.Format("X", constants.GetHRP(networkID), genesisStaker.RewardAddr.Bytes())
+		}
 		config.InitialStakers = append(
 			config.InitialStakers,
 			genesis.UnparsedStaker{
-				NodeID:        genesisVdr,
+				NodeID:        genesisStaker.NodeID,
 				RewardAddress: rewardAddr,
-				DelegationFee: 10_000,
+				DelegationFee: genesisStaker.DelegationFee,
 			},
 		)
 	}
@@ -2,10 +2,22 @@ package network_test
 
 import (
 	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ava-labs/avalanche-network-runner/network"
 	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanche-network-runner/utils"
+	"github.com/ava-labs/avalanchego/config"
+	avagenesis "github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/staking"
+	"github.com/ava-labs/avalanchego/utils/units"
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -55,3 +67,504 @@ func TestConfigMarshalJSON(t *testing.T) {
 	assert := assert.New(t)
 	assert.EqualValues(control, netcfg)
 }
+
+func TestSetStakingConfig(t *testing.T) {
+	assert := assert.New(t)
+	genesis, err := network.NewAvalancheGoGenesis(
+		1337,
+		[]network.AddrAndBalance{{Addr: ids.GenerateTestShortID(), Balance: units.KiloAvax}},
+		nil,
+		[]ids.NodeID{ids.GenerateTestNodeID(), ids.GenerateTestNodeID()},
+	)
+	assert.NoError(err)
+	netcfg := network.Config{Genesis: string(genesis)}
+	assert.NoError(netcfg.SetStakingConfig(network.FastStakingConfig))
+
+	var genesisConfig avagenesis.UnparsedConfig
+	assert.NoError(json.Unmarshal([]byte(netcfg.Genesis), &genesisConfig))
+	assert.EqualValues(600, genesisConfig.InitialStakeDuration)
+	assert.EqualValues(10, genesisConfig.InitialStakeDurationOffset)
+	assert.Len(genesisConfig.InitialStakers, 2)
+	assert.Equal("1m0s", netcfg.Flags[config.MinStakeDurationKey])
+	assert.Equal("10m0s", netcfg.Flags[config.MaxStakeDurationKey])
+	assert.Equal("10m0s", netcfg.Flags[config.StakeMintingPeriodKey])
+
+	// offset too large for the number of stakers
+	stakingConfig := network.FastStakingConfig
+	stakingConfig.InitialStakeDurationOffset = stakingConfig.InitialStakeDuration
+	stakingConfig.InitialStakeDurationOffset += time.Second
+	assert.Error(netcfg.SetStakingConfig(stakingConfig))
+
+	// minting period below max stake duration
+	stakingConfig = network.FastStakingConfig
+	stakingConfig.StakeMintingPeriod = time.Minute
+	assert.Error(netcfg.SetStakingConfig(stakingConfig))
+}
+
+func TestSetGenesisValidators(t *testing.T) {
+	assert := assert.New(t)
+	nodeConfigs := make([]node.Config, 3)
+	nodeIDs := make([]ids.NodeID, len(nodeConfigs))
+	for i := range nodeConfigs {
+		cert, key, err := staking.NewCertAndKeyBytes()
+		assert.NoError(err)
+		nodeConfigs[i] = node.Config{StakingCert: string(cert), StakingKey: string(key)}
+		nodeIDs[i], err = utils.ToNodeID(key, cert)
+		assert.NoError(err)
+	}
+	genesis, err := network.NewAvalancheGoGenesisWithStakers(
+		1337,
+		[]network.AddrAndBalance{{Addr: ids.GenerateTestShortID(), Balance: units.KiloAvax}},
+		nil,
+		[]network.GenesisStaker{{NodeID: nodeIDs[0], DelegationFee: 20_000}},
+	)
+	assert.NoError(err)
+	netcfg := network.Config{Genesis: string(genesis), NodeConfigs: nodeConfigs}
+
+	assert.NoError(netcfg.SetGenesisValidators(network.GenesisValidatorsConfig{
+		Nodes:                      []int{2, 0},
+		Stake:                      units.Avax,
+		InitialStakeDuration:       time.Hour,
+		InitialStakeDurationOffset: time.Minute,
+	}))
+	var genesisConfig avagenesis.UnparsedConfig
+	assert.NoError(json.Unmarshal([]byte(netcfg.Genesis), &genesisConfig))
+	assert.Len(genesisConfig.InitialStakers, 2)
+	assert.Equal(nodeIDs[2], genesisConfig.InitialStakers[0].NodeID)
+	assert.EqualValues(network.DefaultGenesisDelegationFee, genesisConfig.InitialStakers[0].DelegationFee)
+	// The node that already was a validator keeps its delegation fee
+	assert.Equal(nodeIDs[0], genesisConfig.InitialStakers[1].NodeID)
+	assert.EqualValues(20_000, genesisConfig.InitialStakers[1].DelegationFee)
+	assert.EqualValues(3600, genesisConfig.InitialStakeDuration)
+	assert.EqualValues(60, genesisConfig.InitialStakeDurationOffset)
+	for _, allocation := range genesisConfig.Allocations {
+		if allocation.AVAXAddr == genesisConfig.InitialStakedFunds[0] {
+			assert.Len(allocation.UnlockSchedule, 1)
+			assert.EqualValues(2*units.Avax, allocation.UnlockSchedule[0].Amount)
+		}
+	}
+	parsed, err := genesisConfig.Parse()
+	assert.NoError(err)
+	_, _, err = avagenesis.FromConfig(&parsed)
+	assert.NoError(err)
+
+	assert.Error(netcfg.SetGenesisValidators(network.GenesisValidatorsConfig{}))
+	assert.Error(netcfg.SetGenesisValidators(network.GenesisValidatorsConfig{Nodes: []int{3}}))
+	assert.Error(netcfg.SetGenesisValidators(network.GenesisValidatorsConfig{Nodes: []int{1, 1}}))
+	// offset too large for the number of stakers
+	assert.Error(netcfg.SetGenesisValidators(network.GenesisValidatorsConfig{
+		Nodes:                      []int{0, 1, 2},
+		InitialStakeDuration:       time.Minute,
+		InitialStakeDurationOffset: time.Minute,
+	}))
+}
+
+func TestOverrideCChainGenesis(t *testing.T) {
+	assert := assert.New(t)
+	addr := ids.GenerateTestShortID()
+	genesis, err := network.NewAvalancheGoGenesis(
+		1337,
+		nil,
+		[]network.AddrAndBalance{{Addr: addr, Balance: 1}},
+		[]ids.NodeID{ids.GenerateTestNodeID()},
+	)
+	assert.NoError(err)
+	netcfg := network.Config{Genesis: string(genesis)}
+	newAddr := ids.GenerateTestShortID()
+	assert.NoError(netcfg.OverrideCChainGenesis(network.CChainGenesisOverrides{
+		ChainID: 99999,
+		Balances: []network.AddrAndBalance{
+			{Addr: addr, Balance: 2},
+			{Addr: newAddr, Balance: 3},
+		},
+	}))
+
+	var genesisConfig avagenesis.UnparsedConfig
+	assert.NoError(json.Unmarshal([]byte(netcfg.Genesis), &genesisConfig))
+	var cChainGenesis struct {
+		Config struct {
+			ChainID uint64 `json:"chainId"`
+		} `json:"config"`
+		Alloc map[string]struct {
+			Balance string `json:"balance"`
+		} `json:"alloc"`
+	}
+	assert.NoError(json.Unmarshal([]byte(genesisConfig.CChainGenesis), &cChainGenesis))
+	assert.EqualValues(99999, cChainGenesis.Config.ChainID)
+	assert.Len(cChainGenesis.Alloc, 2)
+	assert.Equal("0x2", cChainGenesis.Alloc["0x"+addr.Hex()].Balance)
+	assert.Equal("0x3", cChainGenesis.Alloc["0x"+newAddr.Hex()].Balance)
+
+	assert.NoError(netcfg.SetCChainGenesis(`{"config":{"chainId":1}}`))
+	assert.NoError(json.Unmarshal([]byte(netcfg.Genesis), &genesisConfig))
+	assert.Equal(`{"config":{"chainId":1}}`, genesisConfig.CChainGenesis)
+	assert.Error(netcfg.SetCChainGenesis("not json"))
+}
+
+func TestParseGenesis(t *testing.T) {
+	assert := assert.New(t)
+	xAddr, cAddr, rewardAddr := ids.GenerateTestShortID(), ids.GenerateTestShortID(), ids.GenerateTestShortID()
+	nodeID := ids.GenerateTestNodeID()
+	genesisBytes, err := network.NewAvalancheGoGenesisWithStakers(
+		1337,
+		[]network.AddrAndBalance{{Addr: xAddr, Balance: units.KiloAvax}},
+		[]network.AddrAndBalance{{Addr: cAddr, Balance: units.Avax}},
+		[]network.GenesisStaker{{NodeID: nodeID, RewardAddr: rewardAddr, DelegationFee: 20_000}},
+	)
+	assert.NoError(err)
+	netcfg := network.Config{Genesis: string(genesisBytes)}
+	assert.NoError(netcfg.OverrideCChainGenesis(network.CChainGenesisOverrides{ChainID: 99999}))
+
+	genesis, err := network.ParseGenesis([]byte(netcfg.Genesis))
+	assert.NoError(err)
+	assert.EqualValues(1337, genesis.NetworkID)
+	assert.Equal([]network.GenesisStaker{{NodeID: nodeID, RewardAddr: rewardAddr, DelegationFee: 20_000}}, genesis.InitialStakers)
+	funded := false
+	for _, allocation := range genesis.Allocations {
+		if allocation.AVAXAddr == xAddr {
+			funded = true
+			assert.EqualValues(units.KiloAvax, allocation.InitialAmount)
+		}
+	}
+	assert.True(funded)
+	assert.Len(genesis.InitialStakedFunds, 1)
+	assert.Positive(genesis.InitialStakeDuration)
+	assert.EqualValues(99999, genesis.CChain.ChainID.Int64())
+	assert.Len(genesis.CChain.Balances, 1)
+	assert.EqualValues(units.Avax, genesis.CChain.Balances[ethcommon.BytesToAddress(cAddr.Bytes())].Uint64())
+	assert.NotEmpty(genesis.CChain.JSON)
+
+	_, err = network.ParseGenesis([]byte("not json"))
+	assert.Error(err)
+	assert.NoError(netcfg.SetCChainGenesis(`{"alloc":{"not an address":{"balance":"0x1"}}}`))
+	_, err = network.ParseGenesis([]byte(netcfg.Genesis))
+	assert.Error(err)
+}
+
+func TestMutatedGenesis(t *testing.T) {
+	assert := assert.New(t)
+	genesis, err := network.NewAvalancheGoGenesis(
+		1337,
+		nil,
+		[]network.AddrAndBalance{{Addr: ids.GenerateTestShortID(), Balance: 1}},
+		[]ids.NodeID{ids.GenerateTestNodeID()},
+	)
+	assert.NoError(err)
+	netcfg := network.Config{
+		Genesis: string(genesis),
+		GenesisMutators: []network.GenesisMutator{
+			network.UnparsedGenesisMutator(func(genesisConfig *avagenesis.UnparsedConfig) error {
+				genesisConfig.Message = "first"
+				return nil
+			}),
+			// Applied after the first mutator
+			network.UnparsedGenesisMutator(func(genesisConfig *avagenesis.UnparsedConfig) error {
+				genesisConfig.Message += ", second"
+				return nil
+			}),
+			network.CChainGenesisMutator(func(cChainGenesis map[string]interface{}) error {
+				cChainGenesis["config"].(map[string]interface{})["chainId"] = 12345
+				return nil
+			}),
+		},
+	}
+	assert.Empty(netcfg.Validate())
+	mutatedGenesis, err := netcfg.MutatedGenesis()
+	assert.NoError(err)
+	// The config's genesis isn't modified
+	assert.Equal(string(genesis), netcfg.Genesis)
+
+	var genesisConfig avagenesis.UnparsedConfig
+	assert.NoError(json.Unmarshal(mutatedGenesis, &genesisConfig))
+	assert.Equal("first, second", genesisConfig.Message)
+	var cChainGenesis struct {
+		Config struct {
+			ChainID uint64 `json:"chainId"`
+		} `json:"config"`
+	}
+	assert.NoError(json.Unmarshal([]byte(genesisConfig.CChainGenesis), &cChainGenesis))
+	assert.EqualValues(12345, cChainGenesis.Config.ChainID)
+
+	netcfg.GenesisMutators = append(netcfg.GenesisMutators, func([]byte) ([]byte, error) {
+		return nil, errors.New("mutator failed")
+	})
+	assert.NotEmpty(netcfg.Validate())
+}
+
+func TestConfigValidationIssues(t *testing.T) {
+	assert := assert.New(t)
+	_, key0, err := staking.NewCertAndKeyBytes()
+	assert.NoError(err)
+	cert1, key1, err := staking.NewCertAndKeyBytes()
+	assert.NoError(err)
+	netcfg := network.Config{
+		Genesis: "{\"networkID\": 0}",
+		NodeConfigs: []node.Config{
+			{
+				Name:        "node",
+				StakingKey:  string(key0),
+				StakingCert: string(cert1),
+				ConfigFile:  "not json",
+			},
+			{
+				Name:        "node",
+				StakingKey:  string(key1),
+				StakingCert: string(cert1),
+			},
+		},
+	}
+	issues := netcfg.Validate()
+	fields := make([]string, len(issues))
+	for i, issue := range issues {
+		fields[i] = issue.Field
+	}
+	assert.Equal([]string{
+		"nodeConfigs[0].stakingCert",
+		"nodeConfigs[0].configFile",
+		"nodeConfigs[1].name",
+		"nodeConfigs",
+	}, fields)
+
+	err = network.NewValidationError(issues)
+	assert.Contains(err.Error(), "4 config issues")
+	assert.ErrorIs(err, network.ErrDuplicateNodeName)
+	assert.False(errors.Is(err, &network.ErrInvalidGenesis{}))
+
+	// Without a genesis, the node configs are still validated
+	netcfg.Genesis = ""
+	issues = netcfg.Validate()
+	assert.Len(issues, 5)
+	err = network.NewValidationError(issues)
+	var genesisErr *network.ErrInvalidGenesis
+	assert.True(errors.As(err, &genesisErr))
+	assert.Equal("no genesis given", genesisErr.Reason)
+
+	assert.NoError(network.NewValidationError(nil))
+}
+
+func TestConfigNonValidators(t *testing.T) {
+	assert := assert.New(t)
+	validatorCert, validatorKey, err := staking.NewCertAndKeyBytes()
+	assert.NoError(err)
+	validatorID, err := utils.ToNodeID(validatorKey, validatorCert)
+	assert.NoError(err)
+	apiCert, apiKey, err := staking.NewCertAndKeyBytes()
+	assert.NoError(err)
+	genesis, err := network.NewAvalancheGoGenesis(
+		1337,
+		[]network.AddrAndBalance{{Addr: ids.GenerateTestShortID(), Balance: units.KiloAvax}},
+		nil,
+		[]ids.NodeID{validatorID},
+	)
+	assert.NoError(err)
+	netcfg := network.Config{
+		Genesis: string(genesis),
+		NodeConfigs: []node.Config{
+			{
+				Name:        "validator",
+				IsBeacon:    true,
+				StakingKey:  string(validatorKey),
+				StakingCert: string(validatorCert),
+			},
+			{
+				Name:         "api",
+				NonValidator: true,
+				StakingKey:   string(apiKey),
+				StakingCert:  string(apiCert),
+			},
+		},
+	}
+	assert.Empty(netcfg.Validate())
+
+	// A non-validator can't be a beacon or a subnet validator
+	netcfg.NodeConfigs[1].IsBeacon = true
+	netcfg.SubnetSpecs = []network.SubnetSpec{{Validators: []string{"validator", "api"}}}
+	fields := []string{}
+	for _, issue := range netcfg.Validate() {
+		fields = append(fields, issue.Field)
+	}
+	assert.Equal([]string{"nodeConfigs[1].isBeacon", "subnetSpecs[0].validators[1]"}, fields)
+
+	// A non-validator can't be a genesis validator
+	netcfg.NodeConfigs[1].IsBeacon = false
+	netcfg.SubnetSpecs = nil
+	netcfg.NodeConfigs[0].NonValidator = true
+	netcfg.NodeConfigs[0].IsBeacon = false
+	netcfg.NodeConfigs[1].IsBeacon = true
+	netcfg.NodeConfigs[1].NonValidator = false
+	issues := netcfg.Validate()
+	if assert.Len(issues, 1) {
+		assert.Equal("nodeConfigs[0].nonValidator", issues[0].Field)
+	}
+}
+
+func TestNewAvalancheGoGenesisWithStakers(t *testing.T) {
+	assert := assert.New(t)
+	rewardAddr := ids.GenerateTestShortID()
+	stakers := []network.GenesisStaker{
+		{
+			NodeID:        ids.GenerateTestNodeID(),
+			RewardAddr:    rewardAddr,
+			DelegationFee: 20_000,
+		},
+		{
+			NodeID:        ids.GenerateTestNodeID(),
+			DelegationFee: 0,
+		},
+	}
+	genesis, err := network.NewAvalancheGoGenesisWithStakers(
+		1337,
+		nil,
+		[]network.AddrAndBalance{{Addr: ids.GenerateTestShortID(), Balance: 1}},
+		stakers,
+	)
+	assert.NoError(err)
+
+	var genesisConfig avagenesis.UnparsedConfig
+	assert.NoError(json.Unmarshal(genesis, &genesisConfig))
+	parsedGenesisConfig, err := genesisConfig.Parse()
+	assert.NoError(err)
+	assert.Len(parsedGenesisConfig.InitialStakers, 2)
+	assert.Equal(stakers[0].NodeID, parsedGenesisConfig.InitialStakers[0].NodeID)
+	assert.Equal(rewardAddr, parsedGenesisConfig.InitialStakers[0].RewardAddress)
+	assert.EqualValues(20_000, parsedGenesisConfig.InitialStakers[0].DelegationFee)
+	assert.Equal(stakers[1].NodeID, parsedGenesisConfig.InitialStakers[1].NodeID)
+	assert.NotEqual(rewardAddr, parsedGenesisConfig.InitialStakers[1].RewardAddress)
+	assert.EqualValues(0, parsedGenesisConfig.InitialStakers[1].DelegationFee)
+
+	// delegation fee above 100%
+	stakers[0].DelegationFee = 1_000_001
+	_, err = network.NewAvalancheGoGenesisWithStakers(
+		1337,
+		nil,
+		[]network.AddrAndBalance{{Addr: ids.GenerateTestShortID(), Balance: 1}},
+		stakers,
+	)
+	assert.Error(err)
+}
+
+func TestSubnetSpecValidate(t *testing.T) {
+	nodeNames := map[string]struct{}{"node1": {}, "node2": {}}
+	blockchain := network.BlockchainSpec{VMName: "subnetevm", Genesis: "{}"}
+	tests := []struct {
+		name      string
+		spec      network.SubnetSpec
+		expectErr bool
+	}{
+		{
+			name: "valid",
+			spec: network.SubnetSpec{
+				Validators:  []string{"node1", "node2"},
+				Blockchains: []network.BlockchainSpec{blockchain},
+			},
+		},
+		{
+			name:      "no validators",
+			spec:      network.SubnetSpec{Blockchains: []network.BlockchainSpec{blockchain}},
+			expectErr: true,
+		},
+		{
+			name:      "unknown validator",
+			spec:      network.SubnetSpec{Validators: []string{"node3"}},
+			expectErr: true,
+		},
+		{
+			name:      "repeated validator",
+			spec:      network.SubnetSpec{Validators: []string{"node1", "node1"}},
+			expectErr: true,
+		},
+		{
+			name: "no genesis",
+			spec: network.SubnetSpec{
+				Validators:  []string{"node1"},
+				Blockchains: []network.BlockchainSpec{{VMName: "subnetevm"}},
+			},
+			expectErr: true,
+		},
+		{
+			name: "VM name too long",
+			spec: network.SubnetSpec{
+				Validators:  []string{"node1"},
+				Blockchains: []network.BlockchainSpec{{VMName: strings.Repeat("a", 33), Genesis: "{}"}},
+			},
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate(nodeNames, nil)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCustomVMValidate(t *testing.T) {
+	assert := assert.New(t)
+	binaryPath := filepath.Join(t.TempDir(), "vm")
+	assert.NoError(os.WriteFile(binaryPath, []byte{}, 0o700))
+	notExecutablePath := filepath.Join(t.TempDir(), "vm")
+	assert.NoError(os.WriteFile(notExecutablePath, []byte{}, 0o600))
+	vmID := ids.GenerateTestID()
+
+	vm := network.CustomVM{Name: "vm", BinaryPath: binaryPath}
+	assert.NoError(vm.Validate())
+	derivedID, err := vm.VMID()
+	assert.NoError(err)
+	vm.ID = vmID.String()
+	assert.NoError(vm.Validate())
+	gotID, err := vm.VMID()
+	assert.NoError(err)
+	assert.Equal(vmID, gotID)
+
+	// Blockchain specs refer to custom VMs by name
+	gotID, err = network.VMIDFromName("vm", []network.CustomVM{vm})
+	assert.NoError(err)
+	assert.Equal(vmID, gotID)
+	gotID, err = network.VMIDFromName("vm", nil)
+	assert.NoError(err)
+	assert.Equal(derivedID, gotID)
+
+	for _, invalid := range []network.CustomVM{
+		{BinaryPath: binaryPath},
+		{Name: "vm"},
+		{Name: "vm", ID: "not an ID", BinaryPath: binaryPath},
+		{Name: strings.Repeat("a", 33), BinaryPath: binaryPath},
+		{Name: "vm", BinaryPath: filepath.Join(t.TempDir(), "missing")},
+		{Name: "vm", BinaryPath: t.TempDir()},
+		{Name: "vm", BinaryPath: notExecutablePath},
+	} {
+		assert.Error(invalid.Validate(), "%+v", invalid)
+	}
+}
+
+func TestStateSyncConfig(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError((&network.StateSyncConfig{}).Validate())
+	assert.Error((&network.StateSyncConfig{CommitInterval: 16, SummaryInterval: 40}).Validate())
+
+	// The C-Chain config file's other entries are preserved
+	nodeConfig := node.Config{CChainConfigFile: `{"log-level":"debug"}`}
+	stateSync := network.StateSyncConfig{CommitInterval: 8}
+	assert.NoError(stateSync.Apply(&nodeConfig, false))
+	var cChainConfig map[string]interface{}
+	assert.NoError(json.Unmarshal([]byte(nodeConfig.CChainConfigFile), &cChainConfig))
+	assert.Equal(map[string]interface{}{
+		"log-level":                  "debug",
+		"commit-interval":            8.0,
+		"state-sync-commit-interval": 32.0,
+	}, cChainConfig)
+
+	// A C-Chain config in ChainConfigFiles is merged into there
+	chainConfigFiles := map[string]string{node.CChainAlias: `{"log-level":"debug"}`}
+	nodeConfig = node.Config{ChainConfigFiles: chainConfigFiles}
+	assert.NoError(stateSync.Apply(&nodeConfig, true))
+	assert.Empty(nodeConfig.CChainConfigFile)
+	assert.Equal(`{"log-level":"debug"}`, chainConfigFiles[node.CChainAlias])
+	assert.NoError(json.Unmarshal([]byte(nodeConfig.ChainConfigFiles[node.CChainAlias]), &cChainConfig))
+	assert.Equal(true, cChainConfig["state-sync-enabled"])
+	assert.Equal(32.0, cChainConfig["state-sync-min-blocks"])
+}
@@ -0,0 +1,231 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// Returned, wrapped with the node's name, by operations
+	// given the name of a node that isn't in the network
+	ErrNodeNotFound = errors.New("node not found")
+	// Returned, wrapped with the node's name, when a node is added
+	// with the name of a node that's already in the network
+	ErrDuplicateNodeName = errors.New("duplicate node name")
+	// Returned, wrapped with the node's name, when a node
+	// rejected a tx awaited by AwaitTxAccepted
+	ErrTxRejected = errors.New("tx rejected")
+)
+
+// ErrInvalidGenesis is returned when a network's genesis is invalid.
+// errors.Is(err, &ErrInvalidGenesis{}) is true for any such error.
+type ErrInvalidGenesis struct {
+	// Why the genesis is invalid
+	Reason string
+	// Underlying error, if any
+	Err error
+}
+
+// NewErrInvalidGenesis returns an ErrInvalidGenesis whose
+// reason is [err]'s message, or nil if [err] is nil
+func NewErrInvalidGenesis(err error) error {
+	if err == nil {
+		return nil
+	}
+	var invalidGenesisErr *ErrInvalidGenesis
+	if errors.As(err, &invalidGenesisErr) {
+		return err
+	}
+	return &ErrInvalidGenesis{Reason: err.Error(), Err: err}
+}
+
+func (e *ErrInvalidGenesis) Error() string {
+	return "invalid genesis: " + e.Reason
+}
+
+func (e *ErrInvalidGenesis) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true if [target] is an *ErrInvalidGenesis
+// whose reason is empty or the same as [e]'s
+func (e *ErrInvalidGenesis) Is(target error) bool {
+	t, ok := target.(*ErrInvalidGenesis)
+	return ok && (t.Reason == "" || t.Reason == e.Reason)
+}
+
+// ErrPortInUse is returned when a node is given a port
+// that's already bound, by another node or process.
+// errors.Is(err, &ErrPortInUse{}) is true for any such error.
+type ErrPortInUse struct {
+	Port uint16
+}
+
+func (e *ErrPortInUse) Error() string {
+	return fmt.Sprintf("port %d is in use", e.Port)
+}
+
+// Is returns true if [target] is an *ErrPortInUse
+// whose port is 0 or the same as [e]'s
+func (e *ErrPortInUse) Is(target error) bool {
+	t, ok := target.(*ErrPortInUse)
+	return ok && (t.Port == 0 || t.Port == e.Port)
+}
+
+// UnhealthyError is returned when a node doesn't become healthy in time.
+// It has the checks that were failing the last time the node's
+// Health API was queried. errors.Is(err, &UnhealthyError{}) is
+// true for any such error.
+type UnhealthyError struct {
+	NodeName string
+	// Name of each failing check --> how it failed
+	Checks map[string]HealthCheckFailure
+	// Why the node is unhealthy when no check is failing, e.g.
+	// its Health API couldn't be queried or it lacks peers
+	Reason string
+}
+
+// HealthCheckFailure is a failing check of a node's Health API
+type HealthCheckFailure struct {
+	Error string `json:"error"`
+	// Details reported by the check, if any
+	Details interface{} `json:"details,omitempty"`
+	// How many times in a row the check failed
+	ContiguousFailures int64 `json:"contiguousFailures,omitempty"`
+}
+
+func (e *UnhealthyError) Error() string {
+	msg := fmt.Sprintf("node %q failed to become healthy within timeout, or network stopped", e.NodeName)
+	if len(e.Checks) > 0 {
+		checkNames := make([]string, 0, len(e.Checks))
+		for checkName := range e.Checks {
+			checkNames = append(checkNames, checkName)
+		}
+		sort.Strings(checkNames)
+		failures := make([]string, len(checkNames))
+		for i, checkName := range checkNames {
+			failures[i] = fmt.Sprintf("%s: %s", checkName, e.Checks[checkName].Error)
+		}
+		return fmt.Sprintf("%s: failing checks: %s", msg, strings.Join(failures, "; "))
+	}
+	if e.Reason != "" {
+		return fmt.Sprintf("%s: %s", msg, e.Reason)
+	}
+	return msg
+}
+
+// Is returns true if [target] is an *UnhealthyError
+// whose node name is empty or the same as [e]'s
+func (e *UnhealthyError) Is(target error) bool {
+	t, ok := target.(*UnhealthyError)
+	return ok && (t.NodeName == "" || t.NodeName == e.NodeName)
+}
+
+// ValidationError is returned when a network config is invalid.
+// errors.Is and errors.As match the error of any of its issues.
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+// NewValidationError returns a *ValidationError with
+// [issues], or nil if [issues] is empty
+func NewValidationError(issues []ValidationIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: issues}
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Issues) == 1 {
+		return e.Issues[0].Error()
+	}
+	issues := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		issues[i] = issue.Error()
+	}
+	return fmt.Sprintf("%d config issues: %s", len(e.Issues), strings.Join(issues, "; "))
+}
+
+// Is returns true if the error of one of [e]'s issues is [target]
+func (e *ValidationError) Is(target error) bool {
+	for _, issue := range e.Issues {
+		if errors.Is(issue.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As sets [target] to the error of the first of [e]'s issues
+// that can be assigned to it, and returns whether there was one
+func (e *ValidationError) As(target interface{}) bool {
+	for _, issue := range e.Issues {
+		if errors.As(issue.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// NodesError is returned by operations on several nodes at once,
+// such as starting or stopping a network, when some of them failed.
+// errors.Is and errors.As match the error of any of its nodes.
+type NodesError struct {
+	// Name of each node that failed --> why
+	Errs map[string]error
+}
+
+// NewNodesError returns a *NodesError with [errs],
+// or nil if [errs] is empty
+func NewNodesError(errs map[string]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &NodesError{Errs: errs}
+}
+
+// Returns the names of the nodes that failed, sorted
+func (e *NodesError) nodeNames() []string {
+	nodeNames := make([]string, 0, len(e.Errs))
+	for nodeName := range e.Errs {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	return nodeNames
+}
+
+func (e *NodesError) Error() string {
+	nodeNames := e.nodeNames()
+	if len(nodeNames) == 1 {
+		return fmt.Sprintf("node %q: %s", nodeNames[0], e.Errs[nodeNames[0]])
+	}
+	errs := make([]string, len(nodeNames))
+	for i, nodeName := range nodeNames {
+		errs[i] = fmt.Sprintf("%s: %s", nodeName, e.Errs[nodeName])
+	}
+	return fmt.Sprintf("%d nodes failed: %s", len(nodeNames), strings.Join(errs, "; "))
+}
+
+// Is returns true if the error of one of [e]'s nodes is [target]
+func (e *NodesError) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As sets [target] to the error of the first of [e]'s nodes, by name,
+// that can be assigned to it, and returns whether there was one
+func (e *NodesError) As(target interface{}) bool {
+	for _, nodeName := range e.nodeNames() {
+		if errors.As(e.Errs[nodeName], target) {
+			return true
+		}
+	}
+	return false
+}
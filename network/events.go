@@ -0,0 +1,32 @@
+package network
+
+import (
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// Index is an index of containers accepted by a node, served by
+// avalanchego's Index API when the node is run with indexing enabled
+type Index string
+
+const (
+	IndexPChainBlocks   Index = "P/block"
+	IndexCChainBlocks   Index = "C/block"
+	IndexXChainTxs      Index = "X/tx"
+	IndexXChainVertices Index = "X/vtx"
+)
+
+// AcceptedEvent is a container (block, tx or vertex) accepted by a node
+type AcceptedEvent struct {
+	// Name of the node that accepted the container
+	Node string `json:"node"`
+	// Index the container was read from
+	Index Index `json:"index"`
+	// Position of the container in the node's index
+	Position uint64 `json:"position"`
+	// ID of the container
+	ContainerID ids.ID `json:"containerID"`
+	// When the node accepted the container
+	Timestamp time.Time `json:"timestamp"`
+}
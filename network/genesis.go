@@ -0,0 +1,192 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/ids"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// Genesis is the genesis of a network, parsed. See ParseGenesis.
+type Genesis struct {
+	NetworkID uint32
+	// AVAX allocated at genesis
+	Allocations []GenesisAllocation
+	// Start of the staking periods of the genesis validators
+	StartTime time.Time
+	// How long the first genesis validator stakes for
+	InitialStakeDuration time.Duration
+	// Offset between the end of staking of consecutive genesis validators
+	InitialStakeDurationOffset time.Duration
+	// Addresses whose locked allocations are staked
+	// by the genesis validators
+	InitialStakedFunds []ids.ShortID
+	// Genesis validators
+	InitialStakers []GenesisStaker
+	// C-Chain genesis, parsed
+	CChain  CChainGenesis
+	Message string
+}
+
+// GenesisAllocation is an allocation of AVAX in a network's genesis
+type GenesisAllocation struct {
+	// Address the AVAX are allocated to, on the X-Chain and P-Chain
+	AVAXAddr ids.ShortID
+	ETHAddr  ids.ShortID
+	// AVAX allocated on the X-Chain, in nAVAX
+	InitialAmount uint64
+	// AVAX allocated on the P-Chain, in nAVAX, locked until
+	// the given times (Unix seconds), if any
+	UnlockSchedule []genesis.LockedAmount
+}
+
+// CChainGenesis holds the fields of a C-Chain genesis
+// that tests commonly need
+type CChainGenesis struct {
+	// EVM chain ID. Nil if not given.
+	ChainID *big.Int
+	// Address --> balance in wei, of the pre-funded accounts
+	Balances map[ethcommon.Address]*big.Int
+	// The whole C-Chain genesis, as JSON
+	JSON string
+}
+
+// ParseGenesis parses the avalanchego genesis [genesisBytes], such as
+// Config.Genesis, including the C-Chain genesis nested in it.
+func ParseGenesis(genesisBytes []byte) (*Genesis, error) {
+	var unparsed genesis.UnparsedConfig
+	if err := json.Unmarshal(genesisBytes, &unparsed); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal genesis: %w", err)
+	}
+	parsed, err := unparsed.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse genesis: %w", err)
+	}
+	g := &Genesis{
+		NetworkID:                  parsed.NetworkID,
+		Allocations:                make([]GenesisAllocation, len(parsed.Allocations)),
+		StartTime:                  time.Unix(int64(parsed.StartTime), 0),
+		InitialStakeDuration:       time.Duration(parsed.InitialStakeDuration) * time.Second,
+		InitialStakeDurationOffset: time.Duration(parsed.InitialStakeDurationOffset) * time.Second,
+		InitialStakedFunds:         parsed.InitialStakedFunds,
+		InitialStakers:             make([]GenesisStaker, len(parsed.InitialStakers)),
+		Message:                    parsed.Message,
+	}
+	for i, allocation := range parsed.Allocations {
+		g.Allocations[i] = GenesisAllocation{
+			AVAXAddr:       allocation.AVAXAddr,
+			ETHAddr:        allocation.ETHAddr,
+			InitialAmount:  allocation.InitialAmount,
+			UnlockSchedule: allocation.UnlockSchedule,
+		}
+	}
+	for i, staker := range parsed.InitialStakers {
+		g.InitialStakers[i] = GenesisStaker{
+			NodeID:        staker.NodeID,
+			RewardAddr:    staker.RewardAddress,
+			DelegationFee: staker.DelegationFee,
+		}
+	}
+	g.CChain, err = parseCChainGenesis(parsed.CChainGenesis)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func parseCChainGenesis(cChainGenesisJSON string) (CChainGenesis, error) {
+	var cChainGenesis struct {
+		Config struct {
+			ChainID *big.Int `json:"chainId"`
+		} `json:"config"`
+		Alloc map[string]struct {
+			Balance *math.HexOrDecimal256 `json:"balance"`
+		} `json:"alloc"`
+	}
+	if err := json.Unmarshal([]byte(cChainGenesisJSON), &cChainGenesis); err != nil {
+		return CChainGenesis{}, fmt.Errorf("couldn't unmarshal C-Chain genesis: %w", err)
+	}
+	parsed := CChainGenesis{
+		ChainID:  cChainGenesis.Config.ChainID,
+		Balances: make(map[ethcommon.Address]*big.Int, len(cChainGenesis.Alloc)),
+		JSON:     cChainGenesisJSON,
+	}
+	for addr, account := range cChainGenesis.Alloc {
+		if !ethcommon.IsHexAddress(addr) {
+			return CChainGenesis{}, fmt.Errorf("invalid C-Chain genesis address %q", addr)
+		}
+		balance := new(big.Int)
+		if account.Balance != nil {
+			balance = (*big.Int)(account.Balance)
+		}
+		parsed.Balances[ethcommon.HexToAddress(addr)] = balance
+	}
+	return parsed, nil
+}
+
+// GenesisMutator returns a modified copy of a network's genesis.
+// See Config.GenesisMutators.
+type GenesisMutator func(genesis []byte) ([]byte, error)
+
+// UnparsedGenesisMutator returns a GenesisMutator that
+// modifies the parsed genesis with [f].
+func UnparsedGenesisMutator(f func(*genesis.UnparsedConfig) error) GenesisMutator {
+	return func(genesisBytes []byte) ([]byte, error) {
+		return updateGenesis(genesisBytes, f)
+	}
+}
+
+// CChainGenesisMutator returns a GenesisMutator that modifies the C-Chain
+// genesis, which is nested in the genesis as a string, with [f].
+// Numbers in the C-Chain genesis given to [f] are json.Number.
+func CChainGenesisMutator(f func(cChainGenesis map[string]interface{}) error) GenesisMutator {
+	return UnparsedGenesisMutator(func(genesisConfig *genesis.UnparsedConfig) error {
+		var cChainGenesis map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader([]byte(genesisConfig.CChainGenesis)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&cChainGenesis); err != nil {
+			return fmt.Errorf("couldn't unmarshal C-Chain genesis: %w", err)
+		}
+		if err := f(cChainGenesis); err != nil {
+			return err
+		}
+		cChainGenesisBytes, err := json.Marshal(cChainGenesis)
+		if err != nil {
+			return err
+		}
+		genesisConfig.CChainGenesis = string(cChainGenesisBytes)
+		return nil
+	})
+}
+
+// MutatedGenesis returns [c.Genesis] after applying
+// [c.GenesisMutators] to it, in order.
+func (c *Config) MutatedGenesis() ([]byte, error) {
+	genesisBytes := []byte(c.Genesis)
+	for i, mutator := range c.GenesisMutators {
+		var err error
+		genesisBytes, err = mutator(genesisBytes)
+		if err != nil {
+			return nil, fmt.Errorf("genesis mutator %d failed: %w", i, err)
+		}
+	}
+	return genesisBytes, nil
+}
+
+// updateGenesis returns a copy of [genesisBytes] modified by [f].
+func updateGenesis(genesisBytes []byte, f func(*genesis.UnparsedConfig) error) ([]byte, error) {
+	var genesisConfig genesis.UnparsedConfig
+	if err := json.Unmarshal(genesisBytes, &genesisConfig); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal genesis: %w", err)
+	}
+	if err := f(&genesisConfig); err != nil {
+		return nil, err
+	}
+	return json.Marshal(genesisConfig)
+}
@@ -0,0 +1,50 @@
+package network
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+)
+
+// HealthCheck is a condition nodes must meet, on top of their Health
+// API reporting healthy, for Network.Healthy to return, e.g. that an
+// RPC call of a VM returns some value, or that a metric is above a
+// threshold
+type HealthCheck struct {
+	// Name of the check, which reports its failures in UnhealthyError.
+	// Must not be empty.
+	Name string
+	// Names of the nodes the check applies to.
+	// If empty, the check applies to every node.
+	NodeNames []string
+	// Returns nil if [node] meets the condition.
+	// Called until it does, or Healthy's context is done. Must not call
+	// the methods of the Network, which may be waiting for Healthy.
+	// A panic is reported as a failure. Must not be nil.
+	Check func(ctx context.Context, node node.Node) error
+}
+
+// Validate returns an error if this health check is invalid
+func (c *HealthCheck) Validate() error {
+	switch {
+	case c.Name == "":
+		return errors.New("no name given")
+	case c.Check == nil:
+		return errors.New("no check func given")
+	}
+	return nil
+}
+
+// AppliesTo returns true if this health check applies to the node [nodeName]
+func (c *HealthCheck) AppliesTo(nodeName string) bool {
+	if len(c.NodeNames) == 0 {
+		return true
+	}
+	for _, name := range c.NodeNames {
+		if name == nodeName {
+			return true
+		}
+	}
+	return false
+}
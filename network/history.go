@@ -0,0 +1,55 @@
+package network
+
+import "time"
+
+// Names of the operations recorded in a network's history
+const (
+	OpLoadConfig          = "LoadConfig"
+	OpLoadSnapshot        = "LoadSnapshot"
+	OpHealthy             = "Healthy"
+	OpStop                = "Stop"
+	OpAddNode             = "AddNode"
+	OpAddNodeFromTemplate = "AddNodeFromTemplate"
+	OpRemoveNode          = "RemoveNode"
+	OpSaveSnapshot        = "SaveSnapshot"
+	OpRemoveSnapshot      = "RemoveSnapshot"
+	OpUpdateFlags         = "UpdateNodeFlags"
+	OpSetSubnetWhitelist  = "SetSubnetWhitelist"
+	OpPauseNode           = "PauseNode"
+	OpResumeNode          = "ResumeNode"
+	OpPause               = "Pause"
+	OpResume              = "Resume"
+	OpRestart             = "Restart"
+	OpUpgradeNodes        = "UpgradeNodes"
+	OpUpgradeVM           = "UpgradeVM"
+	OpBootstrapped        = "AwaitBootstrapped"
+	OpRefreshBeacons      = "RefreshBeacons"
+	OpCollectArtifacts    = "CollectArtifacts"
+	OpAwaitFullMesh       = "AwaitFullMesh"
+	OpAwaitStateSync      = "AwaitStateSync"
+	OpClone               = "Clone"
+	OpStopAtHeight        = "StopAtHeight"
+	OpAwaitTxAccepted     = "AwaitTxAccepted"
+	OpRotateStakingKey    = "RotateStakingKey"
+	OpSignalNode          = "SignalNode"
+)
+
+// Operation is a record of an operation done on a network
+type Operation struct {
+	// Name of the operation (e.g. OpAddNode)
+	Name string `json:"name"`
+	// What the operation was done on (e.g. a node or snapshot name).
+	// May be empty.
+	Target string `json:"target,omitempty"`
+	// When the operation started
+	Start time.Time `json:"start"`
+	// How long the operation took
+	Duration time.Duration `json:"duration"`
+	// Error returned by the operation, if any
+	Err string `json:"error,omitempty"`
+}
+
+// Succeeded returns true if the operation didn't return an error
+func (op Operation) Succeeded() bool {
+	return op.Err == ""
+}
@@ -0,0 +1,42 @@
+package network
+
+import (
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// NodeEvent describes the node a lifecycle hook is called for
+type NodeEvent struct {
+	Name    string
+	NodeID  ids.NodeID
+	APIPort uint16
+	P2PPort uint16
+	// ID of the node's process. 0 if not known.
+	PID int
+	// Error the node's process exited with, if any.
+	// Only set for OnNodeStopped and OnNodeCrashed.
+	Err error
+	// How the node's process exited.
+	// Only set for OnNodeStopped and OnNodeCrashed.
+	Exit *node.ExitStatus
+}
+
+// Hooks are called on the lifecycle events of a network and its nodes,
+// e.g. to register cleanup or metrics code. Nil hooks aren't called.
+// Hooks may be called with the network's lock held, so they must not
+// call the network's methods, and should return quickly.
+type Hooks struct {
+	// Called once a node's process is started, including on restarts
+	OnNodeStarted func(NodeEvent)
+	// Called the first time a node's process is seen healthy,
+	// e.g. by Network.Healthy
+	OnNodeHealthy func(NodeEvent)
+	// Called once a node removed from the network (e.g. by
+	// Network.RemoveNode, restarts or Network.Stop) has exited
+	OnNodeStopped func(NodeEvent)
+	// Called once a node's process exits without
+	// having been removed from the network
+	OnNodeCrashed func(NodeEvent)
+	// Called once Network.Stop has stopped every node
+	OnNetworkStopped func()
+}
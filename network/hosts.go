@@ -0,0 +1,17 @@
+package network
+
+import "net"
+
+// Domain of the host names nodes are registered under, unless given
+const DefaultHostsDomain = "avax.local"
+
+// HostsRegistry resolves the host names of a network's nodes,
+// e.g. by writing them to a hosts file (see hosts.File).
+// A node is registered as <node name>.<domain> (e.g. node0.avax.local)
+// before it starts, and unregistered once it's removed.
+type HostsRegistry interface {
+	// Resolve [hostname] to [ip], replacing any previous IP
+	Register(hostname string, ip net.IP) error
+	// Stop resolving [hostname]
+	Unregister(hostname string) error
+}
@@ -0,0 +1,36 @@
+package network
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+// LogFilter selects the log lines returned by Network.AggregatedLogs
+type LogFilter struct {
+	// If non-empty, only lines from these nodes are returned
+	Nodes []string
+	// If non-zero, lines less severe than this are dropped
+	MinLevel logging.Level
+	// If non-nil, only lines matching this are returned
+	Regex *regexp.Regexp
+	// If true, keep returning lines as they are written, until
+	// the context is cancelled. Otherwise, stop at the end of the logs.
+	Follow bool
+}
+
+// LogLine is a line of a node's logs
+type LogLine struct {
+	// Name of the node that wrote this line
+	Node string
+	// Name of the log file this line is in (e.g. "C.log")
+	File string
+	// When this line was written.
+	// Lines that aren't log entries (e.g. stack traces) have
+	// the timestamp and level of the entry they follow.
+	Timestamp time.Time
+	Level     logging.Level
+	// The line, as written in the log file
+	Line string
+}
@@ -3,38 +3,222 @@ package network
 import (
 	"context"
 	"errors"
+	"io"
+	"os"
+	"time"
 
+	"github.com/ava-labs/avalanche-network-runner/api"
 	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanche-network-runner/pkg/observability"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/network/peer"
+	"github.com/ava-labs/avalanchego/snow/networking/router"
 )
 
 var ErrUndefined = errors.New("undefined network")
 var ErrStopped = errors.New("network stopped")
 
+// RestartOptions are applied to every node when the network is restarted
+type RestartOptions struct {
+	// If non-empty, the nodes run this binary after the restart
+	BinaryPath string
+	// Applied on top of each node's flags, taking precedence over
+	// its config file. A nil value removes the flag.
+	Flags map[string]interface{}
+}
+
+// RotateStakingKeyOptions configures Network.RotateStakingKey
+type RotateStakingKeyOptions struct {
+	// If true, once the network is healthy, the node is added as a
+	// validator of the primary network with its new node ID, with the
+	// stake, end time and delegation fee of its old node ID, which must
+	// be a current validator. The stake comes from genesis.EWOQKey.
+	AddValidator bool
+}
+
+// UpgradeOptions configures a rolling upgrade of a network's nodes
+type UpgradeOptions struct {
+	// Names of the nodes to upgrade, in the order they're upgraded.
+	// If empty, all the nodes are upgraded, in name order.
+	NodeNames []string
+	// Applied on top of each upgraded node's flags, taking precedence
+	// over its config file. A nil value removes the flag.
+	Flags map[string]interface{}
+	// If non-zero, the max time for each upgraded node to
+	// become healthy and bootstrapped
+	NodeTimeout time.Duration
+}
+
 // Network is an abstraction of an Avalanche network
 type Network interface {
 	// Returns nil if all the nodes in the network are healthy.
 	// A stopped network is considered unhealthy.
 	// Timeout is given by the context parameter.
 	Healthy(context.Context) error
+	// Returns nil once every node reports, through its Info API, that it
+	// finished bootstrapping each chain in [chains] (IDs or aliases, e.g.
+	// "P", "X", "C"). A chain of a subnet created from the network's
+	// config is only awaited on the subnet's validators. If [chains] is
+	// empty, the P, X and C chains, and the chains of the subnets created
+	// from the config, are awaited. A node can report healthy before its
+	// subnets' chains are bootstrapped.
+	// Returns ErrStopped if Stop() was previously called.
+	AwaitBootstrapped(ctx context.Context, chains []string) error
+	// Wait until every node is connected to every other node.
+	// Timeout is given by the context parameter.
+	// Returns ErrStopped if Stop() was previously called.
+	AwaitFullMesh(ctx context.Context) error
+	// Waits until every node has accepted the tx [txID] of the chain
+	// [chainAlias] ("X", "P", "C" or the alias of an EVM chain), for at
+	// most [timeout] if positive. On the C-Chain, [txID] is the ID of an
+	// atomic tx or the hash of an Ethereum tx. If the tx isn't accepted
+	// everywhere in time, the error lists the nodes lagging behind.
+	// Returns an error wrapping ErrTxRejected as soon as a node rejects
+	// or drops the tx.
+	// Returns ErrStopped if Stop() was previously called.
+	AwaitTxAccepted(ctx context.Context, chainAlias string, txID ids.ID, timeout time.Duration) error
 	// Stop all the nodes.
 	// Returns ErrStopped if Stop() was previously called.
 	Stop(context.Context) error
+	// Waits until every node has accepted block [height] of the chain
+	// [chainAlias] (e.g. "C", "P" or the alias of an EVM chain), freezing
+	// each node as soon as it does, then stops the network, killing the
+	// frozen nodes without resuming them, so that their databases are at
+	// the same height, e.g. to snapshot or analyze them.
+	// Returns the height each node was last seen at, which may be above
+	// [height] if blocks are accepted faster than nodes are polled.
+	// If [ctx] is done first, the frozen nodes are resumed.
+	// The X-Chain, a DAG, has no height.
+	// Returns ErrStopped if Stop() was previously called.
+	StopAtHeight(ctx context.Context, chainAlias string, height uint64) (map[string]uint64, error)
 	// Start a new node with the given config.
 	// Returns ErrStopped if Stop() was previously called.
 	AddNode(node.Config) (node.Node, error)
+	// Return the nodes that nodes added to the network bootstrap from,
+	// sorted by name. When the last of them is removed, the next node
+	// added promotes a running node to beacon.
+	// Returns ErrStopped if Stop() was previously called.
+	GetBootstrapBeacons() ([]Beacon, error)
+	// Restart the nodes started with bootstrap beacons that were since
+	// removed, so that they bootstrap from the current beacons.
+	// Returns the names of the restarted nodes.
+	// Returns ErrStopped if Stop() was previously called.
+	RefreshBeacons(ctx context.Context) ([]string, error)
+	// Return the URL of the network's faucet, which requests to fund an
+	// address are POSTed to (see faucet.Request), or an error if the
+	// network's config has no faucet.
+	// Returns ErrStopped if Stop() was previously called.
+	GetFaucetURL() (string, error)
+	// Return the URLs of the network's Prometheus and Grafana,
+	// or an error if the network's config has no observability.
+	// Returns ErrStopped if Stop() was previously called.
+	GetObservabilityURLs() (observability.URLs, error)
+	// Start a new node with the config of the node named [templateName]:
+	// its flags, config files, binary and other settings. The new node gets
+	// a generated name, a new staking key/cert, and its own ports and dirs,
+	// and isn't a beacon. If non-nil, [overrides] is then applied to the
+	// config, e.g. to name the node or change its flags.
+	// Returns ErrStopped if Stop() was previously called.
+	AddNodeFromTemplate(templateName string, overrides func(*node.Config)) (node.Node, error)
 	// Stop the node with this name.
 	// Returns ErrStopped if Stop() was previously called.
 	RemoveNode(name string) error
+	// Restart the node with this name with [flags] applied on top of its
+	// current flags, taking precedence over its config file.
+	// A nil value removes the flag. The node keeps its database,
+	// staking key/cert and ports.
+	// Returns ErrStopped if Stop() was previously called.
+	UpdateNodeFlags(name string, flags map[string]interface{}) error
+	// Restart the node with this name so that it tracks exactly the
+	// subnets [subnetIDs] (avalanchego's whitelisted-subnets flag),
+	// which replace the ones it tracked. The node keeps its database,
+	// staking key/cert and ports.
+	// Returns ErrStopped if Stop() was previously called.
+	SetSubnetWhitelist(name string, subnetIDs []ids.ID) error
+	// Restart the node with this name with a newly generated staking
+	// key/cert, and so a new node ID, which is returned. The node keeps
+	// its database and ports. Its old node ID stays a validator, if it
+	// was one, until its validation period ends. Nodes bootstrapping from
+	// the node, if it's a beacon, are restarted by RefreshBeacons.
+	// If the node doesn't start with the new key, it's started again
+	// with its old one, and an error is returned.
+	// Returns ErrStopped if Stop() was previously called.
+	RotateStakingKey(ctx context.Context, name string, opts RotateStakingKeyOptions) (ids.NodeID, error)
+	// Returns the IDs of the subnets the node with this name tracks, sorted.
+	// Returns ErrStopped if Stop() was previously called.
+	GetTrackedSubnets(name string) ([]ids.ID, error)
+	// Stop all the nodes, and then start them again with [opts] applied,
+	// beacons first. The nodes keep their databases, staking keys/certs
+	// and ports. Returns once the nodes are healthy, or [ctx] is done.
+	// Returns ErrStopped if Stop() was previously called.
+	Restart(ctx context.Context, opts RestartOptions) error
+	// Restart the nodes one at a time onto the binary at [binaryPath],
+	// waiting for each node to be healthy and bootstrapped before
+	// upgrading the next one. The nodes keep their databases,
+	// staking keys/certs and ports.
+	// Returns ErrStopped if Stop() was previously called.
+	UpgradeNodes(ctx context.Context, binaryPath string, opts UpgradeOptions) error
+	// Replace the binary of the custom VM with ID [vmID] by the one at
+	// [binaryPath], and restart the nodes one at a time to load it,
+	// waiting for each node to be healthy and bootstrapped, including
+	// the VM's chains it validates, before restarting the next one.
+	// Nodes not in [opts.NodeNames], if given, load the new binary
+	// the next time they're restarted.
+	// Returns ErrStopped if Stop() was previously called.
+	UpgradeVM(ctx context.Context, vmID ids.ID, binaryPath string, opts UpgradeOptions) error
+	// Suspend the process of the node with this name, freeing the CPU
+	// it uses while keeping its state. The node doesn't answer API calls
+	// or peers until it's resumed. Does nothing if the node is paused.
+	// Returns ErrStopped if Stop() was previously called.
+	PauseNode(name string) error
+	// Continue running the node with this name after PauseNode.
+	// Does nothing if the node isn't paused.
+	// Returns ErrStopped if Stop() was previously called.
+	ResumeNode(name string) error
+	// Send [sig] to the process of the node with this name, e.g. SIGHUP
+	// to make it reopen its log files. Only os.Kill is supported on
+	// windows.
+	// Returns ErrStopped if Stop() was previously called.
+	SignalNode(name string, sig os.Signal) error
+	// Returns the stdin of the process of the node with this name, which
+	// must have been started with node.Config.Stdin set. Writes fail once
+	// the process exits. If the node is restarted, its new process has
+	// another stdin.
+	// Returns ErrStopped if Stop() was previously called.
+	NodeStdin(name string) (io.Writer, error)
+	// Suspend the processes of all the nodes. See PauseNode.
+	// Nodes paused before an error stay paused.
+	// Returns ErrStopped if Stop() was previously called.
+	Pause(ctx context.Context) error
+	// Continue running all the nodes paused by Pause or PauseNode.
+	// Returns once the nodes are healthy, or [ctx] is done.
+	// Returns ErrStopped if Stop() was previously called.
+	Resume(ctx context.Context) error
 	// Return the node with this name.
 	// Returns ErrStopped if Stop() was previously called.
 	GetNode(name string) (node.Node, error)
+	// Return the node with this node ID, e.g. one returned by the
+	// validators or peers APIs of avalanchego.
+	// Returns ErrStopped if Stop() was previously called.
+	GetNodeByID(nodeID ids.NodeID) (node.Node, error)
 	// Return all the nodes in this network.
 	// Node name --> Node.
 	// Returns ErrStopped if Stop() was previously called.
 	GetAllNodes() (map[string]node.Node, error)
+	// Return all the nodes in this network.
+	// Node ID --> Node.
+	// Returns ErrStopped if Stop() was previously called.
+	GetAllNodesByID() (map[ids.NodeID]node.Node, error)
+	// Returns the genesis of this network, parsed. See ParseGenesis.
+	// Returns ErrStopped if Stop() was previously called.
+	GetGenesis() (*Genesis, error)
 	// Returns the names of all nodes in this network.
 	// Returns ErrStopped if Stop() was previously called.
 	GetNodeNames() ([]string, error)
+	// Returns the API URIs of all nodes in this network, sorted by
+	// node name (e.g. http://127.0.0.1:9650). See node.Node.GetURI.
+	// Returns ErrStopped if Stop() was previously called.
+	GetURIs() ([]string, error)
 	// Save network snapshot
 	// Network is stopped in order to do a safe preservation
 	// Returns the full local path to the snapshot dir
@@ -43,4 +227,109 @@ type Network interface {
 	RemoveSnapshot(string) error
 	// Get name of available snapshots
 	GetSnapshotNames() ([]string, error)
+	// Returns a new, independent network started from a copy of the
+	// chain state of this network, which must be healthy. Each node of
+	// the clone has the staking key, config and database of a node of
+	// this network, with [overrides] applied, and new ports.
+	// The clone has the other settings of this network's config too,
+	// except for its subnet specs, whose subnets are in the copied
+	// state, its explorer, and the paths and hosts registry the two
+	// networks would share. Its faucet and observability use free ports.
+	// This network's nodes are paused while their databases are copied,
+	// so this requires Capabilities().Pause.
+	// It's the caller's responsibility to call Stop on the clone.
+	// Returns ErrStopped if Stop() was previously called.
+	Clone(ctx context.Context, overrides CloneOverrides) (Network, error)
+	// Returns the operations done on this network, oldest first.
+	// Available even after Stop() is called.
+	History() []Operation
+	// Returns the startup timings of the network's nodes.
+	// Available even after Stop() is called.
+	// Returns an error if the network wasn't created with
+	// Config.RecordTimings set.
+	TimingReport() (TimingReport, error)
+	// Returns, for each node, the command line flags and config file
+	// its process was started with, and the flags they merge to.
+	// Node name --> rendered config.
+	// Returns ErrStopped if Stop() was previously called.
+	RenderNodeConfigs() (map[string]node.RenderedConfig, error)
+	// Returns the log lines of the network's nodes that pass [filter],
+	// merged in timestamp order and tagged with the node that wrote them.
+	// The channel is closed when [ctx] is cancelled or, if not following,
+	// when the end of the logs is reached.
+	AggregatedLogs(ctx context.Context, filter LogFilter) <-chan LogLine
+	// Returns the optional features this network supports.
+	// Available even after Stop() is called.
+	Capabilities() Capabilities
+	// Checks that the resources used by every node that ran in this
+	// network were released: no node process is still running, no port
+	// a node used is still bound, and node data dirs were preserved,
+	// or deleted if the config's DataDirCleanup policy says so.
+	// Returns ErrNotStopped if Stop() wasn't previously called.
+	VerifyTeardown() (TeardownReport, error)
+	// Write to [path] a tar.gz archive of the artifacts of every node that
+	// ran in this network: its logs, config files, metrics (if running)
+	// and exit error, along with the genesis, the history of operations,
+	// and a manifest (see ArtifactsManifest). Databases aren't included.
+	// Can be called after Stop(), unless the node dirs were deleted.
+	CollectArtifacts(ctx context.Context, path string) error
+	// Registers [hook] to be called on the config of every node started
+	// from now on, after network-wide settings have been merged into it
+	// and its ports assigned (in its http-port and staking-port flags),
+	// right before its process is started. Allows late-bound changes,
+	// e.g. pointing a flag at a port chosen at runtime by another service.
+	// Hooks are called in registration order, without holding the
+	// network's lock, so they may call its methods. If a hook returns an
+	// error, the node isn't started.
+	BeforeNodeStart(hook func(*node.Config) error)
+	// Adds [check] to the conditions nodes must meet for Healthy to
+	// return, which are AND-ed with their Health API reporting healthy.
+	// Applies to the following calls of Healthy.
+	// Returns an error if [check] is invalid.
+	AddHealthCheck(check HealthCheck) error
+	// Returns the state of every node in the network.
+	// Errors querying a node are reported in its status.
+	// Returns ErrStopped if Stop() was previously called.
+	Status(context.Context) (Status, error)
+	// Returns the peers each node reports being connected to.
+	// Errors querying a node are reported in the topology.
+	// Returns ErrStopped if Stop() was previously called.
+	GetTopology(context.Context) (Topology, error)
+	// Connects a new in-process peer to the node with this name, and
+	// returns it once the handshake is done. Messages the node sends
+	// to the peer are passed to [handler].
+	// The peer is closed when the node is removed or the network stopped.
+	// Returns ErrStopped if Stop() was previously called.
+	AttachPeer(ctx context.Context, name string, handler router.InboundHandler) (peer.Peer, error)
+	// Returns the subnets created from Config.SubnetSpecs.
+	// Returns ErrStopped if Stop() was previously called.
+	GetSubnets() ([]Subnet, error)
+	// Makes the blockchain [chainID] reachable at /ext/bc/[alias]
+	// on every node of the network. The nodes must have the Admin
+	// API enabled. Nodes started or restarted afterwards don't have
+	// the alias. See api.AddBlockchainAlias to alias on a single node.
+	// Returns ErrStopped if Stop() was previously called.
+	AddBlockchainAlias(ctx context.Context, chainID ids.ID, alias string) error
+	// Creates [users] in the keystore of every node of the network, and
+	// imports their keys, so that legacy API based tests can use the same
+	// funded users on any node. Users that exist are kept, and their keys
+	// imported. Nodes started afterwards don't have the users.
+	// See api.CreateKeystoreUser to create a user on a single node.
+	// Returns ErrStopped if Stop() was previously called.
+	SeedKeystoreUsers(ctx context.Context, users []api.KeystoreUser) error
+	// Waits until the node with this name has state synced the C-Chain,
+	// or skipped state sync, as logged by the node. The node must have been
+	// started with state sync enabled (see StateSyncConfig.Apply).
+	// Returns an error if state sync failed, along with the report.
+	// Returns ErrStopped if Stop() was previously called.
+	AwaitStateSync(ctx context.Context, name string) (StateSyncReport, error)
+	// Returns the containers accepted in [index] by the network's nodes,
+	// merged into a single stream. Every node's index is streamed from its
+	// first container, so containers accepted before the call are included.
+	// Nodes added after the call are streamed too. The channel is closed
+	// when [ctx] is cancelled or Stop() is called.
+	// Returns an error if the network wasn't created with indexing enabled
+	// (see Config.IndexEnabled).
+	// Returns ErrStopped if Stop() was previously called.
+	AcceptedEvents(ctx context.Context, index Index) (<-chan AcceptedEvent, error)
 }
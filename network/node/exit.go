@@ -0,0 +1,15 @@
+package node
+
+import "time"
+
+// ExitStatus describes how a node's process exited
+type ExitStatus struct {
+	// When the exit was seen
+	Time time.Time `json:"time"`
+	// The process's exit code, or -1 if it was killed by a signal
+	ExitCode int `json:"exitCode"`
+	// Name of the signal that killed the process, if any
+	Signal string `json:"signal,omitempty"`
+	// The last lines the process wrote to stderr, oldest first
+	StderrTail []string `json:"stderrTail,omitempty"`
+}
@@ -0,0 +1,81 @@
+package node
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Flags a node is running with.
+// Flag name --> value, formatted as on the command line.
+type Flags map[string]string
+
+// Get returns the value of flag [key], and whether it's set
+func (f Flags) Get(key string) (string, bool) {
+	val, ok := f[key]
+	return val, ok
+}
+
+// Bool returns the value of flag [key] as a bool
+func (f Flags) Bool(key string) (bool, error) {
+	val, err := f.lookup(key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("flag %q is not a bool: %w", key, err)
+	}
+	return b, nil
+}
+
+// Int returns the value of flag [key] as an int
+func (f Flags) Int(key string) (int64, error) {
+	val, err := f.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("flag %q is not an int: %w", key, err)
+	}
+	return i, nil
+}
+
+// Duration returns the value of flag [key] as a duration
+func (f Flags) Duration(key string) (time.Duration, error) {
+	val, err := f.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("flag %q is not a duration: %w", key, err)
+	}
+	return d, nil
+}
+
+func (f Flags) lookup(key string) (string, error) {
+	val, ok := f[key]
+	if !ok {
+		return "", fmt.Errorf("flag %q not set", key)
+	}
+	return val, nil
+}
+
+// RenderedConfig is what a node's process was started with,
+// after merging the network's flags, the node's flags, the
+// node's config file and the flags set by the network runner
+type RenderedConfig struct {
+	// Path of the binary the node runs
+	BinaryPath string `json:"binaryPath"`
+	// Command line flags the binary was started with,
+	// e.g. "--http-port=9650"
+	Args []string `json:"args"`
+	// Contents of the config file written to the node's
+	// dir (see flag --config-file), or empty if none
+	ConfigFile string `json:"configFile"`
+	// The flags from [Args] and [ConfigFile].
+	// [Args] take precedence, as they do in avalanchego.
+	Flags Flags `json:"flags"`
+}
@@ -0,0 +1,175 @@
+package node
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanchego/config"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+// FlagsBuilder builds the flags of a node config (see Config.Flags)
+// with a setter per flag, so that flag names and value types are
+// checked at compile time rather than when the node starts.
+// Values are stored with the types the network runner expects.
+// The zero value is ready to use. Setters can be chained, e.g.
+//
+//	flags := (&FlagsBuilder{}).HTTPPort(9650).LogLevel(logging.Debug).Build()
+type FlagsBuilder struct {
+	flags map[string]interface{}
+}
+
+// NewFlagsBuilder returns a FlagsBuilder starting from a copy of [flags],
+// so that flags without a setter can still be given
+func NewFlagsBuilder(flags map[string]interface{}) *FlagsBuilder {
+	b := &FlagsBuilder{}
+	for key, val := range flags {
+		b.set(key, val)
+	}
+	return b
+}
+
+// Build returns the flags set so far. The builder can be reused.
+func (b *FlagsBuilder) Build() map[string]interface{} {
+	flags := make(map[string]interface{}, len(b.flags))
+	for key, val := range b.flags {
+		flags[key] = val
+	}
+	return flags
+}
+
+func (b *FlagsBuilder) set(key string, val interface{}) *FlagsBuilder {
+	if b.flags == nil {
+		b.flags = map[string]interface{}{}
+	}
+	b.flags[key] = val
+	return b
+}
+
+// HTTPHost sets the address the node's API listens on
+func (b *FlagsBuilder) HTTPHost(host string) *FlagsBuilder {
+	return b.set(config.HTTPHostKey, host)
+}
+
+// HTTPPort sets the port of the node's API
+func (b *FlagsBuilder) HTTPPort(port uint16) *FlagsBuilder {
+	return b.set(config.HTTPPortKey, int(port))
+}
+
+// StakingPort sets the node's P2P port
+func (b *FlagsBuilder) StakingPort(port uint16) *FlagsBuilder {
+	return b.set(config.StakingPortKey, int(port))
+}
+
+// PublicIP sets the IP the node advertises to its peers
+func (b *FlagsBuilder) PublicIP(ip string) *FlagsBuilder {
+	return b.set(config.PublicIPKey, ip)
+}
+
+// StakingEnabled sets whether the node samples peers by stake
+func (b *FlagsBuilder) StakingEnabled(enabled bool) *FlagsBuilder {
+	return b.set(config.StakingEnabledKey, enabled)
+}
+
+// DBType sets the node's database type, e.g. "leveldb" or "memdb"
+func (b *FlagsBuilder) DBType(dbType string) *FlagsBuilder {
+	return b.set(config.DBTypeKey, dbType)
+}
+
+// DBDir sets the node's database dir
+func (b *FlagsBuilder) DBDir(dir string) *FlagsBuilder {
+	return b.set(config.DBPathKey, dir)
+}
+
+// LogDir sets the node's logs dir
+func (b *FlagsBuilder) LogDir(dir string) *FlagsBuilder {
+	return b.set(config.LogsDirKey, dir)
+}
+
+// LogLevel sets the level of the node's log files
+func (b *FlagsBuilder) LogLevel(level logging.Level) *FlagsBuilder {
+	return b.set(config.LogLevelKey, level.String())
+}
+
+// LogDisplayLevel sets the level of the node's stdout logs
+func (b *FlagsBuilder) LogDisplayLevel(level logging.Level) *FlagsBuilder {
+	return b.set(config.LogDisplayLevelKey, level.String())
+}
+
+// AdminAPIEnabled sets whether the node serves the Admin API
+func (b *FlagsBuilder) AdminAPIEnabled(enabled bool) *FlagsBuilder {
+	return b.set(config.AdminAPIEnabledKey, enabled)
+}
+
+// IPCsAPIEnabled sets whether the node serves the IPCs API
+func (b *FlagsBuilder) IPCsAPIEnabled(enabled bool) *FlagsBuilder {
+	return b.set(config.IpcAPIEnabledKey, enabled)
+}
+
+// KeystoreAPIEnabled sets whether the node serves the Keystore API
+func (b *FlagsBuilder) KeystoreAPIEnabled(enabled bool) *FlagsBuilder {
+	return b.set(config.KeystoreAPIEnabledKey, enabled)
+}
+
+// MetricsAPIEnabled sets whether the node serves the Metrics API
+func (b *FlagsBuilder) MetricsAPIEnabled(enabled bool) *FlagsBuilder {
+	return b.set(config.MetricsAPIEnabledKey, enabled)
+}
+
+// IndexEnabled sets whether the node indexes accepted containers
+func (b *FlagsBuilder) IndexEnabled(enabled bool) *FlagsBuilder {
+	return b.set(config.IndexEnabledKey, enabled)
+}
+
+// HealthCheckFrequency sets how often the node runs its health checks
+func (b *FlagsBuilder) HealthCheckFrequency(freq time.Duration) *FlagsBuilder {
+	return b.set(config.HealthCheckFreqKey, freq.String())
+}
+
+// NetworkHealthMinPeers sets the min number of peers
+// the node must be connected to to be healthy
+func (b *FlagsBuilder) NetworkHealthMinPeers(peers uint) *FlagsBuilder {
+	return b.set(config.NetworkHealthMinPeersKey, int(peers))
+}
+
+// NetworkPeerListGossipFrequency sets how often the node gossips its peers
+func (b *FlagsBuilder) NetworkPeerListGossipFrequency(freq time.Duration) *FlagsBuilder {
+	return b.set(config.NetworkPeerListGossipFreqKey, freq.String())
+}
+
+// NetworkMaxReconnectDelay sets the max time
+// between the node's attempts to reconnect to a peer
+func (b *FlagsBuilder) NetworkMaxReconnectDelay(delay time.Duration) *FlagsBuilder {
+	return b.set(config.NetworkMaxReconnectDelayKey, delay.String())
+}
+
+// ConsensusGossipFrequency sets how often the node
+// gossips its last accepted frontier
+func (b *FlagsBuilder) ConsensusGossipFrequency(freq time.Duration) *FlagsBuilder {
+	return b.set(config.ConsensusGossipFrequencyKey, freq.String())
+}
+
+// SnowSampleSize sets the node's consensus sample size (k)
+func (b *FlagsBuilder) SnowSampleSize(k int) *FlagsBuilder {
+	return b.set(config.SnowSampleSizeKey, k)
+}
+
+// SnowQuorumSize sets the node's consensus quorum size (alpha)
+func (b *FlagsBuilder) SnowQuorumSize(alpha int) *FlagsBuilder {
+	return b.set(config.SnowQuorumSizeKey, alpha)
+}
+
+// SnowConcurrentRepolls sets the node's number of outstanding polls
+func (b *FlagsBuilder) SnowConcurrentRepolls(repolls int) *FlagsBuilder {
+	return b.set(config.SnowConcurrentRepollsKey, repolls)
+}
+
+// WhitelistedSubnets sets the subnets the node tracks
+func (b *FlagsBuilder) WhitelistedSubnets(subnetIDs ...ids.ID) *FlagsBuilder {
+	subnets := make([]string, len(subnetIDs))
+	for i, subnetID := range subnetIDs {
+		subnets[i] = subnetID.String()
+	}
+	return b.set(config.WhitelistedSubnetsKey, strings.Join(subnets, ","))
+}
@@ -2,17 +2,28 @@ package node
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/ava-labs/avalanche-network-runner/api"
 	"github.com/ava-labs/avalanchego/config"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/network/peer"
 	"github.com/ava-labs/avalanchego/snow/networking/router"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/units"
 )
 
+// Alias of the C-Chain in chain config files
+const CChainAlias = "C"
+
 // Node represents an AvalancheGo node
 type Node interface {
 	// Return this node's name, which is unique
@@ -22,8 +33,21 @@ type Node interface {
 	GetNodeID() ids.NodeID
 	// Return a client that can be used to make API calls.
 	GetAPIClient() api.Client
-	// Return this node's IP (e.g. 127.0.0.1).
+	// Return the IP this node's API listens on (e.g. 127.0.0.1).
 	GetURL() string
+	// Return the URI of this node's API (e.g. http://127.0.0.1:9650),
+	// whose scheme is https if the API uses TLS.
+	GetURI() string
+	// Return the host name this node is registered under (e.g.
+	// node0.avax.local), or empty if the network has no hosts registry.
+	// See network.Config.HostsRegistry.
+	GetHostname() string
+	// Return the cert this node authenticates with on the P2P network.
+	// Its public key determines the node ID.
+	GetStakingCert() *x509.Certificate
+	// Return the hex encoded SHA-256 hash of this node's staking cert,
+	// for comparison with the cert presented on a TLS connection.
+	GetStakingCertFingerprint() string
 	// Return this node's P2P (staking) port.
 	GetP2PPort() uint16
 	// Return this node's HTTP API port.
@@ -36,12 +60,34 @@ type Node interface {
 	AttachPeer(ctx context.Context, handler router.InboundHandler) (peer.Peer, error)
 	// Return this node's avalanchego binary path
 	GetBinaryPath() string
+	// Return the ID of this node's process, or 0 if it isn't running
+	GetPID() int
 	// Return this node's db dir
 	GetDbDir() string
 	// Return this node's logs dir
 	GetLogsDir() string
+	// Copy this node's database to [destDir], which must not exist or be
+	// empty, so that other nodes can be started from it (see Config.DBPath).
+	// The node should be paused or stopped first, or else the copy
+	// may be inconsistent.
+	ExportDB(destDir string) error
 	// Return this node's config file contents
 	GetConfigFile() string
+	// Return the flags this node was started with, including the
+	// ones in its config file. Command line flags take precedence
+	// over the config file, as they do in avalanchego.
+	GetFlags() Flags
+	// Return the command line flags and config file this node's
+	// process was started with, and the flags they merge to.
+	GetRenderedConfig() RenderedConfig
+	// Return the config this node reports it's running with.
+	// Requires the node's admin API to be enabled.
+	GetRuntimeConfig(ctx context.Context) (map[string]interface{}, error)
+	// Return a sample of the resources this node's process uses.
+	GetResourceUsage() (ResourceUsage, error)
+	// Return how this node's process exited, and true,
+	// or false if it hasn't exited.
+	GetExitStatus() (ExitStatus, bool)
 }
 
 // Config encapsulates an avalanchego configuration
@@ -53,6 +99,11 @@ type Config struct {
 	// True if other nodes should use this node
 	// as a bootstrap beacon.
 	IsBeacon bool `json:"isBeacon"`
+	// True if this node is an API node that doesn't validate the
+	// primary network. Its node ID must not be a genesis validator,
+	// and it can't be a beacon or a subnet validator.
+	// It's still part of the network's health checks.
+	NonValidator bool `json:"nonValidator"`
 	// Must not be nil.
 	StakingKey string `json:"stakingKey"`
 	// Must not be nil.
@@ -61,12 +112,27 @@ type Config struct {
 	ConfigFile string `json:"configFile"`
 	// May be nil.
 	CChainConfigFile string `json:"cChainConfigFile"`
+	// Chain alias or ID --> contents of that chain's config file.
+	// May be nil. If CChainConfigFile is given, must not have the C-Chain.
+	// Takes precedence over the network config's chain config
+	// file for the same chain.
+	ChainConfigFiles map[string]string `json:"chainConfigFiles"`
+	// Chain alias or ID --> contents of that chain's upgrade file.
+	// May be nil.
+	UpgradeConfigFiles map[string]string `json:"upgradeConfigFiles"`
+	// Subnet ID --> contents of that subnet's config file.
+	// May be nil. Takes precedence over the network config's
+	// subnet config file for the same subnet.
+	SubnetConfigFiles map[string]string `json:"subnetConfigFiles"`
 	// Flags can hold additional flags for the node.
 	// It can be empty.
 	// The precedence of flags handling is:
 	// 1. Flags defined in node.Config (this struct) override
 	// 2. Flags defined in network.Config override
 	// 3. Flags defined in the json config file
+	// The runner writes the chain and subnet config files of the
+	// node to its own dirs, so the chain-config-dir and
+	// subnet-config-dir flags can't be given with such files.
 	Flags map[string]interface{} `json:"flags"`
 	// What type of node this is
 	BinaryPath string `json:"binaryPath"`
@@ -74,23 +140,315 @@ type Config struct {
 	RedirectStdout bool `json:"redirectStdout"`
 	// If non-nil, direct this node's Stderr to os.Stderr
 	RedirectStderr bool `json:"redirectStderr"`
+	// If non-empty, this node's stdout is appended to the file at this path,
+	// which is created if it doesn't exist. Can't be given with RedirectStdout.
+	StdoutPath string `json:"stdoutPath"`
+	// If non-empty, this node's stderr is appended to the file at this path,
+	// which is created if it doesn't exist. Can't be given with RedirectStderr.
+	// May be the same as StdoutPath.
+	StderrPath string `json:"stderrPath"`
+	// If true, this node's stdin is a pipe written to with
+	// Network.NodeStdin. Otherwise, the node's stdin is empty.
+	Stdin bool `json:"stdin"`
+	// Environment variables (e.g. GOGC=50) the node is started with,
+	// in the form "key=value", on top of the environment of this process.
+	// A variable given here overrides the inherited one.
+	Env []string `json:"env"`
+	// Working dir of the node's process.
+	// If empty, the working dir of this process is used.
+	WorkingDir string `json:"workingDir"`
+	// If non-zero, the niceness of this node's process, from -20
+	// (highest priority) to 19 (lowest). Below 0 requires privileges.
+	// Not supported on Windows.
+	Nice int `json:"nice"`
+	// If non-empty, the CPUs (numbered from 0) this node's
+	// process may run on. Only supported on Linux.
+	CPUAffinity []int `json:"cpuAffinity"`
+	// Transport used by this node's C-Chain eth API client.
+	// If empty, websocket is used, or HTTP if the node's API
+	// uses TLS or auth, which websocket isn't supported with.
+	CChainEthTransport api.EthTransport `json:"cChainEthTransport"`
+	// If non-empty, the IP this node advertises to its peers, which
+	// nodes bootstrapping from it connect to (avalanchego's public-ip).
+	// Defaults to 127.0.0.1. May be an IPv6 IP, bracketed or not.
+	PublicIP string `json:"publicIP"`
+	// If non-empty, the IP (e.g. a 127.0.0.X alias, ::1 or a LAN IP) this
+	// node's HTTP API listens on (avalanchego's http-host), which its API
+	// clients and attached test peers connect to. Defaults to 127.0.0.1.
+	// If it's unspecified (0.0.0.0 or ::), they connect to the loopback IP.
+	// May be an IPv6 IP, bracketed or not.
+	// The P2P port listens on every interface, so use PublicIP for
+	// peers to connect to the node on a given IP.
+	BindAddr string `json:"bindAddr"`
+	// If both are given, this node serves its HTTP API over TLS with this
+	// PEM encoded cert and key, and its API clients use HTTPS, trusting
+	// the cert. The cert must be valid for localhost.
+	APITLSCert string `json:"apiTLSCert"`
+	APITLSKey  string `json:"apiTLSKey"`
+	// If non-empty, this node requires API calls to be authorized with a
+	// token (see avalanchego's api-auth-required flag), and its API
+	// clients get tokens from its auth API with this password.
+	APIAuthPassword string `json:"apiAuthPassword"`
+	// If non-empty, this node's API clients authorize calls with this
+	// token. For nodes started with api-auth-required by their flags
+	// or config file.
+	APIAuthToken string `json:"apiAuthToken"`
+	// If non-empty, this node's database is initialized with a copy of the
+	// database dir at this path (e.g. one written by Node.ExportDB, or a
+	// node's db-dir). It must have the database of the network's ID (e.g.
+	// the network-1337 dir). The copy is skipped if the node's database
+	// already has it, e.g. when the node is restarted.
+	DBPath string `json:"dbPath"`
+	// If non-nil, this node's database is put under storage pressure.
+	// Only supported on Linux, by the local backend running as root.
+	DiskFault *DiskFault `json:"diskFault"`
+}
+
+// Bounds of the niceness of a node's process
+const (
+	MinNice = -20
+	MaxNice = 19
+)
+
+// Min size of a node's database filesystem under a disk fault
+const MinDiskFaultSize = 32 * units.MiB
+
+// DiskFault puts a node's database under storage pressure, to test how
+// avalanchego behaves when its disk fills up or is slow. The node's
+// db-dir is a filesystem of [MaxDBSize] bytes, backed by an image file
+// next to it, and mounted while the node runs. Once the node is removed,
+// its database is only in the image file (<db-dir>.img).
+type DiskFault struct {
+	// Size of the node's database filesystem, in bytes. Once it's full,
+	// writes fail with "no space left on device".
+	// Must be at least MinDiskFaultSize.
+	MaxDBSize uint64 `json:"maxDBSize"`
+	// If positive, every read and write of the node's database
+	// filesystem is delayed by this long. Millisecond granularity.
+	IOLatency time.Duration `json:"ioLatency"`
+}
+
+// Validate returns an error if [d] is invalid
+func (d *DiskFault) Validate() error {
+	switch {
+	case d.MaxDBSize < MinDiskFaultSize:
+		return fmt.Errorf("max database size must be at least %d bytes", MinDiskFaultSize)
+	case d.IOLatency < 0:
+		return errors.New("negative IO latency given")
+	}
+	return nil
+}
+
+// ValidationIssue is a problem with a field of a config
+type ValidationIssue struct {
+	// JSON path of the field, e.g. "nodeConfigs[1].stakingCert",
+	// or empty if the problem isn't with a single field
+	Field string
+	Err   error
+}
+
+func (i ValidationIssue) Error() string {
+	if i.Field == "" {
+		return i.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", i.Field, i.Err)
+}
+
+func (i ValidationIssue) Unwrap() error {
+	return i.Err
 }
 
 // Validate returns an error if this config is invalid
 func (c *Config) Validate(expectedNetworkID uint32) error {
+	if issues := c.ValidationIssues(&expectedNetworkID); len(issues) > 0 {
+		return issues[0]
+	}
+	return nil
+}
+
+// ValidationIssues returns all the problems with this config, whose field
+// paths are relative to the config. If [expectedNetworkID] is nil, such as
+// when the network's genesis is invalid, the config file and database
+// aren't checked against the network ID.
+func (c *Config) ValidationIssues(expectedNetworkID *uint32) []ValidationIssue {
+	var issues []ValidationIssue
+	addIssue := func(field string, err error) {
+		issues = append(issues, ValidationIssue{Field: field, Err: err})
+	}
+	if c.StakingKey == "" {
+		addIssue("stakingKey", errors.New("staking key not given"))
+	}
+	if c.StakingCert == "" {
+		addIssue("stakingCert", errors.New("staking cert not given"))
+	}
+	if c.StakingKey != "" && c.StakingCert != "" {
+		if _, err := tls.X509KeyPair([]byte(c.StakingCert), []byte(c.StakingKey)); err != nil {
+			addIssue("stakingCert", fmt.Errorf("staking cert doesn't match staking key: %w", err))
+		}
+	}
+	if c.IsBeacon && c.NonValidator {
+		addIssue("isBeacon", errors.New("non-validator node can't be a beacon"))
+	}
+	if len(c.CChainConfigFile) != 0 && len(c.ChainConfigFiles[CChainAlias]) != 0 {
+		addIssue("cChainConfigFile", errors.New("C-Chain config file given twice"))
+	}
+	if err := c.CChainEthTransport.Validate(); err != nil {
+		addIssue("cChainEthTransport", err)
+	}
+	if c.RedirectStdout && c.StdoutPath != "" {
+		addIssue("stdoutPath", errors.New("stdout redirected twice"))
+	}
+	if c.RedirectStderr && c.StderrPath != "" {
+		addIssue("stderrPath", errors.New("stderr redirected twice"))
+	}
+	if c.PublicIP != "" && ParseIP(c.PublicIP) == nil {
+		addIssue("publicIP", fmt.Errorf("invalid public IP %q", c.PublicIP))
+	}
+	if c.BindAddr != "" && ParseIP(c.BindAddr) == nil {
+		addIssue("bindAddr", fmt.Errorf("invalid bind address %q", c.BindAddr))
+	}
 	switch {
-	case c.StakingKey == "":
-		return errors.New("staking key not given")
-	case c.StakingCert == "":
-		return errors.New("staking cert not given")
-	default:
-		return validateConfigFile([]byte(c.ConfigFile), expectedNetworkID)
+	case (c.APITLSCert == "") != (c.APITLSKey == ""):
+		addIssue("apiTLSCert", errors.New("API TLS cert and key must be given together"))
+	case c.APITLSCert != "":
+		if _, err := tls.X509KeyPair([]byte(c.APITLSCert), []byte(c.APITLSKey)); err != nil {
+			addIssue("apiTLSCert", fmt.Errorf("invalid API TLS cert/key: %w", err))
+		}
+	}
+	if c.APIAuthPassword != "" && c.APIAuthToken != "" {
+		addIssue("apiAuthToken", errors.New("API auth password and token given"))
+	}
+	if c.UsesAPIAuthOrTLS() && c.CChainEthTransport == api.EthTransportWS {
+		addIssue("cChainEthTransport", errors.New("websocket C-Chain eth transport isn't supported with API TLS or auth"))
+	}
+	for i, env := range c.Env {
+		if !strings.Contains(env, "=") {
+			addIssue(fmt.Sprintf("env[%d]", i), fmt.Errorf("env var %q isn't of the form key=value", env))
+		}
 	}
+	if c.WorkingDir != "" {
+		info, err := os.Stat(c.WorkingDir)
+		switch {
+		case err != nil:
+			addIssue("workingDir", fmt.Errorf("couldn't stat working dir: %w", err))
+		case !info.IsDir():
+			addIssue("workingDir", fmt.Errorf("working dir %q isn't a dir", c.WorkingDir))
+		}
+	}
+	if c.Nice < MinNice || c.Nice > MaxNice {
+		addIssue("nice", fmt.Errorf("niceness %d isn't in [%d, %d]", c.Nice, MinNice, MaxNice))
+	}
+	cpus := make(map[int]struct{}, len(c.CPUAffinity))
+	for i, cpu := range c.CPUAffinity {
+		if cpu < 0 {
+			addIssue(fmt.Sprintf("cpuAffinity[%d]", i), fmt.Errorf("negative CPU %d", cpu))
+		}
+		if _, ok := cpus[cpu]; ok {
+			addIssue(fmt.Sprintf("cpuAffinity[%d]", i), fmt.Errorf("CPU %d given twice", cpu))
+		}
+		cpus[cpu] = struct{}{}
+	}
+	if c.DBPath != "" && expectedNetworkID != nil {
+		if err := CheckDBNetwork(c.DBPath, *expectedNetworkID); err != nil {
+			addIssue("dbPath", err)
+		}
+	}
+	if c.DiskFault != nil {
+		if err := c.DiskFault.Validate(); err != nil {
+			addIssue("diskFault", fmt.Errorf("disk fault: %w", err))
+		}
+	}
+	if err := ValidateSubnetConfigFiles(c.SubnetConfigFiles); err != nil {
+		addIssue("subnetConfigFiles", err)
+	}
+	if err := validateConfigFile([]byte(c.ConfigFile), expectedNetworkID); err != nil {
+		addIssue("configFile", err)
+	}
+	return issues
+}
+
+// CheckDBNetwork returns an error if the database dir [dbPath]
+// doesn't have the database of network [networkID]
+func CheckDBNetwork(dbPath string, networkID uint32) error {
+	entries, err := os.ReadDir(dbPath)
+	if err != nil {
+		return fmt.Errorf("couldn't read database dir: %w", err)
+	}
+	networkName := constants.NetworkName(networkID)
+	networkNames := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if entry.Name() == networkName {
+			return nil
+		}
+		networkNames = append(networkNames, entry.Name())
+	}
+	if len(networkNames) == 0 {
+		return fmt.Errorf("database dir %q has no network's database", dbPath)
+	}
+	return fmt.Errorf("database dir %q is of network %s, not %s", dbPath, strings.Join(networkNames, ", "), networkName)
+}
+
+// MergeCChainConfig sets [entries] in this node's C-Chain config file,
+// which is ChainConfigFiles[CChainAlias] if given there, or else
+// CChainConfigFile. Other entries of the config file are preserved.
+func (c *Config) MergeCChainConfig(entries map[string]interface{}) error {
+	cChainConfigFile := &c.CChainConfigFile
+	if len(c.ChainConfigFiles[CChainAlias]) != 0 {
+		file := c.ChainConfigFiles[CChainAlias]
+		cChainConfigFile = &file
+	}
+	cChainConfig := map[string]interface{}{}
+	if len(*cChainConfigFile) != 0 {
+		decoder := json.NewDecoder(strings.NewReader(*cChainConfigFile))
+		decoder.UseNumber()
+		if err := decoder.Decode(&cChainConfig); err != nil {
+			return fmt.Errorf("couldn't unmarshal C-Chain config file: %w", err)
+		}
+	}
+	for key, value := range entries {
+		cChainConfig[key] = value
+	}
+	merged, err := json.Marshal(cChainConfig)
+	if err != nil {
+		return err
+	}
+	*cChainConfigFile = string(merged)
+	if len(c.ChainConfigFiles[CChainAlias]) != 0 {
+		// Copy the map, which may be shared with other configs
+		chainConfigFiles := make(map[string]string, len(c.ChainConfigFiles))
+		for chain, file := range c.ChainConfigFiles {
+			chainConfigFiles[chain] = file
+		}
+		chainConfigFiles[CChainAlias] = *cChainConfigFile
+		c.ChainConfigFiles = chainConfigFiles
+	}
+	return nil
+}
+
+// UsesAPIAuthOrTLS returns true if this node's API
+// is served over TLS or requires auth tokens
+func (c *Config) UsesAPIAuthOrTLS() bool {
+	return c.APITLSCert != "" || c.APIAuthPassword != "" || c.APIAuthToken != ""
+}
+
+// ValidateSubnetConfigFiles returns an error if a key
+// of [subnetConfigFiles] isn't a subnet ID
+func ValidateSubnetConfigFiles(subnetConfigFiles map[string]string) error {
+	for subnetID := range subnetConfigFiles {
+		if _, err := ids.FromString(subnetID); err != nil {
+			return fmt.Errorf("invalid subnet ID %q in subnet config files: %w", subnetID, err)
+		}
+	}
+	return nil
 }
 
 // Returns an error if config file [configFile] is invalid.
 // If len([configFile]) == 0, returns nil.
-func validateConfigFile(configFile []byte, expectedNetworkID uint32) error {
+// If [expectedNetworkID] is nil, the network ID isn't checked.
+func validateConfigFile(configFile []byte, expectedNetworkID *uint32) error {
 	if len(configFile) == 0 {
 		// No config file given. Skip.
 		return nil
@@ -106,8 +464,8 @@ func validateConfigFile(configFile []byte, expectedNetworkID uint32) error {
 		if !ok {
 			return fmt.Errorf("wrong type for field %q in config expected float64 got %T", config.NetworkNameKey, networkIDIntf)
 		}
-		if uint32(networkID) != expectedNetworkID {
-			return fmt.Errorf("config file network id %d differs from genesis network id %d", int(networkID), expectedNetworkID)
+		if expectedNetworkID != nil && uint32(networkID) != *expectedNetworkID {
+			return fmt.Errorf("config file network id %d differs from genesis network id %d", int(networkID), *expectedNetworkID)
 		}
 	}
 	if dbPathIntf, ok := configMap[config.DBPathKey]; ok {
@@ -132,3 +490,12 @@ func validateConfigFile(configFile []byte, expectedNetworkID uint32) error {
 	}
 	return nil
 }
+
+// ParseIP is like net.ParseIP, but also accepts
+// bracketed IPv6 IPs (e.g. [::1])
+func ParseIP(s string) net.IP {
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+	}
+	return net.ParseIP(s)
+}
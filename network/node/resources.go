@@ -0,0 +1,18 @@
+package node
+
+import "time"
+
+// ResourceUsage is a sample of the resources used by a node
+type ResourceUsage struct {
+	// When the sample was taken
+	Time time.Time `json:"time"`
+	// CPU time used by the node's process since it started
+	CPUTime time.Duration `json:"cpuTime"`
+	// Percentage of a CPU core the node's process used since it
+	// started. May be over 100 on multi-core machines.
+	CPUPercent float64 `json:"cpuPercent"`
+	// Resident memory of the node's process
+	MemoryBytes uint64 `json:"memoryBytes"`
+	// Size of the node's database and logs on disk
+	DiskBytes uint64 `json:"diskBytes"`
+}
@@ -0,0 +1,250 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/utils"
+	"github.com/ava-labs/avalanchego/config"
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// FastStakingConfig makes staking periods and reward cycles elapse
+// in minutes rather than weeks, so that end-of-staking and reward
+// distribution logic can be exercised in tests.
+var FastStakingConfig = StakingConfig{
+	MinStakeDuration:           time.Minute,
+	MaxStakeDuration:           10 * time.Minute,
+	StakeMintingPeriod:         10 * time.Minute,
+	InitialStakeDuration:       10 * time.Minute,
+	InitialStakeDurationOffset: 10 * time.Second,
+}
+
+// StakingConfig holds the parameters that control how long
+// validators and delegators stake on a network.
+// The initial stake durations are written to the genesis, and
+// apply to the genesis validators. The remaining parameters are
+// passed to every node as flags, and apply to stakers added later.
+// Note that avalanchego only honors these flags on custom networks.
+type StakingConfig struct {
+	// Minimum duration a validator or delegator can stake for
+	MinStakeDuration time.Duration `json:"minStakeDuration"`
+	// Maximum duration a validator or delegator can stake for
+	MaxStakeDuration time.Duration `json:"maxStakeDuration"`
+	// Period over which staking rewards are computed.
+	// Must not be less than [MaxStakeDuration].
+	StakeMintingPeriod time.Duration `json:"stakeMintingPeriod"`
+	// How long the genesis validators stake for
+	InitialStakeDuration time.Duration `json:"initialStakeDuration"`
+	// Offset between the end of staking of consecutive genesis validators
+	InitialStakeDurationOffset time.Duration `json:"initialStakeDurationOffset"`
+}
+
+// Validate returns an error if this staking config is invalid.
+// The checks mirror the ones done by avalanchego on startup.
+func (c *StakingConfig) Validate() error {
+	switch {
+	case c.MinStakeDuration <= 0:
+		return errors.New("min stake duration must be > 0")
+	case c.MaxStakeDuration < c.MinStakeDuration:
+		return errors.New("max stake duration can't be less than min stake duration")
+	case c.StakeMintingPeriod < c.MaxStakeDuration:
+		return errors.New("stake minting period can't be less than max stake duration")
+	case c.InitialStakeDuration < time.Second:
+		return errors.New("initial stake duration must be at least 1s")
+	case c.InitialStakeDurationOffset < 0:
+		return errors.New("initial stake duration offset must be >= 0")
+	default:
+		return nil
+	}
+}
+
+// Flags returns the avalanchego flags that apply this staking config
+// to stakers added after genesis.
+func (c *StakingConfig) Flags() map[string]interface{} {
+	return map[string]interface{}{
+		config.MinStakeDurationKey:   c.MinStakeDuration.String(),
+		config.MaxStakeDurationKey:   c.MaxStakeDuration.String(),
+		config.StakeMintingPeriodKey: c.StakeMintingPeriod.String(),
+	}
+}
+
+// ApplyToGenesis returns a copy of [genesisBytes] where the genesis
+// validators stake for [c.InitialStakeDuration], starting now.
+func (c *StakingConfig) ApplyToGenesis(genesisBytes []byte) ([]byte, error) {
+	initialStakeDuration := uint64(c.InitialStakeDuration / time.Second)
+	initialStakeDurationOffset := uint64(c.InitialStakeDurationOffset / time.Second)
+	return updateGenesis(genesisBytes, func(genesisConfig *genesis.UnparsedConfig) error {
+		if len(genesisConfig.InitialStakers) > 1 {
+			offsetTimeRequired := initialStakeDurationOffset * uint64(len(genesisConfig.InitialStakers)-1)
+			if offsetTimeRequired > initialStakeDuration {
+				return fmt.Errorf(
+					"initial stake duration %s is less than the %ds required by %d stakers with offset %s",
+					c.InitialStakeDuration, offsetTimeRequired, len(genesisConfig.InitialStakers), c.InitialStakeDurationOffset,
+				)
+			}
+		}
+		// The genesis start time can't be in the future
+		genesisConfig.StartTime = uint64(time.Now().Unix())
+		genesisConfig.InitialStakeDuration = initialStakeDuration
+		genesisConfig.InitialStakeDurationOffset = initialStakeDurationOffset
+		return nil
+	})
+}
+
+// SetStakingConfig applies [stakingConfig] to this network config,
+// updating both its genesis and its flags.
+// Flags already given in [c.Flags] are overwritten.
+func (c *Config) SetStakingConfig(stakingConfig StakingConfig) error {
+	if err := stakingConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid staking config: %w", err)
+	}
+	genesis, err := stakingConfig.ApplyToGenesis([]byte(c.Genesis))
+	if err != nil {
+		return err
+	}
+	c.Genesis = string(genesis)
+	if c.Flags == nil {
+		c.Flags = map[string]interface{}{}
+	}
+	for k, v := range stakingConfig.Flags() {
+		c.Flags[k] = v
+	}
+	return nil
+}
+
+// GenesisValidatorsConfig sets which of a network's nodes are genesis
+// validators. avalanchego splits the genesis stake evenly between the
+// genesis validators, and ends their staking periods the genesis'
+// initial stake duration offset apart, in the order they're given,
+// so the stake and end time of a validator can't be set on its own.
+type GenesisValidatorsConfig struct {
+	// Indices, in the config's NodeConfigs, of the genesis validators,
+	// from the one whose staking period ends last to the one whose
+	// staking period ends first
+	Nodes []int `json:"nodes"`
+	// Stake of each genesis validator, in nAVAX.
+	// If zero, the genesis stake is kept, and split between [Nodes].
+	Stake uint64 `json:"stake"`
+	// If non-zero, how long the first genesis validator stakes for,
+	// starting now, and [InitialStakeDurationOffset] is applied too
+	InitialStakeDuration time.Duration `json:"initialStakeDuration"`
+	// Offset between the end of staking of consecutive genesis validators
+	InitialStakeDurationOffset time.Duration `json:"initialStakeDurationOffset"`
+}
+
+// SetGenesisValidators updates the genesis of this network config so that
+// the genesis validators are the nodes given by [validatorsConfig].
+// A node that was already a genesis validator keeps its reward address
+// and delegation fee. The others get the reward address of the first
+// genesis validator, and DefaultGenesisDelegationFee.
+func (c *Config) SetGenesisValidators(validatorsConfig GenesisValidatorsConfig) error {
+	numValidators := len(validatorsConfig.Nodes)
+	switch {
+	case numValidators == 0:
+		return errors.New("no genesis validators given")
+	case validatorsConfig.Stake > math.MaxUint64/uint64(numValidators):
+		return fmt.Errorf("stake %d of %d validators overflows", validatorsConfig.Stake, numValidators)
+	case validatorsConfig.InitialStakeDuration != 0 && validatorsConfig.InitialStakeDuration < time.Second:
+		return errors.New("initial stake duration must be at least 1s")
+	case validatorsConfig.InitialStakeDurationOffset < 0:
+		return errors.New("initial stake duration offset must be >= 0")
+	}
+	nodeIDs := make([]ids.NodeID, numValidators)
+	seen := make(map[int]struct{}, numValidators)
+	for i, nodeIndex := range validatorsConfig.Nodes {
+		if nodeIndex < 0 || nodeIndex >= len(c.NodeConfigs) {
+			return fmt.Errorf("genesis validator %d is node %d, but there are %d nodes", i, nodeIndex, len(c.NodeConfigs))
+		}
+		if _, ok := seen[nodeIndex]; ok {
+			return fmt.Errorf("node %d given twice", nodeIndex)
+		}
+		seen[nodeIndex] = struct{}{}
+		nodeConfig := c.NodeConfigs[nodeIndex]
+		nodeID, err := utils.ToNodeID([]byte(nodeConfig.StakingKey), []byte(nodeConfig.StakingCert))
+		if err != nil {
+			return fmt.Errorf("couldn't get ID of node %d: %w", nodeIndex, err)
+		}
+		nodeIDs[i] = nodeID
+	}
+
+	genesisBytes, err := updateGenesis([]byte(c.Genesis), func(genesisConfig *genesis.UnparsedConfig) error {
+		if len(genesisConfig.InitialStakers) == 0 {
+			return errors.New("genesis has no validators")
+		}
+		stakers := make(map[ids.NodeID]genesis.UnparsedStaker, len(genesisConfig.InitialStakers))
+		for _, staker := range genesisConfig.InitialStakers {
+			stakers[staker.NodeID] = staker
+		}
+		rewardAddr := genesisConfig.InitialStakers[0].RewardAddress
+		genesisConfig.InitialStakers = make([]genesis.UnparsedStaker, numValidators)
+		for i, nodeID := range nodeIDs {
+			staker, ok := stakers[nodeID]
+			if !ok {
+				staker = genesis.UnparsedStaker{
+					NodeID:        nodeID,
+					RewardAddress: rewardAddr,
+					DelegationFee: DefaultGenesisDelegationFee,
+				}
+			}
+			genesisConfig.InitialStakers[i] = staker
+		}
+
+		if validatorsConfig.InitialStakeDuration != 0 {
+			// The genesis start time can't be in the future
+			genesisConfig.StartTime = uint64(time.Now().Unix())
+			genesisConfig.InitialStakeDuration = uint64(validatorsConfig.InitialStakeDuration / time.Second)
+			genesisConfig.InitialStakeDurationOffset = uint64(validatorsConfig.InitialStakeDurationOffset / time.Second)
+		}
+		offsetTimeRequired := genesisConfig.InitialStakeDurationOffset * uint64(numValidators-1)
+		if offsetTimeRequired > genesisConfig.InitialStakeDuration {
+			return fmt.Errorf(
+				"initial stake duration %ds is less than the %ds required by %d stakers with offset %ds",
+				genesisConfig.InitialStakeDuration, offsetTimeRequired, numValidators, genesisConfig.InitialStakeDurationOffset,
+			)
+		}
+
+		if validatorsConfig.Stake != 0 {
+			return setGenesisStake(genesisConfig, validatorsConfig.Stake*uint64(numValidators))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.Genesis = string(genesisBytes)
+	return nil
+}
+
+// Makes the total stake of the genesis validators [totalStake], by
+// giving it to the first allocation of the initial staked funds,
+// unlocked when that allocation's stake was, and emptying the others.
+func setGenesisStake(genesisConfig *genesis.UnparsedConfig, totalStake uint64) error {
+	stakedFunds := make(map[string]struct{}, len(genesisConfig.InitialStakedFunds))
+	for _, addr := range genesisConfig.InitialStakedFunds {
+		stakedFunds[addr] = struct{}{}
+	}
+	set := false
+	for i, allocation := range genesisConfig.Allocations {
+		if _, ok := stakedFunds[allocation.AVAXAddr]; !ok {
+			continue
+		}
+		if set {
+			genesisConfig.Allocations[i].UnlockSchedule = nil
+			continue
+		}
+		lockedAmount := genesis.LockedAmount{Amount: totalStake}
+		if len(allocation.UnlockSchedule) > 0 {
+			lockedAmount.Locktime = allocation.UnlockSchedule[0].Locktime
+		}
+		genesisConfig.Allocations[i].UnlockSchedule = []genesis.LockedAmount{lockedAmount}
+		set = true
+	}
+	if !set {
+		return errors.New("genesis has no allocation of initial staked funds")
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package network
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+)
+
+const (
+	// Default number of blocks between C-Chain tries committed to disk,
+	// far lower than coreth's so that local chains soon have summaries
+	DefaultStateSyncCommitInterval uint64 = 16
+	// Default number of blocks between C-Chain state summaries,
+	// as a multiple of the commit interval
+	defaultStateSyncSummaryIntervals = 4
+)
+
+// C-Chain config keys of coreth's state sync settings
+const (
+	cChainCommitIntervalKey          = "commit-interval"
+	cChainStateSyncCommitIntervalKey = "state-sync-commit-interval"
+	cChainStateSyncEnabledKey        = "state-sync-enabled"
+	cChainStateSyncMinBlocksKey      = "state-sync-min-blocks"
+)
+
+// StateSyncConfig configures C-Chain state sync in a network.
+// Every node of the network serves state summaries at the given
+// interval, and the nodes it's applied to with sync set (see Apply)
+// state sync rather than bootstrap the C-Chain when they join.
+type StateSyncConfig struct {
+	// Number of blocks between C-Chain tries committed to disk.
+	// Defaults to DefaultStateSyncCommitInterval.
+	CommitInterval uint64 `json:"commitInterval"`
+	// Number of blocks between the state summaries nodes serve.
+	// Must be a multiple of [CommitInterval].
+	// Defaults to 4 times [CommitInterval].
+	SummaryInterval uint64 `json:"summaryInterval"`
+	// Min number of blocks a joining node must be behind the latest
+	// state summary to state sync rather than bootstrap.
+	// Defaults to [SummaryInterval].
+	MinBlocks uint64 `json:"minBlocks"`
+}
+
+// StateSyncReport is the outcome of a node's C-Chain state sync
+type StateSyncReport struct {
+	// Name of the node
+	Node string `json:"node"`
+	// False if the node skipped state sync, e.g. because it
+	// wasn't far enough behind the latest state summary
+	Synced bool `json:"synced"`
+	// How long state sync took, from the time it was
+	// logged as started until it was logged as completed
+	Duration time.Duration `json:"duration"`
+}
+
+// Validate returns an error if [c] is invalid
+func (c *StateSyncConfig) Validate() error {
+	commitInterval, summaryInterval, _ := c.intervals()
+	if summaryInterval%commitInterval != 0 {
+		return errors.New("state sync summary interval isn't a multiple of the commit interval")
+	}
+	return nil
+}
+
+// Returns the commit interval, summary interval and
+// min blocks of [c], defaulted if not given
+func (c *StateSyncConfig) intervals() (uint64, uint64, uint64) {
+	commitInterval := c.CommitInterval
+	if commitInterval == 0 {
+		commitInterval = DefaultStateSyncCommitInterval
+	}
+	summaryInterval := c.SummaryInterval
+	if summaryInterval == 0 {
+		summaryInterval = defaultStateSyncSummaryIntervals * commitInterval
+	}
+	minBlocks := c.MinBlocks
+	if minBlocks == 0 {
+		minBlocks = summaryInterval
+	}
+	return commitInterval, summaryInterval, minBlocks
+}
+
+// Apply sets the C-Chain config of [nodeConfig] so that the node serves
+// state summaries as given by [c]. If [sync], the node also state syncs
+// the C-Chain when it starts, if it's at least [c.MinBlocks] behind.
+// All the nodes of a network must serve summaries at the same interval,
+// which the network does if its Config.StateSync is [c].
+func (c *StateSyncConfig) Apply(nodeConfig *node.Config, sync bool) error {
+	commitInterval, summaryInterval, minBlocks := c.intervals()
+	entries := map[string]interface{}{
+		cChainCommitIntervalKey:          commitInterval,
+		cChainStateSyncCommitIntervalKey: summaryInterval,
+	}
+	if sync {
+		entries[cChainStateSyncEnabledKey] = true
+		entries[cChainStateSyncMinBlocksKey] = minBlocks
+	}
+	return nodeConfig.MergeCChainConfig(entries)
+}
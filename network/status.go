@@ -0,0 +1,76 @@
+package network
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// Status is a snapshot of the state of a network
+type Status struct {
+	// ID of the network, unique across runs
+	UUID string `json:"uuid"`
+	// Sorted by node name
+	Nodes []NodeStatus `json:"nodes"`
+	// URL of the network's block explorer, if any
+	ExplorerURL string `json:"explorerURL,omitempty"`
+}
+
+// NodeStatus is a snapshot of the state of a node
+type NodeStatus struct {
+	Name    string     `json:"name"`
+	NodeID  ids.NodeID `json:"nodeID"`
+	URI     string     `json:"uri"`
+	APIPort uint16     `json:"apiPort"`
+	P2PPort uint16     `json:"p2pPort"`
+	// True if the node is an API node that doesn't
+	// validate the primary network
+	NonValidator bool `json:"nonValidator,omitempty"`
+	// ID of the node's process, or 0 if unknown
+	PID     int           `json:"pid,omitempty"`
+	Healthy bool          `json:"healthy"`
+	Uptime  time.Duration `json:"uptime"`
+	// How long the node's health API took to answer
+	HealthLatency time.Duration `json:"healthLatency"`
+	// Version of the node's binary
+	Version            string   `json:"version"`
+	WhitelistedSubnets []string `json:"whitelistedSubnets"`
+	// Blockchains the node validates or is syncing
+	Blockchains []BlockchainStatus `json:"blockchains"`
+	// Number of peers the node is connected to
+	NumPeers int `json:"numPeers"`
+	// Names of the other nodes of the network
+	// the node isn't connected to, sorted
+	MissingPeers []string `json:"missingPeers,omitempty"`
+	// Errors encountered while getting the node's state.
+	// The fields they relate to are left empty.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// BlockchainStatus is a blockchain run by a node
+type BlockchainStatus struct {
+	ID       ids.ID `json:"id"`
+	Name     string `json:"name"`
+	SubnetID ids.ID `json:"subnetID"`
+	// e.g. "Validating" or "Syncing"
+	Status string `json:"status"`
+}
+
+// Healthy returns true if all nodes are healthy
+func (s Status) Healthy() bool {
+	for _, node := range s.Nodes {
+		if !node.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteJSON writes [s] to [w] as indented JSON
+func (s Status) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
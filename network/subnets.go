@@ -0,0 +1,77 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// SubnetSpec declares a subnet to create when a network starts
+type SubnetSpec struct {
+	// Names of the nodes that validate the subnet.
+	// Must be named nodes of the network's config.
+	Validators []string `json:"validators"`
+	// Blockchains to create in the subnet
+	Blockchains []BlockchainSpec `json:"blockchains"`
+}
+
+// BlockchainSpec declares a blockchain to create in a subnet
+type BlockchainSpec struct {
+	// Name of the VM that runs the blockchain.
+	// Either the name of one of the config's custom VMs, or else
+	// the VM ID is derived from it (see utils.VMID), and the
+	// VM binary must be in the plugin dir of the validators.
+	VMName string `json:"vmName"`
+	// Genesis of the blockchain
+	Genesis string `json:"genesis"`
+	// Name of the blockchain. Defaults to [VMName].
+	Name string `json:"name"`
+}
+
+// Subnet is a subnet created from a SubnetSpec
+type Subnet struct {
+	ID ids.ID `json:"id"`
+	// Names of the nodes that validate the subnet
+	Validators  []string     `json:"validators"`
+	Blockchains []Blockchain `json:"blockchains"`
+}
+
+// Blockchain is a blockchain created from a BlockchainSpec
+type Blockchain struct {
+	ID     ids.ID `json:"id"`
+	Name   string `json:"name"`
+	VMName string `json:"vmName"`
+	VMID   ids.ID `json:"vmID"`
+}
+
+// Validate returns an error if [s] is invalid.
+// [nodeNames] is the set of names of the network's nodes,
+// and [customVMs] the network's custom VMs.
+func (s *SubnetSpec) Validate(nodeNames map[string]struct{}, customVMs []CustomVM) error {
+	if len(s.Validators) == 0 {
+		return errors.New("no validators given")
+	}
+	seen := make(map[string]struct{}, len(s.Validators))
+	for _, validator := range s.Validators {
+		if _, ok := nodeNames[validator]; !ok {
+			return fmt.Errorf("validator %q isn't a node of the network", validator)
+		}
+		if _, ok := seen[validator]; ok {
+			return fmt.Errorf("validator %q given twice", validator)
+		}
+		seen[validator] = struct{}{}
+	}
+	for i, blockchain := range s.Blockchains {
+		if _, err := VMIDFromName(blockchain.VMName, customVMs); err != nil {
+			return fmt.Errorf("blockchain %d: %w", i, err)
+		}
+		switch {
+		case blockchain.VMName == "":
+			return fmt.Errorf("blockchain %d: no VM name given", i)
+		case blockchain.Genesis == "":
+			return fmt.Errorf("blockchain %d: no genesis given", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrNotStopped = errors.New("network not stopped")
+
+// TeardownViolationKind is the kind of resource left behind by a network
+type TeardownViolationKind string
+
+const (
+	// A node's process is still running
+	ViolationProcessRunning TeardownViolationKind = "process running"
+	// A port used by a node is still bound
+	ViolationPortBound TeardownViolationKind = "port bound"
+	// A node's data dir isn't in the expected state
+	ViolationDataDir TeardownViolationKind = "data dir"
+)
+
+// TeardownViolation is a resource a stopped network didn't clean up as expected
+type TeardownViolation struct {
+	// Name of the node the resource belonged to
+	Node    string                `json:"node"`
+	Kind    TeardownViolationKind `json:"kind"`
+	Details string                `json:"details"`
+}
+
+func (v TeardownViolation) String() string {
+	return fmt.Sprintf("node %q: %s: %s", v.Node, v.Kind, v.Details)
+}
+
+// TeardownReport is the result of checking that a stopped network
+// released all the resources its nodes used
+type TeardownReport struct {
+	Violations []TeardownViolation `json:"violations"`
+}
+
+// Err returns an error describing the violations,
+// or nil if there are none
+func (r TeardownReport) Err() error {
+	if len(r.Violations) == 0 {
+		return nil
+	}
+	violations := make([]string, len(r.Violations))
+	for i, violation := range r.Violations {
+		violations[i] = violation.String()
+	}
+	return fmt.Errorf("teardown incomplete: %s", strings.Join(violations, "; "))
+}
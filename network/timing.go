@@ -0,0 +1,46 @@
+package network
+
+import "time"
+
+// NodeTiming is when a node reached each milestone of its startup.
+// Milestones not reached yet are zero.
+type NodeTiming struct {
+	// Name of the node
+	Node string `json:"node"`
+	// When the node's process was started
+	ProcessStarted time.Time `json:"processStarted"`
+	// When the node first answered an API request
+	FirstAPIResponse time.Time `json:"firstAPIResponse"`
+	// When the node first reported healthy through the Health API
+	Healthy time.Time `json:"healthy"`
+	// Chain alias or ID --> when the node reported it bootstrapped.
+	// Has the chains the node ran when it was started.
+	ChainsBootstrapped map[string]time.Time `json:"chainsBootstrapped"`
+}
+
+// TimeToHealthy returns how long the node took to report healthy
+// since its process was started, or 0 if it hasn't yet
+func (t *NodeTiming) TimeToHealthy() time.Duration {
+	if t.Healthy.IsZero() {
+		return 0
+	}
+	return t.Healthy.Sub(t.ProcessStarted)
+}
+
+// TimeToBootstrap returns how long the node took to bootstrap [chain]
+// since its process was started, or 0 if it hasn't yet
+func (t *NodeTiming) TimeToBootstrap(chain string) time.Duration {
+	bootstrapped, ok := t.ChainsBootstrapped[chain]
+	if !ok || bootstrapped.IsZero() {
+		return 0
+	}
+	return bootstrapped.Sub(t.ProcessStarted)
+}
+
+// TimingReport has the startup timings of a network's nodes,
+// recorded if the network's config has RecordTimings set
+type TimingReport struct {
+	// Sorted by node name. A restarted node has the timings of its
+	// last start. Nodes removed from the network are kept.
+	Nodes []NodeTiming `json:"nodes"`
+}
@@ -0,0 +1,91 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// Topology is a snapshot of the P2P connections of a network's nodes,
+// as reported by each node's Info API
+type Topology struct {
+	// Node ID --> the node's peers, sorted by node ID.
+	// Nodes whose peers couldn't be queried are in [Errors] instead.
+	Peers map[ids.NodeID][]PeerConnection `json:"peers"`
+	// Node ID --> name of the network's node with that ID
+	Names map[ids.NodeID]string `json:"names"`
+	// Node name --> error querying its peers
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// PeerConnection is a connection of a node to a peer.
+// avalanchego's Info API doesn't report the latency of a connection,
+// so how recently messages were exchanged is reported instead.
+type PeerConnection struct {
+	NodeID ids.NodeID `json:"nodeID"`
+	// Name of the peer in the network, or empty if it isn't
+	// a node of the network, such as a peer attached by a test
+	Name string `json:"name,omitempty"`
+	// IP and port the node is connected to the peer on
+	IP           string    `json:"ip"`
+	LastSent     time.Time `json:"lastSent"`
+	LastReceived time.Time `json:"lastReceived"`
+	// Uptime of the node observed by the peer, in percent
+	ObservedUptime uint8 `json:"observedUptime"`
+	// IDs of the chains on which the peer is benched by the node
+	Benched []ids.ID `json:"benched,omitempty"`
+}
+
+// Connected returns true if node [a] reports being connected to node [b]
+func (t Topology) Connected(a, b ids.NodeID) bool {
+	for _, peer := range t.Peers[a] {
+		if peer.NodeID == b {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteDOT writes [t] to [w] as an undirected Graphviz graph whose vertices
+// are the nodes, labeled by name, and whose edges are the connections.
+// A connection reported by only one of its ends is dashed.
+// Peers that aren't nodes of the network are left out.
+func (t Topology) WriteDOT(w io.Writer) error {
+	nodeIDs := make([]ids.NodeID, 0, len(t.Names))
+	for nodeID := range t.Names {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Slice(nodeIDs, func(i, j int) bool {
+		return t.Names[nodeIDs[i]] < t.Names[nodeIDs[j]]
+	})
+
+	if _, err := fmt.Fprintln(w, "graph topology {"); err != nil {
+		return err
+	}
+	for _, nodeID := range nodeIDs {
+		if _, err := fmt.Fprintf(w, "\t%q [label=%q];\n", nodeID, t.Names[nodeID]); err != nil {
+			return err
+		}
+	}
+	for i, a := range nodeIDs {
+		for _, b := range nodeIDs[i+1:] {
+			aToB, bToA := t.Connected(a, b), t.Connected(b, a)
+			var attrs string
+			switch {
+			case aToB && bToA:
+			case aToB || bToA:
+				attrs = " [style=dashed]"
+			default:
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "\t%q -- %q%s;\n", a, b, attrs); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
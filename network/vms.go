@@ -0,0 +1,95 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/ava-labs/avalanche-network-runner/utils"
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// CustomVM is a VM binary installed in the plugin dir of every node.
+// The binary is linked, or copied if it can't be linked, into the
+// node's plugin dir under the VM ID, so the binary can be anywhere on disk.
+type CustomVM struct {
+	// Name of the VM.
+	// Blockchain specs refer to the VM by this name.
+	Name string `json:"name"`
+	// VM ID, CB58 encoded.
+	// If empty, it's derived from [Name] (see utils.VMID).
+	ID string `json:"id"`
+	// Path to the VM binary
+	BinaryPath string `json:"binaryPath"`
+}
+
+// VMID returns the ID of the VM
+func (vm *CustomVM) VMID() (ids.ID, error) {
+	if vm.ID == "" {
+		return utils.VMID(vm.Name)
+	}
+	vmID, err := ids.FromString(vm.ID)
+	if err != nil {
+		return ids.Empty, fmt.Errorf("invalid VM ID %q: %w", vm.ID, err)
+	}
+	return vmID, nil
+}
+
+// Validate returns an error if [vm] is invalid
+func (vm *CustomVM) Validate() error {
+	switch {
+	case vm.Name == "":
+		return errors.New("no VM name given")
+	case vm.BinaryPath == "":
+		return errors.New("no VM binary path given")
+	}
+	if _, err := vm.VMID(); err != nil {
+		return err
+	}
+	info, err := os.Stat(vm.BinaryPath)
+	if err != nil {
+		return fmt.Errorf("couldn't find VM binary: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("VM binary %q isn't a regular file", vm.BinaryPath)
+	}
+	// Windows has no executable permission bits
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("VM binary %q isn't executable", vm.BinaryPath)
+	}
+	return nil
+}
+
+// VMIDFromName returns the ID of the VM named [vmName], which is
+// either one of [customVMs] or else has an ID derived from its name
+func VMIDFromName(vmName string, customVMs []CustomVM) (ids.ID, error) {
+	for _, vm := range customVMs {
+		if vm.Name == vmName {
+			return vm.VMID()
+		}
+	}
+	return utils.VMID(vmName)
+}
+
+// validateCustomVMs returns an error if a custom VM is
+// invalid, or if two of them have the same name or ID
+func validateCustomVMs(vms []CustomVM) error {
+	names := make(map[string]struct{}, len(vms))
+	vmIDs := make(map[ids.ID]struct{}, len(vms))
+	for i, vm := range vms {
+		if err := vm.Validate(); err != nil {
+			return fmt.Errorf("custom VM %d failed validation: %w", i, err)
+		}
+		if _, ok := names[vm.Name]; ok {
+			return fmt.Errorf("custom VM name %q given twice", vm.Name)
+		}
+		names[vm.Name] = struct{}{}
+		vmID, _ := vm.VMID()
+		if _, ok := vmIDs[vmID]; ok {
+			return fmt.Errorf("custom VM ID %s given twice", vmID)
+		}
+		vmIDs[vmID] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,432 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package binutils downloads avalanchego release binaries, so that
+// a network can be run from a version instead of a local build.
+package binutils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultDownloadURL = "https://github.com/ava-labs/avalanchego/releases/download"
+	DefaultReleasesURL = "https://api.github.com/repos/ava-labs/avalanchego/releases"
+
+	binaryName = "avalanchego"
+)
+
+var (
+	// e.g. v1.7.11, or v1.7.x for the latest v1.7 patch release
+	versionRegex = regexp.MustCompile(`^v(\d+)\.(\d+)\.(\d+|x)$`)
+
+	// e.g. <https://api.github.com/...?page=2>; rel="next"
+	nextLinkRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+	errChecksumMismatch = errors.New("checksum mismatch")
+	errNoChecksum       = errors.New("no checksum")
+	errFound            = errors.New("found")
+)
+
+// Release as listed by GitHub's releases API
+type release struct {
+	TagName    string `json:"tag_name"`
+	Prerelease bool   `json:"prerelease"`
+	Draft      bool   `json:"draft"`
+	Assets     []struct {
+		Name string `json:"name"`
+		// e.g. sha256:<hex encoded hash>
+		Digest string `json:"digest"`
+	} `json:"assets"`
+}
+
+// IsVersion returns true if [s] is a version that can be given to Download,
+// rather than e.g. the path to a binary
+func IsVersion(s string) bool {
+	return versionRegex.MatchString(s)
+}
+
+// Downloader downloads avalanchego release binaries,
+// and caches them so each version is downloaded once
+type Downloader struct {
+	// Where downloaded releases are unpacked
+	CacheDir string
+	// Release assets are downloaded from [DownloadURL]/<version>/<asset name>
+	DownloadURL string
+	// Lists the releases, to resolve versions such as v1.7.x.
+	// Must return GitHub's release list format.
+	ReleasesURL string
+	// Asset name --> hex encoded SHA-256 hash of the asset.
+	// Assets without a checksum here are verified against the
+	// digest GitHub publishes for them at [ReleasesURL]/tags/<version>.
+	// The download fails if the asset doesn't match its checksum,
+	// or if it has no checksum at all.
+	Checksums map[string]string
+	// If true, assets without a checksum are downloaded unverified
+	// rather than failing the download
+	SkipMissingChecksums bool
+	Client               *http.Client
+}
+
+// NewDownloader returns a downloader of GitHub
+// releases that caches them in [cacheDir]
+func NewDownloader(cacheDir string) *Downloader {
+	return &Downloader{
+		CacheDir:    cacheDir,
+		DownloadURL: DefaultDownloadURL,
+		ReleasesURL: DefaultReleasesURL,
+		Client:      http.DefaultClient,
+	}
+}
+
+// DefaultCacheDir returns the per user cache dir of release binaries
+func DefaultCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "avalanche-network-runner", "binaries"), nil
+}
+
+// AssetName returns the name of the release asset of [version] for
+// the given OS and architecture (e.g. runtime.GOOS and runtime.GOARCH)
+func AssetName(version string, goos string, goarch string) (string, error) {
+	switch goos {
+	case "linux":
+		return fmt.Sprintf("avalanchego-linux-%s-%s.tar.gz", goarch, version), nil
+	case "darwin":
+		return fmt.Sprintf("avalanchego-macos-%s.zip", version), nil
+	default:
+		return "", fmt.Errorf("no avalanchego releases for %s/%s", goos, goarch)
+	}
+}
+
+// Download returns the path of the avalanchego binary of [version],
+// downloading and unpacking the release if it isn't cached already.
+// The plugins of the release are in the "plugins" dir next to the binary.
+// [version] may end with ".x" to get the latest patch release.
+func (d *Downloader) Download(ctx context.Context, version string) (string, error) {
+	if !IsVersion(version) {
+		return "", fmt.Errorf("invalid version %q", version)
+	}
+	if strings.HasSuffix(version, ".x") {
+		resolved, err := d.resolve(ctx, version)
+		if err != nil {
+			return "", err
+		}
+		version = resolved
+	}
+
+	versionDir := filepath.Join(d.CacheDir, version)
+	if binaryPath, err := findBinary(versionDir); err == nil {
+		return binaryPath, nil
+	}
+
+	assetName, err := AssetName(version, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(d.CacheDir, 0o755); err != nil {
+		return "", err
+	}
+	// Unpack to a temporary dir which is then renamed, so
+	// a partial download is never mistaken for a cached one
+	tmpDir, err := os.MkdirTemp(d.CacheDir, version+"-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	checksum, err := d.checksum(ctx, version, assetName)
+	if err != nil {
+		return "", err
+	}
+	assetPath := filepath.Join(tmpDir, assetName)
+	if err := d.downloadAsset(ctx, version, assetName, checksum, assetPath); err != nil {
+		return "", err
+	}
+	unpackDir := filepath.Join(tmpDir, "release")
+	if strings.HasSuffix(assetName, ".zip") {
+		err = unzip(assetPath, unpackDir)
+	} else {
+		err = untar(assetPath, unpackDir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("couldn't unpack %s: %w", assetName, err)
+	}
+	if _, err := findBinary(unpackDir); err != nil {
+		return "", fmt.Errorf("release %s: %w", version, err)
+	}
+	if err := os.Rename(unpackDir, versionDir); err != nil {
+		// Another download of the same version may have finished first,
+		// in which case its result is used
+		if _, findErr := findBinary(versionDir); findErr != nil {
+			return "", err
+		}
+	}
+	return findBinary(versionDir)
+}
+
+// ResolveBinaryPath returns [pathOrVersion] if it isn't a version,
+// or else the path of the binary of that version downloaded to
+// the default cache dir. See Downloader.Download.
+func ResolveBinaryPath(ctx context.Context, pathOrVersion string) (string, error) {
+	if !IsVersion(pathOrVersion) {
+		return pathOrVersion, nil
+	}
+	cacheDir, err := DefaultCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return NewDownloader(cacheDir).Download(ctx, pathOrVersion)
+}
+
+// Returns the hex encoded SHA-256 hash that [assetName] of [version] must have,
+// or "" if it has none and [d.SkipMissingChecksums] is set
+func (d *Downloader) checksum(ctx context.Context, version string, assetName string) (string, error) {
+	if checksum, ok := d.Checksums[assetName]; ok {
+		return checksum, nil
+	}
+	var rel release
+	url := fmt.Sprintf("%s/tags/%s", strings.TrimSuffix(d.ReleasesURL, "/"), version)
+	if _, err := d.getJSON(ctx, url, &rel); err != nil {
+		if d.SkipMissingChecksums {
+			return "", nil
+		}
+		return "", fmt.Errorf("couldn't get the checksum of %s: %w", assetName, err)
+	}
+	for _, asset := range rel.Assets {
+		if asset.Name == assetName && strings.HasPrefix(asset.Digest, "sha256:") {
+			return strings.TrimPrefix(asset.Digest, "sha256:"), nil
+		}
+	}
+	if d.SkipMissingChecksums {
+		return "", nil
+	}
+	return "", fmt.Errorf("%w for %s: release %s publishes none, set Downloader.Checksums or Downloader.SkipMissingChecksums", errNoChecksum, assetName, version)
+}
+
+// Downloads [assetName] of [version] to [path], and verifies it has
+// the hex encoded SHA-256 hash [checksum] unless [checksum] is empty
+func (d *Downloader) downloadAsset(ctx context.Context, version string, assetName string, checksum string, path string) error {
+	url := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(d.DownloadURL, "/"), version, assetName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("couldn't download %s: %s", url, resp.Status)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hash), resp.Body); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("couldn't download %s: %w", url, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if checksum != "" {
+		if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(actual, checksum) {
+			return fmt.Errorf("%w for %s: expected %s, got %s", errChecksumMismatch, assetName, checksum, actual)
+		}
+	}
+	return nil
+}
+
+// Decodes the JSON at [url] into [v], and returns the
+// URL of the next page given by the response's Link header, if any
+func (d *Downloader) getJSON(ctx context.Context, url string, v interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("couldn't get %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("couldn't get %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("couldn't parse %s: %w", url, err)
+	}
+	if match := nextLinkRegex.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+		return match[1], nil
+	}
+	return "", nil
+}
+
+// Returns the latest non-prerelease version matching [version],
+// which ends with ".x"
+func (d *Downloader) resolve(ctx context.Context, version string) (string, error) {
+	prefix := strings.TrimSuffix(version, "x")
+	latest, latestPatch := "", -1
+	// Releases are listed newest first, so older series
+	// may only be on later pages
+	url := strings.TrimSuffix(d.ReleasesURL, "/") + "?per_page=100"
+	for url != "" && latest == "" {
+		var releases []release
+		next, err := d.getJSON(ctx, url, &releases)
+		if err != nil {
+			return "", fmt.Errorf("couldn't list releases: %w", err)
+		}
+		for _, rel := range releases {
+			if rel.Prerelease || rel.Draft || !strings.HasPrefix(rel.TagName, prefix) || !IsVersion(rel.TagName) {
+				continue
+			}
+			patch, err := strconv.Atoi(strings.TrimPrefix(rel.TagName, prefix))
+			if err != nil {
+				continue
+			}
+			if patch > latestPatch {
+				latest, latestPatch = rel.TagName, patch
+			}
+		}
+		url = next
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no release matches %s", version)
+	}
+	return latest, nil
+}
+
+// Returns the path of the avalanchego binary under [dir]
+func findBinary(dir string) (string, error) {
+	var binaryPath string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && info.Name() == binaryName {
+			binaryPath = path
+			return errFound
+		}
+		return nil
+	})
+	if err != nil && err != errFound {
+		return "", err
+	}
+	if binaryPath == "" {
+		return "", fmt.Errorf("no %s binary in %s", binaryName, dir)
+	}
+	return binaryPath, nil
+}
+
+// Returns the path under [dir] to unpack the archive entry [name] to
+func entryPath(dir string, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("archive entry %q is outside of the archive", name)
+	}
+	return path, nil
+}
+
+func untar(archivePath string, dir string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gzipReader, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path, err := entryPath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(path, tarReader, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func unzip(archivePath string, dir string) error {
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zipReader.Close()
+	for _, file := range zipReader.File {
+		path, err := entryPath(dir, file.Name)
+		if err != nil {
+			return err
+		}
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(path, r, file.Mode())
+		_ = r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
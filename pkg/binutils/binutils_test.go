@@ -0,0 +1,145 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package binutils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a tar.gz with the given files
+func newTarGz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, contents := range files {
+		assert.NoError(t, tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o755,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tarWriter.Write([]byte(contents))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tarWriter.Close())
+	assert.NoError(t, gzipWriter.Close())
+	return buf.Bytes()
+}
+
+func TestIsVersion(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(IsVersion("v1.7.11"))
+	assert.True(IsVersion("v1.7.x"))
+	assert.False(IsVersion("1.7.11"))
+	assert.False(IsVersion("v1.x.x"))
+	assert.False(IsVersion("/usr/bin/avalanchego"))
+}
+
+func TestDownload(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("test release is a linux release")
+	}
+	assert := assert.New(t)
+
+	escapingArchive := newTarGz(t, map[string]string{
+		"avalanchego-v1.7.11/avalanchego":     "binary",
+		"avalanchego-v1.7.11/plugins/evm":     "plugin",
+		"avalanchego-v1.7.11/../../../escape": "",
+	})
+	otherArchive := newTarGz(t, map[string]string{"avalanchego-v1.7.11/avalanchego": "other binary"})
+	assetName, err := AssetName("v1.7.11", runtime.GOOS, runtime.GOARCH)
+	assert.NoError(err)
+	escapingAssetName, err := AssetName("v1.7.10", runtime.GOOS, runtime.GOARCH)
+	assert.NoError(err)
+	goodArchive := newTarGz(t, map[string]string{
+		"avalanchego-v1.7.11/avalanchego": "binary",
+		"avalanchego-v1.7.11/plugins/evm": "plugin",
+	})
+
+	goodHash := sha256.Sum256(goodArchive)
+
+	var numDownloads int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases":
+			assert.Equal("100", r.URL.Query().Get("per_page"))
+			// The v1.7 series is only on the second page
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", fmt.Sprintf(`<%s/releases?per_page=100&page=2>; rel="next"`, server.URL))
+				fmt.Fprint(w, `[{"tag_name": "v1.8.1"}, {"tag_name": "v1.8.0"}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"tag_name": "v1.7.12", "prerelease": true}, {"tag_name": "v1.7.11"}, {"tag_name": "v1.7.9"}]`)
+		case "/releases/tags/v1.7.11":
+			fmt.Fprintf(w, `{"tag_name": "v1.7.11", "assets": [{"name": %q, "digest": "sha256:%s"}]}`, assetName, hex.EncodeToString(goodHash[:]))
+		case "/releases/tags/v1.7.10":
+			fmt.Fprint(w, `{"tag_name": "v1.7.10", "assets": []}`)
+		case "/download/v1.7.11/" + assetName:
+			atomic.AddInt32(&numDownloads, 1)
+			_, _ = w.Write(goodArchive)
+		case "/download/v1.7.10/" + escapingAssetName:
+			_, _ = w.Write(escapingArchive)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	d := NewDownloader(t.TempDir())
+	d.DownloadURL = server.URL + "/download"
+	d.ReleasesURL = server.URL + "/releases"
+
+	// Assets without a checksum aren't downloaded
+	_, err = d.Download(context.Background(), "v1.7.10")
+	assert.True(errors.Is(err, errNoChecksum))
+
+	// Unless that's explicitly allowed.
+	// Archive entries can't escape the unpack dir.
+	d.SkipMissingChecksums = true
+	_, err = d.Download(context.Background(), "v1.7.10")
+	assert.Error(err)
+	assert.Contains(err.Error(), "outside of the archive")
+	d.SkipMissingChecksums = false
+
+	// Download, verified against the published digest, and then use the cache
+	for i := 0; i < 2; i++ {
+		binaryPath, err := d.Download(context.Background(), "v1.7.x")
+		assert.NoError(err)
+		assert.Equal(filepath.Join(d.CacheDir, "v1.7.11", "avalanchego-v1.7.11", "avalanchego"), binaryPath)
+		contents, err := os.ReadFile(binaryPath)
+		assert.NoError(err)
+		assert.Equal("binary", string(contents))
+		_, err = os.Stat(filepath.Join(filepath.Dir(binaryPath), "plugins", "evm"))
+		assert.NoError(err)
+	}
+	assert.EqualValues(1, atomic.LoadInt32(&numDownloads))
+
+	// Checksum mismatch
+	d.CacheDir = t.TempDir()
+	hash := sha256.Sum256(otherArchive)
+	d.Checksums = map[string]string{assetName: hex.EncodeToString(hash[:])}
+	_, err = d.Download(context.Background(), "v1.7.11")
+	assert.True(errors.Is(err, errChecksumMismatch))
+
+	// No such release
+	_, err = d.Download(context.Background(), "v1.6.x")
+	assert.Error(err)
+}
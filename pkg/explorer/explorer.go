@@ -0,0 +1,206 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package explorer runs a block explorer or indexer, from a binary or a
+// docker image, against the APIs of a running network.
+package explorer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+const (
+	// Max time for the explorer to exit once asked to
+	stopTimeout = 10 * time.Second
+	// Name of the docker CLI binary
+	dockerBinary = "docker"
+)
+
+// Config of an explorer. Exactly one of BinaryPath and Image must be given.
+//
+// The explorer is given the network's endpoints (see Endpoints) in
+// environment variables, e.g. EXPLORER_C_CHAIN_RPC_URL, and [Args]
+// may refer to them as Go templates, e.g. "--rpc={{.CChainRPCURL}}".
+type Config struct {
+	// Path of the binary run as the explorer
+	BinaryPath string `json:"binaryPath"`
+	// Docker image run as the explorer. The container uses
+	// the host's network, so it can reach the nodes.
+	Image string `json:"image"`
+	// Args given to the binary or the image's entrypoint
+	Args []string `json:"args"`
+	// Additional environment variables, e.g. "KEY=value"
+	Env []string `json:"env"`
+	// Port the explorer serves its UI on, on 127.0.0.1
+	Port uint16 `json:"port"`
+	// Path of the explorer's UI, appended to its URL. May be empty.
+	Path string `json:"path"`
+}
+
+// Validate returns an error if this config is invalid
+func (c *Config) Validate() error {
+	switch {
+	case c.BinaryPath == "" && c.Image == "":
+		return errors.New("neither binary path nor image given")
+	case c.BinaryPath != "" && c.Image != "":
+		return errors.New("both binary path and image given")
+	case c.Port == 0:
+		return errors.New("port not given")
+	}
+	return nil
+}
+
+// URL returns the URL of the explorer's UI
+func (c *Config) URL() string {
+	return "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(int(c.Port))) + c.Path
+}
+
+// Endpoints of the network the explorer is given
+type Endpoints struct {
+	NetworkID uint32
+	// URI of the node the explorer uses, e.g. http://127.0.0.1:9650
+	NodeURI      string
+	CChainRPCURL string
+	CChainWSURL  string
+	XChainURL    string
+	PChainURL    string
+	// Port the explorer must serve its UI on
+	Port uint16
+}
+
+// NewEndpoints returns the endpoints of the node at [nodeURI],
+// of a network with ID [networkID]
+func NewEndpoints(networkID uint32, nodeURI string, port uint16) Endpoints {
+	wsURI := "ws" + strings.TrimPrefix(nodeURI, "http")
+	return Endpoints{
+		NetworkID:    networkID,
+		NodeURI:      nodeURI,
+		CChainRPCURL: nodeURI + "/ext/bc/C/rpc",
+		CChainWSURL:  wsURI + "/ext/bc/C/ws",
+		XChainURL:    nodeURI + "/ext/bc/X",
+		PChainURL:    nodeURI + "/ext/bc/P",
+		Port:         port,
+	}
+}
+
+// Returns the environment variables giving [e]
+func (e Endpoints) env() []string {
+	return []string{
+		fmt.Sprintf("EXPLORER_NETWORK_ID=%d", e.NetworkID),
+		"EXPLORER_NODE_URI=" + e.NodeURI,
+		"EXPLORER_C_CHAIN_RPC_URL=" + e.CChainRPCURL,
+		"EXPLORER_C_CHAIN_WS_URL=" + e.CChainWSURL,
+		"EXPLORER_X_CHAIN_URL=" + e.XChainURL,
+		"EXPLORER_P_CHAIN_URL=" + e.PChainURL,
+		fmt.Sprintf("EXPLORER_PORT=%d", e.Port),
+	}
+}
+
+// Explorer is a running explorer
+type Explorer struct {
+	log logging.Logger
+	url string
+	cmd *exec.Cmd
+	// Name of the docker container, if run from an image
+	containerName string
+	// Closed when the process exits
+	done chan struct{}
+}
+
+// Start starts the explorer of [config] against [endpoints].
+// [name] identifies it, e.g. in the name of its container.
+func Start(log logging.Logger, name string, config Config, endpoints Endpoints) (*Explorer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	args := make([]string, len(config.Args))
+	for i, arg := range config.Args {
+		expanded, err := expand(arg, endpoints)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't expand arg %q: %w", arg, err)
+		}
+		args[i] = expanded
+	}
+	env := append(endpoints.env(), config.Env...)
+
+	e := &Explorer{
+		log:  log,
+		url:  config.URL(),
+		done: make(chan struct{}),
+	}
+	if config.Image != "" {
+		e.containerName = "anr-explorer-" + name
+		dockerArgs := []string{"run", "--rm", "--name", e.containerName, "--network", "host"}
+		for _, kv := range env {
+			dockerArgs = append(dockerArgs, "-e", kv)
+		}
+		dockerArgs = append(dockerArgs, config.Image)
+		e.cmd = exec.Command(dockerBinary, append(dockerArgs, args...)...)
+	} else {
+		e.cmd = exec.Command(config.BinaryPath, args...)
+		e.cmd.Env = append(os.Environ(), env...)
+	}
+	if err := e.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("couldn't start explorer: %w", err)
+	}
+	go func() {
+		err := e.cmd.Wait()
+		log.Debug("explorer exited: %v", err)
+		close(e.done)
+	}()
+	return e, nil
+}
+
+// Expands the templates in [arg] with [endpoints]
+func expand(arg string, endpoints Endpoints) (string, error) {
+	tmpl, err := template.New("arg").Option("missingkey=error").Parse(arg)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, endpoints); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// URL returns the URL of the explorer's UI
+func (e *Explorer) URL() string {
+	return e.url
+}
+
+// Close stops the explorer, killing it if it
+// doesn't exit within a timeout
+func (e *Explorer) Close() error {
+	if e.containerName != "" {
+		// Killing the docker CLI doesn't stop the container
+		if out, err := exec.Command(dockerBinary, "stop", e.containerName).CombinedOutput(); err != nil {
+			e.log.Warn("couldn't stop container %s: %s: %s", e.containerName, err, out)
+		}
+	} else if err := e.cmd.Process.Signal(os.Interrupt); err != nil {
+		// Interrupts aren't supported on windows
+		_ = e.cmd.Process.Kill()
+	}
+	select {
+	case <-e.done:
+		return nil
+	case <-time.After(stopTimeout):
+	}
+	e.log.Warn("explorer didn't exit in %s; killing it", stopTimeout)
+	if err := e.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("couldn't kill explorer: %w", err)
+	}
+	<-e.done
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package explorer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidate(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError((&Config{BinaryPath: "explorer", Port: 4000}).Validate())
+	assert.NoError((&Config{Image: "explorer:latest", Port: 4000}).Validate())
+	assert.Error((&Config{Port: 4000}).Validate())
+	assert.Error((&Config{BinaryPath: "explorer", Image: "explorer:latest", Port: 4000}).Validate())
+	assert.Error((&Config{BinaryPath: "explorer"}).Validate())
+}
+
+func TestNewEndpoints(t *testing.T) {
+	endpoints := NewEndpoints(1337, "http://127.0.0.1:9650", 4000)
+	assert.Equal(t, Endpoints{
+		NetworkID:    1337,
+		NodeURI:      "http://127.0.0.1:9650",
+		CChainRPCURL: "http://127.0.0.1:9650/ext/bc/C/rpc",
+		CChainWSURL:  "ws://127.0.0.1:9650/ext/bc/C/ws",
+		XChainURL:    "http://127.0.0.1:9650/ext/bc/X",
+		PChainURL:    "http://127.0.0.1:9650/ext/bc/P",
+		Port:         4000,
+	}, endpoints)
+	assert.Equal(t, "wss://localhost:9650/ext/bc/C/ws", NewEndpoints(1337, "https://localhost:9650", 4000).CChainWSURL)
+}
+
+func TestExplorer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	assert := assert.New(t)
+	dir := t.TempDir()
+	outPath := filepath.Join(dir, "out")
+	// Writes its args and endpoint variables, then runs until interrupted
+	binaryPath := filepath.Join(dir, "explorer")
+	script := "#!/bin/sh\necho \"$@ $EXPLORER_NETWORK_ID $EXPLORER_C_CHAIN_RPC_URL $EXTRA\" > " + outPath + "\ntrap 'exit 0' INT\nwhile true; do sleep 0.1; done\n"
+	assert.NoError(os.WriteFile(binaryPath, []byte(script), 0o755))
+
+	config := Config{
+		BinaryPath: binaryPath,
+		Args:       []string{"--rpc={{.CChainRPCURL}}", "--port={{.Port}}"},
+		Env:        []string{"EXTRA=extra"},
+		Port:       4000,
+		Path:       "/ui",
+	}
+	e, err := Start(logging.NoLog{}, "test", config, NewEndpoints(1337, "http://127.0.0.1:9650", config.Port))
+	assert.NoError(err)
+	assert.Equal("http://127.0.0.1:4000/ui", e.URL())
+	var out []byte
+	assert.Eventually(func() bool {
+		out, err = os.ReadFile(outPath)
+		return err == nil && len(out) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal("--rpc=http://127.0.0.1:9650/ext/bc/C/rpc --port=4000 1337 http://127.0.0.1:9650/ext/bc/C/rpc extra\n", string(out))
+	assert.NoError(e.Close())
+	assert.True(e.cmd.ProcessState.Exited())
+
+	// Args must be valid templates
+	config.Args = []string{"{{.NotAnEndpoint}}"}
+	_, err = Start(logging.NoLog{}, "test", config, Endpoints{})
+	assert.Error(err)
+}
@@ -0,0 +1,268 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package faucet implements an HTTP service that sends AVAX on the
+// primary network chains of a running network to requested addresses.
+package faucet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/formatting/address"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// nAVAX sent per request, unless given, i.e. 100 AVAX
+	DefaultAmount = 100_000_000_000
+	// Min time between two requests to fund an address, unless given
+	DefaultAddrInterval = time.Minute
+	// Max time to fund an address
+	fundTimeout = 2 * time.Minute
+)
+
+// Funder sends nAVAX on the primary network chains, e.g. a *wallet.Wallet
+type Funder interface {
+	FundX(ctx context.Context, to ids.ShortID, amount uint64) (ids.ID, error)
+	FundP(ctx context.Context, to ids.ShortID, amount uint64) (ids.ID, ids.ID, error)
+	FundC(ctx context.Context, to ethcommon.Address, amount uint64) (ethcommon.Hash, error)
+}
+
+// Config of a faucet. The zero value is a valid config.
+type Config struct {
+	// Address the faucet listens on.
+	// Defaults to 127.0.0.1 on a free port.
+	ListenAddr string `json:"listenAddr"`
+	// nAVAX sent per request. Defaults to DefaultAmount.
+	Amount uint64 `json:"amount"`
+	// Min time between two requests to fund the same
+	// address on the same chain. Defaults to DefaultAddrInterval.
+	AddrInterval time.Duration `json:"addrInterval"`
+	// Max number of requests per second from all clients.
+	// Zero means unlimited.
+	RequestsPerSec float64 `json:"requestsPerSec"`
+}
+
+// Validate returns an error if this config is invalid
+func (c *Config) Validate() error {
+	switch {
+	case c.AddrInterval < 0:
+		return errors.New("negative address interval given")
+	case c.RequestsPerSec < 0:
+		return errors.New("negative requests per second given")
+	}
+	return nil
+}
+
+// Request is the body of a request to the faucet
+type Request struct {
+	// "X", "P" or "C"
+	Chain string `json:"chain"`
+	// Address to fund. A bech32 address (e.g. X-custom1...)
+	// on the X-Chain and P-Chain, a hex address on the C-Chain.
+	Address string `json:"address"`
+}
+
+// Response is the body of the faucet's response to a request
+type Response struct {
+	// IDs of the accepted txs that funded the address.
+	// The funds of a P-Chain address are exported from
+	// the X-Chain and imported to the P-Chain.
+	TxIDs []string `json:"txIDs,omitempty"`
+	// nAVAX sent
+	Amount uint64 `json:"amount,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Faucet is an HTTP service that funds the address given by a
+// Request POSTed to it. Requests are funded one at a time.
+type Faucet struct {
+	amount       uint64
+	addrInterval time.Duration
+	limiter      *rate.Limiter
+	newFunder    func(context.Context) (Funder, error)
+
+	listener net.Listener
+	server   *http.Server
+
+	// Serializes funding, as funders track
+	// the UTXOs/nonces of their keys
+	lock   sync.Mutex
+	funder Funder
+	// Chain and address --> when it was last funded
+	lastFunded map[string]time.Time
+}
+
+// New returns a faucet that serves requests as given by [config].
+// [newFunder] is called on the first request, and until it succeeds,
+// so that the faucet can be started before the network is healthy.
+func New(config Config, newFunder func(context.Context) (Funder, error)) (*Faucet, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	listenAddr := config.ListenAddr
+	if listenAddr == "" {
+		listenAddr = "127.0.0.1:0"
+	}
+	amount := config.Amount
+	if amount == 0 {
+		amount = DefaultAmount
+	}
+	addrInterval := config.AddrInterval
+	if addrInterval == 0 {
+		addrInterval = DefaultAddrInterval
+	}
+	limit, burst := rate.Inf, 1
+	if config.RequestsPerSec > 0 {
+		limit = rate.Limit(config.RequestsPerSec)
+	}
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't listen on %s: %w", listenAddr, err)
+	}
+	f := &Faucet{
+		amount:       amount,
+		addrInterval: addrInterval,
+		limiter:      rate.NewLimiter(limit, burst),
+		newFunder:    newFunder,
+		listener:     listener,
+		lastFunded:   map[string]time.Time{},
+	}
+	f.server = &http.Server{Handler: f, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = f.server.Serve(listener)
+	}()
+	return f, nil
+}
+
+// URL returns the URL requests are POSTed to
+func (f *Faucet) URL() string {
+	return "http://" + f.listener.Addr().String()
+}
+
+// Close stops the faucet
+func (f *Faucet) Close() error {
+	return f.server.Close()
+}
+
+func (f *Faucet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeResponse(w, http.StatusMethodNotAllowed, Response{Error: "only POST is supported"})
+		return
+	}
+	if !f.limiter.Allow() {
+		writeResponse(w, http.StatusTooManyRequests, Response{Error: "too many requests"})
+		return
+	}
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, Response{Error: fmt.Sprintf("couldn't parse request: %s", err)})
+		return
+	}
+	fund, key, err := f.parseRequest(req)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, Response{Error: err.Error()})
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if lastFunded, ok := f.lastFunded[key]; ok && time.Since(lastFunded) < f.addrInterval {
+		writeResponse(w, http.StatusTooManyRequests, Response{
+			Error: fmt.Sprintf("%s was funded less than %s ago", req.Address, f.addrInterval),
+		})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), fundTimeout)
+	defer cancel()
+	if f.funder == nil {
+		funder, err := f.newFunder(ctx)
+		if err != nil {
+			writeResponse(w, http.StatusServiceUnavailable, Response{Error: fmt.Sprintf("faucet isn't ready: %s", err)})
+			return
+		}
+		f.funder = funder
+	}
+	txIDs, err := fund(ctx, f.funder)
+	if err != nil {
+		writeResponse(w, http.StatusInternalServerError, Response{TxIDs: txIDs, Error: err.Error()})
+		return
+	}
+	f.lastFunded[key] = time.Now()
+	writeResponse(w, http.StatusOK, Response{TxIDs: txIDs, Amount: f.amount})
+}
+
+// Returns the function that funds the address of [req], and
+// the key under which the address' last funding is tracked
+func (f *Faucet) parseRequest(req Request) (func(context.Context, Funder) ([]string, error), string, error) {
+	chain := strings.ToUpper(req.Chain)
+	switch chain {
+	case "X", "P":
+		addr, err := parseShortID(req.Address)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid %s-Chain address %q: %w", chain, req.Address, err)
+		}
+		key := chain + ":" + addr.String()
+		if chain == "X" {
+			return func(ctx context.Context, funder Funder) ([]string, error) {
+				txID, err := funder.FundX(ctx, addr, f.amount)
+				if err != nil {
+					return nil, err
+				}
+				return []string{txID.String()}, nil
+			}, key, nil
+		}
+		return func(ctx context.Context, funder Funder) ([]string, error) {
+			exportTxID, importTxID, err := funder.FundP(ctx, addr, f.amount)
+			switch {
+			case err != nil && exportTxID == ids.Empty:
+				return nil, err
+			case err != nil:
+				return []string{exportTxID.String()}, err
+			}
+			return []string{exportTxID.String(), importTxID.String()}, nil
+		}, key, nil
+	case "C":
+		if !ethcommon.IsHexAddress(req.Address) {
+			return nil, "", fmt.Errorf("invalid C-Chain address %q", req.Address)
+		}
+		addr := ethcommon.HexToAddress(req.Address)
+		return func(ctx context.Context, funder Funder) ([]string, error) {
+			txHash, err := funder.FundC(ctx, addr, f.amount)
+			if err != nil {
+				return nil, err
+			}
+			return []string{txHash.Hex()}, nil
+		}, chain + ":" + addr.Hex(), nil
+	default:
+		return nil, "", fmt.Errorf("invalid chain %q, expected X, P or C", req.Chain)
+	}
+}
+
+// Parses a bech32 address, with or without its chain prefix (e.g. "X-")
+func parseShortID(addrStr string) (ids.ShortID, error) {
+	if strings.Contains(addrStr, "-") {
+		return address.ParseToID(addrStr)
+	}
+	_, addrBytes, err := address.ParseBech32(addrStr)
+	if err != nil {
+		return ids.ShortID{}, err
+	}
+	return ids.ToShortID(addrBytes)
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package faucet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/formatting/address"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// Records the addresses it funds
+type testFunder struct {
+	funded []string
+}
+
+func (f *testFunder) FundX(_ context.Context, to ids.ShortID, _ uint64) (ids.ID, error) {
+	f.funded = append(f.funded, "X:"+to.String())
+	return ids.GenerateTestID(), nil
+}
+
+func (f *testFunder) FundP(_ context.Context, to ids.ShortID, _ uint64) (ids.ID, ids.ID, error) {
+	f.funded = append(f.funded, "P:"+to.String())
+	return ids.GenerateTestID(), ids.GenerateTestID(), nil
+}
+
+func (f *testFunder) FundC(_ context.Context, to ethcommon.Address, _ uint64) (ethcommon.Hash, error) {
+	f.funded = append(f.funded, "C:"+to.Hex())
+	return ethcommon.Hash{1}, nil
+}
+
+// POSTs [req] to [url], and returns the status code and response
+func post(t *testing.T, url string, req Request) (int, Response) {
+	body, err := json.Marshal(req)
+	assert.NoError(t, err)
+	httpResp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	assert.NoError(t, err)
+	defer httpResp.Body.Close()
+	var resp Response
+	assert.NoError(t, json.NewDecoder(httpResp.Body).Decode(&resp))
+	return httpResp.StatusCode, resp
+}
+
+func TestFaucet(t *testing.T) {
+	assert := assert.New(t)
+	funder := &testFunder{}
+	numNewFunderCalls := 0
+	f, err := New(Config{Amount: 5}, func(context.Context) (Funder, error) {
+		numNewFunderCalls++
+		if numNewFunderCalls == 1 {
+			return nil, errors.New("network isn't healthy")
+		}
+		return funder, nil
+	})
+	assert.NoError(err)
+	defer f.Close()
+
+	addr := ids.GenerateTestShortID()
+	xAddr, err := address.Format("X", "custom", addr.Bytes())
+	assert.NoError(err)
+	bech32Addr, err := address.FormatBech32("custom", addr.Bytes())
+	assert.NoError(err)
+	ethAddr := ethcommon.Address{2}
+
+	// The funder is created again after failing
+	status, resp := post(t, f.URL(), Request{Chain: "X", Address: xAddr})
+	assert.Equal(http.StatusServiceUnavailable, status)
+	assert.Contains(resp.Error, "network isn't healthy")
+	status, resp = post(t, f.URL(), Request{Chain: "X", Address: xAddr})
+	assert.Equal(http.StatusOK, status)
+	assert.Len(resp.TxIDs, 1)
+	assert.EqualValues(5, resp.Amount)
+	status, resp = post(t, f.URL(), Request{Chain: "p", Address: bech32Addr})
+	assert.Equal(http.StatusOK, status)
+	assert.Len(resp.TxIDs, 2)
+	status, _ = post(t, f.URL(), Request{Chain: "C", Address: ethAddr.Hex()})
+	assert.Equal(http.StatusOK, status)
+	assert.Equal([]string{"X:" + addr.String(), "P:" + addr.String(), "C:" + ethAddr.Hex()}, funder.funded)
+	assert.Equal(2, numNewFunderCalls)
+
+	// An address is funded at most once per [DefaultAddrInterval]
+	status, resp = post(t, f.URL(), Request{Chain: "X", Address: bech32Addr})
+	assert.Equal(http.StatusTooManyRequests, status)
+	assert.Contains(resp.Error, "funded less than")
+
+	// Invalid requests
+	for _, req := range []Request{
+		{Chain: "Y", Address: xAddr},
+		{Chain: "X", Address: "notanaddress"},
+		{Chain: "C", Address: xAddr},
+	} {
+		status, _ = post(t, f.URL(), req)
+		assert.Equal(http.StatusBadRequest, status)
+	}
+	httpResp, err := http.Get(f.URL())
+	assert.NoError(err)
+	assert.NoError(httpResp.Body.Close())
+	assert.Equal(http.StatusMethodNotAllowed, httpResp.StatusCode)
+	assert.Len(funder.funded, 3)
+}
+
+func TestFaucetRateLimit(t *testing.T) {
+	assert := assert.New(t)
+	f, err := New(Config{RequestsPerSec: 1, AddrInterval: time.Nanosecond}, func(context.Context) (Funder, error) {
+		return &testFunder{}, nil
+	})
+	assert.NoError(err)
+	defer f.Close()
+	status, _ := post(t, f.URL(), Request{Chain: "C", Address: ethcommon.Address{1}.Hex()})
+	assert.Equal(http.StatusOK, status)
+	status, _ = post(t, f.URL(), Request{Chain: "C", Address: ethcommon.Address{2}.Hex()})
+	assert.Equal(http.StatusTooManyRequests, status)
+
+	_, err = New(Config{RequestsPerSec: -1}, nil)
+	assert.Error(err)
+}
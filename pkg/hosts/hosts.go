@@ -0,0 +1,136 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package hosts registers host names in a hosts file, such as /etc/hosts,
+// so that nodes can be reached by name rather than by IP.
+package hosts
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	beginMarker = "# BEGIN avalanche-network-runner"
+	endMarker   = "# END avalanche-network-runner"
+)
+
+// File is a hosts file (see hosts(5)). The host names registered are
+// written between marker comments, so that the rest of the file
+// (e.g. the system's entries of /etc/hosts) is kept as is.
+type File struct {
+	path string
+	lock sync.Mutex
+}
+
+// NewFile returns the hosts file at [path], which is created
+// on the first registration if it doesn't exist
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Register resolves [hostname] to [ip], replacing any previous IP
+func (f *File) Register(hostname string, ip net.IP) error {
+	if hostname == "" || strings.ContainsAny(hostname, " \t\n#") {
+		return fmt.Errorf("invalid host name %q", hostname)
+	}
+	if ip == nil {
+		return errors.New("no IP given")
+	}
+	return f.update(func(entries map[string]string) {
+		entries[hostname] = ip.String()
+	})
+}
+
+// Unregister stops resolving [hostname]
+func (f *File) Unregister(hostname string) error {
+	return f.update(func(entries map[string]string) {
+		delete(entries, hostname)
+	})
+}
+
+// Entries returns host name --> IP of the registered host names
+func (f *File) Entries() (map[string]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	_, entries, _, err := f.read()
+	return entries, err
+}
+
+// Updates the registered entries (host name --> IP) with [updateF]
+func (f *File) update(updateF func(entries map[string]string)) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	before, entries, after, err := f.read()
+	if err != nil {
+		return err
+	}
+	updateF(entries)
+
+	hostnames := make([]string, 0, len(entries))
+	for hostname := range entries {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	lines := append([]string{}, before...)
+	if len(hostnames) > 0 {
+		lines = append(lines, beginMarker)
+		for _, hostname := range hostnames {
+			lines = append(lines, entries[hostname]+" "+hostname)
+		}
+		lines = append(lines, endMarker)
+	}
+	lines = append(lines, after...)
+	contents := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		contents += "\n"
+	}
+	// Written in place, rather than renamed over, as
+	// /etc/hosts is often a mount point in containers
+	return os.WriteFile(f.path, []byte(contents), 0o644)
+}
+
+// Returns the lines before and after the registered entries,
+// and the entries (host name --> IP)
+func (f *File) read() ([]string, map[string]string, []string, error) {
+	entries := map[string]string{}
+	contents, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, entries, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var before, after []string
+	inBlock, blockSeen := false, false
+	for _, line := range strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n") {
+		switch {
+		case line == beginMarker && !blockSeen:
+			inBlock, blockSeen = true, true
+		case line == endMarker && inBlock:
+			inBlock = false
+		case inBlock:
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			for _, hostname := range fields[1:] {
+				entries[hostname] = fields[0]
+			}
+		case blockSeen:
+			after = append(after, line)
+		default:
+			before = append(before, line)
+		}
+	}
+	if len(contents) == 0 {
+		before = nil
+	}
+	return before, entries, after, nil
+}
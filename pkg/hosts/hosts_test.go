@@ -0,0 +1,55 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package hosts
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "hosts")
+	systemEntries := "127.0.0.1 localhost\n::1 localhost ip6-localhost\n"
+	assert.NoError(os.WriteFile(path, []byte(systemEntries), 0o644))
+
+	f := NewFile(path)
+	assert.NoError(f.Register("node1.avax.local", net.ParseIP("127.0.0.1")))
+	assert.NoError(f.Register("node0.avax.local", net.ParseIP("::1")))
+	assert.NoError(f.Register("node1.avax.local", net.ParseIP("127.0.0.2")))
+	contents, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.Equal(systemEntries+
+		beginMarker+"\n"+
+		"::1 node0.avax.local\n"+
+		"127.0.0.2 node1.avax.local\n"+
+		endMarker+"\n",
+		string(contents),
+	)
+
+	// Entries are read back from the file
+	entries, err := NewFile(path).Entries()
+	assert.NoError(err)
+	assert.Equal(map[string]string{"node0.avax.local": "::1", "node1.avax.local": "127.0.0.2"}, entries)
+
+	assert.NoError(f.Unregister("node0.avax.local"))
+	assert.NoError(f.Unregister("node1.avax.local"))
+	contents, err = os.ReadFile(path)
+	assert.NoError(err)
+	assert.Equal(systemEntries, string(contents))
+
+	assert.Error(f.Register("node0 avax.local", net.ParseIP("::1")))
+	assert.Error(f.Register("node0.avax.local", nil))
+
+	// The file is created if it doesn't exist
+	f = NewFile(filepath.Join(t.TempDir(), "hosts"))
+	assert.NoError(f.Register("node0.avax.local", net.ParseIP("::1")))
+	entries, err = f.Entries()
+	assert.NoError(err)
+	assert.Equal(map[string]string{"node0.avax.local": "::1"}, entries)
+}
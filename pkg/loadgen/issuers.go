@@ -0,0 +1,227 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/pkg/wallet"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/ethclient"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// Gas of a transfer of the C-Chain's native asset
+	transferGas = 21_000
+	// How often issued txs are polled for acceptance
+	pollFrequency = 100 * time.Millisecond
+)
+
+var (
+	_ Issuer = (*CChainTransferIssuer)(nil)
+	_ Issuer = (*XChainTransferIssuer)(nil)
+	_ Issuer = (*JSONRPCIssuer)(nil)
+)
+
+// CChainTransferIssuer issues C-Chain transfers of 1 wei from a key to
+// itself, and waits for them to be accepted
+type CChainTransferIssuer struct {
+	client   ethclient.Client
+	key      *crypto.PrivateKeySECP256K1R
+	addr     ethcommon.Address
+	chainID  *big.Int
+	gasPrice *big.Int
+
+	// Guards [nonce]
+	lock  sync.Mutex
+	nonce uint64
+}
+
+// NewCChainTransferIssuer returns an issuer of C-Chain transfers to the node
+// at [uri] (e.g. http://127.0.0.1:9650), paid for by [key].
+// The gas price is set once, with some headroom over the suggested gas price.
+func NewCChainTransferIssuer(ctx context.Context, uri string, key *crypto.PrivateKeySECP256K1R) (*CChainTransferIssuer, error) {
+	client, err := ethclient.DialContext(ctx, uri+"/ext/bc/C/rpc")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't connect to the C-Chain: %w", err)
+	}
+	issuer := &CChainTransferIssuer{
+		client: client,
+		key:    key,
+		addr:   wallet.EthAddress(key),
+	}
+	if issuer.chainID, err = client.ChainID(ctx); err != nil {
+		return nil, fmt.Errorf("couldn't get C-Chain ID: %w", err)
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get gas price: %w", err)
+	}
+	// Double the gas price so that the txs stay valid as the base fee rises
+	issuer.gasPrice = new(big.Int).Mul(gasPrice, big.NewInt(2))
+	if issuer.nonce, err = client.AcceptedNonceAt(ctx, issuer.addr); err != nil {
+		return nil, fmt.Errorf("couldn't get nonce of %s: %w", issuer.addr, err)
+	}
+	return issuer, nil
+}
+
+func (i *CChainTransferIssuer) Issue(ctx context.Context) error {
+	tx, err := i.send(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		receipt, err := i.client.TransactionReceipt(ctx, tx.Hash())
+		if err == nil {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return fmt.Errorf("tx %s failed", tx.Hash())
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("tx %s wasn't accepted: %w", tx.Hash(), ctx.Err())
+		case <-time.After(pollFrequency):
+		}
+	}
+}
+
+// Signs and sends a tx with the next nonce
+func (i *CChainTransferIssuer) send(ctx context.Context) (*types.Transaction, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	tx, err := types.SignTx(
+		types.NewTransaction(i.nonce, i.addr, big.NewInt(1), transferGas, i.gasPrice, nil),
+		types.LatestSignerForChainID(i.chainID),
+		i.key.ToECDSA(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't sign tx: %w", err)
+	}
+	if err := i.client.SendTransaction(ctx, tx); err != nil {
+		// The nonce is only resynced if it's out of sync, e.g. if txs of
+		// the key were issued by someone else. Otherwise the tx wasn't
+		// accepted to the mempool, so the next tx reuses its nonce.
+		if isNonceErr(err) {
+			if nonce, nonceErr := i.client.AcceptedNonceAt(ctx, i.addr); nonceErr == nil {
+				i.nonce = nonce
+			}
+		}
+		return nil, fmt.Errorf("couldn't send tx: %w", err)
+	}
+	i.nonce++
+	return tx, nil
+}
+
+// Returns true if [err], returned by the node a tx was sent to,
+// shows that the nonce of the tx is out of sync
+func isNonceErr(err error) bool {
+	// The error is only known by its message once sent over RPC
+	msg := err.Error()
+	return strings.Contains(msg, core.ErrNonceTooLow.Error()) || strings.Contains(msg, core.ErrNonceTooHigh.Error())
+}
+
+// Close closes the connection to the C-Chain
+func (i *CChainTransferIssuer) Close() {
+	i.client.Close()
+}
+
+// XChainTransferIssuer issues X-Chain transfers of 1 nAVAX from the
+// wallet's first key to itself, and waits for them to be accepted.
+// Since the wallet tracks the UTXOs it spends, transfers are issued one
+// at a time, so the TPS is bounded by the X-Chain's acceptance latency.
+type XChainTransferIssuer struct {
+	wallet *wallet.Wallet
+	addr   ids.ShortID
+	lock   sync.Mutex
+}
+
+// NewXChainTransferIssuer returns an issuer of X-Chain transfers paid
+// for by [w]. [addr] must be an address of one of the wallet's keys, so
+// that the wallet can keep spending the UTXOs of the transfers.
+func NewXChainTransferIssuer(w *wallet.Wallet, addr ids.ShortID) *XChainTransferIssuer {
+	return &XChainTransferIssuer{
+		wallet: w,
+		addr:   addr,
+	}
+}
+
+func (i *XChainTransferIssuer) Issue(ctx context.Context) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	_, err := i.wallet.FundX(ctx, i.addr, 1)
+	return err
+}
+
+// JSONRPCIssuer issues a JSON-RPC request, e.g. to a custom VM's API,
+// and fails if the response is an error
+type JSONRPCIssuer struct {
+	client *http.Client
+	url    string
+	method string
+	params interface{}
+	nextID uint64
+}
+
+// NewJSONRPCIssuer returns an issuer of calls to [method] with
+// [params] at [url] (e.g. http://127.0.0.1:9650/ext/bc/<blockchain ID>/rpc)
+func NewJSONRPCIssuer(url string, method string, params interface{}) *JSONRPCIssuer {
+	return &JSONRPCIssuer{
+		client: http.DefaultClient,
+		url:    url,
+		method: method,
+		params: params,
+	}
+}
+
+func (i *JSONRPCIssuer) Issue(ctx context.Context) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      atomic.AddUint64(&i.nextID, 1),
+		"method":  i.method,
+		"params":  i.params,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", i.method, resp.Status)
+	}
+	var reply struct {
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("couldn't parse reply of %s: %w", i.method, err)
+	}
+	if reply.Error != nil {
+		return fmt.Errorf("%s failed with code %d: %s", i.method, reply.Error.Code, reply.Error.Message)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package loadgen generates sustained request load against
+// a running network, and reports its latency and throughput.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const defaultMaxInFlight = 100
+
+// Issuer issues the requests of a load
+type Issuer interface {
+	// Issues one request, and returns once it's done
+	// (e.g. the tx it issued is accepted).
+	// May be called concurrently.
+	Issue(ctx context.Context) error
+}
+
+// IssuerFunc is an Issuer that calls itself
+type IssuerFunc func(ctx context.Context) error
+
+func (f IssuerFunc) Issue(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Config of a load
+type Config struct {
+	// Requests issued per second. Must be positive.
+	TPS float64
+	// How long requests are issued for.
+	// If zero, until the context given to Run is done.
+	Duration time.Duration
+	// Max number of requests in flight at once. Once reached, new
+	// requests wait for one to finish, lowering the effective TPS.
+	// Defaults to 100.
+	MaxInFlight int
+}
+
+// Stats of a load run
+type Stats struct {
+	// Number of requests issued
+	Issued uint64 `json:"issued"`
+	// Number of requests that succeeded
+	Succeeded uint64 `json:"succeeded"`
+	// Number of requests that failed
+	Failed uint64 `json:"failed"`
+	// First error of a failed request, if any
+	FirstErr string `json:"firstError,omitempty"`
+	// How long the load ran for, including waiting
+	// for the last requests to finish
+	Duration time.Duration `json:"duration"`
+	// Succeeded requests per second
+	Throughput float64 `json:"throughput"`
+	// Latencies of the succeeded requests
+	MinLatency  time.Duration `json:"minLatency"`
+	MeanLatency time.Duration `json:"meanLatency"`
+	P50Latency  time.Duration `json:"p50Latency"`
+	P90Latency  time.Duration `json:"p90Latency"`
+	P99Latency  time.Duration `json:"p99Latency"`
+	MaxLatency  time.Duration `json:"maxLatency"`
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf(
+		"issued %d, succeeded %d, failed %d in %s (%.2f/s); latency min %s, mean %s, p50 %s, p90 %s, p99 %s, max %s",
+		s.Issued, s.Succeeded, s.Failed, s.Duration, s.Throughput,
+		s.MinLatency, s.MeanLatency, s.P50Latency, s.P90Latency, s.P99Latency, s.MaxLatency,
+	)
+}
+
+// Run issues requests with [issuer] at the TPS of [config] until its
+// duration passes or [ctx] is done, then waits for the requests in
+// flight to finish and returns their stats.
+// Failed requests are counted in the stats rather than stopping the load.
+func Run(ctx context.Context, config Config, issuer Issuer) (Stats, error) {
+	if config.TPS <= 0 {
+		return Stats{}, errors.New("TPS must be positive")
+	}
+	maxInFlight := config.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = defaultMaxInFlight
+	}
+	loadCtx := ctx
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		loadCtx, cancel = context.WithTimeout(ctx, config.Duration)
+		defer cancel()
+	}
+
+	var (
+		limiter   = rate.NewLimiter(rate.Limit(config.TPS), 1)
+		inFlight  = make(chan struct{}, maxInFlight)
+		wg        sync.WaitGroup
+		lock      sync.Mutex
+		latencies []time.Duration
+		stats     Stats
+	)
+	start := time.Now()
+	for {
+		if err := limiter.Wait(loadCtx); err != nil {
+			break
+		}
+		select {
+		case inFlight <- struct{}{}:
+		case <-loadCtx.Done():
+		}
+		if loadCtx.Err() != nil {
+			break
+		}
+		stats.Issued++
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-inFlight
+				wg.Done()
+			}()
+			// Requests in flight may finish after the load's duration
+			issueStart := time.Now()
+			err := issuer.Issue(ctx)
+			latency := time.Since(issueStart)
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				if stats.Failed == 0 {
+					stats.FirstErr = err.Error()
+				}
+				stats.Failed++
+				return
+			}
+			stats.Succeeded++
+			latencies = append(latencies, latency)
+		}()
+	}
+	wg.Wait()
+	stats.Duration = time.Since(start)
+
+	if stats.Duration > 0 {
+		stats.Throughput = float64(stats.Succeeded) / stats.Duration.Seconds()
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		var total time.Duration
+		for _, latency := range latencies {
+			total += latency
+		}
+		stats.MinLatency = latencies[0]
+		stats.MeanLatency = total / time.Duration(len(latencies))
+		stats.P50Latency = percentile(latencies, 50)
+		stats.P90Latency = percentile(latencies, 90)
+		stats.P99Latency = percentile(latencies, 99)
+		stats.MaxLatency = latencies[len(latencies)-1]
+	}
+	return stats, nil
+}
+
+// Returns the [p]th percentile of [sorted], which must be non-empty
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p + 99) / 100
+	if i > 0 {
+		i--
+	}
+	return sorted[i]
+}
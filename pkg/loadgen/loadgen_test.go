@@ -0,0 +1,146 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/coreth/core"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/ethclient"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := Run(context.Background(), Config{}, IssuerFunc(func(context.Context) error { return nil }))
+	assert.Error(err)
+
+	var calls int32
+	issuer := IssuerFunc(func(context.Context) error {
+		// Every 4th request fails
+		if atomic.AddInt32(&calls, 1)%4 == 0 {
+			return errors.New("request failed")
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	stats, err := Run(context.Background(), Config{TPS: 100, Duration: 500 * time.Millisecond}, issuer)
+	assert.NoError(err)
+	assert.EqualValues(calls, stats.Issued)
+	assert.Equal(stats.Issued, stats.Succeeded+stats.Failed)
+	// Allow for slow test machines
+	assert.Greater(stats.Issued, uint64(10))
+	assert.LessOrEqual(stats.Issued, uint64(60))
+	assert.Greater(stats.Failed, uint64(0))
+	assert.Equal("request failed", stats.FirstErr)
+	assert.GreaterOrEqual(stats.MinLatency, 10*time.Millisecond)
+	assert.LessOrEqual(stats.MinLatency, stats.P50Latency)
+	assert.LessOrEqual(stats.P50Latency, stats.P99Latency)
+	assert.LessOrEqual(stats.P99Latency, stats.MaxLatency)
+	assert.Greater(stats.Throughput, 0.0)
+
+	// The load stops when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	stats, err = Run(ctx, Config{TPS: 100}, IssuerFunc(func(context.Context) error { return nil }))
+	assert.NoError(err)
+	assert.Greater(stats.Issued, uint64(0))
+}
+
+// A C-Chain client whose accepted nonce is [acceptedNonce], and
+// that fails to send txs with [sendErr] if it's set
+type nonceTestClient struct {
+	ethclient.Client
+	acceptedNonce uint64
+	sendErr       error
+	nonceCalls    int
+	sentNonces    []uint64
+}
+
+func (c *nonceTestClient) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	if c.sendErr != nil {
+		return c.sendErr
+	}
+	c.sentNonces = append(c.sentNonces, tx.Nonce())
+	return nil
+}
+
+func (c *nonceTestClient) AcceptedNonceAt(context.Context, ethcommon.Address) (uint64, error) {
+	c.nonceCalls++
+	return c.acceptedNonce, nil
+}
+
+func TestCChainTransferIssuerNonce(t *testing.T) {
+	assert := assert.New(t)
+	client := &nonceTestClient{}
+	issuer := &CChainTransferIssuer{
+		client:   client,
+		key:      genesis.EWOQKey,
+		chainID:  big.NewInt(43112),
+		gasPrice: big.NewInt(1),
+		nonce:    5,
+	}
+	send := func() error {
+		_, err := issuer.send(context.Background())
+		return err
+	}
+
+	// The nonce is tracked locally
+	assert.NoError(send())
+	assert.NoError(send())
+	// A tx that fails to be sent doesn't use up its nonce
+	client.sendErr = errors.New("connection refused")
+	assert.Error(send())
+	client.sendErr = nil
+	assert.NoError(send())
+	assert.Equal([]uint64{5, 6, 7}, client.sentNonces)
+	assert.Zero(client.nonceCalls)
+
+	// The nonce is resynced once it's out of sync
+	client.acceptedNonce = 10
+	client.sendErr = fmt.Errorf("%s: address %s", core.ErrNonceTooLow, issuer.addr)
+	assert.Error(send())
+	client.sendErr = nil
+	assert.NoError(send())
+	assert.Equal([]uint64{5, 6, 7, 10}, client.sentNonces)
+	assert.Equal(1, client.nonceCalls)
+}
+
+func TestJSONRPCIssuer(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+			ID     uint64 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Method == "vm.fail" {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32000,"message":"failed"}}`, req.ID)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":{}}`, req.ID)
+	}))
+	defer server.Close()
+
+	assert.NoError(NewJSONRPCIssuer(server.URL, "vm.ok", map[string]string{}).Issue(context.Background()))
+	err := NewJSONRPCIssuer(server.URL, "vm.fail", nil).Issue(context.Background())
+	assert.Error(err)
+	assert.Contains(err.Error(), "failed")
+}
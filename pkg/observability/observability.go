@@ -0,0 +1,368 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package observability runs Prometheus, and optionally Grafana with
+// avalanchego dashboards, as processes that scrape the metrics of a
+// network's nodes.
+package observability
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/ava-labs/avalanchego/utils/wrappers"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Binary run for Prometheus, unless given
+	DefaultPrometheusPath = "prometheus"
+	// Binary run for Grafana, unless given
+	DefaultGrafanaPath = "grafana-server"
+
+	// Path of the metrics endpoint of avalanchego nodes
+	metricsPath = "/ext/metrics"
+	// Label added to the metrics of a node to tell nodes apart
+	nodeLabel = "node"
+	// How often the nodes are scraped, and the targets file is reread
+	scrapeInterval = "5s"
+	// Max time for a process to exit once asked to
+	stopTimeout = 10 * time.Second
+	// Name of the file the targets are written to
+	targetsFileName = "targets.json"
+)
+
+//go:embed dashboards/*.json
+var dashboards embed.FS
+
+// Config of the observability processes of a network.
+// The zero value runs Prometheus only, from the PATH.
+type Config struct {
+	// Path of the Prometheus binary. Defaults to DefaultPrometheusPath.
+	PrometheusPath string `json:"prometheusPath"`
+	// Port Prometheus listens on, on 127.0.0.1.
+	// If zero, the network picks a free port.
+	PrometheusPort uint16 `json:"prometheusPort"`
+	// If true, Grafana is started too, with Prometheus as its
+	// data source and dashboards of the nodes' metrics
+	Grafana bool `json:"grafana"`
+	// Path of the Grafana server binary. Defaults to DefaultGrafanaPath.
+	GrafanaPath string `json:"grafanaPath"`
+	// Grafana's home path, where its default config and static
+	// files are (e.g. /usr/share/grafana). Required if [Grafana].
+	GrafanaHomePath string `json:"grafanaHomePath"`
+	// Port Grafana listens on, on 127.0.0.1.
+	// If zero, the network picks a free port.
+	GrafanaPort uint16 `json:"grafanaPort"`
+}
+
+// Validate returns an error if this config is invalid
+func (c *Config) Validate() error {
+	if c.Grafana && c.GrafanaHomePath == "" {
+		return errors.New("grafana home path not given")
+	}
+	if c.Grafana && c.GrafanaPort != 0 && c.GrafanaPort == c.PrometheusPort {
+		return errors.New("prometheus and grafana ports are the same")
+	}
+	return nil
+}
+
+// Target is a node whose metrics are scraped
+type Target struct {
+	// Name of the node
+	Node string
+	// host:port of the node's API
+	Addr string
+}
+
+// URLs of the observability processes
+type URLs struct {
+	Prometheus string `json:"prometheus"`
+	// Empty if Grafana isn't started
+	Grafana string `json:"grafana,omitempty"`
+}
+
+// Stack is a set of running observability processes
+type Stack struct {
+	log         logging.Logger
+	urls        URLs
+	targetsPath string
+	processes   []*process
+}
+
+// A running process of the stack
+type process struct {
+	name string
+	cmd  *exec.Cmd
+	// Closed when the process exits
+	done chan struct{}
+}
+
+// Start writes the configs of the processes of [config] to [dir]
+// and starts them, on the ports of [config], which must be given.
+// They scrape no node until SetTargets is called.
+func Start(log logging.Logger, dir string, config Config) (*Stack, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.PrometheusPath == "" {
+		config.PrometheusPath = DefaultPrometheusPath
+	}
+	if config.GrafanaPath == "" {
+		config.GrafanaPath = DefaultGrafanaPath
+	}
+	switch {
+	case config.PrometheusPort == 0:
+		return nil, errors.New("prometheus port not given")
+	case config.Grafana && config.GrafanaPort == 0:
+		return nil, errors.New("grafana port not given")
+	}
+	s := &Stack{
+		log:         log,
+		targetsPath: filepath.Join(dir, targetsFileName),
+	}
+	prometheusAddr := net.JoinHostPort("127.0.0.1", strconv.Itoa(int(config.PrometheusPort)))
+	s.urls.Prometheus = "http://" + prometheusAddr
+
+	prometheusConfigPath, err := writePrometheusConfig(dir)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't write prometheus config: %w", err)
+	}
+	// Prometheus watches the targets file, which must exist
+	if err := s.SetTargets(nil); err != nil {
+		return nil, err
+	}
+	if err := s.start("prometheus", exec.Command(
+		config.PrometheusPath,
+		"--config.file="+prometheusConfigPath,
+		"--storage.tsdb.path="+filepath.Join(dir, "prometheus-data"),
+		"--web.listen-address="+prometheusAddr,
+	)); err != nil {
+		return nil, err
+	}
+
+	if config.Grafana {
+		grafanaDir := filepath.Join(dir, "grafana")
+		grafanaConfigPath, err := writeGrafanaConfig(grafanaDir, config.GrafanaPort, s.urls.Prometheus)
+		if err != nil {
+			_ = s.Close()
+			return nil, fmt.Errorf("couldn't write grafana config: %w", err)
+		}
+		if err := s.start("grafana", exec.Command(
+			config.GrafanaPath,
+			"--homepath="+config.GrafanaHomePath,
+			"--config="+grafanaConfigPath,
+		)); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+		s.urls.Grafana = "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(int(config.GrafanaPort)))
+	}
+	return s, nil
+}
+
+// Starts [cmd] as the process [name] of the stack
+func (s *Stack) start(name string, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("couldn't start %s: %w", name, err)
+	}
+	p := &process{
+		name: name,
+		cmd:  cmd,
+		done: make(chan struct{}),
+	}
+	go func() {
+		err := cmd.Wait()
+		s.log.Debug("%s exited: %v", name, err)
+		close(p.done)
+	}()
+	s.processes = append(s.processes, p)
+	return nil
+}
+
+// URLs returns the URLs of the stack's processes
+func (s *Stack) URLs() URLs {
+	return s.urls
+}
+
+// The entries of a Prometheus file based service discovery file
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// SetTargets sets the nodes Prometheus scrapes to [targets].
+// Prometheus picks them up within a few seconds.
+func (s *Stack) SetTargets(targets []Target) error {
+	groups := make([]targetGroup, 0, len(targets))
+	for _, target := range targets {
+		groups = append(groups, targetGroup{
+			Targets: []string{target.Addr},
+			Labels:  map[string]string{nodeLabel: target.Node},
+		})
+	}
+	groupsBytes, err := json.Marshal(groups)
+	if err != nil {
+		return err
+	}
+	// Written to a temporary file first so Prometheus
+	// never reads a partially written file
+	tmpPath := s.targetsPath + ".tmp"
+	if err := os.WriteFile(tmpPath, groupsBytes, 0o644); err != nil {
+		return fmt.Errorf("couldn't write targets: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.targetsPath); err != nil {
+		return fmt.Errorf("couldn't write targets: %w", err)
+	}
+	return nil
+}
+
+// Close stops the stack's processes, killing
+// the ones that don't exit within a timeout
+func (s *Stack) Close() error {
+	errs := wrappers.Errs{}
+	for _, p := range s.processes {
+		// Interrupts aren't supported on windows
+		if err := p.cmd.Process.Signal(os.Interrupt); err != nil {
+			_ = p.cmd.Process.Kill()
+		}
+	}
+	timer := time.NewTimer(stopTimeout)
+	defer timer.Stop()
+	for _, p := range s.processes {
+		select {
+		case <-p.done:
+		case <-timer.C:
+			s.log.Warn("%s didn't exit in %s; killing it", p.name, stopTimeout)
+			if err := p.cmd.Process.Kill(); err != nil {
+				errs.Add(fmt.Errorf("couldn't kill %s: %w", p.name, err))
+			}
+			<-p.done
+		}
+	}
+	s.processes = nil
+	return errs.Err
+}
+
+// Writes a Prometheus config that scrapes the nodes in the targets
+// file in [dir] to [dir]. Returns the config's path.
+func writePrometheusConfig(dir string) (string, error) {
+	config := map[string]interface{}{
+		"global": map[string]interface{}{
+			"scrape_interval": scrapeInterval,
+		},
+		"scrape_configs": []interface{}{
+			map[string]interface{}{
+				"job_name":     "avalanchego",
+				"metrics_path": metricsPath,
+				"file_sd_configs": []interface{}{
+					map[string]interface{}{
+						"files":            []string{filepath.Join(dir, targetsFileName)},
+						"refresh_interval": scrapeInterval,
+					},
+				},
+			},
+		},
+	}
+	path := filepath.Join(dir, "prometheus.yml")
+	return path, writeYAML(path, config)
+}
+
+// Writes to [dir] a Grafana config listening on [port], with the
+// Prometheus at [prometheusURL] as data source and the embedded
+// dashboards provisioned. Returns the config's path.
+func writeGrafanaConfig(dir string, port uint16, prometheusURL string) (string, error) {
+	provisioningDir := filepath.Join(dir, "provisioning")
+	dashboardsDir := filepath.Join(dir, "dashboards")
+	// Grafana expects every provisioning dir to exist
+	for _, subDir := range []string{"datasources", "dashboards", "plugins", "notifiers", "alerting"} {
+		if err := os.MkdirAll(filepath.Join(provisioningDir, subDir), 0o755); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(dashboardsDir, 0o755); err != nil {
+		return "", err
+	}
+
+	if err := writeYAML(filepath.Join(provisioningDir, "datasources", "prometheus.yml"), map[string]interface{}{
+		"apiVersion": 1,
+		"datasources": []interface{}{
+			map[string]interface{}{
+				"name":      "Prometheus",
+				"type":      "prometheus",
+				"uid":       "prometheus",
+				"access":    "proxy",
+				"url":       prometheusURL,
+				"isDefault": true,
+			},
+		},
+	}); err != nil {
+		return "", err
+	}
+	if err := writeYAML(filepath.Join(provisioningDir, "dashboards", "avalanchego.yml"), map[string]interface{}{
+		"apiVersion": 1,
+		"providers": []interface{}{
+			map[string]interface{}{
+				"name":    "avalanchego",
+				"type":    "file",
+				"options": map[string]interface{}{"path": dashboardsDir},
+			},
+		},
+	}); err != nil {
+		return "", err
+	}
+	entries, err := dashboards.ReadDir("dashboards")
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		dashboard, err := dashboards.ReadFile("dashboards/" + entry.Name())
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(dashboardsDir, entry.Name()), dashboard, 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	config := fmt.Sprintf(`[server]
+http_addr = 127.0.0.1
+http_port = %d
+
+[paths]
+data = %s
+logs = %s
+provisioning = %s
+
+[auth.anonymous]
+enabled = true
+org_role = Admin
+
+[analytics]
+reporting_enabled = false
+check_for_updates = false
+`, port, filepath.Join(dir, "data"), filepath.Join(dir, "logs"), provisioningDir)
+	path := filepath.Join(dir, "grafana.ini")
+	return path, os.WriteFile(path, []byte(config), 0o644)
+}
+
+// Writes [value] as YAML to the file at [path], creating its dir if needed
+func writeYAML(path string, value interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	valueBytes, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, valueBytes, 0o644)
+}
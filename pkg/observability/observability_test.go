@@ -0,0 +1,119 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package observability
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// Writes to [dir] a script named [name] that writes its
+// args to [name].args and runs until interrupted
+func writeFakeBinary(t *testing.T, dir string, name string) string {
+	path := filepath.Join(dir, name)
+	script := "#!/bin/sh\necho \"$@\" > " + path + ".args\ntrap 'exit 0' INT\nwhile true; do sleep 0.1; done\n"
+	assert.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	return path
+}
+
+// Returns the args the fake binary at [path] was run with
+func fakeBinaryArgs(t *testing.T, path string) string {
+	var args []byte
+	assert.Eventually(t, func() bool {
+		var err error
+		args, err = os.ReadFile(path + ".args")
+		return err == nil && len(args) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	return string(args)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError((&Config{}).Validate())
+	assert.Error((&Config{Grafana: true}).Validate())
+	assert.NoError((&Config{Grafana: true, GrafanaHomePath: "/usr/share/grafana"}).Validate())
+	assert.Error((&Config{Grafana: true, GrafanaHomePath: "/usr/share/grafana", PrometheusPort: 1, GrafanaPort: 1}).Validate())
+}
+
+func TestStack(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	assert := assert.New(t)
+	binDir, dir := t.TempDir(), t.TempDir()
+	config := Config{
+		PrometheusPath:  writeFakeBinary(t, binDir, "prometheus"),
+		PrometheusPort:  19090,
+		Grafana:         true,
+		GrafanaPath:     writeFakeBinary(t, binDir, "grafana-server"),
+		GrafanaHomePath: "/usr/share/grafana",
+		GrafanaPort:     13000,
+	}
+	// The network picks the ports
+	_, err := Start(logging.NoLog{}, dir, Config{PrometheusPath: config.PrometheusPath})
+	assert.Error(err)
+
+	s, err := Start(logging.NoLog{}, dir, config)
+	assert.NoError(err)
+	assert.Equal(URLs{
+		Prometheus: "http://127.0.0.1:19090",
+		Grafana:    "http://127.0.0.1:13000",
+	}, s.URLs())
+
+	// Prometheus reads the targets file
+	prometheusArgs := fakeBinaryArgs(t, config.PrometheusPath)
+	assert.Contains(prometheusArgs, "--config.file="+filepath.Join(dir, "prometheus.yml"))
+	assert.Contains(prometheusArgs, "--web.listen-address=127.0.0.1:19090")
+	prometheusConfigBytes, err := os.ReadFile(filepath.Join(dir, "prometheus.yml"))
+	assert.NoError(err)
+	var prometheusConfig map[string]interface{}
+	assert.NoError(yaml.Unmarshal(prometheusConfigBytes, &prometheusConfig))
+	assert.Contains(string(prometheusConfigBytes), filepath.Join(dir, targetsFileName))
+	assert.Contains(string(prometheusConfigBytes), metricsPath)
+
+	// Targets are written as file based service discovery groups
+	assert.NoError(s.SetTargets([]Target{{Node: "node1", Addr: "127.0.0.1:9650"}}))
+	targetsBytes, err := os.ReadFile(filepath.Join(dir, targetsFileName))
+	assert.NoError(err)
+	var groups []targetGroup
+	assert.NoError(json.Unmarshal(targetsBytes, &groups))
+	assert.Equal([]targetGroup{{
+		Targets: []string{"127.0.0.1:9650"},
+		Labels:  map[string]string{nodeLabel: "node1"},
+	}}, groups)
+
+	// Grafana uses Prometheus as data source, and has the dashboards
+	grafanaArgs := fakeBinaryArgs(t, config.GrafanaPath)
+	assert.Contains(grafanaArgs, "--homepath=/usr/share/grafana")
+	datasources, err := os.ReadFile(filepath.Join(dir, "grafana", "provisioning", "datasources", "prometheus.yml"))
+	assert.NoError(err)
+	assert.Contains(string(datasources), "http://127.0.0.1:19090")
+	dashboard, err := os.ReadFile(filepath.Join(dir, "grafana", "dashboards", "avalanchego.json"))
+	assert.NoError(err)
+	assert.True(json.Valid(dashboard))
+	grafanaConfig, err := os.ReadFile(filepath.Join(dir, "grafana", "grafana.ini"))
+	assert.NoError(err)
+	assert.Contains(string(grafanaConfig), "http_port = 13000")
+
+	// The processes exit once closed
+	processes := s.processes
+	assert.Len(processes, 2)
+	assert.NoError(s.Close())
+	for _, p := range processes {
+		assert.True(p.cmd.ProcessState.Exited(), p.name)
+	}
+}
+
+func TestStartMissingBinary(t *testing.T) {
+	_, err := Start(logging.NoLog{}, t.TempDir(), Config{PrometheusPath: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+}
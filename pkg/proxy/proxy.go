@@ -0,0 +1,220 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package proxy implements a TCP proxy that shapes the traffic going
+// through it, e.g. to put a node's API behind a constrained link.
+//
+// It isn't put in front of the nodes' P2P traffic: avalanchego v1.7.11
+// listens for peers on all interfaces at the same staking port it
+// advertises, so peers that learn its address through gossip, and its
+// own outbound connections, would bypass the proxy.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// Max number of bytes read from a connection before
+// waiting for the bandwidth limiter
+const copyBufSize = 32 * 1024
+
+// Limits bound the bandwidth of all the connections going through a Proxy.
+// A zero value means unlimited.
+type Limits struct {
+	// Max number of bytes per second sent from clients to the target
+	UploadBytesPerSec uint64 `json:"uploadBytesPerSec"`
+	// Max number of bytes per second sent from the target to clients
+	DownloadBytesPerSec uint64 `json:"downloadBytesPerSec"`
+}
+
+// DialFunc opens connections to the target of a Proxy, e.g.
+// (*net.Dialer).DialContext, or the dialer of an SSH client to
+// reach a target on a remote host
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Proxy accepts TCP connections and forwards them to a target address.
+type Proxy struct {
+	listener net.Listener
+	target   string
+	dial     DialFunc
+	upload   *rate.Limiter
+	download *rate.Limiter
+
+	// Cancelled on Close
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock  sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// New returns a proxy listening on [listenAddr] that forwards
+// connections to [targetAddr], within [limits].
+// If the port of [listenAddr] is 0, a free port is chosen. See Addr.
+func New(listenAddr, targetAddr string, limits Limits) (*Proxy, error) {
+	var dialer net.Dialer
+	return NewWithDialer(listenAddr, targetAddr, limits, dialer.DialContext)
+}
+
+// NewWithDialer is like New, but connections to [targetAddr] are opened
+// with [dial], so that the target needn't be reachable from this host.
+func NewWithDialer(listenAddr, targetAddr string, limits Limits, dial DialFunc) (*Proxy, error) {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Proxy{
+		listener: listener,
+		target:   targetAddr,
+		dial:     dial,
+		upload:   rate.NewLimiter(toLimit(limits.UploadBytesPerSec), copyBufSize),
+		download: rate.NewLimiter(toLimit(limits.DownloadBytesPerSec), copyBufSize),
+		ctx:      ctx,
+		cancel:   cancel,
+		conns:    map[net.Conn]struct{}{},
+	}
+	p.wg.Add(1)
+	go p.acceptLoop()
+	return p, nil
+}
+
+func toLimit(bytesPerSec uint64) rate.Limit {
+	if bytesPerSec == 0 {
+		return rate.Inf
+	}
+	return rate.Limit(bytesPerSec)
+}
+
+// Addr returns the address this proxy listens on
+func (p *Proxy) Addr() net.Addr {
+	return p.listener.Addr()
+}
+
+// SetLimits replaces this proxy's limits.
+// Applies to existing connections too.
+func (p *Proxy) SetLimits(limits Limits) {
+	p.upload.SetLimit(toLimit(limits.UploadBytesPerSec))
+	p.download.SetLimit(toLimit(limits.DownloadBytesPerSec))
+}
+
+// Close stops accepting connections, closes the existing
+// ones and waits for them to be torn down.
+func (p *Proxy) Close() error {
+	p.cancel()
+	err := p.listener.Close()
+	p.lock.Lock()
+	for conn := range p.conns {
+		_ = conn.Close()
+	}
+	p.lock.Unlock()
+	p.wg.Wait()
+	return err
+}
+
+func (p *Proxy) acceptLoop() {
+	defer p.wg.Done()
+	for {
+		clientConn, err := p.listener.Accept()
+		if err != nil {
+			// Closed
+			return
+		}
+		p.wg.Add(1)
+		go p.handle(clientConn)
+	}
+}
+
+// Returns false if the proxy is closed, in which case [conn] is closed
+func (p *Proxy) track(conn net.Conn) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.ctx.Err() != nil {
+		_ = conn.Close()
+		return false
+	}
+	p.conns[conn] = struct{}{}
+	return true
+}
+
+func (p *Proxy) untrack(conn net.Conn) {
+	p.lock.Lock()
+	delete(p.conns, conn)
+	p.lock.Unlock()
+	_ = conn.Close()
+}
+
+func (p *Proxy) handle(clientConn net.Conn) {
+	defer p.wg.Done()
+	if !p.track(clientConn) {
+		return
+	}
+	defer p.untrack(clientConn)
+
+	targetConn, err := p.dial(p.ctx, "tcp", p.target)
+	if err != nil {
+		return
+	}
+	if !p.track(targetConn) {
+		return
+	}
+	defer p.untrack(targetConn)
+
+	// When either direction hits EOF, only the write side of its
+	// destination is closed, so that a client that half-closes its
+	// connection still gets the response. Both connections are closed
+	// when both directions are done, or as soon as either one fails.
+	errs := make(chan error, 2)
+	go func() {
+		errs <- p.copy(targetConn, clientConn, p.upload)
+	}()
+	go func() {
+		errs <- p.copy(clientConn, targetConn, p.download)
+	}()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			_ = clientConn.Close()
+			_ = targetConn.Close()
+		}
+	}
+}
+
+// Signals EOF to the reader of [conn], or closes
+// [conn] if it can't be closed for writing only
+func closeWrite(conn net.Conn) error {
+	if c, ok := conn.(interface{ CloseWrite() error }); ok {
+		return c.CloseWrite()
+	}
+	return conn.Close()
+}
+
+// Copies from [src] to [dst] until EOF, an error, or the proxy is closed.
+// Waits for [limiter] before writing each chunk.
+// On EOF, [dst] is closed for writing.
+func (p *Proxy) copy(dst net.Conn, src net.Conn, limiter *rate.Limiter) error {
+	buf := make([]byte, copyBufSize)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if err := limiter.WaitN(p.ctx, n); err != nil {
+				return err
+			}
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return closeWrite(dst)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
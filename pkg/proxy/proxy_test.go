@@ -0,0 +1,110 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Starts a server that echoes back what it receives
+func newEchoServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestProxyUploadLimit(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	server := newEchoServer(t)
+	defer server.Close()
+
+	p, err := New("127.0.0.1:0", server.Addr().String(), Limits{UploadBytesPerSec: 64 * 1024})
+	assert.NoError(err)
+	defer p.Close()
+
+	conn, err := net.Dial("tcp", p.Addr().String())
+	assert.NoError(err)
+	defer conn.Close()
+
+	// After the initial burst, the rest takes 1.5s to be sent
+	sent := bytes.Repeat([]byte{1}, 128*1024)
+	start := time.Now()
+	go func() {
+		_, _ = conn.Write(sent)
+	}()
+	received := make([]byte, len(sent))
+	_, err = io.ReadFull(conn, received)
+	assert.NoError(err)
+	assert.Equal(sent, received)
+	assert.Greater(time.Since(start), time.Second)
+}
+
+func TestProxyClose(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	server := newEchoServer(t)
+	defer server.Close()
+
+	p, err := New("127.0.0.1:0", server.Addr().String(), Limits{})
+	assert.NoError(err)
+
+	conn, err := net.Dial("tcp", p.Addr().String())
+	assert.NoError(err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("hi"))
+	assert.NoError(err)
+	received := make([]byte, 2)
+	_, err = io.ReadFull(conn, received)
+	assert.NoError(err)
+	assert.Equal([]byte("hi"), received)
+
+	// Closing the proxy closes the existing connections
+	assert.NoError(p.Close())
+	_, err = conn.Read(received)
+	assert.Error(err)
+	_, err = net.Dial("tcp", p.Addr().String())
+	assert.Error(err)
+}
+
+func TestProxyHalfClose(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	server := newEchoServer(t)
+	defer server.Close()
+
+	p, err := New("127.0.0.1:0", server.Addr().String(), Limits{})
+	assert.NoError(err)
+	defer p.Close()
+
+	conn, err := net.Dial("tcp", p.Addr().String())
+	assert.NoError(err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("hi"))
+	assert.NoError(err)
+
+	// The response still arrives after the client is done sending
+	assert.NoError(conn.(*net.TCPConn).CloseWrite())
+	received, err := io.ReadAll(conn)
+	assert.NoError(err)
+	assert.Equal("hi", string(received))
+}
@@ -0,0 +1,188 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package runner runs a network from a program that embeds the
+// network runner until the program is interrupted, then stops it.
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/local"
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+// DefaultStopTimeout is the max time for the network to stop if not
+// given in the config
+const DefaultStopTimeout = 30 * time.Second
+
+// Config of RunUntilSignalWithConfig. The zero value is valid.
+type Config struct {
+	// Defaults to logging.NoLog
+	Log logging.Logger
+	// Where the status banner is printed. Defaults to os.Stdout.
+	Out io.Writer
+	// Max time for the network to stop.
+	// Defaults to DefaultStopTimeout.
+	StopTimeout time.Duration
+	// Signals upon which the network is stopped.
+	// Defaults to SIGINT and SIGTERM.
+	Signals []os.Signal
+	// If true, the nodes left running by runners that crashed are
+	// killed before the network is started (see local.CleanupStaleNetworks)
+	CleanupStaleNetworks bool
+}
+
+// StartFunc starts the network run by RunUntilSignal, e.g. by creating
+// it and waiting for it to be healthy. [ctx] is cancelled when a signal
+// is received. The network returned, if non-nil, is stopped even if an
+// error is returned too.
+type StartFunc func(ctx context.Context) (network.Network, error)
+
+// RunUntilSignal is RunUntilSignalWithConfig with the default config
+func RunUntilSignal(start StartFunc) error {
+	return RunUntilSignalWithConfig(start, Config{})
+}
+
+// RunUntilSignalWithConfig handles [config.Signals], and cleans up the
+// stale networks if [config.CleanupStaleNetworks], before calling
+// [start], so that a signal received while the network is starting
+// stops it too. Then it prints the URIs of the network's nodes and
+// blocks until one of [config.Signals] is received, upon which the
+// network is stopped. Returns the error [start] or Stop returned, if any.
+func RunUntilSignalWithConfig(start StartFunc, config Config) error {
+	signals := config.Signals
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, signals...)
+	defer signal.Stop(signalCh)
+	return run(start, config, signalCh)
+}
+
+// Result of a StartFunc
+type startResult struct {
+	nw  network.Network
+	err error
+}
+
+// Like RunUntilSignalWithConfig, but with the signals received on [signalCh]
+func run(start StartFunc, config Config, signalCh <-chan os.Signal) error {
+	log := config.Log
+	if log == nil {
+		log = logging.NoLog{}
+	}
+	out := config.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	stopTimeout := config.StopTimeout
+	if stopTimeout == 0 {
+		stopTimeout = DefaultStopTimeout
+	}
+
+	if config.CleanupStaleNetworks {
+		cleanupStaleNetworks(log)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resultCh := make(chan startResult, 1)
+	go func() {
+		nw, err := start(ctx)
+		resultCh <- startResult{nw: nw, err: err}
+	}()
+	var result startResult
+	select {
+	case sig := <-signalCh:
+		log.Info("got OS signal %s while starting network, stopping it", sig)
+		cancel()
+		result = <-resultCh
+		if result.nw == nil {
+			return nil
+		}
+		return stop(log, result.nw, stopTimeout)
+	case result = <-resultCh:
+	}
+	if result.err != nil {
+		if result.nw != nil {
+			if err := stop(log, result.nw, stopTimeout); err != nil {
+				log.Warn("%s", err)
+			}
+		}
+		return result.err
+	}
+
+	banner, err := statusBanner(result.nw)
+	if err != nil {
+		if err := stop(log, result.nw, stopTimeout); err != nil {
+			log.Warn("%s", err)
+		}
+		return err
+	}
+	fmt.Fprint(out, banner)
+
+	sig := <-signalCh
+	log.Info("got OS signal %s, stopping network", sig)
+	return stop(log, result.nw, stopTimeout)
+}
+
+// Stops [nw] within [stopTimeout]
+func stop(log logging.Logger, nw network.Network, stopTimeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	if err := nw.Stop(ctx); err != nil {
+		return fmt.Errorf("couldn't stop network: %w", err)
+	}
+	log.Info("network stopped")
+	return nil
+}
+
+// Returns the banner listing the URIs of [nw]'s nodes
+func statusBanner(nw network.Network) (string, error) {
+	nodes, err := nw.GetAllNodes()
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(nodes))
+	nameWidth := 0
+	for name := range nodes {
+		names = append(names, name)
+		if len(name) > nameWidth {
+			nameWidth = len(name)
+		}
+	}
+	sort.Strings(names)
+
+	var banner strings.Builder
+	fmt.Fprintf(&banner, "network running with %d nodes:\n", len(nodes))
+	for _, name := range names {
+		fmt.Fprintf(&banner, "  %-*s  %s\n", nameWidth, name, nodes[name].GetURI())
+	}
+	fmt.Fprintln(&banner, "press Ctrl+C to stop the network")
+	return banner.String(), nil
+}
+
+// Kills the nodes left running by runners that crashed
+func cleanupStaleNetworks(log logging.Logger) {
+	staleNetworks, err := local.CleanupStaleNetworks(log)
+	if err != nil {
+		log.Warn("couldn't clean up all stale networks: %s", err)
+	}
+	for _, staleNetwork := range staleNetworks {
+		log.Info("cleaned up network %s of crashed runner %d, killing nodes %v", staleNetwork.UUID, staleNetwork.RunnerPID, staleNetwork.KilledNodes)
+		if len(staleNetwork.UnverifiedNodes) > 0 {
+			log.Warn("the processes of nodes %v of network %s may still run, as they couldn't be verified to be these nodes' ones", staleNetwork.UnverifiedNodes, staleNetwork.UUID)
+		}
+	}
+}
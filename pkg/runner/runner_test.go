@@ -0,0 +1,157 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/local"
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/stretchr/testify/assert"
+)
+
+type testNode struct {
+	node.Node
+	uri string
+}
+
+func (n *testNode) GetURI() string {
+	return n.uri
+}
+
+type testNetwork struct {
+	network.Network
+	nodes   map[string]node.Node
+	stopErr error
+	// Deadline of the context Stop was called with
+	stopDeadline time.Time
+	stopped      bool
+}
+
+func (n *testNetwork) GetAllNodes() (map[string]node.Node, error) {
+	return n.nodes, nil
+}
+
+func (n *testNetwork) Stop(ctx context.Context) error {
+	n.stopped = true
+	n.stopDeadline, _ = ctx.Deadline()
+	return n.stopErr
+}
+
+// Sends [sig] on [signalCh] once the banner is written to it
+type signalingWriter struct {
+	bytes.Buffer
+	signalCh chan<- os.Signal
+	sig      os.Signal
+}
+
+func (w *signalingWriter) Write(p []byte) (int, error) {
+	n, err := w.Buffer.Write(p)
+	w.signalCh <- w.sig
+	return n, err
+}
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+	local.RegistryDir = t.TempDir()
+	nw := &testNetwork{
+		nodes: map[string]node.Node{
+			"node2":  &testNode{uri: "http://127.0.0.1:9652"},
+			"node10": &testNode{uri: "http://127.0.0.1:9670"},
+		},
+	}
+	startF := func(context.Context) (network.Network, error) {
+		return nw, nil
+	}
+	signalCh := make(chan os.Signal, 1)
+	out := &signalingWriter{signalCh: signalCh, sig: syscall.SIGINT}
+	start := time.Now()
+	assert.NoError(run(startF, Config{Out: out, StopTimeout: time.Minute}, signalCh))
+	assert.True(nw.stopped)
+	assert.WithinDuration(start.Add(time.Minute), nw.stopDeadline, 5*time.Second)
+	assert.Equal(
+		"network running with 2 nodes:\n"+
+			"  node10  http://127.0.0.1:9670\n"+
+			"  node2   http://127.0.0.1:9652\n"+
+			"press Ctrl+C to stop the network\n",
+		out.String(),
+	)
+
+	nw.stopErr = errors.New("stop failed")
+	out.sig = syscall.SIGTERM
+	assert.ErrorIs(run(startF, Config{Out: out}, signalCh), nw.stopErr)
+}
+
+func TestRunSignalWhileStarting(t *testing.T) {
+	assert := assert.New(t)
+	local.RegistryDir = t.TempDir()
+	nw := &testNetwork{}
+	started := make(chan struct{})
+	startF := func(ctx context.Context) (network.Network, error) {
+		close(started)
+		// Waits for the network to be healthy
+		<-ctx.Done()
+		return nw, ctx.Err()
+	}
+	signalCh := make(chan os.Signal, 1)
+	go func() {
+		<-started
+		signalCh <- syscall.SIGINT
+	}()
+	out := &bytes.Buffer{}
+	assert.NoError(run(startF, Config{Out: out}, signalCh))
+	assert.True(nw.stopped)
+	assert.Empty(out.String())
+}
+
+func TestRunStartFailed(t *testing.T) {
+	assert := assert.New(t)
+	local.RegistryDir = t.TempDir()
+	nw := &testNetwork{}
+	startErr := errors.New("network unhealthy")
+	startF := func(context.Context) (network.Network, error) {
+		return nw, startErr
+	}
+	assert.ErrorIs(run(startF, Config{}, make(chan os.Signal)), startErr)
+	assert.True(nw.stopped)
+
+	startF = func(context.Context) (network.Network, error) {
+		return nil, startErr
+	}
+	assert.ErrorIs(run(startF, Config{}, make(chan os.Signal)), startErr)
+}
+
+func TestRunCleanupStaleNetworks(t *testing.T) {
+	assert := assert.New(t)
+	local.RegistryDir = t.TempDir()
+	// A network of a runner that's no longer running
+	deadRunner := exec.Command(os.Args[0], "-test.run=^$")
+	assert.NoError(deadRunner.Run())
+	staleDir := filepath.Join(local.RegistryDir, "stale")
+	assert.NoError(os.MkdirAll(staleDir, 0o755))
+	assert.NoError(os.WriteFile(
+		filepath.Join(staleDir, "network.json"),
+		[]byte(fmt.Sprintf(`{"uuid":"stale","runnerPID":%d}`, deadRunner.Process.Pid)),
+		0o644,
+	))
+	startF := func(context.Context) (network.Network, error) {
+		return nil, errors.New("network unhealthy")
+	}
+
+	// Stale networks are only cleaned up if asked to
+	assert.Error(run(startF, Config{}, make(chan os.Signal)))
+	assert.DirExists(staleDir)
+	assert.Error(run(startF, Config{CleanupStaleNetworks: true}, make(chan os.Signal)))
+	assert.NoDirExists(staleDir)
+}
@@ -0,0 +1,381 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package tunnel forwards the API ports of the nodes of a network whose
+// nodes aren't reachable from this host (e.g. nodes on remote hosts or in
+// a k8s cluster) to localhost, so that code written against a local
+// network works against it unchanged:
+//
+//	nw, err = tunnel.WrapNetwork(nw, tunnel.Config{Dial: dialRemote})
+//	node, err := nw.GetNode("node1")
+//	node.GetURI() // http://127.0.0.1:<forwarded port>
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ava-labs/avalanche-network-runner/api"
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanche-network-runner/pkg/proxy"
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// IP the forwarded ports listen on
+const localIP = "127.0.0.1"
+
+var (
+	_ network.Network = (*tunneledNetwork)(nil)
+	_ node.Node       = (*tunneledNode)(nil)
+
+	errClosed = errors.New("tunnel closed")
+)
+
+// Config of a Tunnel
+type Config struct {
+	// Opens connections to the nodes' API addresses, as given by their
+	// GetURL and GetAPIPort. Must not be nil.
+	Dial proxy.DialFunc
+	// Creates the API clients of the tunneled nodes, e.g. one returned
+	// by api.NewAPIClientWithConfig for nodes whose API uses TLS or
+	// auth. Defaults to api.NewAPIClient.
+	NewAPIClientF api.NewAPIClientF
+}
+
+// Tunnel forwards local ports to remote addresses
+type Tunnel struct {
+	config Config
+
+	lock   sync.Mutex
+	closed bool
+	// Remote address --> forward to it
+	forwards map[string]*forward
+}
+
+// A local port forwarded to a remote address
+type forward struct {
+	proxy  *proxy.Proxy
+	port   uint16
+	client api.Client
+}
+
+// New returns a tunnel that opens connections with [config.Dial]
+func New(config Config) (*Tunnel, error) {
+	if config.Dial == nil {
+		return nil, errors.New("no dial function given")
+	}
+	if config.NewAPIClientF == nil {
+		config.NewAPIClientF = api.NewAPIClient
+	}
+	return &Tunnel{
+		config:   config,
+		forwards: map[string]*forward{},
+	}, nil
+}
+
+// Forward returns the local port forwarded to [remoteAddr], which
+// is forwarded on the first call, and the same port afterwards
+func (t *Tunnel) Forward(remoteAddr string) (uint16, error) {
+	fwd, err := t.forward(remoteAddr)
+	if err != nil {
+		return 0, err
+	}
+	return fwd.port, nil
+}
+
+func (t *Tunnel) forward(remoteAddr string) (*forward, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.closed {
+		return nil, errClosed
+	}
+	if fwd, ok := t.forwards[remoteAddr]; ok {
+		return fwd, nil
+	}
+	p, err := proxy.NewWithDialer(net.JoinHostPort(localIP, "0"), remoteAddr, proxy.Limits{}, t.config.Dial)
+	if err != nil {
+		return nil, err
+	}
+	port := uint16(p.Addr().(*net.TCPAddr).Port)
+	fwd := &forward{
+		proxy:  p,
+		port:   port,
+		client: t.config.NewAPIClientF(localIP, port),
+	}
+	t.forwards[remoteAddr] = fwd
+	return fwd, nil
+}
+
+// Unforward stops forwarding a local port to [remoteAddr], if any
+func (t *Tunnel) Unforward(remoteAddr string) error {
+	t.lock.Lock()
+	fwd, ok := t.forwards[remoteAddr]
+	delete(t.forwards, remoteAddr)
+	t.lock.Unlock()
+	if !ok {
+		return nil
+	}
+	return fwd.proxy.Close()
+}
+
+// Close stops forwarding all the ports
+func (t *Tunnel) Close() error {
+	t.lock.Lock()
+	t.closed = true
+	forwards := t.forwards
+	t.forwards = map[string]*forward{}
+	t.lock.Unlock()
+
+	var errs []string
+	for _, fwd := range forwards {
+		if err := fwd.proxy.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// WrapNode returns [n] with its API reached through this tunnel
+func (t *Tunnel) WrapNode(n node.Node) (node.Node, error) {
+	if tunneled, ok := n.(*tunneledNode); ok {
+		n = tunneled.Node
+	}
+	fwd, err := t.forward(apiAddr(n))
+	if err != nil {
+		return nil, err
+	}
+	return &tunneledNode{Node: n, fwd: fwd}, nil
+}
+
+// Returns the remote address of [n]'s API
+func apiAddr(n node.Node) string {
+	return net.JoinHostPort(n.GetURL(), strconv.Itoa(int(n.GetAPIPort())))
+}
+
+// A node whose API is reached through a tunnel
+type tunneledNode struct {
+	node.Node
+	fwd *forward
+}
+
+func (n *tunneledNode) GetAPIClient() api.Client {
+	return n.fwd.client
+}
+
+func (n *tunneledNode) GetURL() string {
+	return localIP
+}
+
+func (n *tunneledNode) GetAPIPort() uint16 {
+	return n.fwd.port
+}
+
+func (n *tunneledNode) GetURI() string {
+	scheme := "http"
+	if strings.HasPrefix(n.Node.GetURI(), "https://") {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(localIP, strconv.Itoa(int(n.fwd.port)))
+}
+
+// WrapNetwork returns [nw], whose nodes' APIs are reached through
+// a tunnel given by [config]. The nodes returned by the network, and
+// those of its Status, have the URL, API port, URI and API client of
+// their forwarded port. The forward to a node's API is closed when the
+// node is removed, or when its API address changes, e.g. on restart.
+// Stopping the network closes the tunnel.
+// Other addresses, such as the nodes' P2P ports, aren't forwarded.
+func WrapNetwork(nw network.Network, config Config) (network.Network, error) {
+	t, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	return &tunneledNetwork{
+		Network:     nw,
+		tunnel:      t,
+		remoteAddrs: map[string]string{},
+	}, nil
+}
+
+type tunneledNetwork struct {
+	network.Network
+	tunnel *Tunnel
+
+	lock sync.Mutex
+	// Node name --> remote address of its API, forwarded by [tunnel]
+	remoteAddrs map[string]string
+}
+
+// Returns [n] with its API reached through the tunnel. If [n]'s API
+// address changed since it was last wrapped, the forward to its
+// previous address is closed.
+func (nw *tunneledNetwork) wrap(n node.Node) (node.Node, error) {
+	remoteAddr := apiAddr(n)
+	nw.lock.Lock()
+	prevAddr, ok := nw.remoteAddrs[n.GetName()]
+	nw.remoteAddrs[n.GetName()] = remoteAddr
+	nw.lock.Unlock()
+	if ok && prevAddr != remoteAddr {
+		if err := nw.tunnel.Unforward(prevAddr); err != nil {
+			return nil, err
+		}
+	}
+	return nw.tunnel.WrapNode(n)
+}
+
+// Closes the forward to the API of the node named [name], if any
+func (nw *tunneledNetwork) unforward(name string) error {
+	nw.lock.Lock()
+	remoteAddr, ok := nw.remoteAddrs[name]
+	delete(nw.remoteAddrs, name)
+	nw.lock.Unlock()
+	if !ok {
+		return nil
+	}
+	return nw.tunnel.Unforward(remoteAddr)
+}
+
+// Closes the forwards to the APIs of the nodes not in [nodeNames],
+// which were removed from the network
+func (nw *tunneledNetwork) prune(nodeNames map[string]struct{}) error {
+	nw.lock.Lock()
+	var removed []string
+	for name := range nw.remoteAddrs {
+		if _, ok := nodeNames[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	nw.lock.Unlock()
+	for _, name := range removed {
+		if err := nw.unforward(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (nw *tunneledNetwork) wrapNode(n node.Node, err error) (node.Node, error) {
+	if err != nil {
+		return n, err
+	}
+	return nw.wrap(n)
+}
+
+func (nw *tunneledNetwork) AddNode(config node.Config) (node.Node, error) {
+	return nw.wrapNode(nw.Network.AddNode(config))
+}
+
+func (nw *tunneledNetwork) AddNodeFromTemplate(templateName string, overrides func(*node.Config)) (node.Node, error) {
+	return nw.wrapNode(nw.Network.AddNodeFromTemplate(templateName, overrides))
+}
+
+func (nw *tunneledNetwork) GetNode(name string) (node.Node, error) {
+	return nw.wrapNode(nw.Network.GetNode(name))
+}
+
+func (nw *tunneledNetwork) GetNodeByID(nodeID ids.NodeID) (node.Node, error) {
+	return nw.wrapNode(nw.Network.GetNodeByID(nodeID))
+}
+
+func (nw *tunneledNetwork) GetAllNodes() (map[string]node.Node, error) {
+	nodes, err := nw.Network.GetAllNodes()
+	if err != nil {
+		return nil, err
+	}
+	nodeNames := make(map[string]struct{}, len(nodes))
+	for name, n := range nodes {
+		nodeNames[n.GetName()] = struct{}{}
+		if nodes[name], err = nw.wrap(n); err != nil {
+			return nil, err
+		}
+	}
+	if err := nw.prune(nodeNames); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func (nw *tunneledNetwork) GetAllNodesByID() (map[ids.NodeID]node.Node, error) {
+	nodes, err := nw.Network.GetAllNodesByID()
+	if err != nil {
+		return nil, err
+	}
+	nodeNames := make(map[string]struct{}, len(nodes))
+	for nodeID, n := range nodes {
+		nodeNames[n.GetName()] = struct{}{}
+		if nodes[nodeID], err = nw.wrap(n); err != nil {
+			return nil, err
+		}
+	}
+	if err := nw.prune(nodeNames); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func (nw *tunneledNetwork) GetURIs() ([]string, error) {
+	nodes, err := nw.GetAllNodes()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	uris := make([]string, 0, len(names))
+	for _, name := range names {
+		uris = append(uris, nodes[name].GetURI())
+	}
+	return uris, nil
+}
+
+func (nw *tunneledNetwork) Status(ctx context.Context) (network.Status, error) {
+	status, err := nw.Network.Status(ctx)
+	if err != nil {
+		return status, err
+	}
+	nodes, err := nw.GetAllNodes()
+	if err != nil {
+		return status, err
+	}
+	for i := range status.Nodes {
+		nodeStatus := &status.Nodes[i]
+		n, ok := nodes[nodeStatus.Name]
+		if !ok {
+			// Removed since its status was gotten
+			nodeStatus.URI = ""
+			nodeStatus.APIPort = 0
+			nodeStatus.Errors = append(nodeStatus.Errors, "API port not forwarded: node removed")
+			continue
+		}
+		nodeStatus.URI = n.GetURI()
+		nodeStatus.APIPort = n.GetAPIPort()
+	}
+	return status, nil
+}
+
+func (nw *tunneledNetwork) RemoveNode(name string) error {
+	if err := nw.Network.RemoveNode(name); err != nil {
+		return err
+	}
+	return nw.unforward(name)
+}
+
+func (nw *tunneledNetwork) Stop(ctx context.Context) error {
+	err := nw.Network.Stop(ctx)
+	if closeErr := nw.tunnel.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
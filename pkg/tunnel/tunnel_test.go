@@ -0,0 +1,216 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/stretchr/testify/assert"
+)
+
+// A node whose API is served by a test server
+type fakeNode struct {
+	node.Node
+	name    string
+	url     string
+	apiPort uint16
+}
+
+func (n *fakeNode) GetName() string    { return n.name }
+func (n *fakeNode) GetURL() string     { return n.url }
+func (n *fakeNode) GetAPIPort() uint16 { return n.apiPort }
+func (n *fakeNode) GetURI() string {
+	return "http://" + net.JoinHostPort(n.url, strconv.Itoa(int(n.apiPort)))
+}
+
+type fakeNetwork struct {
+	network.Network
+	nodes map[string]*fakeNode
+}
+
+func (nw *fakeNetwork) GetNode(name string) (node.Node, error) {
+	n, ok := nw.nodes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", network.ErrNodeNotFound, name)
+	}
+	return n, nil
+}
+
+func (nw *fakeNetwork) GetAllNodes() (map[string]node.Node, error) {
+	nodes := make(map[string]node.Node, len(nw.nodes))
+	for name, n := range nw.nodes {
+		nodes[name] = n
+	}
+	return nodes, nil
+}
+
+func (nw *fakeNetwork) RemoveNode(name string) error {
+	delete(nw.nodes, name)
+	return nil
+}
+
+func (nw *fakeNetwork) Stop(context.Context) error {
+	return nil
+}
+
+func (nw *fakeNetwork) Status(context.Context) (network.Status, error) {
+	names := make([]string, 0, len(nw.nodes))
+	for name := range nw.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	status := network.Status{}
+	for _, name := range names {
+		n := nw.nodes[name]
+		status.Nodes = append(status.Nodes, network.NodeStatus{
+			Name:    name,
+			URI:     n.GetURI(),
+			APIPort: n.apiPort,
+		})
+	}
+	return status, nil
+}
+
+// Returns a node whose API replies with its name
+func newFakeNode(t *testing.T, name string) *fakeNode {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, name)
+	}))
+	t.Cleanup(server.Close)
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	assert.NoError(t, err)
+	return &fakeNode{name: name, url: host, apiPort: uint16(port)}
+}
+
+func get(t *testing.T, uri string) string {
+	resp, err := http.Get(uri)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestWrapNetwork(t *testing.T) {
+	assert := assert.New(t)
+	fakeNw := &fakeNetwork{nodes: map[string]*fakeNode{
+		"node1": newFakeNode(t, "node1"),
+		"node2": newFakeNode(t, "node2"),
+	}}
+	var dials int32
+	var dialer net.Dialer
+	nw, err := WrapNetwork(fakeNw, Config{
+		Dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			return dialer.DialContext(ctx, network, addr)
+		},
+	})
+	assert.NoError(err)
+
+	n, err := nw.GetNode("node1")
+	assert.NoError(err)
+	assert.Equal(localIP, n.GetURL())
+	assert.NotEqual(fakeNw.nodes["node1"].apiPort, n.GetAPIPort())
+	assert.Equal(fmt.Sprintf("http://127.0.0.1:%d", n.GetAPIPort()), n.GetURI())
+	assert.NotNil(n.GetAPIClient())
+	assert.Equal("node1", get(t, n.GetURI()))
+	assert.Positive(atomic.LoadInt32(&dials))
+
+	// A node keeps its forwarded port
+	nodes, err := nw.GetAllNodes()
+	assert.NoError(err)
+	assert.Equal(n.GetAPIPort(), nodes["node1"].GetAPIPort())
+	uris, err := nw.GetURIs()
+	assert.NoError(err)
+	assert.Equal([]string{nodes["node1"].GetURI(), nodes["node2"].GetURI()}, uris)
+	assert.Equal("node2", get(t, uris[1]))
+
+	// Removing a node stops forwarding its port
+	assert.NoError(nw.RemoveNode("node2"))
+	_, err = http.Get(uris[1])
+	assert.Error(err)
+
+	// So does stopping the network
+	assert.NoError(nw.Stop(context.Background()))
+	_, err = http.Get(uris[0])
+	assert.Error(err)
+	_, err = nw.GetNode("node1")
+	assert.ErrorIs(err, errClosed)
+}
+
+func TestWrapNetworkStatus(t *testing.T) {
+	assert := assert.New(t)
+	fakeNw := &fakeNetwork{nodes: map[string]*fakeNode{
+		"node1": newFakeNode(t, "node1"),
+		"node2": newFakeNode(t, "node2"),
+	}}
+	var dialer net.Dialer
+	nw, err := WrapNetwork(fakeNw, Config{Dial: dialer.DialContext})
+	assert.NoError(err)
+
+	status, err := nw.Status(context.Background())
+	assert.NoError(err)
+	assert.Len(status.Nodes, 2)
+	for _, nodeStatus := range status.Nodes {
+		n, err := nw.GetNode(nodeStatus.Name)
+		assert.NoError(err)
+		assert.Equal(n.GetURI(), nodeStatus.URI)
+		assert.Equal(n.GetAPIPort(), nodeStatus.APIPort)
+		assert.Equal(nodeStatus.Name, get(t, nodeStatus.URI))
+	}
+	assert.NoError(nw.Stop(context.Background()))
+}
+
+func TestWrapNetworkStaleForwards(t *testing.T) {
+	assert := assert.New(t)
+	fakeNw := &fakeNetwork{nodes: map[string]*fakeNode{
+		"node1": newFakeNode(t, "node1"),
+		"node2": newFakeNode(t, "node2"),
+	}}
+	var dialer net.Dialer
+	nw, err := WrapNetwork(fakeNw, Config{Dial: dialer.DialContext})
+	assert.NoError(err)
+	uris, err := nw.GetURIs()
+	assert.NoError(err)
+
+	// A node restarted with another API port gets a new forward,
+	// and the forward to its old port is closed
+	fakeNw.nodes["node1"] = newFakeNode(t, "node1")
+	n, err := nw.GetNode("node1")
+	assert.NoError(err)
+	assert.NotEqual(uris[0], n.GetURI())
+	assert.Equal("node1", get(t, n.GetURI()))
+	_, err = http.Get(uris[0])
+	assert.Error(err)
+
+	// The forward to a node removed otherwise than
+	// by RemoveNode is closed once nodes are listed
+	delete(fakeNw.nodes, "node2")
+	nodes, err := nw.GetAllNodes()
+	assert.NoError(err)
+	assert.Len(nodes, 1)
+	_, err = http.Get(uris[1])
+	assert.Error(err)
+	assert.NoError(nw.Stop(context.Background()))
+}
+
+func TestNewNoDial(t *testing.T) {
+	_, err := New(Config{})
+	assert.Error(t, err)
+}
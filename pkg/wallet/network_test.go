@@ -0,0 +1,127 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package wallet_test
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/local"
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/pkg/wallet"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/ava-labs/coreth/ethclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFundOnNetwork tests the balances the wallet funds on a local
+// network of the avalanchego binary at $AVALANCHEGO_PATH
+func TestFundOnNetwork(t *testing.T) {
+	binaryPath := os.Getenv("AVALANCHEGO_PATH")
+	if binaryPath == "" {
+		t.Skip("Environment variable AVALANCHEGO_PATH not set; skipping tests against a network")
+	}
+	assert := assert.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	nw, err := local.NewDefaultNetwork(logging.NoLog{}, binaryPath)
+	if !assert.NoError(err) {
+		return
+	}
+	defer func() {
+		assert.NoError(nw.Stop(context.Background()))
+	}()
+	if !assert.NoError(nw.Healthy(ctx)) {
+		return
+	}
+	w, err := wallet.NewFromNetwork(ctx, nw)
+	if !assert.NoError(err) {
+		return
+	}
+
+	const amount = 1_000_000_000
+	key := newKey(t)
+	addr := key.PublicKey().Address()
+	_, err = w.FundX(ctx, addr, amount)
+	assert.NoError(err)
+	_, _, err = w.FundP(ctx, addr, amount)
+	assert.NoError(err)
+	_, err = w.FundC(ctx, wallet.EthAddress(key), amount)
+	assert.NoError(err)
+	xBalance, pBalance, cBalance := balances(ctx, t, nw, key)
+	assert.Equal(uint64(amount), xBalance)
+	// The fees of the imports are paid by the funding wallet
+	assert.Equal(uint64(amount), pBalance)
+	assert.Equal(uint64(amount), cBalance)
+
+	// The funded key sends from its P-Chain balance
+	keyWallet, err := wallet.NewFromNetwork(ctx, nw, key)
+	if !assert.NoError(err) {
+		return
+	}
+	to := newKey(t)
+	_, _, err = keyWallet.TransferPToX(ctx, to.PublicKey().Address(), amount/4)
+	assert.NoError(err)
+	_, _, err = keyWallet.TransferPToC(ctx, wallet.EthAddress(to), amount/4)
+	assert.NoError(err)
+	xBalance, pBalance, cBalance = balances(ctx, t, nw, to)
+	assert.Equal(uint64(amount/4), xBalance)
+	assert.Zero(pBalance)
+	assert.Equal(uint64(amount/4), cBalance)
+	_, pBalance, _ = balances(ctx, t, nw, key)
+	assert.Less(pBalance, uint64(amount/2))
+}
+
+func newKey(t *testing.T) *crypto.PrivateKeySECP256K1R {
+	factory := crypto.FactorySECP256K1R{}
+	key, err := factory.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key.(*crypto.PrivateKeySECP256K1R)
+}
+
+// Returns the nAVAX balances of [key] on the X-Chain, P-Chain and
+// C-Chain, as seen by the node the wallets of [nw] issue txs to
+func balances(ctx context.Context, t *testing.T, nw network.Network, key *crypto.PrivateKeySECP256K1R) (uint64, uint64, uint64) {
+	w, err := wallet.NewFromNetwork(ctx, nw, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xWallet, pWallet := w.Primary().X(), w.Primary().P()
+	xBalances, err := xWallet.Builder().GetFTBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pBalances, err := pWallet.Builder().GetBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := nw.GetAllNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeNames := make([]string, 0, len(nodes))
+	for nodeName := range nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	client, err := ethclient.DialContext(ctx, nodes[nodeNames[0]].GetURI()+"/ext/bc/C/rpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	wei, err := client.BalanceAt(ctx, wallet.EthAddress(key), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cBalance := new(big.Int).Div(wei, big.NewInt(1_000_000_000))
+	return xBalances[xWallet.AVAXAssetID()], pBalances[pWallet.AVAXAssetID()], cBalance.Uint64()
+}
@@ -0,0 +1,419 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package wallet funds test accounts on the primary network
+// chains of a running network, from genesis-funded keys.
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanchego/api/info"
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ava-labs/avalanchego/utils/crypto"
+	"github.com/ava-labs/avalanchego/utils/formatting/address"
+	"github.com/ava-labs/avalanchego/vms/components/avax"
+	"github.com/ava-labs/avalanchego/vms/secp256k1fx"
+	"github.com/ava-labs/avalanchego/wallet/subnet/primary"
+	"github.com/ava-labs/avalanchego/wallet/subnet/primary/common"
+	"github.com/ava-labs/coreth/core/types"
+	"github.com/ava-labs/coreth/ethclient"
+	"github.com/ava-labs/coreth/plugin/evm"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// How often issued txs are polled for acceptance
+	pollFrequency = time.Second
+	// Gas of a transfer of the C-Chain's native asset
+	transferGas = 21_000
+	// nAVAX --> wei, as the C-Chain uses 18 decimals
+	x2cRate = 1_000_000_000
+	// The C-Chain's base fee may rise between the estimation of the fee
+	// of an import and its acceptance, so the fee is paid at this many
+	// times the estimated base fee
+	cImportFeeMargin = 2
+	cChainAlias      = "C"
+)
+
+// Wallet issues transactions on the X-Chain, P-Chain and C-Chain of
+// a running network, paid for by the keys it's created with
+type Wallet struct {
+	uri     string
+	keys    []*crypto.PrivateKeySECP256K1R
+	wallet  primary.Wallet
+	ethAddr ethcommon.Address
+}
+
+// New returns a wallet that issues transactions to the node at [uri]
+// (e.g. http://127.0.0.1:9650), with the funds of [keys].
+// If no keys are given, genesis.EWOQKey, which the default
+// network genesis funds on all the chains, is used.
+// C-Chain transfers are paid for by the first key.
+func New(ctx context.Context, uri string, keys ...*crypto.PrivateKeySECP256K1R) (*Wallet, error) {
+	if len(keys) == 0 {
+		keys = []*crypto.PrivateKeySECP256K1R{genesis.EWOQKey}
+	}
+	kc := secp256k1fx.NewKeychain(keys...)
+	wallet, err := primary.NewWalletFromURI(ctx, uri, kc)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create wallet: %w", err)
+	}
+	return &Wallet{
+		uri:     uri,
+		keys:    keys,
+		wallet:  primary.NewWalletWithOptions(wallet, common.WithPollFrequency(pollFrequency)),
+		ethAddr: EthAddress(keys[0]),
+	}, nil
+}
+
+// NewFromNetwork is like New, but issues the transactions to a node of [nw]
+func NewFromNetwork(ctx context.Context, nw network.Network, keys ...*crypto.PrivateKeySECP256K1R) (*Wallet, error) {
+	nodes, err := nw.GetAllNodes()
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("network has no nodes")
+	}
+	nodeNames := make([]string, 0, len(nodes))
+	for nodeName := range nodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	node := nodes[nodeNames[0]]
+	return New(ctx, node.GetURI(), keys...)
+}
+
+// EthAddress returns the C-Chain address of [key]
+func EthAddress(key *crypto.PrivateKeySECP256K1R) ethcommon.Address {
+	return ethcrypto.PubkeyToAddress(key.ToECDSA().PublicKey)
+}
+
+// Primary returns the wallet of the X-Chain and P-Chain,
+// to issue transactions not covered by this wallet
+func (w *Wallet) Primary() primary.Wallet {
+	return w.wallet
+}
+
+// FundX sends [amount] nAVAX to [to] on the X-Chain.
+// Returns the ID of the accepted tx.
+func (w *Wallet) FundX(ctx context.Context, to ids.ShortID, amount uint64) (ids.ID, error) {
+	txID, err := w.wallet.X().IssueBaseTx(
+		[]*avax.TransferableOutput{output(w.wallet.X().AVAXAssetID(), to, amount)},
+		common.WithContext(ctx),
+	)
+	if err != nil {
+		return ids.Empty, fmt.Errorf("couldn't send %d nAVAX to %s on the X-Chain: %w", amount, to, err)
+	}
+	return txID, nil
+}
+
+// FundP sends [amount] nAVAX to [to] on the P-Chain. The funds are
+// exported from the X-Chain, and imported to the P-Chain. The fee of
+// the import is exported along with [amount], so that [to] receives
+// [amount]. Funds exported to the P-Chain by earlier calls that
+// failed to import them are imported to [to] as well.
+// Returns the IDs of the accepted export and import txs.
+func (w *Wallet) FundP(ctx context.Context, to ids.ShortID, amount uint64) (ids.ID, ids.ID, error) {
+	xWallet, pWallet := w.wallet.X(), w.wallet.P()
+	// Export to the wallet's own address, so that
+	// the wallet can sign the import
+	exportTxID, err := xWallet.IssueExportTx(
+		constants.PlatformChainID,
+		[]*avax.TransferableOutput{output(xWallet.AVAXAssetID(), w.keys[0].PublicKey().Address(), amount+pWallet.BaseTxFee())},
+		common.WithContext(ctx),
+	)
+	if err != nil {
+		return ids.Empty, ids.Empty, fmt.Errorf("couldn't export %d nAVAX from the X-Chain: %w", amount, err)
+	}
+	importTxID, err := pWallet.IssueImportTx(
+		xWallet.BlockchainID(),
+		&secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{to}},
+		common.WithContext(ctx),
+	)
+	if err != nil {
+		return exportTxID, ids.Empty, fmt.Errorf("couldn't import %d nAVAX to the P-Chain: %w", amount, err)
+	}
+	return exportTxID, importTxID, nil
+}
+
+// TransferPToX sends [amount] nAVAX from the P-Chain to [to] on the
+// X-Chain. The fee of the import is exported along with [amount], so
+// that [to] receives [amount]. Funds exported to the X-Chain by earlier
+// calls that failed to import them are imported to [to] as well.
+// Returns the IDs of the accepted export and import txs.
+func (w *Wallet) TransferPToX(ctx context.Context, to ids.ShortID, amount uint64) (ids.ID, ids.ID, error) {
+	xWallet, pWallet := w.wallet.X(), w.wallet.P()
+	exportTxID, err := pWallet.IssueExportTx(
+		xWallet.BlockchainID(),
+		[]*avax.TransferableOutput{output(pWallet.AVAXAssetID(), w.keys[0].PublicKey().Address(), amount+xWallet.BaseTxFee())},
+		common.WithContext(ctx),
+	)
+	if err != nil {
+		return ids.Empty, ids.Empty, fmt.Errorf("couldn't export %d nAVAX from the P-Chain: %w", amount, err)
+	}
+	importTxID, err := xWallet.IssueImportTx(
+		constants.PlatformChainID,
+		&secp256k1fx.OutputOwners{Threshold: 1, Addrs: []ids.ShortID{to}},
+		common.WithContext(ctx),
+	)
+	if err != nil {
+		return exportTxID, ids.Empty, fmt.Errorf("couldn't import %d nAVAX to the X-Chain: %w", amount, err)
+	}
+	return exportTxID, importTxID, nil
+}
+
+// TransferPToC sends [amount] nAVAX from the P-Chain to [to] on the
+// C-Chain. The fee of the import is exported along with [amount], so
+// that [to] receives [amount]. The fee is paid at a multiple of the
+// C-Chain's base fee, so that the import doesn't fail if it rises.
+// Returns the IDs of the accepted export and import txs.
+func (w *Wallet) TransferPToC(ctx context.Context, to ethcommon.Address, amount uint64) (ids.ID, ids.ID, error) {
+	pWallet := w.wallet.P()
+	cChainID, err := info.NewClient(w.uri).GetBlockchainID(ctx, cChainAlias)
+	if err != nil {
+		return ids.Empty, ids.Empty, fmt.Errorf("couldn't get C-Chain ID: %w", err)
+	}
+	client, err := ethclient.DialContext(ctx, w.uri+"/ext/bc/C/rpc")
+	if err != nil {
+		return ids.Empty, ids.Empty, fmt.Errorf("couldn't connect to the C-Chain: %w", err)
+	}
+	defer client.Close()
+	baseFee, err := client.EstimateBaseFee(ctx)
+	if err != nil {
+		return ids.Empty, ids.Empty, fmt.Errorf("couldn't get C-Chain base fee: %w", err)
+	}
+	baseFee.Mul(baseFee, big.NewInt(cImportFeeMargin))
+
+	// The import spends the single output of the export, so its fee
+	// is known before the export
+	fee, err := w.cImportFee(cChainID, to, amount, baseFee)
+	if err != nil {
+		return ids.Empty, ids.Empty, err
+	}
+	exportTxID, err := pWallet.IssueExportTx(
+		cChainID,
+		[]*avax.TransferableOutput{output(pWallet.AVAXAssetID(), w.keys[0].PublicKey().Address(), amount+fee)},
+		common.WithContext(ctx),
+	)
+	if err != nil {
+		return ids.Empty, ids.Empty, fmt.Errorf("couldn't export %d nAVAX from the P-Chain: %w", amount, err)
+	}
+	importTxID, err := w.importToC(ctx, cChainID, exportTxID, to, amount)
+	if err != nil {
+		return exportTxID, ids.Empty, fmt.Errorf("couldn't import %d nAVAX to the C-Chain: %w", amount, err)
+	}
+	return exportTxID, importTxID, nil
+}
+
+// Returns the fee, in nAVAX, of an import to the C-Chain [cChainID] of
+// a single output of the wallet's first key, sending [amount] to [to],
+// at [baseFee]
+func (w *Wallet) cImportFee(cChainID ids.ID, to ethcommon.Address, amount uint64, baseFee *big.Int) (uint64, error) {
+	input := &avax.TransferableInput{
+		Asset: avax.Asset{ID: w.wallet.P().AVAXAssetID()},
+		In: &secp256k1fx.TransferInput{
+			Amt:   amount,
+			Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+		},
+	}
+	tx, err := w.newCImportTx(cChainID, []*avax.TransferableInput{input}, nil, to, amount)
+	if err != nil {
+		return 0, err
+	}
+	gas, err := tx.GasUsed(true)
+	if err != nil {
+		return 0, err
+	}
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(gas), baseFee)
+	fee.Add(fee, big.NewInt(x2cRate-1))
+	fee.Div(fee, big.NewInt(x2cRate))
+	if !fee.IsUint64() {
+		return 0, fmt.Errorf("import fee %s nAVAX is too high", fee)
+	}
+	return fee.Uint64(), nil
+}
+
+// Imports the output of the export [exportTxID] from the P-Chain to the
+// C-Chain [cChainID], sending [amount] to [to] and burning the rest.
+// Returns the ID of the accepted import tx.
+func (w *Wallet) importToC(ctx context.Context, cChainID ids.ID, exportTxID ids.ID, to ethcommon.Address, amount uint64) (ids.ID, error) {
+	client := evm.NewCChainClient(w.uri)
+	addr, err := address.Format(cChainAlias, constants.GetHRP(w.wallet.P().NetworkID()), w.keys[0].PublicKey().Address().Bytes())
+	if err != nil {
+		return ids.Empty, err
+	}
+	utxosBytes, _, err := client.GetAtomicUTXOs(ctx, []string{addr}, "P", 0, "", "")
+	if err != nil {
+		return ids.Empty, fmt.Errorf("couldn't get UTXOs exported to the C-Chain: %w", err)
+	}
+	kc := secp256k1fx.NewKeychain(w.keys...)
+	inputs := []*avax.TransferableInput{}
+	signers := [][]*crypto.PrivateKeySECP256K1R{}
+	for _, utxoBytes := range utxosBytes {
+		utxo := &avax.UTXO{}
+		if _, err := evm.Codec.Unmarshal(utxoBytes, utxo); err != nil {
+			return ids.Empty, fmt.Errorf("couldn't parse UTXO: %w", err)
+		}
+		if utxo.TxID != exportTxID {
+			continue
+		}
+		inIntf, utxoSigners, err := kc.Spend(utxo.Out, uint64(time.Now().Unix()))
+		if err != nil {
+			return ids.Empty, fmt.Errorf("couldn't spend UTXO %s: %w", utxo.InputID(), err)
+		}
+		in, ok := inIntf.(avax.TransferableIn)
+		if !ok {
+			return ids.Empty, fmt.Errorf("unexpected input type %T", inIntf)
+		}
+		inputs = append(inputs, &avax.TransferableInput{
+			UTXOID: utxo.UTXOID,
+			Asset:  utxo.Asset,
+			In:     in,
+		})
+		signers = append(signers, utxoSigners)
+	}
+	if len(inputs) == 0 {
+		return ids.Empty, fmt.Errorf("output of export %s not found", exportTxID)
+	}
+	avax.SortTransferableInputsWithSigners(inputs, signers)
+	tx, err := w.newCImportTx(cChainID, inputs, signers, to, amount)
+	if err != nil {
+		return ids.Empty, err
+	}
+	txID, err := client.IssueTx(ctx, tx.Bytes())
+	if err != nil {
+		return ids.Empty, err
+	}
+	for {
+		status, err := client.GetAtomicTxStatus(ctx, txID)
+		if err == nil {
+			switch status {
+			case evm.Accepted:
+				return txID, nil
+			case evm.Dropped:
+				return txID, fmt.Errorf("tx %s was dropped", txID)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return txID, fmt.Errorf("tx %s wasn't accepted: %w", txID, ctx.Err())
+		case <-time.After(pollFrequency):
+		}
+	}
+}
+
+// Returns an import from the P-Chain to the C-Chain [cChainID] of
+// [inputs], signed by [signers], that sends [amount] to [to]
+func (w *Wallet) newCImportTx(
+	cChainID ids.ID,
+	inputs []*avax.TransferableInput,
+	signers [][]*crypto.PrivateKeySECP256K1R,
+	to ethcommon.Address,
+	amount uint64,
+) (*evm.Tx, error) {
+	pWallet := w.wallet.P()
+	tx := &evm.Tx{UnsignedAtomicTx: &evm.UnsignedImportTx{
+		NetworkID:      pWallet.NetworkID(),
+		BlockchainID:   cChainID,
+		SourceChain:    constants.PlatformChainID,
+		ImportedInputs: inputs,
+		Outs: []evm.EVMOutput{{
+			Address: to,
+			Amount:  amount,
+			AssetID: pWallet.AVAXAssetID(),
+		}},
+	}}
+	if err := tx.Sign(evm.Codec, signers); err != nil {
+		return nil, fmt.Errorf("couldn't sign tx: %w", err)
+	}
+	return tx, nil
+}
+
+// FundC sends [amount] nAVAX to [to] on the C-Chain,
+// from the C-Chain balance of the wallet's first key.
+// Returns the hash of the accepted tx.
+func (w *Wallet) FundC(ctx context.Context, to ethcommon.Address, amount uint64) (ethcommon.Hash, error) {
+	client, err := ethclient.DialContext(ctx, w.uri+"/ext/bc/C/rpc")
+	if err != nil {
+		return ethcommon.Hash{}, fmt.Errorf("couldn't connect to the C-Chain: %w", err)
+	}
+	defer client.Close()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return ethcommon.Hash{}, fmt.Errorf("couldn't get C-Chain ID: %w", err)
+	}
+	nonce, err := client.AcceptedNonceAt(ctx, w.ethAddr)
+	if err != nil {
+		return ethcommon.Hash{}, fmt.Errorf("couldn't get nonce of %s: %w", w.ethAddr, err)
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return ethcommon.Hash{}, fmt.Errorf("couldn't get gas price: %w", err)
+	}
+	value := new(big.Int).Mul(new(big.Int).SetUint64(amount), big.NewInt(x2cRate))
+	tx, err := types.SignTx(
+		types.NewTransaction(nonce, to, value, transferGas, gasPrice, nil),
+		types.LatestSignerForChainID(chainID),
+		w.keys[0].ToECDSA(),
+	)
+	if err != nil {
+		return ethcommon.Hash{}, fmt.Errorf("couldn't sign tx: %w", err)
+	}
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		return ethcommon.Hash{}, fmt.Errorf("couldn't send %d nAVAX to %s on the C-Chain: %w", amount, to, err)
+	}
+	for {
+		receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+		if err == nil {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return tx.Hash(), fmt.Errorf("tx %s failed", tx.Hash())
+			}
+			return tx.Hash(), nil
+		}
+		select {
+		case <-ctx.Done():
+			return tx.Hash(), fmt.Errorf("tx %s wasn't accepted: %w", tx.Hash(), ctx.Err())
+		case <-time.After(pollFrequency):
+		}
+	}
+}
+
+// FundKey sends [amount] nAVAX on each of the X-Chain,
+// P-Chain and C-Chain to the addresses of [key]
+func (w *Wallet) FundKey(ctx context.Context, key *crypto.PrivateKeySECP256K1R, amount uint64) error {
+	addr := key.PublicKey().Address()
+	if _, err := w.FundX(ctx, addr, amount); err != nil {
+		return err
+	}
+	if _, _, err := w.FundP(ctx, addr, amount); err != nil {
+		return err
+	}
+	_, err := w.FundC(ctx, EthAddress(key), amount)
+	return err
+}
+
+// Returns an output that sends [amount] of [assetID] to [to]
+func output(assetID ids.ID, to ids.ShortID, amount uint64) *avax.TransferableOutput {
+	return &avax.TransferableOutput{
+		Asset: avax.Asset{ID: assetID},
+		Out: &secp256k1fx.TransferOutput{
+			Amt: amount,
+			OutputOwners: secp256k1fx.OutputOwners{
+				Threshold: 1,
+				Addrs:     []ids.ShortID{to},
+			},
+		},
+	}
+}
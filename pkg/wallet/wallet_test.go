@@ -0,0 +1,16 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package wallet
+
+import (
+	"testing"
+
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEthAddress(t *testing.T) {
+	// The C-Chain address funded by the default genesis
+	assert.Equal(t, "0x8db97C7cEcE249c2b98bDC0226Cc4C2A57BF52FC", EthAddress(genesis.EWOQKey).Hex())
+}
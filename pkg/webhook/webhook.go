@@ -0,0 +1,230 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package webhook posts the lifecycle events of a network, as JSON, to
+// webhook URLs, so that dashboards and chat integrations can track
+// long-running networks without polling them.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+const (
+	// Max time an event is posted for, if not given in the config
+	DefaultTimeout = 10 * time.Second
+	// Max time Close waits for the pending events to be posted
+	closeTimeout = 30 * time.Second
+	// Max number of events waiting to be posted.
+	// Events beyond it are dropped.
+	queueSize = 1024
+	// Times an event is posted to a URL before giving up
+	maxAttempts = 3
+	retryDelay  = time.Second
+)
+
+// EventType is the type of an event
+type EventType string
+
+const (
+	EventNetworkStarted EventType = "network_started"
+	EventNetworkHealthy EventType = "network_healthy"
+	EventNetworkStopped EventType = "network_stopped"
+	EventNodeStarted    EventType = "node_started"
+	EventNodeRestarted  EventType = "node_restarted"
+	EventNodeStopped    EventType = "node_stopped"
+	EventNodeCrashed    EventType = "node_crashed"
+)
+
+// Event is the JSON body posted to the webhook URLs
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	// UUID of the network, see network.Network.Status
+	NetworkUUID string `json:"networkUUID"`
+	// Name and ID of the node, for node events
+	Node   string `json:"node,omitempty"`
+	NodeID string `json:"nodeID,omitempty"`
+	// Error the node exited with, if any
+	Error string `json:"error,omitempty"`
+}
+
+// Config of the webhooks of a network
+type Config struct {
+	// URLs each event is posted to. Must not be empty.
+	URLs []string `json:"urls"`
+	// Headers added to the requests, e.g. Authorization
+	Headers map[string]string `json:"headers"`
+	// Max time an event is posted for.
+	// Defaults to DefaultTimeout.
+	Timeout time.Duration `json:"timeout"`
+	// If non-empty, only events of these types are posted
+	Events []EventType `json:"events"`
+}
+
+// Validate returns an error if this config is invalid
+func (c *Config) Validate() error {
+	if len(c.URLs) == 0 {
+		return errors.New("no URLs given")
+	}
+	for _, rawURL := range c.URLs {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return fmt.Errorf("invalid URL %q: %w", rawURL, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("URL %q isn't http or https", rawURL)
+		}
+	}
+	if c.Timeout < 0 {
+		return errors.New("negative timeout given")
+	}
+	return nil
+}
+
+// Notifier posts events to the webhook URLs of its config, in the
+// background, in the order they're given
+type Notifier struct {
+	log    logging.Logger
+	config Config
+	client *http.Client
+	// Types of the events posted. Nil if all are.
+	events map[EventType]struct{}
+
+	lock   sync.Mutex
+	closed bool
+	queue  chan Event
+	done   chan struct{}
+}
+
+// New returns a notifier that posts events as given by [config]
+func New(log logging.Logger, config Config) (*Notifier, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	n := &Notifier{
+		log:    log,
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		queue:  make(chan Event, queueSize),
+		done:   make(chan struct{}),
+	}
+	if len(config.Events) > 0 {
+		n.events = make(map[EventType]struct{}, len(config.Events))
+		for _, eventType := range config.Events {
+			n.events[eventType] = struct{}{}
+		}
+	}
+	go n.run()
+	return n, nil
+}
+
+// Notify queues [event] to be posted, and returns without waiting for
+// it to be. [event.Time] defaults to now. The event is dropped if too
+// many events are waiting to be posted, or the notifier is closed.
+func (n *Notifier) Notify(event Event) {
+	if n.events != nil {
+		if _, ok := n.events[event.Type]; !ok {
+			return
+		}
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.queue <- event:
+	default:
+		n.log.Warn("dropping %s webhook event: too many events pending", event.Type)
+	}
+}
+
+// Close waits for the queued events to be posted, within
+// a timeout, then stops posting events
+func (n *Notifier) Close() error {
+	n.lock.Lock()
+	if n.closed {
+		n.lock.Unlock()
+		return nil
+	}
+	n.closed = true
+	close(n.queue)
+	n.lock.Unlock()
+
+	select {
+	case <-n.done:
+		return nil
+	case <-time.After(closeTimeout):
+		return errors.New("timed out posting webhook events")
+	}
+}
+
+// Posts the queued events until the queue is closed
+func (n *Notifier) run() {
+	defer close(n.done)
+	for event := range n.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			n.log.Warn("couldn't marshal %s webhook event: %s", event.Type, err)
+			continue
+		}
+		for _, url := range n.config.URLs {
+			if err := n.post(url, body); err != nil {
+				n.log.Warn("couldn't post %s webhook event to %s: %s", event.Type, url, err)
+			}
+		}
+	}
+}
+
+// Posts [body] to [url], retrying on failure
+func (n *Notifier) post(url string, body []byte) error {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(retryDelay)
+		}
+		if err = n.postOnce(url, body); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (n *Notifier) postOnce(url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), n.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range n.config.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("got status %s", resp.Status)
+	}
+	return nil
+}
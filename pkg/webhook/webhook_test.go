@@ -0,0 +1,94 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// Starts a webhook server recording the events posted to it. The first
+// [failures] requests fail.
+func newTestServer(t *testing.T, failures int) (*httptest.Server, func() []Event) {
+	var (
+		lock   sync.Mutex
+		events []Event
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		var event Event
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []Event {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]Event(nil), events...)
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	assert := assert.New(t)
+	server1, events1 := newTestServer(t, 0)
+	// Posting to the second server is retried
+	server2, events2 := newTestServer(t, 1)
+	n, err := New(logging.NoLog{}, Config{
+		URLs:    []string{server1.URL, server2.URL},
+		Headers: map[string]string{"Authorization": "Bearer token"},
+		Events:  []EventType{EventNetworkStarted, EventNodeCrashed},
+	})
+	assert.NoError(err)
+
+	n.Notify(Event{Type: EventNetworkStarted, NetworkUUID: "uuid"})
+	// Not in the config's events
+	n.Notify(Event{Type: EventNodeStarted, Node: "node1"})
+	n.Notify(Event{Type: EventNodeCrashed, Node: "node1", NodeID: "NodeID-1", Error: "exit status 1"})
+	assert.NoError(n.Close())
+	// Dropped once closed
+	n.Notify(Event{Type: EventNetworkStarted})
+	assert.NoError(n.Close())
+
+	for _, events := range [][]Event{events1(), events2()} {
+		assert.Len(events, 2)
+		assert.Equal(EventNetworkStarted, events[0].Type)
+		assert.Equal("uuid", events[0].NetworkUUID)
+		assert.False(events[0].Time.IsZero())
+		assert.Equal(Event{
+			Type:   EventNodeCrashed,
+			Time:   events[1].Time,
+			Node:   "node1",
+			NodeID: "NodeID-1",
+			Error:  "exit status 1",
+		}, events[1])
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+	valid := Config{URLs: []string{"http://localhost:8080/hook", "https://hooks.example.com/x"}}
+	assert.NoError(valid.Validate())
+	for name, config := range map[string]Config{
+		"no URLs":          {},
+		"invalid URL":      {URLs: []string{"http://[::1"}},
+		"not http":         {URLs: []string{"ftp://example.com"}},
+		"negative timeout": {URLs: []string{"http://example.com"}, Timeout: -1},
+	} {
+		assert.Error(config.Validate(), name)
+	}
+}
@@ -0,0 +1,279 @@
+package remote
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ava-labs/avalanche-network-runner/local"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"golang.org/x/crypto/ssh"
+)
+
+var _ local.NodeProcess = (*process)(nil)
+
+// A node process on a remote host
+type process struct {
+	host    *host
+	name    string
+	command string
+	// Where the process's stdout and stderr are copied to, if anywhere
+	stdout io.Writer
+	stderr io.Writer
+	// Closed once the process's output is copied
+	outputFiles []*os.File
+	// If true, the process's stdin is a pipe
+	pipeStdin bool
+
+	lock    sync.Mutex
+	session *ssh.Session
+	// The process's stdin, if it's a pipe. Nil until started.
+	stdin io.WriteCloser
+	// ID of the process on its host. 0 until started.
+	pid int
+	// Wait may be called more than once, e.g. by the watcher
+	// of crashes and when the node is removed
+	waitOnce sync.Once
+	waitErr  error
+	// Done once the process's output is copied
+	outputWG sync.WaitGroup
+}
+
+// NewNodeProcess uploads the files a node needs to its host, which is the
+// host whose IP is the node's PublicIP, and returns a process that runs
+// the node's binary with [args] on the host once started.
+// See local.NodeProcessCreator.
+func (b *Backend) NewNodeProcess(config node.Config, args ...string) (local.NodeProcess, error) {
+	h, err := b.hostOf(config)
+	if err != nil {
+		return nil, err
+	}
+	// Absolute paths are files of the node on this host, which are
+	// uploaded, or dirs that avalanchego creates, such as its db dir
+	remoteArgs := make([]string, len(args))
+	toUpload := []string{}
+	for i, arg := range args {
+		remoteArgs[i] = arg
+		if !strings.HasPrefix(arg, "--") || !strings.Contains(arg, "=") {
+			continue
+		}
+		kv := strings.SplitN(arg, "=", 2)
+		value := kv[1]
+		if !filepath.IsAbs(value) {
+			continue
+		}
+		remoteArgs[i] = kv[0] + "=" + h.remotePath(value)
+		if _, err := os.Stat(value); err == nil {
+			toUpload = append(toUpload, value)
+		}
+	}
+	// The binary, and the plugins next to it, are only uploaded once
+	binaryPaths := []string{config.BinaryPath}
+	pluginDir := filepath.Join(filepath.Dir(config.BinaryPath), "plugins")
+	if _, err := os.Stat(pluginDir); err == nil {
+		binaryPaths = append(binaryPaths, pluginDir)
+	}
+	h.lock.Lock()
+	for _, binaryPath := range binaryPaths {
+		if _, ok := h.uploaded[binaryPath]; !ok {
+			toUpload = append(toUpload, binaryPath)
+		}
+	}
+	h.lock.Unlock()
+	b.log.Info("uploading files of node %q to host %s", config.Name, h.config.Address)
+	if err := h.upload(toUpload); err != nil {
+		return nil, fmt.Errorf("couldn't upload files of node %q: %w", config.Name, err)
+	}
+	h.lock.Lock()
+	for _, binaryPath := range binaryPaths {
+		h.uploaded[binaryPath] = struct{}{}
+	}
+	h.lock.Unlock()
+
+	p := &process{
+		host:      h,
+		name:      config.Name,
+		pipeStdin: config.Stdin,
+	}
+	quoted := make([]string, 0, len(remoteArgs)+1)
+	quoted = append(quoted, shellQuote(h.remotePath(config.BinaryPath)))
+	for _, arg := range remoteArgs {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	// The shell prints its PID, which is the node's once it execs the
+	// binary, so that the node can be signaled
+	p.command = "echo $$; exec " + strings.Join(quoted, " ")
+	if config.RedirectStdout {
+		p.stdout = b.stdout
+	}
+	if config.RedirectStderr {
+		p.stderr = b.stderr
+	}
+	if config.StdoutPath != "" {
+		if p.stdout, err = p.openOutputFile(config.StdoutPath); err != nil {
+			return nil, err
+		}
+	}
+	if config.StderrPath != "" {
+		if config.StderrPath == config.StdoutPath {
+			p.stderr = p.stdout
+		} else if p.stderr, err = p.openOutputFile(config.StderrPath); err != nil {
+			p.closeOutputFiles()
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Opens the local file at [path] for appending the process's output
+func (p *process) openOutputFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	p.outputFiles = append(p.outputFiles, f)
+	return f, nil
+}
+
+func (p *process) closeOutputFiles() {
+	for _, f := range p.outputFiles {
+		_ = f.Close()
+	}
+	p.outputFiles = nil
+}
+
+func (p *process) Start() error {
+	client, err := p.host.connect()
+	if err != nil {
+		p.closeOutputFiles()
+		return err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		p.closeOutputFiles()
+		return err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		_ = session.Close()
+		p.closeOutputFiles()
+		return err
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		_ = session.Close()
+		p.closeOutputFiles()
+		return err
+	}
+	var stdin io.WriteCloser
+	if p.pipeStdin {
+		if stdin, err = session.StdinPipe(); err != nil {
+			_ = session.Close()
+			p.closeOutputFiles()
+			return err
+		}
+	}
+	if err := session.Start(p.command); err != nil {
+		_ = session.Close()
+		p.closeOutputFiles()
+		return fmt.Errorf("couldn't start node %q on host %s: %w", p.name, p.host.config.Address, err)
+	}
+	stdoutReader := bufio.NewReader(stdout)
+	pidLine, err := stdoutReader.ReadString('\n')
+	if err != nil {
+		_ = session.Close()
+		p.closeOutputFiles()
+		return fmt.Errorf("couldn't read PID of node %q: %w", p.name, err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(pidLine))
+	if err != nil {
+		_ = session.Close()
+		p.closeOutputFiles()
+		return fmt.Errorf("couldn't parse PID of node %q: %w", p.name, err)
+	}
+	p.lock.Lock()
+	p.session = session
+	p.stdin = stdin
+	p.pid = pid
+	p.lock.Unlock()
+
+	p.outputWG.Add(2)
+	go p.copyOutput(p.stdout, stdoutReader)
+	go p.copyOutput(p.stderr, stderr)
+	return nil
+}
+
+// Copies [src] to [dst] until EOF, or discards it if [dst] is nil
+func (p *process) copyOutput(dst io.Writer, src io.Reader) {
+	defer p.outputWG.Done()
+	if dst == nil {
+		dst = io.Discard
+	}
+	_, _ = io.Copy(dst, src)
+}
+
+// Sends [signal] to the process
+func (p *process) signal(signal string) error {
+	p.lock.Lock()
+	pid := p.pid
+	p.lock.Unlock()
+	if pid == 0 {
+		return errors.New("process not started")
+	}
+	_, err := p.host.run(fmt.Sprintf("kill -%s %d", signal, pid))
+	return err
+}
+
+func (p *process) Stop() error {
+	return p.signal("TERM")
+}
+
+func (p *process) Pause() error {
+	return p.signal("STOP")
+}
+
+func (p *process) Resume() error {
+	return p.signal("CONT")
+}
+
+// Sends [sig] to the process, by name, since
+// signal numbers differ between operating systems
+func (p *process) Signal(sig os.Signal) error {
+	name, err := signalName(sig)
+	if err != nil {
+		return err
+	}
+	return p.signal(name)
+}
+
+func (p *process) Stdin() io.WriteCloser {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.stdin
+}
+
+func (p *process) Wait() error {
+	p.waitOnce.Do(func() {
+		p.lock.Lock()
+		session := p.session
+		p.lock.Unlock()
+		if session == nil {
+			p.waitErr = errors.New("process not started")
+			return
+		}
+		p.waitErr = session.Wait()
+		p.outputWG.Wait()
+		_ = session.Close()
+		p.closeOutputFiles()
+	})
+	return p.waitErr
+}
@@ -0,0 +1,303 @@
+// Package remote runs the nodes of a network on remote hosts, over SSH,
+// so that the nodes are separated by real network links. The network is
+// a local network (see package local) whose node processes are started
+// on the hosts: the runner writes the nodes' files on this host as usual,
+// uploads them along with the avalanchego binary, and starts the binary
+// over SSH, streaming its stdout and stderr back.
+//
+// The files of a node are mirrored under the dir of its host: the file
+// at /a/b on this host is uploaded to <dir>/a/b, and the paths given to
+// avalanchego on the command line are rewritten to match. The db and
+// logs dirs of a node are then on its host, rather than at the paths
+// returned by node.Node.GetDbDir and node.Node.GetLogsDir.
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/ava-labs/avalanche-network-runner/local"
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultDir is the dir the files of the nodes
+// are uploaded under on a host, if not given
+const DefaultDir = "/tmp/avalanche-network-runner"
+
+const defaultSSHPort = "22"
+
+var (
+	_ local.NodeProcessCreator = (*Backend)(nil)
+
+	errNoHosts = errors.New("no hosts given")
+)
+
+// HostConfig is how a host is reached
+type HostConfig struct {
+	// Address of the host's SSH server, as host or host:port.
+	// The port defaults to 22.
+	Address string `json:"address"`
+	// IP the nodes on this host advertise to their peers, and serve their
+	// API on, which must be reachable from the other hosts and from this
+	// one. Defaults to the host of [Address], which must then be an IP.
+	IP string `json:"ip"`
+	// Dir the files of the nodes are uploaded under.
+	// Defaults to DefaultDir.
+	Dir string `json:"dir"`
+	// SSH client config, with the user, auth methods and host key
+	// callback. Must not be nil.
+	SSH *ssh.ClientConfig `json:"-"`
+}
+
+// Config of a Backend
+type Config struct {
+	// Must not be empty
+	Hosts []HostConfig
+	// Where the stdout and stderr of nodes whose config has RedirectStdout
+	// or RedirectStderr are written. Default to os.Stdout and os.Stderr.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Backend starts node processes on remote hosts, over SSH
+type Backend struct {
+	log    logging.Logger
+	stdout io.Writer
+	stderr io.Writer
+	hosts  []*host
+}
+
+// A remote host
+type host struct {
+	config HostConfig
+	// Guards the fields below
+	lock   sync.Mutex
+	client *ssh.Client
+	// Local paths of the binaries and build dirs uploaded to this
+	// host, which are only uploaded once
+	uploaded map[string]struct{}
+}
+
+// NewBackend returns a backend that starts node processes on
+// the hosts of [config]. The hosts are connected to on first use.
+func NewBackend(log logging.Logger, config Config) (*Backend, error) {
+	if len(config.Hosts) == 0 {
+		return nil, errNoHosts
+	}
+	if config.Stdout == nil {
+		config.Stdout = os.Stdout
+	}
+	if config.Stderr == nil {
+		config.Stderr = os.Stderr
+	}
+	b := &Backend{
+		log:    log,
+		stdout: config.Stdout,
+		stderr: config.Stderr,
+	}
+	ips := map[string]struct{}{}
+	for i, hostConfig := range config.Hosts {
+		if hostConfig.SSH == nil {
+			return nil, fmt.Errorf("hosts[%d]: no SSH client config given", i)
+		}
+		if _, _, err := net.SplitHostPort(hostConfig.Address); err != nil {
+			hostConfig.Address = net.JoinHostPort(hostConfig.Address, defaultSSHPort)
+		}
+		if hostConfig.IP == "" {
+			hostConfig.IP, _, _ = net.SplitHostPort(hostConfig.Address)
+		}
+		if node.ParseIP(hostConfig.IP) == nil {
+			return nil, fmt.Errorf("hosts[%d]: invalid IP %q", i, hostConfig.IP)
+		}
+		hostConfig.IP = node.ParseIP(hostConfig.IP).String()
+		if _, ok := ips[hostConfig.IP]; ok {
+			return nil, fmt.Errorf("hosts[%d]: IP %s given twice", i, hostConfig.IP)
+		}
+		ips[hostConfig.IP] = struct{}{}
+		if hostConfig.Dir == "" {
+			hostConfig.Dir = DefaultDir
+		}
+		if !path.IsAbs(hostConfig.Dir) {
+			return nil, fmt.Errorf("hosts[%d]: dir %q isn't absolute", i, hostConfig.Dir)
+		}
+		b.hosts = append(b.hosts, &host{
+			config:   hostConfig,
+			uploaded: map[string]struct{}{},
+		})
+	}
+	return b, nil
+}
+
+// NewNetwork returns a network whose nodes run on the hosts of [config].
+// Nodes whose config has no PublicIP are spread over the hosts, in turn,
+// by setting their PublicIP and BindAddr to the IP of a host. Other nodes,
+// including the nodes added later, run on the host whose IP is their
+// PublicIP. Stopping the network closes the SSH connections.
+// See local.NewNetwork for [rootDir] and [snapshotsDir].
+func NewNetwork(
+	log logging.Logger,
+	networkConfig network.Config,
+	config Config,
+	rootDir string,
+	snapshotsDir string,
+) (network.Network, error) {
+	b, err := NewBackend(log, config)
+	if err != nil {
+		return nil, err
+	}
+	networkConfig.NodeConfigs = b.AssignHosts(networkConfig.NodeConfigs)
+	nw, err := local.NewNetworkWithProcessCreator(log, networkConfig, rootDir, snapshotsDir, b)
+	if err != nil {
+		_ = b.Close()
+		return nil, err
+	}
+	return &remoteNetwork{Network: nw, backend: b}, nil
+}
+
+// AssignHosts returns a copy of [nodeConfigs] in which the nodes without
+// a PublicIP run on the hosts of this backend, in turn
+func (b *Backend) AssignHosts(nodeConfigs []node.Config) []node.Config {
+	assigned := make([]node.Config, len(nodeConfigs))
+	i := 0
+	for j, nodeConfig := range nodeConfigs {
+		if nodeConfig.PublicIP == "" {
+			ip := b.hosts[i%len(b.hosts)].config.IP
+			nodeConfig.PublicIP = ip
+			nodeConfig.BindAddr = ip
+			i++
+		}
+		assigned[j] = nodeConfig
+	}
+	return assigned
+}
+
+// Dial opens a connection to [addr] through the SSH connection of the
+// host whose IP is the host of [addr], e.g. to reach a port of a node
+// that its host's firewall only opens to the host itself. Other
+// addresses are dialed directly. See package tunnel.
+func (b *Backend) Dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	ipStr, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := node.ParseIP(ipStr); ip != nil {
+		if h, ok := b.hostByIP(ip.String()); ok {
+			client, err := h.connect()
+			if err != nil {
+				return nil, err
+			}
+			return client.Dial(network, addr)
+		}
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// Close closes the SSH connections to the hosts
+func (b *Backend) Close() error {
+	var errs []string
+	for _, h := range b.hosts {
+		h.lock.Lock()
+		if h.client != nil {
+			if err := h.client.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+				errs = append(errs, fmt.Sprintf("%s: %s", h.config.Address, err))
+			}
+			h.client = nil
+		}
+		h.lock.Unlock()
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("couldn't close SSH connections: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Returns the host whose IP is [ip]
+func (b *Backend) hostByIP(ip string) (*host, bool) {
+	for _, h := range b.hosts {
+		if h.config.IP == ip {
+			return h, true
+		}
+	}
+	return nil, false
+}
+
+// Returns the host a node with config [config] runs on
+func (b *Backend) hostOf(config node.Config) (*host, error) {
+	ip := node.ParseIP(config.PublicIP)
+	if ip == nil {
+		return nil, fmt.Errorf("node %q has no public IP, so no host", config.Name)
+	}
+	h, ok := b.hostByIP(ip.String())
+	if !ok {
+		return nil, fmt.Errorf("no host has the public IP %s of node %q", ip, config.Name)
+	}
+	return h, nil
+}
+
+// Returns the SSH client of [h], connecting if needed
+func (h *host) connect() (*ssh.Client, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.client != nil {
+		return h.client, nil
+	}
+	client, err := ssh.Dial("tcp", h.config.Address, h.config.SSH)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't connect to host %s: %w", h.config.Address, err)
+	}
+	h.client = client
+	return client, nil
+}
+
+// Returns the path on [h] of the file at [localPath] on this host
+func (h *host) remotePath(localPath string) string {
+	return path.Join(h.config.Dir, strings.ReplaceAll(localPath, string(os.PathSeparator), "/"))
+}
+
+// Runs [cmd] on [h], and returns its combined output
+func (h *host) run(cmd string) (string, error) {
+	client, err := h.connect()
+	if err != nil {
+		return "", err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+	out, err := session.CombinedOutput(cmd)
+	if err != nil {
+		return string(out), fmt.Errorf("command %q failed on host %s: %w: %s", cmd, h.config.Address, err, out)
+	}
+	return string(out), nil
+}
+
+// Returns [s] quoted for the remote shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// A network whose nodes run on remote hosts
+type remoteNetwork struct {
+	network.Network
+	backend *Backend
+}
+
+func (nw *remoteNetwork) Stop(ctx context.Context) error {
+	err := nw.Network.Stop(ctx)
+	if closeErr := nw.backend.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
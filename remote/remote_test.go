@@ -0,0 +1,333 @@
+//go:build !windows
+// +build !windows
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+// Starts an SSH server that runs the commands it's given on this host,
+// and returns its address
+func newTestSSHServer(t *testing.T) string {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	assert.NoError(t, err)
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSHConn(conn, serverConfig)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveSSHConn(conn net.Conn, serverConfig *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		switch newChan.ChannelType() {
+		case "session":
+			go serveSession(newChan)
+		case "direct-tcpip":
+			go serveDirectTCPIP(newChan)
+		default:
+			_ = newChan.Reject(ssh.UnknownChannelType, "unsupported")
+		}
+	}
+}
+
+// Runs the command of an exec request of the session
+func serveSession(newChan ssh.NewChannel) {
+	channel, reqs, err := newChan.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	for req := range reqs {
+		if req.Type != "exec" {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		_ = req.Reply(true, nil)
+		cmd := exec.Command("sh", "-c", payload.Command)
+		cmd.Stdin = channel
+		cmd.Stdout = channel
+		cmd.Stderr = channel.Stderr()
+		status := uint32(0)
+		if err := cmd.Run(); err != nil {
+			status = 1
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+				status = uint32(exitErr.ExitCode())
+			}
+		}
+		statusPayload := make([]byte, 4)
+		binary.BigEndian.PutUint32(statusPayload, status)
+		_, _ = channel.SendRequest("exit-status", false, statusPayload)
+		return
+	}
+}
+
+// Forwards a connection to the address it asks for
+func serveDirectTCPIP(newChan ssh.NewChannel) {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChan.ExtraData(), &payload); err != nil {
+		_ = newChan.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		_ = newChan.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, reqs, err := newChan.Accept()
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	go func() {
+		_, _ = io.Copy(channel, conn)
+		_ = channel.Close()
+	}()
+	_, _ = io.Copy(conn, channel)
+	_ = conn.Close()
+}
+
+func newTestBackend(t *testing.T, stdout io.Writer) *Backend {
+	b, err := NewBackend(logging.NoLog{}, Config{
+		Hosts: []HostConfig{{
+			Address: newTestSSHServer(t),
+			Dir:     t.TempDir(),
+			SSH: &ssh.ClientConfig{
+				User:            "test",
+				HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			},
+		}},
+		Stdout: stdout,
+	})
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = b.Close() })
+	return b
+}
+
+// Writes a binary that prints its args and the contents of the
+// file given by its --config-file flag, then sleeps
+func writeTestBinary(t *testing.T) string {
+	binaryPath := filepath.Join(t.TempDir(), "avalanchego")
+	script := `#!/bin/sh
+echo "args: $@"
+for arg in "$@"; do
+	case "$arg" in
+	--config-file=*) cat "${arg#--config-file=}"; echo ;;
+	esac
+done
+exec sleep 30
+`
+	assert.NoError(t, os.WriteFile(binaryPath, []byte(script), 0o700))
+	return binaryPath
+}
+
+// Writer that can be read while it's written to
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+func TestNodeProcess(t *testing.T) {
+	assert := assert.New(t)
+	var stdout syncBuffer
+	b := newTestBackend(t, &stdout)
+	h := b.hosts[0]
+
+	nodeDir := t.TempDir()
+	configFile := filepath.Join(nodeDir, "config.json")
+	assert.NoError(os.WriteFile(configFile, []byte(`{"network-id":1337}`), 0o600))
+	dbDir := filepath.Join(nodeDir, "db")
+	config := node.Config{
+		Name:           "node1",
+		BinaryPath:     writeTestBinary(t),
+		PublicIP:       "127.0.0.1",
+		RedirectStdout: true,
+	}
+	p, err := b.NewNodeProcess(config, "--config-file="+configFile, "--db-dir="+dbDir, "--http-port=9650")
+	assert.NoError(err)
+
+	// The files are mirrored under the host's dir
+	remoteConfigFile := h.remotePath(configFile)
+	assert.FileExists(remoteConfigFile)
+	assert.FileExists(h.remotePath(config.BinaryPath))
+
+	assert.NoError(p.Start())
+	assert.Eventually(func() bool {
+		return strings.Contains(stdout.String(), `{"network-id":1337}`)
+	}, 10*time.Second, 50*time.Millisecond)
+	out := stdout.String()
+	assert.Contains(out, "--config-file="+remoteConfigFile)
+	assert.Contains(out, "--db-dir="+h.remotePath(dbDir))
+	assert.Contains(out, "--http-port=9650")
+
+	assert.NoError(p.Pause())
+	assert.NoError(p.Resume())
+	// Signals are sent by name
+	assert.NoError(p.Signal(syscall.SIGCONT))
+	assert.Nil(p.Stdin())
+	assert.NoError(p.Stop())
+	err = p.Wait()
+	var exitErr *ssh.ExitError
+	assert.True(err == nil || errors.As(err, &exitErr), err)
+	// The process is gone
+	assert.Error(syscall.Kill(p.(*process).pid, 0))
+
+	// A node must run on one of the hosts
+	config.PublicIP = "10.0.0.1"
+	_, err = b.NewNodeProcess(config)
+	assert.Error(err)
+}
+
+// TestUploadSymlinks tests that the targets of symlinks, like those
+// of the plugins of a node's build dir, are uploaded under their paths
+func TestUploadSymlinks(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBackend(t, io.Discard)
+	h := b.hosts[0]
+
+	vmDir := t.TempDir()
+	vmBinary := filepath.Join(vmDir, "vm")
+	assert.NoError(os.WriteFile(vmBinary, []byte("vm binary"), 0o700))
+	assert.NoError(os.Mkdir(filepath.Join(vmDir, "data"), 0o700))
+	assert.NoError(os.WriteFile(filepath.Join(vmDir, "data", "genesis"), []byte("genesis"), 0o600))
+	buildDir := t.TempDir()
+	pluginDir := filepath.Join(buildDir, "plugins")
+	assert.NoError(os.Mkdir(pluginDir, 0o700))
+	assert.NoError(os.Symlink(vmBinary, filepath.Join(pluginDir, "vmID")))
+	assert.NoError(os.Symlink(filepath.Join(vmDir, "data"), filepath.Join(pluginDir, "data")))
+
+	assert.NoError(h.upload([]string{buildDir}))
+	plugin := h.remotePath(filepath.Join(pluginDir, "vmID"))
+	info, err := os.Lstat(plugin)
+	assert.NoError(err)
+	assert.True(info.Mode().IsRegular())
+	assert.NotZero(info.Mode() & 0o100)
+	contents, err := os.ReadFile(plugin)
+	assert.NoError(err)
+	assert.Equal("vm binary", string(contents))
+	contents, err = os.ReadFile(h.remotePath(filepath.Join(pluginDir, "data", "genesis")))
+	assert.NoError(err)
+	assert.Equal("genesis", string(contents))
+
+	// Symlink loops aren't followed forever
+	assert.NoError(os.Symlink(buildDir, filepath.Join(pluginDir, "loop")))
+	assert.Error(h.upload([]string{buildDir}))
+}
+
+func TestAssignHosts(t *testing.T) {
+	assert := assert.New(t)
+	b, err := NewBackend(logging.NoLog{}, Config{Hosts: []HostConfig{
+		{Address: "10.0.0.1", SSH: &ssh.ClientConfig{}},
+		{Address: "10.0.0.2:2222", SSH: &ssh.ClientConfig{}},
+	}})
+	assert.NoError(err)
+	assert.Equal("10.0.0.1:22", b.hosts[0].config.Address)
+	assert.Equal(DefaultDir, b.hosts[0].config.Dir)
+
+	assigned := b.AssignHosts([]node.Config{{}, {PublicIP: "10.0.0.2"}, {}, {}})
+	assert.Equal("10.0.0.1", assigned[0].PublicIP)
+	assert.Equal("10.0.0.1", assigned[0].BindAddr)
+	assert.Equal("10.0.0.2", assigned[1].PublicIP)
+	assert.Empty(assigned[1].BindAddr)
+	assert.Equal("10.0.0.2", assigned[2].PublicIP)
+	assert.Equal("10.0.0.1", assigned[3].PublicIP)
+
+	for name, config := range map[string]Config{
+		"no hosts": {},
+		"no SSH":   {Hosts: []HostConfig{{Address: "10.0.0.1"}}},
+		"no IP":    {Hosts: []HostConfig{{Address: "example.com", SSH: &ssh.ClientConfig{}}}},
+		"same IP":  {Hosts: []HostConfig{{Address: "10.0.0.1", SSH: &ssh.ClientConfig{}}, {Address: "10.0.0.1:2222", SSH: &ssh.ClientConfig{}}}},
+		"relative": {Hosts: []HostConfig{{Address: "10.0.0.1", Dir: "runner", SSH: &ssh.ClientConfig{}}}},
+	} {
+		_, err := NewBackend(logging.NoLog{}, config)
+		assert.Error(err, name)
+	}
+}
+
+func TestDial(t *testing.T) {
+	assert := assert.New(t)
+	b := newTestBackend(t, io.Discard)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("hello"))
+		_ = conn.Close()
+	}()
+
+	// 127.0.0.1 is the test host's IP, so it's dialed through SSH
+	conn, err := b.Dial(context.Background(), "tcp", listener.Addr().String())
+	assert.NoError(err)
+	defer conn.Close()
+	received, err := io.ReadAll(conn)
+	assert.NoError(err)
+	assert.Equal("hello", string(received))
+}
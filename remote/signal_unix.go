@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package remote
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Returns the name of [sig] as given to kill, e.g. HUP
+func signalName(sig os.Signal) (string, error) {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return "", fmt.Errorf("unsupported signal %s", sig)
+	}
+	name := unix.SignalName(s)
+	if name == "" {
+		return "", fmt.Errorf("unknown signal %d", s)
+	}
+	return strings.TrimPrefix(name, "SIG"), nil
+}
@@ -0,0 +1,32 @@
+package remote
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Names, as given to kill, of the signals defined on windows
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP:  "HUP",
+	syscall.SIGINT:  "INT",
+	syscall.SIGQUIT: "QUIT",
+	syscall.SIGABRT: "ABRT",
+	syscall.SIGKILL: "KILL",
+	syscall.SIGPIPE: "PIPE",
+	syscall.SIGALRM: "ALRM",
+	syscall.SIGTERM: "TERM",
+}
+
+// Returns the name of [sig] as given to kill, e.g. HUP
+func signalName(sig os.Signal) (string, error) {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return "", fmt.Errorf("unsupported signal %s", sig)
+	}
+	name, ok := signalNames[s]
+	if !ok {
+		return "", fmt.Errorf("unsupported signal %s", sig)
+	}
+	return name, nil
+}
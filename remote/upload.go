@@ -0,0 +1,113 @@
+package remote
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Uploads the files and dirs at [localPaths] on this host to their
+// remote paths on [h], as a tar archive extracted by the host
+func (h *host) upload(localPaths []string) error {
+	if len(localPaths) == 0 {
+		return nil
+	}
+	client, err := h.connect()
+	if err != nil {
+		return err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	session.Stderr = &stderr
+	dir := shellQuote(h.config.Dir)
+	if err := session.Start(fmt.Sprintf("mkdir -p %s && tar -xf - -C %s", dir, dir)); err != nil {
+		return err
+	}
+	writeErr := writeTar(stdin, localPaths)
+	closeErr := stdin.Close()
+	if err := session.Wait(); err != nil {
+		return fmt.Errorf("couldn't extract files on host %s: %w: %s", h.config.Address, err, stderr.String())
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	return closeErr
+}
+
+// Writes a tar archive of the files and dirs at [localPaths] to [w].
+// The names of the entries are the paths, relative to the root dir.
+// Symlinks are followed, so their targets are archived under their paths.
+func writeTar(w io.Writer, localPaths []string) error {
+	tw := tar.NewWriter(w)
+	for _, localPath := range localPaths {
+		if err := writeTarEntry(tw, localPath, map[string]bool{}); err != nil {
+			return fmt.Errorf("couldn't archive %q: %w", localPath, err)
+		}
+	}
+	return tw.Close()
+}
+
+// Writes the file or dir at [path], and everything in it, to [tw].
+// [ancestors] are the resolved paths of the dirs being archived
+// that contain [path], so that symlink loops are caught.
+func writeTarEntry(tw *tar.Writer, path string, ancestors map[string]bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		// Sockets and such aren't uploaded
+		return nil
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = strings.TrimPrefix(filepath.ToSlash(path), "/")
+	if !info.IsDir() {
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	if ancestors[realPath] {
+		return fmt.Errorf("symlink loop at %q", path)
+	}
+	header.Name += "/"
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	ancestors[realPath] = true
+	defer delete(ancestors, realPath)
+	for _, entry := range entries {
+		if err := writeTarEntry(tw, filepath.Join(path, entry.Name()), ancestors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
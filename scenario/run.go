@@ -0,0 +1,403 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/config"
+	"github.com/ava-labs/avalanche-network-runner/local"
+	"github.com/ava-labs/avalanche-network-runner/metrics"
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanche-network-runner/pkg/loadgen"
+	"github.com/ava-labs/avalanche-network-runner/pkg/wallet"
+	"github.com/ava-labs/avalanchego/genesis"
+	"github.com/ava-labs/avalanchego/staking"
+	"github.com/ava-labs/avalanchego/utils/logging"
+)
+
+const (
+	// Max time a step may take if not given
+	DefaultStepTimeout = 5 * time.Minute
+	// Number of nodes of a default network if not given
+	defaultNumNodes = 5
+	// How often the metric of an AssertMetricStep is scraped
+	metricPollFrequency = time.Second
+	// Max time for the network to stop once the scenario ends
+	stopTimeout = 30 * time.Second
+)
+
+var errAssertionFailed = errors.New("assertion failed")
+
+// Config of Run. The zero value is valid.
+type Config struct {
+	// Defaults to logging.NoLog
+	Log logging.Logger
+	// Creates the networks of start steps.
+	// Defaults to local.NewNetwork, in a new temporary dir.
+	NewNetwork func(log logging.Logger, config network.Config) (network.Network, error)
+}
+
+// Report of a scenario run
+type Report struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"duration"`
+	Steps    []StepReport  `json:"steps"`
+}
+
+// StepReport is the outcome of a step
+type StepReport struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	// If true, the step wasn't run since a previous step failed
+	Skipped  bool          `json:"skipped"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	// Why the step failed. Empty if it passed.
+	Error string `json:"error,omitempty"`
+	// What the step observed, e.g. the stats of the txs it issued
+	Details string `json:"details,omitempty"`
+}
+
+func (r Report) String() string {
+	var sb strings.Builder
+	outcome := "PASSED"
+	if !r.Passed {
+		outcome = "FAILED"
+	}
+	fmt.Fprintf(&sb, "scenario %q %s in %s\n", r.Name, outcome, r.Duration.Round(time.Millisecond))
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	for i, step := range r.Steps {
+		status := "ok"
+		switch {
+		case step.Skipped:
+			status = "skipped"
+		case step.Error != "":
+			status = "FAILED: " + step.Error
+		}
+		details := ""
+		if step.Details != "" {
+			details = " (" + step.Details + ")"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s%s\n", i, step.Name, step.Duration.Round(time.Millisecond), status, details)
+	}
+	_ = w.Flush()
+	return sb.String()
+}
+
+// The state of a scenario run
+type run struct {
+	config Config
+	nw     network.Network
+	// Names of the partitioned nodes
+	partitioned map[string]struct{}
+	// Stats of the txs issued by the last IssueTxsStep
+	txStats *loadgen.Stats
+}
+
+// Run runs the steps of [sc] in order, until one fails. The network
+// is stopped once the steps are done, if a step didn't stop it.
+// Returns the report of the run, and an error if a step failed.
+func Run(ctx context.Context, sc Scenario, config Config) (Report, error) {
+	if config.Log == nil {
+		config.Log = logging.NoLog{}
+	}
+	if config.NewNetwork == nil {
+		config.NewNetwork = func(log logging.Logger, networkConfig network.Config) (network.Network, error) {
+			return local.NewNetwork(log, networkConfig, "", "")
+		}
+	}
+	report := Report{Name: sc.Name, Passed: true}
+	if err := sc.Validate(); err != nil {
+		report.Passed = false
+		return report, fmt.Errorf("invalid scenario: %w", err)
+	}
+
+	r := &run{
+		config:      config,
+		partitioned: map[string]struct{}{},
+	}
+	start := time.Now()
+	var runErr error
+	for i := range sc.Steps {
+		step := &sc.Steps[i]
+		action, _ := step.action()
+		stepReport := StepReport{
+			Name:   step.displayName(),
+			Action: action,
+		}
+		if runErr != nil {
+			stepReport.Skipped = true
+			report.Steps = append(report.Steps, stepReport)
+			continue
+		}
+		config.Log.Info("running step %d: %s", i, stepReport.Name)
+		stepReport.Start = time.Now()
+		details, err := r.runStep(ctx, step)
+		stepReport.Duration = time.Since(stepReport.Start)
+		stepReport.Details = details
+		if err != nil {
+			config.Log.Error("step %d (%s) failed: %s", i, stepReport.Name, err)
+			stepReport.Error = err.Error()
+			report.Passed = false
+			runErr = fmt.Errorf("step %d (%s) failed: %w", i, stepReport.Name, err)
+		}
+		report.Steps = append(report.Steps, stepReport)
+	}
+	if r.nw != nil {
+		stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		if err := r.nw.Stop(stopCtx); err != nil {
+			config.Log.Warn("couldn't stop network: %s", err)
+		}
+		cancel()
+	}
+	report.Duration = time.Since(start)
+	return report, runErr
+}
+
+// Runs [step]. Returns what it observed, if anything.
+func (r *run) runStep(ctx context.Context, step *Step) (string, error) {
+	timeout := time.Duration(step.Timeout)
+	if timeout == 0 {
+		timeout = DefaultStepTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch {
+	case step.Start != nil:
+		return "", r.start(step.Start)
+	case step.AwaitHealthy != nil:
+		return "", r.nw.Healthy(ctx)
+	case step.AddNode != nil:
+		return "", r.addNode(step.AddNode)
+	case step.RemoveNode != nil:
+		return "", r.nw.RemoveNode(step.RemoveNode.Name)
+	case step.Partition != nil:
+		return "", r.partition(step.Partition.Nodes)
+	case step.Heal != nil:
+		return "", r.heal(step.Heal.Nodes)
+	case step.IssueTxs != nil:
+		return r.issueTxs(ctx, step.IssueTxs)
+	case step.AssertTxsAccepted != nil:
+		return r.assertTxsAccepted(step.AssertTxsAccepted)
+	case step.AssertMetric != nil:
+		return r.assertMetric(ctx, step.AssertMetric)
+	case step.Sleep != nil:
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(time.Duration(step.Sleep.Duration)):
+			return "", nil
+		}
+	case step.Stop != nil:
+		err := r.nw.Stop(ctx)
+		r.nw = nil
+		r.partitioned = map[string]struct{}{}
+		return "", err
+	default:
+		return "", errors.New("no action given")
+	}
+}
+
+func (r *run) start(step *StartStep) error {
+	var (
+		networkConfig network.Config
+		err           error
+	)
+	if step.ConfigFile != "" {
+		networkConfig, err = config.LoadFile(step.ConfigFile)
+	} else {
+		numNodes := step.NumNodes
+		if numNodes == 0 {
+			numNodes = defaultNumNodes
+		}
+		networkConfig, err = local.NewDefaultConfigNNodes(step.BinaryPath, numNodes)
+	}
+	if err != nil {
+		return err
+	}
+	nw, err := r.config.NewNetwork(r.config.Log, networkConfig)
+	if err != nil {
+		if nw != nil {
+			_ = nw.Stop(context.Background())
+		}
+		return err
+	}
+	r.nw = nw
+	return nil
+}
+
+func (r *run) addNode(step *AddNodeStep) error {
+	binaryPath := step.BinaryPath
+	if binaryPath == "" {
+		nodeName, err := r.firstNodeName()
+		if err != nil {
+			return err
+		}
+		node, err := r.nw.GetNode(nodeName)
+		if err != nil {
+			return err
+		}
+		binaryPath = node.GetBinaryPath()
+	}
+	stakingCert, stakingKey, err := staking.NewCertAndKeyBytes()
+	if err != nil {
+		return fmt.Errorf("couldn't generate staking key: %w", err)
+	}
+	_, err = r.nw.AddNode(node.Config{
+		Name:        step.Name,
+		BinaryPath:  binaryPath,
+		StakingKey:  string(stakingKey),
+		StakingCert: string(stakingCert),
+		Flags:       step.Flags,
+	})
+	return err
+}
+
+func (r *run) partition(nodeNames []string) error {
+	for _, nodeName := range nodeNames {
+		if err := r.nw.PauseNode(nodeName); err != nil {
+			return err
+		}
+		r.partitioned[nodeName] = struct{}{}
+	}
+	return nil
+}
+
+func (r *run) heal(nodeNames []string) error {
+	if len(nodeNames) == 0 {
+		for nodeName := range r.partitioned {
+			nodeNames = append(nodeNames, nodeName)
+		}
+		sort.Strings(nodeNames)
+	}
+	for _, nodeName := range nodeNames {
+		if err := r.nw.ResumeNode(nodeName); err != nil {
+			return err
+		}
+		delete(r.partitioned, nodeName)
+	}
+	return nil
+}
+
+func (r *run) issueTxs(ctx context.Context, step *IssueTxsStep) (string, error) {
+	nodeName := step.Node
+	if nodeName == "" {
+		var err error
+		if nodeName, err = r.firstNodeName(); err != nil {
+			return "", err
+		}
+	}
+	node, err := r.nw.GetNode(nodeName)
+	if err != nil {
+		return "", err
+	}
+	var issuer loadgen.Issuer
+	switch step.Chain {
+	case ChainC:
+		cIssuer, err := loadgen.NewCChainTransferIssuer(ctx, node.GetURI(), genesis.EWOQKey)
+		if err != nil {
+			return "", err
+		}
+		defer cIssuer.Close()
+		issuer = cIssuer
+	case ChainX:
+		w, err := wallet.New(ctx, node.GetURI(), genesis.EWOQKey)
+		if err != nil {
+			return "", err
+		}
+		issuer = loadgen.NewXChainTransferIssuer(w, genesis.EWOQKey.PublicKey().Address())
+	default:
+		return "", fmt.Errorf("unknown chain %q", step.Chain)
+	}
+	stats, err := loadgen.Run(ctx, loadgen.Config{
+		TPS:      step.TPS,
+		Duration: time.Duration(step.Duration),
+	}, issuer)
+	if err != nil {
+		return "", err
+	}
+	r.txStats = &stats
+	return stats.String(), nil
+}
+
+func (r *run) assertTxsAccepted(step *AssertTxsAcceptedStep) (string, error) {
+	stats := r.txStats
+	if stats == nil {
+		return "", errors.New("no txs issued")
+	}
+	details := fmt.Sprintf("%d of %d txs accepted, %d failed", stats.Succeeded, stats.Issued, stats.Failed)
+	minAccepted := step.MinAccepted
+	if minAccepted == 0 {
+		minAccepted = stats.Issued
+	}
+	if stats.Succeeded < minAccepted {
+		return details, fmt.Errorf("%w: %d txs accepted, expected at least %d", errAssertionFailed, stats.Succeeded, minAccepted)
+	}
+	if stats.Failed > step.MaxFailed {
+		return details, fmt.Errorf("%w: %d txs failed, expected at most %d (first error: %s)", errAssertionFailed, stats.Failed, step.MaxFailed, stats.FirstErr)
+	}
+	return details, nil
+}
+
+// Scrapes the metric of [step] until its value is within
+// bounds, or [ctx] is done
+func (r *run) assertMetric(ctx context.Context, step *AssertMetricStep) (string, error) {
+	scraper := metrics.NewScraper(r.config.Log, func() ([]metrics.Target, error) {
+		targets, err := metrics.TargetsFromNetwork(r.nw)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range targets {
+			if target.Node == step.Node {
+				return []metrics.Target{target}, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %q", network.ErrNodeNotFound, step.Node)
+	}, metricPollFrequency)
+
+	// Why the last check failed
+	var lastErr error
+	for {
+		if err := scraper.Scrape(ctx); err != nil {
+			lastErr = err
+		} else if value, ok := scraper.Value(step.Node, step.Metric, step.Labels); !ok {
+			lastErr = fmt.Errorf("%w: node %q has no metric %q", errAssertionFailed, step.Node, step.Metric)
+		} else {
+			details := fmt.Sprintf("%s = %g", step.Metric, value)
+			switch {
+			case step.Min != nil && value < *step.Min:
+				lastErr = fmt.Errorf("%w: %s is below %g", errAssertionFailed, details, *step.Min)
+			case step.Max != nil && value > *step.Max:
+				lastErr = fmt.Errorf("%w: %s is above %g", errAssertionFailed, details, *step.Max)
+			default:
+				return details, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return "", lastErr
+		case <-time.After(metricPollFrequency):
+		}
+	}
+}
+
+// Returns the name of the first node of the network, by name
+func (r *run) firstNodeName() (string, error) {
+	nodeNames, err := r.nw.GetNodeNames()
+	if err != nil {
+		return "", err
+	}
+	if len(nodeNames) == 0 {
+		return "", errors.New("network has no nodes")
+	}
+	sort.Strings(nodeNames)
+	return nodeNames[0], nil
+}
@@ -0,0 +1,354 @@
+// Package scenario runs declarative scenarios against a network, so that
+// e2e and chaos tests can be written as JSON or YAML files of steps
+// rather than in Go. For example:
+//
+//	name: partition-heals
+//	steps:
+//	  - start: {binaryPath: /path/to/avalanchego, numNodes: 5}
+//	  - awaitHealthy: {}
+//	  - partition: {nodes: [node5]}
+//	  - issueTxs: {chain: C, tps: 5, duration: 10s}
+//	  - assertTxsAccepted: {}
+//	  - heal: {}
+//	  - assertMetric: {node: node5, metric: avalanche_network_peers, min: 4}
+//	  - stop: {}
+//
+// Run runs the steps in order, and reports the timing and the outcome
+// of each.
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Chains transactions can be issued on
+const (
+	ChainC = "C"
+	ChainX = "X"
+)
+
+// Scenario is a sequence of steps run against a network
+type Scenario struct {
+	// Identifies the scenario in its report
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+// Step of a scenario. Exactly one action must be given.
+type Step struct {
+	// Identifies the step in the report. Defaults to its action.
+	Name string `json:"name"`
+	// Max time the step may take. Defaults to DefaultStepTimeout.
+	Timeout Duration `json:"timeout"`
+
+	Start             *StartStep             `json:"start"`
+	AwaitHealthy      *AwaitHealthyStep      `json:"awaitHealthy"`
+	AddNode           *AddNodeStep           `json:"addNode"`
+	RemoveNode        *RemoveNodeStep        `json:"removeNode"`
+	Partition         *PartitionStep         `json:"partition"`
+	Heal              *HealStep              `json:"heal"`
+	IssueTxs          *IssueTxsStep          `json:"issueTxs"`
+	AssertTxsAccepted *AssertTxsAcceptedStep `json:"assertTxsAccepted"`
+	AssertMetric      *AssertMetricStep      `json:"assertMetric"`
+	Sleep             *SleepStep             `json:"sleep"`
+	Stop              *StopStep              `json:"stop"`
+}
+
+// StartStep starts a network. Exactly one of [ConfigFile]
+// and [BinaryPath] must be given.
+type StartStep struct {
+	// Path of a network config file. See package config.
+	// Relative to the scenario file's dir.
+	ConfigFile string `json:"configFile"`
+	// Path of the avalanchego binary of a default network
+	BinaryPath string `json:"binaryPath"`
+	// Number of nodes of a default network. Defaults to 5.
+	NumNodes uint32 `json:"numNodes"`
+}
+
+// AwaitHealthyStep waits until all the nodes are healthy
+type AwaitHealthyStep struct{}
+
+// AddNodeStep adds a node with new staking keys
+type AddNodeStep struct {
+	Name string `json:"name"`
+	// Defaults to the binary of a node of the network
+	BinaryPath string                 `json:"binaryPath"`
+	Flags      map[string]interface{} `json:"flags"`
+}
+
+// RemoveNodeStep removes a node
+type RemoveNodeStep struct {
+	Name string `json:"name"`
+}
+
+// PartitionStep cuts nodes off from the rest of the network, until
+// healed. The runner has no network level partitions, so this is done by
+// suspending the nodes' processes (see network.Network.PauseNode), which
+// their peers then see as unreachable.
+type PartitionStep struct {
+	Nodes []string `json:"nodes"`
+}
+
+// HealStep resumes partitioned nodes
+type HealStep struct {
+	// Defaults to all the partitioned nodes
+	Nodes []string `json:"nodes"`
+}
+
+// IssueTxsStep issues transfers on a chain, paid for by genesis.EWOQKey,
+// and waits for them to be accepted. See package loadgen.
+type IssueTxsStep struct {
+	// ChainC or ChainX
+	Chain string `json:"chain"`
+	// Txs issued per second
+	TPS float64 `json:"tps"`
+	// How long txs are issued for
+	Duration Duration `json:"duration"`
+	// Node the txs are issued to. Defaults to the first node by name.
+	Node string `json:"node"`
+}
+
+// AssertTxsAcceptedStep asserts on the txs of the last IssueTxsStep
+type AssertTxsAcceptedStep struct {
+	// Min number of txs accepted. Defaults to all the issued txs.
+	MinAccepted uint64 `json:"minAccepted"`
+	// Max number of txs that failed
+	MaxFailed uint64 `json:"maxFailed"`
+}
+
+// AssertMetricStep waits until the value of a metric of a node is within
+// bounds. The value is the sum of the matching series. At least one of
+// [Min] and [Max] must be given.
+type AssertMetricStep struct {
+	Node   string `json:"node"`
+	Metric string `json:"metric"`
+	// Labels of the series summed. All series if empty.
+	Labels map[string]string `json:"labels"`
+	Min    *float64          `json:"min"`
+	Max    *float64          `json:"max"`
+}
+
+// SleepStep waits
+type SleepStep struct {
+	Duration Duration `json:"duration"`
+}
+
+// StopStep stops the network
+type StopStep struct{}
+
+// Duration is a time.Duration given as a string, e.g. "1m30s",
+// or as a number of nanoseconds
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var ns int64
+		if err := json.Unmarshal(b, &ns); err != nil {
+			return fmt.Errorf("expected duration but got %s", b)
+		}
+		*d = Duration(ns)
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Returns the action of [s], and the number of actions given
+func (s *Step) action() (string, int) {
+	action := ""
+	numActions := 0
+	for name, given := range map[string]bool{
+		"start":             s.Start != nil,
+		"awaitHealthy":      s.AwaitHealthy != nil,
+		"addNode":           s.AddNode != nil,
+		"removeNode":        s.RemoveNode != nil,
+		"partition":         s.Partition != nil,
+		"heal":              s.Heal != nil,
+		"issueTxs":          s.IssueTxs != nil,
+		"assertTxsAccepted": s.AssertTxsAccepted != nil,
+		"assertMetric":      s.AssertMetric != nil,
+		"sleep":             s.Sleep != nil,
+		"stop":              s.Stop != nil,
+	} {
+		if given {
+			action = name
+			numActions++
+		}
+	}
+	return action, numActions
+}
+
+// Returns the name of [s] in the report
+func (s *Step) displayName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	action, _ := s.action()
+	return action
+}
+
+// Validate returns an error if this scenario is invalid, including
+// steps that need a network while none is running
+func (sc *Scenario) Validate() error {
+	if len(sc.Steps) == 0 {
+		return errors.New("no steps given")
+	}
+	started := false
+	issuedTxs := false
+	for i := range sc.Steps {
+		step := &sc.Steps[i]
+		if err := step.validate(started, issuedTxs); err != nil {
+			return fmt.Errorf("steps[%d]: %w", i, err)
+		}
+		switch {
+		case step.Start != nil:
+			started = true
+		case step.Stop != nil:
+			started = false
+		case step.IssueTxs != nil:
+			issuedTxs = true
+		}
+	}
+	return nil
+}
+
+// Returns an error if [s] is invalid. [started] is whether a network is
+// running, and [issuedTxs] whether txs were issued, before [s].
+func (s *Step) validate(started bool, issuedTxs bool) error {
+	action, numActions := s.action()
+	switch {
+	case numActions == 0:
+		return errors.New("no action given")
+	case numActions > 1:
+		return errors.New("more than one action given")
+	case s.Timeout < 0:
+		return errors.New("negative timeout given")
+	case s.Start != nil && started:
+		return errors.New("network already started")
+	case s.Start == nil && s.Sleep == nil && !started:
+		return fmt.Errorf("%s: network not started", action)
+	}
+	switch {
+	case s.Start != nil:
+		if (s.Start.ConfigFile == "") == (s.Start.BinaryPath == "") {
+			return errors.New("start: exactly one of configFile and binaryPath must be given")
+		}
+		if s.Start.ConfigFile != "" && s.Start.NumNodes != 0 {
+			return errors.New("start: numNodes can't be given with configFile")
+		}
+	case s.AddNode != nil:
+		if s.AddNode.Name == "" {
+			return errors.New("addNode: name not given")
+		}
+	case s.RemoveNode != nil:
+		if s.RemoveNode.Name == "" {
+			return errors.New("removeNode: name not given")
+		}
+	case s.Partition != nil:
+		if len(s.Partition.Nodes) == 0 {
+			return errors.New("partition: no nodes given")
+		}
+	case s.IssueTxs != nil:
+		if s.IssueTxs.Chain != ChainC && s.IssueTxs.Chain != ChainX {
+			return fmt.Errorf("issueTxs: chain must be %q or %q but got %q", ChainC, ChainX, s.IssueTxs.Chain)
+		}
+		if s.IssueTxs.TPS <= 0 {
+			return errors.New("issueTxs: tps must be positive")
+		}
+		if s.IssueTxs.Duration <= 0 {
+			return errors.New("issueTxs: duration must be positive")
+		}
+	case s.AssertTxsAccepted != nil:
+		if !issuedTxs {
+			return errors.New("assertTxsAccepted: no txs issued before")
+		}
+	case s.AssertMetric != nil:
+		if s.AssertMetric.Node == "" || s.AssertMetric.Metric == "" {
+			return errors.New("assertMetric: node and metric must be given")
+		}
+		if s.AssertMetric.Min == nil && s.AssertMetric.Max == nil {
+			return errors.New("assertMetric: neither min nor max given")
+		}
+	case s.Sleep != nil:
+		if s.Sleep.Duration <= 0 {
+			return errors.New("sleep: duration must be positive")
+		}
+	}
+	return nil
+}
+
+// LoadFile loads and validates the scenario at [path].
+// See config.FormatFromPath for the formats supported.
+func LoadFile(path string) (Scenario, error) {
+	format, err := config.FormatFromPath(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("couldn't read scenario file: %w", err)
+	}
+	return Load(data, format, filepath.Dir(path))
+}
+
+// Load loads and validates the scenario in [data].
+// Relative config file paths are resolved against [baseDir].
+func Load(data []byte, format config.Format, baseDir string) (Scenario, error) {
+	var raw interface{}
+	switch format {
+	case config.FormatJSON:
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return Scenario{}, fmt.Errorf("couldn't parse JSON scenario: %w", err)
+		}
+	case config.FormatYAML:
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return Scenario{}, fmt.Errorf("couldn't parse YAML scenario: %w", err)
+		}
+	default:
+		return Scenario{}, fmt.Errorf("unknown scenario format %q", format)
+	}
+
+	// Decoded as JSON, so that both formats use the JSON field names
+	rawBytes, err := json.Marshal(raw)
+	if err != nil {
+		return Scenario{}, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(rawBytes))
+	decoder.DisallowUnknownFields()
+	var sc Scenario
+	if err := decoder.Decode(&sc); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return Scenario{}, fmt.Errorf("%s: expected %s but got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return Scenario{}, fmt.Errorf("couldn't decode scenario: %w", err)
+	}
+	for _, step := range sc.Steps {
+		if step.Start != nil && step.Start.ConfigFile != "" && !filepath.IsAbs(step.Start.ConfigFile) {
+			step.Start.ConfigFile = filepath.Join(baseDir, step.Start.ConfigFile)
+		}
+	}
+	if err := sc.Validate(); err != nil {
+		return Scenario{}, fmt.Errorf("invalid scenario: %w", err)
+	}
+	return sc, nil
+}
@@ -0,0 +1,267 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/config"
+	"github.com/ava-labs/avalanche-network-runner/metrics"
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanchego/utils/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// A node whose metrics are served by a test server
+type fakeNode struct {
+	node.Node
+	name    string
+	apiPort uint16
+}
+
+func (n *fakeNode) GetName() string       { return n.name }
+func (n *fakeNode) GetURL() string        { return "127.0.0.1" }
+func (n *fakeNode) GetAPIPort() uint16    { return n.apiPort }
+func (n *fakeNode) GetBinaryPath() string { return "/bin/avalanchego" }
+
+// A network that records the operations run against it
+type fakeNetwork struct {
+	network.Network
+	lock    sync.Mutex
+	ops     []string
+	nodes   map[string]*fakeNode
+	stopped bool
+}
+
+func (n *fakeNetwork) record(op string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.ops = append(n.ops, op)
+}
+
+func (n *fakeNetwork) Healthy(context.Context) error {
+	n.record("healthy")
+	return nil
+}
+
+func (n *fakeNetwork) AddNode(nodeConfig node.Config) (node.Node, error) {
+	if nodeConfig.StakingKey == "" || nodeConfig.BinaryPath == "" {
+		return nil, errors.New("missing staking key or binary path")
+	}
+	n.record("add " + nodeConfig.Name)
+	n.nodes[nodeConfig.Name] = &fakeNode{name: nodeConfig.Name}
+	return n.nodes[nodeConfig.Name], nil
+}
+
+func (n *fakeNetwork) RemoveNode(name string) error {
+	if _, ok := n.nodes[name]; !ok {
+		return fmt.Errorf("%w: %q", network.ErrNodeNotFound, name)
+	}
+	n.record("remove " + name)
+	delete(n.nodes, name)
+	return nil
+}
+
+func (n *fakeNetwork) PauseNode(name string) error {
+	n.record("pause " + name)
+	return nil
+}
+
+func (n *fakeNetwork) ResumeNode(name string) error {
+	n.record("resume " + name)
+	return nil
+}
+
+func (n *fakeNetwork) GetNode(name string) (node.Node, error) {
+	node, ok := n.nodes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", network.ErrNodeNotFound, name)
+	}
+	return node, nil
+}
+
+func (n *fakeNetwork) GetNodeNames() ([]string, error) {
+	names := make([]string, 0, len(n.nodes))
+	for name := range n.nodes {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (n *fakeNetwork) GetAllNodes() (map[string]node.Node, error) {
+	nodes := make(map[string]node.Node, len(n.nodes))
+	for name, node := range n.nodes {
+		nodes[name] = node
+	}
+	return nodes, nil
+}
+
+func (n *fakeNetwork) Stop(context.Context) error {
+	n.record("stop")
+	n.stopped = true
+	return nil
+}
+
+// Returns a network of one node, node1, whose metric
+// avalanche_network_peers is [peers]
+func newFakeNetwork(t *testing.T, peers int) *fakeNetwork {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != metrics.MetricsPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, "# TYPE avalanche_network_peers gauge\navalanche_network_peers %d\n", peers)
+	}))
+	t.Cleanup(server.Close)
+	_, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.NoError(t, err)
+	port, err := strconv.Atoi(portStr)
+	assert.NoError(t, err)
+	return &fakeNetwork{
+		nodes: map[string]*fakeNode{
+			"node1": {name: "node1", apiPort: uint16(port)},
+		},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	scenarioPath := filepath.Join(dir, "scenario.yaml")
+	assert.NoError(os.WriteFile(scenarioPath, []byte(`
+name: test
+steps:
+  - start: {configFile: network.json}
+  - name: wait
+    timeout: 1m
+    awaitHealthy: {}
+  - partition: {nodes: [node1]}
+  - sleep: {duration: 1500ms}
+  - heal: {}
+  - assertMetric: {node: node1, metric: avalanche_network_peers, min: 4, labels: {a: b}}
+  - stop: {}
+`), 0o600))
+	sc, err := LoadFile(scenarioPath)
+	assert.NoError(err)
+	assert.Equal("test", sc.Name)
+	assert.Len(sc.Steps, 7)
+	assert.Equal(filepath.Join(dir, "network.json"), sc.Steps[0].Start.ConfigFile)
+	assert.Equal("wait", sc.Steps[1].displayName())
+	assert.Equal(Duration(time.Minute), sc.Steps[1].Timeout)
+	assert.Equal([]string{"node1"}, sc.Steps[2].Partition.Nodes)
+	assert.Equal(Duration(1500*time.Millisecond), sc.Steps[3].Sleep.Duration)
+	assert.Equal("heal", sc.Steps[4].displayName())
+	assert.Equal(4.0, *sc.Steps[5].AssertMetric.Min)
+	assert.Nil(sc.Steps[5].AssertMetric.Max)
+	assert.Equal(map[string]string{"a": "b"}, sc.Steps[5].AssertMetric.Labels)
+
+	// JSON uses the same field names
+	_, err = Load([]byte(`{"steps": [{"start": {"binaryPath": "avalanchego"}}, {"stop": {}}]}`), config.FormatJSON, dir)
+	assert.NoError(err)
+
+	for name, contents := range map[string]string{
+		"unknown field":               "steps: [{start: {binaryPath: a, foo: 1}}]",
+		"no steps":                    "name: test",
+		"no action":                   "steps: [{name: a}]",
+		"two actions":                 "steps: [{start: {binaryPath: a}, stop: {}}]",
+		"not started":                 "steps: [{awaitHealthy: {}}]",
+		"started twice":               "steps: [{start: {binaryPath: a}}, {start: {binaryPath: a}}]",
+		"stopped":                     "steps: [{start: {binaryPath: a}}, {stop: {}}, {removeNode: {name: node1}}]",
+		"bad chain":                   "steps: [{start: {binaryPath: a}}, {issueTxs: {chain: P, tps: 1, duration: 1s}}]",
+		"no txs issued":               "steps: [{start: {binaryPath: a}}, {assertTxsAccepted: {}}]",
+		"no metric bound":             "steps: [{start: {binaryPath: a}}, {assertMetric: {node: node1, metric: m}}]",
+		"bad duration":                "steps: [{sleep: {duration: soon}}]",
+		"config file and binary path": "steps: [{start: {binaryPath: a, configFile: b}}]",
+	} {
+		_, err := Load([]byte(contents), config.FormatYAML, dir)
+		assert.Error(err, name)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	nw := newFakeNetwork(t, 4)
+	var networkConfig network.Config
+	runConfig := Config{
+		Log: logging.NoLog{},
+		NewNetwork: func(_ logging.Logger, config network.Config) (network.Network, error) {
+			networkConfig = config
+			return nw, nil
+		},
+	}
+	min := 4.0
+	sc := Scenario{
+		Name: "test",
+		Steps: []Step{
+			{Start: &StartStep{BinaryPath: "/bin/avalanchego", NumNodes: 2}},
+			{AwaitHealthy: &AwaitHealthyStep{}},
+			{AddNode: &AddNodeStep{Name: "node2"}},
+			{Partition: &PartitionStep{Nodes: []string{"node1", "node2"}}},
+			{Heal: &HealStep{}},
+			{RemoveNode: &RemoveNodeStep{Name: "node2"}},
+			{AssertMetric: &AssertMetricStep{Node: "node1", Metric: "avalanche_network_peers", Min: &min}},
+			{Stop: &StopStep{}},
+		},
+	}
+	report, err := Run(context.Background(), sc, runConfig)
+	assert.NoError(err)
+	assert.True(report.Passed)
+	assert.Len(networkConfig.NodeConfigs, 2)
+	assert.Equal([]string{
+		"healthy",
+		"add node2",
+		"pause node1",
+		"pause node2",
+		"resume node1",
+		"resume node2",
+		"remove node2",
+		"stop",
+	}, nw.ops)
+	assert.Len(report.Steps, len(sc.Steps))
+	assert.Equal("assertMetric", report.Steps[6].Name)
+	assert.Equal("avalanche_network_peers = 4", report.Steps[6].Details)
+	assert.Contains(report.String(), `scenario "test" PASSED`)
+}
+
+func TestRunFailedStep(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	nw := newFakeNetwork(t, 2)
+	runConfig := Config{
+		NewNetwork: func(logging.Logger, network.Config) (network.Network, error) {
+			return nw, nil
+		},
+	}
+	min := 4.0
+	sc := Scenario{
+		Name: "test",
+		Steps: []Step{
+			{Start: &StartStep{BinaryPath: "/bin/avalanchego"}},
+			{
+				Name:         "enough peers",
+				Timeout:      Duration(100 * time.Millisecond),
+				AssertMetric: &AssertMetricStep{Node: "node1", Metric: "avalanche_network_peers", Min: &min},
+			},
+			{RemoveNode: &RemoveNodeStep{Name: "node1"}},
+		},
+	}
+	report, err := Run(context.Background(), sc, runConfig)
+	assert.ErrorIs(err, errAssertionFailed)
+	assert.False(report.Passed)
+	assert.Contains(report.Steps[1].Error, "avalanche_network_peers = 2 is below 4")
+	assert.True(report.Steps[2].Skipped)
+	// The network is stopped once the scenario ends
+	assert.Equal([]string{"stop"}, nw.ops)
+	assert.Contains(report.String(), "FAILED")
+}
@@ -85,7 +85,7 @@ func (lc *localNetwork) waitForCustomVMsReady(ctx context.Context) error {
 	println()
 	color.Outf("{{blue}}{{bold}}waiting for custom VMs to report healthy...{{/}}\n")
 
-	if err := lc.nw.Healthy(ctx); err != nil {
+	if err := lc.getNetwork().Healthy(ctx); err != nil {
 		return err
 	}
 
@@ -324,11 +324,11 @@ func (lc *localNetwork) restartNodesWithWhitelistedSubnets(ctx context.Context)
 
 		lc.customVMRestartMu.Lock()
 		zap.L().Info("removing and adding back the node for whitelisted subnets", zap.String("node-name", nodeName))
-		if err := lc.nw.RemoveNode(nodeName); err != nil {
+		if err := lc.getNetwork().RemoveNode(nodeName); err != nil {
 			lc.customVMRestartMu.Unlock()
 			return err
 		}
-		if _, err := lc.nw.AddNode(nodeConfig); err != nil {
+		if _, err := lc.getNetwork().AddNode(nodeConfig); err != nil {
 			lc.customVMRestartMu.Unlock()
 			return err
 		}
@@ -0,0 +1,257 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	DefaultResumeTimeout = 2 * time.Minute
+
+	minIdleCheckFreq = time.Second
+	// Requests to this service don't count as activity,
+	// so that pinging the server doesn't keep a network awake
+	pingServicePrefix = "/rpcpb.PingService/"
+	// Header set to "true" on the responses to passive methods
+	// (see [passiveMethods]) while the network is suspended
+	SuspendedHeader = "network-suspended"
+)
+
+// Methods that don't resume a suspended network: Stop tears it down
+// as it is, even if it couldn't be resumed (e.g. a node died while it
+// was paused), and the read-only methods report it as suspended.
+// They still count as activity.
+var passiveMethods = map[string]bool{
+	"/rpcpb.ControlService/Stop":   true,
+	"/rpcpb.ControlService/Status": true,
+	"/rpcpb.ControlService/Health": true,
+	"/rpcpb.ControlService/URIs":   true,
+}
+
+// idleSuspender pauses the nodes of a network when the server gets
+// no requests for a while, and resumes them on the next request.
+// Only requests to the server count: requests sent straight to the
+// nodes' APIs, e.g. by tests given the nodes' URIs, don't keep the
+// network awake, so this is off unless an idle timeout is given.
+type idleSuspender struct {
+	// How long the server must be idle for the nodes to be paused
+	timeout time.Duration
+	// Max time to resume the nodes and for them to become healthy
+	resumeTimeout time.Duration
+	// Returns the current network, or nil if there's none
+	getNetwork func() network.Network
+
+	lock         sync.Mutex
+	lastActivity time.Time
+	suspended    bool
+	// Closed when the ongoing pause, if any, is done
+	pausing chan struct{}
+	// Closed when the ongoing resume, if any, is done
+	resuming chan struct{}
+	// Number of streams open, during which the server isn't idle
+	activeStreams int
+}
+
+func newIdleSuspender(timeout time.Duration, resumeTimeout time.Duration, getNetwork func() network.Network) *idleSuspender {
+	if resumeTimeout == 0 {
+		resumeTimeout = DefaultResumeTimeout
+	}
+	return &idleSuspender{
+		timeout:       timeout,
+		resumeTimeout: resumeTimeout,
+		getNetwork:    getNetwork,
+		lastActivity:  time.Now(),
+	}
+}
+
+// Records activity, resuming the network's nodes if they were paused.
+// Blocks until they're healthy again. Only one resume runs at a time,
+// without holding [is.lock]; concurrent callers wait for it, and retry
+// it if it failed. A resume waits for the ongoing pause, if any.
+func (is *idleSuspender) touch(ctx context.Context) error {
+	for {
+		is.lock.Lock()
+		is.lastActivity = time.Now()
+		if !is.suspended {
+			is.lock.Unlock()
+			return nil
+		}
+		if ongoing := is.ongoing(); ongoing != nil {
+			is.lock.Unlock()
+			select {
+			case <-ongoing:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		nw := is.getNetwork()
+		if nw == nil {
+			is.suspended = false
+			is.lock.Unlock()
+			return nil
+		}
+		resuming := make(chan struct{})
+		is.resuming = resuming
+		is.lock.Unlock()
+
+		err := is.resume(ctx, nw)
+
+		is.lock.Lock()
+		// Stays suspended on error, so that the next request retries
+		if err == nil {
+			is.suspended = false
+		}
+		is.resuming = nil
+		is.lock.Unlock()
+		close(resuming)
+		return err
+	}
+}
+
+// Returns the channel closed when the ongoing pause or resume is done,
+// or nil if there's none. Assumes [is.lock] is held.
+func (is *idleSuspender) ongoing() chan struct{} {
+	if is.pausing != nil {
+		return is.pausing
+	}
+	return is.resuming
+}
+
+func (is *idleSuspender) resume(ctx context.Context, nw network.Network) error {
+	zap.L().Info("resuming idle network")
+	ctx, cancel := context.WithTimeout(ctx, is.resumeTimeout)
+	defer cancel()
+	return nw.Resume(ctx)
+}
+
+// Pauses the network's nodes whenever the server has been
+// idle for [is.timeout], until [ctx] is done
+func (is *idleSuspender) run(ctx context.Context) {
+	checkFreq := is.timeout / 10
+	if checkFreq < minIdleCheckFreq {
+		checkFreq = minIdleCheckFreq
+	}
+	ticker := time.NewTicker(checkFreq)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		is.suspendIfIdle()
+	}
+}
+
+// Pauses the network's nodes if the server is idle. The pause runs
+// without holding [is.lock], so that requests aren't blocked by it;
+// they wait for it to be done before resuming the nodes.
+func (is *idleSuspender) suspendIfIdle() {
+	is.lock.Lock()
+	if is.suspended || is.activeStreams > 0 || time.Since(is.lastActivity) < is.timeout {
+		is.lock.Unlock()
+		return
+	}
+	nw := is.getNetwork()
+	if nw == nil || !nw.Capabilities().Pause {
+		is.lock.Unlock()
+		return
+	}
+	zap.L().Info("pausing idle network", zap.Duration("idle-for", time.Since(is.lastActivity)))
+	is.suspended = true
+	pausing := make(chan struct{})
+	is.pausing = pausing
+	is.lock.Unlock()
+
+	if err := nw.Pause(context.Background()); err != nil {
+		// Nodes paused before the error are resumed on the next request
+		zap.L().Warn("couldn't pause idle network", zap.Error(err))
+	}
+
+	is.lock.Lock()
+	is.pausing = nil
+	is.lock.Unlock()
+	close(pausing)
+}
+
+// Records activity without resuming the network's nodes.
+// Returns true if they're suspended.
+func (is *idleSuspender) record() bool {
+	is.lock.Lock()
+	defer is.lock.Unlock()
+	is.lastActivity = time.Now()
+	return is.suspended
+}
+
+// Returns true if the network's nodes are suspended
+func (is *idleSuspender) isSuspended() bool {
+	is.lock.Lock()
+	defer is.lock.Unlock()
+	return is.suspended
+}
+
+// Forgets that the network was suspended, once it's stopped, so
+// that the next network isn't resumed on the next request
+func (is *idleSuspender) reset() {
+	is.lock.Lock()
+	defer is.lock.Unlock()
+	is.suspended = false
+	is.lastActivity = time.Now()
+}
+
+func (is *idleSuspender) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(info.FullMethod, pingServicePrefix):
+	case passiveMethods[info.FullMethod]:
+		if is.record() {
+			// Fails if [ctx] isn't a gRPC server's, e.g. in tests
+			_ = grpc.SetHeader(ctx, metadata.Pairs(SuspendedHeader, "true"))
+		}
+	default:
+		if err := is.touch(ctx); err != nil {
+			return nil, fmt.Errorf("couldn't resume idle network: %w", err)
+		}
+	}
+	return handler(ctx, req)
+}
+
+func (is *idleSuspender) streamInterceptor(
+	srv interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if strings.HasPrefix(info.FullMethod, pingServicePrefix) {
+		return handler(srv, stream)
+	}
+	if err := is.touch(stream.Context()); err != nil {
+		return fmt.Errorf("couldn't resume idle network: %w", err)
+	}
+	is.lock.Lock()
+	is.activeStreams++
+	is.lock.Unlock()
+	defer func() {
+		is.lock.Lock()
+		is.activeStreams--
+		is.lastActivity = time.Now()
+		is.lock.Unlock()
+	}()
+	return handler(srv, stream)
+}
@@ -0,0 +1,243 @@
+// Copyright (C) 2019-2022, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanche-network-runner/network"
+	"github.com/ava-labs/avalanche-network-runner/rpcpb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+type pausableNetwork struct {
+	network.Network
+	paused bool
+}
+
+func (*pausableNetwork) Capabilities() network.Capabilities {
+	return network.Capabilities{Pause: true}
+}
+
+func (n *pausableNetwork) Pause(context.Context) error {
+	n.paused = true
+	return nil
+}
+
+func (n *pausableNetwork) Resume(context.Context) error {
+	n.paused = false
+	return nil
+}
+
+// Blocks in Resume until [release] is closed, failing the first time
+type slowResumeNetwork struct {
+	pausableNetwork
+	release chan struct{}
+
+	lock    sync.Mutex
+	resumes int
+}
+
+func (n *slowResumeNetwork) Resume(context.Context) error {
+	<-n.release
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.resumes++
+	if n.resumes == 1 {
+		return errors.New("resume failed")
+	}
+	return nil
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+}
+
+func (*testServerStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestIdleSuspender(t *testing.T) {
+	assert := assert.New(t)
+	nw := &pausableNetwork{}
+	is := newIdleSuspender(time.Millisecond, 0, func() network.Network { return nw })
+
+	// Not idle while a stream is open, however long it is
+	streamInfo := &grpc.StreamServerInfo{FullMethod: "/rpcpb.ControlService/StreamStatus"}
+	assert.NoError(is.streamInterceptor(nil, &testServerStream{}, streamInfo, func(interface{}, grpc.ServerStream) error {
+		time.Sleep(10 * time.Millisecond)
+		is.suspendIfIdle()
+		assert.False(nw.paused)
+		return nil
+	}))
+	time.Sleep(10 * time.Millisecond)
+	is.suspendIfIdle()
+	assert.True(nw.paused)
+
+	// Resumed on the next request
+	unaryInfo := &grpc.UnaryServerInfo{FullMethod: "/rpcpb.ControlService/AddNode"}
+	_, err := is.unaryInterceptor(context.Background(), nil, unaryInfo, func(context.Context, interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.NoError(err)
+	assert.False(nw.paused)
+}
+
+func TestIdleSuspenderResume(t *testing.T) {
+	assert := assert.New(t)
+	nw := &slowResumeNetwork{release: make(chan struct{})}
+	is := newIdleSuspender(time.Millisecond, 0, func() network.Network { return nw })
+	time.Sleep(10 * time.Millisecond)
+	is.suspendIfIdle()
+	assert.True(nw.paused)
+
+	errCh := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { errCh <- is.touch(context.Background()) }()
+	}
+	// The lock isn't held while resuming
+	time.Sleep(10 * time.Millisecond)
+	is.lock.Lock()
+	assert.True(is.suspended)
+	assert.NotNil(is.resuming)
+	is.lock.Unlock()
+
+	// The first resume fails, leaving the network suspended,
+	// and the waiting caller retries it
+	close(nw.release)
+	err1, err2 := <-errCh, <-errCh
+	assert.True((err1 == nil) != (err2 == nil))
+	assert.Equal(2, nw.resumes)
+	is.lock.Lock()
+	assert.False(is.suspended)
+	assert.Nil(is.resuming)
+	is.lock.Unlock()
+}
+
+// Blocks in Pause until [release] is closed
+type slowPauseNetwork struct {
+	pausableNetwork
+	pausing chan struct{}
+	release chan struct{}
+}
+
+func (n *slowPauseNetwork) Pause(ctx context.Context) error {
+	close(n.pausing)
+	<-n.release
+	return n.pausableNetwork.Pause(ctx)
+}
+
+func TestIdleSuspenderPause(t *testing.T) {
+	assert := assert.New(t)
+	nw := &slowPauseNetwork{pausing: make(chan struct{}), release: make(chan struct{})}
+	is := newIdleSuspender(time.Millisecond, 0, func() network.Network { return nw })
+	time.Sleep(10 * time.Millisecond)
+	suspended := make(chan struct{})
+	go func() {
+		is.suspendIfIdle()
+		close(suspended)
+	}()
+	<-nw.pausing
+
+	// The lock isn't held while pausing, and
+	// requests wait for the pause to resume the nodes
+	touched := make(chan error)
+	go func() { touched <- is.touch(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	is.lock.Lock()
+	assert.True(is.suspended)
+	assert.NotNil(is.pausing)
+	is.lock.Unlock()
+	select {
+	case <-touched:
+		assert.Fail("resumed while pausing")
+	default:
+	}
+
+	close(nw.release)
+	<-suspended
+	assert.NoError(<-touched)
+	assert.False(nw.paused)
+	is.lock.Lock()
+	assert.False(is.suspended)
+	assert.Nil(is.pausing)
+	is.lock.Unlock()
+}
+
+// Fails to resume, e.g. because a node died while it was paused
+type failResumeNetwork struct {
+	pausableNetwork
+	resumes int
+	stopped bool
+}
+
+func (n *failResumeNetwork) Resume(context.Context) error {
+	n.resumes++
+	return errors.New("node died")
+}
+
+func (n *failResumeNetwork) Stop(context.Context) error {
+	n.stopped = true
+	return nil
+}
+
+// TestIdleSuspenderStop tests that a suspended network that can't be
+// resumed can still be stopped, and that its status is reported
+// without resuming it
+func TestIdleSuspenderStop(t *testing.T) {
+	assert := assert.New(t)
+	nw := &failResumeNetwork{}
+	startDoneCh := make(chan struct{})
+	close(startDoneCh)
+	lc := &localNetwork{stopCh: make(chan struct{}), startDoneCh: startDoneCh}
+	lc.setNetwork(nw)
+	s := &server{
+		mu:          new(sync.RWMutex),
+		network:     lc,
+		clusterInfo: &rpcpb.ClusterInfo{Healthy: true},
+	}
+	s.idle = newIdleSuspender(time.Millisecond, 0, s.getNetwork)
+	time.Sleep(10 * time.Millisecond)
+	s.idle.suspendIfIdle()
+	assert.True(nw.paused)
+
+	call := func(method string, handler grpc.UnaryHandler) (interface{}, error) {
+		return s.idle.unaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+	// Other methods resume the network first
+	_, err := call("/rpcpb.ControlService/AddNode", func(context.Context, interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.Error(err)
+	assert.Equal(1, nw.resumes)
+
+	// The status is reported as unhealthy, without resuming the network
+	resp, err := call("/rpcpb.ControlService/Status", func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return s.Status(ctx, &rpcpb.StatusRequest{})
+	})
+	assert.NoError(err)
+	assert.False(resp.(*rpcpb.StatusResponse).ClusterInfo.Healthy)
+	assert.True(s.clusterInfo.Healthy)
+	resp, err = call("/rpcpb.ControlService/Health", func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return s.Health(ctx, &rpcpb.HealthRequest{})
+	})
+	assert.NoError(err)
+	assert.False(resp.(*rpcpb.HealthResponse).ClusterInfo.Healthy)
+	assert.Equal(1, nw.resumes)
+
+	// The network is stopped as it is
+	_, err = call("/rpcpb.ControlService/Stop", func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return s.Stop(ctx, &rpcpb.StopRequest{})
+	})
+	assert.NoError(err)
+	assert.True(nw.stopped)
+	assert.Equal(1, nw.resumes)
+	assert.Nil(s.network)
+	assert.False(s.idle.isSuspended())
+}
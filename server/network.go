@@ -15,6 +15,7 @@ import (
 	"github.com/ava-labs/avalanche-network-runner/local"
 	"github.com/ava-labs/avalanche-network-runner/network"
 	"github.com/ava-labs/avalanche-network-runner/pkg/color"
+	"github.com/ava-labs/avalanche-network-runner/pkg/observability"
 	"github.com/ava-labs/avalanche-network-runner/rpcpb"
 	"github.com/ava-labs/avalanchego/config"
 	"github.com/ava-labs/avalanchego/ids"
@@ -47,7 +48,11 @@ type localNetwork struct {
 	binPath string
 	cfg     network.Config
 
-	nw network.Network
+	// Set once the network is created, concurrently with readers
+	// such as the idle suspender, so guarded by [nwLock].
+	// Only accessed through setNetwork and getNetwork.
+	nwLock sync.RWMutex
+	nw     network.Network
 
 	nodeNames []string
 	nodeInfos map[string]*rpcpb.NodeInfo
@@ -94,6 +99,9 @@ type localNetworkOptions struct {
 	pluginDir         string
 	customVMs         map[string][]byte
 	customNodeConfigs map[string]string
+	// If non-nil, the network's nodes are scraped by
+	// the observability processes it configures
+	observability *observability.Config
 
 	// to block racey restart while installing custom VMs
 	restartMu *sync.RWMutex
@@ -189,6 +197,7 @@ func (lc *localNetwork) createConfig() error {
 		cfg.NodeConfigs[i].RedirectStderr = lc.options.redirectNodesOutput
 	}
 
+	cfg.Observability = lc.options.observability
 	lc.cfg = cfg
 	return nil
 }
@@ -270,7 +279,7 @@ func (lc *localNetwork) start(argCtx context.Context) {
 		lc.startErrCh <- err
 		return
 	}
-	lc.nw = nw
+	lc.setNetwork(nw)
 
 	if err := lc.waitForLocalClusterReady(ctx); err != nil {
 		lc.startErrCh <- err
@@ -291,6 +300,19 @@ func (lc *localNetwork) start(argCtx context.Context) {
 	}
 }
 
+func (lc *localNetwork) setNetwork(nw network.Network) {
+	lc.nwLock.Lock()
+	defer lc.nwLock.Unlock()
+	lc.nw = nw
+}
+
+// Returns the network, or nil if it isn't created yet
+func (lc *localNetwork) getNetwork() network.Network {
+	lc.nwLock.RLock()
+	defer lc.nwLock.RUnlock()
+	return lc.nw
+}
+
 func (lc *localNetwork) loadSnapshot(ctx context.Context, snapshotName string) error {
 	defer func() {
 		close(lc.startDoneCh)
@@ -300,7 +322,7 @@ func (lc *localNetwork) loadSnapshot(ctx context.Context, snapshotName string) e
 	if err != nil {
 		return err
 	}
-	lc.nw = nw
+	lc.setNetwork(nw)
 	return nil
 }
 
@@ -323,7 +345,7 @@ func (lc *localNetwork) loadSnapshotWait(ctx context.Context, loadSnapshotReadyC
 }
 
 func (lc *localNetwork) updateSubnetInfo(ctx context.Context) error {
-	node, err := lc.nw.GetNode(lc.nodeNames[0])
+	node, err := lc.getNetwork().GetNode(lc.nodeNames[0])
 	if err != nil {
 		return err
 	}
@@ -353,7 +375,7 @@ var errAborted = errors.New("aborted")
 func (lc *localNetwork) waitForLocalClusterReady(ctx context.Context) error {
 	color.Outf("{{blue}}{{bold}}waiting for all nodes to report healthy...{{/}}\n")
 
-	if err := lc.nw.Healthy(ctx); err != nil {
+	if err := lc.getNetwork().Healthy(ctx); err != nil {
 		return err
 	}
 
@@ -372,7 +394,7 @@ func (lc *localNetwork) waitForLocalClusterReady(ctx context.Context) error {
 }
 
 func (lc *localNetwork) updateNodeInfo() error {
-	nodes, err := lc.nw.GetAllNodes()
+	nodes, err := lc.getNetwork().GetAllNodes()
 	if err != nil {
 		return err
 	}
@@ -413,7 +435,7 @@ func (lc *localNetwork) updateNodeInfo() error {
 
 		lc.nodeInfos[name] = &rpcpb.NodeInfo{
 			Name:               node.GetName(),
-			Uri:                fmt.Sprintf("http://%s:%d", node.GetURL(), node.GetAPIPort()),
+			Uri:                node.GetURI(),
 			Id:                 node.GetNodeID().String(),
 			ExecPath:           node.GetBinaryPath(),
 			LogDir:             node.GetLogsDir(),
@@ -433,7 +455,7 @@ func (lc *localNetwork) stop(ctx context.Context) {
 		if lc.startCtxCancel != nil {
 			lc.startCtxCancel()
 		}
-		serr := lc.nw.Stop(ctx)
+		serr := lc.getNetwork().Stop(ctx)
 		<-lc.startDoneCh
 		color.Outf("{{red}}{{bold}}terminated network{{/}} (error %v)\n", serr)
 	})
@@ -20,7 +20,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ava-labs/avalanche-network-runner/network"
 	"github.com/ava-labs/avalanche-network-runner/network/node"
+	"github.com/ava-labs/avalanche-network-runner/pkg/observability"
 	"github.com/ava-labs/avalanche-network-runner/rpcpb"
 	"github.com/ava-labs/avalanche-network-runner/utils"
 	"github.com/ava-labs/avalanchego/message"
@@ -33,6 +35,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type Config struct {
@@ -43,6 +46,18 @@ type Config struct {
 	DialTimeout         time.Duration
 	RedirectNodesOutput bool
 	SnapshotsDir        string
+	// If non-zero, the network's nodes are paused when the server
+	// gets no requests, and has no streams open, for this long, and
+	// resumed on the next request. Requests sent straight to the nodes'
+	// APIs don't count, so this must only be set if the network is only
+	// used through the server.
+	IdleSuspendTimeout time.Duration
+	// Max time for paused nodes to become healthy again when resumed.
+	// Defaults to DefaultResumeTimeout.
+	ResumeTimeout time.Duration
+	// If non-nil, Prometheus, and optionally Grafana, are
+	// started with the networks to scrape their nodes
+	Observability *observability.Config
 }
 
 type Server interface {
@@ -67,6 +82,10 @@ type server struct {
 	clusterInfo *rpcpb.ClusterInfo
 	network     *localNetwork
 
+	// Pauses the network when the server is idle.
+	// Nil if disabled.
+	idle *idleSuspender
+
 	rpcpb.UnimplementedPingServiceServer
 	rpcpb.UnimplementedControlServiceServer
 }
@@ -106,11 +125,19 @@ func New(cfg Config) (Server, error) {
 
 		closed: make(chan struct{}),
 
-		ln:         ln,
-		gRPCServer: grpc.NewServer(),
+		ln: ln,
 
 		mu: new(sync.RWMutex),
 	}
+	var opts []grpc.ServerOption
+	if cfg.IdleSuspendTimeout > 0 {
+		srv.idle = newIdleSuspender(cfg.IdleSuspendTimeout, cfg.ResumeTimeout, srv.getNetwork)
+		opts = append(opts,
+			grpc.UnaryInterceptor(srv.idle.unaryInterceptor),
+			grpc.StreamInterceptor(srv.idle.streamInterceptor),
+		)
+	}
+	srv.gRPCServer = grpc.NewServer(opts...)
 	if !cfg.GwDisabled {
 		srv.gwMux = runtime.NewServeMux()
 		srv.gwServer = &http.Server{
@@ -130,6 +157,10 @@ func (s *server) Run(rootCtx context.Context) (err error) {
 		rpcpb.RegisterControlServiceServer(s.gRPCServer, s)
 	})
 
+	if s.idle != nil {
+		go s.idle.run(rootCtx)
+	}
+
 	gRPCErrc := make(chan error)
 	go func() {
 		zap.L().Info("serving gRPC server", zap.String("port", s.cfg.Port))
@@ -211,6 +242,33 @@ func (s *server) Run(rootCtx context.Context) (err error) {
 	return err
 }
 
+// Returns true if the network's nodes are paused because
+// the server was idle. See idleSuspender.
+func (s *server) suspended() bool {
+	return s.idle != nil && s.idle.isSuspended()
+}
+
+// Returns a copy of [info] reporting the network as unhealthy
+// if its nodes are suspended, or else [info]
+func (s *server) withSuspended(info *rpcpb.ClusterInfo) *rpcpb.ClusterInfo {
+	if !s.suspended() {
+		return info
+	}
+	info = proto.Clone(info).(*rpcpb.ClusterInfo)
+	info.Healthy = false
+	return info
+}
+
+// Returns the current network, or nil if there's none
+func (s *server) getNetwork() network.Network {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.network == nil {
+		return nil
+	}
+	return s.network.getNetwork()
+}
+
 func (s *server) Ping(ctx context.Context, req *rpcpb.PingRequest) (*rpcpb.PingResponse, error) {
 	zap.L().Debug("received ping request")
 	return &rpcpb.PingResponse{Pid: int32(os.Getpid())}, nil
@@ -338,6 +396,7 @@ func (s *server) Start(ctx context.Context, req *rpcpb.StartRequest) (*rpcpb.Sta
 		customVMs:           customVMs,
 		globalNodeConfig:    globalNodeConfig,
 		customNodeConfigs:   customNodeConfigs,
+		observability:       s.cfg.Observability,
 
 		// to block racey restart
 		// "s.network.start" runs asynchronously
@@ -412,6 +471,9 @@ func (s *server) Health(ctx context.Context, req *rpcpb.HealthRequest) (*rpcpb.H
 	zap.L().Debug("health")
 	if info := s.getClusterInfo(); info == nil {
 		return nil, ErrNotBootstrapped
+	} else if s.suspended() {
+		// Its nodes can't be healthy while they're paused
+		return &rpcpb.HealthResponse{ClusterInfo: s.withSuspended(info)}, nil
 	}
 
 	zap.L().Info("waiting for local cluster readiness")
@@ -448,7 +510,7 @@ func (s *server) Status(ctx context.Context, req *rpcpb.StatusRequest) (*rpcpb.S
 	if info == nil {
 		return nil, ErrNotBootstrapped
 	}
-	return &rpcpb.StatusResponse{ClusterInfo: info}, nil
+	return &rpcpb.StatusResponse{ClusterInfo: s.withSuspended(info)}, nil
 }
 
 func (s *server) StreamStatus(req *rpcpb.StreamStatusRequest, stream rpcpb.ControlService_StreamStatusServer) (err error) {
@@ -626,7 +688,7 @@ func (s *server) AddNode(ctx context.Context, req *rpcpb.AddNodeRequest) (*rpcpb
 		RedirectStdout: s.cfg.RedirectNodesOutput,
 		RedirectStderr: s.cfg.RedirectNodesOutput,
 	}
-	_, err = s.network.nw.AddNode(nodeConfig)
+	_, err = s.network.getNetwork().AddNode(nodeConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -647,7 +709,7 @@ func (s *server) RemoveNode(ctx context.Context, req *rpcpb.RemoveNodeRequest) (
 		return nil, ErrNodeNotFound
 	}
 
-	if err := s.network.nw.RemoveNode(req.Name); err != nil {
+	if err := s.network.getNetwork().RemoveNode(req.Name); err != nil {
 		return nil, err
 	}
 
@@ -725,13 +787,13 @@ func (s *server) RestartNode(ctx context.Context, req *rpcpb.RestartNodeRequest)
 
 	// now remove the node before restart
 	zap.L().Info("removing the node")
-	if err := s.network.nw.RemoveNode(req.Name); err != nil {
+	if err := s.network.getNetwork().RemoveNode(req.Name); err != nil {
 		return nil, err
 	}
 
 	// now adding the new node
 	zap.L().Info("adding the node")
-	if _, err := s.network.nw.AddNode(nodeConfig); err != nil {
+	if _, err := s.network.getNetwork().AddNode(nodeConfig); err != nil {
 		return nil, err
 	}
 
@@ -763,9 +825,13 @@ func (s *server) Stop(ctx context.Context, req *rpcpb.StopRequest) (*rpcpb.StopR
 		info = &rpcpb.ClusterInfo{}
 	}
 
+	// Paused nodes are stopped as they are, without being resumed
 	s.network.stop(ctx)
 	s.network = nil
 	s.clusterInfo = nil
+	if s.idle != nil {
+		s.idle.reset()
+	}
 
 	info.Healthy = false
 	return &rpcpb.StopResponse{ClusterInfo: info}, nil
@@ -781,19 +847,9 @@ func (s *server) AttachPeer(ctx context.Context, req *rpcpb.AttachPeerRequest) (
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	node, err := s.network.nw.GetNode((req.NodeName))
-	if err != nil {
-		return nil, err
-	}
-
 	lh := &loggingInboundHandler{nodeName: req.NodeName}
-	newPeer, err := node.AttachPeer(ctx, lh)
-	if err != nil {
-		return nil, err
-	}
-
 	cctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	err = newPeer.AwaitReady(cctx)
+	newPeer, err := s.network.getNetwork().AttachPeer(cctx, req.NodeName, lh)
 	cancel()
 	if err != nil {
 		return nil, err
@@ -979,7 +1035,7 @@ func (s *server) SaveSnapshot(ctx context.Context, req *rpcpb.SaveSnapshotReques
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	snapshotPath, err := s.network.nw.SaveSnapshot(ctx, req.SnapshotName)
+	snapshotPath, err := s.network.getNetwork().SaveSnapshot(ctx, req.SnapshotName)
 	if err != nil {
 		zap.L().Warn("snapshot save failed to complete", zap.Error(err))
 		return nil, err
@@ -997,7 +1053,7 @@ func (s *server) RemoveSnapshot(ctx context.Context, req *rpcpb.RemoveSnapshotRe
 		return nil, ErrNotBootstrapped
 	}
 
-	if err := s.network.nw.RemoveSnapshot(req.SnapshotName); err != nil {
+	if err := s.network.getNetwork().RemoveSnapshot(req.SnapshotName); err != nil {
 		zap.L().Warn("snapshot remove failed to complete", zap.Error(err))
 		return nil, err
 	}
@@ -1011,7 +1067,7 @@ func (s *server) GetSnapshotNames(ctx context.Context, req *rpcpb.GetSnapshotNam
 		return nil, ErrNotBootstrapped
 	}
 
-	snapshotNames, err := s.network.nw.GetSnapshotNames()
+	snapshotNames, err := s.network.getNetwork().GetSnapshotNames()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+)
+
+const (
+	stakingKeyBits = 4096
+	stakingKeyExp  = 65537
+)
+
+// Validity period of deterministic staking certs.
+// Must not depend on the current time, or the cert
+// bytes, and so the node ID, would change between runs.
+var (
+	deterministicCertNotBefore = time.Date(2000, time.January, 0, 0, 0, 0, 0, time.UTC)
+	deterministicCertNotAfter  = time.Date(2100, time.January, 0, 0, 0, 0, 0, time.UTC)
+)
+
+// NewDeterministicCertAndKeyBytes is like staking.NewCertAndKeyBytes
+// except that the key and cert are derived only from [rng], so the same
+// sequence of random numbers always results in the same node ID.
+// crypto/rsa can't be used for this as it doesn't guarantee
+// deterministic key generation from a given random source.
+// Must not be used outside of tests.
+// Returns the PEM byte representations of the cert and key.
+func NewDeterministicCertAndKeyBytes(rng *rand.Rand) ([]byte, []byte, error) {
+	key, err := newDeterministicRSAKey(rng, stakingKeyBits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't generate rsa key: %w", err)
+	}
+
+	// Create self-signed staking cert.
+	// PKCS #1 v1.5 signatures are deterministic, so the cert is too.
+	certTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(0),
+		NotBefore:             deterministicCertNotBefore,
+		NotAfter:              deterministicCertNotAfter,
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageDataEncipherment,
+		BasicConstraintsValid: true,
+	}
+	certBytes, err := x509.CreateCertificate(rng, certTemplate, certTemplate, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't create certificate: %w", err)
+	}
+	var certBuff bytes.Buffer
+	if err := pem.Encode(&certBuff, &pem.Block{Type: "CERTIFICATE", Bytes: certBytes}); err != nil {
+		return nil, nil, fmt.Errorf("couldn't write cert file: %w", err)
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't marshal private key: %w", err)
+	}
+	var keyBuff bytes.Buffer
+	if err := pem.Encode(&keyBuff, &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}); err != nil {
+		return nil, nil, fmt.Errorf("couldn't write private key: %w", err)
+	}
+	return certBuff.Bytes(), keyBuff.Bytes(), nil
+}
+
+// Returns an RSA key of [bits] bits whose primes are drawn from [rng]
+func newDeterministicRSAKey(rng *rand.Rand, bits int) (*rsa.PrivateKey, error) {
+	e := big.NewInt(stakingKeyExp)
+	one := big.NewInt(1)
+	for {
+		p := newDeterministicPrime(rng, bits/2)
+		q := newDeterministicPrime(rng, bits-bits/2)
+		if p.Cmp(q) == 0 {
+			continue
+		}
+		n := new(big.Int).Mul(p, q)
+		if n.BitLen() != bits {
+			continue
+		}
+		totient := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+		d := new(big.Int).ModInverse(e, totient)
+		if d == nil {
+			// [e] isn't coprime with the totient
+			continue
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{
+				N: n,
+				E: stakingKeyExp,
+			},
+			D:      d,
+			Primes: []*big.Int{p, q},
+		}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		key.Precompute()
+		return key, nil
+	}
+}
+
+// Returns a prime of [bits] bits, with its 2 most significant bits set,
+// drawn from [rng]
+func newDeterministicPrime(rng *rand.Rand, bits int) *big.Int {
+	buf := make([]byte, (bits+7)/8)
+	two := big.NewInt(2)
+	for {
+		_, _ = rng.Read(buf)
+		candidate := new(big.Int).SetBytes(buf)
+		// Truncate to [bits] bits
+		candidate.SetBit(candidate, bits-1, 1)
+		candidate.SetBit(candidate, bits-2, 1)
+		for i := len(buf)*8 - 1; i >= bits; i-- {
+			candidate.SetBit(candidate, i, 0)
+		}
+		candidate.SetBit(candidate, 0, 1)
+		// Search for a prime from the candidate onwards
+		for ; candidate.BitLen() == bits; candidate.Add(candidate, two) {
+			if candidate.ProbablyPrime(20) {
+				return candidate
+			}
+		}
+	}
+}
+
+// NewRand returns a random source seeded with [seed].
+// If [seed] is 0, the source is seeded with the current time.
+func NewRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed)) //nolint:gosec
+}
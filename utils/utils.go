@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,14 +17,30 @@ import (
 const genesisNetworkIDKey = "networkID"
 
 func ToNodeID(stakingKey, stakingCert []byte) (ids.NodeID, error) {
-	cert, err := staking.LoadTLSCertFromBytes(stakingKey, stakingCert)
+	cert, err := ToStakingCert(stakingKey, stakingCert)
 	if err != nil {
 		return ids.EmptyNodeID, err
 	}
-	nodeID := ids.NodeIDFromCert(cert.Leaf)
+	nodeID := ids.NodeIDFromCert(cert)
 	return nodeID, nil
 }
 
+// ToStakingCert returns the parsed staking cert, after checking that
+// it matches the staking key. Both are in PEM format.
+func ToStakingCert(stakingKey, stakingCert []byte) (*x509.Certificate, error) {
+	cert, err := staking.LoadTLSCertFromBytes(stakingKey, stakingCert)
+	if err != nil {
+		return nil, err
+	}
+	return cert.Leaf, nil
+}
+
+// CertFingerprint returns the hex encoded SHA-256 hash of [cert]
+func CertFingerprint(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(hash[:])
+}
+
 // Returns the network ID in the given genesis
 func NetworkIDFromGenesis(genesis []byte) (uint32, error) {
 	genesisMap := map[string]interface{}{}